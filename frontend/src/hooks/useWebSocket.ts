@@ -267,6 +267,18 @@ export function useWebSocket() {
           }
           break;
         }
+        // ── まとめ送りされたセンサーデータの受信 ──
+        // payload.samples に sensor_data と同じ形のサンプルが時刻順に並んでいるので、
+        // 1つずつ取り出して sensor_data と同じようにストアへ反映する
+        case "sensor_batch": {
+          const samples = (msg.payload.samples ?? []) as unknown as SensorData[];
+          if (msg.robot_id) {
+            for (const sample of samples) {
+              updateSensorData(msg.robot_id, sample);
+            }
+          }
+          break;
+        }
         // ── ロボットステータスの受信 ──
         case "robot_status": {
           if (msg.robot_id) {
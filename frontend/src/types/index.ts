@@ -197,6 +197,7 @@ export type WSMessageType =
   | "lock_release"   // ロボット操作権の解放
   | "lock_response"  // ロボット操作権の応答
   | "sensor_data"    // センサーデータ（ロボット→クライアント）
+  | "sensor_batch"   // 複数サンプルをまとめたセンサーデータ（ロボット→クライアント）
   | "robot_status"   // ロボット状態更新（ロボット→クライアント）
   | "error";         // エラー通知
 
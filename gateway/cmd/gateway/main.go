@@ -44,6 +44,10 @@ import (
 	// サーバーの安全な停止（グレースフルシャットダウン）に使用。
 	"os/signal"
 
+	// sync: 同期処理のためのパッケージ。
+	// sync.WaitGroup でバックグラウンドゴルーチンの終了を待ち合わせる。
+	"sync"
+
 	// syscall: 低レベルのOSシステムコールを定義するパッケージ。
 	// SIGINT（Ctrl+C）やSIGTERM（終了要求）などのシグナル定数を使用。
 	"syscall"
@@ -74,6 +78,9 @@ import (
 	// 環境変数やデフォルト値から設定を構築する。
 	"github.com/robot-ai-webapp/gateway/internal/config"
 
+	// heartbeat: 常駐ゴルーチンの生存確認（/debug/health で使う）
+	"github.com/robot-ai-webapp/gateway/internal/heartbeat"
+
 	// mw: ミドルウェアパッケージ（エイリアスで短縮名「mw」を付けている）。
 	// 【Go言語の知識: パッケージエイリアス】
 	//
@@ -82,10 +89,16 @@ import (
 	//	例: mw "..." で、middleware の代わりに mw.XXX と書ける。
 	mw "github.com/robot-ai-webapp/gateway/internal/middleware"
 
+	// metrics: メッセージタイプ別の処理時間・エラー数を /metrics で公開するパッケージ。
+	"github.com/robot-ai-webapp/gateway/internal/metrics"
+
 	// protocol: WebSocketメッセージの形式（プロトコル）を定義するパッケージ。
 	// メッセージのエンコード（変換）・デコード（復元）も担当。
 	"github.com/robot-ai-webapp/gateway/internal/protocol"
 
+	// recorder: 接続のメッセージをファイルに記録するセッションレコーダー。
+	"github.com/robot-ai-webapp/gateway/internal/recorder"
+
 	// safety: ロボットの安全機構を提供するパッケージ。
 	// 緊急停止、速度制限、タイムアウト監視などの安全機能。
 	"github.com/robot-ai-webapp/gateway/internal/safety"
@@ -94,6 +107,10 @@ import (
 	// Hub（接続管理）、Handler（メッセージ処理）を含む。
 	"github.com/robot-ai-webapp/gateway/internal/server"
 
+	// version: ビルド時に埋め込まれたバージョン情報（バージョン、コミット、ビルド日時）。
+	// /version エンドポイントと起動ログで使用する。
+	"github.com/robot-ai-webapp/gateway/internal/version"
+
 	// --- 外部ライブラリ ---
 
 	// zap: Uber社が開発した高性能ログライブラリ。
@@ -150,13 +167,27 @@ func main() {
 		os.Exit(1)
 	}
 
+	// 値の範囲と組み合わせを検証する（0 秒のタイムアウトなど、起動はするが
+	// 正しく動かない設定を、起動時にわかりやすいエラーとして止める）。
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid config:\n%v\n", err)
+		os.Exit(1)
+	}
+
 	// -------------------------------------------------------------------------
 	// ステップ2: ロガー（ログ出力器）を初期化する
 	// -------------------------------------------------------------------------
 	// ログレベルに応じたロガーを生成。
 	// ログレベルとは、出力するログの詳細度を制御する仕組み。
 	// debug > info > warn > error の順に、より重要なログだけ出力される。
-	logger := initLogger(cfg.Logging.Level)
+	// GATEWAY_LOG_SAMPLING_ENABLED が true なら、高頻度の同一ログを間引く。
+	// GATEWAY_LOG_STREAM_BUFFER が正なら、管理者へのログ配信（log_stream）用のコアも組み込む。
+	// 配信先の Hub はまだないので、配信はステップ後半の Start で始まる。
+	var logStream *server.LogStream
+	if cfg.Logging.StreamBuffer > 0 {
+		logStream = server.NewLogStream(cfg.Logging.StreamBuffer)
+	}
+	logger := initLogger(cfg.Logging, logStream)
 
 	// 【Go言語の知識: defer（ディファー）】
 	//
@@ -168,9 +199,15 @@ func main() {
 
 	// ログを出力。zap.Int(), zap.String() で構造化ログフィールドを追加。
 	// 構造化ログは JSON 形式で出力されるため、ログ解析ツールで検索しやすい。
+	// バージョン情報も一緒に出力し、ログからデプロイ済みのビルドを特定できるようにする。
+	buildInfo := version.Get()
 	logger.Info("Starting Robot AI Gateway",
 		zap.Int("ws_port", cfg.Server.Port),
 		zap.Int("grpc_port", cfg.Server.GRPCPort),
+		zap.String("version", buildInfo.Version),
+		zap.String("git_commit", buildInfo.GitCommit),
+		zap.String("build_time", buildInfo.BuildTime),
+		zap.String("go_version", buildInfo.GoVersion),
 	)
 
 	// -------------------------------------------------------------------------
@@ -187,6 +224,10 @@ func main() {
 		logger.Warn("Redis connection failed, running without persistence", zap.Error(err))
 		// 接続失敗時は nil（null）を設定し、後で nil チェックで使用を回避する。
 		redisPublisher = nil
+	} else {
+		// payload のエンコード方式（GATEWAY_REDIS_PAYLOAD_CODEC、Validate() で検証済み）
+		codec, _ := bridge.ParsePayloadCodec(cfg.Redis.PayloadCodec)
+		redisPublisher.SetPayloadCodec(codec)
 	}
 
 	// -------------------------------------------------------------------------
@@ -201,6 +242,13 @@ func main() {
 	registry := adapter.NewRegistry(logger)
 	// "mock" タイプのアダプターを登録。mock.Factory がモックアダプターを生成する関数。
 	registry.RegisterFactory("mock", mock.Factory)
+	// 作成してよいアダプタータイプ（GATEWAY_ADAPTER_TYPES_ALLOW/DENY）。
+	// 拒否したタイプはファクトリがあっても CreateAdapter がエラーを返す。
+	registry.SetTypePolicy(cfg.Server.AdapterTypesAllow, cfg.Server.AdapterTypesDeny)
+	// タイプごとの作成数の上限（GATEWAY_ADAPTER_TYPE_LIMITS、指定のないタイプは無制限）
+	registry.SetTypeLimits(cfg.Server.AdapterTypeLimits)
+	// ロボットの所属グループ（GATEWAY_ROBOT_GROUPS、group_command の対象を決める）
+	registry.SetRobotGroups(cfg.Server.RobotGroups)
 
 	// -------------------------------------------------------------------------
 	// ステップ5: 安全機構を初期化する
@@ -215,6 +263,8 @@ func main() {
 	// ロボットの移動速度が設定された上限を超えないようにする。
 	// MaxLinearVelocity: 直線速度の上限、MaxAngularVelocity: 回転速度の上限。
 	velLimiter := safety.NewVelocityLimiter(cfg.Safety.MaxLinearVelocity, cfg.Safety.MaxAngularVelocity, logger)
+	// 上限を超えた時にクランプするか拒否するか（GATEWAY_VELOCITY_CLAMP_MODE）
+	velLimiter.SetClampMode(safety.ClampMode(cfg.Safety.VelocityClampMode))
 
 	// OperationLock: 操作ロック。
 	// 同時に一人のユーザーだけがロボットを操作できるようにする（排他制御）。
@@ -225,6 +275,11 @@ func main() {
 	// これにより、通信が途切れた場合の暴走を防ぐ。
 	watchdog := safety.NewTimeoutWatchdog(cfg.Safety.CommandTimeout(), registry, logger)
 
+	// CommandDeduplicator: コマンドの重複排除。
+	// タイムアウト後の再送（同じ command_id）でナビゲーションやドッキングが
+	// 二重に実行されるのを防ぐ。対象種別は設定で選ぶ（オプトイン）。
+	dedup := safety.NewCommandDeduplicator(cfg.Safety.CommandDedupWindow(), cfg.Safety.CommandDedupTypes, logger)
+
 	// -------------------------------------------------------------------------
 	// ステップ6: WebSocket Hub（接続管理ハブ）を起動する
 	// -------------------------------------------------------------------------
@@ -232,6 +287,19 @@ func main() {
 	// クライアントの接続・切断・メッセージ配信を一元管理する。
 	hub := server.NewHub(logger)
 
+	// 送信キューが満杯の時の方針（GATEWAY_OVERFLOW_POLICY）。値は Validate で確認済み。
+	hub.SetOverflowPolicies(server.OverflowPolicies{
+		Sensor:       server.OverflowPolicy(cfg.Server.OverflowPolicies["sensor"]),
+		Critical:     server.OverflowPolicy(cfg.Server.OverflowPolicies["critical"]),
+		Bulk:         server.OverflowPolicy(cfg.Server.OverflowPolicies["bulk"]),
+		BlockTimeout: time.Duration(cfg.Server.OverflowBlockMs) * time.Millisecond,
+	})
+
+	// 常駐ゴルーチン（Hub.Run、ウォッチドッグ、操作ロックのクリーンアップ）の生存確認。
+	// 各ゴルーチンがループのたびに Beat し、/debug/health で止まっていないかを確認できる。
+	beats := heartbeat.NewRegistry()
+	hub.SetHeartbeat(beats.Register("hub", server.HubHeartbeatInterval))
+
 	// 【Go言語の知識: ゴルーチン（goroutine）】
 	//
 	//	「go 関数名()」で、その関数を別のスレッド（軽量スレッド）で並行実行する。
@@ -260,8 +328,187 @@ func main() {
 	//
 	//	コンポーネントが必要とする依存オブジェクトを外部から渡す手法。
 	//	テストしやすく、モジュール間の結合度が低くなる。
-	handler := server.NewHandler(hub, registry, estopMgr, velLimiter, watchdog, opLock, publisher, logger)
-	wsServer := server.NewWebSocketServer(hub, handler, logger)
+	handler := server.NewHandler(hub, registry, estopMgr, velLimiter, watchdog, opLock, dedup, publisher, logger)
+
+	// 名前付き速度プリセット（GATEWAY_VELOCITY_PRESETS）をハンドラーに設定する。
+	// 設定の型（config.VelocityPreset）からアダプターの速度型に詰め替える。
+	presets := make(map[string]adapter.Velocity, len(cfg.Safety.VelocityPresets))
+	for name, p := range cfg.Safety.VelocityPresets {
+		presets[name] = adapter.Velocity{LinearX: p.LinearX, LinearY: p.LinearY, AngularZ: p.AngularZ}
+	}
+	handler.SetVelocityPresets(presets)
+
+	// ロボットごとに受け付けるコマンド種別（GATEWAY_ROBOT_ALLOWED_COMMANDS）。
+	// 指定のないロボットは、能力（Capabilities）が示すコマンドをすべて受け付ける。
+	handler.SetAllowedCommands(cfg.Safety.RobotAllowedCommands)
+
+	// 速度コマンドの最小間隔（GATEWAY_VELOCITY_MIN_INTERVAL_MS）。0 なら受け付けたらすぐ送る。
+	handler.SetVelocityMinInterval(time.Duration(cfg.Safety.VelocityMinIntervalMs) * time.Millisecond)
+
+	// 配列の長さの上限（GATEWAY_MAX_ARRAY_LEN）。上限を超える配列を含むコマンドは拒否し、
+	// センサーデータは GATEWAY_ARRAY_LIMIT_MODE に従って切り詰めるか捨てる（forwardSensorData）。
+	arrayLimit := safety.NewArrayLimiter(cfg.Server.MaxArrayLen, safety.ArrayLimitMode(cfg.Server.ArrayLimitMode), logger)
+	handler.SetArrayLimiter(arrayLimit)
+
+	// ウォッチドッグがロボットを止めたら、相対速度コマンド（velocity_delta）の基準も 0 に戻す。
+	watchdog.SetTimeoutCallback(handler.ResetVelocityBaseline)
+	// タイムアウト時の減速時間（GATEWAY_WATCHDOG_RAMP_DOWN_MS）。0 なら即座に止める。
+	// 減速は直前に送った速度（基準を 0 に戻す前の値）から始める。
+	watchdog.SetRampDown(time.Duration(cfg.Safety.WatchdogRampDownMs)*time.Millisecond, handler.LastVelocity)
+	watchdog.SetEStop(estopMgr)
+
+	// 操作ロックの期限切れ前の警告と最大保持時間。
+	// 警告は handler が lock_status としてロック保持者の全接続に送る。
+	opLock.SetMaxHold(time.Duration(cfg.Safety.OperationLockMaxHoldSec) * time.Second)
+	opLock.SetExpiryWarning(time.Duration(cfg.Safety.OperationLockWarnSec)*time.Second, handler.NotifyLockExpiring)
+	// 1人のユーザーが同時にロックできるロボットの数（管理者などは対象外にできる）。
+	opLock.SetMaxLocksPerUser(cfg.Safety.OperationLockMaxPerUser, cfg.Safety.OperationLockExemptUsers)
+	opLock.SetHeartbeat(beats.Register("op_lock_cleanup", opLock.CleanupInterval()))
+	watchdog.SetHeartbeat(beats.Register("watchdog", safety.WatchdogCheckInterval))
+
+	// 操作ロックの永続化（GATEWAY_OPERATION_LOCK_PERSIST）。
+	// 再起動前のロックのうち、期限内のものを復元する。Redis がなければメモリ上だけで動く。
+	if cfg.Safety.OperationLockPersist {
+		if redisPublisher != nil {
+			opLock.SetStore(redisPublisher)
+			restored, err := opLock.Restore(context.Background())
+			if err != nil {
+				logger.Warn("Failed to restore operation locks from Redis", zap.Error(err))
+			} else {
+				logger.Info("Operation locks restored", zap.Int("count", restored))
+			}
+		} else {
+			logger.Warn("GATEWAY_OPERATION_LOCK_PERSIST is set but Redis is unavailable; operation locks are kept in memory only")
+		}
+	}
+
+	// センサー停止検出（GATEWAY_SENSOR_STALL_SEC）。0 なら無効（stallDetector は nil のまま）。
+	// アダプターが接続中でも、トピックのデータが途絶えたら safety_alert で知らせる。
+	var stallDetector *safety.SensorStallDetector
+	if cfg.Safety.SensorStallSec > 0 {
+		stallDetector = safety.NewSensorStallDetector(cfg.Safety.SensorStallTimeout(), logger)
+		stallDetector.SetStallCallback(handler.NotifySensorStall)
+		handler.SetStallDetector(stallDetector)
+	}
+
+	// 衝突リスクでの自動停止（GATEWAY_COLLISION_STOP_*）。どのロボットも有効でなければ nil のまま。
+	// LiDAR のスキャンで、指令している進行方向に近い障害物があれば E-Stop（または速度 0）で止める。
+	var collisionMonitor *safety.CollisionMonitor
+	if cfg.Safety.CollisionStopActive() {
+		defaults := safety.CollisionSettings{Enabled: cfg.Safety.CollisionStopEnabled, StopDistance: cfg.Safety.CollisionStopDistance}
+		action, _ := safety.ParseCollisionAction(cfg.Safety.CollisionStopAction) // Validate で確認済み
+		collisionMonitor = safety.NewCollisionMonitor(defaults, cfg.Safety.CollisionStopConeRad(), action, logger)
+		robots := make(map[string]safety.CollisionSettings, len(cfg.Safety.CollisionStopRobots))
+		for robotID, r := range cfg.Safety.CollisionStopRobots {
+			distance := r.StopDistance
+			if distance == 0 {
+				distance = cfg.Safety.CollisionStopDistance
+			}
+			robots[robotID] = safety.CollisionSettings{Enabled: r.Enabled, StopDistance: distance}
+		}
+		collisionMonitor.SetRobotSettings(robots)
+		collisionMonitor.SetVelocitySource(handler.LastVelocity)
+		collisionMonitor.SetRiskCallback(handler.NotifyCollisionRisk)
+	}
+
+	// 操作者の切断時の即時停止（GATEWAY_STOP_ON_LAST_DISCONNECT）。
+	// 最後の操作者が切断したロボットに、ウォッチドッグを待たずに速度 0 を送る。
+	//
+	// 短い切断からの再開（GATEWAY_RESUME_BUFFER_DEPTH）。
+	// 切断したセッションの購読を覚えておき、猶予時間内の再接続で溜めたデータを再送する。
+	var sessionBuffer *server.SessionBuffer
+	if cfg.Server.ResumeBufferDepth > 0 {
+		sessionBuffer = server.NewSessionBuffer(cfg.Server.ResumeBufferDepth, time.Duration(cfg.Server.ResumeGraceSec)*time.Second, logger)
+		handler.SetSessionBuffer(sessionBuffer)
+	}
+	// セッションの記録（GATEWAY_SESSION_RECORD_DIR）。
+	// 全接続（GATEWAY_SESSION_RECORD_ALL）か、管理者が record_session で指定した接続のメッセージをファイルに残す。
+	var sessionRecorder *recorder.Recorder
+	if cfg.Server.SessionRecordDir != "" {
+		sessionRecorder, err = recorder.New(cfg.Server.SessionRecordDir, cfg.Server.SessionRecordMaxBytes, cfg.Server.SessionRecordMaxFiles, logger)
+		if err != nil {
+			logger.Fatal("Failed to create session recorder", zap.Error(err))
+		}
+		handler.SetSessionRecorder(sessionRecorder, cfg.Server.SessionRecordAll)
+	}
+	// 認証時に自動で購読するロボットの範囲（GATEWAY_AUTO_SUBSCRIBE、値は Validate で検証済み）。
+	autoSubscribe, _ := server.ParseAutoSubscribeMode(cfg.Server.AutoSubscribe)
+	handler.SetAutoSubscribe(autoSubscribe)
+
+	// ロックを持たずに操作コマンドを送った時の扱い（GATEWAY_OPERATION_LOCK_MODE、Validate() で検証済み）
+	lockMode, _ := server.ParseLockMode(cfg.Safety.OperationLockMode)
+	handler.SetLockMode(lockMode)
+
+	// 管理者（GATEWAY_ADMIN_USERS）だけが log_stream でのログの購読と、
+	// set_speed_limit でのロボットごとの速度上限の変更を使える。
+	handler.SetAdminUsers(cfg.Auth.AdminUsers)
+	// 観察者（GATEWAY_OBSERVER_USERS）と管理者は、observe_commands で操作者のコマンドの写しを受け取れる。
+	handler.SetObserverUsers(cfg.Auth.ObserverUsers)
+
+	var onUnregister []func(*server.Client)
+	if cfg.Safety.StopOnLastDisconnect {
+		onUnregister = append(onUnregister, handler.StopRobotsControlledBy)
+	}
+	if sessionBuffer != nil {
+		onUnregister = append(onUnregister, handler.ParkSession)
+	}
+	if len(onUnregister) > 0 {
+		hub.SetUnregisterCallback(func(client *server.Client) {
+			for _, fn := range onUnregister {
+				fn(client)
+			}
+		})
+	}
+
+	// レジストリからロボットが削除されたら、購読していたクライアントに知らせて購読から外す。
+	registry.SetRemoveCallback(handler.RobotRemoved)
+
+	// ナビゲーション目標の座標変換（GATEWAY_NAV_FRAME_TRANSFORMS）をハンドラーに設定する。
+	// 変換先はすべて TargetFrame なので、変換元の座標系をキーにして詰め替える。
+	frameTransforms := make(map[string]server.FrameTransform, len(cfg.Navigation.FrameTransforms))
+	for _, t := range cfg.Navigation.FrameTransforms {
+		frameTransforms[t.From] = server.FrameTransform{
+			TranslationX: t.TranslationX,
+			TranslationY: t.TranslationY,
+			Rotation:     t.Rotation,
+			Scale:        t.Scale,
+		}
+	}
+	handler.SetNavFrameTransforms(cfg.Navigation.TargetFrame, frameTransforms)
+
+	// メッセージタイプ別の処理時間ヒストグラムとエラー数を記録する（/metrics で公開）
+	messageMetrics := metrics.NewMessageMetrics()
+	handler.SetMessageMetrics(messageMetrics)
+	wsServer := server.NewWebSocketServer(hub, handler, cfg.Server.WSReadBufferSize, cfg.Server.WSWriteBufferSize, logger)
+	// 連続して GATEWAY_CLIENT_ERROR_BUDGET 回エラーになったクライアントは切断する
+	wsServer.SetErrorBudget(cfg.Server.ClientErrorBudget)
+	// クライアントへの送信量をメッセージタイプ×ロボットごとに数える（/metrics で公開）
+	egressMetrics := metrics.NewEgressMetrics()
+	wsServer.SetEgressMetrics(egressMetrics)
+	messageMetrics.AddCollector(egressMetrics)
+	// ロボットごとの速度（指令値と実測値）と E-Stop の状態のゲージ（/metrics で公開）。
+	// 指令値は handler、実測値はオドメトリを配信する fanout が記録する。
+	robotMetrics := metrics.NewRobotMetrics()
+	robotMetrics.SetEStopSource(func() []string {
+		active := registry.GetAllActive()
+		robots := make([]string, 0, len(active))
+		for robotID := range active {
+			robots = append(robots, robotID)
+		}
+		return robots
+	}, estopMgr.IsActive)
+	handler.SetRobotMetrics(robotMetrics)
+	messageMetrics.AddCollector(robotMetrics)
+
+	// 起動時のセルフテスト: トラフィックを受け付ける前に、一時的なモックロボットで
+	// 安全パイプラインとセンサーデータの経路を確認する。重要な経路が壊れていれば
+	// logger.Fatal で終了する（終了コードは非ゼロ）。Redis の失敗は警告のみ。
+	if cfg.Server.SelfTestEnabled {
+		report := server.NewSelfTest(registry, estopMgr, velLimiter, watchdog, opLock, publisher, logger).Run(context.Background())
+		if !report.Passed() {
+			logger.Fatal("Startup self-test failed")
+		}
+	}
 
 	// -------------------------------------------------------------------------
 	// ステップ8: バックグラウンド処理を開始する
@@ -275,9 +522,41 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// アダプターへのコマンド送信は ctx から派生させ、シャットダウンで打ち切れるようにする。
+	// アダプターが応答しない時は GATEWAY_ADAPTER_CMD_TIMEOUT_MS で諦めて command_timeout を返す。
+	handler.SetCommandContext(ctx, time.Duration(cfg.Safety.AdapterCommandTimeoutMs)*time.Millisecond)
+
+	// 【Go言語の知識: sync.WaitGroup】
+	//
+	//	ゴルーチンの終了を待ち合わせる仕組み。Add(1) で登録し、終了時に Done()、
+	//	Wait() は登録された全ゴルーチンが Done() するまでブロックする。
+	//	cancel() や close(done) は「止まって」と伝えるだけなので、
+	//	実際に止まったことはシャットダウン時にこれで確認する。
+	//	どのゴルーチンが止まらなかったかをログに出せるよう、名前ごとに分けて持つ。
+	bgTasks := map[string]*sync.WaitGroup{
+		"watchdog":          {},
+		"op_lock_cleanup":   {},
+		"cmd_dedup_cleanup": {},
+		"sensor_forwarder":  {},
+		"event_forwarder":   {},
+		"flow_control":      {},
+		"sensor_batch":      {},
+		"sensor_stall":      {},
+		"sensor_fanout":     {},
+		"log_stream":        {},
+		"redis_reconnect":   {},
+		"app_heartbeat":     {},
+	}
+
 	// ウォッチドッグ（タイムアウト監視）をバックグラウンドで開始。
 	// ctx がキャンセルされると自動的に停止する。
-	watchdog.Start(ctx)
+	watchdog.Start(ctx, bgTasks["watchdog"])
+	if logStream != nil {
+		logStream.Start(ctx, hub, bgTasks["log_stream"])
+	}
+	if stallDetector != nil {
+		stallDetector.Start(ctx, bgTasks["sensor_stall"])
+	}
 
 	// 操作ロックのクリーンアップ処理を開始。
 	// 【Go言語の知識: チャネル（channel）】
@@ -287,21 +566,43 @@ func main() {
 	//	struct{} は空の構造体で、メモリを消費しない（シグナルだけに使う）。
 	//	close(done) でチャネルを閉じると、受信側に「終了」のシグナルが伝わる。
 	done := make(chan struct{})
-	opLock.StartCleanup(done)
+	opLock.StartCleanup(done, bgTasks["op_lock_cleanup"])
+	dedup.StartCleanup(done, bgTasks["cmd_dedup_cleanup"]) // 期限切れの重複排除記録も同じ done で停止する
 
 	// -------------------------------------------------------------------------
 	// ステップ9: モックロボットを作成・接続する（開発用）
 	// -------------------------------------------------------------------------
 	// 開発時は実際のロボットがないため、モック（偽物）のロボットを使う。
 	// 実運用では、実際のロボットのアダプターに置き換える。
-	mockAdapter, err := registry.CreateAdapter("mock-robot-1", "mock")
-	if err != nil {
-		// logger.Fatal: 致命的エラー。ログ出力後にプロセスを即座に終了する。
-		logger.Fatal("Failed to create mock adapter", zap.Error(err))
-	}
-	// モックロボットに接続開始。nil はオプション設定がないことを示す。
-	if err := mockAdapter.Connect(ctx, nil); err != nil {
-		logger.Fatal("Failed to connect mock adapter", zap.Error(err))
+	// 設定（GATEWAY_ADAPTER_TYPES_ALLOW/DENY）で mock が許可されていなければ作らない。
+	// 本番で誤ってモックロボットの偽データが流れるのを防ぐため。
+	var mockAdapter adapter.RobotAdapter
+	if registry.TypeAllowed("mock") {
+		mockAdapter, err = registry.CreateAdapter("mock-robot-1", "mock")
+		if err != nil {
+			// logger.Fatal: 致命的エラー。ログ出力後にプロセスを即座に終了する。
+			logger.Fatal("Failed to create mock adapter", zap.Error(err))
+		}
+		// モックロボットに接続開始。
+		// ノイズプロファイルのファイルが設定されていれば、接続設定として渡す。
+		// 空文字列の場合、モックは従来どおりの一様ノイズを使う。
+		// velocity_ramp_ms が正なら、速度指令をその時間かけてなめらかに反映する。
+		// enabled_topics が空なら全センサー（odom, scan, imu, battery）を生成する。
+		// odom_drift_* が 0 以外なら、オドメトリに累積誤差（ドリフト）を加える。
+		mockConfig := map[string]any{
+			"noise_profile_file":    cfg.Mock.NoiseProfileFile,
+			"noise_profile":         cfg.Mock.NoiseProfile,
+			"velocity_ramp_ms":      cfg.Mock.VelocityRampMs,
+			"enabled_topics":        cfg.Mock.EnabledTopics,
+			"odom_drift_per_meter":  cfg.Mock.OdomDriftPerMeter,
+			"odom_drift_per_radian": cfg.Mock.OdomDriftPerRadian,
+			"odom_drift_noise":      cfg.Mock.OdomDriftNoise,
+		}
+		if err := mockAdapter.Connect(ctx, mockConfig); err != nil {
+			logger.Fatal("Failed to connect mock adapter", zap.Error(err))
+		}
+	} else {
+		logger.Warn("Mock robot disabled by adapter type policy")
 	}
 
 	// -------------------------------------------------------------------------
@@ -312,7 +613,103 @@ func main() {
 
 	// センサーデータをロボットから受信し、WebSocketクライアントとRedisに転送する
 	// ゴルーチンをバックグラウンドで開始。
-	go forwardSensorData(ctx, "mock-robot-1", mockAdapter, hub, codec, redisPublisher, logger)
+	// まとめ送り（sensor_batch）を希望したクライアント向けに、
+	// GATEWAY_SENSOR_BATCH_WINDOW_MS ごとにサンプルをまとめて配信する。0 なら無効。
+	var batcher *server.SensorBatcher
+	if cfg.Server.SensorBatchWindowMs > 0 {
+		batcher = server.NewSensorBatcher(hub, time.Duration(cfg.Server.SensorBatchWindowMs)*time.Millisecond, logger)
+		batcher.Start(ctx, bgTasks["sensor_batch"])
+	}
+
+	// エンコードと配信は配信ワーカー（GATEWAY_SENSOR_FANOUT_WORKERS 個）が行い、
+	// Redis への永続化は上限付きのキュー（GATEWAY_SENSOR_PERSIST_QUEUE）で別に行う。
+	fanout := server.NewSensorFanout(hub, codec, cfg.Server.SensorFanoutWorkers, cfg.Server.SensorPersistQueue, logger)
+	fanout.SetBatcher(batcher)
+	fanout.SetStallDetector(stallDetector)
+	fanout.SetCollisionMonitor(collisionMonitor)
+	fanout.SetTopicRemap(server.TopicRemap(cfg.Server.TopicRemaps))
+	fanout.SetSessionBuffer(sessionBuffer)
+	// トピックごとの最新のサンプルを残し、sensor_request（購読しない単発の問い合わせ）に答える。
+	sensorCache := server.NewSensorCache(server.TopicRemap(cfg.Server.TopicRemaps))
+	fanout.SetSensorCache(sensorCache)
+	fanout.SetRobotMetrics(robotMetrics)
+	// 運用から外したロボット（set_robot_enabled）のセンサーデータを止める（GATEWAY_DISABLED_ROBOT_PAUSE_SENSORS）
+	if cfg.Server.DisabledRobotPauseSensors {
+		fanout.SetRobotEnabled(registry.IsEnabled)
+	}
+	handler.SetSensorCache(sensorCache)
+	if redisPublisher != nil {
+		fanout.SetPersister(redisPublisher)
+	}
+	fanout.Start(ctx, bgTasks["sensor_fanout"])
+
+	// アプリケーション層のハートビート（GATEWAY_APP_HEARTBEAT_INTERVAL_MS / GATEWAY_APP_HEARTBEAT_TIMEOUT_MS）
+	if cfg.Server.AppHeartbeatIntervalMs > 0 || cfg.Server.AppHeartbeatTimeoutMs > 0 {
+		server.NewAppHeartbeat(hub, codec,
+			time.Duration(cfg.Server.AppHeartbeatIntervalMs)*time.Millisecond,
+			time.Duration(cfg.Server.AppHeartbeatTimeoutMs)*time.Millisecond,
+			logger,
+		).Start(ctx, bgTasks["app_heartbeat"])
+	}
+
+	// 起動時に Redis へ接続できなかった場合は、バックグラウンドで接続を試み直す
+	// （GATEWAY_REDIS_RECONNECT_INTERVAL_MS、0 なら試み直さない）。
+	// 接続できたら、コマンドの記録（handler）とセンサーデータの永続化（fanout）を始める。
+	var redisReconnector *bridge.Reconnector
+	if redisPublisher == nil && cfg.Redis.ReconnectIntervalMs > 0 {
+		redisReconnector = bridge.NewReconnector(cfg.Redis.URL, time.Duration(cfg.Redis.ReconnectIntervalMs)*time.Millisecond, logger)
+		redisReconnector.Start(ctx, bgTasks["redis_reconnect"], func(p *bridge.RedisPublisher) {
+			codec, _ := bridge.ParsePayloadCodec(cfg.Redis.PayloadCodec)
+			p.SetPayloadCodec(codec)
+			handler.SetPublisher(p)
+			fanout.SetPersister(p)
+		})
+	}
+
+	// センサーデータの検証: 物理的にありえない値を、トピックごとのポリシーで扱う。
+	// GATEWAY_SENSOR_VALIDATION が空なら validator は nil（検証しない）。
+	var validator *safety.SensorValidator
+	if len(cfg.Server.SensorValidation) > 0 {
+		policies := make(map[string]safety.SensorValidationPolicy, len(cfg.Server.SensorValidation))
+		for topic, p := range cfg.Server.SensorValidation {
+			policy, err := safety.ParseSensorValidationPolicy(p)
+			if err != nil {
+				logger.Fatal("Invalid sensor validation policy", zap.Error(err))
+			}
+			policies[topic] = policy
+		}
+		validator = safety.NewSensorValidator(policies, logger)
+	}
+
+	if mockAdapter != nil {
+		forwarderWG := bgTasks["sensor_forwarder"]
+		forwarderWG.Add(1)
+		go func() {
+			defer forwarderWG.Done()
+			forwardSensorData(ctx, "mock-robot-1", mockAdapter, validator, arrayLimit, fanout)
+		}()
+
+		// ロボットからのイベント（バンパー接触、物理 E-Stop など）は、センサーデータとは別に転送する。
+		eventWG := bgTasks["event_forwarder"]
+		eventWG.Add(1)
+		go func() {
+			defer eventWG.Done()
+			forwardRobotEvents(ctx, "mock-robot-1", mockAdapter, handler)
+		}()
+	}
+
+	// フロー制御: クライアントが全員遅い時は、アダプターの生成頻度を一時的に下げる。
+	// 頻度を変更できるアダプター（SampleRateController を実装）の場合のみ有効。
+	// 【Go言語の知識: 型アサーション（Type Assertion）】
+	//
+	//	x.(T) で「インターフェース値 x が型 T も満たすか」を確認できる。
+	//	ok が false なら未対応なので、フロー制御は行わない。
+	// set_sensor_rate で変えた頻度に倍率を掛けるよう、ハンドラーにも登録する。
+	if rc, ok := mockAdapter.(adapter.SampleRateController); ok {
+		flowController := server.NewFlowController(hub, "mock-robot-1", rc, logger)
+		handler.SetFlowController("mock-robot-1", flowController)
+		flowController.Start(ctx, bgTasks["flow_control"])
+	}
 
 	// -------------------------------------------------------------------------
 	// ステップ11: HTTPサーバーを設定・起動する
@@ -324,16 +721,32 @@ func main() {
 	//	リクエストごとにトークンを消費する。
 	//	トークンがなくなるとリクエストを拒否する。
 	//	DDoS攻撃やサーバー過負荷を防ぐための仕組み。
-	rateLimiter := mw.NewRateLimiter(120, logger)
+	//
+	// クライアントIPの解決器も作成する。GATEWAY_TRUSTED_PROXIES で指定した
+	// プロキシからの接続に限り X-Forwarded-For を信用する（未指定なら信用しない）。
+	ipResolver, err := mw.NewClientIPResolver(cfg.Server.TrustedProxies)
+	if err != nil {
+		logger.Fatal("Invalid trusted proxies configuration", zap.Error(err))
+	}
+	rateLimiter := mw.NewRateLimiter(120, ipResolver, logger)
 
 	// 【Go言語の知識: ServeMux（マルチプレクサ）】
 	//
 	//	HTTPリクエストのURLパスに応じて、適切なハンドラーに振り分ける「ルーター」。
 	//	HandleFunc でパスとハンドラー関数を登録する。
 	mux := http.NewServeMux()
-	mux.HandleFunc("/ws", wsServer.HandleWebSocket)   // WebSocket接続エンドポイント
-	mux.HandleFunc("/health", wsServer.HealthHandler) // ヘルスチェック用（監視ツール用）
-	mux.HandleFunc("/ready", wsServer.HealthHandler)  // 準備完了チェック用（Kubernetes用）
+	mux.HandleFunc("/ws", wsServer.HandleWebSocket)             // WebSocket接続エンドポイント
+	mux.HandleFunc("/health", wsServer.HealthHandler)           // ヘルスチェック用（監視ツール用）
+	mux.HandleFunc("/ready", wsServer.HealthHandler)            // 準備完了チェック用（Kubernetes用）
+	mux.HandleFunc("/version", version.Handler)                 // ビルド情報（バージョン、コミット等）
+	mux.HandleFunc("/metrics", messageMetrics.Handler)          // Prometheus形式のメトリクス
+	mux.HandleFunc("/speed-limits", handler.SpeedLimitsHandler) // ロボットごとに適用中の速度上限
+	mux.HandleFunc("/schema", handler.SchemaHandler)            // メッセージタイプと Payload の一覧（認証不要）
+	mux.HandleFunc("/stats", handler.StatsHandler)              // 接続数とアダプタータイプごとの台数・上限
+	// ゴルーチンとチャネルの健全性（GATEWAY_DEBUG_HEALTH_ENABLED、管理者トークンが必要）
+	if cfg.Server.DebugHealthEnabled {
+		mux.HandleFunc("/debug/health", server.NewDebugHealth(hub, registry, beats, cfg.Server.DebugToken).Handler)
+	}
 
 	// 【Go言語の知識: 構造体リテラル（Struct Literal）と & 演算子】
 	//
@@ -352,7 +765,7 @@ func main() {
 	httpServer := &http.Server{
 		// fmt.Sprintf で "ホスト:ポート" 形式のアドレス文字列を生成。
 		Addr:         fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port),
-		Handler:      rateLimiter.Middleware(mw.LoggingMiddleware(logger)(mux)),
+		Handler:      rateLimiter.Middleware(mw.LoggingMiddleware(logger, ipResolver)(mux)),
 		ReadTimeout:  15 * time.Second, // リクエスト読み取りのタイムアウト
 		WriteTimeout: 15 * time.Second, // レスポンス書き込みのタイムアウト
 		IdleTimeout:  60 * time.Second, // キープアライブ接続のアイドルタイムアウト
@@ -400,6 +813,15 @@ func main() {
 
 	logger.Info("Shutting down gracefully...")
 
+	// 接続中のクライアントに停止を予告し、正常終了の Close フレームで接続を閉じる。
+	// httpServer.Shutdown() は WebSocket の接続を閉じないため、ここで先に行う。
+	// クライアントは「サーバー再起動中」と表示して、再接続を予定できる。
+	notifyCtx, notifyCancel := context.WithTimeout(context.Background(), 3*time.Second)
+	if err := wsServer.Shutdown(notifyCtx, "server shutting down"); err != nil {
+		logger.Warn("Timed out closing client connections", zap.Error(err))
+	}
+	notifyCancel()
+
 	// すべてのバックグラウンドゴルーチンにキャンセルを通知。
 	// cancel() を呼ぶと、ctx.Done() チャネルが閉じられ、
 	// ctx を使っているすべてのゴルーチン内の select 文の case <-ctx.Done() が発火する。
@@ -407,18 +829,35 @@ func main() {
 	// done チャネルを閉じて、opLock のクリーンアップゴルーチンを停止。
 	close(done)
 
+	// バックグラウンドゴルーチンが実際に終了するのを待つ（最大5秒）。
+	// センサー転送が止まる前に Redis を閉じると、最後の書き込みが失われるため、
+	// Redis のクローズより前に待ち合わせる。
+	waitForBackgroundTasks(bgTasks, 5*time.Second, logger)
+
+	// 記録中のファイルを閉じる（接続はすべて閉じたので、以後の記録はない）。
+	if sessionRecorder != nil {
+		_ = sessionRecorder.Close()
+	}
+
 	// モックロボットとの接続を切断。
 	// 【Go言語の知識: _ （アンダースコア）によるエラー無視】
 	//
 	//	_ = は戻り値のエラーを意図的に無視することを明示。
 	//	シャットダウン時のエラーは通常無視しても問題ないため。
 	//	ただし、通常のコードでは err を必ずチェックすべき。
-	_ = mockAdapter.Disconnect(context.Background())
+	if mockAdapter != nil {
+		_ = mockAdapter.Disconnect(context.Background())
+	}
 
 	// Redis接続を閉じる。
 	if redisPublisher != nil {
 		_ = redisPublisher.Close()
 	}
+	if redisReconnector != nil {
+		if p := redisReconnector.Publisher(); p != nil {
+			_ = p.Close()
+		}
+	}
 
 	// HTTPサーバーを停止する。
 	// 【Go言語の知識: context.WithTimeout】
@@ -436,12 +875,68 @@ func main() {
 }
 
 // =============================================================================
-// forwardSensorData: センサーデータをロボットからクライアントに転送する関数
+// waitForBackgroundTasks: バックグラウンドゴルーチンの終了を待つ関数
+//
+// 全タスク共通の期限（timeout）まで待ち、期限までに終わらなかった
+// タスクの名前を警告ログに出す。期限を過ぎてもブロックし続けないため、
+// 止まらないゴルーチンがあってもプロセスは終了できる。
+//
+// 【Go言語の知識: WaitGroup をチャネルに変換する】
+//
+//	wg.Wait() にはタイムアウトがないため、別ゴルーチンで Wait() して
+//	終わったらチャネルを閉じる。チャネルなら select で期限と一緒に待てる。
+//
+// =============================================================================
+func waitForBackgroundTasks(tasks map[string]*sync.WaitGroup, timeout time.Duration, logger *zap.Logger) {
+	finished := make(map[string]chan struct{}, len(tasks))
+	for name, wg := range tasks {
+		ch := make(chan struct{})
+		go func(wg *sync.WaitGroup) {
+			wg.Wait()
+			close(ch)
+		}(wg)
+		finished[name] = ch
+	}
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	expired := false
+
+	var stuck []string
+	for name, ch := range finished {
+		if !expired {
+			select {
+			case <-ch:
+				continue
+			case <-deadline.C:
+				expired = true
+			}
+		}
+		// 期限切れ後は待たずに、終わっているかどうかだけを確認する
+		select {
+		case <-ch:
+		default:
+			stuck = append(stuck, name)
+		}
+	}
+
+	if len(stuck) > 0 {
+		logger.Warn("Background tasks did not stop in time",
+			zap.Strings("tasks", stuck),
+			zap.Duration("timeout", timeout),
+		)
+		return
+	}
+	logger.Info("All background tasks stopped")
+}
+
+// =============================================================================
+// forwardSensorData: センサーデータをロボットから配信ワーカーに渡す関数
 //
 // この関数はゴルーチンとして実行され、ロボットのセンサーデータを
-// 継続的に受信し、以下の2つの宛先に転送します：
-//  1. WebSocketクライアントへのリアルタイム配信（Hub経由）
-//  2. Redisへの永続化（バックエンドでの記録・分析用）
+// 継続的に受信し、SensorFanout（配信ワーカー）に渡します。
+// エンコード・WebSocket 配信・Redis への永続化はワーカー側で行うため、
+// Redis が遅れても、ここでの受信（とリアルタイム配信）は遅れません。
 //
 // 【Go言語の知識: チャネルと select 文】
 //
@@ -452,24 +947,15 @@ func main() {
 //
 // 引数の説明：
 //
-//	ctx           : キャンセル可能なコンテキスト。停止シグナルを受け取る
-//	robotID       : ロボットの一意な識別子（例: "mock-robot-1"）
-//	adp           : ロボットアダプター（センサーデータのソース）
-//	hub           : WebSocket Hub（クライアントへの配信を管理）
-//	codec         : メッセージのエンコーダー（バイト列に変換）
-//	redisPublisher: Redis への発行者（nil の場合は Redis に記録しない）
-//	logger        : ログ出力器
+//	ctx       : キャンセル可能なコンテキスト。停止シグナルを受け取る
+//	robotID   : ロボットの一意な識別子（例: "mock-robot-1"）
+//	adp       : ロボットアダプター（センサーデータのソース）
+//	validator : センサーデータの検証器（nil なら検証しない）
+//	arrayLimit: 配列の長さの制限器（nil なら制限しない）
+//	fanout    : 配信ワーカー（WebSocket 配信と Redis への永続化を行う）
 //
 // =============================================================================
-func forwardSensorData(
-	ctx context.Context,
-	robotID string,
-	adp adapter.RobotAdapter,
-	hub *server.Hub,
-	codec *protocol.Codec,
-	redisPublisher *bridge.RedisPublisher,
-	logger *zap.Logger,
-) {
+func forwardSensorData(ctx context.Context, robotID string, adp adapter.RobotAdapter, validator *safety.SensorValidator, arrayLimit *safety.ArrayLimiter, fanout *server.SensorFanout) {
 	// ロボットアダプターからセンサーデータを受信するチャネルを取得。
 	// 【Go言語の知識: チャネル（Channel）の方向】
 	//
@@ -505,43 +991,43 @@ func forwardSensorData(
 			// ロボットIDをセンサーデータに設定（どのロボットからのデータか識別するため）。
 			data.RobotID = robotID
 
-			// --- WebSocket クライアントへの転送 ---
-
-			// WebSocket用のメッセージを作成。
-			// protocol.NewMessage: タイプとロボットIDを指定してメッセージ構造体を生成。
-			msg := protocol.NewMessage(protocol.MsgTypeSensorData, robotID)
-			msg.Topic = data.Topic
-
-			// 【Go言語の知識: map[string]any（マップ）】
-			//
-			//	map[string]any は「文字列キー → 任意の型の値」のマップ。
-			//	any は interface{} のエイリアスで、どんな型でも格納できる。
-			//	JSON のオブジェクトに似た構造。
-			msg.Payload = map[string]any{
-				"data_type": data.DataType,
-				"frame_id":  data.FrameID,
-				"data":      data.Data,
+			// 物理的にありえない値を検証する。drop（または clamp で直せない）なら、
+			// クライアントにも Redis にも渡さずに捨てる。
+			if !validator.Check(&data) {
+				continue
 			}
-
-			// メッセージをバイト列にエンコード（MessagePack形式）。
-			encoded, err := codec.Encode(msg)
-			if err != nil {
-				logger.Error("Failed to encode sensor data", zap.Error(err))
-				// 【Go言語の知識: continue】
-				//
-				//	ループの残りの処理をスキップし、次のイテレーション（繰り返し）に進む。
-				//	エンコードに失敗したデータは諦めて、次のデータを処理する。
+			// 上限を超える配列は切り詰める（drop モードなら捨てる）。
+			if !arrayLimit.CheckSensor(&data) {
 				continue
 			}
-			// 指定したロボットIDのクライアントにブロードキャスト（一斉送信）。
-			hub.BroadcastToRobot(robotID, encoded)
-
-			// --- Redis への永続化 ---
-			// Redis が有効な場合のみ、センサーデータを Redis Stream に発行。
-			// Redis Stream はログのような時系列データに最適。
-			if redisPublisher != nil {
-				_ = redisPublisher.PublishSensorData(ctx, robotID, data)
+
+			// ワーカーに渡す。ワーカーが詰まっていれば空くまで待つ（停止時は抜ける）。
+			if !fanout.Submit(ctx, data) {
+				return
+			}
+		}
+	}
+}
+
+// =============================================================================
+// forwardRobotEvents: ロボットからのイベントをハンドラーに渡す関数
+//
+// forwardSensorData と同じ形のループで、EventChannel() から受け取ったイベントを
+// Handler.NotifyRobotEvent に渡す（購読者への配信、Redis への記録、物理 E-Stop のラッチ）。
+// イベントはセンサーデータよりずっと少なく、1件ずつ確実に届けたいので、ワーカーを挟まずに直接処理する。
+// =============================================================================
+func forwardRobotEvents(ctx context.Context, robotID string, adp adapter.RobotAdapter, handler *server.Handler) {
+	ch := adp.EventChannel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-ch:
+			if !ok {
+				return
 			}
+			ev.RobotID = robotID
+			handler.NotifyRobotEvent(ctx, ev)
 		}
 	}
 }
@@ -561,11 +1047,23 @@ func forwardSensorData(
 //	Go の switch は C/Java と異なり、自動的に break される（fall-through しない）。
 //	default は、どの case にも一致しない場合に実行される。
 //
+// 【ログのサンプリング】
+//
+//	debug レベルで 50Hz の IMU などを扱うと、同じログが大量に出て
+//	標準出力が溢れ、ホットパス（高頻度で実行される処理）まで遅くなる。
+//	cfg.SamplingEnabled が true なら、zap のサンプラーで Info 以下のログを間引く。
+//	Warn / Error は問題調査に必要なので、常にすべて出力する。
+//
+// 【ログ配信】
+//
+//	logStream が nil でなければ、出力先に管理者への配信を加える。
+//	サンプリングより内側に組み込むので、間引かれたログは配信もされない。
+//
 // =============================================================================
-func initLogger(level string) *zap.Logger {
+func initLogger(cfg config.LoggingConfig, logStream *server.LogStream) *zap.Logger {
 	// zapcore.Level: ログレベルの型。数値で定義されている。
 	var zapLevel zapcore.Level
-	switch level {
+	switch cfg.Level {
 	case "debug":
 		zapLevel = zapcore.DebugLevel
 	case "info":
@@ -604,7 +1102,17 @@ func initLogger(level string) *zap.Logger {
 	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 
 	// Build(): 設定からロガーインスタンスを構築する。
-	logger, err := config.Build()
+	// サンプリングが有効なら、構築したコアを zap.WrapCore で包み直す。
+	var opts []zap.Option
+	if logStream != nil {
+		opts = append(opts, zap.WrapCore(logStream.WrapCore))
+	}
+	if cfg.SamplingEnabled {
+		opts = append(opts, zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return newSampledCore(core, cfg.SamplingInitial, cfg.SamplingThereafter)
+		}))
+	}
+	logger, err := config.Build(opts...)
 	if err != nil {
 		// 【Go言語の知識: panic】
 		//
@@ -615,3 +1123,54 @@ func initLogger(level string) *zap.Logger {
 	}
 	return logger
 }
+
+// =============================================================================
+// newSampledCore: Info 以下だけを間引き、Warn 以上はそのまま出力するコアを作る
+//
+// zapcore.NewSamplerWithOptions はすべてのレベルを間引いてしまうため、
+// レベルでコアを2つに分け、zapcore.NewTee で束ねる。
+//
+//	Debug / Info ─→ belowLevelCore ─→ サンプラー ─┐
+//	                                              ├─→ 出力
+//	Warn / Error ─→ IncreaseLevelCore ────────────┘
+//
+// =============================================================================
+func newSampledCore(core zapcore.Core, initial, thereafter int) zapcore.Core {
+	sampled := zapcore.NewSamplerWithOptions(
+		belowLevelCore{Core: core, max: zapcore.WarnLevel},
+		time.Second, initial, thereafter,
+	)
+
+	// 元のコアのレベルが既に Warn 以上ならエラーになるが、その場合は元のコアをそのまま使えばよい。
+	unsampled, err := zapcore.NewIncreaseLevelCore(core, zapcore.WarnLevel)
+	if err != nil {
+		unsampled = core
+	}
+	return zapcore.NewTee(sampled, unsampled)
+}
+
+// belowLevelCore: max 未満のレベルのログだけを通すコア
+//
+// 【Go言語の知識: 構造体の埋め込み（Embedding）】
+//
+//	zapcore.Core を埋め込むと、そのメソッドをすべて引き継げる。
+//	ここではレベル判定に関わる Enabled / Check / With だけを上書きしている。
+type belowLevelCore struct {
+	zapcore.Core
+	max zapcore.Level
+}
+
+func (c belowLevelCore) Enabled(lvl zapcore.Level) bool {
+	return lvl < c.max && c.Core.Enabled(lvl)
+}
+
+func (c belowLevelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+func (c belowLevelCore) With(fields []zapcore.Field) zapcore.Core {
+	return belowLevelCore{Core: c.Core.With(fields), max: c.max}
+}
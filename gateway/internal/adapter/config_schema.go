@@ -0,0 +1,172 @@
+// =============================================================================
+// ファイル: config_schema.go
+// パッケージ: adapter（アダプターパッケージ）
+//
+// 【このファイルの概要】
+// Connect(ctx, config) に渡す設定（map[string]any）の「スキーマ（形の定義）」です。
+// config は自由な形の map なので、キーのタイプミス（"enabled_topic" など）は
+// 黙って無視され、「設定したはずのトピックが出ない」のような分かりにくい動作になります。
+//
+// アダプターが ConfigSchema() でキー・型・必須かどうか・デフォルト値を宣言しておけば、
+// Connect の時点で説明付きのエラーにできます。
+//
+//	schema := adapter.ConfigSchema{
+//	    "host":    {Type: adapter.ConfigString, Required: true},
+//	    "port":    {Type: adapter.ConfigNumber, Default: 9090},
+//	}
+//	config, err := schema.Validate(map[string]any{"hots": "10.0.0.1"})
+//	// err: unknown config key "hots" / missing required config key "host"
+//
+// スキーマを宣言しないアダプターは、従来どおり config をそのまま受け取ります。
+// =============================================================================
+package adapter
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/robot-ai-webapp/gateway/internal/convert"
+)
+
+// ConfigType - 設定値の型
+type ConfigType string
+
+const (
+	// ConfigString: 文字列
+	ConfigString ConfigType = "string"
+	// ConfigNumber: 数値（int / float のほか、環境変数から来た "1.5" のような数値の文字列も可）
+	ConfigNumber ConfigType = "number"
+	// ConfigBool: 真偽値
+	ConfigBool ConfigType = "bool"
+	// ConfigStringList: 文字列の列（[]string、JSON 由来の []any、カンマ区切りの文字列）
+	ConfigStringList ConfigType = "string_list"
+	// ConfigObject: 入れ子の設定（map[string]any）
+	ConfigObject ConfigType = "object"
+)
+
+// ConfigField - 1つの設定キーの定義
+type ConfigField struct {
+	Type        ConfigType
+	Required    bool   // 省略できないか
+	Default     any    // 省略された時に入れる値（nil なら入れない）
+	Description string // エラーメッセージやドキュメント用の説明
+}
+
+// ConfigSchema - 設定キーから定義へのmap
+type ConfigSchema map[string]ConfigField
+
+// ConfigSchemaProvider - 設定のスキーマを宣言するアダプターが実装するインターフェース（任意）
+type ConfigSchemaProvider interface {
+	ConfigSchema() ConfigSchema
+}
+
+// =============================================================================
+// Validate - config をスキーマで検証し、デフォルト値を補った新しい map を返す
+// =============================================================================
+//
+// 未知のキー、必須キーの欠落、型の違いをすべて集めて1つのエラーとして返します
+// （1つ直したら次のエラー、という繰り返しを避けるため）。
+// 値が nil のキーは省略されたものとして扱います。元の config は変更しません。
+func (s ConfigSchema) Validate(config map[string]any) (map[string]any, error) {
+	result := make(map[string]any, len(s))
+	var errs []error
+
+	// 未知のキー（タイプミスの可能性が高い）
+	for _, key := range sortedKeys(config) {
+		if _, ok := s[key]; !ok {
+			errs = append(errs, fmt.Errorf("unknown config key %q (known keys: %s)",
+				key, strings.Join(sortedKeys(s), ", ")))
+		}
+	}
+
+	for _, key := range sortedKeys(s) {
+		field := s[key]
+		value := config[key]
+		if value == nil {
+			if field.Required {
+				errs = append(errs, fmt.Errorf("missing required config key %q%s", key, field.describe()))
+				continue
+			}
+			if field.Default != nil {
+				result[key] = field.Default
+			}
+			continue
+		}
+		if !field.Type.accepts(value) {
+			errs = append(errs, fmt.Errorf("config key %q must be a %s, got %T%s", key, field.Type, value, field.describe()))
+			continue
+		}
+		result[key] = value
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid config: %w", errors.Join(errs...))
+	}
+	return result, nil
+}
+
+// sortedKeys: map のキーを名前順で返す（エラーメッセージの順序を安定させるため）
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// describe: エラーメッセージに付ける説明（" (説明)"、説明がなければ空）
+func (f ConfigField) describe() string {
+	if f.Description == "" {
+		return ""
+	}
+	return " (" + f.Description + ")"
+}
+
+// accepts: value がこの型として受け付けられるか
+func (t ConfigType) accepts(value any) bool {
+	switch t {
+	case ConfigString:
+		_, ok := value.(string)
+		return ok
+	case ConfigNumber:
+		_, ok := convert.ToFloat64(value)
+		return ok
+	case ConfigBool:
+		_, ok := value.(bool)
+		return ok
+	case ConfigStringList:
+		switch v := value.(type) {
+		case string, []string:
+			return true
+		case []any:
+			for _, item := range v {
+				if _, ok := item.(string); !ok {
+					return false
+				}
+			}
+			return true
+		}
+		return false
+	case ConfigObject:
+		_, ok := value.(map[string]any)
+		return ok
+	}
+	return false
+}
+
+// =============================================================================
+// ValidateConfig - アダプターがスキーマを宣言していれば config を検証する
+// =============================================================================
+//
+// スキーマを宣言していない（ConfigSchemaProvider を実装していない）アダプターには、
+// config をそのまま返します。Connect の最初で呼ぶことを想定しています。
+func ValidateConfig(adp RobotAdapter, config map[string]any) (map[string]any, error) {
+	provider, ok := adp.(ConfigSchemaProvider)
+	if !ok {
+		return config, nil
+	}
+	return provider.ConfigSchema().Validate(config)
+}
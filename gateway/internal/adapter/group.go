@@ -0,0 +1,98 @@
+// =============================================================================
+// ファイル: group.go
+// 概要: ロボットのグループ（ゾーン、チームなど）
+//
+// 【なぜ必要か？】
+// 台数が増えると、1台ずつ止めるのでは間に合わない場面が出てきます。
+// ロボットにグループIDを付けておけば、「Aゾーンの全ロボットを止める」のように
+// グループ単位で操作できます（server パッケージの group_command）。
+//
+// 【階層】
+// グループIDは "/" で区切って階層にできます。上位のグループは下位のグループを含みます。
+//
+//	robot-1 → site-1/zone-a
+//	robot-2 → site-1/zone-a
+//	robot-3 → site-1/zone-b
+//
+//	GetAdaptersByGroup("site-1/zone-a") → robot-1, robot-2
+//	GetAdaptersByGroup("site-1")        → robot-1, robot-2, robot-3
+//
+// 所属は起動時の設定（SetRobotGroups）で決まり、アダプターの作成・削除とは独立しています。
+// まだ作成されていないロボットのグループも設定でき、作成された時点からグループに含まれます。
+// =============================================================================
+package adapter
+
+import (
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// SetRobotGroups - ロボットごとの所属グループを設定する（ロボットID → グループIDの一覧）
+//
+// 1台のロボットが複数のグループに属することもできます。
+// アダプターを作成する前（起動時）に一度だけ呼んでください。
+func (r *Registry) SetRobotGroups(groups map[string][]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.groups = make(map[string][]string, len(groups))
+	for robotID, ids := range groups {
+		r.groups[robotID] = append([]string(nil), ids...)
+	}
+	if len(groups) > 0 {
+		r.logger.Info("Robot groups configured", zap.Any("groups", groups))
+	}
+}
+
+// RobotGroups - ロボットが直接属するグループIDの一覧（コピー、ID順）
+func (r *Registry) RobotGroups(robotID string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ids := append([]string(nil), r.groups[robotID]...)
+	sort.Strings(ids)
+	return ids
+}
+
+// HasGroup - groupID が設定上のグループ（またはその上位の階層）として存在するか
+//
+// アダプターが1台も作成されていなくても、設定にあれば true です。
+func (r *Registry) HasGroup(groupID string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, ids := range r.groups {
+		for _, id := range ids {
+			if inGroup(id, groupID) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GetAdaptersByGroup - グループ（下位の階層を含む）に属する、作成済みのアダプター
+//
+// 戻り値は新しい map なので、呼び出し側で自由に変更できます（GetAllActive と同じ）。
+func (r *Registry) GetAdaptersByGroup(groupID string) map[string]RobotAdapter {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make(map[string]RobotAdapter)
+	for robotID, adp := range r.active {
+		for _, id := range r.groups[robotID] {
+			if inGroup(id, groupID) {
+				result[robotID] = adp
+				break
+			}
+		}
+	}
+	return result
+}
+
+// inGroup: member のグループが groupID と同じか、その下位の階層か
+func inGroup(member, groupID string) bool {
+	return member == groupID || strings.HasPrefix(member, groupID+"/")
+}
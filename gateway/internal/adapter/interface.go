@@ -41,8 +41,33 @@ import (
 	// コンテキストを使うことで「5秒以内に応答がなければキャンセル」などの
 	// 制御を各メソッドに統一的に提供できます。
 	"context"
+
+	// errors: エラー値を作成するための標準パッケージ
+	// アダプター共通の「センチネルエラー」（後述の ErrFaultActive）の定義に使います。
+	"errors"
+
+	// time: 最後にロボットから応答があった時刻（LinkStats.LastContact）に使います。
+	"time"
 )
 
+// =============================================================================
+// ErrFaultActive - 故障がまだ解消していないことを示すエラー
+// =============================================================================
+//
+// 【センチネルエラー（sentinel error）とは？】
+// パッケージ変数として公開された「目印」になるエラー値です。
+// 呼び出し側は errors.Is(err, adapter.ErrFaultActive) で種類を判定できます。
+//
+// ClearFault() は故障の原因が残っている場合、このエラーをラップして返します。
+// 例: fmt.Errorf("%w: battery_depleted", adapter.ErrFaultActive)
+var ErrFaultActive = errors.New("robot fault is still active")
+
+// ErrNotSupported - アダプター（ロボット）がその操作に対応していないことを示すエラー
+//
+// 例えば GetDiagnostics() は、診断情報を取得する手段のないロボットではこのエラーを返します。
+// 呼び出し側は errors.Is(err, adapter.ErrNotSupported) で「失敗」と「非対応」を区別できます。
+var ErrNotSupported = errors.New("operation not supported by adapter")
+
 // =============================================================================
 // SensorData - センサーデータを表す構造体
 // =============================================================================
@@ -97,6 +122,55 @@ type SensorData struct {
 	Data map[string]any
 }
 
+// =============================================================================
+// RobotEvent - ロボットが自分から知らせるイベント（バンパー接触、物理 E-Stop など）
+// =============================================================================
+//
+// 【センサーデータとの違い】
+// センサーデータは一定の周期で流れ続ける「測定値」ですが、イベントは
+// 「何かが起きた」ことを一度だけ知らせるものです。間引いたり最新値で置き換えたりせず、
+// 1件ずつオペレーターに届ける必要があるため、SensorDataChannel とは別のチャネルで流します。
+type RobotEvent struct {
+	// RobotID: イベントを起こしたロボットのID（ゲートウェイが転送時に設定します）
+	RobotID string
+
+	// Type: イベントの種類（EventBumperHit など。ロボット固有の種類も使えます）
+	Type string
+
+	// Severity: 重要度（EventSeverityInfo / EventSeverityWarning / EventSeverityCritical）
+	Severity string
+
+	// Message: オペレーター向けの説明（例: "front bumper pressed"）
+	Message string
+
+	// Data: 種類ごとの追加情報（例: バンパーの位置 {"side": "front"}）
+	Data map[string]any
+
+	// Timestamp: イベントが起きた時刻（Unix時間、ナノ秒）
+	Timestamp int64
+}
+
+// イベントの種類（RobotEvent.Type）
+const (
+	// EventBumperHit: バンパーが何かに接触した
+	EventBumperHit = "bumper_hit"
+	// EventPayloadDetected: 荷台に荷物が載った（Data の "present" が false なら降ろされた）
+	EventPayloadDetected = "payload_detected"
+	// EventEStopPressed: ロボット本体の非常停止ボタンが押された
+	// ゲートウェイはこれを受けてソフトウェアの E-Stop もラッチします。
+	EventEStopPressed = "estop_pressed"
+	// EventEStopReleased: 本体の非常停止ボタンが解除された
+	// ソフトウェアの E-Stop は自動では解除しません（オペレーターが確認して解除する）。
+	EventEStopReleased = "estop_released"
+)
+
+// イベントの重要度（RobotEvent.Severity）
+const (
+	EventSeverityInfo     = "info"
+	EventSeverityWarning  = "warning"
+	EventSeverityCritical = "critical"
+)
+
 // =============================================================================
 // Command - ロボットへのコマンドを表す構造体
 // =============================================================================
@@ -114,6 +188,13 @@ type SensorData struct {
 //
 //  3. 緊急停止コマンド:
 //     Type: "estop", Payload: {}
+//
+//  4. ドッキングコマンド（Capabilities.SupportsDocking が true のロボットのみ）:
+//     Type: "dock", Payload: {}    → 充電ドックへ移動して充電を開始
+//     Type: "undock", Payload: {}  → 充電を終了してドックから離脱
+//
+//  5. 姿勢リセットコマンド（Capabilities.SupportsPoseReset が true のロボットのみ）:
+//     Type: "reset_pose", Payload: {"x": 0.0, "y": 0.0, "theta": 0.0, "reset_battery": true}
 type Command struct {
 	// RobotID: コマンド送信先のロボットID
 	RobotID string
@@ -164,6 +245,15 @@ type Capabilities struct {
 	// 一部のセンサー専用デバイスは非対応かもしれません。
 	SupportsEStop bool `json:"supports_estop"`
 
+	// SupportsDocking: 充電ドックへの自動ドッキングに対応しているか
+	// false のロボットに "dock" / "undock" コマンドを送ると、
+	// ハンドラーが能力エラーとして拒否します。
+	SupportsDocking bool `json:"supports_docking"`
+
+	// SupportsPoseReset: "reset_pose" コマンドで自己位置を書き換えられるか
+	// 主にシミュレーター／モック向けの機能で、実機では通常 false です。
+	SupportsPoseReset bool `json:"supports_pose_reset"`
+
 	// SensorTopics: このロボットが提供するセンサートピックのリスト
 	// 例: ["/camera/image", "/lidar/scan", "/odom"]
 	//
@@ -177,6 +267,28 @@ type Capabilities struct {
 	MaxAngularVelocity float64 `json:"max_angular_velocity"`
 }
 
+// =============================================================================
+// LinkStats - ロボットとの通信回線の品質
+// =============================================================================
+//
+// 遠隔操作の画面で「操作が遅れ始めている」ことをオペレーターに示すための値です。
+// 回線の計測値を持たないアダプター（モックなど）は、コマンドやセンサーの
+// タイミングから推定した値を返し、Estimated を true にします。
+type LinkStats struct {
+	// RoundTripMs: 往復の遅延（ミリ秒）
+	RoundTripMs float64 `json:"round_trip_ms"`
+
+	// PacketLoss: 届かなかったメッセージの割合（0.0〜1.0）
+	PacketLoss float64 `json:"packet_loss"`
+
+	// LastContact: 最後にロボットから応答（センサーデータなど）があった時刻
+	// まだ一度も応答がなければゼロ値です。
+	LastContact time.Time `json:"last_contact"`
+
+	// Estimated: 実測ではなく推定値か
+	Estimated bool `json:"estimated"`
+}
+
 // =============================================================================
 // RobotAdapter - ロボットアダプターインターフェース
 // =============================================================================
@@ -274,6 +386,11 @@ type RobotAdapter interface {
 	//	}
 	SensorDataChannel() <-chan SensorData
 
+	// EventChannel: ロボットが自分から知らせるイベント（RobotEvent）を受信するチャネルを返す
+	// センサーデータとは別のチャネルで、バンパー接触や物理 E-Stop などを1件ずつ届けます。
+	// イベントを出さないロボットは、何も送られないチャネルを返します（nil でもかまいません）。
+	EventChannel() <-chan RobotEvent
+
 	// GetCapabilities: ロボットがサポートする機能を返す
 	// UIは、この情報に基づいて表示する機能を切り替えます。
 	// 戻り値:
@@ -288,4 +405,74 @@ type RobotAdapter interface {
 	// 戻り値:
 	// - error: 緊急停止の実行に失敗した場合のエラー
 	EmergencyStop(ctx context.Context) error
+
+	// ClearFault: ロボットの故障（エラー）状態を解除する
+	// ERROR状態から再接続なしで復帰させるために使います。
+	// 故障の原因がまだ残っている場合は解除せず、ErrFaultActive を返します。
+	// 故障していないロボットに対して呼んでも nil を返します（冪等）。
+	// 引数:
+	// - ctx: コンテキスト
+	// 戻り値:
+	// - error: 故障が継続中（ErrFaultActive）またはリセット失敗時のエラー
+	ClearFault(ctx context.Context) error
+
+	// GetDiagnostics: ロボットの診断情報（ハードウェアの状態）を返す
+	// モーター温度、エラーフラグ、ファームウェアのバージョンなど、保守用の画面で使う情報です。
+	// キーと値の形はロボットごとに異なってかまいません。
+	// 引数:
+	// - ctx: コンテキスト（ロボットへの問い合わせのタイムアウト制御用）
+	// 戻り値:
+	// - map[string]any: 診断情報
+	// - error: 取得失敗時のエラー。診断情報に対応していない場合は ErrNotSupported
+	GetDiagnostics(ctx context.Context) (map[string]any, error)
+
+	// GetLinkStats: ロボットとの通信回線の品質（遅延、パケットロス、最終応答時刻）を返す
+	// 回線の計測値を持たないアダプターは、コマンドやセンサーのタイミングから推定します。
+	// 引数:
+	// - ctx: コンテキスト（ロボットへの問い合わせのタイムアウト制御用）
+	// 戻り値:
+	// - LinkStats: 回線の品質
+	// - error: 取得失敗時のエラー。回線の品質に対応していない場合は ErrNotSupported
+	GetLinkStats(ctx context.Context) (LinkStats, error)
+}
+
+// =============================================================================
+// SampleRateController - センサーの送信頻度を変更できるアダプター（任意実装）
+// =============================================================================
+//
+// 【なぜ任意（オプション）なのか？】
+// 送信頻度をロボット側で変えられるかはハードウェア次第です。
+// RobotAdapter に含めると、対応できないアダプターにもダミー実装を強いることになるため、
+// 別のインターフェースに分け、呼び出し側が型アサーションで対応を確認します。
+//
+//	if rc, ok := adp.(adapter.SampleRateController); ok {
+//	    _ = rc.SetSampleRate("scan", 5.0)
+//	}
+//
+// 主な利用者は server.FlowController で、クライアントが全員遅い時に
+// 生成頻度を一時的に下げ、無駄なCPU消費を抑えます。
+// 管理者が set_sensor_rate で頻度を指定する場合にも使います。
+type SampleRateController interface {
+	// NominalSampleRates: 頻度を変更できるトピックと、その標準の送信頻度（Hz）を返す
+	NominalSampleRates() map[string]float64
+
+	// MaxSampleRates: 頻度を変更できるトピックと、センサーが出せる最大の送信頻度（Hz）を返す
+	MaxSampleRates() map[string]float64
+
+	// SetSampleRate: トピックの送信頻度（Hz）を変更する
+	// 未知のトピック、0 以下の頻度、最大の頻度を超える値にはエラーを返します。
+	SetSampleRate(topic string, hz float64) error
+}
+
+// =============================================================================
+// SensorReader - センサーの値をその場で読めるアダプター（任意実装）
+// =============================================================================
+//
+// クライアントの sensor_request に対して、ゲートウェイが最新値を持っていない時に使います
+// （例: 接続直後で、まだバッテリーの定期送信が届いていない）。
+// SampleRateController と同じく、呼び出し側が型アサーションで対応を確認します。
+type SensorReader interface {
+	// ReadSensor: topic の現在の値を1つ作って返す（SensorDataChannel には流さない）
+	// その場で読めないトピックには ErrNotSupported を返します。
+	ReadSensor(ctx context.Context, topic string) (SensorData, error)
 }
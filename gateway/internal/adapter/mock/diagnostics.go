@@ -0,0 +1,62 @@
+// =============================================================================
+// ファイル: diagnostics.go
+// 概要: モックの診断情報（adapter.RobotAdapter.GetDiagnostics の実装）
+//
+// 実機のロボットは、モーター温度・エラーフラグ・ファームウェアのバージョンなどを
+// 診断情報として公開しています。モックでは現在の速度とバッテリー残量から
+// それらしい値を合成し、保守用の画面を実機なしで開発できるようにします。
+// =============================================================================
+package mock
+
+import (
+	"context"
+	"math"
+)
+
+// mockFirmwareVersion: モックが報告するファームウェアのバージョン
+const mockFirmwareVersion = "mock-1.0.0"
+
+// モーター温度のモデル（℃）: 停止中は室温、速度に比例して上がる
+const (
+	motorAmbientTemp   = 25.0 // 停止中の温度
+	motorTempPerSpeed  = 20.0 // 1 m/s あたりの上昇
+	motorTempPerRotate = 5.0  // 1 rad/s あたりの上昇（左右で逆向き）
+)
+
+// =============================================================================
+// GetDiagnostics - 合成した診断情報を返す
+// =============================================================================
+//
+// 【返す値】
+//
+//	firmware_version: ファームウェアのバージョン
+//	motor_temp_left / motor_temp_right: 左右のモーター温度（℃）
+//	battery_voltage: バッテリー電圧（V、残量 0〜100% を 22.0〜25.2V に対応させる）
+//	error_flags: 現在の故障コードの一覧（正常なら空）
+//	docked: 充電ドックに接続中か
+func (m *MockAdapter) GetDiagnostics(ctx context.Context) (map[string]any, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	// 旋回中は外側のモーターの方が多く回るため、左右で温度に差をつける
+	speed := math.Abs(m.linearX) * motorTempPerSpeed
+	turn := m.angularZ * motorTempPerRotate
+
+	errorFlags := []string{}
+	if m.fault != "" {
+		errorFlags = append(errorFlags, m.fault)
+	}
+
+	return map[string]any{
+		"firmware_version": mockFirmwareVersion,
+		"motor_temp_left":  motorAmbientTemp + speed + math.Max(-turn, 0),
+		"motor_temp_right": motorAmbientTemp + speed + math.Max(turn, 0),
+		"battery_voltage":  22.0 + 3.2*m.battery/100.0,
+		"error_flags":      errorFlags,
+		"docked":           m.docked,
+	}, nil
+}
@@ -0,0 +1,76 @@
+// =============================================================================
+// ファイル: events.go
+// 概要: モックのロボットイベント（adapter.RobotAdapter.EventChannel の実装）
+//
+// モックには本物のバンパーや非常停止ボタンがないため、イベントは自分では起きません。
+// EmitEvent で合成のイベントを流し、実機なしで「ロボットから知らせが来た時」の
+// 画面表示や E-Stop の連動を確かめられます。
+//
+//	adp.EmitEvent(adapter.RobotEvent{Type: adapter.EventBumperHit, Data: map[string]any{"side": "front"}})
+//
+// 本体の非常停止（estop_pressed）は、実機と同じようにモック自身もその場で止まります。
+// =============================================================================
+package mock
+
+import (
+	"errors"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"go.uber.org/zap"
+)
+
+// eventBufferSize: 受け取られていないイベントを溜めておける数
+const eventBufferSize = 16
+
+// errEventBufferFull: イベントのバッファが満杯で、イベントを送れなかった
+var errEventBufferFull = errors.New("mock adapter: event buffer full")
+
+// EventChannel - ロボットからのイベントを受信するチャネルを返す
+func (m *MockAdapter) EventChannel() <-chan adapter.RobotEvent {
+	return m.eventCh
+}
+
+// =============================================================================
+// EmitEvent - 合成のイベントを流す
+// =============================================================================
+//
+// Severity を省略すると種類から決め（estop_pressed は critical、bumper_hit は warning、
+// それ以外は info）、Timestamp を省略すると現在時刻を入れます。
+// イベントは捨てずに届けたいので、バッファが満杯ならエラーを返します（呼び出し側で再送できる）。
+func (m *MockAdapter) EmitEvent(ev adapter.RobotEvent) error {
+	if ev.Severity == "" {
+		ev.Severity = defaultEventSeverity(ev.Type)
+	}
+	if ev.Timestamp == 0 {
+		ev.Timestamp = time.Now().UnixNano()
+	}
+
+	// 本体の非常停止ボタンは、ゲートウェイの指示を待たずにモーターを止める
+	if ev.Type == adapter.EventEStopPressed {
+		m.mu.Lock()
+		m.setVelocityNow(adapter.Velocity{})
+		m.docking = false
+		m.mu.Unlock()
+	}
+
+	select {
+	case m.eventCh <- ev:
+		m.markContact()
+		return nil
+	default:
+		m.logger.Warn("Mock event dropped: buffer full", zap.String("type", ev.Type))
+		return errEventBufferFull
+	}
+}
+
+// defaultEventSeverity: Severity を省略したイベントの重要度
+func defaultEventSeverity(eventType string) string {
+	switch eventType {
+	case adapter.EventEStopPressed:
+		return adapter.EventSeverityCritical
+	case adapter.EventBumperHit:
+		return adapter.EventSeverityWarning
+	}
+	return adapter.EventSeverityInfo
+}
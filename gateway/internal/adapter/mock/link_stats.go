@@ -0,0 +1,95 @@
+// =============================================================================
+// ファイル: link_stats.go
+// 概要: モックの回線品質（adapter.RobotAdapter.GetLinkStats の実装）
+//
+// モックには実際の回線がないため、遅延とパケットロスは推定値です。
+//
+//	遅延:         link_rtt_ms（既定 10ms）を基準に、パケットロスが多いほど長くする
+//	              （再送を待つ分だけ往復が遅れる様子の模擬）
+//	パケットロス: link_loss_rate と、ノイズプロファイルの dropout_rate のうち大きい方
+//	              （オドメトリ・IMU の欠損はメッセージ自体が届かないため、回線の損失と同じに見える）
+//	最終応答時刻: 最後にセンサーデータを送った時刻、またはコマンドを受けた時刻
+//
+// link_loss_rate や noise_profile で故障を注入すると、それに合わせて値が悪化するため、
+// 「回線が悪くなった時の画面表示」を実機なしで確かめられます。
+// =============================================================================
+package mock
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/convert"
+)
+
+// defaultLinkRTTMs: link_rtt_ms を省略した時の往復遅延（ミリ秒、有線 LAN 程度）
+const defaultLinkRTTMs = 10.0
+
+// lossLatencyFactor: パケットロス 100% あたりの遅延の増加倍率
+// 例: ロス 10% なら基準の 2 倍（1 + 0.1 × 10）の遅延になります。
+const lossLatencyFactor = 10.0
+
+// linkModel: Connect() の config から決まる回線品質の模擬パラメータ
+type linkModel struct {
+	rttMs    float64 // 基準の往復遅延（ミリ秒）
+	lossRate float64 // 注入するパケットロス率（0.0〜1.0）
+}
+
+// linkModelFromConfig: config の "link_rtt_ms" と "link_loss_rate" を読む
+func linkModelFromConfig(config map[string]any) (linkModel, error) {
+	link := linkModel{rttMs: defaultLinkRTTMs}
+	if v, ok := config["link_rtt_ms"]; ok {
+		link.rttMs, _ = convert.ToFloat64(v)
+		if link.rttMs < 0 {
+			return linkModel{}, fmt.Errorf("link_rtt_ms must not be negative, got %v", link.rttMs)
+		}
+	}
+	if v, ok := config["link_loss_rate"]; ok {
+		link.lossRate, _ = convert.ToFloat64(v)
+		if link.lossRate < 0 || link.lossRate > 1 {
+			return linkModel{}, fmt.Errorf("link_loss_rate must be within [0, 1], got %v", link.lossRate)
+		}
+	}
+	return link, nil
+}
+
+// markContact: 最終応答時刻を現在時刻にする
+func (m *MockAdapter) markContact() {
+	m.lastContact.Store(time.Now().UnixNano())
+}
+
+// =============================================================================
+// GetLinkStats - 推定した回線品質を返す
+// =============================================================================
+//
+// 未接続の場合はエラーを返します（測る相手がいないため）。
+func (m *MockAdapter) GetLinkStats(ctx context.Context) (adapter.LinkStats, error) {
+	if err := ctx.Err(); err != nil {
+		return adapter.LinkStats{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if !m.connected {
+		return adapter.LinkStats{}, fmt.Errorf("mock adapter is not connected")
+	}
+
+	loss := m.link.lossRate
+	if m.noise != nil {
+		loss = math.Max(loss, math.Max(m.noise.Odometry.DropoutRate, m.noise.IMU.DropoutRate))
+	}
+
+	stats := adapter.LinkStats{
+		RoundTripMs: m.link.rttMs * (1 + loss*lossLatencyFactor),
+		PacketLoss:  loss,
+		Estimated:   true,
+	}
+	if nanos := m.lastContact.Load(); nanos != 0 {
+		stats.LastContact = time.Unix(0, nanos)
+	}
+	return stats, nil
+}
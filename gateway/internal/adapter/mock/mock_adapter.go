@@ -9,7 +9,13 @@
 //   - 仮想ロボットへの接続・切断
 //   - 速度コマンドの受信と仮想的な位置更新
 //   - センサーデータ（オドメトリ、LiDAR、IMU、バッテリー）の模擬生成
+//   - 座標変換ツリー（TF）の生成（tf.go 参照）
 //   - 緊急停止（E-Stop）機能
+//   - 充電ドックへのドッキング／離脱の模擬
+//   - バッテリー切れによる故障（fault）と、そのリセットの模擬
+//   - 速度指令のなめらか化（ランプ補間、オプション）
+//   - 起動するセンサー生成器の選択（enabled_topics、オプション）
+//   - 姿勢（位置・向き）のリセット（テストシナリオの再現用）
 //
 // デザインパターン:
 //   - アダプターパターン: adapter.RobotAdapter インターフェースを実装し、
@@ -38,6 +44,9 @@ import (
 	// 例えば「接続を切ったらセンサー生成も止める」という制御に使います。
 	"context"
 
+	// "fmt": 書式付き文字列の作成。故障中エラーに故障コードを含めるために使います。
+	"fmt"
+
 	// "math": 数学関数（Sin, Cos, Piなど）を提供するパッケージ。
 	// ロボットの位置計算や角度計算に必要です。
 	"math"
@@ -46,11 +55,21 @@ import (
 	// センサーデータにノイズ（雑音）を加えて、よりリアルなシミュレーションにします。
 	"math/rand"
 
+	// "slices": スライス操作の汎用関数（Go 1.21以降）。
+	// 有効化するセンサートピック名の検証（slices.Contains）に使います。
+	"slices"
+
+	// "strings": 文字列操作。カンマ区切りのトピック指定の分割に使います。
+	"strings"
+
 	// "sync": 同期処理のためのパッケージ。
 	// sync.RWMutex（読み書きロック）を使って、複数のゴルーチンが同時に
 	// データにアクセスしても安全にするために使います。
 	"sync"
 
+	// "sync/atomic": ロックを取らずに読み書きできる値。最終応答時刻（lastContact）に使います。
+	"sync/atomic"
+
 	// "time": 時間関連の機能を提供するパッケージ。
 	// タイマー（Ticker）やタイムスタンプの取得に使います。
 	"time"
@@ -62,6 +81,12 @@ import (
 	// 型がここで定義されています。
 	"github.com/robot-ai-webapp/gateway/internal/adapter"
 
+	// convert: Payload や config の値（any 型）を数値に変換するヘルパー。
+	"github.com/robot-ai-webapp/gateway/internal/convert"
+
+	// recovery: センサー生成器の panic を回復し、プロセスを落とさないために使います。
+	"github.com/robot-ai-webapp/gateway/internal/recovery"
+
 	// --- 外部ライブラリ ---
 
 	// zap: Uber社が開発した高性能なログ出力ライブラリ。
@@ -142,6 +167,9 @@ type MockAdapter struct {
 	// これにより、外部からはデータの読み取りのみ可能になります。
 	dataCh chan adapter.SensorData
 
+	// eventCh: ロボットからのイベント（バンパー接触など）を送信するためのチャネル（events.go 参照）
+	eventCh chan adapter.RobotEvent
+
 	// cancel: context.CancelFunc - ゴルーチンを停止するための関数
 	// 【CancelFuncとは？】
 	// context.WithCancel() で生成されるキャンセル関数です。
@@ -178,8 +206,75 @@ type MockAdapter struct {
 
 	// battery: バッテリー残量（0.0〜100.0のパーセンテージ）
 	battery float64
+
+	// --- ドッキング状態 ---
+
+	// docking: 充電ドックへ向かって自律移動中かどうか
+	// "dock" コマンドで true になり、ドックに到着すると false に戻ります。
+	docking bool
+
+	// docked: 充電ドックに接続済み（＝充電中）かどうか
+	// true の間はバッテリーが減る代わりに回復していきます。
+	docked bool
+
+	// --- 故障状態 ---
+
+	// fault: 現在の故障コード（空文字列なら正常）
+	// バッテリーが0%になると "battery_depleted" が設定され、
+	// 速度コマンドを受け付けなくなります。ClearFault() で解除します。
+	fault string
+
+	// --- センサーノイズ ---
+
+	// noise: Connect() で選択されたノイズプロファイル（noise_profile.go 参照）
+	// nil の場合は従来どおりの一様ノイズを使います。
+	noise *NoiseProfile
+
+	// noiseSeed: センサー生成器ごとの乱数シードの基準値
+	noiseSeed int64
+
+	// ramp: 速度指令のなめらか化（adapter.VelocityRamp）
+	// Connect() の config で "velocity_ramp_ms" が正の値のときだけ作られます。
+	// nil の場合は従来どおり、速度指令が即座に反映されます。
+	ramp *adapter.VelocityRamp
+
+	// drift: オドメトリの累積誤差モデル（odometry_drift.go 参照）
+	// Connect() の config で "odom_drift_*" のいずれかが 0 以外のときだけ作られます。
+	// nil の場合、オドメトリは真の位置をそのまま報告します。
+	drift *odometryDrift
+
+	// intervals: SetSampleRate() で変更されたトピックごとの送信周期（sample_rate.go 参照）
+	// 登録のないトピックは標準の周期で送信します。
+	intervals map[string]time.Duration
+
+	// topics: 現在有効なセンサートピック（Connect() の "enabled_topics" で選択）
+	// GetCapabilities().SensorTopics はこの一覧を返します。
+	topics []string
+	// link: 回線品質の模擬（link_stats.go 参照）。Connect() の "link_*" で設定します。
+	link linkModel
+
+	// lastContact: 最後にセンサーデータを送った・コマンドを受けた時刻（UnixNano）
+	// センサー生成器はロックの外で送信するため、m.mu ではなく atomic で更新します。
+	lastContact atomic.Int64
 }
 
+// allSensorTopics: モックが生成できる全センサートピック（既定ではすべて有効）
+var allSensorTopics = []string{"odom", "scan", "imu", "battery", "tf"}
+
+// =============================================================================
+// ドッキング関連の定数
+// =============================================================================
+//
+// 模擬ロボットの充電ドックは原点(0, 0)に置かれている想定です。
+const (
+	dockX            = 0.0  // ドックのX座標（m）
+	dockY            = 0.0  // ドックのY座標（m）
+	dockTolerance    = 0.05 // この距離（m）以内に入ったらドッキング完了とみなす
+	dockApproachVel  = 0.3  // ドックへ向かう時の最大前進速度（m/s）
+	dockChargeRate   = 0.5  // 充電中に1回（5秒）で回復するバッテリー量（%）
+	dockUndockOffset = 0.3  // 離脱時にドックから後退する距離（m）
+)
+
 // =============================================================================
 // NewMockAdapter - コンストラクタ関数
 // =============================================================================
@@ -198,6 +293,9 @@ func NewMockAdapter(logger *zap.Logger) *MockAdapter {
 		// 100個分のセンサーデータを一時的に蓄えられます
 		dataCh: make(chan adapter.SensorData, 100),
 
+		// イベントはセンサーデータよりずっと少ないので、小さめのバッファで十分です
+		eventCh: make(chan adapter.RobotEvent, eventBufferSize),
+
 		// ロガーを保存
 		logger: logger,
 
@@ -220,6 +318,23 @@ func NewMockAdapter(logger *zap.Logger) *MockAdapter {
 // Goでは短いメソッドを1行で書くことも一般的です。
 func (m *MockAdapter) Name() string { return "mock" }
 
+// ConfigSchema - Connect に渡せる設定キーの定義（adapter.ConfigSchemaProvider の実装）
+//
+// どのキーも省略でき、省略時は従来どおりの動作（一様ノイズ、ランプなし、全トピック、ドリフトなし）になります。
+func (m *MockAdapter) ConfigSchema() adapter.ConfigSchema {
+	return adapter.ConfigSchema{
+		"noise_profile_file":    {Type: adapter.ConfigString, Description: "path to a noise profile file"},
+		"noise_profile":         {Type: adapter.ConfigString, Description: "profile name within noise_profile_file"},
+		"velocity_ramp_ms":      {Type: adapter.ConfigNumber, Default: 0, Description: "time to ramp to a new velocity in ms"},
+		"enabled_topics":        {Type: adapter.ConfigStringList, Description: "sensor topics to generate, e.g. \"odom,scan\""},
+		"odom_drift_per_meter":  {Type: adapter.ConfigNumber, Default: 0.0, Description: "odometry drift per meter traveled"},
+		"odom_drift_per_radian": {Type: adapter.ConfigNumber, Default: 0.0, Description: "odometry drift per radian turned"},
+		"odom_drift_noise":      {Type: adapter.ConfigNumber, Default: 0.0, Description: "random odometry drift noise"},
+		"link_rtt_ms":           {Type: adapter.ConfigNumber, Default: defaultLinkRTTMs, Description: "simulated round-trip latency in ms"},
+		"link_loss_rate":        {Type: adapter.ConfigNumber, Default: 0.0, Description: "simulated packet loss rate (0.0-1.0)"},
+	}
+}
+
 // =============================================================================
 // Connect - ロボットへの接続を開始するメソッド
 // =============================================================================
@@ -258,6 +373,56 @@ func (m *MockAdapter) Connect(ctx context.Context, config map[string]any) error
 		return nil
 	}
 
+	// 設定の検証（キーのタイプミスや型の間違いを、黙って無視せずエラーにする）
+	config, err := adapter.ValidateConfig(m, config)
+	if err != nil {
+		return fmt.Errorf("mock adapter: %w", err)
+	}
+
+	// ノイズプロファイルの読み込み（config で指定された場合のみ）
+	// 不正なファイルなら接続自体を失敗させ、誤ったデータの生成を防ぎます。
+	noise, err := noiseProfileFromConfig(config)
+	if err != nil {
+		return fmt.Errorf("mock adapter: %w", err)
+	}
+	m.noise = noise
+
+	// 起動するセンサー生成器の選択（省略時は全トピック）
+	topics, err := enabledTopicsFromConfig(config)
+	if err != nil {
+		return fmt.Errorf("mock adapter: %w", err)
+	}
+	m.topics = topics
+	m.intervals = nil // 送信頻度は標準に戻す
+
+	// 速度のランプ補間（遅延のある回線での遠隔操作を模擬する時に使う）
+	m.ramp = nil
+	if rampMs, _ := convert.ToFloat64(config["velocity_ramp_ms"]); rampMs > 0 {
+		m.ramp = adapter.NewVelocityRamp(time.Duration(rampMs * float64(time.Millisecond)))
+	}
+
+	// オドメトリのドリフト（推定位置が真の位置から少しずつずれていく現象の模擬）
+	drift, err := odometryDriftFromConfig(config)
+	if err != nil {
+		return fmt.Errorf("mock adapter: %w", err)
+	}
+	if drift != nil {
+		drift.reset(m.posX, m.posY, m.theta)
+	}
+	m.drift = drift
+
+	// 回線品質の模擬（GetLinkStats が報告する遅延とパケットロス）
+	link, err := linkModelFromConfig(config)
+	if err != nil {
+		return fmt.Errorf("mock adapter: %w", err)
+	}
+	m.link = link
+
+	m.noiseSeed = time.Now().UnixNano()
+	if noise != nil && noise.Seed != 0 {
+		m.noiseSeed = noise.Seed
+	}
+
 	// 【context.WithCancel の仕組み】
 	// 親context（ctx）から新しい子context（sensorCtx）を作成します。
 	// cancel 関数を呼ぶと、sensorCtx.Done() チャネルが閉じられます。
@@ -268,6 +433,7 @@ func (m *MockAdapter) Connect(ctx context.Context, config map[string]any) error
 	sensorCtx, cancel := context.WithCancel(ctx)
 	m.cancel = cancel
 	m.connected = true
+	m.markContact()
 
 	// 【ゴルーチン（goroutine）とは？】
 	// go キーワードを付けて関数を呼ぶと、その関数が「別のスレッド（軽量スレッド）」で
@@ -279,14 +445,42 @@ func (m *MockAdapter) Connect(ctx context.Context, config map[string]any) error
 	// - IMU（慣性計測装置）: 50Hz
 	// - バッテリー: 0.2Hz
 
+	// ノイズを使う生成器には、それぞれ専用の乱数生成器を渡します。
+	// シードを「基準値 + 生成器ごとの番号」にすることで、
+	// 同じシードなら毎回同じノイズ列が再現されます。
+	//
+	// enabled_topics で選ばれたトピックの生成器だけを起動します。
+	// 注意: battery を無効にすると、バッテリー残量の減少・充電・故障判定も止まります。
+	// 生成器が panic しても、そのトピックが止まるだけでプロセスは落ちません（recovery.Go）。
 	// Start sensor data generators
-	go m.generateOdometry(sensorCtx)
-	go m.generateLiDAR(sensorCtx)
-	go m.generateIMU(sensorCtx)
-	go m.generateBattery(sensorCtx)
+	for _, topic := range topics {
+		switch topic {
+		case "odom":
+			recovery.Go(m.logger, "mock_odom_generator", func() { m.generateOdometry(sensorCtx, m.newRand(1)) })
+		case "scan":
+			recovery.Go(m.logger, "mock_scan_generator", func() { m.generateLiDAR(sensorCtx, m.newRand(2)) })
+		case "imu":
+			recovery.Go(m.logger, "mock_imu_generator", func() { m.generateIMU(sensorCtx, m.newRand(3)) })
+		case "battery":
+			recovery.Go(m.logger, "mock_battery_generator", func() { m.generateBattery(sensorCtx) })
+		case "tf":
+			// 静的な変換は接続時に一度だけ送る（動的な変換は generateOdometry が送る）
+			select {
+			case m.dataCh <- tfStaticSample():
+			default:
+			}
+		}
+	}
 
 	// 接続成功のログを出力
-	m.logger.Info("Mock adapter connected")
+	if noise != nil {
+		m.logger.Info("Mock adapter connected",
+			zap.String("noise_profile_file", config["noise_profile_file"].(string)),
+			zap.Int64("noise_seed", m.noiseSeed),
+		)
+	} else {
+		m.logger.Info("Mock adapter connected")
+	}
 	return nil
 }
 
@@ -337,14 +531,100 @@ func (m *MockAdapter) IsConnected() bool {
 func (m *MockAdapter) SendCommand(ctx context.Context, cmd adapter.Command) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	m.markContact()
+
+	switch cmd.Type {
+	case "velocity":
+		// 故障中は手動での移動を受け付けません（ClearFault() で解除が必要）
+		if m.fault != "" {
+			return fmt.Errorf("%w: %s", adapter.ErrFaultActive, m.fault)
+		}
 
-	// コマンドタイプが "velocity"（速度指令）の場合
-	if cmd.Type == "velocity" {
-		// Payload からそれぞれの速度成分を取得
-		// toFloat64() はany型をfloat64に安全に変換するヘルパー関数（後述）
-		m.linearX = toFloat64(cmd.Payload["linear_x"])
-		m.linearY = toFloat64(cmd.Payload["linear_y"])
-		m.angularZ = toFloat64(cmd.Payload["angular_z"])
+		// コマンドタイプが "velocity"（速度指令）の場合
+		// Payload からそれぞれの速度成分を取得（変換できない成分は 0）
+		// convert.ToFloat64() はany型をfloat64に安全に変換するヘルパー関数
+		var target adapter.Velocity
+		target.LinearX, _ = convert.ToFloat64(cmd.Payload["linear_x"])
+		target.LinearY, _ = convert.ToFloat64(cmd.Payload["linear_y"])
+		target.AngularZ, _ = convert.ToFloat64(cmd.Payload["angular_z"])
+		if m.ramp != nil {
+			// ランプ有効時は目標だけ設定し、実際の速度は generateOdometry() が
+			// 制御周期ごとに少しずつ目標へ近づけます。
+			m.ramp.SetTarget(target)
+		} else {
+			m.linearX, m.linearY, m.angularZ = target.LinearX, target.LinearY, target.AngularZ
+		}
+
+		// 手動の速度指令が来たら、ドックへの自律移動は中断します。
+		// 充電中（docked）の場合も、動き出したらドックから外れたとみなします。
+		if target != (adapter.Velocity{}) {
+			m.docking = false
+			m.docked = false
+		}
+
+	case "dock":
+		// ドッキング開始: 実際の移動は generateOdometry() がドックに向けて行います。
+		// 既にドッキング済みなら何もしません（冪等）。
+		if !m.docked {
+			m.docking = true
+			m.logger.Info("Mock adapter docking started")
+		}
+
+	case "undock":
+		// 離脱: 充電を終了し、ドックから少し後退した位置に移動したことにします。
+		if m.docked {
+			m.docked = false
+			dx := dockX - dockUndockOffset*math.Cos(m.theta) - m.posX
+			dy := dockY - dockUndockOffset*math.Sin(m.theta) - m.posY
+			m.posX += dx
+			m.posY += dy
+			// 位置の書き換えなので、推定姿勢も誤差なしで同じだけ（ロボットから見た向きで）動かす
+			if m.drift != nil {
+				cos, sin := math.Cos(m.theta), math.Sin(m.theta)
+				m.drift.overwrite(dx*cos+dy*sin, -dx*sin+dy*cos, 0)
+			}
+			m.logger.Info("Mock adapter undocked")
+		}
+		m.docking = false
+
+	case "nav_cancel":
+		// ナビゲーションの中止: モックの自律移動はドックへの移動だけなので、それを中断します。
+		if m.docking {
+			m.docking = false
+			m.logger.Info("Mock adapter navigation cancelled")
+		}
+
+	case "reset_pose":
+		// 姿勢のリセット: 再接続せずに、決まった位置からテストをやり直すためのコマンド。
+		// Payload に x, y, theta が無ければ 0（原点・東向き）になります。
+		m.posX, _ = convert.ToFloat64(cmd.Payload["x"])
+		m.posY, _ = convert.ToFloat64(cmd.Payload["y"])
+		m.theta, _ = convert.ToFloat64(cmd.Payload["theta"])
+		// 蓄積したドリフトも捨て、推定位置を新しい姿勢に揃える
+		if m.drift != nil {
+			m.drift.reset(m.posX, m.posY, m.theta)
+		}
+		m.setVelocityNow(adapter.Velocity{})
+		m.docking = false
+		m.docked = false
+
+		// reset_battery が true なら満充電に戻す（バッテリー切れの原因も解消される）
+		if reset, _ := cmd.Payload["reset_battery"].(bool); reset {
+			m.battery = 100.0
+		}
+
+		m.logger.Info("Mock adapter pose reset",
+			zap.Float64("x", m.posX),
+			zap.Float64("y", m.posY),
+			zap.Float64("theta", m.theta),
+		)
+
+		// 次の定期送信（最大50ms後）を待たず、新しい姿勢をすぐに配信します。
+		// ノイズは加えず、リセットした値そのものを送ります。
+		select {
+		case m.dataCh <- m.odometrySample(m.posX, m.posY, m.theta):
+		default:
+		}
 	}
 
 	return nil
@@ -380,11 +660,21 @@ func (m *MockAdapter) SensorDataChannel() <-chan adapter.SensorData {
 // 【構造体リテラル】
 // adapter.Capabilities{...} のように、フィールド名: 値 の形式で初期化します。
 func (m *MockAdapter) GetCapabilities() adapter.Capabilities {
+	// 実際に有効なトピックだけを返す（未接続なら全トピック）
+	m.mu.RLock()
+	topics := m.topics
+	m.mu.RUnlock()
+	if topics == nil {
+		topics = allSensorTopics
+	}
+
 	return adapter.Capabilities{
 		SupportsVelocityControl: true,
 		SupportsNavigation:      true,
 		SupportsEStop:           true,
-		SensorTopics:            []string{"odom", "scan", "imu", "battery"},
+		SupportsDocking:         true,
+		SupportsPoseReset:       true,
+		SensorTopics:            append([]string(nil), topics...), // 呼び出し側が書き換えても影響しないようコピー
 		MaxLinearVelocity:       1.0,
 		MaxAngularVelocity:      2.0,
 	}
@@ -405,13 +695,134 @@ func (m *MockAdapter) GetCapabilities() adapter.Capabilities {
 func (m *MockAdapter) EmergencyStop(ctx context.Context) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.linearX = 0
-	m.linearY = 0
-	m.angularZ = 0
+	// 緊急停止はランプ補間せず、即座に0にします
+	m.setVelocityNow(adapter.Velocity{})
+	// ドックへの自律移動も緊急停止の対象です
+	m.docking = false
 	m.logger.Warn("EMERGENCY STOP triggered on mock adapter")
 	return nil
 }
 
+// =============================================================================
+// enabledTopicsFromConfig - config の "enabled_topics" を解釈する
+// =============================================================================
+//
+// 受け付ける形式:
+//   - []string{"odom", "battery"}     … Go のコードから渡す場合
+//   - []any{"odom", "battery"}        … JSON をデコードした設定から渡す場合
+//   - "odom,battery"                  … 環境変数などの文字列から渡す場合
+//
+// 省略・空の場合は後方互換のため全トピックを返します。
+// 未知のトピック名はタイプミスの可能性が高いのでエラーにします。
+func enabledTopicsFromConfig(config map[string]any) ([]string, error) {
+	var requested []string
+	switch v := config["enabled_topics"].(type) {
+	case []string:
+		requested = v
+	case []any:
+		for _, item := range v {
+			name, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("enabled_topics must contain strings, got %T", item)
+			}
+			requested = append(requested, name)
+		}
+	case string:
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				requested = append(requested, name)
+			}
+		}
+	case nil:
+	default:
+		return nil, fmt.Errorf("enabled_topics has unsupported type %T", v)
+	}
+
+	if len(requested) == 0 {
+		return allSensorTopics, nil
+	}
+
+	// 重複を除きつつ、allSensorTopics の順序に揃える
+	want := make(map[string]bool, len(requested))
+	for _, name := range requested {
+		if !slices.Contains(allSensorTopics, name) {
+			return nil, fmt.Errorf("unknown sensor topic %q (available: %s)", name, strings.Join(allSensorTopics, ", "))
+		}
+		want[name] = true
+	}
+	// 動的な TF はオドメトリの生成器が送るため、odom なしでは届かない
+	if want["tf"] && !want["odom"] {
+		return nil, fmt.Errorf("sensor topic \"tf\" requires \"odom\"")
+	}
+	topics := make([]string, 0, len(want))
+	for _, name := range allSensorTopics {
+		if want[name] {
+			topics = append(topics, name)
+		}
+	}
+	return topics, nil
+}
+
+// =============================================================================
+// newRand - センサー生成器専用の乱数生成器を作る
+// =============================================================================
+//
+// Connect() の中（m.mu を保持した状態）で呼ばれます。
+// offset は生成器ごとに異なる番号で、同じシードでも生成器間で
+// 同じ乱数列にならないようにするためのものです。
+func (m *MockAdapter) newRand(offset int64) *rand.Rand {
+	return rand.New(rand.NewSource(m.noiseSeed + offset))
+}
+
+// =============================================================================
+// ClearFault - 故障状態を解除するメソッド
+// =============================================================================
+//
+// 【解除できる条件】
+// 故障の原因が取り除かれている必要があります。
+// このモックではバッテリー切れが唯一の故障なので、
+// 充電されて残量が0%より大きくなっていれば解除できます。
+// 原因が残ったままリセットすると、すぐにまた故障してしまうためです。
+func (m *MockAdapter) ClearFault(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.fault == "" {
+		return nil
+	}
+	if m.fault == "battery_depleted" && m.battery <= 0 {
+		return fmt.Errorf("%w: %s", adapter.ErrFaultActive, m.fault)
+	}
+
+	m.logger.Info("Mock adapter fault cleared", zap.String("fault", m.fault))
+	m.fault = ""
+	return nil
+}
+
+// =============================================================================
+// DrainBattery - バッテリーを 0% にして、バッテリー切れの故障を起こす
+// =============================================================================
+//
+// 通常のバッテリーは 5 秒ごとに 0.01% しか減らないため、
+// 故障とリセット（reset_error）の流れをテストやデモですぐに確かめられるようにします。
+// 解除するには、reset_pose の reset_battery かドックでの充電で残量を戻してから ClearFault() を呼びます。
+func (m *MockAdapter) DrainBattery() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.depleteBattery()
+}
+
+// depleteBattery: 残量を 0% にし、バッテリー切れを故障として扱ってロボットを停止させる
+// m.mu のロックを保持した状態で呼んでください。
+func (m *MockAdapter) depleteBattery() {
+	m.battery = 0 // 0%以下にはならない
+	if m.fault == "" {
+		m.fault = "battery_depleted"
+		m.setVelocityNow(adapter.Velocity{})
+		m.logger.Warn("Mock adapter fault: battery depleted")
+	}
+}
+
 // =============================================================================
 //
 //	センサーデータ生成器（ゴルーチンで実行される関数群）
@@ -429,6 +840,7 @@ func (m *MockAdapter) EmergencyStop(ctx context.Context) error {
 // 【更新頻度: 20Hz】
 // 50ミリ秒ごと（1秒に20回）にデータを更新します。
 // ロボット工学では、制御ループの周波数が高いほど精密な制御が可能です。
+// SetSampleRate() で頻度が下がった場合は、その分 dt を大きくして積算します。
 //
 // 【位置計算の数学】
 // dt = 0.05秒（50ミリ秒）
@@ -438,11 +850,13 @@ func (m *MockAdapter) EmergencyStop(ctx context.Context) error {
 //
 // これは「デッドレコニング（推測航法）」と呼ばれる手法で、
 // 速度から位置を積分（近似的に積算）しています。
-func (m *MockAdapter) generateOdometry(ctx context.Context) {
+func (m *MockAdapter) generateOdometry(ctx context.Context, rng *rand.Rand) {
 	// 【time.NewTicker】
 	// 指定した間隔で定期的にチャネルに値を送信するタイマーです。
 	// 50ミリ秒 = 0.05秒ごと → 20Hz
-	ticker := time.NewTicker(50 * time.Millisecond) // 20Hz
+	// 周期は SetSampleRate() で変更されることがあるため、変数で持っておきます。
+	period := 50 * time.Millisecond
+	ticker := time.NewTicker(period) // 20Hz
 	// deferでTickerを停止（メモリリーク防止）
 	defer ticker.Stop()
 
@@ -462,12 +876,36 @@ func (m *MockAdapter) generateOdometry(ctx context.Context) {
 			// これにより、ゴルーチンを安全に終了できます。
 			return
 		case <-ticker.C:
-			// Tickerから50ミリ秒ごとに通知が来る
+			// Tickerから50ミリ秒ごと（標準の場合）に通知が来る
 
 			// 書き込みロックを取得（位置と速度の更新のため）
 			m.mu.Lock()
 
-			dt := 0.05 // 50ms = 0.05秒
+			// dt: 前回からの経過時間（標準は 50ms = 0.05秒）
+			dt := period.Seconds()
+			step := period
+
+			// 送信頻度が変更されていれば、次の周期から反映する
+			if next := m.sampleIntervalLocked("odom"); next != period {
+				period = next
+				ticker.Reset(period)
+			}
+
+			// ドッキング中はドックに向かう速度を自動で決める
+			// それ以外でランプ補間が有効なら、目標速度へ少しずつ近づける
+			if m.docking {
+				// approachDock() は向きを直接書き換える（車輪で回ったのではない）ため、
+				// 書き換えた分はドリフトを加えずに推定姿勢へ反映する。
+				// 差分は -π〜π に折り返す（-π 付近から π 付近への書き換えを、ほぼ 2π の回転と誤解しないように）
+				prevTheta := m.theta
+				m.approachDock()
+				if m.drift != nil {
+					m.drift.overwrite(0, 0, math.Remainder(m.theta-prevTheta, 2*math.Pi))
+				}
+			} else if m.ramp != nil {
+				v := m.ramp.Step(step)
+				m.linearX, m.linearY, m.angularZ = v.LinearX, v.LinearY, v.AngularZ
+			}
 
 			// 【位置の更新計算】
 			// 1. 向き（theta）を更新: 回転速度 × 時間
@@ -481,20 +919,33 @@ func (m *MockAdapter) generateOdometry(ctx context.Context) {
 			//    sin(theta) は、向きのY成分（南北方向）を計算します
 			m.posY += m.linearX * math.Sin(m.theta) * dt
 
-			// 送信するセンサーデータを構造体リテラルで作成
-			data := adapter.SensorData{
-				Topic:     "odom",                 // トピック名（購読者がフィルタに使う）
-				DataType:  "odometry",             // データの種類
-				FrameID:   "odom",                 // 座標系の基準フレーム
-				Timestamp: time.Now().UnixMilli(), // 現在時刻のミリ秒タイムスタンプ
-				Data: map[string]any{
-					"position_x":    m.posX,     // X座標（m）
-					"position_y":    m.posY,     // Y座標（m）
-					"orientation_z": m.theta,    // 向き（rad）
-					"velocity_x":    m.linearX,  // 前進速度（m/s）
-					"velocity_y":    m.linearY,  // 横方向速度（m/s）
-					"angular_z":     m.angularZ, // 回転速度（rad/s）
-				},
+			// ドリフトが有効なら、同じ移動量から誤差を含む推定位置も更新し、
+			// 報告する位置を推定位置にします（実機のオドメトリと同じ振る舞い）。
+			reportX, reportY, reportTheta := m.posX, m.posY, m.theta
+			if m.drift != nil {
+				m.drift.step(rng, m.linearX*dt, m.angularZ*dt)
+				reportX, reportY, reportTheta = m.drift.estX, m.drift.estY, m.drift.estTheta
+			}
+
+			// ノイズプロファイルがあれば、報告する位置にだけノイズを加えます。
+			// 内部の真の位置（m.posX, m.posY）は変えません。
+			if m.noise != nil {
+				if m.noise.Odometry.dropped(rng) {
+					m.mu.Unlock()
+					continue
+				}
+				reportX = m.noise.Odometry.perturb(rng, reportX)
+				reportY = m.noise.Odometry.perturb(rng, reportY)
+			}
+
+			// 送信するセンサーデータを作成（内容は odometrySample() を参照）
+			data := m.odometrySample(reportX, reportY, reportTheta)
+
+			// TF が有効なら、同じ姿勢で odom → base_link の変換も送る（tf.go 参照）
+			var tf *adapter.SensorData
+			if slices.Contains(m.topics, "tf") {
+				sample := tfSample(reportX, reportY, reportTheta)
+				tf = &sample
 			}
 
 			// ロックを解放（データ作成が完了したので）
@@ -506,12 +957,19 @@ func (m *MockAdapter) generateOdometry(ctx context.Context) {
 			select {
 			case m.dataCh <- data:
 				// チャネルに正常に送信できた
+				m.markContact()
 			default: // drop if channel is full
 				// 【default節】
 				// チャネルのバッファが満杯の場合、ブロックせずにここに来ます。
 				// センサーデータは連続的に生成されるので、古いデータを捨てても問題ありません。
 				// これは「非ブロッキング送信」のパターンです。
 			}
+			if tf != nil {
+				select {
+				case m.dataCh <- *tf:
+				default:
+				}
+			}
 		}
 	}
 }
@@ -532,8 +990,9 @@ func (m *MockAdapter) generateOdometry(ctx context.Context) {
 // 【更新頻度: 10Hz】
 // 100ミリ秒ごと（1秒に10回）にデータを生成します。
 // 実際のLiDARも5~40Hzで動作することが多いです。
-func (m *MockAdapter) generateLiDAR(ctx context.Context) {
-	ticker := time.NewTicker(100 * time.Millisecond) // 10Hz
+func (m *MockAdapter) generateLiDAR(ctx context.Context, rng *rand.Rand) {
+	period := 100 * time.Millisecond
+	ticker := time.NewTicker(period) // 10Hz
 	defer ticker.Stop()
 
 	for {
@@ -541,6 +1000,18 @@ func (m *MockAdapter) generateLiDAR(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
+			// 選択中のノイズプロファイルを読み取りロックで取得
+			m.mu.RLock()
+			noise := m.noise
+			next := m.sampleIntervalLocked("scan")
+			m.mu.RUnlock()
+
+			// 送信頻度が変更されていれば、次の周期から反映する
+			if next != period {
+				period = next
+				ticker.Reset(period)
+			}
+
 			// 360個の距離データを格納するスライスを作成
 			// 【スライスとは？】
 			// Goの可変長配列です。make([]float64, 360) で360個のfloat64を確保します。
@@ -561,9 +1032,20 @@ func (m *MockAdapter) generateLiDAR(ctx context.Context) {
 				// Simulate a room
 				baseRange := 3.0 + math.Sin(angle*2.0)*1.0
 
+				// ノイズプロファイルがあれば、ガウスノイズ・偏り・欠損を適用
+				// 欠損したビームは range_min 未満の 0 にして「無効値」を表します。
+				if noise != nil {
+					if noise.LiDAR.dropped(rng) {
+						ranges[i] = 0
+						continue
+					}
+					ranges[i] = noise.LiDAR.perturb(rng, baseRange)
+					continue
+				}
+
 				// ランダムノイズ（0〜0.1m）を追加
 				// 実際のセンサーにもノイズ（測定誤差）があります
-				ranges[i] = baseRange + rand.Float64()*0.1 // add noise
+				ranges[i] = baseRange + rng.Float64()*0.1 // add noise
 			}
 
 			// LiDARデータの構造体を作成
@@ -585,6 +1067,7 @@ func (m *MockAdapter) generateLiDAR(ctx context.Context) {
 			// チャネルへの非ブロッキング送信
 			select {
 			case m.dataCh <- data:
+				m.markContact()
 			default:
 			}
 		}
@@ -608,8 +1091,9 @@ func (m *MockAdapter) generateLiDAR(ctx context.Context) {
 // 【更新頻度: 50Hz】
 // 20ミリ秒ごと（1秒に50回）にデータを生成します。
 // IMUは高速なセンサーで、実際には100Hz〜1000Hzで動作することもあります。
-func (m *MockAdapter) generateIMU(ctx context.Context) {
-	ticker := time.NewTicker(20 * time.Millisecond) // 50Hz
+func (m *MockAdapter) generateIMU(ctx context.Context, rng *rand.Rand) {
+	period := 20 * time.Millisecond
+	ticker := time.NewTicker(period) // 50Hz
 	defer ticker.Stop()
 
 	for {
@@ -621,8 +1105,32 @@ func (m *MockAdapter) generateIMU(ctx context.Context) {
 			// ここでは読み取りだけなので RLock を使用（他の読み取りをブロックしない）
 			m.mu.RLock()
 			theta := m.theta
+			noise := m.noise
+			next := m.sampleIntervalLocked("imu")
 			m.mu.RUnlock()
 
+			// 送信頻度が変更されていれば、次の周期から反映する
+			if next != period {
+				period = next
+				ticker.Reset(period)
+			}
+
+			// 加速度センサー: 各軸の加速度（m/s²）
+			// x, y: ランダムノイズ（-0.05〜+0.05）で微小な振動を模擬
+			// z: 重力加速度（9.81 m/s²）+ ノイズ（-0.01〜+0.01）
+			accX := rng.Float64()*0.1 - 0.05
+			accY := rng.Float64()*0.1 - 0.05
+			accZ := 9.81 + rng.Float64()*0.02 - 0.01
+			if noise != nil {
+				// プロファイル指定時は一様ノイズの代わりにプロファイルを使う
+				if noise.IMU.dropped(rng) {
+					continue
+				}
+				accX = noise.IMU.perturb(rng, 0)
+				accY = noise.IMU.perturb(rng, 0)
+				accZ = noise.IMU.perturb(rng, 9.81)
+			}
+
 			data := adapter.SensorData{
 				Topic:     "imu",
 				DataType:  "imu",
@@ -644,16 +1152,15 @@ func (m *MockAdapter) generateIMU(ctx context.Context) {
 					"angular_vel_z": m.angularZ,
 
 					// 加速度センサー: 各軸の加速度（m/s²）
-					// x, y: ランダムノイズ（-0.05〜+0.05）で微小な振動を模擬
-					// z: 重力加速度（9.81 m/s²）+ ノイズ（-0.01〜+0.01）
-					"linear_acc_x": rand.Float64()*0.1 - 0.05,
-					"linear_acc_y": rand.Float64()*0.1 - 0.05,
-					"linear_acc_z": 9.81 + rand.Float64()*0.02 - 0.01,
+					"linear_acc_x": accX,
+					"linear_acc_y": accY,
+					"linear_acc_z": accZ,
 				},
 			}
 
 			select {
 			case m.dataCh <- data:
+				m.markContact()
 			default:
 			}
 		}
@@ -687,28 +1194,27 @@ func (m *MockAdapter) generateBattery(ctx context.Context) {
 		case <-ticker.C:
 			// 書き込みロックでバッテリー値を更新
 			m.mu.Lock()
-			m.battery -= 0.01 // 0.01%ずつ減少
-			if m.battery < 0 {
-				m.battery = 0 // 0%以下にはならない
+			charging := m.docked
+			if charging {
+				// ドッキング中は充電される（100%が上限）
+				m.battery += dockChargeRate
+				if m.battery > 100 {
+					m.battery = 100
+				}
+			} else {
+				m.battery -= 0.01 // 0.01%ずつ減少
+				if m.battery <= 0 {
+					m.depleteBattery()
+				}
 			}
 			bat := m.battery // ローカル変数にコピー（ロック外で使うため）
 			m.mu.Unlock()
 
-			data := adapter.SensorData{
-				Topic:     "battery",
-				DataType:  "battery",
-				FrameID:   "base_link",
-				Timestamp: time.Now().UnixMilli(),
-				Data: map[string]any{
-					"percentage": bat,                  // バッテリー残量（%）
-					"voltage":    12.0 * (bat / 100.0), // 電圧（V）= 12V × 残量比率
-					"current":    -0.5,                 // 電流（A）負の値は放電中を意味する
-					"charging":   false,                // 充電中フラグ
-				},
-			}
+			data := batterySample(bat, charging)
 
 			select {
 			case m.dataCh <- data:
+				m.markContact()
 			default:
 			}
 		}
@@ -716,44 +1222,115 @@ func (m *MockAdapter) generateBattery(ctx context.Context) {
 }
 
 // =============================================================================
-// toFloat64 - any型からfloat64への安全な型変換ヘルパー関数
-// =============================================================================
-//
-// 【型スイッチ（type switch）とは？】
-// Go言語の any（interface{}）型の値が実際に何の型なのかを判定する構文です。
-// 通常のswitch文に似ていますが、値ではなく「型」で分岐します。
-//
-// 【なぜこの関数が必要？】
-// map[string]any のPayloadから値を取り出すと、型が any のままです。
-// JSONデコードした場合、数値は通常 float64 になりますが、
-// 他のソースからは int や float32 など、様々な型で来る可能性があります。
-// この関数は、どの数値型が来ても安全にfloat64に変換します。
-//
-// 【構文の説明】
-//
-//	switch val := v.(type) {  ← v の実際の型で分岐し、val にキャスト済みの値が入る
-//	case float64:             ← v が float64 型の場合
-//	    return val            ← val は float64 型として使える
-//	}
-//
-// 【型アサーション v.(type) との違い】
-// - v.(float64): 単一の型へのアサーション。失敗するとpanicまたは第2戻り値がfalse
-// - v.(type): switch文専用。複数の型を安全に判定可能
-func toFloat64(v any) float64 {
-	switch val := v.(type) {
-	case float64:
-		return val
-	case float32:
-		// float32 → float64 への変換
-		return float64(val)
-	case int:
-		// int → float64 への変換
-		return float64(val)
-	case int64:
-		// int64 → float64 への変換
-		return float64(val)
-	default:
-		// 上記以外の型（nil、string等）の場合は0.0を返す
-		return 0.0
+// approachDock - ドックに向かう速度を決める（ドッキング中のみ呼ばれる）
+// =============================================================================
+//
+// 【呼び出し条件】
+// generateOdometry() の中で m.mu の書き込みロックを保持したまま呼ばれます。
+// そのため、この関数自身ではロックを取りません。
+//
+// 【簡易的な経路追従】
+// 実際のロボットはドックのマーカーを検出して精密に位置合わせしますが、
+// ここでは「ドックの方向を向いて、距離に比例した速度で直進する」だけの
+// 単純な制御で模擬しています（P制御の一種）。
+func (m *MockAdapter) approachDock() {
+	dx := dockX - m.posX
+	dy := dockY - m.posY
+	// math.Hypot(dx, dy) = √(dx² + dy²): ドックまでの直線距離
+	dist := math.Hypot(dx, dy)
+
+	if dist < dockTolerance {
+		// 到着: 停止して充電を開始する
+		m.setVelocityNow(adapter.Velocity{})
+		m.docking = false
+		m.docked = true
+		m.logger.Info("Mock adapter docked, charging started")
+		return
+	}
+
+	// math.Atan2(dy, dx): ドックの方向（ラジアン）
+	m.theta = math.Atan2(dy, dx)
+	m.setVelocityNow(adapter.Velocity{LinearX: math.Min(dockApproachVel, dist)})
+}
+
+// =============================================================================
+// odometrySample - 報告する位置からオドメトリのセンサーデータを作る
+// =============================================================================
+//
+// generateOdometry() の定期送信と、reset_pose 直後の即時送信で共通に使います。
+// m.mu のロックを保持した状態で呼んでください。
+//
+// ドリフトが有効な場合、position_x などは誤差を含む推定姿勢で、
+// 真の姿勢は true_position_x / true_position_y / true_orientation_z に載せます。
+// ML の学習では、真の姿勢を「正解ラベル」として使えます。
+func (m *MockAdapter) odometrySample(reportX, reportY, reportTheta float64) adapter.SensorData {
+	// 送信するセンサーデータを構造体リテラルで作成
+	data := adapter.SensorData{
+		Topic:     "odom",                 // トピック名（購読者がフィルタに使う）
+		DataType:  "odometry",             // データの種類
+		FrameID:   "odom",                 // 座標系の基準フレーム
+		Timestamp: time.Now().UnixMilli(), // 現在時刻のミリ秒タイムスタンプ
+		Data: map[string]any{
+			"position_x":    reportX,     // X座標（m）
+			"position_y":    reportY,     // Y座標（m）
+			"orientation_z": reportTheta, // 向き（rad）
+			"velocity_x":    m.linearX,   // 前進速度（m/s）
+			"velocity_y":    m.linearY,   // 横方向速度（m/s）
+			"angular_z":     m.angularZ,  // 回転速度（rad/s）
+		},
+	}
+
+	// ランプ補間が有効なら、補間前の目標速度も載せる。
+	// velocity_x などは補間後（実際に動いている）の速度です。
+	if m.ramp != nil {
+		target := m.ramp.Target()
+		data.Data["target_velocity_x"] = target.LinearX
+		data.Data["target_velocity_y"] = target.LinearY
+		data.Data["target_angular_z"] = target.AngularZ
+	}
+
+	if m.drift != nil {
+		data.Data["true_position_x"] = m.posX
+		data.Data["true_position_y"] = m.posY
+		data.Data["true_orientation_z"] = m.theta
+	}
+	return data
+}
+
+// =============================================================================
+// batterySample - バッテリーのセンサーデータを作る
+// =============================================================================
+//
+// generateBattery() の定期送信と、ReadSensor() の単発の読み取りで共通に使います。
+func batterySample(bat float64, charging bool) adapter.SensorData {
+	current := -0.5 // 電流（A）負の値は放電中を意味する
+	if charging {
+		current = 2.0 // 正の値は充電中を意味する
+	}
+	return adapter.SensorData{
+		Topic:     "battery",
+		DataType:  "battery",
+		FrameID:   "base_link",
+		Timestamp: time.Now().UnixMilli(),
+		Data: map[string]any{
+			"percentage": bat,                  // バッテリー残量（%）
+			"voltage":    12.0 * (bat / 100.0), // 電圧（V）= 12V × 残量比率
+			"current":    current,              // 電流（A）
+			"charging":   charging,             // 充電中フラグ（ドッキング中は true）
+		},
+	}
+}
+
+// =============================================================================
+// setVelocityNow - ランプ補間を通さずに速度を即座に設定する
+// =============================================================================
+//
+// 緊急停止・故障・ドッキング制御など、ゲートウェイ側が直接速度を決める場面で使います。
+// ランプの内部状態も揃えておかないと、次の Step() で古い目標に引き戻されてしまいます。
+// m.mu の書き込みロックを保持した状態で呼んでください。
+func (m *MockAdapter) setVelocityNow(v adapter.Velocity) {
+	m.linearX, m.linearY, m.angularZ = v.LinearX, v.LinearY, v.AngularZ
+	if m.ramp != nil {
+		m.ramp.Reset(v)
 	}
 }
@@ -0,0 +1,198 @@
+// =============================================================================
+// ファイル: noise_profile.go
+// 概要: 模擬センサーに加えるノイズ（雑音）の設定を、ファイルから読み込む仕組み
+//
+// 【なぜノイズプロファイルが必要？】
+// 機械学習モデルは「きれいすぎる」データだけで学習すると、
+// 実機の汚れたセンサーデータに弱くなります（ロバスト性が低い）。
+// ノイズの強さ・偏り・欠損をファイルで切り替えられるようにすることで、
+// 再コンパイルせずに多様な学習データを生成できます。
+//
+// 【ファイル形式（JSON または YAML）】
+// 拡張子が .yaml / .yml なら YAML、それ以外は JSON として読み込みます。
+//
+//	{
+//	  "profiles": {
+//	    "clean": { "seed": 42 },
+//	    "noisy_lidar": {
+//	      "seed": 7,
+//	      "lidar":    { "std_dev": 0.05, "bias": 0.02, "dropout_rate": 0.01 },
+//	      "imu":      { "std_dev": 0.02 },
+//	      "odometry": { "std_dev": 0.005 }
+//	    }
+//	  }
+//	}
+//
+// 【接続時の指定方法】
+// MockAdapter.Connect() の config に以下のキーを渡します。
+//   - "noise_profile_file": プロファイルファイルのパス
+//   - "noise_profile":      使用するプロファイル名（省略時は "default"）
+//
+// ファイルを指定しない場合は、従来どおりの一様ノイズで動作します。
+// =============================================================================
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+
+	// yaml.v3: YAML形式のプロファイルファイルを読み込むためのライブラリ
+	"gopkg.in/yaml.v3"
+)
+
+// defaultNoiseProfileName: config で名前が省略された時に使うプロファイル名
+const defaultNoiseProfileName = "default"
+
+// =============================================================================
+// NoiseParams - 1種類のセンサーに加えるノイズのパラメータ
+// =============================================================================
+//
+// 測定値 = 真値 + Bias + N(0, StdDev²)
+// さらに DropoutRate の確率で測定値が欠損します。
+type NoiseParams struct {
+	// StdDev: ガウスノイズ（正規分布）の標準偏差
+	StdDev float64 `json:"std_dev" yaml:"std_dev"`
+
+	// Bias: すべての測定値に加わる一定の偏り（キャリブレーションずれの模擬）
+	Bias float64 `json:"bias" yaml:"bias"`
+
+	// DropoutRate: 測定値が欠損する確率（0.0〜1.0）
+	// LiDAR では1本のビームが無効値（0 = range_min 未満）になり、
+	// IMU・オドメトリではそのメッセージ自体が送信されません。
+	DropoutRate float64 `json:"dropout_rate" yaml:"dropout_rate"`
+}
+
+// =============================================================================
+// NoiseProfile - センサーごとのノイズ設定をまとめたもの
+// =============================================================================
+type NoiseProfile struct {
+	// Seed: 乱数のシード値。同じシードなら同じノイズ列が再現されます。
+	// 0 の場合は接続時刻から決めるため、毎回異なるノイズになります。
+	Seed int64 `json:"seed" yaml:"seed"`
+
+	LiDAR    NoiseParams `json:"lidar" yaml:"lidar"`       // LiDAR の距離値に加えるノイズ
+	IMU      NoiseParams `json:"imu" yaml:"imu"`           // IMU の加速度に加えるノイズ
+	Odometry NoiseParams `json:"odometry" yaml:"odometry"` // オドメトリの位置に加えるノイズ
+}
+
+// noiseProfileFile: プロファイルファイル全体の構造
+type noiseProfileFile struct {
+	Profiles map[string]NoiseProfile `json:"profiles" yaml:"profiles"`
+}
+
+// =============================================================================
+// Validate - パラメータが物理的に意味のある範囲にあるかを検証する
+// =============================================================================
+func (p NoiseParams) Validate() error {
+	for name, v := range map[string]float64{"std_dev": p.StdDev, "bias": p.Bias, "dropout_rate": p.DropoutRate} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("%s must be a finite number", name)
+		}
+	}
+	if p.StdDev < 0 {
+		return fmt.Errorf("std_dev must be >= 0, got %v", p.StdDev)
+	}
+	if p.DropoutRate < 0 || p.DropoutRate > 1 {
+		return fmt.Errorf("dropout_rate must be within [0, 1], got %v", p.DropoutRate)
+	}
+	return nil
+}
+
+// Validate - プロファイル内の全センサーのパラメータを検証する
+func (p NoiseProfile) Validate() error {
+	if err := p.LiDAR.Validate(); err != nil {
+		return fmt.Errorf("lidar: %w", err)
+	}
+	if err := p.IMU.Validate(); err != nil {
+		return fmt.Errorf("imu: %w", err)
+	}
+	if err := p.Odometry.Validate(); err != nil {
+		return fmt.Errorf("odometry: %w", err)
+	}
+	return nil
+}
+
+// =============================================================================
+// LoadNoiseProfiles - ファイルから名前付きプロファイルの一覧を読み込む
+// =============================================================================
+//
+// 読み込んだ全プロファイルを検証し、1つでも不正なものがあればエラーを返します。
+// 実験の途中で不正な値に気づくより、起動時に失敗する方が安全だからです。
+func LoadNoiseProfiles(path string) (map[string]NoiseProfile, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read noise profile file: %w", err)
+	}
+
+	var file noiseProfileFile
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(raw, &file)
+	default:
+		err = json.Unmarshal(raw, &file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse noise profile file %s: %w", path, err)
+	}
+
+	if len(file.Profiles) == 0 {
+		return nil, fmt.Errorf("noise profile file %s defines no profiles", path)
+	}
+	for name, p := range file.Profiles {
+		if err := p.Validate(); err != nil {
+			return nil, fmt.Errorf("noise profile %q: %w", name, err)
+		}
+	}
+	return file.Profiles, nil
+}
+
+// =============================================================================
+// noiseProfileFromConfig - Connect() の config からプロファイルを選ぶ
+// =============================================================================
+//
+// ファイルが指定されていなければ (nil, nil) を返し、従来の一様ノイズを使います。
+func noiseProfileFromConfig(config map[string]any) (*NoiseProfile, error) {
+	path, _ := config["noise_profile_file"].(string)
+	if path == "" {
+		return nil, nil
+	}
+
+	profiles, err := LoadNoiseProfiles(path)
+	if err != nil {
+		return nil, err
+	}
+
+	name, _ := config["noise_profile"].(string)
+	if name == "" {
+		name = defaultNoiseProfileName
+	}
+	p, ok := profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("noise profile %q not found in %s", name, path)
+	}
+	return &p, nil
+}
+
+// =============================================================================
+// dropped / perturb - 1つの測定値に欠損・ノイズを適用する
+// =============================================================================
+//
+// 【rng を引数で受け取る理由】
+// *rand.Rand はゴルーチン安全ではありません。
+// センサー生成器ごとに専用の rng を持たせ、ロックなしで使えるようにしています。
+
+// dropped: DropoutRate の確率で true（＝この測定値は欠損）を返す
+func (p NoiseParams) dropped(rng *rand.Rand) bool {
+	return p.DropoutRate > 0 && rng.Float64() < p.DropoutRate
+}
+
+// perturb: 真値 v に偏り（Bias）とガウスノイズを加えた値を返す
+func (p NoiseParams) perturb(rng *rand.Rand, v float64) float64 {
+	// NormFloat64(): 平均0・標準偏差1の正規分布に従う乱数
+	return v + p.Bias + rng.NormFloat64()*p.StdDev
+}
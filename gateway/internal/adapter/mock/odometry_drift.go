@@ -0,0 +1,94 @@
+// =============================================================================
+// ファイル: odometry_drift.go
+// 概要: オドメトリの累積誤差（ドリフト）を模擬するモデル
+//
+// 【ドリフトとは？】
+// オドメトリは車輪の回転量を積み上げて位置を推定するため、
+// タイヤの摩耗や滑り、径の誤差があると、走れば走るほど誤差が溜まっていきます。
+// 実機では「推定位置」と「本当の位置」が少しずつずれていくのが普通で、
+// 自己位置推定（ローカライゼーション）や SLAM はこのずれを補正する技術です。
+//
+// 【このモデル】
+// 1ステップの移動量（距離 d, 回転 dθ）に対して、推定側の移動量を
+//
+//	d'  = d  × (1 + PerMeter)  + N(0, (Noise × |d|)²)
+//	dθ' = dθ × (1 + PerRadian) + N(0, (Noise × |dθ|)²)
+//
+// とします。PerMeter / PerRadian は系統誤差（毎回同じ向きにずれる）、
+// Noise はランダム誤差で、どちらも移動量に比例して蓄積します。
+//
+// 【Connect() の config キー】
+//   - "odom_drift_per_meter":  距離の系統誤差（例: 0.02 = 2% 多く進んだと推定）
+//   - "odom_drift_per_radian": 回転の系統誤差（例: 0.01 = 1% 多く回ったと推定）
+//   - "odom_drift_noise":      ランダム誤差の標準偏差（移動量に対する比率）
+//
+// すべて 0（既定）の場合はドリフトなしで、従来どおり真の位置を出力します。
+//
+// 【姿勢の書き換え】
+// ドックへの向き合わせ（approachDock）や離脱（undock）は、車輪で動いたのではなく
+// シミュレーターが姿勢を直接書き換えます。この分は overwrite で誤差を加えずに推定姿勢へ反映します。
+// =============================================================================
+package mock
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/robot-ai-webapp/gateway/internal/convert"
+)
+
+// odometryDrift: ドリフトのパラメータと、推定側の姿勢
+//
+// MockAdapter の m.mu で保護される前提で、自身はロックを持ちません。
+type odometryDrift struct {
+	perMeter  float64 // 距離の系統誤差（比率）
+	perRadian float64 // 回転の系統誤差（比率）
+	noise     float64 // ランダム誤差の標準偏差（移動量に対する比率）
+
+	// 推定（ドリフトを含む）姿勢
+	estX, estY, estTheta float64
+}
+
+// =============================================================================
+// odometryDriftFromConfig - config からドリフトモデルを作る
+// =============================================================================
+//
+// どのパラメータも 0 なら nil を返し、ドリフトなしとして扱います。
+func odometryDriftFromConfig(config map[string]any) (*odometryDrift, error) {
+	d := &odometryDrift{}
+	d.perMeter, _ = convert.ToFloat64(config["odom_drift_per_meter"])
+	d.perRadian, _ = convert.ToFloat64(config["odom_drift_per_radian"])
+	d.noise, _ = convert.ToFloat64(config["odom_drift_noise"])
+	if d.perMeter == 0 && d.perRadian == 0 && d.noise == 0 {
+		return nil, nil
+	}
+	if d.noise < 0 {
+		return nil, fmt.Errorf("odom_drift_noise must be >= 0, got %v", d.noise)
+	}
+	return d, nil
+}
+
+// reset: 推定姿勢を真の姿勢に合わせる（接続時や reset_pose の時）
+func (d *odometryDrift) reset(x, y, theta float64) {
+	d.estX, d.estY, d.estTheta = x, y, theta
+}
+
+// overwrite: シミュレーターが直接書き換えた姿勢の変化を、誤差を加えずに推定姿勢へ反映する
+// forward / lateral はロボットから見た前後・左右の移動量（m）、dTheta は回転量（rad、-π〜π）。
+func (d *odometryDrift) overwrite(forward, lateral, dTheta float64) {
+	d.estTheta += dTheta
+	d.estX += forward*math.Cos(d.estTheta) - lateral*math.Sin(d.estTheta)
+	d.estY += forward*math.Sin(d.estTheta) + lateral*math.Cos(d.estTheta)
+}
+
+// step: 1ステップ分の移動量（距離 dist, 回転 dTheta）から推定姿勢を更新する
+func (d *odometryDrift) step(rng *rand.Rand, dist, dTheta float64) {
+	estDTheta := dTheta*(1+d.perRadian) + rng.NormFloat64()*d.noise*math.Abs(dTheta)
+	estDist := dist*(1+d.perMeter) + rng.NormFloat64()*d.noise*math.Abs(dist)
+
+	// 真の位置の計算（generateOdometry）と同じく、向きを更新してから進める
+	d.estTheta += estDTheta
+	d.estX += estDist * math.Cos(d.estTheta)
+	d.estY += estDist * math.Sin(d.estTheta)
+}
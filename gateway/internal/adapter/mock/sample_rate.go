@@ -0,0 +1,113 @@
+// =============================================================================
+// ファイル: sample_rate.go
+// 概要: モックのセンサー送信頻度を実行中に変更する仕組み（adapter.SampleRateController の実装）
+//
+// 【なぜ必要？】
+// 接続中のクライアントが全員遅いと、Hub はデータを捨てるしかありません。
+// それでも生成器が全速力で動き続けると、捨てるためだけに CPU を使うことになります。
+// server.FlowController が混雑を検知した時に、ここで生成頻度を下げます。
+//
+// 【対象トピック】
+// odom / scan / imu の3つです。battery は残量の減少・充電の計算を
+// 周期（5秒）に結びつけているため、頻度を変えると放電の速さまで変わってしまいます。
+// そのため対象外にしています。
+//
+// 管理者が set_sensor_rate で頻度を変える場合も同じ仕組みを使います。
+// 上げられるのは maxSampleRates（実機のセンサーの一般的な上限に合わせた値）までです。
+// =============================================================================
+package mock
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// nominalSampleRates: 頻度を変更できるトピックと、その標準の送信頻度（Hz）
+var nominalSampleRates = map[string]float64{
+	"odom": 20, // 50ms ごと
+	"scan": 10, // 100ms ごと
+	"imu":  50, // 20ms ごと
+}
+
+// maxSampleRates: 頻度を変更できるトピックの最大の送信頻度（Hz）
+var maxSampleRates = map[string]float64{
+	"odom": 100,
+	"scan": 40,
+	"imu":  200,
+}
+
+// =============================================================================
+// NominalSampleRates - 頻度を変更できるトピックの標準頻度を返す
+// =============================================================================
+//
+// enabled_topics で無効にしたトピックは含めません（生成器が動いていないため）。
+func (m *MockAdapter) NominalSampleRates() map[string]float64 {
+	m.mu.RLock()
+	topics := m.topics
+	m.mu.RUnlock()
+	if topics == nil {
+		topics = allSensorTopics
+	}
+
+	rates := make(map[string]float64, len(nominalSampleRates))
+	for _, topic := range topics {
+		if hz, ok := nominalSampleRates[topic]; ok {
+			rates[topic] = hz
+		}
+	}
+	return rates
+}
+
+// =============================================================================
+// MaxSampleRates - 頻度を変更できるトピックの最大の送信頻度を返す
+// =============================================================================
+func (m *MockAdapter) MaxSampleRates() map[string]float64 {
+	rates := make(map[string]float64, len(maxSampleRates))
+	for topic, hz := range maxSampleRates {
+		rates[topic] = hz
+	}
+	return rates
+}
+
+// =============================================================================
+// SetSampleRate - トピックの送信頻度（Hz）を変更する
+// =============================================================================
+//
+// 新しい頻度は、各生成器の次の周期から反映されます。
+// 再接続（Connect）すると標準の頻度に戻ります。
+func (m *MockAdapter) SetSampleRate(topic string, hz float64) error {
+	if _, ok := nominalSampleRates[topic]; !ok {
+		return fmt.Errorf("mock adapter: sample rate of topic %q cannot be changed", topic)
+	}
+	if hz <= 0 || math.IsNaN(hz) || math.IsInf(hz, 0) {
+		return fmt.Errorf("mock adapter: sample rate must be a positive number, got %v", hz)
+	}
+	if hz > maxSampleRates[topic] {
+		return fmt.Errorf("mock adapter: sample rate of topic %q must not exceed %v Hz, got %v", topic, maxSampleRates[topic], hz)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.intervals == nil {
+		m.intervals = make(map[string]time.Duration)
+	}
+	m.intervals[topic] = time.Duration(float64(time.Second) / hz)
+
+	m.logger.Debug("Mock adapter sample rate changed",
+		zap.String("topic", topic),
+		zap.Float64("hz", hz),
+	)
+	return nil
+}
+
+// sampleIntervalLocked: トピックの現在の送信周期を返す
+// m.mu のロック（読み取りでも可）を保持した状態で呼んでください。
+func (m *MockAdapter) sampleIntervalLocked(topic string) time.Duration {
+	if d, ok := m.intervals[topic]; ok {
+		return d
+	}
+	return time.Duration(float64(time.Second) / nominalSampleRates[topic])
+}
@@ -0,0 +1,52 @@
+// =============================================================================
+// ファイル: sensor_read.go
+// 概要: センサーの値をその場で読む（adapter.SensorReader の実装）
+//
+// ゲートウェイが最新値を持っていない時の sensor_request に使われます。
+// 例えばバッテリーは5秒ごとにしか送らないため、接続直後の問い合わせには
+// ここで現在の値を作って答えます。
+//
+// 【対象トピック】
+// odom と battery だけです。scan と imu はノイズを含む連続した計測値で、
+// 1回分だけ作っても定期送信と見分けがつかないため、最新値（キャッシュ）に任せます。
+// =============================================================================
+package mock
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+)
+
+// =============================================================================
+// ReadSensor - トピックの現在の値を1つ作って返す
+// =============================================================================
+//
+// 作った値は SensorDataChannel には流しません（問い合わせたクライアントにだけ届く）。
+// 未接続や、enabled_topics で無効にしたトピックはエラーになります。
+func (m *MockAdapter) ReadSensor(ctx context.Context, topic string) (adapter.SensorData, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if !m.connected {
+		return adapter.SensorData{}, fmt.Errorf("mock adapter is not connected")
+	}
+	if !slices.Contains(m.topics, topic) {
+		return adapter.SensorData{}, fmt.Errorf("sensor topic %q is not enabled", topic)
+	}
+
+	switch topic {
+	case "odom":
+		// ドリフトが有効なら、定期送信と同じく誤差を含む推定姿勢を報告する
+		x, y, theta := m.posX, m.posY, m.theta
+		if m.drift != nil {
+			x, y, theta = m.drift.estX, m.drift.estY, m.drift.estTheta
+		}
+		return m.odometrySample(x, y, theta), nil
+	case "battery":
+		return batterySample(m.battery, m.docked), nil
+	}
+	return adapter.SensorData{}, fmt.Errorf("read %s: %w", topic, adapter.ErrNotSupported)
+}
@@ -0,0 +1,111 @@
+// =============================================================================
+// ファイル: tf.go
+// 概要: モックの座標変換ツリー（TF）の生成
+//
+// 【TF とは？】
+// ROS の座標変換の仕組みです。ロボットの各部品（センサーなど）にはそれぞれの座標系
+// （フレーム）があり、「親フレームから見た子フレームの位置と向き」を並べたものが
+// TF ツリーになります。ブラウザの 3D 表示は、これを辿って LiDAR の点群などを
+// 正しい位置に描画します。
+//
+//	odom ──(動的: 走行に合わせて変わる)──→ base_link
+//	                                       ├──(静的)──→ lidar_link
+//	                                       └──(静的)──→ imu_link
+//
+// 【メッセージの形】
+// ROS の tf2_msgs/TFMessage と同じ形にしているため、
+// ROS 向けの可視化ライブラリ（ros3djs など）にそのまま渡せます。
+//
+//	{"transforms": [{
+//	    "header": {"stamp": {"secs": ..., "nsecs": ...}, "frame_id": "odom"},
+//	    "child_frame_id": "base_link",
+//	    "transform": {"translation": {"x", "y", "z"}, "rotation": {"x", "y", "z", "w"}}
+//	}]}
+//
+// 【トピック】
+//
+//	tf:        動的な変換（odom → base_link）。オドメトリと同じ周期で送る
+//	tf_static: 静的な変換（base_link → 各センサー）。接続時に一度だけ送る
+//
+// ROS と同じく tf_static は一度しか送らないため、後から購読したクライアントにも
+// 届くよう、ゲートウェイが最後の値を保持して購読時に送ります（server/latched_topics.go）。
+// enabled_topics では "tf" を選ぶと両方が有効になります。動的な変換は
+// オドメトリの生成器が送るため、"odom" も一緒に有効にしてください。
+// =============================================================================
+package mock
+
+import (
+	"math"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+)
+
+// tfDataType: TF メッセージの data_type（ROS のメッセージ型名に合わせる）
+const tfDataType = "tf2_msgs/TFMessage"
+
+// staticTransform - base_link から見たセンサーの取り付け位置
+type staticTransform struct {
+	child   string
+	x, y, z float64 // 取り付け位置（m）
+	yaw     float64 // 取り付けの向き（rad）
+}
+
+// sensorMounts: モックのセンサーの取り付け位置
+// 実機では URDF（ロボットの形状定義）から得る値です。
+var sensorMounts = []staticTransform{
+	{child: "lidar_link", x: 0.1, z: 0.2},
+	{child: "imu_link", z: 0.05},
+}
+
+// tfStaticSample - 静的な変換（base_link → 各センサー）の tf_static メッセージを作る
+func tfStaticSample() adapter.SensorData {
+	now := time.Now()
+	transforms := make([]any, 0, len(sensorMounts))
+	for _, mount := range sensorMounts {
+		transforms = append(transforms, transformStamped(now, "base_link", mount.child, mount.x, mount.y, mount.z, mount.yaw))
+	}
+	return adapter.SensorData{
+		Topic:     "tf_static",
+		DataType:  tfDataType,
+		FrameID:   "base_link",
+		Timestamp: now.UnixMilli(),
+		Data:      map[string]any{"transforms": transforms},
+	}
+}
+
+// tfSample - 動的な変換（odom → base_link）の tf メッセージを作る
+// 姿勢はオドメトリで報告したもの（ドリフト・ノイズを含む推定姿勢）を渡します。
+func tfSample(x, y, theta float64) adapter.SensorData {
+	now := time.Now()
+	return adapter.SensorData{
+		Topic:     "tf",
+		DataType:  tfDataType,
+		FrameID:   "odom",
+		Timestamp: now.UnixMilli(),
+		Data: map[string]any{
+			"transforms": []any{transformStamped(now, "odom", "base_link", x, y, 0, theta)},
+		},
+	}
+}
+
+// transformStamped - geometry_msgs/TransformStamped の形の map を作る
+// 平面上のロボットなので、回転は Z 軸まわり（yaw）だけのクォータニオンになります。
+func transformStamped(stamp time.Time, parent, child string, x, y, z, yaw float64) map[string]any {
+	return map[string]any{
+		"header": map[string]any{
+			"stamp":    map[string]any{"secs": stamp.Unix(), "nsecs": stamp.Nanosecond()},
+			"frame_id": parent,
+		},
+		"child_frame_id": child,
+		"transform": map[string]any{
+			"translation": map[string]any{"x": x, "y": y, "z": z},
+			"rotation": map[string]any{
+				"x": 0.0,
+				"y": 0.0,
+				"z": math.Sin(yaw / 2),
+				"w": math.Cos(yaw / 2),
+			},
+		},
+	}
+}
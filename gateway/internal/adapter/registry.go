@@ -45,6 +45,12 @@
 package adapter
 
 import (
+	// context: Disconnect() に渡すキャンセル・タイムアウトの伝搬用
+	"context"
+
+	// errors: センチネルエラー（ErrAdapterExists）の作成に使います。
+	"errors"
+
 	// fmt: フォーマット済みI/Oパッケージ
 	// エラーメッセージの生成に使います。
 	// fmt.Errorf() でフォーマット済みのエラーを作ります。
@@ -84,6 +90,12 @@ import (
 //	adapter := factory(logger)  // ファクトリを使ってアダプターを作成
 type AdapterFactory func(logger *zap.Logger) RobotAdapter
 
+// ErrAdapterExists - 同じロボットIDのアダプターが既に登録されていることを示すエラー
+//
+// CreateAdapter() はこのエラーをラップして返します。
+// 呼び出し側は errors.Is(err, adapter.ErrAdapterExists) で判定できます。
+var ErrAdapterExists = errors.New("adapter already exists for robot")
+
 // =============================================================================
 // Registry - アダプターレジストリ構造体
 // =============================================================================
@@ -129,6 +141,25 @@ type Registry struct {
 	// どちらも RobotAdapter インターフェースとして同じように操作できます。
 	active map[string]RobotAdapter // robot_id -> adapter
 
+	// onRemove: RemoveAdapter でアダプターを削除した後に呼ぶ関数（SetRemoveCallback で設定、nil なら呼ばない）
+	onRemove func(robotID string)
+
+	// allowedTypes / deniedTypes: 作成してよいアダプタータイプの許可リストと拒否リスト
+	// （SetTypePolicy で設定、nil ならすべて許可。type_policy.go 参照）
+	allowedTypes map[string]bool
+	deniedTypes  map[string]bool
+
+	// disabled: 一時的に運用から外したロボット（SetEnabled で設定。robot_enabled.go 参照）
+	disabled map[string]bool
+
+	// types: ロボットID → アダプタータイプ（タイプごとの台数を数えるため、active と同時に更新する）
+	// typeLimits: タイプごとの作成数の上限（SetTypeLimits で設定、ないタイプは無制限。type_limit.go 参照）
+	types      map[string]string
+	typeLimits map[string]int
+
+	// groups: ロボットID → 所属グループIDの一覧（SetRobotGroups で設定。group.go 参照）
+	groups map[string][]string
+
 	// logger: ログ出力用のロガー
 	logger *zap.Logger
 }
@@ -149,6 +180,8 @@ func NewRegistry(logger *zap.Logger) *Registry {
 		// 必ず make() で初期化してから使います。
 		factories: make(map[string]AdapterFactory),
 		active:    make(map[string]RobotAdapter),
+		disabled:  make(map[string]bool),
+		types:     make(map[string]string),
 		logger:    logger,
 	}
 }
@@ -197,7 +230,13 @@ func (r *Registry) RegisterFactory(adapterType string, factory AdapterFactory) {
 //
 // 【戻り値】
 // - RobotAdapter: 作成されたアダプター（インターフェース型で返す）
-// - error: 未知のアダプタータイプの場合のエラー
+// - error: 未知のタイプ、同じロボットIDのアダプターが既にある、タイプの上限（SetTypeLimits）に達した場合のエラー
+//
+// 【同じロボットIDで2回呼んだ場合】
+// 以前は黙って上書きしていたため、古いアダプターが接続したまま取り残され、
+// センサー生成のゴルーチンや接続が漏れていました。
+// 今は ErrAdapterExists をラップしたエラーを返し、既存のアダプターには触れません。
+// 意図的に差し替えたい場合は ReplaceAdapter を使います。
 //
 // 【インターフェース型で返す利点】
 // 戻り値が RobotAdapter（インターフェース型）なので、
@@ -216,6 +255,21 @@ func (r *Registry) CreateAdapter(robotID, adapterType string) (RobotAdapter, err
 		return nil, fmt.Errorf("unknown adapter type: %s", adapterType)
 	}
 
+	// ファクトリがあっても、設定で許可されていないタイプは作らない
+	if !r.typeAllowedLocked(adapterType) {
+		return nil, fmt.Errorf("%w: %s", ErrAdapterTypeNotAllowed, adapterType)
+	}
+
+	// 既に同じロボットIDのアダプターがあれば、上書きせずにエラーを返す
+	if _, exists := r.active[robotID]; exists {
+		return nil, fmt.Errorf("%w: %s", ErrAdapterExists, robotID)
+	}
+
+	// タイプごとの上限に達していれば作らない
+	if err := r.checkTypeLimitLocked(adapterType, ""); err != nil {
+		return nil, err
+	}
+
 	// ファクトリを使ってアダプターを作成する
 	//
 	// 【logger.With() とは？】
@@ -232,6 +286,7 @@ func (r *Registry) CreateAdapter(robotID, adapterType string) (RobotAdapter, err
 
 	// active map にアダプターを登録する
 	r.active[robotID] = adapter
+	r.types[robotID] = adapterType
 
 	r.logger.Info("Created adapter",
 		zap.String("robot_id", robotID),
@@ -241,6 +296,60 @@ func (r *Registry) CreateAdapter(robotID, adapterType string) (RobotAdapter, err
 	return adapter, nil
 }
 
+// =============================================================================
+// ReplaceAdapter - 既存のアダプターを切断してから、新しいアダプターに差し替える
+// =============================================================================
+//
+// 【この関数の用途】
+// ロボットの接続方式を切り替える時など、同じロボットIDのアダプターを
+// 意図的に作り直す場合に使います。既存のアダプターがなければ CreateAdapter と同じです。
+//
+// 【処理の順番】
+// 1. 新しいアダプタータイプのファクトリがあるか確認する（なければ何も変更しない）
+// 2. 古いアダプターを Disconnect() する（ゴルーチンと接続を解放する）
+// 3. 新しいアダプターを作成して登録する
+//
+// 切断中もロックを保持するため、その間の GetAdapter() は待たされますが、
+// 「古いアダプターがまだ動いているのに新しいアダプターが登録されている」瞬間は生まれません。
+// 切断に失敗しても差し替えは行い、エラーはログに残します（古いアダプターは既に使えないため）。
+func (r *Registry) ReplaceAdapter(ctx context.Context, robotID, adapterType string) (RobotAdapter, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	factory, ok := r.factories[adapterType]
+	if !ok {
+		return nil, fmt.Errorf("unknown adapter type: %s", adapterType)
+	}
+	if !r.typeAllowedLocked(adapterType) {
+		return nil, fmt.Errorf("%w: %s", ErrAdapterTypeNotAllowed, adapterType)
+	}
+	// 差し替えで外れるアダプターは数に入れずに上限を確認する
+	if err := r.checkTypeLimitLocked(adapterType, robotID); err != nil {
+		return nil, err
+	}
+
+	if old, exists := r.active[robotID]; exists {
+		if err := old.Disconnect(ctx); err != nil {
+			r.logger.Warn("Failed to disconnect replaced adapter",
+				zap.String("robot_id", robotID),
+				zap.Error(err),
+			)
+		}
+		delete(r.active, robotID)
+	}
+
+	adapter := factory(r.logger.With(zap.String("robot_id", robotID), zap.String("adapter", adapterType)))
+	r.active[robotID] = adapter
+	r.types[robotID] = adapterType
+
+	r.logger.Info("Replaced adapter",
+		zap.String("robot_id", robotID),
+		zap.String("type", adapterType),
+	)
+
+	return adapter, nil
+}
+
 // =============================================================================
 // GetAdapter - ロボットIDに対応するアダプターを取得する
 // =============================================================================
@@ -284,16 +393,38 @@ func (r *Registry) GetAdapter(robotID string) (RobotAdapter, bool) {
 // この関数はアダプターをmapから削除するだけで、
 // Disconnect（切断処理）は行いません。
 // 切断は呼び出し側の責任です。
+//
+// 登録されていたアダプターを削除した場合は、SetRemoveCallback で設定した関数を
+// ロックを外してから呼びます（購読の後始末などで GetAdapter を呼んでも待たされないように）。
 func (r *Registry) RemoveAdapter(robotID string) {
 	r.mu.Lock()
-	defer r.mu.Unlock()
 
 	// mapからアダプターを削除する
 	// delete(map, key): Goの組み込み関数でmapから要素を削除する
 	// キーが存在しなくてもエラーにはなりません（安全）。
+	_, existed := r.active[robotID]
 	delete(r.active, robotID)
+	delete(r.disabled, robotID)
+	delete(r.types, robotID)
+	onRemove := r.onRemove
+	r.mu.Unlock()
 
 	r.logger.Info("Removed adapter", zap.String("robot_id", robotID))
+	if existed && onRemove != nil {
+		onRemove(robotID)
+	}
+}
+
+// =============================================================================
+// SetRemoveCallback - アダプターの削除後に呼ぶ関数を設定する
+// =============================================================================
+//
+// 削除されたロボットを購読していたクライアントへの通知と、購読の後始末に使います
+// （Handler.RobotRemoved をそのまま登録できる形です）。
+func (r *Registry) SetRemoveCallback(fn func(robotID string)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onRemove = fn
 }
 
 // =============================================================================
@@ -336,7 +467,7 @@ func (r *Registry) GetAllActive() map[string]RobotAdapter {
 }
 
 // =============================================================================
-// ListFactories - 登録されているアダプタータイプ名のリストを返す
+// ListFactories - 登録されていて、作成が許可されているアダプタータイプ名のリストを返す
 // =============================================================================
 //
 // 【この関数の用途】
@@ -367,8 +498,11 @@ func (r *Registry) ListFactories() []string {
 	types := make([]string, 0, len(r.factories))
 
 	// mapのキー（アダプタータイプ名）をスライスに追加する
+	// 設定で許可されていないタイプは、作れないので一覧にも出さない
 	for k := range r.factories {
-		types = append(types, k)
+		if r.typeAllowedLocked(k) {
+			types = append(types, k)
+		}
 	}
 	return types
 }
@@ -0,0 +1,72 @@
+// =============================================================================
+// ファイル: robot_enabled.go
+// 概要: ロボットの一時的な無効化（アダプターを残したまま運用から外す）
+//
+// 【なぜ必要か？】
+// 点検や清掃のために、ロボットを一時的に運用から外したいことがあります。
+// 以前はアダプターを切断・削除するしかなく、戻すときに接続し直す必要がありました。
+// 無効にしたロボットは、接続を保ったまま次のように扱われます。
+//
+//	コマンド（速度、ナビゲーション、ドックなど） → robot_disabled で拒否（server 側）
+//	センサーデータ                               → 設定によって転送を止める（server 側）
+//	E-Stop と停止（stop_all）                    → 安全のため、常に受け付ける
+//
+// ここではロボットごとの状態を持つだけで、拒否や転送の停止は server パッケージが行います。
+// アダプターを削除すると状態も消え、同じ ID で作り直したロボットは有効で始まります。
+// =============================================================================
+package adapter
+
+import (
+	"errors"
+	"sort"
+
+	"go.uber.org/zap"
+)
+
+// ErrAdapterNotFound - 指定したロボットIDのアダプターが登録されていないことを示すエラー
+var ErrAdapterNotFound = errors.New("adapter not found for robot")
+
+// SetEnabled - ロボットを有効／無効にする
+//
+// 状態が変わった場合は changed=true を返します（同じ状態を指定したら false）。
+// アダプターが登録されていなければ ErrAdapterNotFound を返します。
+func (r *Registry) SetEnabled(robotID string, enabled bool) (changed bool, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.active[robotID]; !ok {
+		return false, ErrAdapterNotFound
+	}
+	if r.disabled[robotID] == !enabled {
+		return false, nil
+	}
+	if enabled {
+		delete(r.disabled, robotID)
+	} else {
+		r.disabled[robotID] = true
+	}
+	r.logger.Info("Robot enabled state changed",
+		zap.String("robot_id", robotID),
+		zap.Bool("enabled", enabled),
+	)
+	return true, nil
+}
+
+// IsEnabled - ロボットが有効か（無効にしていなければ true。未登録のロボットも true）
+func (r *Registry) IsEnabled(robotID string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return !r.disabled[robotID]
+}
+
+// DisabledRobots - 無効にしているロボットIDの一覧（ID順）
+func (r *Registry) DisabledRobots() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	robots := make([]string, 0, len(r.disabled))
+	for robotID := range r.disabled {
+		robots = append(robots, robotID)
+	}
+	sort.Strings(robots)
+	return robots
+}
@@ -0,0 +1,92 @@
+// =============================================================================
+// ファイル: type_limit.go
+// 概要: アダプタータイプごとの作成数の上限
+//
+// 【なぜ必要か？】
+// プロビジョニングの不具合で CreateAdapter が繰り返し呼ばれると、
+// アダプター（と、その接続やセンサー生成のゴルーチン）が際限なく増え、
+// ゲートウェイのメモリや接続先を使い果たしてしまいます。
+// タイプごとに上限を設けておけば、上限に達した時点で作成を拒否できます。
+//
+//	SetTypeLimits({"ros2": 100})
+//	CreateAdapter("robot-101", "ros2") ──→ ErrAdapterTypeLimit（既に 100 台ある場合）
+//
+// 上限を設定していないタイプは無制限です。
+// =============================================================================
+package adapter
+
+import (
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// ErrAdapterTypeLimit - そのアダプタータイプの作成数が上限に達していることを示すエラー
+//
+// CreateAdapter() と ReplaceAdapter() はこのエラーをラップして返します。
+// 呼び出し側は errors.Is(err, adapter.ErrAdapterTypeLimit) で判定できます。
+var ErrAdapterTypeLimit = errors.New("adapter type limit reached")
+
+// SetTypeLimits - アダプタータイプごとの作成数の上限を設定する
+//
+// limits にないタイプは無制限です。既に上限を超えてアダプターがある場合も削除はせず、
+// 以後の作成だけを拒否します。
+// アダプターを作成する前（起動時）に一度だけ呼んでください。
+func (r *Registry) SetTypeLimits(limits map[string]int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.typeLimits = make(map[string]int, len(limits))
+	for t, n := range limits {
+		r.typeLimits[t] = n
+	}
+	if len(limits) > 0 {
+		r.logger.Info("Adapter type limits configured", zap.Any("limits", limits))
+	}
+}
+
+// TypeLimits - 設定されているタイプごとの上限を返す（コピー、上限のないタイプは含まない）
+func (r *Registry) TypeLimits() map[string]int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	limits := make(map[string]int, len(r.typeLimits))
+	for t, n := range r.typeLimits {
+		limits[t] = n
+	}
+	return limits
+}
+
+// TypeCounts - 登録されているアダプターの数をタイプごとに返す
+func (r *Registry) TypeCounts() map[string]int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := make(map[string]int)
+	for _, t := range r.types {
+		counts[t]++
+	}
+	return counts
+}
+
+// checkTypeLimitLocked: adapterType のアダプターをもう1台作れるか確認する（r.mu を持った状態で呼ぶ）
+//
+// replacing には差し替えで外れるロボットIDを渡します（同じタイプなら数に入れない）。
+// 新しく作る場合は空文字列を渡します。
+func (r *Registry) checkTypeLimitLocked(adapterType, replacing string) error {
+	limit, ok := r.typeLimits[adapterType]
+	if !ok {
+		return nil
+	}
+	count := 0
+	for robotID, t := range r.types {
+		if t == adapterType && robotID != replacing {
+			count++
+		}
+	}
+	if count >= limit {
+		return fmt.Errorf("%w: %s (%d of %d)", ErrAdapterTypeLimit, adapterType, count, limit)
+	}
+	return nil
+}
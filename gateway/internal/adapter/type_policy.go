@@ -0,0 +1,76 @@
+// =============================================================================
+// ファイル: type_policy.go
+// 概要: 作成してよいアダプタータイプの許可リスト／拒否リスト
+//
+// 【なぜ必要か？】
+// ファクトリを登録したタイプは、どれでも CreateAdapter で作れてしまいます。
+// 本番環境で誤ってモックロボット（"mock"）を立ち上げると、
+// 実機と区別のつかない偽のセンサーデータが流れてしまいます。
+// 設定でタイプを絞ることで、ファクトリが登録されていても作成を拒否できます。
+//
+// 【判定のルール】
+//
+//	拒否リストにある         → 拒否（許可リストより優先）
+//	許可リストが空           → 許可
+//	許可リストにある／ない   → 許可／拒否
+//
+// 拒否したタイプは ListFactories にも出しません（UI の選択肢から消すため）。
+// =============================================================================
+package adapter
+
+import (
+	"errors"
+
+	"go.uber.org/zap"
+)
+
+// ErrAdapterTypeNotAllowed - 設定で作成が許可されていないアダプタータイプを示すエラー
+//
+// CreateAdapter() と ReplaceAdapter() はこのエラーをラップして返します。
+// 呼び出し側は errors.Is(err, adapter.ErrAdapterTypeNotAllowed) で判定できます。
+var ErrAdapterTypeNotAllowed = errors.New("adapter type not allowed")
+
+// SetTypePolicy - 作成してよいアダプタータイプを設定する
+//
+// allow が空なら拒否リストにないすべてのタイプを許可します。
+// deny にあるタイプは allow にあっても拒否します。
+// アダプターを作成する前（起動時）に一度だけ呼んでください。
+func (r *Registry) SetTypePolicy(allow, deny []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.allowedTypes = toTypeSet(allow)
+	r.deniedTypes = toTypeSet(deny)
+	r.logger.Info("Adapter type policy configured",
+		zap.Strings("allow", allow),
+		zap.Strings("deny", deny),
+	)
+}
+
+// TypeAllowed - 指定したアダプタータイプの作成が許可されているか
+// ファクトリが登録されているかどうかは見ません。
+func (r *Registry) TypeAllowed(adapterType string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.typeAllowedLocked(adapterType)
+}
+
+// typeAllowedLocked: TypeAllowed の本体（r.mu を持った状態で呼ぶ）
+func (r *Registry) typeAllowedLocked(adapterType string) bool {
+	if r.deniedTypes[adapterType] {
+		return false
+	}
+	return len(r.allowedTypes) == 0 || r.allowedTypes[adapterType]
+}
+
+// toTypeSet: タイプ名の一覧を集合（map）にする（空なら nil）
+func toTypeSet(types []string) map[string]bool {
+	if len(types) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(types))
+	for _, t := range types {
+		set[t] = true
+	}
+	return set
+}
@@ -0,0 +1,124 @@
+// =============================================================================
+// ファイル: velocity_ramp.go
+// パッケージ: adapter（アダプターパッケージ）
+//
+// 【このファイルの概要】
+// 速度指令の「なめらか化（スムージング）」を行うヘルパーです。
+// 遅延やジッター（到着間隔のばらつき）のある回線で遠隔操作すると、
+// 速度指令が不規則に届き、ロボットの動きがカクカクします。
+// 新しい目標速度に一瞬で切り替えるのではなく、一定時間（ランプ時間）をかけて
+// 直線的に近づけることで、なめらかな動きを作ります。
+//
+//	速度
+//	 ↑        目標 ───────────
+//	 │       ／
+//	 │     ／   ← ランプ時間をかけて直線的に変化
+//	 │ ───
+//	 └──────────────────→ 時間
+//
+// 特定のロボットに依存しない処理なので adapter パッケージに置き、
+// モックだけでなく実機のアダプターからも再利用できるようにしています。
+// =============================================================================
+package adapter
+
+import (
+	"math"
+	"time"
+)
+
+// =============================================================================
+// Velocity - 平面移動ロボットの速度（並進2軸 + 回転）
+// =============================================================================
+type Velocity struct {
+	LinearX  float64 // 前進方向の速度（m/s）
+	LinearY  float64 // 横方向の速度（m/s）
+	AngularZ float64 // 回転速度（rad/s）
+}
+
+// =============================================================================
+// VelocityRamp - 目標速度へ一定時間かけて近づける補間器
+// =============================================================================
+//
+// 【使い方】
+//
+//	ramp := adapter.NewVelocityRamp(300 * time.Millisecond)
+//	ramp.SetTarget(adapter.Velocity{LinearX: 1.0})  // 速度指令を受けた時
+//	v := ramp.Step(50 * time.Millisecond)            // 制御周期ごとに呼ぶ
+//
+// 【スレッドセーフではない】
+// 内部でロックを取りません。呼び出し側（例: MockAdapter）が自分のミューテックスで
+// 保護している前提です。二重にロックを取らずに済むようにするためです。
+type VelocityRamp struct {
+	rampTime time.Duration
+	current  Velocity // 現在（補間後）の速度
+	target   Velocity // 目標速度
+	rate     Velocity // 各成分の1秒あたりの変化量（絶対値）
+}
+
+// NewVelocityRamp - ランプ時間を指定して補間器を作成する
+// rampTime が 0 以下の場合、SetTarget の速度がそのまま次の Step で反映されます。
+func NewVelocityRamp(rampTime time.Duration) *VelocityRamp {
+	return &VelocityRamp{rampTime: rampTime}
+}
+
+// =============================================================================
+// SetTarget - 新しい目標速度を設定する
+// =============================================================================
+//
+// 現在の速度から目標までの差を、ちょうど rampTime で埋める変化率を計算します。
+// 指令が届くたびに「今の速度」から改めてランプを始めるため、
+// 途中で目標が変わっても速度が飛ぶことはありません。
+func (r *VelocityRamp) SetTarget(v Velocity) {
+	r.target = v
+	if r.rampTime <= 0 {
+		return
+	}
+	sec := r.rampTime.Seconds()
+	r.rate = Velocity{
+		LinearX:  math.Abs(v.LinearX-r.current.LinearX) / sec,
+		LinearY:  math.Abs(v.LinearY-r.current.LinearY) / sec,
+		AngularZ: math.Abs(v.AngularZ-r.current.AngularZ) / sec,
+	}
+}
+
+// =============================================================================
+// Step - dt だけ時間を進め、補間後の速度を返す
+// =============================================================================
+func (r *VelocityRamp) Step(dt time.Duration) Velocity {
+	if r.rampTime <= 0 {
+		r.current = r.target
+		return r.current
+	}
+	sec := dt.Seconds()
+	r.current = Velocity{
+		LinearX:  approach(r.current.LinearX, r.target.LinearX, r.rate.LinearX*sec),
+		LinearY:  approach(r.current.LinearY, r.target.LinearY, r.rate.LinearY*sec),
+		AngularZ: approach(r.current.AngularZ, r.target.AngularZ, r.rate.AngularZ*sec),
+	}
+	return r.current
+}
+
+// Reset - 補間をやめ、現在速度と目標速度を v に即座に揃える
+// 緊急停止のように「なめらかさより即時性が大事」な場面で使います。
+func (r *VelocityRamp) Reset(v Velocity) {
+	r.current = v
+	r.target = v
+	r.rate = Velocity{}
+}
+
+// Current - 現在（補間後）の速度を返す
+func (r *VelocityRamp) Current() Velocity { return r.current }
+
+// Target - 目標速度を返す
+func (r *VelocityRamp) Target() Velocity { return r.target }
+
+// approach: from を to に向けて最大 maxDelta だけ動かす（行き過ぎない）
+func approach(from, to, maxDelta float64) float64 {
+	if math.Abs(to-from) <= maxDelta {
+		return to
+	}
+	if to > from {
+		return from + maxDelta
+	}
+	return from - maxDelta
+}
@@ -0,0 +1,388 @@
+// =============================================================================
+// ファイル: command_consumer.go（コマンドストリームのコンシューマー）
+// 概要: Redis Streams のコンシューマーグループで robot:commands を確実に処理するヘルパー
+//
+// 【なぜコンシューマーグループが必要？】
+//
+//	XRANGE / XREAD で読むだけだと、処理の途中でプロセスが落ちた時に
+//	「どこまで処理したか」が分からず、コマンドを取りこぼしたり二重に処理したりする。
+//	コンシューマーグループを使うと、Redis が「配送済みだが未確認（pending）」の
+//	エントリを覚えておいてくれるため、少なくとも1回（at-least-once）の処理を保証できる。
+//
+// 【処理の流れ】
+//
+//	XREADGROUP で受信 → コールバックで処理 → 成功したら XACK（確認）
+//	                                       └ 失敗したら XACK しない（pending に残る）
+//
+//	起動時には、前回 XACK できずに残った自分の pending エントリを先に処理し直す。
+//	さらに、落ちたまま戻らない別のコンシューマーの pending エントリも、
+//	一定時間（ClaimMinIdle）放置されていれば XAUTOCLAIM で引き取って処理する。
+//
+// 【使い方】
+//
+//	consumer, err := bridge.NewCommandConsumer(redisURL, "command-logger", "worker-1",
+//	    func(ctx context.Context, cmd bridge.CommandMessage) error {
+//	        return saveToDB(ctx, cmd) // nil を返すと XACK される
+//	    }, logger)
+//	consumer.Start(ctx, &wg) // ctx をキャンセルすると処理中の1件を終えてから停止する
+//	...
+//	wg.Wait()
+//	consumer.Close()
+//
+// 【注意: 少なくとも1回 ≠ ちょうど1回】
+//
+//	XACK の直前に落ちると、同じエントリがもう一度届く。
+//	コールバックは同じコマンドを2回処理しても問題ない（べき等な）作りにすること。
+//
+// =============================================================================
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// コンシューマーの既定値
+const (
+	consumerReadCount    = 10               // 1回の XREADGROUP で受け取る最大件数
+	consumerBlockTimeout = 2 * time.Second  // 新着を待つ最大時間（停止要求の確認間隔にもなる）
+	consumerRetryDelay   = time.Second      // Redis エラー時の再試行までの待ち時間
+	defaultClaimMinIdle  = 60 * time.Second // この時間以上放置された他者の pending を引き取る
+)
+
+// =============================================================================
+// CommandMessage: コマンドストリームの1エントリ
+//
+// RedisPublisher.PublishCommand が書き込んだ値を、元の型に戻したもの。
+// =============================================================================
+type CommandMessage struct {
+	ID        string         // ストリームのエントリID（例: "1739600000000-0"）
+	RobotID   string         // どのロボットへのコマンドか
+	Type      string         // コマンドの種類（例: "velocity"）
+	Timestamp int64          // コマンド発行時のタイムスタンプ（ミリ秒）
+	Payload   map[string]any // コマンドのデータ本体
+}
+
+// CommandHandlerFunc: 1件のコマンドを処理するコールバック
+// nil を返すとエントリは XACK され、エラーを返すと pending のまま残って後で再配送される。
+type CommandHandlerFunc func(ctx context.Context, cmd CommandMessage) error
+
+// CommandStreamClient: CommandConsumer が使う Redis の操作（*redis.Client が満たす）
+// テストでは Redis を立てずに XACK / pending の判断を確かめられるよう、偽の実装に差し替える。
+type CommandStreamClient interface {
+	XReadGroup(ctx context.Context, a *redis.XReadGroupArgs) *redis.XStreamSliceCmd
+	XAutoClaim(ctx context.Context, a *redis.XAutoClaimArgs) *redis.XAutoClaimCmd
+	XAck(ctx context.Context, stream, group string, ids ...string) *redis.IntCmd
+	Close() error
+}
+
+// =============================================================================
+// CommandConsumer: コンシューマーグループで robot:commands を読む構造体
+// =============================================================================
+type CommandConsumer struct {
+	client   CommandStreamClient
+	stream   string
+	group    string // コンシューマーグループ名（同じ名前のコンシューマー同士で仕事を分け合う）
+	consumer string // このプロセスのコンシューマー名（グループ内で一意にする）
+	handler  CommandHandlerFunc
+	logger   *zap.Logger
+
+	// ClaimMinIdle: 他のコンシューマーの pending をこの時間以上放置されていたら引き取る。
+	// 0 以下なら引き取りを行わない。Start() の前に変更すること。
+	ClaimMinIdle time.Duration
+}
+
+// =============================================================================
+// NewCommandConsumer: コマンドコンシューマーを作成するコンストラクタ関数
+//
+// NewRedisPublisher と同じく接続テスト（Ping）を行い、
+// さらにコンシューマーグループが無ければ作成する（ストリームが無ければそれも作る）。
+// =============================================================================
+func NewCommandConsumer(redisURL, group, consumer string, handler CommandHandlerFunc, logger *zap.Logger) (*CommandConsumer, error) {
+	if err := validateConsumerArgs(group, consumer, handler); err != nil {
+		return nil, err
+	}
+
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis URL: %w", err)
+	}
+	client := redis.NewClient(opts)
+
+	ctx := context.Background()
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("redis connection failed: %w", err)
+	}
+
+	// XGROUP CREATE ... MKSTREAM: "0" はストリームの先頭から読むという意味。
+	// 既にグループがある場合は BUSYGROUP エラーになるが、問題ないので無視する。
+	err = client.XGroupCreateMkStream(ctx, commandStream, group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		_ = client.Close()
+		return nil, fmt.Errorf("create consumer group %q: %w", group, err)
+	}
+
+	return NewCommandConsumerWithClient(client, group, consumer, handler, logger)
+}
+
+// NewCommandConsumerWithClient: 接続済みのクライアントからコマンドコンシューマーを作成する
+// 接続テストとグループの作成は行わないため、グループは作成済みであること。
+func NewCommandConsumerWithClient(client CommandStreamClient, group, consumer string, handler CommandHandlerFunc, logger *zap.Logger) (*CommandConsumer, error) {
+	if err := validateConsumerArgs(group, consumer, handler); err != nil {
+		return nil, err
+	}
+	return &CommandConsumer{
+		client:       client,
+		stream:       commandStream,
+		group:        group,
+		consumer:     consumer,
+		handler:      handler,
+		logger:       logger,
+		ClaimMinIdle: defaultClaimMinIdle,
+	}, nil
+}
+
+// validateConsumerArgs: コンストラクタの引数を検証する
+func validateConsumerArgs(group, consumer string, handler CommandHandlerFunc) error {
+	if group == "" || consumer == "" {
+		return fmt.Errorf("consumer group and consumer name must not be empty")
+	}
+	if handler == nil {
+		return fmt.Errorf("command handler must not be nil")
+	}
+	return nil
+}
+
+// =============================================================================
+// Start: コマンドを読み続けるゴルーチンを起動する
+//
+// ctx がキャンセルされると、処理中の1件を終えてから停止する（グレースフルシャットダウン）。
+// wg が nil でなければゴルーチンを登録し、終了時に Done() する。
+// =============================================================================
+func (c *CommandConsumer) Start(ctx context.Context, wg *sync.WaitGroup) {
+	if wg != nil {
+		wg.Add(1)
+	}
+	go func() {
+		if wg != nil {
+			defer wg.Done()
+		}
+		c.Run(ctx)
+	}()
+}
+
+// =============================================================================
+// Run: コマンドを読み続ける（ctx がキャンセルされるまで戻らない）
+//
+//  1. 自分の pending エントリ（前回 XACK できなかったもの）を処理し直す
+//  2. 放置された他者の pending エントリを引き取って処理する
+//  3. 新着エントリを待ち受けて処理する
+//
+// =============================================================================
+func (c *CommandConsumer) Run(ctx context.Context) {
+	c.logger.Info("Command consumer started",
+		zap.String("group", c.group),
+		zap.String("consumer", c.consumer),
+	)
+	defer c.logger.Info("Command consumer stopped", zap.String("consumer", c.consumer))
+
+	c.recoverPending(ctx)
+	c.claimStale(ctx)
+
+	for ctx.Err() == nil {
+		// ">" は「まだ誰にも配送されていない新着エントリ」を意味する
+		n, err := c.readAndProcess(ctx, ">")
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			c.logger.Warn("Failed to read command stream", zap.Error(err))
+			c.sleep(ctx, consumerRetryDelay)
+			continue
+		}
+		// 新着が無い時だけ、放置された pending がないかを確認する
+		if n == 0 {
+			c.claimStale(ctx)
+		}
+	}
+}
+
+// Close: Redis 接続を閉じる（Run が終了した後に呼ぶこと）
+func (c *CommandConsumer) Close() error {
+	return c.client.Close()
+}
+
+// recoverPending: 自分宛ての pending エントリを処理し直す
+//
+// XREADGROUP で ID に "0" を指定すると、新着ではなく
+// 「自分に配送済みで未確認のエントリ」が返る。空になるまで繰り返す。
+// 処理に失敗したエントリは pending に残り続けるため、1周で打ち切る。
+func (c *CommandConsumer) recoverPending(ctx context.Context) {
+	start := "0"
+	for ctx.Err() == nil {
+		streams, err := c.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    c.group,
+			Consumer: c.consumer,
+			Streams:  []string{c.stream, start},
+			Count:    consumerReadCount,
+		}).Result()
+		if err != nil {
+			if !errors.Is(err, redis.Nil) && ctx.Err() == nil {
+				c.logger.Warn("Failed to read pending commands", zap.Error(err))
+			}
+			return
+		}
+		if len(streams) == 0 || len(streams[0].Messages) == 0 {
+			return
+		}
+
+		messages := streams[0].Messages
+		c.logger.Info("Recovering pending commands", zap.Int("count", len(messages)))
+		c.processAll(ctx, messages)
+		// 次は今回の最後のエントリより後ろから読む（失敗して残ったものを読み直さないため）
+		start = messages[len(messages)-1].ID
+	}
+}
+
+// claimStale: 他のコンシューマーが放置した pending エントリを引き取って処理する
+func (c *CommandConsumer) claimStale(ctx context.Context) {
+	if c.ClaimMinIdle <= 0 {
+		return
+	}
+	messages, _, err := c.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   c.stream,
+		Group:    c.group,
+		Consumer: c.consumer,
+		MinIdle:  c.ClaimMinIdle,
+		Start:    "0-0",
+		Count:    consumerReadCount,
+	}).Result()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) && ctx.Err() == nil {
+			c.logger.Warn("Failed to claim stale commands", zap.Error(err))
+		}
+		return
+	}
+	if len(messages) > 0 {
+		c.logger.Info("Claimed stale pending commands", zap.Int("count", len(messages)))
+		c.processAll(ctx, messages)
+	}
+}
+
+// readAndProcess: 指定した ID から読み、処理した件数を返す
+func (c *CommandConsumer) readAndProcess(ctx context.Context, id string) (int, error) {
+	streams, err := c.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    c.group,
+		Consumer: c.consumer,
+		Streams:  []string{c.stream, id},
+		Count:    consumerReadCount,
+		Block:    consumerBlockTimeout,
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		// Block の時間内に新着が無かった
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, s := range streams {
+		c.processAll(ctx, s.Messages)
+		n += len(s.Messages)
+	}
+	return n, nil
+}
+
+// processAll: エントリを順に処理し、成功したものだけ XACK する
+//
+// 停止要求が来たら、残りのエントリには手を付けずに戻る（pending に残り、次回の起動時に処理される）。
+// 処理中の1件は最後まで終えられるよう、コールバックにはキャンセルされない context を渡す。
+func (c *CommandConsumer) processAll(ctx context.Context, messages []redis.XMessage) {
+	for _, m := range messages {
+		if ctx.Err() != nil {
+			return
+		}
+
+		cmd, err := parseCommandMessage(m)
+		if err != nil {
+			// 壊れたエントリは何度読んでも失敗するので、記録して確認済みにする
+			c.logger.Error("Malformed command entry, acknowledging to skip",
+				zap.String("id", m.ID),
+				zap.Error(err),
+			)
+			c.ack(m.ID)
+			continue
+		}
+
+		if err := c.handler(context.WithoutCancel(ctx), cmd); err != nil {
+			c.logger.Warn("Command handler failed, leaving entry pending",
+				zap.String("id", m.ID),
+				zap.String("robot_id", cmd.RobotID),
+				zap.Error(err),
+			)
+			continue
+		}
+		c.ack(m.ID)
+	}
+}
+
+// ack: エントリを処理済みとして XACK する
+//
+// 停止要求（ctx のキャンセル）の直後でも確認だけは届けたいので、
+// 呼び出し元の ctx ではなく短いタイムアウト付きの新しい context を使う。
+func (c *CommandConsumer) ack(id string) {
+	ackCtx, cancel := context.WithTimeout(context.Background(), consumerRetryDelay)
+	defer cancel()
+	if err := c.client.XAck(ackCtx, c.stream, c.group, id).Err(); err != nil {
+		c.logger.Warn("Failed to acknowledge command", zap.String("id", id), zap.Error(err))
+	}
+}
+
+// sleep: ctx がキャンセルされたら途中で起きる time.Sleep
+func (c *CommandConsumer) sleep(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
+
+// parseCommandMessage: ストリームのエントリを CommandMessage に変換する
+//
+// Redis のフィールド値は文字列として返ってくるため、
+// timestamp は数値に、payload は JSON（codec が msgpack なら MessagePack）から map に戻す。
+func parseCommandMessage(m redis.XMessage) (CommandMessage, error) {
+	cmd := CommandMessage{ID: m.ID}
+
+	cmd.RobotID, _ = m.Values["robot_id"].(string)
+	cmd.Type, _ = m.Values["type"].(string)
+	if cmd.RobotID == "" || cmd.Type == "" {
+		return cmd, fmt.Errorf("missing robot_id or type")
+	}
+
+	if ts, ok := m.Values["timestamp"].(string); ok && ts != "" {
+		v, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			return cmd, fmt.Errorf("invalid timestamp %q: %w", ts, err)
+		}
+		cmd.Timestamp = v
+	}
+
+	if raw, ok := m.Values["payload"].(string); ok && raw != "" {
+		if err := DecodePayload(m.Values, &cmd.Payload); err != nil {
+			return cmd, fmt.Errorf("invalid payload: %w", err)
+		}
+	}
+	return cmd, nil
+}
@@ -0,0 +1,68 @@
+// =============================================================================
+// ファイル: lock_store.go
+// 概要: 操作ロックを Redis に保存し、ゲートウェイの再起動後に読み戻す（safety.LockStore の実装）
+//
+// 【保存形式】
+//
+//	キー: "robot:op_lock:<robot_id>"（ロボットごとに1キー）
+//	値  : safety.PersistedLock の JSON
+//	TTL : OperationLock が渡す ttl（解放の書き込みを取りこぼしても、いずれ消える）
+//
+// 読み込みは SCAN でキーを列挙してから GET する（KEYS はサーバーを止めるので使わない）。
+// =============================================================================
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/robot-ai-webapp/gateway/internal/safety"
+	"go.uber.org/zap"
+)
+
+// opLockKeyPrefix: 操作ロックのキーの接頭辞
+const opLockKeyPrefix = "robot:op_lock:"
+
+// SaveLock: 操作ロックを保存する
+func (r *RedisPublisher) SaveLock(ctx context.Context, lock safety.PersistedLock, ttl time.Duration) error {
+	data, err := json.Marshal(lock)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, opLockKeyPrefix+lock.RobotID, data, ttl).Err()
+}
+
+// DeleteLock: 操作ロックを削除する（存在しなくてもエラーにしない）
+func (r *RedisPublisher) DeleteLock(ctx context.Context, robotID string) error {
+	return r.client.Del(ctx, opLockKeyPrefix+robotID).Err()
+}
+
+// LoadLocks: 保存されているすべての操作ロックを読み込む
+// 壊れた値は警告を出して読み飛ばす。
+func (r *RedisPublisher) LoadLocks(ctx context.Context) ([]safety.PersistedLock, error) {
+	var locks []safety.PersistedLock
+	iter := r.client.Scan(ctx, 0, opLockKeyPrefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		data, err := r.client.Get(ctx, key).Bytes()
+		if errors.Is(err, redis.Nil) {
+			continue // SCAN と GET の間に期限切れで消えた
+		}
+		if err != nil {
+			return nil, err
+		}
+		var lock safety.PersistedLock
+		if err := json.Unmarshal(data, &lock); err != nil || lock.RobotID == "" {
+			r.logger.Warn("Ignoring malformed operation lock in Redis", zap.String("key", key), zap.Error(err))
+			continue
+		}
+		locks = append(locks, lock)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	return locks, nil
+}
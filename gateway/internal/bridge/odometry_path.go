@@ -0,0 +1,124 @@
+// =============================================================================
+// ファイル: odometry_path.go
+// 概要: Redis のセンサーデータストリームから、ロボットが通った経路（オドメトリの軌跡）を読み出す
+//
+// 【なぜ必要か？】
+//
+//	UI で「ロボットがこれまで通った経路」を地図に重ねて表示したい時、
+//	フロントエンドが何分も sensor_data を購読して溜め続けるのは無駄が多い。
+//	ゲートウェイが既に Redis に記録しているオドメトリを時間範囲で読み出し、
+//	姿勢の列として1回で返す。
+//
+// 【読み出し方: XRANGE】
+//
+//	ストリームのエントリIDは "<ミリ秒>-<連番>" なので、時刻の範囲をそのまま
+//	ID の範囲として XRANGE に渡せる。ストリームには全ロボット・全トピックが
+//	混ざっているため、ページ単位で読みながら robot_id と topic で絞り込む。
+//
+// =============================================================================
+package bridge
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// odometryTopic: オドメトリのトピック名（モックアダプターの odom と同じ）
+const odometryTopic = "odom"
+
+// pathPageSize: XRANGE の1回あたりの読み出し件数
+// 範囲が広くても、一度に大量のエントリをメモリに載せないようにする。
+const pathPageSize = 1000
+
+// =============================================================================
+// PathPose: 経路上の1点（ある時刻の2次元姿勢）
+// =============================================================================
+type PathPose struct {
+	Timestamp int64   `json:"t"`     // センサーデータのタイムスタンプ（ミリ秒）
+	X         float64 `json:"x"`     // X座標（m）
+	Y         float64 `json:"y"`     // Y座標（m）
+	Theta     float64 `json:"theta"` // 向き（rad）
+}
+
+// =============================================================================
+// OdometryPath: 指定した時間範囲のオドメトリを古い順の姿勢の列で返すメソッド
+//
+// 引数:
+//
+//	robotID  : 対象のロボット
+//	from, to : 時間範囲（両端を含む）
+//	maxPoints: 返す点の上限。超える場合は等間隔に間引く（最初と最後の点は必ず残す）
+//
+// =============================================================================
+func (r *RedisPublisher) OdometryPath(ctx context.Context, robotID string, from, to time.Time, maxPoints int) ([]PathPose, error) {
+	start := strconv.FormatInt(from.UnixMilli(), 10)
+	end := strconv.FormatInt(to.UnixMilli(), 10)
+
+	var poses []PathPose
+	for {
+		entries, err := r.client.XRangeN(ctx, sensorDataStream, start, end, pathPageSize).Result()
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if pose, ok := parseOdometryEntry(e, robotID); ok {
+				poses = append(poses, pose)
+			}
+		}
+		if len(entries) < pathPageSize {
+			break
+		}
+		// 次のページは最後に読んだIDの直後から（"(" は「そのIDを含まない」）
+		start = "(" + entries[len(entries)-1].ID
+	}
+
+	return downsamplePath(poses, maxPoints), nil
+}
+
+// parseOdometryEntry: ストリームのエントリが対象ロボットのオドメトリなら姿勢に変換する
+func parseOdometryEntry(e redis.XMessage, robotID string) (PathPose, bool) {
+	if id, _ := e.Values["robot_id"].(string); id != robotID {
+		return PathPose{}, false
+	}
+	if topic, _ := e.Values["topic"].(string); topic != odometryTopic {
+		return PathPose{}, false
+	}
+
+	var data struct {
+		X     *float64 `json:"position_x"`
+		Y     *float64 `json:"position_y"`
+		Theta float64  `json:"orientation_z"`
+	}
+	if err := DecodePayload(e.Values, &data); err != nil || data.X == nil || data.Y == nil {
+		return PathPose{}, false
+	}
+
+	ts, _ := e.Values["timestamp"].(string)
+	timestamp, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return PathPose{}, false
+	}
+	return PathPose{Timestamp: timestamp, X: *data.X, Y: *data.Y, Theta: data.Theta}, true
+}
+
+// =============================================================================
+// downsamplePath: 点の数が maxPoints を超えていれば等間隔に間引く
+//
+// 最初と最後の点は必ず残す（経路の始点と現在地がずれないように）。
+// maxPoints が 2 未満の場合は間引かない。
+// =============================================================================
+func downsamplePath(poses []PathPose, maxPoints int) []PathPose {
+	if maxPoints < 2 || len(poses) <= maxPoints {
+		return poses
+	}
+
+	out := make([]PathPose, 0, maxPoints)
+	step := float64(len(poses)-1) / float64(maxPoints-1)
+	for i := 0; i < maxPoints; i++ {
+		out = append(out, poses[int(float64(i)*step+0.5)])
+	}
+	return out
+}
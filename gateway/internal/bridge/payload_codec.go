@@ -0,0 +1,96 @@
+// =============================================================================
+// ファイル: payload_codec.go（Redis レコードのペイロード形式）
+// 概要: Redis Streams に書くセンサーデータ・コマンドの payload のエンコード方式
+//
+// 【なぜ必要か？】
+//
+//	payload は JSON 文字列で保存してきたが、LiDAR の ranges のような
+//	数値の配列では、1つの float64 が「3.0512345678901234,」のような
+//	長い文字列になり、容量がかさむ。
+//	MessagePack なら float64 は常に 9 バイトで、値も丸めずにそのまま残る。
+//
+// 【レコードの形】
+//
+//	各レコードに "codec" フィールドを付け、payload の読み方を示す。
+//	"codec" のない古いレコードは JSON として読む。
+//
+//	codec     payload
+//	json      JSON 文字列（人が読める。既定）
+//	msgpack   MessagePack のバイト列
+//
+// 【設定（GATEWAY_REDIS_PAYLOAD_CODEC）】
+//
+//	json     すべて JSON（既定）
+//	msgpack  すべて MessagePack
+//	auto     数値の配列を含む payload（LiDAR の ranges など）だけ MessagePack、それ以外は JSON
+//
+// =============================================================================
+package bridge
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// PayloadCodec - Redis レコードの payload のエンコード方式（設定値）
+type PayloadCodec string
+
+const (
+	PayloadCodecJSON    PayloadCodec = "json"
+	PayloadCodecMsgpack PayloadCodec = "msgpack"
+	PayloadCodecAuto    PayloadCodec = "auto"
+)
+
+// ParsePayloadCodec - 設定の文字列を PayloadCodec に変換する（不明な値ならエラー）
+func ParsePayloadCodec(s string) (PayloadCodec, error) {
+	switch c := PayloadCodec(s); c {
+	case PayloadCodecJSON, PayloadCodecMsgpack, PayloadCodecAuto:
+		return c, nil
+	}
+	return "", fmt.Errorf("unknown redis payload codec %q (expected json, msgpack or auto)", s)
+}
+
+// EncodePayload: payload を codec でエンコードし、レコードの "codec" に書く名前と一緒に返す
+//
+// auto の場合は、数値の配列を含むときだけ MessagePack を使う。
+func EncodePayload(codec PayloadCodec, payload map[string]any) (name string, encoded []byte, err error) {
+	if codec == PayloadCodecMsgpack || (codec == PayloadCodecAuto && hasNumericArray(payload)) {
+		encoded, err = msgpack.Marshal(payload)
+		return string(PayloadCodecMsgpack), encoded, err
+	}
+	encoded, err = json.Marshal(payload)
+	return string(PayloadCodecJSON), encoded, err
+}
+
+// DecodePayload: レコードの "codec" と "payload" から v に復元する
+//
+// "codec" がなければ（この機能より前のレコード）JSON として読む。
+// MessagePack でも構造体の json タグで読めるようにしているため、
+// 呼び出し側は codec を気にせず同じ構造体を使えます。
+func DecodePayload(values map[string]any, v any) error {
+	raw, _ := values["payload"].(string)
+	codec, _ := values["codec"].(string)
+	switch PayloadCodec(codec) {
+	case "", PayloadCodecJSON:
+		return json.Unmarshal([]byte(raw), v)
+	case PayloadCodecMsgpack:
+		dec := msgpack.NewDecoder(bytes.NewReader([]byte(raw)))
+		dec.SetCustomStructTag("json")
+		return dec.Decode(v)
+	}
+	return fmt.Errorf("unknown payload codec %q", codec)
+}
+
+// hasNumericArray: payload の値に数値の配列が含まれているか
+func hasNumericArray(payload map[string]any) bool {
+	for _, v := range payload {
+		switch v.(type) {
+		case []float64, []float32, []int, []int64:
+			return true
+		}
+	}
+	return false
+}
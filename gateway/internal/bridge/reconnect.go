@@ -0,0 +1,102 @@
+// =============================================================================
+// ファイル: reconnect.go（Redis への再接続）
+// 概要: 起動時に Redis へ接続できなかった場合に、バックグラウンドで接続を試み続ける
+//
+// 【なぜ必要か？】
+// 起動時に Redis が落ちていると、ゲートウェイは Redis なし（degraded mode）で動き始めます。
+// 以前はその実行中ずっと永続化されず、Redis が後から起動しても記録は再開しませんでした。
+// Reconnector は一定間隔で接続（Ping）を試み、成功したら onConnect でパブリッシャーを渡します。
+// 呼び出し側（main）は、ハンドラーと配信ワーカーにそのパブリッシャーを設定して永続化を再開します。
+//
+// 【起動後に Redis が落ちた場合】
+// go-redis のクライアントはコマンドごとに接続し直すため、ここでは扱いません。
+// 落ちている間の書き込みは失敗し（ログに残るだけで配信は止まらない）、復旧すれば再開します。
+// =============================================================================
+package bridge
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Reconnector - Redis に接続できるまで、一定間隔で接続を試みる
+type Reconnector struct {
+	redisURL string
+	interval time.Duration
+	logger   *zap.Logger
+
+	attempts  atomic.Uint64
+	connected atomic.Pointer[RedisPublisher]
+}
+
+// NewReconnector - コンストラクタ（interval は接続を試みる間隔）
+func NewReconnector(redisURL string, interval time.Duration, logger *zap.Logger) *Reconnector {
+	return &Reconnector{
+		redisURL: redisURL,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Start - 接続を試みるゴルーチンを起動する
+//
+// 接続できたら onConnect を一度だけ呼んで終了します（onConnect の中でパブリッシャーを設定する）。
+// ctx がキャンセルされたら、接続できていなくても終了します。
+// wg が nil でなければゴルーチンを登録し、終了時に Done() します。
+func (r *Reconnector) Start(ctx context.Context, wg *sync.WaitGroup, onConnect func(*RedisPublisher)) {
+	if wg != nil {
+		wg.Add(1)
+	}
+	go func() {
+		if wg != nil {
+			defer wg.Done()
+		}
+		r.run(ctx, onConnect)
+	}()
+}
+
+// run: 接続できるか ctx がキャンセルされるまで、interval ごとに接続を試みる
+func (r *Reconnector) run(ctx context.Context, onConnect func(*RedisPublisher)) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		n := r.attempts.Add(1)
+		publisher, err := NewRedisPublisher(r.redisURL, r.logger)
+		if err != nil {
+			r.logger.Debug("Redis reconnect attempt failed", zap.Uint64("attempt", n), zap.Error(err))
+			continue
+		}
+		// シャットダウンと重なったら、渡さずに閉じる
+		if ctx.Err() != nil {
+			_ = publisher.Close()
+			return
+		}
+
+		onConnect(publisher)
+		r.connected.Store(publisher)
+		r.logger.Info("Redis is available again, persistence resumed", zap.Uint64("attempts", n))
+		return
+	}
+}
+
+// Publisher - 再接続で作ったパブリッシャー（まだ接続できていなければ nil）
+// シャットダウン時に Close() するために使います。
+func (r *Reconnector) Publisher() *RedisPublisher {
+	return r.connected.Load()
+}
+
+// Attempts - これまでに接続を試みた回数
+func (r *Reconnector) Attempts() uint64 {
+	return r.attempts.Load()
+}
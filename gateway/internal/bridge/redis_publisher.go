@@ -41,11 +41,6 @@ import (
 	// Redis への書き込みがタイムアウトした場合のキャンセルに使用。
 	"context"
 
-	// encoding/json: 構造体を JSON 文字列に変換するための標準ライブラリ。
-	// Redis Streams の Values にはプリミティブ型しか格納できないため、
-	// 複雑なデータ構造（map, slice）は JSON 文字列に変換して格納する。
-	"encoding/json"
-
 	// fmt: 文字列のフォーマットとエラーメッセージの生成。
 	// fmt.Errorf で詳細なエラーメッセージを作成する。
 	"fmt"
@@ -83,6 +78,7 @@ import (
 const (
 	sensorDataStream = "robot:sensor_data" // センサーデータを格納するストリーム名
 	commandStream    = "robot:commands"    // コマンドを格納するストリーム名
+	eventStream      = "robot:events"      // ロボットからのイベントを格納するストリーム名
 )
 
 // =============================================================================
@@ -103,6 +99,9 @@ const (
 type RedisPublisher struct {
 	client *redis.Client // Redis クライアント（接続管理やコマンド実行を担当）
 	logger *zap.Logger   // ログ出力器
+
+	// codec: payload のエンコード方式（payload_codec.go 参照、既定は JSON）
+	codec PayloadCodec
 }
 
 // =============================================================================
@@ -161,9 +160,20 @@ func NewRedisPublisher(redisURL string, logger *zap.Logger) (*RedisPublisher, er
 	return &RedisPublisher{
 		client: client,
 		logger: logger,
+		codec:  PayloadCodecJSON,
 	}, nil
 }
 
+// =============================================================================
+// SetPayloadCodec: payload のエンコード方式を設定するメソッド
+//
+// json（既定）、msgpack、auto（数値の配列を含む payload だけ msgpack）のいずれか。
+// 発行を始める前に一度だけ呼ぶ。
+// =============================================================================
+func (r *RedisPublisher) SetPayloadCodec(codec PayloadCodec) {
+	r.codec = codec
+}
+
 // =============================================================================
 // PublishSensorData: センサーデータを Redis Stream に発行するメソッド
 //
@@ -194,15 +204,16 @@ func NewRedisPublisher(redisURL string, logger *zap.Logger) (*RedisPublisher, er
 //
 // =============================================================================
 func (r *RedisPublisher) PublishSensorData(ctx context.Context, robotID string, data adapter.SensorData) error {
-	// data.Data（map型）を JSON 文字列に変換する。
+	// data.Data（map型）を JSON（または MessagePack）のバイト列に変換する。
 	// Redis Streams の Values にはプリミティブ型（文字列、数値）しか
-	// 直接格納できないため、複雑なデータは JSON に変換する必要がある。
+	// 直接格納できないため、複雑なデータはエンコードする必要がある。
+	// どちらで書いたかは "codec" フィールドに残し、読む側が判断できるようにする。
 	//
 	// 【Go言語の知識: json.Marshal】
 	//
 	//	構造体やマップを JSON バイト列に変換する。
 	//	例: map[string]any{"speed": 1.5} → []byte(`{"speed":1.5}`)
-	payload, err := json.Marshal(data.Data)
+	codec, payload, err := EncodePayload(r.codec, data.Data)
 	if err != nil {
 		return err
 	}
@@ -233,7 +244,8 @@ func (r *RedisPublisher) PublishSensorData(ctx context.Context, robotID string,
 			"data_type": data.DataType,   // データの種類（例: "lidar_scan", "imu"）
 			"frame_id":  data.FrameID,    // 座標フレーム（例: "laser_frame"）
 			"timestamp": data.Timestamp,  // データ取得時のタイムスタンプ
-			"payload":   string(payload), // JSON文字列化したセンサーデータ本体
+			"codec":     codec,           // payload のエンコード方式（"json" / "msgpack"）
+			"payload":   string(payload), // エンコードしたセンサーデータ本体
 		},
 	}).Err()
 }
@@ -255,8 +267,8 @@ func (r *RedisPublisher) PublishSensorData(ctx context.Context, robotID string,
 //
 // =============================================================================
 func (r *RedisPublisher) PublishCommand(ctx context.Context, robotID string, cmd adapter.Command) error {
-	// コマンドのペイロード（データ本体）を JSON 文字列に変換。
-	payload, err := json.Marshal(cmd.Payload)
+	// コマンドのペイロード（データ本体）をエンコードする（方式は PublishSensorData と同じ）。
+	codec, payload, err := EncodePayload(r.codec, cmd.Payload)
 	if err != nil {
 		return err
 	}
@@ -270,11 +282,51 @@ func (r *RedisPublisher) PublishCommand(ctx context.Context, robotID string, cmd
 			"robot_id":  robotID,         // どのロボットへのコマンドか
 			"type":      cmd.Type,        // コマンドの種類（例: "velocity_cmd"）
 			"timestamp": cmd.Timestamp,   // コマンド発行時のタイムスタンプ
-			"payload":   string(payload), // JSON文字列化したコマンドデータ
+			"codec":     codec,           // payload のエンコード方式（"json" / "msgpack"）
+			"payload":   string(payload), // エンコードしたコマンドデータ
 		},
 	}).Err()
 }
 
+// =============================================================================
+// PublishRobotEvent: ロボットからのイベントを Redis Stream に発行するメソッド
+//
+// バンパー接触や物理 E-Stop などのイベントを記録する。
+// センサーデータと違って件数が少なく、後から「いつ何が起きたか」を追うために使う。
+// =============================================================================
+func (r *RedisPublisher) PublishRobotEvent(ctx context.Context, robotID string, ev adapter.RobotEvent) error {
+	// イベントの追加情報をエンコードする（方式は PublishSensorData と同じ）。
+	codec, data, err := EncodePayload(r.codec, ev.Data)
+	if err != nil {
+		return err
+	}
+
+	return r.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: eventStream,
+		MaxLen: 10000, // 最大1万エントリを保持
+		Approx: true,
+		Values: map[string]interface{}{
+			"robot_id":  robotID,      // どのロボットのイベントか
+			"type":      ev.Type,      // イベントの種類（例: "bumper_hit"）
+			"severity":  ev.Severity,  // 重要度（info / warning / critical）
+			"message":   ev.Message,   // オペレーター向けの説明
+			"timestamp": ev.Timestamp, // イベントが起きた時刻（ナノ秒）
+			"codec":     codec,        // data のエンコード方式（"json" / "msgpack"）
+			"data":      string(data), // エンコードした追加情報
+		},
+	}).Err()
+}
+
+// =============================================================================
+// Ping: Redis が応答するかを確認するメソッド
+//
+// 起動時だけでなく実行中の疎通確認（ヘルス状態の問い合わせなど）に使う。
+// 応答がなければエラーを返す。
+// =============================================================================
+func (r *RedisPublisher) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
 // =============================================================================
 // Close: Redis 接続を閉じるメソッド
 //
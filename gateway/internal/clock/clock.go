@@ -0,0 +1,75 @@
+// =============================================================================
+// ファイル: clock.go（時計の抽象化）
+// 概要: 現在時刻の取得を差し替え可能にするパッケージ
+//
+// 【なぜ必要か？】
+//
+//	操作ロックの期限切れ、ウォッチドッグのタイムアウト、レート制限のウィンドウは、
+//	どれも「現在時刻」で動作が変わる。time.Now() を直接呼ぶと、テストで期限切れを
+//	確かめるには実際に待つ（time.Sleep）しかなく、遅くて不安定なテストになる。
+//
+//	各コンポーネントが Clock インターフェース経由で時刻を得るようにしておけば、
+//	本番では Real（実際の時計）、テストでは Mock（手で進める時計）を渡せる。
+//
+// 【使い方（テスト）】
+//
+//	clk := clock.NewMock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+//	lock := safety.NewOperationLock(5*time.Minute, logger)
+//	lock.SetClock(clk)
+//	clk.Advance(6 * time.Minute) // 待たずに 6 分進める → ロックは期限切れ
+//
+// =============================================================================
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock - 現在時刻を返すインターフェース
+type Clock interface {
+	Now() time.Time
+}
+
+// Real - 実際の時計（time.Now() をそのまま返す）。ゼロ値のまま使える。
+type Real struct{}
+
+// Now - 現在時刻を返す
+func (Real) Now() time.Time { return time.Now() }
+
+// =============================================================================
+// Mock - テスト用の手で進める時計
+// =============================================================================
+//
+// Set() や Advance() を呼ばない限り、時刻は進みません。
+// 複数のゴルーチンから同時に使っても安全です。
+type Mock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewMock - start の時刻で止まっている時計を作る
+func NewMock(start time.Time) *Mock {
+	return &Mock{now: start}
+}
+
+// Now - 時計の現在時刻を返す
+func (m *Mock) Now() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.now
+}
+
+// Set - 時計を t に合わせる
+func (m *Mock) Set(t time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.now = t
+}
+
+// Advance - 時計を d だけ進める
+func (m *Mock) Advance(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.now = m.now.Add(d)
+}
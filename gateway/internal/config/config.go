@@ -29,6 +29,18 @@
 package config
 
 import (
+	// fmt: 設定値が不正な場合のエラーメッセージ作成に使用。
+	"fmt"
+
+	// math: 設定値の数値が有限か（Inf でないか）の判定と、角度の変換（度 → ラジアン）に使用。
+	"math"
+
+	// strconv: 文字列から数値への変換（速度プリセットの解析）に使用。
+	"strconv"
+
+	// strings: 文字列操作の標準ライブラリ。カンマ区切りの設定値の分割に使用。
+	"strings"
+
 	// time: 時間に関する型と操作を提供する標準ライブラリ。
 	// time.Duration（期間）型を使って、タイムアウト値を表現する。
 	"time"
@@ -54,11 +66,13 @@ import (
 //
 // =============================================================================
 type Config struct {
-	Server  ServerConfig  // サーバー関連の設定（ポート番号など）
-	Redis   RedisConfig   // Redis関連の設定（接続URLなど）
-	Safety  SafetyConfig  // 安全機構関連の設定（速度制限など）
-	Auth    AuthConfig    // 認証関連の設定（JWT公開鍵のパスなど）
-	Logging LoggingConfig // ログ関連の設定（ログレベルなど）
+	Server     ServerConfig     // サーバー関連の設定（ポート番号など）
+	Redis      RedisConfig      // Redis関連の設定（接続URLなど）
+	Safety     SafetyConfig     // 安全機構関連の設定（速度制限など）
+	Navigation NavigationConfig // ナビゲーション関連の設定（座標系の変換など）
+	Auth       AuthConfig       // 認証関連の設定（JWT公開鍵のパスなど）
+	Logging    LoggingConfig    // ログ関連の設定（ログレベルなど）
+	Mock       MockConfig       // モックロボット関連の設定（センサーノイズなど）
 }
 
 // =============================================================================
@@ -80,6 +94,103 @@ type ServerConfig struct {
 	Port     int    `mapstructure:"port"`      // WebSocketサーバーのポート番号（例: 8080）
 	GRPCPort int    `mapstructure:"grpc_port"` // gRPCサーバーのポート番号（例: 50051）
 	Host     string `mapstructure:"host"`      // リッスンするホストアドレス（例: "0.0.0.0"）
+
+	// TrustedProxies: X-Forwarded-For を信用してよいプロキシのIP／CIDR一覧。
+	// 空（デフォルト）の場合はどのプロキシも信頼せず、接続元アドレスをそのまま使う。
+	// ロードバランサーの背後で動かすときだけ、そのアドレス帯を指定する。
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+
+	// SensorBatchWindowMs: センサーデータをまとめ送り（sensor_batch）する時間幅（ミリ秒）。
+	// まとめ送りを希望した購読にだけ適用される。0 ならまとめ送りを無効にする。
+	SensorBatchWindowMs int `mapstructure:"sensor_batch_window_ms"`
+
+	// SensorFanoutWorkers: センサーデータのエンコードと配信を行うワーカーの数。
+	// 同じロボットのデータは同じワーカーが処理する（順番を保つ）ため、ロボット数より多くしても速くならない。
+	SensorFanoutWorkers int `mapstructure:"sensor_fanout_workers"`
+	// SensorPersistQueue: Redis への永続化を待てるセンサーデータの数。
+	// Redis が遅れて満杯になったら捨て、WebSocket への配信は待たせない。
+	SensorPersistQueue int `mapstructure:"sensor_persist_queue"`
+
+	// TopicRemaps: ロボットごとのトピック名の付け替え（robot_id -> 内部のトピック名 -> クライアント向けの名前）
+	// アダプターのトピック名（例: "scan"）を、クライアントが期待する名前（例: "/robot1/laser"）で配信する。
+	TopicRemaps map[string]map[string]string `mapstructure:"topic_remaps"`
+
+	// SensorValidation: トピックごとのセンサーデータ検証のポリシー（topic -> off/flag/clamp/drop、"*" はその他すべて）
+	// 物理的にありえない値（NaN、範囲外のバッテリー残量や LiDAR の距離）をどう扱うかを決める。空なら検証しない。
+	SensorValidation map[string]string `mapstructure:"sensor_validation"`
+
+	// MaxArrayLen: センサーデータとコマンドの Payload に含まれる配列1つあたりの要素数の上限。
+	// ArrayLimitMode: 上限を超えた配列を含むセンサーデータの扱い（truncate / drop）。
+	// コマンドは常に拒否する。
+	MaxArrayLen    int    `mapstructure:"max_array_len"`
+	ArrayLimitMode string `mapstructure:"array_limit_mode"`
+
+	// DisabledRobotPauseSensors: 運用から外したロボット（set_robot_enabled）のセンサーデータの転送も止めるか。
+	// false なら、コマンドだけを拒否し、センサーデータはそのまま配信・記録する。
+	DisabledRobotPauseSensors bool `mapstructure:"disabled_robot_pause_sensors"`
+
+	// AppHeartbeatIntervalMs: server_heartbeat を全クライアントに送る間隔（ミリ秒、0 = 送らない）。
+	// AppHeartbeatTimeoutMs: この間メッセージ（heartbeat を含む）が1通も届かないクライアントを切断する（ミリ秒、0 = 切断しない）。
+	// WebSocket の Ping/Pong とは独立した、アプリケーション層の死活監視。
+	AppHeartbeatIntervalMs int `mapstructure:"app_heartbeat_interval_ms"`
+	AppHeartbeatTimeoutMs  int `mapstructure:"app_heartbeat_timeout_ms"`
+
+	// SelfTestEnabled: 起動時のセルフテストを実行するか。
+	// 有効なら、トラフィックを受け付ける前に速度コマンドとセンサーデータの経路を確認し、
+	// 失敗したら起動を中止する。
+	SelfTestEnabled bool `mapstructure:"selftest_enabled"`
+
+	// AdapterTypesAllow / AdapterTypesDeny: 作成してよいアダプタータイプの許可リストと拒否リスト。
+	// 許可リストが空ならすべて許可する。拒否リストは許可リストより優先される。
+	// 例: 本番では AdapterTypesDeny=["mock"] にしてモックロボットを作れないようにする。
+	AdapterTypesAllow []string `mapstructure:"adapter_types_allow"`
+	AdapterTypesDeny  []string `mapstructure:"adapter_types_deny"`
+
+	// AdapterTypeLimits: アダプタータイプごとの作成数の上限（タイプ -> 台数、ないタイプは無制限）。
+	// プロビジョニングの不具合でアダプターが際限なく作られるのを防ぐ。
+	AdapterTypeLimits map[string]int `mapstructure:"adapter_type_limits"`
+
+	// RobotGroups: ロボットごとの所属グループ（robot_id -> グループIDの一覧）。
+	// グループIDは "/" で区切って階層にでき、group_command で上位のグループを指定すると下位も含む。
+	RobotGroups map[string][]string `mapstructure:"robot_groups"`
+
+	// ClientErrorBudget: 何回連続でエラーになったらクライアントを切断するか。
+	// 0 ならエラーが続いても切断しない。緊急停止のメッセージはカウントしない。
+	ClientErrorBudget int `mapstructure:"client_error_budget"`
+
+	// WSReadBufferSize / WSWriteBufferSize: WebSocket の読み書きバッファ（バイト）。
+	// 接続ごとに確保されるため、大きくするとスループットは上がるがメモリも増える。
+	WSReadBufferSize  int `mapstructure:"ws_read_buffer_size"`
+	WSWriteBufferSize int `mapstructure:"ws_write_buffer_size"`
+
+	// ResumeBufferDepth: 切断中のセッションに、トピックごとに溜めておくメッセージの数。
+	// 猶予時間内に再接続したクライアントへ再送する。0 なら溜めない（再開も無効）。
+	ResumeBufferDepth int `mapstructure:"resume_buffer_depth"`
+	// ResumeGraceSec: 切断したセッションを再開できる時間（秒）
+	ResumeGraceSec int `mapstructure:"resume_grace_sec"`
+
+	// SessionRecordDir: 接続のメッセージを記録する（セッションレコーダー）ディレクトリ。空なら記録しない。
+	// SessionRecordAll: 全接続を記録するか。false なら管理者が record_session で指定した接続だけを記録する。
+	// SessionRecordMaxBytes / SessionRecordMaxFiles: 1ファイルの上限（バイト）と、残すファイル数。
+	SessionRecordDir      string `mapstructure:"session_record_dir"`
+	SessionRecordAll      bool   `mapstructure:"session_record_all"`
+	SessionRecordMaxBytes int64  `mapstructure:"session_record_max_bytes"`
+	SessionRecordMaxFiles int    `mapstructure:"session_record_max_files"`
+
+	// OverflowPolicies: 送信キューが満杯の時の、メッセージの種類ごとの方針（sensor / critical / bulk -> 方針）
+	// 種類ごとに選べる方針は validOverflowPolicies を参照。指定のない種類は drop_newest（以前の動作）。
+	// OverflowBlockMs: block の方針で、キューが空くのを待つ最長時間（ミリ秒）
+	OverflowPolicies map[string]string `mapstructure:"overflow_policies"`
+	OverflowBlockMs  int               `mapstructure:"overflow_block_ms"`
+
+	// AutoSubscribe: 認証時に自動で購読するロボットの範囲（none / single / all）。
+	// auth の "auto_subscribe" で接続ごとに上書きできる。
+	AutoSubscribe string `mapstructure:"auto_subscribe"`
+
+	// DebugHealthEnabled: ゴルーチンとチャネルの健全性を返す /debug/health を公開するか。
+	// DebugToken: /debug/health に必要な管理者用のトークン（Authorization: Bearer <token>）。
+	DebugHealthEnabled bool   `mapstructure:"debug_health_enabled"`
+	DebugToken         string `mapstructure:"debug_token"`
 }
 
 // =============================================================================
@@ -90,6 +201,14 @@ type ServerConfig struct {
 // =============================================================================
 type RedisConfig struct {
 	URL string `mapstructure:"url"` // Redis接続URL（例: "redis://localhost:6379/0"）
+
+	// PayloadCodec: ストリームに書く payload のエンコード方式（json / msgpack / auto）。
+	// auto は LiDAR の ranges のような数値の配列を含む payload だけ msgpack にする。
+	PayloadCodec string `mapstructure:"payload_codec"`
+
+	// ReconnectIntervalMs: 起動時に接続できなかった場合に、接続を試み直す間隔（ミリ秒）。
+	// 接続できたら永続化を始める。0 なら試み直さず、その実行中は Redis なしで動く。
+	ReconnectIntervalMs int `mapstructure:"reconnect_interval_ms"`
 }
 
 // =============================================================================
@@ -109,7 +228,114 @@ type SafetyConfig struct {
 	CommandTimeoutSec       int     `mapstructure:"cmd_timeout_sec"`            // コマンドのタイムアウト（秒）
 	MaxLinearVelocity       float64 `mapstructure:"max_linear_vel"`             // 直線速度の上限（m/s）
 	MaxAngularVelocity      float64 `mapstructure:"max_angular_vel"`            // 回転速度の上限（rad/s）
+	VelocityClampMode       string  `mapstructure:"velocity_clamp_mode"`        // 上限超過時の動作（"clamp" / "reject"）
 	OperationLockTimeoutSec int     `mapstructure:"operation_lock_timeout_sec"` // 操作ロックのタイムアウト（秒）
+
+	// OperationLockWarnSec: 操作ロックの期限切れの何秒前に保持者へ警告するか。0 で警告しない。
+	OperationLockWarnSec int `mapstructure:"operation_lock_warn_sec"`
+	// OperationLockMaxHoldSec: 1つの操作ロックを延長し続けられる最大時間（秒）。0 で無制限。
+	OperationLockMaxHoldSec int `mapstructure:"operation_lock_max_hold_sec"`
+	// OperationLockMaxPerUser: 1人のユーザーが同時に保持できる操作ロックの数。0 で無制限。
+	OperationLockMaxPerUser int `mapstructure:"operation_lock_max_per_user"`
+	// OperationLockExemptUsers: OperationLockMaxPerUser の対象外とするユーザーID（管理者など）
+	OperationLockExemptUsers []string `mapstructure:"operation_lock_exempt_users"`
+	// OperationLockPersist: 操作ロックを Redis に保存し、再起動後に引き継ぐか（Redis がなければメモリ上だけ）
+	OperationLockPersist bool `mapstructure:"operation_lock_persist"`
+	// OperationLockMode: ロックを持たずに操作コマンドを送った時の扱い（auto / strict）。
+	// auto はその場でロックを取得する（従来の動作）。strict は先に op_lock での取得を求め、lock_required で拒否する。
+	OperationLockMode string `mapstructure:"operation_lock_mode"`
+
+	// VelocityMinIntervalMs: ロボットごとに、速度コマンドをアダプターへ送る最小間隔（ミリ秒）。0 で無効。
+	// 間隔内に届いたコマンドはまとめられ（最新のものが勝つ）、間隔が経った時に送られる。
+	VelocityMinIntervalMs int `mapstructure:"velocity_min_interval_ms"`
+
+	// WatchdogRampDownMs: ウォッチドッグのタイムアウト時に、直前の速度から 0 まで下げる時間（ミリ秒）。
+	// 0 なら速度 0 を1回送って即座に止める（デフォルト）。
+	WatchdogRampDownMs int `mapstructure:"watchdog_ramp_down_ms"`
+
+	// AdapterCommandTimeoutMs: アダプターがコマンドを受け付けるまで待つ時間（ミリ秒）。
+	// 超えたら送信を諦め、クライアントに command_timeout を返す。
+	AdapterCommandTimeoutMs int `mapstructure:"adapter_cmd_timeout_ms"`
+
+	// CommandDedupWindowSec: 同じ command_id の再送を重複とみなす時間（秒）。0 で無効。
+	CommandDedupWindowSec int `mapstructure:"cmd_dedup_window_sec"`
+	// CommandDedupTypes: 重複排除の対象とするコマンド種別（例: "nav_goal", "dock"）
+	CommandDedupTypes []string `mapstructure:"cmd_dedup_types"`
+
+	// SensorStallSec: この秒数センサーデータが届かないトピックを「停止」とみなす。0 で無効。
+	SensorStallSec int `mapstructure:"sensor_stall_sec"`
+
+	// CollisionStopEnabled: LiDAR のスキャンで進行方向に近い障害物を見つけたら、自動で止めるか
+	// （GATEWAY_COLLISION_STOP_ROBOTS で指定したロボットは、そちらの設定が優先される）
+	CollisionStopEnabled bool `mapstructure:"collision_stop_enabled"`
+	// CollisionStopDistance: 進行方向の障害物がこの距離（m）より近ければ止める
+	CollisionStopDistance float64 `mapstructure:"collision_stop_distance"`
+	// CollisionStopConeDeg: 進行方向とみなす扇形の半分の角度（度）。30 なら進行方向 ±30°。
+	CollisionStopConeDeg float64 `mapstructure:"collision_stop_cone_deg"`
+	// CollisionStopAction: 止め方。estop は E-Stop を発動し、override は速度 0 で上書きする。
+	CollisionStopAction string `mapstructure:"collision_stop_action"`
+	// CollisionStopRobots: ロボットごとの有効・無効と停止距離（robot_id -> 設定）
+	CollisionStopRobots map[string]CollisionStopRobot `mapstructure:"collision_stop_robots"`
+
+	// StopOnLastDisconnect: ロボットを操作していたクライアントが切断し、他に操作者が
+	// いなければ、ウォッチドッグを待たずにすぐ停止コマンドを送るか
+	StopOnLastDisconnect bool `mapstructure:"stop_on_last_disconnect"`
+
+	// VelocityPresets: 名前付きの速度プリセット（例: "creep_forward" → 0.1 m/s で前進）
+	// クライアントは velocity_preset メッセージで名前を指定して呼び出す。
+	VelocityPresets map[string]VelocityPreset `mapstructure:"velocity_presets"`
+
+	// RobotAllowedCommands: ロボットごとに受け付けるコマンド種別（robot_id -> 種別の一覧）
+	// 指定のないロボットは、能力（Capabilities）が示すコマンドをすべて受け付ける。
+	RobotAllowedCommands map[string][]string `mapstructure:"robot_allowed_commands"`
+}
+
+// =============================================================================
+// VelocityPreset: 1つの速度プリセット（速度コマンドと同じ3成分）
+// =============================================================================
+type VelocityPreset struct {
+	LinearX  float64 // 前進方向の速度（m/s）
+	LinearY  float64 // 横方向の速度（m/s）
+	AngularZ float64 // 回転速度（rad/s）
+}
+
+// =============================================================================
+// CollisionStopRobot: 1台分の衝突リスク監視の設定
+// =============================================================================
+type CollisionStopRobot struct {
+	Enabled      bool    // このロボットを監視するか
+	StopDistance float64 // 停止距離（m）。0 なら GATEWAY_COLLISION_STOP_DISTANCE を使う。
+}
+
+// =============================================================================
+// NavigationConfig: ナビゲーション目標の座標系に関する設定を保持する構造体
+//
+// フロントエンドは表示用の座標系（例: "display"）で目標地点を送ることが多いが、
+// ロボットが理解するのは自分の座標系（例: "map"）だけ。
+// 座標系のペアごとに変換を設定しておき、ゲートウェイで一元的に変換する。
+// =============================================================================
+type NavigationConfig struct {
+	// TargetFrame: ロボットが使う座標系。目標地点はこの座標系に変換してから渡す。
+	TargetFrame string `mapstructure:"target_frame"`
+
+	// FrameTransforms: 座標系のペアごとの変換。To はすべて TargetFrame と同じであること。
+	FrameTransforms []FrameTransform `mapstructure:"frame_transforms"`
+}
+
+// =============================================================================
+// FrameTransform: 座標系 From から To へのアフィン変換（拡大縮小 → 回転 → 平行移動）
+//
+//	x' = Scale * (cos(Rotation) * x - sin(Rotation) * y) + TranslationX
+//	y' = Scale * (sin(Rotation) * x + cos(Rotation) * y) + TranslationY
+//
+// =============================================================================
+type FrameTransform struct {
+	From         string  // 変換元の座標系（目標の frame_id）
+	To           string  // 変換先の座標系（TargetFrame）
+	TranslationX float64 // X方向の平行移動（m）
+	TranslationY float64 // Y方向の平行移動（m）
+	Rotation     float64 // 回転（rad、反時計回りが正）
+	Scale        float64 // 拡大縮小の倍率（省略時は 1）
 }
 
 // =============================================================================
@@ -120,6 +346,13 @@ type SafetyConfig struct {
 // =============================================================================
 type AuthConfig struct {
 	JWTPublicKeyPath string `mapstructure:"jwt_public_key_path"` // JWT公開鍵ファイルのパス
+
+	// AdminUsers: 管理者のユーザーID。ログの購読（log_stream）など管理者専用の機能を使える。
+	AdminUsers []string `mapstructure:"admin_users"`
+
+	// ObserverUsers: 観察者（viewer）のユーザーID。observe_commands で、操作者が送ったコマンドの写しを受け取れる。
+	// 管理者は指定しなくても観察できる。
+	ObserverUsers []string `mapstructure:"observer_users"`
 }
 
 // =============================================================================
@@ -127,6 +360,42 @@ type AuthConfig struct {
 // =============================================================================
 type LoggingConfig struct {
 	Level string `mapstructure:"level"` // ログレベル（"debug", "info", "warn", "error"）
+
+	// ログのサンプリング（間引き）。高頻度で同じログが出る時に出力量を抑える。
+	// 1秒ごとに、同じメッセージの最初の SamplingInitial 件は出力し、
+	// 以降は SamplingThereafter 件に1件だけ出力する。Warn 以上は間引かない。
+	SamplingEnabled    bool `mapstructure:"sampling_enabled"`    // サンプリングを有効にするか
+	SamplingInitial    int  `mapstructure:"sampling_initial"`    // 1秒あたり最初に必ず出力する件数
+	SamplingThereafter int  `mapstructure:"sampling_thereafter"` // それ以降は何件に1件出力するか
+
+	// StreamBuffer: 管理者へのログ配信（log_stream）で、配信待ちにできるログの数。
+	// 超えた分は捨てる（ログを書く側を待たせない）。0 ならログ配信を無効にする。
+	StreamBuffer int `mapstructure:"stream_buffer"`
+}
+
+// =============================================================================
+// CommandDedupWindow: 重複排除のウィンドウを time.Duration 型で返すメソッド
+// =============================================================================
+func (s *SafetyConfig) CommandDedupWindow() time.Duration {
+	return time.Duration(s.CommandDedupWindowSec) * time.Second
+}
+
+// =============================================================================
+// MockConfig: 開発用モックロボットの設定を保持する構造体
+//
+// ML の学習データ生成用に、センサーノイズのプロファイルを切り替えられる。
+// NoiseProfileFile が空なら、モックは従来の一様ノイズで動作する。
+// =============================================================================
+type MockConfig struct {
+	NoiseProfileFile string `mapstructure:"noise_profile_file"` // ノイズプロファイルのファイル（JSON/YAML）
+	NoiseProfile     string `mapstructure:"noise_profile"`      // 使用するプロファイル名（空なら "default"）
+	VelocityRampMs   int    `mapstructure:"velocity_ramp_ms"`   // 速度指令を目標まで補間する時間（ミリ秒）。0 で無効
+	EnabledTopics    string `mapstructure:"enabled_topics"`     // 生成するセンサートピック（カンマ区切り）。空なら全て
+
+	// オドメトリのドリフト（すべて 0 ならドリフトなし）
+	OdomDriftPerMeter  float64 `mapstructure:"odom_drift_per_meter"`  // 距離の系統誤差（比率。0.02 = 2%）
+	OdomDriftPerRadian float64 `mapstructure:"odom_drift_per_radian"` // 回転の系統誤差（比率）
+	OdomDriftNoise     float64 `mapstructure:"odom_drift_noise"`      // ランダム誤差の標準偏差（移動量に対する比率）
 }
 
 // =============================================================================
@@ -159,6 +428,32 @@ func (s *SafetyConfig) CommandTimeout() time.Duration {
 	return time.Duration(s.CommandTimeoutSec) * time.Second
 }
 
+// =============================================================================
+// CollisionStopActive: 衝突リスクでの自動停止が、どれか1台でも有効かを返すメソッド
+// デフォルトで無効でも、GATEWAY_COLLISION_STOP_ROBOTS で個別に有効にしたロボットがあれば true。
+func (s *SafetyConfig) CollisionStopActive() bool {
+	if s.CollisionStopEnabled {
+		return true
+	}
+	for _, r := range s.CollisionStopRobots {
+		if r.Enabled {
+			return true
+		}
+	}
+	return false
+}
+
+// CollisionStopConeRad: 進行方向とみなす扇形の半分の角度をラジアンで返すメソッド
+func (s *SafetyConfig) CollisionStopConeRad() float64 {
+	return s.CollisionStopConeDeg * math.Pi / 180
+}
+
+// SensorStallTimeout: センサー停止とみなすまでの時間を time.Duration 型で返すメソッド
+// =============================================================================
+func (s *SafetyConfig) SensorStallTimeout() time.Duration {
+	return time.Duration(s.SensorStallSec) * time.Second
+}
+
 // =============================================================================
 // OperationLockTimeout: 操作ロックタイムアウトを time.Duration 型で返すメソッド
 //
@@ -204,25 +499,117 @@ func Load() (*Config, error) {
 	v.AutomaticEnv()
 
 	// --- サーバー設定のデフォルト値 ---
-	v.SetDefault("GATEWAY_PORT", 8080)       // WebSocketのデフォルトポート
-	v.SetDefault("GATEWAY_GRPC_PORT", 50051) // gRPCのデフォルトポート
-	v.SetDefault("GATEWAY_HOST", "0.0.0.0")  // 全ネットワークインターフェースでリッスン
+	v.SetDefault("GATEWAY_PORT", 8080)                 // WebSocketのデフォルトポート
+	v.SetDefault("GATEWAY_GRPC_PORT", 50051)           // gRPCのデフォルトポート
+	v.SetDefault("GATEWAY_HOST", "0.0.0.0")            // 全ネットワークインターフェースでリッスン
+	v.SetDefault("GATEWAY_TRUSTED_PROXIES", "")        // デフォルトはプロキシを信頼しない（最も安全）
+	v.SetDefault("GATEWAY_SENSOR_BATCH_WINDOW_MS", 50) // 50ms 分のサンプルをまとめて送る
+	v.SetDefault("GATEWAY_SENSOR_FANOUT_WORKERS", 4)   // 配信ワーカーは4つ
+	v.SetDefault("GATEWAY_SENSOR_PERSIST_QUEUE", 1024) // Redis 待ちは1024件まで
+	v.SetDefault("GATEWAY_TOPIC_REMAP", "")            // トピック名はアダプターのまま配信する
+	v.SetDefault("GATEWAY_SENSOR_VALIDATION", "")      // センサーデータは検証しない
+	v.SetDefault("GATEWAY_SELFTEST_ENABLED", true)     // 起動時にセルフテストを実行する
+	v.SetDefault("GATEWAY_ADAPTER_TYPES_ALLOW", "")    // すべてのアダプタータイプを許可する
+	v.SetDefault("GATEWAY_ADAPTER_TYPES_DENY", "")     // 拒否するタイプはない
+	v.SetDefault("GATEWAY_CLIENT_ERROR_BUDGET", 20)    // 20回連続でエラーなら切断する
+	v.SetDefault("GATEWAY_WS_READ_BUFFER_SIZE", 4096)  // 読みバッファ 4KB/接続
+	v.SetDefault("GATEWAY_WS_WRITE_BUFFER_SIZE", 4096) // 書きバッファ 4KB/接続
+	v.SetDefault("GATEWAY_RESUME_BUFFER_DEPTH", 0)     // 切断中のデータは溜めない（メモリを使わない）
+	v.SetDefault("GATEWAY_RESUME_GRACE_SEC", 10)       // 10秒以内の再接続なら再開できる
+	v.SetDefault("GATEWAY_AUTO_SUBSCRIBE", "single")   // auth の robot_id のロボットだけを購読する
+
+	// セッションの記録
+	v.SetDefault("GATEWAY_SESSION_RECORD_DIR", "")           // 記録しない
+	v.SetDefault("GATEWAY_SESSION_RECORD_ALL", false)        // record_session で指定した接続だけを記録する
+	v.SetDefault("GATEWAY_SESSION_RECORD_MAX_BYTES", 64<<20) // 1ファイル 64MB まで
+	v.SetDefault("GATEWAY_SESSION_RECORD_MAX_FILES", 10)     // 新しい10ファイルを残す
+
+	// 配列の長さの上限
+	v.SetDefault("GATEWAY_MAX_ARRAY_LEN", 100000)        // 配列は10万要素まで（点群でも余裕のある値）
+	v.SetDefault("GATEWAY_ARRAY_LIMIT_MODE", "truncate") // 上限を超えた配列は切り詰めて配信する
+
+	// 運用から外したロボットは、センサーデータの転送も止める
+	v.SetDefault("GATEWAY_DISABLED_ROBOT_PAUSE_SENSORS", true)
+
+	// アダプタータイプごとの作成数の上限
+	v.SetDefault("GATEWAY_ADAPTER_TYPE_LIMITS", "") // 上限なし
+
+	// ロボットのグループ
+	v.SetDefault("GATEWAY_ROBOT_GROUPS", "") // グループなし
+
+	// アプリケーション層のハートビート
+	v.SetDefault("GATEWAY_APP_HEARTBEAT_INTERVAL_MS", 15000) // 15秒ごとに server_heartbeat を送る
+	v.SetDefault("GATEWAY_APP_HEARTBEAT_TIMEOUT_MS", 0)      // クライアントのハートビートは求めない
+
+	// 送信キューが満杯の時の方針（センサーデータは古いものから捨て、アラートや ACK は黙って捨てない）
+	v.SetDefault("GATEWAY_OVERFLOW_POLICY", "sensor=drop_oldest,critical=disconnect,bulk=drop_newest")
+	v.SetDefault("GATEWAY_OVERFLOW_BLOCK_MS", 50) // 50ms 待っても空かなければ切断する
+
+	// 診断用エンドポイント（/debug/health）
+	v.SetDefault("GATEWAY_DEBUG_HEALTH_ENABLED", false) // /debug/health は公開しない
+	v.SetDefault("GATEWAY_DEBUG_TOKEN", "")             // トークンなし
 
 	// --- 安全機構のデフォルト値 ---
 	v.SetDefault("GATEWAY_ESTOP_ENABLED", true)             // 緊急停止はデフォルト有効
 	v.SetDefault("GATEWAY_CMD_TIMEOUT_SEC", 3)              // 3秒のコマンドタイムアウト
+	v.SetDefault("GATEWAY_WATCHDOG_RAMP_DOWN_MS", 0)        // タイムアウトしたら即座に止める
+	v.SetDefault("GATEWAY_ADAPTER_CMD_TIMEOUT_MS", 2000)    // アダプターの応答を2秒まで待つ
 	v.SetDefault("GATEWAY_MAX_LINEAR_VEL", 1.0)             // 直線速度上限 1.0 m/s
 	v.SetDefault("GATEWAY_MAX_ANGULAR_VEL", 2.0)            // 回転速度上限 2.0 rad/s
+	v.SetDefault("GATEWAY_VELOCITY_CLAMP_MODE", "clamp")    // 上限を超えたら上限まで下げて実行する
+	v.SetDefault("GATEWAY_VELOCITY_MIN_INTERVAL_MS", 0)     // 速度コマンドは受け付けたらすぐ送る
 	v.SetDefault("GATEWAY_OPERATION_LOCK_TIMEOUT_SEC", 300) // ロックは5分（300秒）で自動解除
+	v.SetDefault("GATEWAY_OPERATION_LOCK_WARN_SEC", 30)     // 期限切れの30秒前に保持者へ警告
+	v.SetDefault("GATEWAY_OPERATION_LOCK_MAX_HOLD_SEC", 0)  // 延長の上限なし
+	v.SetDefault("GATEWAY_OPERATION_LOCK_MAX_PER_USER", 0)  // 1人が保持できるロックの数は無制限
+	v.SetDefault("GATEWAY_OPERATION_LOCK_EXEMPT_USERS", "") // 上限の対象外のユーザーはなし
+	v.SetDefault("GATEWAY_OPERATION_LOCK_PERSIST", false)   // ロックは再起動で消える（従来どおり）
+	v.SetDefault("GATEWAY_OPERATION_LOCK_MODE", "auto")     // コマンドを送ればロックを取得する（従来どおり）
+	v.SetDefault("GATEWAY_CMD_DEDUP_WINDOW_SEC", 30)        // 30秒以内の同じ command_id は再送とみなす
+	// 二重実行が危険なコマンドだけを対象にする（速度コマンドは次の指令で上書きされるため対象外）
+	v.SetDefault("GATEWAY_CMD_DEDUP_TYPES", "nav_goal,dock,undock")
+	v.SetDefault("GATEWAY_SENSOR_STALL_SEC", 5)            // 5秒データが届かないトピックは停止とみなす
+	v.SetDefault("GATEWAY_COLLISION_STOP_ENABLED", false)  // 衝突リスクでの自動停止は明示的に有効にする
+	v.SetDefault("GATEWAY_COLLISION_STOP_DISTANCE", 0.5)   // 進行方向 0.5m 以内の障害物で止める
+	v.SetDefault("GATEWAY_COLLISION_STOP_CONE_DEG", 30)    // 進行方向 ±30° を見る
+	v.SetDefault("GATEWAY_COLLISION_STOP_ACTION", "estop") // E-Stop で止める
+	v.SetDefault("GATEWAY_COLLISION_STOP_ROBOTS", "")      // ロボットごとの設定はなし
+	v.SetDefault("GATEWAY_STOP_ON_LAST_DISCONNECT", true)  // 操作者の切断ですぐ停止する
+	v.SetDefault("GATEWAY_ROBOT_ALLOWED_COMMANDS", "")     // 能力が示すコマンドをすべて受け付ける
+	// 速度プリセットはデプロイごとに定義する（デフォルトはなし）
+	v.SetDefault("GATEWAY_VELOCITY_PRESETS", "")
+
+	// --- ナビゲーションのデフォルト値 ---
+	v.SetDefault("GATEWAY_NAV_TARGET_FRAME", "map")  // ロボットは地図座標系で目標を受け取る
+	v.SetDefault("GATEWAY_NAV_FRAME_TRANSFORMS", "") // 変換なし（他の座標系の目標は拒否）
 
 	// --- 認証のデフォルト値 ---
 	v.SetDefault("JWT_PUBLIC_KEY_PATH", "/app/keys/public.pem") // JWT公開鍵のパス
+	v.SetDefault("GATEWAY_ADMIN_USERS", "")                     // 管理者なし（管理者専用の機能は使えない）
+	v.SetDefault("GATEWAY_OBSERVER_USERS", "")                  // 観察者なし（コマンドを観察できるのは管理者だけ）
 
 	// --- ログのデフォルト値 ---
-	v.SetDefault("GATEWAY_LOG_LEVEL", "info") // デフォルトは info レベル
+	v.SetDefault("GATEWAY_LOG_LEVEL", "info")            // デフォルトは info レベル
+	v.SetDefault("GATEWAY_LOG_SAMPLING_ENABLED", false)  // デフォルトは間引かない
+	v.SetDefault("GATEWAY_LOG_SAMPLING_INITIAL", 100)    // 1秒あたり最初の100件は出力
+	v.SetDefault("GATEWAY_LOG_SAMPLING_THEREAFTER", 100) // 以降は100件に1件
+	v.SetDefault("GATEWAY_LOG_STREAM_BUFFER", 1024)      // 配信待ちのログは1024件まで
+
+	// --- モックロボットのデフォルト値 ---
+	v.SetDefault("GATEWAY_MOCK_NOISE_PROFILE_FILE", "")     // ノイズプロファイルなし（一様ノイズ）
+	v.SetDefault("GATEWAY_MOCK_NOISE_PROFILE", "")          // プロファイル名（空なら "default"）
+	v.SetDefault("GATEWAY_MOCK_VELOCITY_RAMP_MS", 0)        // 速度のなめらか化なし（即座に反映）
+	v.SetDefault("GATEWAY_MOCK_ENABLED_TOPICS", "")         // 全トピック（odom, scan, imu, battery）
+	v.SetDefault("GATEWAY_MOCK_ODOM_DRIFT_PER_METER", 0.0)  // ドリフトなし
+	v.SetDefault("GATEWAY_MOCK_ODOM_DRIFT_PER_RADIAN", 0.0) // ドリフトなし
+	v.SetDefault("GATEWAY_MOCK_ODOM_DRIFT_NOISE", 0.0)      // ドリフトなし
 
 	// --- Redis のデフォルト値 ---
 	v.SetDefault("REDIS_URL", "redis://localhost:6379/0") // ローカルのRedisに接続
+	v.SetDefault("GATEWAY_REDIS_PAYLOAD_CODEC", "json")   // 人が読める JSON で保存する
+
+	// 起動時に Redis へ接続できなかったら、5秒ごとに接続を試み直す
+	v.SetDefault("GATEWAY_REDIS_RECONNECT_INTERVAL_MS", 5000)
 
 	// 設定構造体を作成して値を設定する。
 	// 【Go言語の知識: 複合リテラル（Composite Literal）】
@@ -234,24 +621,166 @@ func Load() (*Config, error) {
 			Port:     v.GetInt("GATEWAY_PORT"),      // 環境変数 or デフォルト値からポートを取得
 			GRPCPort: v.GetInt("GATEWAY_GRPC_PORT"), // gRPCポートを取得
 			Host:     v.GetString("GATEWAY_HOST"),   // ホストアドレスを取得
+			// カンマ区切りの文字列（例: "10.0.0.0/8,127.0.0.1"）をスライスに分割
+			TrustedProxies: splitList(v.GetString("GATEWAY_TRUSTED_PROXIES")),
+			// センサーデータのまとめ送りの時間幅
+			SensorBatchWindowMs: v.GetInt("GATEWAY_SENSOR_BATCH_WINDOW_MS"),
+			SensorFanoutWorkers: v.GetInt("GATEWAY_SENSOR_FANOUT_WORKERS"),
+			SensorPersistQueue:  v.GetInt("GATEWAY_SENSOR_PERSIST_QUEUE"),
+			// 配列の長さの上限
+			MaxArrayLen:    v.GetInt("GATEWAY_MAX_ARRAY_LEN"),
+			ArrayLimitMode: v.GetString("GATEWAY_ARRAY_LIMIT_MODE"),
+			// 運用から外したロボットのセンサーデータ
+			DisabledRobotPauseSensors: v.GetBool("GATEWAY_DISABLED_ROBOT_PAUSE_SENSORS"),
+			// アプリケーション層のハートビート
+			AppHeartbeatIntervalMs: v.GetInt("GATEWAY_APP_HEARTBEAT_INTERVAL_MS"),
+			AppHeartbeatTimeoutMs:  v.GetInt("GATEWAY_APP_HEARTBEAT_TIMEOUT_MS"),
+			// 起動時のセルフテストの有無
+			SelfTestEnabled: v.GetBool("GATEWAY_SELFTEST_ENABLED"),
+			// 作成してよいアダプタータイプ（カンマ区切り）
+			AdapterTypesAllow: splitList(v.GetString("GATEWAY_ADAPTER_TYPES_ALLOW")),
+			AdapterTypesDeny:  splitList(v.GetString("GATEWAY_ADAPTER_TYPES_DENY")),
+			// クライアントごとのエラーバジェット
+			ClientErrorBudget: v.GetInt("GATEWAY_CLIENT_ERROR_BUDGET"),
+			// WebSocket の読み書きバッファサイズ
+			WSReadBufferSize:  v.GetInt("GATEWAY_WS_READ_BUFFER_SIZE"),
+			WSWriteBufferSize: v.GetInt("GATEWAY_WS_WRITE_BUFFER_SIZE"),
+			// 短い切断からの再開
+			ResumeBufferDepth: v.GetInt("GATEWAY_RESUME_BUFFER_DEPTH"),
+			ResumeGraceSec:    v.GetInt("GATEWAY_RESUME_GRACE_SEC"),
+			AutoSubscribe:     v.GetString("GATEWAY_AUTO_SUBSCRIBE"),
+			OverflowBlockMs:   v.GetInt("GATEWAY_OVERFLOW_BLOCK_MS"),
+			// セッションの記録
+			SessionRecordDir:      v.GetString("GATEWAY_SESSION_RECORD_DIR"),
+			SessionRecordAll:      v.GetBool("GATEWAY_SESSION_RECORD_ALL"),
+			SessionRecordMaxBytes: v.GetInt64("GATEWAY_SESSION_RECORD_MAX_BYTES"),
+			SessionRecordMaxFiles: v.GetInt("GATEWAY_SESSION_RECORD_MAX_FILES"),
+			// 診断用エンドポイント
+			DebugHealthEnabled: v.GetBool("GATEWAY_DEBUG_HEALTH_ENABLED"),
+			DebugToken:         v.GetString("GATEWAY_DEBUG_TOKEN"),
 		},
 		Redis: RedisConfig{
-			URL: v.GetString("REDIS_URL"), // Redis接続URLを取得
+			URL:          v.GetString("REDIS_URL"), // Redis接続URLを取得
+			PayloadCodec: v.GetString("GATEWAY_REDIS_PAYLOAD_CODEC"),
+			// 起動時に接続できなかった場合の再接続
+			ReconnectIntervalMs: v.GetInt("GATEWAY_REDIS_RECONNECT_INTERVAL_MS"),
 		},
 		Safety: SafetyConfig{
-			EStopEnabled:            v.GetBool("GATEWAY_ESTOP_ENABLED"),             // bool型で取得
-			CommandTimeoutSec:       v.GetInt("GATEWAY_CMD_TIMEOUT_SEC"),            // int型で取得
-			MaxLinearVelocity:       v.GetFloat64("GATEWAY_MAX_LINEAR_VEL"),         // float64型で取得
-			MaxAngularVelocity:      v.GetFloat64("GATEWAY_MAX_ANGULAR_VEL"),        // float64型で取得
-			OperationLockTimeoutSec: v.GetInt("GATEWAY_OPERATION_LOCK_TIMEOUT_SEC"), // int型で取得
+			EStopEnabled:             v.GetBool("GATEWAY_ESTOP_ENABLED"),              // bool型で取得
+			CommandTimeoutSec:        v.GetInt("GATEWAY_CMD_TIMEOUT_SEC"),             // int型で取得
+			MaxLinearVelocity:        v.GetFloat64("GATEWAY_MAX_LINEAR_VEL"),          // float64型で取得
+			MaxAngularVelocity:       v.GetFloat64("GATEWAY_MAX_ANGULAR_VEL"),         // float64型で取得
+			VelocityClampMode:        v.GetString("GATEWAY_VELOCITY_CLAMP_MODE"),      // string型で取得
+			OperationLockTimeoutSec:  v.GetInt("GATEWAY_OPERATION_LOCK_TIMEOUT_SEC"),  // int型で取得
+			OperationLockWarnSec:     v.GetInt("GATEWAY_OPERATION_LOCK_WARN_SEC"),     // int型で取得
+			OperationLockMaxHoldSec:  v.GetInt("GATEWAY_OPERATION_LOCK_MAX_HOLD_SEC"), // int型で取得
+			OperationLockMaxPerUser:  v.GetInt("GATEWAY_OPERATION_LOCK_MAX_PER_USER"), // int型で取得
+			OperationLockExemptUsers: splitList(v.GetString("GATEWAY_OPERATION_LOCK_EXEMPT_USERS")),
+			OperationLockPersist:     v.GetBool("GATEWAY_OPERATION_LOCK_PERSIST"),
+			OperationLockMode:        v.GetString("GATEWAY_OPERATION_LOCK_MODE"),
+			VelocityMinIntervalMs:    v.GetInt("GATEWAY_VELOCITY_MIN_INTERVAL_MS"),
+			WatchdogRampDownMs:       v.GetInt("GATEWAY_WATCHDOG_RAMP_DOWN_MS"),
+			AdapterCommandTimeoutMs:  v.GetInt("GATEWAY_ADAPTER_CMD_TIMEOUT_MS"),
+			CommandDedupWindowSec:    v.GetInt("GATEWAY_CMD_DEDUP_WINDOW_SEC"), // int型で取得
+			CommandDedupTypes:        splitList(v.GetString("GATEWAY_CMD_DEDUP_TYPES")),
+			SensorStallSec:           v.GetInt("GATEWAY_SENSOR_STALL_SEC"), // int型で取得
+			CollisionStopEnabled:     v.GetBool("GATEWAY_COLLISION_STOP_ENABLED"),
+			CollisionStopDistance:    v.GetFloat64("GATEWAY_COLLISION_STOP_DISTANCE"),
+			CollisionStopConeDeg:     v.GetFloat64("GATEWAY_COLLISION_STOP_CONE_DEG"),
+			CollisionStopAction:      v.GetString("GATEWAY_COLLISION_STOP_ACTION"),
+			StopOnLastDisconnect:     v.GetBool("GATEWAY_STOP_ON_LAST_DISCONNECT"),
 		},
 		Auth: AuthConfig{
 			JWTPublicKeyPath: v.GetString("JWT_PUBLIC_KEY_PATH"), // 文字列で取得
+			AdminUsers:       splitList(v.GetString("GATEWAY_ADMIN_USERS")),
+			ObserverUsers:    splitList(v.GetString("GATEWAY_OBSERVER_USERS")),
 		},
 		Logging: LoggingConfig{
-			Level: v.GetString("GATEWAY_LOG_LEVEL"), // ログレベルを取得
+			Level:              v.GetString("GATEWAY_LOG_LEVEL"), // ログレベルを取得
+			SamplingEnabled:    v.GetBool("GATEWAY_LOG_SAMPLING_ENABLED"),
+			SamplingInitial:    v.GetInt("GATEWAY_LOG_SAMPLING_INITIAL"),
+			SamplingThereafter: v.GetInt("GATEWAY_LOG_SAMPLING_THEREAFTER"),
+			StreamBuffer:       v.GetInt("GATEWAY_LOG_STREAM_BUFFER"),
 		},
+		Mock: MockConfig{
+			NoiseProfileFile:   v.GetString("GATEWAY_MOCK_NOISE_PROFILE_FILE"),
+			NoiseProfile:       v.GetString("GATEWAY_MOCK_NOISE_PROFILE"),
+			VelocityRampMs:     v.GetInt("GATEWAY_MOCK_VELOCITY_RAMP_MS"),
+			EnabledTopics:      v.GetString("GATEWAY_MOCK_ENABLED_TOPICS"),
+			OdomDriftPerMeter:  v.GetFloat64("GATEWAY_MOCK_ODOM_DRIFT_PER_METER"),
+			OdomDriftPerRadian: v.GetFloat64("GATEWAY_MOCK_ODOM_DRIFT_PER_RADIAN"),
+			OdomDriftNoise:     v.GetFloat64("GATEWAY_MOCK_ODOM_DRIFT_NOISE"),
+		},
+	}
+
+	// 上限超過時の動作は "clamp" か "reject" のどちらか（誤記で意図しない動作にならないよう起動を失敗させる）
+	if m := cfg.Safety.VelocityClampMode; m != "clamp" && m != "reject" {
+		return nil, fmt.Errorf("invalid GATEWAY_VELOCITY_CLAMP_MODE %q: expected clamp or reject", m)
+	}
+
+	// 速度プリセットの解析（書式が不正なら起動を失敗させる）
+	presets, err := parseVelocityPresets(v.GetString("GATEWAY_VELOCITY_PRESETS"))
+	if err != nil {
+		return nil, err
 	}
+	cfg.Safety.VelocityPresets = presets
+
+	// ロボットごとのコマンドのホワイトリストの解析（書式やコマンド種別が不正なら起動を失敗させる）
+	allowed, err := parseAllowedCommands(v.GetString("GATEWAY_ROBOT_ALLOWED_COMMANDS"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Safety.RobotAllowedCommands = allowed
+
+	// ロボットごとの衝突リスク監視の設定の解析（書式や距離が不正なら起動を失敗させる）
+	collisionRobots, err := parseCollisionStopRobots(v.GetString("GATEWAY_COLLISION_STOP_ROBOTS"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Safety.CollisionStopRobots = collisionRobots
+
+	// トピック名の付け替えの解析（書式が不正なら起動を失敗させる）
+	remaps, err := parseTopicRemaps(v.GetString("GATEWAY_TOPIC_REMAP"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Server.TopicRemaps = remaps
+
+	// センサーデータ検証のポリシーの解析（書式やポリシー名が不正なら起動を失敗させる）
+	// 送信キューが満杯の時の方針の解析（書式が不正なら起動を失敗させる。方針の値は Validate で確認する）
+	overflow, err := parseOverflowPolicies(v.GetString("GATEWAY_OVERFLOW_POLICY"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Server.OverflowPolicies = overflow
+
+	validation, err := parseSensorValidation(v.GetString("GATEWAY_SENSOR_VALIDATION"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Server.SensorValidation = validation
+
+	// アダプタータイプごとの上限の解析（書式や台数が不正なら起動を失敗させる）
+	limits, err := parseAdapterTypeLimits(v.GetString("GATEWAY_ADAPTER_TYPE_LIMITS"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Server.AdapterTypeLimits = limits
+
+	// ロボットのグループの解析（書式やグループIDが不正なら起動を失敗させる）
+	groups, err := parseRobotGroups(v.GetString("GATEWAY_ROBOT_GROUPS"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Server.RobotGroups = groups
+
+	// 座標変換の解析（書式が不正、または変換先が TargetFrame でなければ起動を失敗させる）
+	cfg.Navigation.TargetFrame = v.GetString("GATEWAY_NAV_TARGET_FRAME")
+	transforms, err := parseFrameTransforms(v.GetString("GATEWAY_NAV_FRAME_TRANSFORMS"), cfg.Navigation.TargetFrame)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Navigation.FrameTransforms = transforms
 
 	// 設定とnil（エラーなし）を呼び出し元に返す。
 	// 【Go言語の知識: 多値返却】
@@ -260,3 +789,322 @@ func Load() (*Config, error) {
 	//	エラーがない場合は nil を返すのが Go の慣例。
 	return cfg, nil
 }
+
+// =============================================================================
+// splitList: カンマ区切りの文字列をスライスに分割するヘルパー関数
+//
+// 前後の空白を取り除き、空の要素は捨てます。
+// 例: " 10.0.0.0/8, 127.0.0.1 ," → ["10.0.0.0/8", "127.0.0.1"]
+// =============================================================================
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// =============================================================================
+// parseVelocityPresets: 速度プリセットの設定文字列を解析するヘルパー関数
+//
+// 書式: "名前=linear_x:linear_y:angular_z" をカンマで区切って並べる。
+// 例: "creep_forward=0.1:0:0, spin_left=0:0:0.5"
+//
+// 空文字列ならプリセットなし（空のマップ）を返す。
+// =============================================================================
+func parseVelocityPresets(s string) (map[string]VelocityPreset, error) {
+	presets := make(map[string]VelocityPreset)
+	for _, item := range splitList(s) {
+		name, spec, ok := strings.Cut(item, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid velocity preset %q: expected name=linear_x:linear_y:angular_z", item)
+		}
+
+		parts := strings.Split(spec, ":")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid velocity preset %q: expected 3 values", name)
+		}
+		var values [3]float64
+		for i, p := range parts {
+			f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid velocity preset %q: %w", name, err)
+			}
+			values[i] = f
+		}
+		presets[name] = VelocityPreset{LinearX: values[0], LinearY: values[1], AngularZ: values[2]}
+	}
+	return presets, nil
+}
+
+// =============================================================================
+// parseAllowedCommands: ロボットごとのコマンドのホワイトリストを解析するヘルパー関数
+//
+// 書式: "ロボットID=種別|種別" をカンマで区切って並べる。種別が空ならどのコマンドも受け付けない。
+// 例: "station-1=, robot-2=velocity|nav_goal|nav_cancel"
+//
+// 種別は allowedCommandTypes のいずれか。空文字列なら制限なし（空のマップ）を返す。
+// =============================================================================
+func parseAllowedCommands(s string) (map[string][]string, error) {
+	allowed := make(map[string][]string)
+	for _, item := range splitList(s) {
+		robotID, spec, ok := strings.Cut(item, "=")
+		robotID = strings.TrimSpace(robotID)
+		if !ok || robotID == "" {
+			return nil, fmt.Errorf("invalid allowed commands %q: expected robot_id=command|command", item)
+		}
+
+		commands := []string{}
+		for _, command := range strings.Split(spec, "|") {
+			if command = strings.TrimSpace(command); command == "" {
+				continue
+			}
+			if !allowedCommandTypes[command] {
+				return nil, fmt.Errorf("invalid allowed commands %q: unknown command %q", item, command)
+			}
+			commands = append(commands, command)
+		}
+		allowed[robotID] = commands
+	}
+	return allowed, nil
+}
+
+// =============================================================================
+// parseCollisionStopRobots: ロボットごとの衝突リスク監視の設定を解析するヘルパー関数
+//
+// 書式: "ロボットID=設定" をカンマで区切って並べる。設定は on / off / 停止距離（m）のいずれか。
+// 例: "robot-1=0.8, robot-2=off, robot-3=on"
+//
+// 停止距離を指定したロボットは監視が有効になる。on は有効にしてデフォルトの停止距離を使う。
+// 空文字列なら個別の設定なし（空のマップ）を返す。
+// =============================================================================
+func parseCollisionStopRobots(s string) (map[string]CollisionStopRobot, error) {
+	robots := make(map[string]CollisionStopRobot)
+	for _, item := range splitList(s) {
+		robotID, spec, ok := strings.Cut(item, "=")
+		robotID, spec = strings.TrimSpace(robotID), strings.TrimSpace(spec)
+		if !ok || robotID == "" || spec == "" {
+			return nil, fmt.Errorf("invalid collision stop robot %q: expected robot_id=on|off|distance", item)
+		}
+
+		switch spec {
+		case "on":
+			robots[robotID] = CollisionStopRobot{Enabled: true}
+		case "off":
+			robots[robotID] = CollisionStopRobot{Enabled: false}
+		default:
+			distance, err := strconv.ParseFloat(spec, 64)
+			if err != nil || !(distance > 0) || math.IsInf(distance, 0) {
+				return nil, fmt.Errorf("invalid collision stop robot %q: expected on, off or a positive distance", item)
+			}
+			robots[robotID] = CollisionStopRobot{Enabled: true, StopDistance: distance}
+		}
+	}
+	return robots, nil
+}
+
+// allowedCommandTypes: GATEWAY_ROBOT_ALLOWED_COMMANDS に指定できるコマンド種別
+var allowedCommandTypes = map[string]bool{
+	"velocity": true, "nav_goal": true, "nav_cancel": true,
+	"dock": true, "undock": true, "reset_pose": true, "reset_error": true,
+}
+
+// =============================================================================
+// parseTopicRemaps: トピック名の付け替えの設定文字列を解析するヘルパー関数
+//
+// 書式: "ロボットID:内部のトピック名=クライアント向けの名前" をカンマで区切って並べる。
+// 例: "mock-robot-1:scan=/robot1/laser, mock-robot-1:odom=/robot1/odom"
+//
+// 同じロボット×トピックを二度指定するとエラー。空文字列なら付け替えなし（空のマップ）を返す。
+// =============================================================================
+func parseTopicRemaps(s string) (map[string]map[string]string, error) {
+	remaps := make(map[string]map[string]string)
+	for _, item := range splitList(s) {
+		source, target, ok := strings.Cut(item, "=")
+		robotID, topic, okSource := strings.Cut(source, ":")
+		robotID, topic, target = strings.TrimSpace(robotID), strings.TrimSpace(topic), strings.TrimSpace(target)
+		if !ok || !okSource || robotID == "" || topic == "" || target == "" {
+			return nil, fmt.Errorf("invalid topic remap %q: expected robot_id:topic=client_topic", item)
+		}
+
+		robot, exists := remaps[robotID]
+		if !exists {
+			robot = make(map[string]string)
+			remaps[robotID] = robot
+		}
+		if _, dup := robot[topic]; dup {
+			return nil, fmt.Errorf("invalid topic remap %q: topic %q of robot %q is remapped twice", item, topic, robotID)
+		}
+		robot[topic] = target
+	}
+	return remaps, nil
+}
+
+// =============================================================================
+// parseSensorValidation: センサーデータ検証のポリシーの設定文字列を解析するヘルパー関数
+//
+// 書式: "トピック名=ポリシー" をカンマで区切って並べる。トピック名 "*" はその他すべてのトピック。
+// ポリシーは off（検証しない）、flag（印を付けて通す）、clamp（範囲内に丸める）、drop（捨てる）のいずれか。
+// 例: "battery=clamp, scan=flag, *=drop"
+//
+// 同じトピックを二度指定するとエラー。空文字列なら検証なし（空のマップ）を返す。
+// =============================================================================
+func parseSensorValidation(s string) (map[string]string, error) {
+	policies := make(map[string]string)
+	for _, item := range splitList(s) {
+		topic, policy, ok := strings.Cut(item, "=")
+		topic, policy = strings.TrimSpace(topic), strings.TrimSpace(policy)
+		if !ok || topic == "" {
+			return nil, fmt.Errorf("invalid sensor validation %q: expected topic=policy", item)
+		}
+		switch policy {
+		case "off", "flag", "clamp", "drop":
+		default:
+			return nil, fmt.Errorf("invalid sensor validation %q: policy must be off, flag, clamp or drop", item)
+		}
+		if _, dup := policies[topic]; dup {
+			return nil, fmt.Errorf("invalid sensor validation %q: topic %q is configured twice", item, topic)
+		}
+		policies[topic] = policy
+	}
+	return policies, nil
+}
+
+// =============================================================================
+// parseOverflowPolicies: 送信キューが満杯の時の方針の設定文字列を解析するヘルパー関数
+//
+// 書式: "種類=方針" をカンマで区切って並べる。種類は sensor / critical / bulk のいずれか。
+// 例: "sensor=drop_oldest, critical=block, bulk=drop_newest"
+//
+// 同じ種類を二度指定するとエラー。種類ごとに選べる方針は Validate で確認する。
+// =============================================================================
+func parseOverflowPolicies(s string) (map[string]string, error) {
+	policies := make(map[string]string)
+	for _, item := range splitList(s) {
+		class, policy, ok := strings.Cut(item, "=")
+		class, policy = strings.TrimSpace(class), strings.TrimSpace(policy)
+		if !ok || class == "" || policy == "" {
+			return nil, fmt.Errorf("invalid overflow policy %q: expected class=policy", item)
+		}
+		if _, known := validOverflowPolicies[class]; !known {
+			return nil, fmt.Errorf("invalid overflow policy %q: class must be sensor, critical or bulk", item)
+		}
+		if _, dup := policies[class]; dup {
+			return nil, fmt.Errorf("invalid overflow policy %q: class %q is configured twice", item, class)
+		}
+		policies[class] = policy
+	}
+	return policies, nil
+}
+
+// =============================================================================
+// parseAdapterTypeLimits: アダプタータイプごとの作成数の上限を解析するヘルパー関数
+//
+// 書式: "タイプ=台数" をカンマで区切って並べる。台数は1以上の整数。
+// 例: "ros2=100, mock=5"
+//
+// 同じタイプを二度指定するとエラー。空文字列なら上限なし（空のマップ）を返す。
+// =============================================================================
+func parseAdapterTypeLimits(s string) (map[string]int, error) {
+	limits := make(map[string]int)
+	for _, item := range splitList(s) {
+		adapterType, count, ok := strings.Cut(item, "=")
+		adapterType = strings.TrimSpace(adapterType)
+		if !ok || adapterType == "" {
+			return nil, fmt.Errorf("invalid adapter type limit %q: expected type=count", item)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(count))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid adapter type limit %q: count must be a positive integer", item)
+		}
+		if _, dup := limits[adapterType]; dup {
+			return nil, fmt.Errorf("invalid adapter type limit %q: type %q is configured twice", item, adapterType)
+		}
+		limits[adapterType] = n
+	}
+	return limits, nil
+}
+
+// =============================================================================
+// parseRobotGroups: ロボットごとの所属グループを解析するヘルパー関数
+//
+// 書式: "ロボットID=グループ|グループ" をカンマで区切って並べる。グループIDは "/" で階層にできる。
+// 例: "robot-1=site-1/zone-a, robot-2=site-1/zone-a|team-red"
+//
+// 同じロボットを二度指定する、グループが空、階層の区切りが空（"a//b" や "/a"）だとエラー。
+// 空文字列ならグループなし（空のマップ）を返す。
+// =============================================================================
+func parseRobotGroups(s string) (map[string][]string, error) {
+	groups := make(map[string][]string)
+	for _, item := range splitList(s) {
+		robotID, spec, ok := strings.Cut(item, "=")
+		robotID = strings.TrimSpace(robotID)
+		if !ok || robotID == "" {
+			return nil, fmt.Errorf("invalid robot groups %q: expected robot_id=group|group", item)
+		}
+		if _, dup := groups[robotID]; dup {
+			return nil, fmt.Errorf("invalid robot groups %q: robot %q is configured twice", item, robotID)
+		}
+
+		var ids []string
+		for _, id := range strings.Split(spec, "|") {
+			id = strings.TrimSpace(id)
+			if id == "" || strings.HasPrefix(id, "/") || strings.HasSuffix(id, "/") || strings.Contains(id, "//") {
+				return nil, fmt.Errorf("invalid robot groups %q: group ID must be non-empty names separated by /", item)
+			}
+			ids = append(ids, id)
+		}
+		groups[robotID] = ids
+	}
+	return groups, nil
+}
+
+// =============================================================================
+// parseFrameTransforms: 座標変換の設定文字列を解析するヘルパー関数
+//
+// 書式: "変換元->変換先=translation_x:translation_y:rotation[:scale]" をカンマで区切って並べる。
+// 例: "display->map=1.5:-2:1.5708, sim->map=0:0:0:0.01"
+//
+// 変換先は targetFrame（ロボットの座標系）でなければならない。
+// 空文字列なら変換なし（nil）を返す。
+// =============================================================================
+func parseFrameTransforms(s, targetFrame string) ([]FrameTransform, error) {
+	var transforms []FrameTransform
+	for _, item := range splitList(s) {
+		pair, spec, ok := strings.Cut(item, "=")
+		from, to, okPair := strings.Cut(pair, "->")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok || !okPair || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid frame transform %q: expected from->to=tx:ty:rotation[:scale]", item)
+		}
+		if to != targetFrame {
+			return nil, fmt.Errorf("invalid frame transform %q: target frame must be %q", item, targetFrame)
+		}
+
+		parts := strings.Split(spec, ":")
+		if len(parts) != 3 && len(parts) != 4 {
+			return nil, fmt.Errorf("invalid frame transform %q: expected 3 or 4 values", item)
+		}
+		values := []float64{0, 0, 0, 1} // scale の省略時は 1
+		for i, p := range parts {
+			f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid frame transform %q: %w", item, err)
+			}
+			values[i] = f
+		}
+		if values[3] <= 0 {
+			return nil, fmt.Errorf("invalid frame transform %q: scale must be positive", item)
+		}
+
+		transforms = append(transforms, FrameTransform{
+			From: from, To: to,
+			TranslationX: values[0], TranslationY: values[1],
+			Rotation: values[2], Scale: values[3],
+		})
+	}
+	return transforms, nil
+}
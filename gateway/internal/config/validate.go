@@ -0,0 +1,160 @@
+// =============================================================================
+// ファイル: validate.go
+// 概要: 読み込んだ設定値の範囲と組み合わせを検証する
+//
+// 【なぜ必要か？】
+//
+//	Load() は環境変数の「書式」は確かめるが、「値として妥当か」は確かめない。
+//	例えば GATEWAY_CMD_TIMEOUT_SEC=0 だと、ウォッチドッグが速度コマンドのたびに
+//	即座にタイムアウトしてロボットを止め続ける。起動はするのに動かない、
+//	という原因のわかりにくい状態になる。
+//
+//	Validate() で起動時にまとめて検証し、どの設定がなぜ不正かを示して起動を失敗させる。
+//
+// =============================================================================
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// validLogLevels: GATEWAY_LOG_LEVEL に指定できる値（initLogger が解釈するもの）
+var validLogLevels = map[string]bool{"debug": true, "info": true, "warn": true, "error": true}
+
+// validAutoSubscribeModes: GATEWAY_AUTO_SUBSCRIBE に指定できる値（server.AutoSubscribeMode）
+var validAutoSubscribeModes = map[string]bool{"none": true, "single": true, "all": true}
+
+// validOperationLockModes: GATEWAY_OPERATION_LOCK_MODE に指定できる値（server.LockMode）
+var validOperationLockModes = map[string]bool{"auto": true, "strict": true}
+
+// validArrayLimitModes: GATEWAY_ARRAY_LIMIT_MODE に指定できる値（safety.ArrayLimitMode）
+var validArrayLimitModes = map[string]bool{"truncate": true, "drop": true}
+
+// validOverflowPolicies: GATEWAY_OVERFLOW_POLICY の種類ごとに指定できる方針（server.OverflowPolicy）
+// critical（安全アラートや ACK）は黙って捨てないよう drop_oldest を選べない。
+var validOverflowPolicies = map[string]map[string]bool{
+	"sensor":   {"drop_oldest": true, "drop_newest": true, "disconnect": true},
+	"critical": {"block": true, "disconnect": true, "drop_newest": true},
+	"bulk":     {"drop_newest": true, "disconnect": true},
+}
+
+// validCollisionStopActions: GATEWAY_COLLISION_STOP_ACTION に指定できる値（safety.CollisionAction）
+var validCollisionStopActions = map[string]bool{"estop": true, "override": true}
+
+// validRedisPayloadCodecs: GATEWAY_REDIS_PAYLOAD_CODEC に指定できる値（bridge.PayloadCodec）
+var validRedisPayloadCodecs = map[string]bool{"json": true, "msgpack": true, "auto": true}
+
+// =============================================================================
+// Validate: 設定値の範囲と組み合わせを検証するメソッド
+//
+// 不正な設定をすべて集め、環境変数名と理由を並べた1つのエラーとして返す
+// （1つ直して再起動したら次のエラー、という繰り返しを避けるため）。
+// すべて妥当なら nil を返す。main() で Load() の直後に呼ぶ。
+//
+// 【Go言語の知識: errors.Join】
+//
+//	複数のエラーを1つにまとめる（Go 1.20 以降）。
+//	Error() は各エラーのメッセージを改行でつないだ文字列を返す。
+//
+// =============================================================================
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	// --- サーバー ---
+	s := c.Server
+	check(s.Port > 0 && s.Port <= 65535, "GATEWAY_PORT must be between 1 and 65535, got %d", s.Port)
+	check(s.GRPCPort > 0 && s.GRPCPort <= 65535, "GATEWAY_GRPC_PORT must be between 1 and 65535, got %d", s.GRPCPort)
+	check(s.Port != s.GRPCPort, "GATEWAY_PORT and GATEWAY_GRPC_PORT must differ, both are %d", s.Port)
+	check(s.SensorBatchWindowMs >= 0, "GATEWAY_SENSOR_BATCH_WINDOW_MS must not be negative, got %d", s.SensorBatchWindowMs)
+	check(s.SensorFanoutWorkers > 0, "GATEWAY_SENSOR_FANOUT_WORKERS must be positive, got %d", s.SensorFanoutWorkers)
+	check(s.SensorPersistQueue > 0, "GATEWAY_SENSOR_PERSIST_QUEUE must be positive, got %d", s.SensorPersistQueue)
+	check(s.MaxArrayLen > 0, "GATEWAY_MAX_ARRAY_LEN must be positive, got %d", s.MaxArrayLen)
+	check(validArrayLimitModes[s.ArrayLimitMode], "GATEWAY_ARRAY_LIMIT_MODE must be one of truncate, drop, got %q", s.ArrayLimitMode)
+	check(s.ClientErrorBudget >= 0, "GATEWAY_CLIENT_ERROR_BUDGET must not be negative, got %d", s.ClientErrorBudget)
+	check(s.AppHeartbeatIntervalMs >= 0, "GATEWAY_APP_HEARTBEAT_INTERVAL_MS must not be negative, got %d", s.AppHeartbeatIntervalMs)
+	check(s.AppHeartbeatTimeoutMs >= 0, "GATEWAY_APP_HEARTBEAT_TIMEOUT_MS must not be negative, got %d", s.AppHeartbeatTimeoutMs)
+	check(s.AppHeartbeatTimeoutMs == 0 || s.AppHeartbeatIntervalMs == 0 || s.AppHeartbeatTimeoutMs > s.AppHeartbeatIntervalMs,
+		"GATEWAY_APP_HEARTBEAT_TIMEOUT_MS (%d) must be greater than GATEWAY_APP_HEARTBEAT_INTERVAL_MS (%d)", s.AppHeartbeatTimeoutMs, s.AppHeartbeatIntervalMs)
+	check(s.WSReadBufferSize >= 0, "GATEWAY_WS_READ_BUFFER_SIZE must not be negative, got %d", s.WSReadBufferSize)
+	check(s.WSWriteBufferSize >= 0, "GATEWAY_WS_WRITE_BUFFER_SIZE must not be negative, got %d", s.WSWriteBufferSize)
+	check(s.ResumeBufferDepth >= 0, "GATEWAY_RESUME_BUFFER_DEPTH must not be negative, got %d", s.ResumeBufferDepth)
+	// 溜めても再開できる時間がなければ、メモリを使うだけになる
+	check(s.ResumeBufferDepth == 0 || s.ResumeGraceSec > 0,
+		"GATEWAY_RESUME_GRACE_SEC must be positive when GATEWAY_RESUME_BUFFER_DEPTH is set, got %d", s.ResumeGraceSec)
+	// 記録先がなければ記録できないため、全接続の記録を有効にするならディレクトリを必須にする
+	check(!s.SessionRecordAll || s.SessionRecordDir != "", "GATEWAY_SESSION_RECORD_DIR is required when GATEWAY_SESSION_RECORD_ALL is true")
+	check(s.SessionRecordMaxBytes > 0, "GATEWAY_SESSION_RECORD_MAX_BYTES must be positive, got %d", s.SessionRecordMaxBytes)
+	check(s.SessionRecordMaxFiles > 0, "GATEWAY_SESSION_RECORD_MAX_FILES must be positive, got %d", s.SessionRecordMaxFiles)
+	// トークンがなければ誰も使えないため、有効にするならトークンを必須にする
+	check(!s.DebugHealthEnabled || s.DebugToken != "", "GATEWAY_DEBUG_TOKEN is required when GATEWAY_DEBUG_HEALTH_ENABLED is true")
+	for class, policy := range s.OverflowPolicies {
+		check(validOverflowPolicies[class][policy], "GATEWAY_OVERFLOW_POLICY: %s does not accept %q", class, policy)
+	}
+	check(s.OverflowBlockMs > 0, "GATEWAY_OVERFLOW_BLOCK_MS must be positive, got %d", s.OverflowBlockMs)
+	check(validAutoSubscribeModes[s.AutoSubscribe], "GATEWAY_AUTO_SUBSCRIBE must be one of none, single, all, got %q", s.AutoSubscribe)
+	for _, t := range s.AdapterTypesDeny {
+		check(!slices.Contains(s.AdapterTypesAllow, t),
+			"adapter type %q must not be in both GATEWAY_ADAPTER_TYPES_ALLOW and GATEWAY_ADAPTER_TYPES_DENY", t)
+	}
+	// セルフテストは一時的なモックロボットを使うため、mock を作れないと必ず失敗する
+	mockAllowed := !slices.Contains(s.AdapterTypesDeny, "mock") &&
+		(len(s.AdapterTypesAllow) == 0 || slices.Contains(s.AdapterTypesAllow, "mock"))
+	check(!s.SelfTestEnabled || mockAllowed,
+		"GATEWAY_SELFTEST_ENABLED requires the mock adapter type; disable the self-test or allow mock in GATEWAY_ADAPTER_TYPES_ALLOW/DENY")
+
+	// --- Redis ---
+	check(validRedisPayloadCodecs[c.Redis.PayloadCodec],
+		"GATEWAY_REDIS_PAYLOAD_CODEC must be one of json, msgpack, auto, got %q", c.Redis.PayloadCodec)
+	check(c.Redis.ReconnectIntervalMs >= 0,
+		"GATEWAY_REDIS_RECONNECT_INTERVAL_MS must be >= 0, got %d", c.Redis.ReconnectIntervalMs)
+
+	// --- 安全機構 ---
+	// 0 以下のタイムアウトや速度上限は「常に停止」「常に拒否」になるため、必ず正の値を求める
+	sf := c.Safety
+	check(sf.CommandTimeoutSec > 0, "GATEWAY_CMD_TIMEOUT_SEC must be positive, got %d", sf.CommandTimeoutSec)
+	check(sf.MaxLinearVelocity > 0, "GATEWAY_MAX_LINEAR_VEL must be positive, got %g", sf.MaxLinearVelocity)
+	check(sf.MaxAngularVelocity > 0, "GATEWAY_MAX_ANGULAR_VEL must be positive, got %g", sf.MaxAngularVelocity)
+	check(sf.OperationLockTimeoutSec > 0, "GATEWAY_OPERATION_LOCK_TIMEOUT_SEC must be positive, got %d", sf.OperationLockTimeoutSec)
+	// 警告はロックの期限より前に出なければ意味がない
+	check(sf.OperationLockWarnSec >= 0 && sf.OperationLockWarnSec < sf.OperationLockTimeoutSec,
+		"GATEWAY_OPERATION_LOCK_WARN_SEC must be between 0 and GATEWAY_OPERATION_LOCK_TIMEOUT_SEC (%d), got %d",
+		sf.OperationLockTimeoutSec, sf.OperationLockWarnSec)
+	check(sf.OperationLockMaxHoldSec >= 0, "GATEWAY_OPERATION_LOCK_MAX_HOLD_SEC must not be negative, got %d", sf.OperationLockMaxHoldSec)
+	check(sf.OperationLockMaxPerUser >= 0, "GATEWAY_OPERATION_LOCK_MAX_PER_USER must not be negative, got %d", sf.OperationLockMaxPerUser)
+	check(validOperationLockModes[sf.OperationLockMode],
+		"GATEWAY_OPERATION_LOCK_MODE must be one of auto, strict, got %q", sf.OperationLockMode)
+	check(sf.VelocityMinIntervalMs >= 0, "GATEWAY_VELOCITY_MIN_INTERVAL_MS must not be negative, got %d", sf.VelocityMinIntervalMs)
+	// 減速中もロボットは動き続けるため、タイムアウトより長く走らせない
+	check(sf.WatchdogRampDownMs >= 0 && sf.WatchdogRampDownMs <= sf.CommandTimeoutSec*1000,
+		"GATEWAY_WATCHDOG_RAMP_DOWN_MS must be between 0 and GATEWAY_CMD_TIMEOUT_SEC in milliseconds (%d), got %d",
+		sf.CommandTimeoutSec*1000, sf.WatchdogRampDownMs)
+	check(sf.AdapterCommandTimeoutMs > 0, "GATEWAY_ADAPTER_CMD_TIMEOUT_MS must be positive, got %d", sf.AdapterCommandTimeoutMs)
+	check(sf.CommandDedupWindowSec >= 0, "GATEWAY_CMD_DEDUP_WINDOW_SEC must not be negative, got %d", sf.CommandDedupWindowSec)
+	check(sf.SensorStallSec >= 0, "GATEWAY_SENSOR_STALL_SEC must not be negative, got %d", sf.SensorStallSec)
+	check(sf.CollisionStopDistance > 0, "GATEWAY_COLLISION_STOP_DISTANCE must be positive, got %g", sf.CollisionStopDistance)
+	// 180° で全周になる（それ以上は意味がない）
+	check(sf.CollisionStopConeDeg > 0 && sf.CollisionStopConeDeg <= 180,
+		"GATEWAY_COLLISION_STOP_CONE_DEG must be greater than 0 and at most 180, got %g", sf.CollisionStopConeDeg)
+	check(validCollisionStopActions[sf.CollisionStopAction],
+		"GATEWAY_COLLISION_STOP_ACTION must be one of estop, override, got %q", sf.CollisionStopAction)
+
+	// --- ログ ---
+	l := c.Logging
+	check(validLogLevels[l.Level], "GATEWAY_LOG_LEVEL must be one of debug, info, warn, error, got %q", l.Level)
+	check(!l.SamplingEnabled || (l.SamplingInitial > 0 && l.SamplingThereafter > 0),
+		"GATEWAY_LOG_SAMPLING_INITIAL and GATEWAY_LOG_SAMPLING_THEREAFTER must be positive when sampling is enabled, got %d and %d",
+		l.SamplingInitial, l.SamplingThereafter)
+	check(l.StreamBuffer >= 0, "GATEWAY_LOG_STREAM_BUFFER must not be negative, got %d", l.StreamBuffer)
+
+	// --- モックロボット ---
+	check(c.Mock.VelocityRampMs >= 0, "GATEWAY_MOCK_VELOCITY_RAMP_MS must not be negative, got %d", c.Mock.VelocityRampMs)
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,96 @@
+// =============================================================================
+// ファイル: convert.go（値の型変換）
+// 概要: メッセージの Payload（map[string]any）から取り出した値を数値に変換する
+//
+// 【なぜ必要か？】
+//
+//	Payload の数値は、デコード方法によって型が変わる。
+//	JSON なら float64、MessagePack なら int8 や uint16 など値の大きさに応じた整数型、
+//	設定ファイルや手書きのクライアントからは "0.5" のような文字列で来ることもある。
+//
+//	以前は server パッケージの toFloat と mock パッケージの toFloat64 が
+//	それぞれ一部の型だけを扱っており、対応する型が少しずつ食い違っていた。
+//	また、変換できない値を黙って 0 にしていたため、「0 が送られた」のか
+//	「値がない・壊れている」のかを呼び出し側で区別できなかった。
+//
+// 【使い方】
+//
+//	v, ok := convert.ToFloat64(msg.Payload["linear_x"])
+//	if !ok {
+//	    // 値がない、または数値ではない
+//	}
+//
+// =============================================================================
+package convert
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// =============================================================================
+// ToFloat64 - any 型の値を float64 に変換する
+// =============================================================================
+//
+// 整数型・浮動小数点型のすべてと、json.Number、数値を表す文字列（前後の空白は無視）を
+// 変換できます。nil やそれ以外の型、数値として読めない文字列なら ok が false です。
+// NaN と ±Inf も ok が false になります（速度や座標に使うと制限のチェックをすり抜けるため）。
+//
+// 【Go言語の知識: 型スイッチ（type switch）】
+//
+//	switch val := v.(type) { case float64: ... }
+//	v の実際の型で分岐し、各 case の中では val がその型として使える。
+func ToFloat64(v any) (f float64, ok bool) {
+	switch val := v.(type) {
+	case float64:
+		f = val
+	case float32:
+		f = float64(val)
+	case int:
+		f = float64(val)
+	case int8:
+		f = float64(val)
+	case int16:
+		f = float64(val)
+	case int32:
+		f = float64(val)
+	case int64:
+		f = float64(val)
+	case uint:
+		f = float64(val)
+	case uint8:
+		f = float64(val)
+	case uint16:
+		f = float64(val)
+	case uint32:
+		f = float64(val)
+	case uint64:
+		f = float64(val)
+	case json.Number:
+		return parseFloat(string(val))
+	case string:
+		return parseFloat(val)
+	default:
+		return 0, false
+	}
+	return finite(f)
+}
+
+// parseFloat - 数値を表す文字列を float64 に変換する
+func parseFloat(s string) (float64, bool) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0, false
+	}
+	return finite(f)
+}
+
+// finite - NaN と ±Inf を変換失敗として扱う
+func finite(f float64) (float64, bool) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, false
+	}
+	return f, true
+}
@@ -0,0 +1,108 @@
+// =============================================================================
+// ファイル: heartbeat.go（ゴルーチンの生存確認）
+// 概要: 常駐ゴルーチンが「まだループを回しているか」を記録するパッケージ
+//
+// 【なぜ必要か？】
+//
+//	Hub.Run、ウォッチドッグ、操作ロックのクリーンアップなどは、起動したら
+//	ずっと動き続ける前提のゴルーチンである。デッドロックやチャネルの詰まりで
+//	止まっても、プロセスは生きているため /health では気づけない。
+//
+//	各ゴルーチンがループのたびに Beat を呼んで時刻を残しておけば、
+//	「最後に回ったのはいつか」から止まっているかどうかを判断できる。
+//
+// 【使い方】
+//
+//	beats := heartbeat.NewRegistry()
+//	watchdog.SetHeartbeat(beats.Register("watchdog", 500*time.Millisecond))
+//	// ...
+//	for _, s := range beats.Snapshot() { fmt.Println(s.Name, s.Alive) }
+//
+// =============================================================================
+package heartbeat
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/clock"
+)
+
+// staleFactor: 間隔の何倍 Beat がなければ止まっているとみなすか
+const staleFactor = 3
+
+// Status - 1つのゴルーチンの生存状況
+type Status struct {
+	Name     string    `json:"name"`
+	LastBeat time.Time `json:"last_beat"` // まだ一度も Beat していなければゼロ値
+	Interval string    `json:"interval"`  // 期待する Beat の間隔
+	Alive    bool      `json:"alive"`     // 最後の Beat が間隔の staleFactor 倍以内か
+}
+
+// entry: 登録されたゴルーチンの記録
+type entry struct {
+	interval time.Duration
+	last     time.Time
+}
+
+// Registry - ゴルーチンの Beat を記録する。nil でも使える（何も記録しない）。
+type Registry struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+	clock   clock.Clock
+}
+
+// NewRegistry - 空の Registry を作成する
+func NewRegistry() *Registry {
+	return &Registry{entries: make(map[string]*entry), clock: clock.Real{}}
+}
+
+// SetClock - 現在時刻の取得元を差し替える（テスト用）
+func (r *Registry) SetClock(c clock.Clock) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clock = c
+}
+
+// Register - ゴルーチンを登録し、そのゴルーチンが呼ぶ Beat 関数を返す
+//
+// interval はループが回る間隔の目安です（ticker の間隔など）。
+// r が nil なら何もしない関数を返すので、呼び出し側は nil を気にせず使えます。
+func (r *Registry) Register(name string, interval time.Duration) func() {
+	if r == nil {
+		return func() {}
+	}
+	r.mu.Lock()
+	e := &entry{interval: interval}
+	r.entries[name] = e
+	r.mu.Unlock()
+
+	return func() {
+		r.mu.Lock()
+		e.last = r.clock.Now()
+		r.mu.Unlock()
+	}
+}
+
+// Snapshot - 登録されたゴルーチンの生存状況を名前順で返す
+func (r *Registry) Snapshot() []Status {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.clock.Now()
+	statuses := make([]Status, 0, len(r.entries))
+	for name, e := range r.entries {
+		statuses = append(statuses, Status{
+			Name:     name,
+			LastBeat: e.last,
+			Interval: e.interval.String(),
+			Alive:    !e.last.IsZero() && now.Sub(e.last) <= staleFactor*e.interval,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
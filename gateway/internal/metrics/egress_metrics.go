@@ -0,0 +1,135 @@
+// =============================================================================
+// ファイル: egress_metrics.go（送信量のメトリクス）
+// 概要: クライアントへ送ったメッセージの数とバイト数を、メッセージタイプ×ロボットごとに集計する
+//
+// 【なぜ必要か？】
+//
+//	帯域が足りない時に「何が帯域を使っているのか」が分からないと、
+//	間引き（sample_rate）やまとめ送り（sensor_batch）をどこに効かせるべきか決められない。
+//	例えば「送信量の 90% が LiDAR の sensor_data」と分かれば、LiDAR だけを間引けばよい。
+//
+// 【何を数えるか】
+//
+//	WebSocket のフレームとして書き出した payload のバイト数（クライアントのフォーマットに変換した後）。
+//	クライアントが N 人いれば、同じメッセージも N 回数える（実際に回線に出る量に合わせるため）。
+//	ゲートウェイは permessage-deflate（WebSocket の圧縮）を有効にしていないため、
+//	この値がそのまま回線に出るデータ量になる（フレームヘッダーの数バイトを除く）。
+//	圧縮を有効にした場合、ここで数えるのは圧縮前のバイト数になる。
+//
+// 【オーバーヘッドを小さくする工夫】
+//
+//	MessageMetrics と同じく、記録はマップの読み取りロックと atomic 加算だけで済ませる。
+//	ラベル値の種類には上限を設け、超えた分は "other" にまとめる。
+//
+// =============================================================================
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// maxEgressSeries: (メッセージタイプ, ロボット) の組み合わせの上限
+// ロボットは動的に増減するため、上限を超えた分は robot_id="other" にまとめる。
+const maxEgressSeries = 1024
+
+// egressKey: 集計の単位（メッセージタイプ × ロボット）
+type egressKey struct {
+	msgType string
+	robotID string
+}
+
+// egressStats: 1つの組み合わせの集計値
+type egressStats struct {
+	messages atomic.Uint64
+	bytes    atomic.Uint64
+}
+
+// =============================================================================
+// EgressMetrics: メッセージタイプ×ロボットごとの送信量
+// =============================================================================
+type EgressMetrics struct {
+	mu     sync.RWMutex
+	series map[egressKey]*egressStats
+}
+
+// NewEgressMetrics: 空のメトリクスを作成する
+func NewEgressMetrics() *EgressMetrics {
+	return &EgressMetrics{series: make(map[egressKey]*egressStats)}
+}
+
+// Add: 1メッセージの送信を記録する
+// robotID が空のメッセージ（pong や全体への通知など）は robot_id="" として数える。
+func (e *EgressMetrics) Add(msgType, robotID string, size int) {
+	s := e.stats(egressKey{msgType: msgType, robotID: robotID})
+	s.messages.Add(1)
+	s.bytes.Add(uint64(size))
+}
+
+// stats: 組み合わせの集計値を取得する（なければ作る）
+func (e *EgressMetrics) stats(key egressKey) *egressStats {
+	e.mu.RLock()
+	s, ok := e.series[key]
+	e.mu.RUnlock()
+	if ok {
+		return s
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if s, ok := e.series[key]; ok {
+		return s
+	}
+	if len(e.series) >= maxEgressSeries {
+		key = egressKey{msgType: overflowType, robotID: overflowType}
+		if s, ok := e.series[key]; ok {
+			return s
+		}
+	}
+	s = &egressStats{}
+	e.series[key] = s
+	return s
+}
+
+// =============================================================================
+// WritePrometheus: Prometheus のテキスト形式で書き出す（/metrics に追加される）
+//
+// 出力例:
+//
+//	# TYPE gateway_egress_bytes_total counter
+//	gateway_egress_bytes_total{type="sensor_data",robot_id="robot-1"} 1048576
+//	# TYPE gateway_egress_messages_total counter
+//	gateway_egress_messages_total{type="sensor_data",robot_id="robot-1"} 512
+//
+// =============================================================================
+func (e *EgressMetrics) WritePrometheus(w io.Writer) {
+	e.mu.RLock()
+	keys := make([]egressKey, 0, len(e.series))
+	stats := make(map[egressKey]*egressStats, len(e.series))
+	for key, s := range e.series {
+		keys = append(keys, key)
+		stats[key] = s
+	}
+	e.mu.RUnlock()
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].msgType != keys[j].msgType {
+			return keys[i].msgType < keys[j].msgType
+		}
+		return keys[i].robotID < keys[j].robotID
+	})
+
+	fmt.Fprintln(w, "# HELP gateway_egress_bytes_total Bytes written to WebSocket clients, by message type and robot.")
+	fmt.Fprintln(w, "# TYPE gateway_egress_bytes_total counter")
+	for _, key := range keys {
+		fmt.Fprintf(w, "gateway_egress_bytes_total{type=\"%s\",robot_id=\"%s\"} %d\n", escapeLabel(key.msgType), escapeLabel(key.robotID), stats[key].bytes.Load())
+	}
+
+	fmt.Fprintln(w, "# HELP gateway_egress_messages_total Messages written to WebSocket clients, by message type and robot.")
+	fmt.Fprintln(w, "# TYPE gateway_egress_messages_total counter")
+	for _, key := range keys {
+		fmt.Fprintf(w, "gateway_egress_messages_total{type=\"%s\",robot_id=\"%s\"} %d\n", escapeLabel(key.msgType), escapeLabel(key.robotID), stats[key].messages.Load())
+	}
+}
@@ -0,0 +1,202 @@
+// =============================================================================
+// ファイル: message_metrics.go（メッセージ処理のメトリクス）
+// 概要: メッセージタイプごとの処理時間ヒストグラムとエラー数を集計し、
+//
+//	Prometheus のテキスト形式で /metrics に公開するパッケージ
+//
+// 【なぜ必要か？】
+//
+//	負荷が高い時に「どのハンドラーが遅いのか」が分からないと、
+//	ボトルネック（例: nav_goal がアダプター呼び出しで詰まっている）を特定できない。
+//	HandleMessage の処理時間をメッセージタイプ別に記録しておけば、
+//	Prometheus / Grafana でタイプごとのレイテンシ分布を比較できる。
+//
+// 【なぜ公式クライアント（client_golang）を使わないのか？】
+//
+//	必要なのはラベル1つのヒストグラムとカウンターだけなので、
+//	依存ライブラリを増やさずにテキスト形式（exposition format）を直接書き出している。
+//	出力は Prometheus がそのままスクレイプできる形式に従う。
+//
+// 【オーバーヘッドを小さくする工夫】
+//
+//	記録（Observe）はマップの読み取りロックと atomic 加算だけで済むようにし、
+//	メッセージ処理のホットパスでミューテックスの書き込みロックを取らない。
+//
+// =============================================================================
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// durationBuckets: 処理時間ヒストグラムのバケット上限（秒）
+// ping のような数十マイクロ秒の処理から、アダプター呼び出しで数秒かかる処理までを覆う。
+var durationBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+// UnknownType: プロトコルに定義のないメッセージタイプをまとめるラベル値
+// メッセージタイプはクライアントが自由に送れる文字列なので、呼び出し側（Handler.HandleMessage）で
+// 定義済みのタイプ以外はこの値に置き換えてから Observe に渡す。
+const UnknownType = "unknown"
+
+// maxTypes: ラベル値（メッセージタイプ）の種類の上限
+// 呼び出し側の置き換えが漏れた場合の保険として、
+// 上限を超えた分は "other" にまとめて系列数（カーディナリティ）の爆発を防ぐ。
+const maxTypes = 64
+
+// overflowType: 上限を超えたメッセージタイプをまとめるラベル値
+const overflowType = "other"
+
+// typeStats: 1つのメッセージタイプの集計値
+// すべて atomic で更新するため、読み取りロックのまま記録できる。
+type typeStats struct {
+	buckets  []atomic.Uint64 // 各バケット「以下」に入った回数（累積ではない）
+	count    atomic.Uint64   // 観測回数
+	sumNanos atomic.Uint64   // 処理時間の合計（ナノ秒）
+	errors   atomic.Uint64   // エラー応答を返した回数
+}
+
+// =============================================================================
+// MessageMetrics: メッセージタイプ別のメトリクス
+// =============================================================================
+type MessageMetrics struct {
+	mu    sync.RWMutex
+	types map[string]*typeStats
+
+	// collectors: /metrics に続けて書き出す他のメトリクス（AddCollector で追加）
+	collectors []Collector
+}
+
+// Collector: /metrics に追加で書き出すメトリクス（EgressMetrics など）
+type Collector interface {
+	WritePrometheus(w io.Writer)
+}
+
+// AddCollector: /metrics の出力に c を追加する（サーバー起動前に呼ぶこと）
+func (m *MessageMetrics) AddCollector(c Collector) {
+	m.collectors = append(m.collectors, c)
+}
+
+// NewMessageMetrics: 空のメトリクスを作成する
+func NewMessageMetrics() *MessageMetrics {
+	return &MessageMetrics{types: make(map[string]*typeStats)}
+}
+
+// Observe: 1メッセージの処理時間を記録する
+// msgType はそのままラベル値になるため、クライアントの送った文字列を直接渡さないこと（UnknownType を参照）。
+// failed が true の場合は、そのタイプのエラー数も1つ増やす。
+func (m *MessageMetrics) Observe(msgType string, d time.Duration, failed bool) {
+	s := m.stats(msgType)
+
+	seconds := d.Seconds()
+	for i, upper := range durationBuckets {
+		if seconds <= upper {
+			s.buckets[i].Add(1)
+			break
+		}
+	}
+	s.count.Add(1)
+	s.sumNanos.Add(uint64(d.Nanoseconds()))
+	if failed {
+		s.errors.Add(1)
+	}
+}
+
+// stats: メッセージタイプの集計値を取得する（なければ作る）
+// 通常は読み取りロックだけで済み、初めて見るタイプの時だけ書き込みロックを取る。
+func (m *MessageMetrics) stats(msgType string) *typeStats {
+	m.mu.RLock()
+	s, ok := m.types[msgType]
+	m.mu.RUnlock()
+	if ok {
+		return s
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.types[msgType]; ok {
+		return s
+	}
+	if len(m.types) >= maxTypes {
+		msgType = overflowType
+		if s, ok := m.types[msgType]; ok {
+			return s
+		}
+	}
+	s = &typeStats{buckets: make([]atomic.Uint64, len(durationBuckets))}
+	m.types[msgType] = s
+	return s
+}
+
+// =============================================================================
+// Handler: /metrics エンドポイントのHTTPハンドラー
+//
+// 出力例:
+//
+//	# TYPE gateway_message_duration_seconds histogram
+//	gateway_message_duration_seconds_bucket{type="nav_goal",le="0.1"} 12
+//	...
+//	gateway_message_duration_seconds_sum{type="nav_goal"} 0.83
+//	gateway_message_duration_seconds_count{type="nav_goal"} 14
+//	# TYPE gateway_message_errors_total counter
+//	gateway_message_errors_total{type="nav_goal"} 2
+//
+// =============================================================================
+func (m *MessageMetrics) Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	// 出力順を安定させるため、タイプ名でソートする
+	m.mu.RLock()
+	names := make([]string, 0, len(m.types))
+	for name := range m.types {
+		names = append(names, name)
+	}
+	stats := make(map[string]*typeStats, len(m.types))
+	for name, s := range m.types {
+		stats[name] = s
+	}
+	m.mu.RUnlock()
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "# HELP gateway_message_duration_seconds Time spent handling a WebSocket message, by message type.")
+	fmt.Fprintln(w, "# TYPE gateway_message_duration_seconds histogram")
+	for _, name := range names {
+		s := stats[name]
+		label := escapeLabel(name)
+		var cumulative uint64
+		for i, upper := range durationBuckets {
+			cumulative += s.buckets[i].Load()
+			fmt.Fprintf(w, "gateway_message_duration_seconds_bucket{type=\"%s\",le=\"%g\"} %d\n", label, upper, cumulative)
+		}
+		count := s.count.Load()
+		fmt.Fprintf(w, "gateway_message_duration_seconds_bucket{type=\"%s\",le=\"+Inf\"} %d\n", label, count)
+		fmt.Fprintf(w, "gateway_message_duration_seconds_sum{type=\"%s\"} %g\n", label, float64(s.sumNanos.Load())/1e9)
+		fmt.Fprintf(w, "gateway_message_duration_seconds_count{type=\"%s\"} %d\n", label, count)
+	}
+
+	fmt.Fprintln(w, "# HELP gateway_message_errors_total WebSocket messages answered with an error, by message type.")
+	fmt.Fprintln(w, "# TYPE gateway_message_errors_total counter")
+	for _, name := range names {
+		fmt.Fprintf(w, "gateway_message_errors_total{type=\"%s\"} %d\n", escapeLabel(name), stats[name].errors.Load())
+	}
+
+	for _, c := range m.collectors {
+		c.WritePrometheus(w)
+	}
+}
+
+// labelEscaper: exposition format のラベル値で必要なエスケープ（\\ と \" と \n の3つだけ）
+// Go の %q は \x00 や \t のような Go の書式でエスケープし、Prometheus がスクレイプに失敗するため使わない。
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabel: ラベル値を exposition format に合わせてエスケープする
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
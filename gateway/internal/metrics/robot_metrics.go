@@ -0,0 +1,149 @@
+// =============================================================================
+// ファイル: robot_metrics.go（ロボットの状態のメトリクス）
+// 概要: ロボットごとの速度（指令値と実測値）と E-Stop の状態を Prometheus のゲージとして出す
+//
+// 【なぜ必要か？】
+//
+//	「人のいるエリアで 0.5 m/s を超えたら通知」のようなアラートや Grafana のグラフのために、
+//	WebSocket で購読しなくてもロボットの動きを見られるようにする。
+//
+// 【何を出すか】
+//
+//	gateway_robot_commanded_velocity  … 直前にロボットへ送った速度（速度制限の後）
+//	gateway_robot_reported_velocity   … オドメトリでロボットが報告した速度
+//	gateway_robot_estop_active        … E-Stop 中なら 1、そうでなければ 0
+//
+//	速度は axis ラベル（linear_x / linear_y / angular_z）で成分を分ける。
+//
+// 【カーディナリティ（系列の数）】
+//
+//	ラベルは robot_id と axis だけなので、系列の数はロボットの数に比例する。
+//	削除されたロボットの系列は RemoveRobot で消す。
+//
+// =============================================================================
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// velocityAxes: 速度の成分（axis ラベルの値）
+var velocityAxes = [3]string{"linear_x", "linear_y", "angular_z"}
+
+// RobotMetrics: ロボットごとの速度と E-Stop の状態
+type RobotMetrics struct {
+	mu        sync.RWMutex
+	commanded map[string][3]float64 // robot_id -> 指令した速度
+	reported  map[string][3]float64 // robot_id -> 報告された速度
+
+	// robots / estopActive: E-Stop の状態の取得元（SetEStopSource で設定、nil なら出さない）
+	// E-Stop は複数の場所で切り替わるため、記録せずに /metrics を読む時に問い合わせる。
+	robots      func() []string
+	estopActive func(robotID string) bool
+}
+
+// NewRobotMetrics: 空のメトリクスを作成する
+func NewRobotMetrics() *RobotMetrics {
+	return &RobotMetrics{
+		commanded: make(map[string][3]float64),
+		reported:  make(map[string][3]float64),
+	}
+}
+
+// SetEStopSource: E-Stop の状態の取得元を設定する（サーバー起動前に一度だけ呼ぶこと）
+//
+// robots は登録中のロボットの一覧、active はロボットが E-Stop 中かを返す関数です。
+func (r *RobotMetrics) SetEStopSource(robots func() []string, active func(robotID string) bool) {
+	r.robots = robots
+	r.estopActive = active
+}
+
+// SetCommandedVelocity: ロボットに送った速度を記録する
+func (r *RobotMetrics) SetCommandedVelocity(robotID string, linearX, linearY, angularZ float64) {
+	r.mu.Lock()
+	r.commanded[robotID] = [3]float64{linearX, linearY, angularZ}
+	r.mu.Unlock()
+}
+
+// SetReportedVelocity: ロボットが報告した速度を記録する
+func (r *RobotMetrics) SetReportedVelocity(robotID string, linearX, linearY, angularZ float64) {
+	r.mu.Lock()
+	r.reported[robotID] = [3]float64{linearX, linearY, angularZ}
+	r.mu.Unlock()
+}
+
+// RemoveRobot: 削除されたロボットの系列を消す
+func (r *RobotMetrics) RemoveRobot(robotID string) {
+	r.mu.Lock()
+	delete(r.commanded, robotID)
+	delete(r.reported, robotID)
+	r.mu.Unlock()
+}
+
+// =============================================================================
+// WritePrometheus: Prometheus のテキスト形式で書き出す（/metrics に追加される）
+//
+// 出力例:
+//
+//	# TYPE gateway_robot_commanded_velocity gauge
+//	gateway_robot_commanded_velocity{robot_id="robot-1",axis="linear_x"} 0.5
+//	# TYPE gateway_robot_estop_active gauge
+//	gateway_robot_estop_active{robot_id="robot-1"} 0
+//
+// =============================================================================
+func (r *RobotMetrics) WritePrometheus(w io.Writer) {
+	r.mu.RLock()
+	commanded := copyVelocities(r.commanded)
+	reported := copyVelocities(r.reported)
+	r.mu.RUnlock()
+
+	writeVelocities(w, "gateway_robot_commanded_velocity",
+		"Last velocity sent to the robot after safety limits, by axis.", commanded)
+	writeVelocities(w, "gateway_robot_reported_velocity",
+		"Last velocity reported by the robot's odometry, by axis.", reported)
+
+	if r.robots == nil || r.estopActive == nil {
+		return
+	}
+	robots := r.robots()
+	sort.Strings(robots)
+	fmt.Fprintln(w, "# HELP gateway_robot_estop_active Whether the robot's emergency stop is active (1) or not (0).")
+	fmt.Fprintln(w, "# TYPE gateway_robot_estop_active gauge")
+	for _, robotID := range robots {
+		active := 0
+		if r.estopActive(robotID) {
+			active = 1
+		}
+		fmt.Fprintf(w, "gateway_robot_estop_active{robot_id=\"%s\"} %d\n", escapeLabel(robotID), active)
+	}
+}
+
+// copyVelocities: ロックを持ったまま書き出さないよう、速度の map をコピーする
+func copyVelocities(src map[string][3]float64) map[string][3]float64 {
+	out := make(map[string][3]float64, len(src))
+	for k, v := range src {
+		out[k] = v
+	}
+	return out
+}
+
+// writeVelocities: 速度のゲージをロボット ID 順に書き出す
+func writeVelocities(w io.Writer, name, help string, velocities map[string][3]float64) {
+	robots := make([]string, 0, len(velocities))
+	for robotID := range velocities {
+		robots = append(robots, robotID)
+	}
+	sort.Strings(robots)
+
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", name)
+	for _, robotID := range robots {
+		v := velocities[robotID]
+		for i, axis := range velocityAxes {
+			fmt.Fprintf(w, "%s{robot_id=\"%s\",axis=\"%s\"} %g\n", name, escapeLabel(robotID), axis, v[i])
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// =============================================================================
+// ファイル: client_ip.go（クライアントIPの解決）
+// 概要: プロキシ／ロードバランサーの背後でも正しいクライアントIPを求める
+//
+// 【なぜ必要か？】
+//
+//	本番環境ではゲートウェイの前に nginx やクラウドのロードバランサーが立つことが多い。
+//	その場合 r.RemoteAddr は「プロキシのIP」になり、全クライアントが同じIPに見える。
+//	→ レート制限が全員で1つのバケツを共有してしまう
+//	→ ログに本当のアクセス元が残らない
+//
+//	プロキシは本来のアクセス元を X-Forwarded-For ヘッダーに追記して転送する:
+//	  X-Forwarded-For: <クライアント>, <プロキシ1>, <プロキシ2>
+//
+// 【セキュリティ上の注意: ヘッダーは偽装できる】
+//
+//	X-Forwarded-For はただのHTTPヘッダーなので、クライアントが自由に書ける。
+//	無条件に信用すると、攻撃者はヘッダーを毎回変えるだけでレート制限を回避できる。
+//
+//	そこで「信頼するプロキシ（TrustedProxies）」を明示的に設定し、
+//	  1. 直接の接続元（RemoteAddr）が信頼済みプロキシのときだけヘッダーを見る
+//	  2. ヘッダーは右（自分に近い側）から辿り、信頼済みプロキシを読み飛ばす
+//	  3. 最初に現れた「信頼していないIP」をクライアントIPとする
+//	という手順で解決します。
+//
+//	デフォルトは「どのプロキシも信頼しない」= 常に RemoteAddr を使う（最も安全）。
+//
+// 【設定方法】
+//
+//	環境変数 GATEWAY_TRUSTED_PROXIES にカンマ区切りでIPまたはCIDRを指定する。
+//	例: GATEWAY_TRUSTED_PROXIES=10.0.0.0/8,172.16.0.0/12,127.0.0.1
+//
+// =============================================================================
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// =============================================================================
+// ClientIPResolver: HTTPリクエストから実際のクライアントIPを求める
+//
+// trusted は起動時に一度だけ作られ、以後は読み取り専用なので
+// Mutex なしで複数のゴルーチンから同時に使えます。
+// =============================================================================
+type ClientIPResolver struct {
+	trusted []*net.IPNet // 信頼するプロキシのネットワーク一覧（空なら誰も信頼しない）
+}
+
+// =============================================================================
+// NewClientIPResolver: 信頼するプロキシの一覧からリゾルバーを作成する
+//
+// 引数:
+//
+//	trustedProxies: IPアドレス（"127.0.0.1"）または CIDR（"10.0.0.0/8"）の一覧。
+//	                空文字列の要素は無視する。nil / 空なら誰も信頼しない。
+//
+// 戻り値:
+//
+//	解釈できない値が含まれていた場合はエラーを返す。
+//	設定ミスを起動時に検出するため、黙って無視はしない。
+//
+// =============================================================================
+func NewClientIPResolver(trustedProxies []string) (*ClientIPResolver, error) {
+	r := &ClientIPResolver{}
+	for _, p := range trustedProxies {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+
+		// CIDR 表記でなければ単一アドレスとみなし、/32（IPv6 は /128）を付ける。
+		if !strings.Contains(p, "/") {
+			ip := net.ParseIP(p)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", p)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				bits = 32
+			}
+			p = fmt.Sprintf("%s/%d", p, bits)
+		}
+
+		_, ipNet, err := net.ParseCIDR(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", p, err)
+		}
+		r.trusted = append(r.trusted, ipNet)
+	}
+	return r, nil
+}
+
+// =============================================================================
+// ClientIP: リクエストのクライアントIPを返す
+//
+// nil のリゾルバー（設定されていない場合）でも呼び出せるようにしてあり、
+// その場合は RemoteAddr のホスト部分だけを返します。
+// =============================================================================
+func (c *ClientIPResolver) ClientIP(req *http.Request) string {
+	remote := hostOnly(req.RemoteAddr)
+	if c == nil || !c.isTrusted(remote) {
+		return remote
+	}
+
+	// X-Forwarded-For は複数行に分かれて届くこともあるため、全行を連結してから分割する。
+	var hops []string
+	for _, h := range req.Header.Values("X-Forwarded-For") {
+		for _, part := range strings.Split(h, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				hops = append(hops, part)
+			}
+		}
+	}
+
+	// 右（自分に近い側）から辿り、信頼済みプロキシでない最初のIPを採用する。
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := hostOnly(hops[i])
+		if net.ParseIP(ip) == nil {
+			// 壊れた値が混ざっていたら、それより左は信用できない
+			break
+		}
+		if !c.isTrusted(ip) {
+			return ip
+		}
+	}
+
+	// すべて信頼済みプロキシだった（またはヘッダーがない）場合は接続元を使う
+	return remote
+}
+
+// isTrusted: ip が信頼済みプロキシのネットワークに含まれるか
+func (c *ClientIPResolver) isTrusted(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, n := range c.trusted {
+		if n.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostOnly: "IP:ポート" 形式ならポートを取り除く。ポートがなければそのまま返す。
+func hostOnly(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
@@ -19,6 +19,8 @@
 //  1. RateLimiter: レート制限（過剰リクエストの防止）
 //  2. LoggingMiddleware: リクエストのログ記録
 //
+// どちらもクライアントIPは ClientIPResolver（client_ip.go）で求めます。
+//
 // =============================================================================
 package middleware
 
@@ -45,6 +47,9 @@ import (
 
 	// zap: 高性能構造化ログライブラリ。
 	"go.uber.org/zap"
+
+	// clock: 現在時刻の取得元（テストでは手で進める時計に差し替える）
+	"github.com/robot-ai-webapp/gateway/internal/clock"
 )
 
 // =============================================================================
@@ -83,7 +88,9 @@ type RateLimiter struct {
 	tokens   map[string]*bucket // IPアドレスごとのトークンバケット
 	rate     int                // 1インターバルあたりの最大リクエスト数
 	interval time.Duration      // トークンがリセットされるインターバル（ここでは1分）
+	ips      *ClientIPResolver  // バケットのキーとなるクライアントIPの解決器
 	logger   *zap.Logger        // ログ出力器
+	clock    clock.Clock        // 現在時刻の取得元（テストでは SetClock で差し替える）
 }
 
 // =============================================================================
@@ -113,6 +120,7 @@ type bucket struct {
 // 引数:
 //
 //	ratePerMinute: 1分あたりの最大リクエスト数
+//	ips: クライアントIPの解決器（nil なら RemoteAddr のホスト部分を使う）
 //	logger: ログ出力器
 //
 // 戻り値:
@@ -120,7 +128,7 @@ type bucket struct {
 //	初期化された RateLimiter のポインタ
 //
 // =============================================================================
-func NewRateLimiter(ratePerMinute int, logger *zap.Logger) *RateLimiter {
+func NewRateLimiter(ratePerMinute int, ips *ClientIPResolver, logger *zap.Logger) *RateLimiter {
 	// 【Go言語の知識: make関数】
 	//
 	//	make() はスライス、マップ、チャネルを初期化するための組み込み関数。
@@ -131,10 +139,24 @@ func NewRateLimiter(ratePerMinute int, logger *zap.Logger) *RateLimiter {
 		tokens:   make(map[string]*bucket),
 		rate:     ratePerMinute,
 		interval: time.Minute, // time.Minute は 1分を表す定数
+		ips:      ips,
 		logger:   logger,
+		clock:    clock.Real{},
 	}
 }
 
+// =============================================================================
+// SetClock: 現在時刻の取得元を差し替えるメソッド（テスト用）
+//
+// clock.Mock を渡すと、1分待たずにトークンのリセットを確かめられます。
+// Middleware() でリクエストを受け付け始める前に呼んでください。
+// =============================================================================
+func (rl *RateLimiter) SetClock(c clock.Clock) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.clock = c
+}
+
 // =============================================================================
 // Middleware: レート制限を適用するHTTPミドルウェアを返すメソッド
 //
@@ -166,8 +188,10 @@ func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
 	//	rl と next はこの関数が作られた時点の値を参照し続ける。
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// クライアントのIPアドレスを取得。
-		// RemoteAddr は "IPアドレス:ポート" 形式の文字列。
-		ip := r.RemoteAddr
+		// RemoteAddr は "IPアドレス:ポート" 形式で、接続ごとにポートが変わるため
+		// そのままキーにすると制限が効かない。リゾルバーでIP部分だけを求める。
+		// プロキシ経由の場合は、信頼済みプロキシが付けた X-Forwarded-For から求める。
+		ip := rl.ips.ClientIP(r)
 
 		// レート制限チェック。allow() が false を返したら制限超過。
 		if !rl.allow(ip) {
@@ -217,7 +241,7 @@ func (rl *RateLimiter) allow(key string) bool {
 	//	ロック・アンロックの対はバグの温床なので、defer で安全に管理する。
 	defer rl.mu.Unlock()
 
-	now := time.Now()
+	now := rl.clock.Now()
 
 	// マップからこのキー（IPアドレス）のバケットを取得。
 	// 【Go言語の知識: マップの2値返却】
@@ -261,18 +285,19 @@ func (rl *RateLimiter) allow(key string) bool {
 //	「関数を引数に取る関数」または「関数を返す関数」を高階関数と呼ぶ。
 //	この関数は「logger を受け取り、ミドルウェア関数を返す」高階関数。
 //
-//	呼び出し方: LoggingMiddleware(logger)(mux)
-//	1. LoggingMiddleware(logger) → func(http.Handler) http.Handler を返す
+//	呼び出し方: LoggingMiddleware(logger, ips)(mux)
+//	1. LoggingMiddleware(logger, ips) → func(http.Handler) http.Handler を返す
 //	2. その戻り値に (mux) を渡して最終的なハンドラーを取得
 //
 // 【ログに記録する情報】
 //   - method: HTTPメソッド（GET, POST など）
 //   - path: リクエストされたURLパス
-//   - remote_addr: クライアントのIPアドレス
+//   - remote_addr: 直接の接続元（プロキシ経由ならプロキシのアドレス）
+//   - client_ip: ClientIPResolver で求めた実際のクライアントIP
 //   - duration: リクエストの処理にかかった時間
 //
 // =============================================================================
-func LoggingMiddleware(logger *zap.Logger) func(http.Handler) http.Handler {
+func LoggingMiddleware(logger *zap.Logger, ips *ClientIPResolver) func(http.Handler) http.Handler {
 	// 外側の関数: logger を受け取る
 	return func(next http.Handler) http.Handler {
 		// 中間の関数: next ハンドラーを受け取る
@@ -292,7 +317,8 @@ func LoggingMiddleware(logger *zap.Logger) func(http.Handler) http.Handler {
 			logger.Info("HTTP request",
 				zap.String("method", r.Method),              // HTTPメソッド（GET, POST等）
 				zap.String("path", r.URL.Path),              // リクエストパス（/ws, /health等）
-				zap.String("remote_addr", r.RemoteAddr),     // クライアントのIPアドレス
+				zap.String("remote_addr", r.RemoteAddr),     // 直接の接続元アドレス
+				zap.String("client_ip", ips.ClientIP(r)),    // 解決済みのクライアントIP
 				zap.Duration("duration", time.Since(start)), // 処理時間
 			)
 		})
@@ -20,16 +20,37 @@
 //	   - デバッグに便利
 //	   - MessagePack より遅く、サイズが大きい
 //
-// 【設計パターン: Codec パターン（フォールバック戦略）】
+// 【設計パターン: Codec インターフェース（ストラテジーパターン）】
 //
-//	デフォルトは高速な MessagePack を使い、
-//	デコード失敗時は JSON にフォールバック（代替手段に切り替え）する。
-//	これにより、異なるクライアントからのメッセージも処理できる。
+//	Codec はフォーマットごとの実装（MsgpackCodec / JSONCodec）を差し替えられる
+//	インターフェース。クライアントは接続時にフォーマットを選び
+//	（Sec-WebSocket-Protocol または ?format=）、以後はそのフォーマットだけで読み書きする。
+//	CBOR や protobuf などの新しいフォーマットも、Codec を実装して RegisterCodec するだけで追加できる。
+//
+//	フォーマットを指定しないクライアントには、従来どおりの AutoCodec を使う：
+//	MessagePack でデコードを試み、失敗したら JSON にフォールバック（代替手段に切り替え）する。
+//
+// 【サーバー内部の形式】
+//
+//	Hub が配信するメッセージは、すべて MessagePack でエンコード済みのバイト列（1回だけエンコードして
+//	全購読者で共有する）。MessagePack 以外を選んだクライアントには、送信直前に Transcode で変換する。
 //
 // =============================================================================
 package protocol
 
 import (
+	// bytes: PeekHeader でバイト列を読み進めるための Reader
+	"bytes"
+
+	// fmt: エラーメッセージの組み立て
+	"fmt"
+
+	// sort: 対応フォーマット名の一覧を安定した順序で返す
+	"sort"
+
+	// sync: フォーマットの登録表（codecs）を複数のゴルーチンから安全に読むためのロック
+	"sync"
+
 	// encoding/json: Go 標準の JSON エンコード/デコードライブラリ。
 	// json.Marshal()   : 構造体 → JSON バイト列
 	// json.Unmarshal() : JSON バイト列 → 構造体
@@ -42,28 +63,185 @@ import (
 	"github.com/vmihailenco/msgpack/v5"
 )
 
+// フォーマット名（Sec-WebSocket-Protocol や ?format= で指定する値）
+const (
+	FormatMsgpack = "msgpack"
+	FormatJSON    = "json"
+	// FormatAuto: フォーマットを指定しなかったクライアント向け（AutoCodec）
+	FormatAuto = "auto"
+)
+
 // =============================================================================
-// Codec: メッセージのエンコード・デコードを行う構造体
+// Codec: メッセージのエンコード・デコードを行うインターフェース
 //
-// 【Go言語の知識: 空の構造体】
+// 【Go言語の知識: インターフェース】
 //
-//	Codec はフィールドを持たない空の構造体。
-//	「状態を持たないが、メソッドを持つ型」を作るために使用。
-//	これは Java の「ユーティリティクラス」に似た考え方。
-//	メソッドをグループ化してまとめる役割がある。
+//	インターフェースは「どんなメソッドを持つか」だけを定める型。
+//	MsgpackCodec も JSONCodec も、この3つのメソッドを持つので Codec として扱える。
+//	呼び出し側はフォーマットを意識せずに Encode / Decode を呼べる。
 //
-// 【なぜ Codec 構造体を使うのか？】
+// 【なぜインターフェースを使うのか？】
 //
 //	直接 msgpack.Marshal() を呼ぶ代わりに Codec を使う理由：
-//	- 将来、エンコード/デコードにカスタム処理を追加できる
+//	- クライアントごとにフォーマットを選べる
 //	- テスト時にモック（偽物）に差し替えやすい
-//	- エンコード方式の変更が1箇所で済む
+//	- 新しいフォーマットの追加が、実装を1つ書くだけで済む
 //
 // =============================================================================
-type Codec struct{}
+type Codec interface {
+	// Name: フォーマット名（例: "msgpack"）
+	Name() string
+	// Encode: Message をこのフォーマットのバイト列に変換する
+	Encode(msg *Message) ([]byte, error)
+	// Decode: このフォーマットのバイト列を Message に変換する
+	Decode(data []byte) (*Message, error)
+}
+
+// MsgpackCodec: MessagePack だけで読み書きする Codec
+type MsgpackCodec struct{}
+
+// Name はフォーマット名 "msgpack" を返す
+func (MsgpackCodec) Name() string { return FormatMsgpack }
+
+// Encode は Message を MessagePack に変換する
+func (MsgpackCodec) Encode(msg *Message) ([]byte, error) { return msgpack.Marshal(msg) }
+
+// Decode は MessagePack を Message に変換する（JSON へのフォールバックはしない）
+func (MsgpackCodec) Decode(data []byte) (*Message, error) {
+	var msg Message
+	if err := msgpack.Unmarshal(data, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// JSONCodec: JSON だけで読み書きする Codec（WebSocket のテキストフレームで送る）
+type JSONCodec struct{}
+
+// Name はフォーマット名 "json" を返す
+func (JSONCodec) Name() string { return FormatJSON }
+
+// Encode は Message を JSON に変換する
+func (JSONCodec) Encode(msg *Message) ([]byte, error) { return json.Marshal(msg) }
 
+// Decode は JSON を Message に変換する
+func (JSONCodec) Decode(data []byte) (*Message, error) {
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// =============================================================================
+// codecs: フォーマット名 → Codec の登録表
 // =============================================================================
-// NewCodec: Codec のコンストラクタ関数
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[string]Codec{
+		FormatMsgpack: MsgpackCodec{},
+		FormatJSON:    JSONCodec{},
+	}
+)
+
+// RegisterCodec: 新しいフォーマットを登録する（同じ名前があれば置き換える）
+// サーバー起動前（NewWebSocketServer より前）に呼んでください。
+func RegisterCodec(c Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	codecs[c.Name()] = c
+}
+
+// LookupCodec: フォーマット名から Codec を探す
+func LookupCodec(name string) (Codec, bool) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	c, ok := codecs[name]
+	return c, ok
+}
+
+// CodecNames: 登録されているフォーマット名の一覧（名前順）
+func CodecNames() []string {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	names := make([]string, 0, len(codecs))
+	for name := range codecs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// =============================================================================
+// Transcode: サーバー内部の形式（MessagePack）のバイト列を、クライアントのフォーマットに変換する
+//
+// 送り先が MessagePack（または AutoCodec）なら、変換せずにそのまま返す。
+// =============================================================================
+func Transcode(data []byte, to Codec) ([]byte, error) {
+	switch to.Name() {
+	case FormatMsgpack, FormatAuto:
+		return data, nil
+	}
+	msg, err := MsgpackCodec{}.Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("transcode to %s: %w", to.Name(), err)
+	}
+	return to.Encode(msg)
+}
+
+// =============================================================================
+// PeekHeader: サーバー内部の形式（MessagePack）のバイト列から、type と robot_id だけを読む
+//
+// 送信量の集計のように、配信するたびに宛先を知りたいが中身は要らない場面で使う。
+// Message のフィールドは type → topic → robot_id → ... → payload の順にエンコードされるため、
+// ts に達した時点で読むのをやめ、大きな payload（LiDAR の ranges など）には触れない。
+// 読めなかった場合は空文字を返す。
+// =============================================================================
+func PeekHeader(data []byte) (msgType MessageType, robotID string) {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	n, err := dec.DecodeMapLen()
+	if err != nil {
+		return "", ""
+	}
+	for i := 0; i < n; i++ {
+		key, err := dec.DecodeString()
+		if err != nil {
+			break
+		}
+		switch key {
+		case "type":
+			s, _ := dec.DecodeString()
+			msgType = MessageType(s)
+		case "robot_id":
+			robotID, _ = dec.DecodeString()
+		case "ts", "payload":
+			return msgType, robotID
+		default:
+			if dec.Skip() != nil {
+				return msgType, robotID
+			}
+		}
+	}
+	return msgType, robotID
+}
+
+// =============================================================================
+// AutoCodec: フォーマットを指定しなかったクライアント向けの Codec
+//
+// 【Go言語の知識: 空の構造体】
+//
+//	AutoCodec はフィールドを持たない空の構造体。
+//	「状態を持たないが、メソッドを持つ型」を作るために使用。
+//	これは Java の「ユーティリティクラス」に似た考え方。
+//	メソッドをグループ化してまとめる役割がある。
+//
+// エンコードは MessagePack、デコードは MessagePack → JSON の順に試す。
+// サーバー内部でのエンコード（Hub で配信するバイト列の作成）にも使う。
+// =============================================================================
+type AutoCodec struct{}
+
+// =============================================================================
+// NewCodec: AutoCodec のコンストラクタ関数
 //
 // 【Go言語の知識: コンストラクタパターン】
 //
@@ -72,10 +250,13 @@ type Codec struct{}
 //	呼び出し側のコードを変更しなくて済むようにするため。
 //
 // =============================================================================
-func NewCodec() *Codec {
-	return &Codec{}
+func NewCodec() *AutoCodec {
+	return &AutoCodec{}
 }
 
+// Name はフォーマット名 "auto" を返す
+func (c *AutoCodec) Name() string { return FormatAuto }
+
 // =============================================================================
 // EncodeMsgpack: Message 構造体を MessagePack 形式のバイト列に変換
 //
@@ -96,10 +277,10 @@ func NewCodec() *Codec {
 //	大きな構造体のコピーを避け、効率的にデータを渡せる。
 //
 // =============================================================================
-func (c *Codec) EncodeMsgpack(msg *Message) ([]byte, error) {
+func (c *AutoCodec) EncodeMsgpack(msg *Message) ([]byte, error) {
 	// msgpack.Marshal: 構造体をMessagePackバイト列にシリアライズする。
 	// 構造体のフィールドに付いている `msgpack:"..."` タグに従って変換される。
-	return msgpack.Marshal(msg)
+	return MsgpackCodec{}.Encode(msg)
 }
 
 // =============================================================================
@@ -116,16 +297,9 @@ func (c *Codec) EncodeMsgpack(msg *Message) ([]byte, error) {
 //	Unmarshal は受け取ったポインタが指す変数に直接値を書き込む。
 //
 // =============================================================================
-func (c *Codec) DecodeMsgpack(data []byte) (*Message, error) {
-	var msg Message
-	// msgpack.Unmarshal: バイト列を構造体にデシリアライズする。
-	// &msg にデコード結果が書き込まれる（ポインタ渡し）。
-	if err := msgpack.Unmarshal(data, &msg); err != nil {
-		// デコード失敗時は nil と エラーを返す。
-		return nil, err
-	}
-	// デコード成功時は構造体のポインタを返す。
-	return &msg, nil
+func (c *AutoCodec) DecodeMsgpack(data []byte) (*Message, error) {
+	// MsgpackCodec.Decode の中で msgpack.Unmarshal が &msg にデコード結果を書き込む。
+	return MsgpackCodec{}.Decode(data)
 }
 
 // =============================================================================
@@ -136,19 +310,15 @@ func (c *Codec) DecodeMsgpack(data []byte) (*Message, error) {
 //
 // 構造体のフィールドに付いている `json:"..."` タグに従って変換される。
 // =============================================================================
-func (c *Codec) EncodeJSON(msg *Message) ([]byte, error) {
-	return json.Marshal(msg)
+func (c *AutoCodec) EncodeJSON(msg *Message) ([]byte, error) {
+	return JSONCodec{}.Encode(msg)
 }
 
 // =============================================================================
 // DecodeJSON: JSON 形式のバイト列を Message 構造体に変換（フォールバック用）
 // =============================================================================
-func (c *Codec) DecodeJSON(data []byte) (*Message, error) {
-	var msg Message
-	if err := json.Unmarshal(data, &msg); err != nil {
-		return nil, err
-	}
-	return &msg, nil
+func (c *AutoCodec) DecodeJSON(data []byte) (*Message, error) {
+	return JSONCodec{}.Decode(data)
 }
 
 // =============================================================================
@@ -170,7 +340,7 @@ func (c *Codec) DecodeJSON(data []byte) (*Message, error) {
 //	ここでは msg と err をそれぞれの関数呼び出しで適切に処理している。
 //
 // =============================================================================
-func (c *Codec) Decode(data []byte) (*Message, error) {
+func (c *AutoCodec) Decode(data []byte) (*Message, error) {
 	// まず MessagePack でデコードを試みる。
 	msg, err := c.DecodeMsgpack(data)
 	if err != nil {
@@ -188,6 +358,6 @@ func (c *Codec) Decode(data []byte) (*Message, error) {
 // デフォルトは MessagePack を使用（高速・コンパクト）。
 // 必要に応じて EncodeJSON() を直接呼ぶこともできる。
 // =============================================================================
-func (c *Codec) Encode(msg *Message) ([]byte, error) {
+func (c *AutoCodec) Encode(msg *Message) ([]byte, error) {
 	return c.EncodeMsgpack(msg)
 }
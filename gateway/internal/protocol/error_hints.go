@@ -0,0 +1,59 @@
+// =============================================================================
+// ファイル: error_hints.go
+// 概要: エラー応答に付ける「復旧のヒント」（action と retry_after_ms）
+//
+// 【なぜ必要か？】
+// エラーコードだけでは、クライアントは「どうすれば復旧できるか」を推測するしかありません。
+// 例えば not_authenticated なら再認証、adapter_timeout なら少し待って再送、が正解ですが、
+// クライアントごとに決め打ちすると、ゲートウェイ側の変更で食い違います。
+// エラーコードごとの復旧方法をゲートウェイが一か所で決め、エラーメッセージに載せます。
+//
+// 【ヒントのないエラー】
+// invalid_message や forbidden など、同じメッセージを送り直しても成功しないエラーには
+// ヒントを付けません（action が空 = 自動で再試行しない）。
+// =============================================================================
+package protocol
+
+// エラーからの復旧方法（Message.Action に入る値）
+const (
+	// ActionReauth: auth メッセージで認証し直してから送り直す
+	ActionReauth = "reauth"
+	// ActionRetry: RetryAfterMs だけ待ってから同じメッセージを送り直す
+	ActionRetry = "retry"
+	// ActionAcquireLock: op_lock で操作ロックを取得してから送り直す
+	ActionAcquireLock = "acquire_lock"
+	// ActionReconnect: 接続が切られるので、RetryAfterMs だけ待ってから接続し直す
+	ActionReconnect = "reconnect"
+)
+
+// RecoveryHint - エラーコードに対応する復旧のヒント
+type RecoveryHint struct {
+	Action       string // 復旧方法（Action* のいずれか）
+	RetryAfterMs int64  // 再試行までに待つ時間（ミリ秒、0 なら待たなくてよい）
+}
+
+// errorHints: エラーコードごとの復旧のヒント（ここにないコードにはヒントを付けない）
+var errorHints = map[string]RecoveryHint{
+	ErrCodeNotAuthenticated:    {Action: ActionReauth},
+	ErrCodeLockRequired:        {Action: ActionAcquireLock},
+	ErrCodeAdapterTimeout:      {Action: ActionRetry, RetryAfterMs: 1000},
+	ErrCodeCommandTimeout:      {Action: ActionRetry, RetryAfterMs: 1000},
+	ErrCodeNoSensorData:        {Action: ActionRetry, RetryAfterMs: 1000},
+	ErrCodeRobotDisconnected:   {Action: ActionRetry, RetryAfterMs: 5000},
+	ErrCodeErrorBudgetExceeded: {Action: ActionReconnect, RetryAfterMs: 5000},
+}
+
+// HintFor - エラーコードに対応する復旧のヒントを返す（なければ ok=false）
+func HintFor(code string) (hint RecoveryHint, ok bool) {
+	hint, ok = errorHints[code]
+	return hint, ok
+}
+
+// SetRecoveryHint - エラーコードに対応する復旧のヒントをメッセージに設定する
+// ヒントのないコードなら何もしません。
+func (m *Message) SetRecoveryHint(code string) {
+	if hint, ok := HintFor(code); ok {
+		m.Action = hint.Action
+		m.RetryAfterMs = hint.RetryAfterMs
+	}
+}
@@ -66,6 +66,14 @@ const (
 	// 直線速度（linear_x, linear_y）と回転速度（angular_z）を含む。
 	MsgTypeVelocityCommand MessageType = "velocity_cmd"
 
+	// MsgTypeVelocityPreset: 名前付き速度プリセットの呼び出し。Payload の "preset" で名前を指定する。
+	// サーバー側で速度コマンドに展開され、速度コマンドと同じ安全チェックを通る。
+	MsgTypeVelocityPreset MessageType = "velocity_preset"
+
+	// MsgTypeVelocityDelta: 相対速度コマンド。直前に指令した速度に Payload の
+	// linear_x / linear_y / angular_z を加算し、通常の速度コマンドと同じ安全チェックを通す。
+	MsgTypeVelocityDelta MessageType = "velocity_delta"
+
 	// MsgTypeNavigationGoal: ナビゲーション目標。ロボットに目的地を指定する。
 	// 座標（x, y, z）と向き（orientation）を含む。
 	MsgTypeNavigationGoal MessageType = "nav_goal"
@@ -83,10 +91,108 @@ const (
 	// MsgTypeOperationUnlock: 操作ロック解除。他のユーザーも操作可能にする。
 	MsgTypeOperationUnlock MessageType = "op_unlock"
 
+	// MsgTypeDock: ドッキング。ロボットを充電ドックへ移動させて充電を開始する。
+	// ドッキングに対応したロボット（Capabilities.SupportsDocking）のみ受け付ける。
+	MsgTypeDock MessageType = "dock"
+
+	// MsgTypeUndock: ドックからの離脱。充電を終了してドックから離れる。
+	MsgTypeUndock MessageType = "undock"
+
+	// MsgTypeResetError: エラー状態のリセット。故障から復帰したロボットを
+	// 再接続なしで操作可能な状態（idle）に戻す。故障が継続中なら拒否される。
+	MsgTypeResetError MessageType = "reset_error"
+
+	// MsgTypeResetPose: 姿勢リセット（管理者向け）。シミュレーターの位置・向きを
+	// 指定値（省略時は原点）に戻す。テストシナリオを再現可能にするために使う。
+	MsgTypeResetPose MessageType = "reset_pose"
+
 	// MsgTypePing: 生存確認（Ping）。接続が生きているかの確認メッセージ。
 	// サーバーは Pong で応答する（WebSocket のキープアライブ機構）。
 	MsgTypePing MessageType = "ping"
 
+	// MsgTypeHealthStatus: ヘルス状態の問い合わせ。要認証。
+	// ゲートウェイ（稼働時間、接続数、Redis）と購読中の各ロボット
+	// （接続状態、最終センサー時刻、E-Stop、ロック保持者）の状態を1回で返す。
+	// 応答も同じタイプで返る。
+	MsgTypeHealthStatus MessageType = "health_status"
+
+	// MsgTypeOdometryPath: ロボットが通った経路の問い合わせ。要認証・要 Redis。
+	// Redis に記録済みのオドメトリを時間範囲（from_ms / to_ms）で読み出し、
+	// 姿勢の列（poses）として返す。応答も同じタイプで返る。
+	MsgTypeOdometryPath MessageType = "odometry_path"
+
+	// MsgTypeGetDiagnostics: ロボットの診断情報（モーター温度、エラーフラグ、
+	// ファームウェアのバージョンなど）の問い合わせ。要認証。応答も同じタイプで返る。
+	// 対応していないロボットでは、エラーではなく supported: false の応答になる。
+	MsgTypeGetDiagnostics MessageType = "get_diagnostics"
+
+	// MsgTypeLinkStats: ロボットとの回線品質（往復遅延、パケットロス、最終応答時刻）の問い合わせ。
+	// 要認証。応答も同じタイプで返る。回線の計測値を持たないロボットでは推定値（estimated: true）、
+	// 対応していないロボットでは supported: false の応答になる。
+	MsgTypeLinkStats MessageType = "link_stats"
+
+	// MsgTypeSubscribeAlerts: 安全アラートだけの購読（alerts-only）。要認証。
+	// センサーデータを購読せずに、全ロボットの safety_alert などを受け取る。
+	// Payload の "enabled"（省略時 true）で購読・解除を切り替える。
+	MsgTypeSubscribeAlerts MessageType = "subscribe_alerts"
+
+	// MsgTypeLogStream: ゲートウェイのログの購読（管理者のみ）。要認証。
+	// Payload の "enabled"（省略時 true）、"level"（省略時 "info"）で購読条件を指定し、
+	// RobotID を指定するとそのロボットのログ（robot_id フィールド付き）だけを受け取る。
+	MsgTypeLogStream MessageType = "log_stream"
+
+	// MsgTypeSetSpeedLimit: ロボットの速度上限を実行中に下げる（管理者のみ）。要認証、RobotID 必須。
+	// Payload の "max_linear"（m/s）、"max_angular"（rad/s）で新しい上限を指定し（省略した方は現在の値のまま）、
+	// "reset": true で設定の上限に戻す。変更は safety_alert（type "speed_limit_changed"）で通知される。
+	MsgTypeSetSpeedLimit MessageType = "set_speed_limit"
+
+	// MsgTypeStopAll: 自分が操作中の全ロボットを止める（ソフトストップ）。要認証。
+	// 速度 0 とナビゲーションの中止を送るが、E-Stop と違ってラッチしないため、次の速度コマンドですぐ再開できる。
+	// 応答の cmd_ack（command "stop_all"）に、止めたロボット（"stopped"）と失敗したロボット（"failed"）が入る。
+	MsgTypeStopAll MessageType = "stop_all"
+
+	// MsgTypeGroupCommand: グループ（GATEWAY_ROBOT_GROUPS）内の全ロボットに同じ操作を行う。要認証。
+	// Payload の "group" にグループID（下位の階層も含む）、"action" に "estop" か "stop" を指定する。
+	// 応答の cmd_ack（command "group_command"）の "results" に、ロボットごとの結果（robot_id, ok, error）が入る。
+	MsgTypeGroupCommand MessageType = "group_command"
+
+	// MsgTypeSensorRequest: センサーの最新値を1回だけ問い合わせる（購読しない）。要認証。
+	// topic に問い合わせるトピック名（クライアント向けの名前）を入れる。
+	// 応答は sensor_data で、Payload に "timestamp"（サンプルの時刻）と "source"（"cache" / "adapter"）が付く。
+	// 値がなければ no_sensor_data のエラーになる。
+	MsgTypeSensorRequest MessageType = "sensor_request"
+
+	// MsgTypeDescribe: 対応しているメッセージタイプ、方向、Payload のフィールド、エラーコードの一覧を問い合わせる。
+	// 認証は不要。応答も describe で、Payload に "messages" と "error_codes" が入る（GET /schema と同じ内容）。
+	MsgTypeDescribe MessageType = "describe"
+
+	// MsgTypeSetRobotEnabled: ロボットを一時的に運用から外す／戻す（管理者のみ）。要認証。
+	// Payload の "enabled"（必須）が false なら、ロボットを止めてからコマンドを robot_disabled で拒否し、
+	// 設定（GATEWAY_DISABLED_ROBOT_PAUSE_SENSORS）によってセンサーデータの転送も止める。アダプターは接続したまま。
+	// 変更は safety_alert（type "robot_enabled_changed"）で通知される。
+	MsgTypeSetRobotEnabled MessageType = "set_robot_enabled"
+
+	// MsgTypeObserveCommands: ロボットに送られたコマンドの観察（観察者モード、観察者か管理者のみ）。要認証、RobotID 必須。
+	// Payload の "enabled"（省略時 true）で観察・解除を切り替える。観察中は、他のクライアントが送って
+	// 受け付けられたコマンドの写しが command_observed で届く。
+	MsgTypeObserveCommands MessageType = "observe_commands"
+
+	// MsgTypeSetSensorRate: ロボットのセンサーの送信頻度を実行中に変更する（管理者のみ）。要認証、RobotID 必須。
+	// Payload の "rates"（トピック名 → Hz）で新しい頻度を指定し、"reset": true で標準の頻度に戻す。
+	// センサーの最大の頻度を超える値は sensor_rate_out_of_range で拒否される（どのトピックも変更されない）。
+	MsgTypeSetSensorRate MessageType = "set_sensor_rate"
+
+	// MsgTypeRecordSession: 接続のメッセージの記録（セッションレコーダー）を開始・停止する（管理者のみ）。要認証。
+	// Payload の "client_id"（省略時は自分の接続）と "enabled"（省略時 true）で対象と開始・停止を指定する。
+	// GATEWAY_SESSION_RECORD_DIR が設定されていなければ session_recording_disabled で拒否される。
+	MsgTypeRecordSession MessageType = "record_session"
+
+	// MsgTypeHeartbeat: アプリケーション層のハートビート。認証は不要で、応答は返らない。
+	// WebSocket の Ping/Pong と違い、クライアントのイベントループが動いていることを示す。
+	// GATEWAY_APP_HEARTBEAT_TIMEOUT_MS を設定すると、その間メッセージ（heartbeat を含む）が
+	// 1通も届かないクライアントは切断される。
+	MsgTypeHeartbeat MessageType = "heartbeat"
+
 	// --- ゲートウェイ → クライアント 方向のメッセージ ---
 	// これらはサーバーからブラウザに送信されるメッセージの種類。
 
@@ -94,6 +200,11 @@ const (
 	// LiDAR、カメラ、IMU（慣性計測装置）などのデータを含む。
 	MsgTypeSensorData MessageType = "sensor_data"
 
+	// MsgTypeSensorBatch: センサーデータのまとめ送り。一定時間分のサンプルを
+	// Payload の "samples"（sensor_data の Payload と同じ形＋timestamp の配列）に入れて1通で送る。
+	// 認証時に Payload の "sensor_batch" を true にした購読だけが受け取る。
+	MsgTypeSensorBatch MessageType = "sensor_batch"
+
 	// MsgTypeRobotStatus: ロボットの状態。バッテリー残量、接続状態など。
 	MsgTypeRobotStatus MessageType = "robot_status"
 
@@ -105,8 +216,14 @@ const (
 	MsgTypeLockStatus MessageType = "lock_status"
 
 	// MsgTypeConnectionStatus: 接続状態の通知。ロボットの接続・切断を通知。
+	// ロボットがレジストリから削除された時は "removed": true が付き、そのロボットの購読は解除される。
 	MsgTypeConnectionStatus MessageType = "conn_status"
 
+	// MsgTypeServerShutdown: サーバーの停止予告。接続を閉じる直前に全クライアントへ送る。
+	// Payload: {"reason": "...", "reconnect": true}。クライアントはメッセージを表示し、
+	// 少し待ってから再接続するとよい（デプロイによる再起動など）。
+	MsgTypeServerShutdown MessageType = "server_shutdown"
+
 	// MsgTypeError: エラー通知。処理中にエラーが発生したことをクライアントに通知。
 	MsgTypeError MessageType = "error"
 
@@ -115,6 +232,93 @@ const (
 
 	// MsgTypeSafetyAlert: 安全警告。速度制限違反や緊急停止の通知。
 	MsgTypeSafetyAlert MessageType = "safety_alert"
+
+	// MsgTypeRobotEvent: ロボットが自分から知らせたイベント（バンパー接触、物理 E-Stop など）。
+	// そのロボットの購読者に届く。Payload: {"event_type", "severity", "message", "data", "timestamp"}
+	MsgTypeRobotEvent MessageType = "robot_event"
+
+	// MsgTypeLogEntry: ログ1件（log_stream の購読者に届く）。
+	// Payload: {"level", "time_ms", "logger", "message", "caller", "fields"}
+	MsgTypeLogEntry MessageType = "log_entry"
+
+	// MsgTypeCommandObserved: 観察中のロボットに受け付けられたコマンドの写し（observe_commands の購読者に届く）。
+	// Payload: {"command", "payload", "user_id"}。payload は制限・変換を適用した後の値。
+	MsgTypeCommandObserved MessageType = "command_observed"
+
+	// MsgTypeServerHeartbeat: ゲートウェイのハートビート（全クライアントに定期的に届く）。
+	// Payload: {"seq", "server_time_ms", "interval_ms", "timeout_ms"}。
+	// seq の抜けや server_time_ms の遅れで、ゲートウェイ側の停止や配信の詰まりを検出できる。
+	// timeout_ms が 0 より大きい場合、クライアントはその間隔より短い周期で heartbeat を送ること。
+	MsgTypeServerHeartbeat MessageType = "server_heartbeat"
+)
+
+// =============================================================================
+// エラーコード
+// =============================================================================
+//
+// エラーメッセージ（MsgTypeError）の Payload["code"] に入る、機械判定用の識別子。
+// Error フィールドの文章は人間向けで変わることがあるため、
+// クライアントはこのコードで処理を分岐してください。
+const (
+	// ErrCodeRobotDisconnected: ロボット（アダプター）が切断されている。
+	// コマンドはロボットに届かないため、クライアントは送信を止めるべき。
+	ErrCodeRobotDisconnected = "robot_disconnected"
+
+	// ErrCodeUnknownPreset: 指定された名前の速度プリセットが定義されていない。
+	ErrCodeUnknownPreset = "unknown_preset"
+
+	// ErrCodeInvalidMessage: 受信したメッセージをデコードできなかった。
+	ErrCodeInvalidMessage = "invalid_message"
+
+	// ErrCodeErrorBudgetExceeded: エラーが連続しすぎたため、接続を切断する（最後のエラー）。
+	ErrCodeErrorBudgetExceeded = "error_budget_exceeded"
+
+	// ErrCodeUnknownFrame: ナビゲーション目標の座標系（frame_id）からロボットの座標系への変換が設定されていない。
+	ErrCodeUnknownFrame = "unknown_frame"
+
+	// ErrCodeVelocityOutOfRange: 速度が上限を超えている（拒否モード）。メッセージに上限値が入る。
+	ErrCodeVelocityOutOfRange = "velocity_out_of_range"
+
+	// ErrCodeAdapterTimeout: アダプター（ロボット）が時間内に応答しなかった。
+	ErrCodeAdapterTimeout = "adapter_timeout"
+
+	// ErrCodeCommandNotAllowed: そのロボットはこのコマンド種別を受け付けない（能力または設定による制限）。
+	ErrCodeCommandNotAllowed = "command_not_allowed"
+
+	// ErrCodeForbidden: このユーザーには許可されていない操作（管理者専用の機能など）。
+	ErrCodeForbidden = "forbidden"
+
+	// ErrCodeLockRequired: 操作ロックを持っていない（strict モード）。先に op_lock でロックを取得する必要がある。
+	ErrCodeLockRequired = "lock_required"
+
+	// ErrCodeNoSensorData: sensor_request で問い合わせたトピックの値がない（まだ届いていない、またはロボットが読めない）。
+	ErrCodeNoSensorData = "no_sensor_data"
+
+	// ErrCodeCommandTimeout: アダプター（ロボット）がコマンドを時間内に受け付けなかった。
+	ErrCodeCommandTimeout = "command_timeout"
+
+	// ErrCodeNotAuthenticated: 認証していない（または認証に失敗した）。auth で認証し直す必要がある。
+	ErrCodeNotAuthenticated = "not_authenticated"
+
+	// ErrCodeRobotDisabled: ロボットが一時的に運用から外されている（set_robot_enabled）。E-Stop と stop_all は受け付ける。
+	ErrCodeRobotDisabled = "robot_disabled"
+
+	// ErrCodeSensorRateOutOfRange: 指定したセンサーの送信頻度が、センサーの最大の頻度を超えている。メッセージに最大値が入る。
+	ErrCodeSensorRateOutOfRange = "sensor_rate_out_of_range"
+
+	// ErrCodeUnknownGroup: group_command で指定したグループが設定（GATEWAY_ROBOT_GROUPS）にない。
+	ErrCodeUnknownGroup = "unknown_group"
+
+	// ErrCodeSessionRecordingDisabled: record_session を送ったが、記録先（GATEWAY_SESSION_RECORD_DIR）が設定されていない。
+	ErrCodeSessionRecordingDisabled = "session_recording_disabled"
+
+	// ErrCodeCommandInProgress: 同じ command_id のコマンドをまだ実行中（重複排除の対象種別）。
+	// 最初のコマンドの ACK かエラーを待ってください。
+	ErrCodeCommandInProgress = "command_in_progress"
+
+	// ErrCodeUnsupportedProtocolVersion: auth の protocol_version がゲートウェイの対応範囲外。
+	// Payload に対応範囲（min_protocol_version / max_protocol_version）が入る。
+	ErrCodeUnsupportedProtocolVersion = "unsupported_protocol_version"
 )
 
 // =============================================================================
@@ -148,6 +352,10 @@ type Message struct {
 	Timestamp int64          `msgpack:"ts" json:"ts"`                                 // タイムスタンプ（ミリ秒）
 	Payload   map[string]any `msgpack:"payload,omitempty" json:"payload,omitempty"`   // メッセージ本体データ
 	Error     string         `msgpack:"error,omitempty" json:"error,omitempty"`       // エラーメッセージ（エラー時のみ使用）
+
+	// Action / RetryAfterMs: エラーからの復旧のヒント（エラー時のみ、error_hints.go 参照）
+	Action       string `msgpack:"action,omitempty" json:"action,omitempty"`                 // 復旧方法（reauth / retry など）
+	RetryAfterMs int64  `msgpack:"retry_after_ms,omitempty" json:"retry_after_ms,omitempty"` // 再試行までに待つ時間（ミリ秒）
 }
 
 // =============================================================================
@@ -201,14 +409,15 @@ type VelocityPayload struct {
 // NavigationGoalPayload: ナビゲーション目標のペイロード
 //
 // ロボットの自律移動の目的地を指定する。
-// 位置（X, Y, Z）と向き（OrientationW）を含む。
+// 位置（X, Y, Z）と向き（OrientationZ, OrientationW）を含む。
 //
 // 【ロボット工学の知識: 座標系とクォータニオン】
 //
 //	X, Y, Z: 3D空間での位置座標（メートル単位）
-//	OrientationW: クォータニオン（四元数）のW成分。ロボットの向きを表す。
-//	  簡単に言えば、目的地でどの方向を向いているかを指定する値。
+//	OrientationZ, OrientationW: クォータニオン（四元数）のZ成分とW成分。ロボットの向きを表す。
+//	  簡単に言えば、目的地でどの方向を向いているかを指定する値（Z軸回りの回転のみ）。
 //	FrameID: 座標系の基準フレーム（例: "map" = 地図座標系）
+//	  ロボットの座標系と異なる場合、ゲートウェイが設定された変換で座標を変換する。
 //	Tolerance: 許容誤差（この範囲内なら到着とみなす）
 //
 // =============================================================================
@@ -216,6 +425,7 @@ type NavigationGoalPayload struct {
 	X                    float64 `msgpack:"x" json:"x"`               // 目標X座標 [m]
 	Y                    float64 `msgpack:"y" json:"y"`               // 目標Y座標 [m]
 	Z                    float64 `msgpack:"z" json:"z"`               // 目標Z座標 [m]
+	OrientationZ         float64 `msgpack:"oz" json:"oz"`             // 向き（クォータニオンZ成分）
 	OrientationW         float64 `msgpack:"ow" json:"ow"`             // 向き（クォータニオンW成分）
 	FrameID              string  `msgpack:"frame_id" json:"frame_id"` // 座標系フレームID
 	TolerancePosition    float64 `msgpack:"tol_pos" json:"tol_pos"`   // 位置の許容誤差 [m]
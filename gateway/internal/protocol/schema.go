@@ -0,0 +1,416 @@
+// =============================================================================
+// ファイル: schema.go
+// 概要: メッセージタイプごとの「スキーマ（形の定義）」と、その一覧（describe / GET /schema）
+//
+// 【なぜ必要か？】
+// クライアントを作る人は、どのメッセージがあり、Payload に何を入れればよいかを
+// Go のソースを読んで調べるしかありませんでした。
+// ゲートウェイ自身がメッセージの一覧・方向・Payload のフィールド・エラーコードを返せば、
+// それが常に実装と一致する「生きた API ドキュメント」になります。
+//
+// 【実装と一致させる仕組み】
+// ここで宣言したフィールドの型は、Handler.HandleMessage が受信時の検証
+// （ValidatePayload）にそのまま使います。ドキュメントだけを直して検証を忘れる、
+// という食い違いが起きません。
+//
+// 【必須フィールドについて】
+// Required は一覧に出すだけで、ValidatePayload では確認しません。
+// 未認証のクライアントには、フィールドの不足より先に not_authenticated を返したいため、
+// 必須の確認は各ハンドラーが認証の後に行います。
+// =============================================================================
+package protocol
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/robot-ai-webapp/gateway/internal/convert"
+)
+
+// FieldType - Payload のフィールドの型
+type FieldType string
+
+const (
+	// FieldString: 文字列
+	FieldString FieldType = "string"
+	// FieldNumber: 数値（数値の文字列も可。速度コマンドなどと同じ convert.ToFloat64 で判定する）
+	FieldNumber FieldType = "number"
+	// FieldBool: 真偽値
+	FieldBool FieldType = "bool"
+	// FieldObject: 入れ子のオブジェクト
+	FieldObject FieldType = "object"
+	// FieldArray: 配列
+	FieldArray FieldType = "array"
+)
+
+// Direction - メッセージの向き
+type Direction string
+
+const (
+	// DirectionClientToGateway: クライアントが送り、ゲートウェイが処理する
+	DirectionClientToGateway Direction = "client_to_gateway"
+	// DirectionGatewayToClient: ゲートウェイが送る
+	DirectionGatewayToClient Direction = "gateway_to_client"
+	// DirectionBoth: 問い合わせと応答が同じタイプ（health_status など）
+	DirectionBoth Direction = "both"
+)
+
+// FieldSchema - Payload の1つのフィールドの定義
+type FieldSchema struct {
+	Name        string    `msgpack:"name" json:"name"`
+	Type        FieldType `msgpack:"type" json:"type"`
+	Required    bool      `msgpack:"required,omitempty" json:"required,omitempty"`
+	Description string    `msgpack:"description,omitempty" json:"description,omitempty"`
+}
+
+// MessageSchema - 1つのメッセージタイプの定義
+type MessageSchema struct {
+	Type            MessageType   `msgpack:"type" json:"type"`
+	Direction       Direction     `msgpack:"direction" json:"direction"`
+	Description     string        `msgpack:"description" json:"description"`
+	RequiresAuth    bool          `msgpack:"requires_auth,omitempty" json:"requires_auth,omitempty"`
+	RequiresRobotID bool          `msgpack:"requires_robot_id,omitempty" json:"requires_robot_id,omitempty"`
+	Fields          []FieldSchema `msgpack:"fields,omitempty" json:"fields,omitempty"`
+	Errors          []string      `msgpack:"errors,omitempty" json:"errors,omitempty"` // 返ることのあるエラーコード（ErrCode*）
+}
+
+// ErrorCodeSchema - エラーコードの説明と復旧のヒント
+type ErrorCodeSchema struct {
+	Code         string `msgpack:"code" json:"code"`
+	Description  string `msgpack:"description" json:"description"`
+	Action       string `msgpack:"action,omitempty" json:"action,omitempty"`
+	RetryAfterMs int64  `msgpack:"retry_after_ms,omitempty" json:"retry_after_ms,omitempty"`
+}
+
+// Description - スキーマ全体（describe の応答と GET /schema の本体）
+type Description struct {
+	ProtocolVersion    int               `msgpack:"protocol_version" json:"protocol_version"`         // 最新のバージョン（version.go）
+	MinProtocolVersion int               `msgpack:"min_protocol_version" json:"min_protocol_version"` // 受け付ける最も古いバージョン
+	Messages           []MessageSchema   `msgpack:"messages" json:"messages"`
+	ErrorCodes         []ErrorCodeSchema `msgpack:"error_codes" json:"error_codes"`
+}
+
+// commandIDField: 再送の重複排除に使う command_id（コマンド系のメッセージに共通）
+var commandIDField = FieldSchema{Name: "command_id", Type: FieldString,
+	Description: "Client-chosen ID; a resend with the same ID is acknowledged without running the command again"}
+
+// dryRunField: 安全チェックだけを行い、送らずに結果を返すフラグ（コマンド系のメッセージに共通）
+var dryRunField = FieldSchema{Name: "dry_run", Type: FieldBool,
+	Description: "Run the safety checks and return the result in cmd_ack without sending the command"}
+
+// velocityFields: 速度の3成分（velocity_cmd と velocity_delta に共通）
+var velocityFields = []FieldSchema{
+	{Name: "linear_x", Type: FieldNumber, Description: "Forward velocity in m/s (at least one axis is required)"},
+	{Name: "linear_y", Type: FieldNumber, Description: "Sideways velocity in m/s"},
+	{Name: "angular_z", Type: FieldNumber, Description: "Rotation velocity in rad/s"},
+}
+
+// withFields: フィールドの列をつなげる（共通のフィールドを複数のメッセージで使い回すため）
+func withFields(groups ...[]FieldSchema) []FieldSchema {
+	var out []FieldSchema
+	for _, g := range groups {
+		out = append(out, g...)
+	}
+	return out
+}
+
+// messageSchemas: すべてのメッセージタイプの定義（クライアント → ゲートウェイ、ゲートウェイ → クライアントの順）
+var messageSchemas = []MessageSchema{
+	{Type: MsgTypeAuth, Direction: DirectionClientToGateway, Description: "Authenticate the connection and subscribe to robots",
+		Fields: []FieldSchema{
+			{Name: "token", Type: FieldString, Required: true, Description: "JWT access token"},
+			{Name: "auto_subscribe", Type: FieldString, Description: "none, single or all"},
+			{Name: "sensor_batch", Type: FieldBool, Description: "Receive sensor data as sensor_batch"},
+			{Name: "latest_only_topics", Type: FieldArray, Description: "Topics to receive as the latest sample only instead of every queued sample (e.g. [\"odom\"])"},
+			{Name: "session_id", Type: FieldString, Description: "Session to resume after a short disconnect"},
+			{Name: "protocol_version", Type: FieldNumber, Description: "Protocol version the client speaks; omitted means 1"},
+		},
+		Errors: []string{ErrCodeNotAuthenticated, ErrCodeInvalidMessage, ErrCodeUnsupportedProtocolVersion}},
+	{Type: MsgTypeVelocityCommand, Direction: DirectionClientToGateway, Description: "Drive the robot at a velocity",
+		RequiresAuth: true, RequiresRobotID: true,
+		Fields: withFields(velocityFields, []FieldSchema{
+			{Name: "partial", Type: FieldBool, Description: "Keep omitted axes at the last velocity instead of zero"},
+			commandIDField, dryRunField,
+		}),
+		Errors: []string{ErrCodeNotAuthenticated, ErrCodeInvalidMessage, ErrCodeCommandNotAllowed, ErrCodeLockRequired,
+			ErrCodeVelocityOutOfRange, ErrCodeRobotDisconnected, ErrCodeCommandTimeout, ErrCodeRobotDisabled}},
+	{Type: MsgTypeVelocityPreset, Direction: DirectionClientToGateway, Description: "Drive the robot at a named velocity preset",
+		RequiresAuth: true, RequiresRobotID: true,
+		Fields: []FieldSchema{{Name: "preset", Type: FieldString, Required: true, Description: "Preset name"}, commandIDField, dryRunField},
+		Errors: []string{ErrCodeNotAuthenticated, ErrCodeUnknownPreset, ErrCodeRobotDisabled}},
+	{Type: MsgTypeVelocityDelta, Direction: DirectionClientToGateway, Description: "Add to the last commanded velocity",
+		RequiresAuth: true, RequiresRobotID: true,
+		Fields: withFields(velocityFields, []FieldSchema{commandIDField, dryRunField}),
+		Errors: []string{ErrCodeNotAuthenticated, ErrCodeInvalidMessage, ErrCodeRobotDisabled}},
+	{Type: MsgTypeEmergencyStop, Direction: DirectionClientToGateway, Description: "Activate or release the emergency stop (all robots if robot_id is empty)",
+		RequiresAuth: true,
+		Fields: []FieldSchema{
+			{Name: "activate", Type: FieldBool, Description: "true to stop, false to release"},
+			{Name: "reason", Type: FieldString},
+		},
+		Errors: []string{ErrCodeNotAuthenticated}},
+	{Type: MsgTypeNavigationGoal, Direction: DirectionClientToGateway, Description: "Send the robot to a goal pose",
+		RequiresAuth: true, RequiresRobotID: true,
+		Fields: []FieldSchema{
+			{Name: "x", Type: FieldNumber, Description: "Goal x in meters"},
+			{Name: "y", Type: FieldNumber, Description: "Goal y in meters"},
+			{Name: "oz", Type: FieldNumber, Description: "Orientation quaternion z"},
+			{Name: "ow", Type: FieldNumber, Description: "Orientation quaternion w"},
+			{Name: "frame_id", Type: FieldString, Description: "Frame of the goal (transformed to the robot frame)"},
+			commandIDField, dryRunField,
+		},
+		Errors: []string{ErrCodeNotAuthenticated, ErrCodeCommandNotAllowed, ErrCodeUnknownFrame, ErrCodeRobotDisconnected, ErrCodeRobotDisabled}},
+	{Type: MsgTypeNavigationCancel, Direction: DirectionClientToGateway, Description: "Cancel the current navigation goal",
+		RequiresAuth: true, RequiresRobotID: true, Fields: []FieldSchema{dryRunField}, Errors: []string{ErrCodeNotAuthenticated, ErrCodeRobotDisabled}},
+	{Type: MsgTypeOperationLock, Direction: DirectionClientToGateway, Description: "Take the operation lock for the robot",
+		RequiresAuth: true, RequiresRobotID: true, Errors: []string{ErrCodeNotAuthenticated}},
+	{Type: MsgTypeOperationUnlock, Direction: DirectionClientToGateway, Description: "Release the operation lock",
+		RequiresAuth: true, RequiresRobotID: true, Errors: []string{ErrCodeNotAuthenticated}},
+	{Type: MsgTypeDock, Direction: DirectionClientToGateway, Description: "Drive to the charging dock",
+		RequiresAuth: true, RequiresRobotID: true, Fields: []FieldSchema{commandIDField, dryRunField},
+		Errors: []string{ErrCodeNotAuthenticated, ErrCodeCommandTimeout, ErrCodeRobotDisabled}},
+	{Type: MsgTypeUndock, Direction: DirectionClientToGateway, Description: "Leave the charging dock",
+		RequiresAuth: true, RequiresRobotID: true, Fields: []FieldSchema{commandIDField, dryRunField},
+		Errors: []string{ErrCodeNotAuthenticated, ErrCodeCommandTimeout, ErrCodeRobotDisabled}},
+	{Type: MsgTypeResetError, Direction: DirectionClientToGateway, Description: "Clear a fault once its cause is gone",
+		RequiresAuth: true, RequiresRobotID: true, Errors: []string{ErrCodeNotAuthenticated}},
+	{Type: MsgTypeResetPose, Direction: DirectionClientToGateway, Description: "Reset the simulated pose (admin only)",
+		RequiresAuth: true, RequiresRobotID: true,
+		Fields: []FieldSchema{
+			{Name: "x", Type: FieldNumber},
+			{Name: "y", Type: FieldNumber},
+			{Name: "theta", Type: FieldNumber},
+			{Name: "reset_battery", Type: FieldBool},
+			dryRunField,
+		},
+		Errors: []string{ErrCodeNotAuthenticated, ErrCodeForbidden, ErrCodeCommandTimeout, ErrCodeRobotDisabled}},
+	{Type: MsgTypePing, Direction: DirectionClientToGateway, Description: "Keepalive; answered with pong"},
+	{Type: MsgTypeHealthStatus, Direction: DirectionBoth, Description: "Gateway and subscribed robot health",
+		RequiresAuth: true, Errors: []string{ErrCodeNotAuthenticated}},
+	{Type: MsgTypeOdometryPath, Direction: DirectionBoth, Description: "Path the robot drove, read from Redis",
+		RequiresAuth: true, RequiresRobotID: true,
+		Fields: []FieldSchema{
+			{Name: "from_ms", Type: FieldNumber, Description: "Start of the range in Unix milliseconds"},
+			{Name: "to_ms", Type: FieldNumber, Description: "End of the range in Unix milliseconds"},
+			{Name: "max_points", Type: FieldNumber, Description: "Maximum poses to return"},
+		},
+		Errors: []string{ErrCodeNotAuthenticated}},
+	{Type: MsgTypeGetDiagnostics, Direction: DirectionBoth, Description: "Robot diagnostics (motor temperatures, firmware)",
+		RequiresAuth: true, RequiresRobotID: true, Errors: []string{ErrCodeNotAuthenticated, ErrCodeAdapterTimeout}},
+	{Type: MsgTypeLinkStats, Direction: DirectionBoth, Description: "Link quality to the robot (round-trip latency, packet loss, last contact)",
+		RequiresAuth: true, RequiresRobotID: true, Errors: []string{ErrCodeNotAuthenticated, ErrCodeAdapterTimeout}},
+	{Type: MsgTypeSubscribeAlerts, Direction: DirectionClientToGateway, Description: "Receive safety alerts for all robots",
+		RequiresAuth: true,
+		Fields:       []FieldSchema{{Name: "enabled", Type: FieldBool, Description: "Defaults to true"}},
+		Errors:       []string{ErrCodeNotAuthenticated}},
+	{Type: MsgTypeLogStream, Direction: DirectionClientToGateway, Description: "Stream gateway logs (admin only)",
+		RequiresAuth: true,
+		Fields: []FieldSchema{
+			{Name: "enabled", Type: FieldBool, Description: "Defaults to true"},
+			{Name: "level", Type: FieldString, Description: "Minimum level; defaults to info"},
+		},
+		Errors: []string{ErrCodeNotAuthenticated, ErrCodeForbidden}},
+	{Type: MsgTypeSetSpeedLimit, Direction: DirectionClientToGateway, Description: "Lower the robot's speed limit at runtime (admin only)",
+		RequiresAuth: true, RequiresRobotID: true,
+		Fields: []FieldSchema{
+			{Name: "max_linear", Type: FieldNumber, Description: "m/s"},
+			{Name: "max_angular", Type: FieldNumber, Description: "rad/s"},
+			{Name: "reset", Type: FieldBool, Description: "Return to the configured limits"},
+		},
+		Errors: []string{ErrCodeNotAuthenticated, ErrCodeForbidden, ErrCodeInvalidMessage}},
+	{Type: MsgTypeObserveCommands, Direction: DirectionClientToGateway, Description: "Receive a copy of every command other clients send to the robot (observers and admins)",
+		RequiresAuth: true, RequiresRobotID: true,
+		Fields: []FieldSchema{{Name: "enabled", Type: FieldBool, Description: "Defaults to true"}},
+		Errors: []string{ErrCodeNotAuthenticated, ErrCodeForbidden}},
+	{Type: MsgTypeSetSensorRate, Direction: DirectionClientToGateway, Description: "Change the robot's sensor emission rates at runtime (admin only)",
+		RequiresAuth: true, RequiresRobotID: true,
+		Fields: []FieldSchema{
+			{Name: "rates", Type: FieldObject, Description: "Topic name to Hz"},
+			{Name: "reset", Type: FieldBool, Description: "Return every topic to its nominal rate"},
+		},
+		Errors: []string{ErrCodeNotAuthenticated, ErrCodeForbidden, ErrCodeInvalidMessage, ErrCodeCommandNotAllowed, ErrCodeSensorRateOutOfRange}},
+	{Type: MsgTypeSetRobotEnabled, Direction: DirectionClientToGateway, Description: "Take the robot out of service or back in without disconnecting it (admin only)",
+		RequiresAuth: true, RequiresRobotID: true,
+		Fields: []FieldSchema{{Name: "enabled", Type: FieldBool, Required: true, Description: "false stops the robot and rejects its commands"}},
+		Errors: []string{ErrCodeNotAuthenticated, ErrCodeForbidden, ErrCodeInvalidMessage}},
+	{Type: MsgTypeRecordSession, Direction: DirectionClientToGateway, Description: "Start or stop recording a connection's messages to a file (admin only)",
+		RequiresAuth: true,
+		Fields: []FieldSchema{
+			{Name: "client_id", Type: FieldString, Description: "Connection to record; defaults to the sender"},
+			{Name: "enabled", Type: FieldBool, Description: "Defaults to true"},
+		},
+		Errors: []string{ErrCodeNotAuthenticated, ErrCodeForbidden, ErrCodeInvalidMessage, ErrCodeSessionRecordingDisabled}},
+	{Type: MsgTypeStopAll, Direction: DirectionClientToGateway, Description: "Stop every robot the user is operating",
+		RequiresAuth: true, Errors: []string{ErrCodeNotAuthenticated}},
+	{Type: MsgTypeGroupCommand, Direction: DirectionClientToGateway, Description: "Apply an action to every robot in a group",
+		RequiresAuth: true,
+		Fields: []FieldSchema{
+			{Name: "group", Type: FieldString, Required: true, Description: "Group ID; includes its subgroups (e.g. site-1 includes site-1/zone-a)"},
+			{Name: "action", Type: FieldString, Required: true, Description: "estop or stop"},
+			{Name: "reason", Type: FieldString, Description: "E-Stop reason"},
+		},
+		Errors: []string{ErrCodeNotAuthenticated, ErrCodeInvalidMessage, ErrCodeUnknownGroup}},
+	{Type: MsgTypeSensorRequest, Direction: DirectionClientToGateway, Description: "Read the latest value of one topic (set the topic field of the message)",
+		RequiresAuth: true, RequiresRobotID: true, Errors: []string{ErrCodeNotAuthenticated, ErrCodeNoSensorData, ErrCodeAdapterTimeout}},
+	{Type: MsgTypeDescribe, Direction: DirectionBoth, Description: "This list of message types and error codes"},
+	{Type: MsgTypeHeartbeat, Direction: DirectionClientToGateway, Description: "Application-level keepalive; no reply (required when server_heartbeat has timeout_ms > 0)"},
+
+	{Type: MsgTypeSensorData, Direction: DirectionGatewayToClient, Description: "One sensor sample for a subscribed robot",
+		Fields: []FieldSchema{
+			{Name: "data_type", Type: FieldString},
+			{Name: "frame_id", Type: FieldString},
+			{Name: "data", Type: FieldObject},
+		}},
+	{Type: MsgTypeSensorBatch, Direction: DirectionGatewayToClient, Description: "Several sensor samples in one message",
+		Fields: []FieldSchema{{Name: "samples", Type: FieldArray}, {Name: "count", Type: FieldNumber}}},
+	{Type: MsgTypeRobotStatus, Direction: DirectionGatewayToClient, Description: "Robot state and battery"},
+	{Type: MsgTypeCommandAck, Direction: DirectionGatewayToClient, Description: "A command was accepted",
+		Fields: []FieldSchema{{Name: "command", Type: FieldString}, commandIDField,
+			{Name: "dry_run", Type: FieldBool, Description: "The command was only evaluated, not sent"},
+			{Name: "result", Type: FieldObject, Description: "For dry_run: what would have been sent (e.g. clamped velocity)"},
+			{Name: "would_acquire_lock", Type: FieldBool, Description: "For dry_run: running the command would take the operation lock"}}},
+	{Type: MsgTypeLockStatus, Direction: DirectionGatewayToClient, Description: "Operation lock changes and expiry warnings"},
+	{Type: MsgTypeConnectionStatus, Direction: DirectionGatewayToClient, Description: "Robot connected, disconnected or removed",
+		Fields: []FieldSchema{{Name: "robot_connected", Type: FieldBool}, {Name: "removed", Type: FieldBool},
+			{Name: "protocol_version", Type: FieldNumber, Description: "After auth: the negotiated protocol version"},
+			{Name: "min_protocol_version", Type: FieldNumber, Description: "After auth: oldest version the gateway accepts"},
+			{Name: "max_protocol_version", Type: FieldNumber, Description: "After auth: newest version the gateway speaks"},
+			{Name: "protocol_warning", Type: FieldString, Description: "After auth: set when the requested version was not used as is"}}},
+	{Type: MsgTypeServerShutdown, Direction: DirectionGatewayToClient, Description: "The gateway is about to close the connection",
+		Fields: []FieldSchema{{Name: "reason", Type: FieldString}, {Name: "reconnect", Type: FieldBool}}},
+	{Type: MsgTypeError, Direction: DirectionGatewayToClient, Description: "A message was rejected; see error, action and retry_after_ms",
+		Fields: []FieldSchema{{Name: "code", Type: FieldString}}},
+	{Type: MsgTypePong, Direction: DirectionGatewayToClient, Description: "Answer to ping"},
+	{Type: MsgTypeSafetyAlert, Direction: DirectionGatewayToClient, Description: "E-Stop, speed limit and sensor stall alerts",
+		Fields: []FieldSchema{{Name: "type", Type: FieldString}}},
+	{Type: MsgTypeRobotEvent, Direction: DirectionGatewayToClient, Description: "An event raised by the robot itself (bumper hit, physical E-Stop, payload detected)",
+		Fields: []FieldSchema{
+			{Name: "event_type", Type: FieldString, Description: "bumper_hit, payload_detected, estop_pressed, estop_released or a robot-specific type"},
+			{Name: "severity", Type: FieldString, Description: "info, warning or critical"},
+			{Name: "message", Type: FieldString},
+			{Name: "data", Type: FieldObject, Description: "Event-specific details"},
+			{Name: "timestamp", Type: FieldNumber, Description: "When the robot raised the event, Unix milliseconds"},
+		}},
+	{Type: MsgTypeCommandObserved, Direction: DirectionGatewayToClient, Description: "A command another client sent to an observed robot was accepted",
+		Fields: []FieldSchema{
+			{Name: "command", Type: FieldString},
+			{Name: "payload", Type: FieldObject, Description: "Command as sent to the robot, after limits and transforms"},
+			{Name: "user_id", Type: FieldString, Description: "Operator who sent the command"},
+		}},
+	{Type: MsgTypeServerHeartbeat, Direction: DirectionGatewayToClient, Description: "Periodic application-level keepalive from the gateway",
+		Fields: []FieldSchema{
+			{Name: "seq", Type: FieldNumber, Description: "Increments by one per heartbeat"},
+			{Name: "server_time_ms", Type: FieldNumber, Description: "Gateway clock, Unix milliseconds"},
+			{Name: "interval_ms", Type: FieldNumber, Description: "Time between heartbeats"},
+			{Name: "timeout_ms", Type: FieldNumber, Description: "Disconnect after this long without any client message; 0 = disabled"},
+		}},
+	{Type: MsgTypeLogEntry, Direction: DirectionGatewayToClient, Description: "One gateway log line (log_stream)",
+		Fields: []FieldSchema{
+			{Name: "level", Type: FieldString},
+			{Name: "time_ms", Type: FieldNumber},
+			{Name: "logger", Type: FieldString},
+			{Name: "message", Type: FieldString},
+			{Name: "caller", Type: FieldString},
+			{Name: "fields", Type: FieldObject},
+		}},
+}
+
+// errorCodeDescriptions: エラーコードの説明（ErrCode* の定義と同じ順）
+var errorCodeDescriptions = []ErrorCodeSchema{
+	{Code: ErrCodeRobotDisconnected, Description: "The robot is disconnected; commands cannot reach it"},
+	{Code: ErrCodeUnknownPreset, Description: "No velocity preset has this name"},
+	{Code: ErrCodeInvalidMessage, Description: "The message or a payload field is malformed"},
+	{Code: ErrCodeErrorBudgetExceeded, Description: "Too many consecutive errors; the connection is closed"},
+	{Code: ErrCodeUnknownFrame, Description: "No transform from the goal's frame_id to the robot frame"},
+	{Code: ErrCodeVelocityOutOfRange, Description: "The velocity exceeds the limit and the gateway rejects instead of clamping"},
+	{Code: ErrCodeAdapterTimeout, Description: "The robot did not answer in time"},
+	{Code: ErrCodeCommandNotAllowed, Description: "The robot does not accept this command"},
+	{Code: ErrCodeForbidden, Description: "The user is not allowed to do this (admin only)"},
+	{Code: ErrCodeLockRequired, Description: "Take the operation lock with op_lock first"},
+	{Code: ErrCodeNoSensorData, Description: "No value is available for the requested topic"},
+	{Code: ErrCodeCommandTimeout, Description: "The robot did not accept the command in time"},
+	{Code: ErrCodeNotAuthenticated, Description: "Authenticate with auth first"},
+	{Code: ErrCodeRobotDisabled, Description: "The robot is out of service; only E-Stop and stop_all are accepted"},
+	{Code: ErrCodeSensorRateOutOfRange, Description: "The requested sensor rate exceeds what the sensor can emit"},
+	{Code: ErrCodeUnknownGroup, Description: "The group is not configured"},
+	{Code: ErrCodeSessionRecordingDisabled, Description: "Session recording is not configured on this gateway"},
+	{Code: ErrCodeUnsupportedProtocolVersion, Description: "The client's protocol_version is outside the supported range (see min/max_protocol_version)"},
+}
+
+// SchemaFor - メッセージタイプの定義を返す（定義がなければ ok=false）
+func SchemaFor(msgType MessageType) (MessageSchema, bool) {
+	for _, s := range messageSchemas {
+		if s.Type == msgType {
+			return s, true
+		}
+	}
+	return MessageSchema{}, false
+}
+
+// Describe - すべてのメッセージタイプとエラーコードの定義を返す
+// エラーコードには、復旧のヒント（error_hints.go）も付けます。
+func Describe() Description {
+	messages := make([]MessageSchema, len(messageSchemas))
+	copy(messages, messageSchemas)
+
+	codes := make([]ErrorCodeSchema, len(errorCodeDescriptions))
+	copy(codes, errorCodeDescriptions)
+	for i := range codes {
+		if hint, ok := HintFor(codes[i].Code); ok {
+			codes[i].Action = hint.Action
+			codes[i].RetryAfterMs = hint.RetryAfterMs
+		}
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i].Code < codes[j].Code })
+	return Description{
+		ProtocolVersion:    ProtocolVersion,
+		MinProtocolVersion: MinProtocolVersion,
+		Messages:           messages,
+		ErrorCodes:         codes,
+	}
+}
+
+// =============================================================================
+// ValidatePayload - 受信した Payload のフィールドの型を検証する
+// =============================================================================
+//
+// 定義にあるフィールドが、宣言した型で入っているかを確認します。
+// 定義にないフィールドと、値が nil のフィールドは確認しません（必須かどうかも見ません。
+// ファイル冒頭の説明を参照）。
+func (s MessageSchema) ValidatePayload(payload map[string]any) error {
+	for _, f := range s.Fields {
+		value, ok := payload[f.Name]
+		if !ok || value == nil {
+			continue
+		}
+		if !f.Type.accepts(value) {
+			return fmt.Errorf("%s must be a %s, got %T", f.Name, f.Type, value)
+		}
+	}
+	return nil
+}
+
+// accepts: value がこの型として受け付けられるか
+func (t FieldType) accepts(value any) bool {
+	switch t {
+	case FieldString:
+		_, ok := value.(string)
+		return ok
+	case FieldNumber:
+		_, ok := convert.ToFloat64(value)
+		return ok
+	case FieldBool:
+		_, ok := value.(bool)
+		return ok
+	case FieldObject:
+		_, ok := value.(map[string]any)
+		return ok
+	case FieldArray:
+		switch value.(type) {
+		case []any, []string, []float64:
+			return true
+		}
+		return false
+	}
+	return true
+}
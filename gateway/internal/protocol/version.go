@@ -0,0 +1,66 @@
+// =============================================================================
+// ファイル: version.go
+// 概要: プロトコルのバージョンと、接続時のバージョンの取り決め（ネゴシエーション）
+//
+// 【なぜ必要か？】
+// Message にはバージョンがないため、メッセージの形を変えると、古いクライアントが
+// 何も知らされないまま壊れるおそれがありました。
+// クライアントは auth の Payload に自分の話すバージョン（"protocol_version"）を入れ、
+// ゲートウェイは使うバージョンを決めて conn_status で返します。
+//
+// 【取り決めのルール】
+//
+//	送らなかった                    → 1 とみなす（バージョン導入前のクライアント）。警告付きで受け付ける
+//	MinProtocolVersion 未満         → unsupported_protocol_version で拒否（対応範囲を返す）
+//	ProtocolVersion より新しい      → ProtocolVersion で受け付ける（警告付き。クライアントが古い形に合わせる）
+//	範囲内                          → そのバージョンで受け付ける
+//
+// メッセージの形を変える時は ProtocolVersion を上げ、各ハンドラーで
+// Client.ProtocolVersion を見て古い形と新しい形を分けます。古い形をやめる時は
+// MinProtocolVersion を上げます。
+// =============================================================================
+package protocol
+
+import "fmt"
+
+const (
+	// ProtocolVersion: このゲートウェイが話す最新のプロトコルのバージョン
+	ProtocolVersion = 1
+
+	// MinProtocolVersion: このゲートウェイが受け付ける最も古いバージョン
+	MinProtocolVersion = 1
+
+	// legacyProtocolVersion: protocol_version を送らないクライアントが話しているとみなすバージョン
+	legacyProtocolVersion = 1
+)
+
+// UnsupportedVersionError - 対応範囲外のバージョンを要求された
+type UnsupportedVersionError struct {
+	Requested int
+}
+
+func (e *UnsupportedVersionError) Error() string {
+	return fmt.Sprintf("protocol version %d is not supported (supported: %d-%d)",
+		e.Requested, MinProtocolVersion, ProtocolVersion)
+}
+
+// =============================================================================
+// NegotiateVersion - クライアントが要求したバージョンから、使うバージョンを決める
+// =============================================================================
+//
+// requested は auth の "protocol_version"（送られなかった場合は 0）です。
+// 受け付ける場合は使うバージョンと、必要なら警告の文を返します。
+// 範囲外で受け付けられなければ *UnsupportedVersionError を返します。
+func NegotiateVersion(requested int) (version int, warning string, err error) {
+	switch {
+	case requested == 0:
+		return legacyProtocolVersion, fmt.Sprintf("protocol_version not sent; assuming %d (latest is %d)",
+			legacyProtocolVersion, ProtocolVersion), nil
+	case requested < MinProtocolVersion:
+		return 0, "", &UnsupportedVersionError{Requested: requested}
+	case requested > ProtocolVersion:
+		return ProtocolVersion, fmt.Sprintf("protocol version %d is newer than this gateway; using %d",
+			requested, ProtocolVersion), nil
+	}
+	return requested, "", nil
+}
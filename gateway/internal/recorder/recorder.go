@@ -0,0 +1,262 @@
+// =============================================================================
+// ファイル: recorder.go
+// 概要: WebSocket セッションの記録（受信・送信した全メッセージをファイルに書く）
+//
+// 【なぜ必要か？】
+// 「あの時クライアントは何を送り、ゲートウェイは何を返したか」を後から確かめたい場面
+// （不具合の調査、学習用データセットの作成）のためのものです。
+// Redis への永続化はセンサーデータとコマンドの記録ですが、こちらはエラー応答や
+// 購読の操作も含めた、プロトコルのやり取りそのものを記録します。
+//
+// 【ファイルの形式（JSONL）】
+// 1行に1メッセージの JSON です。デコード済みのメッセージを、クライアントが選んだ
+// フォーマットに関係なく JSON で書くため、jq などでそのまま読めます。
+//
+//	{"ts_ms": 1700000000000, "client_id": "c-1", "dir": "in",  "msg": {"type": "auth", "payload": {"token": "[REDACTED]"}}}
+//	{"ts_ms": 1700000000002, "client_id": "c-1", "dir": "out", "msg": {"type": "conn_status", ...}}
+//
+// 【秘密の値は書かない】
+// auth の "token"（JWT）は、記録する前に "[REDACTED]" に置き換えます。
+// 記録ファイルを読める人がトークンを使って他人になりすませないようにするためです。
+// ファイルは作成者だけが読めるように作ります（ディレクトリ 0700、ファイル 0600）。
+// そのため、JWT を検証するハンドラーに再生する場合は、auth のトークンを差し替えてください。
+//
+// 【ローテーション】
+// 1ファイルが maxBytes を超えそうになったら、新しいファイル（開始時刻入りの名前）に切り替えます。
+// このレコーダーが作ったファイルのうち、新しい maxFiles 個だけを残して古いものは消します。
+// 同じディレクトリにある他のファイル（以前の起動の記録など）には触りません。
+// =============================================================================
+package recorder
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"go.uber.org/zap"
+)
+
+// Direction: メッセージの向き（ゲートウェイから見て）
+type Direction string
+
+const (
+	DirectionIn  Direction = "in"  // クライアント → ゲートウェイ
+	DirectionOut Direction = "out" // ゲートウェイ → クライアント
+)
+
+// fileTimeFormat: 記録ファイル名に入れる開始時刻の書式（名前順 = 時刻順になる）
+const fileTimeFormat = "20060102-150405.000"
+
+// maxLineBytes: Load で読める1行（1メッセージ）の最大サイズ
+const maxLineBytes = 16 << 20
+
+// Redacted: 記録しない秘密の値の代わりに書く文字列
+const Redacted = "[REDACTED]"
+
+// redactedFields: メッセージの種類ごとに、記録しない Payload のフィールド
+var redactedFields = map[protocol.MessageType][]string{
+	protocol.MsgTypeAuth: {"token"},
+}
+
+// =============================================================================
+// Entry - 記録の1行
+// =============================================================================
+type Entry struct {
+	TimestampMs int64             `json:"ts_ms"`     // 記録した時刻（Unix ミリ秒）
+	ClientID    string            `json:"client_id"` // 接続のID
+	Direction   Direction         `json:"dir"`       // in / out
+	Message     *protocol.Message `json:"msg"`       // デコード済みのメッセージ
+}
+
+// =============================================================================
+// Recorder - 記録ファイルへの書き込みとローテーション
+// =============================================================================
+//
+// 複数の接続の readPump / writePump から同時に呼ばれるため、書き込みは mu で直列化します。
+type Recorder struct {
+	mu       sync.Mutex
+	dir      string
+	maxBytes int64
+	maxFiles int
+	logger   *zap.Logger
+
+	file  *os.File
+	size  int64    // 現在のファイルに書いたバイト数
+	files []string // このレコーダーが作ったファイル（古い順）
+	seq   int      // 同じミリ秒に切り替えた場合の名前の重複を避ける通し番号
+}
+
+// =============================================================================
+// New - dir に記録するレコーダーを作る
+// =============================================================================
+//
+// dir がなければ作ります。ファイルは最初のメッセージを記録した時に開きます。
+// maxBytes は1ファイルの上限、maxFiles は残すファイル数で、どちらも正の値が必要です。
+func New(dir string, maxBytes int64, maxFiles int, logger *zap.Logger) (*Recorder, error) {
+	if dir == "" {
+		return nil, errors.New("session recorder: directory is required")
+	}
+	if maxBytes <= 0 || maxFiles <= 0 {
+		return nil, fmt.Errorf("session recorder: max bytes and max files must be positive, got %d and %d", maxBytes, maxFiles)
+	}
+	// 記録には操作の内容がすべて入るため、作成者以外は読めないようにする
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("session recorder: %w", err)
+	}
+	return &Recorder{dir: dir, maxBytes: maxBytes, maxFiles: maxFiles, logger: logger}, nil
+}
+
+// Record - 1メッセージを記録する
+//
+// 書き込みに失敗してもセッション自体は続けるため、エラーはログに残すだけです。
+func (r *Recorder) Record(clientID string, dir Direction, msg *protocol.Message) {
+	line, err := json.Marshal(Entry{
+		TimestampMs: time.Now().UnixMilli(),
+		ClientID:    clientID,
+		Direction:   dir,
+		Message:     redact(msg),
+	})
+	if err != nil {
+		r.logger.Warn("Failed to encode recorded message", zap.String("client_id", clientID), zap.Error(err))
+		return
+	}
+	line = append(line, '\n')
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// 1行が上限を超える場合でも、空のファイルには書く（行を分割しない）
+	if r.file == nil || (r.size > 0 && r.size+int64(len(line)) > r.maxBytes) {
+		if err := r.rotateLocked(); err != nil {
+			r.logger.Warn("Failed to rotate session recording", zap.Error(err))
+			return
+		}
+	}
+	n, err := r.file.Write(line)
+	r.size += int64(n)
+	if err != nil {
+		r.logger.Warn("Failed to write session recording", zap.String("file", r.file.Name()), zap.Error(err))
+	}
+}
+
+// Files - このレコーダーが作り、まだ残っている記録ファイル（古い順）
+func (r *Recorder) Files() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.files...)
+}
+
+// Close - 現在のファイルを閉じる（以後の Record は新しいファイルを開く）
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// rotateLocked: 新しいファイルに切り替え、古いファイルを消す（r.mu を保持した状態で呼ぶこと）
+func (r *Recorder) rotateLocked() error {
+	if r.file != nil {
+		if err := r.file.Close(); err != nil {
+			r.logger.Warn("Failed to close session recording", zap.String("file", r.file.Name()), zap.Error(err))
+		}
+		r.file = nil
+	}
+
+	r.seq++
+	name := fmt.Sprintf("session-%s-%04d.jsonl", time.Now().Format(fileTimeFormat), r.seq)
+	path := filepath.Join(r.dir, name)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	r.file = file
+	r.size = 0
+	r.files = append(r.files, path)
+
+	for len(r.files) > r.maxFiles {
+		if err := os.Remove(r.files[0]); err != nil && !errors.Is(err, os.ErrNotExist) {
+			r.logger.Warn("Failed to remove old session recording", zap.String("file", r.files[0]), zap.Error(err))
+		}
+		r.files = r.files[1:]
+	}
+	return nil
+}
+
+// redact: 秘密のフィールドを Redacted に置き換えたメッセージを返す
+// 受信したメッセージはこの後ハンドラーが使うため、元のメッセージは変えずにコピーを作ります。
+func redact(msg *protocol.Message) *protocol.Message {
+	fields := redactedFields[msg.Type]
+	if len(fields) == 0 {
+		return msg
+	}
+	copied := *msg
+	copied.Payload = make(map[string]any, len(msg.Payload))
+	for k, v := range msg.Payload {
+		copied.Payload[k] = v
+	}
+	for _, field := range fields {
+		if _, ok := copied.Payload[field]; ok {
+			copied.Payload[field] = Redacted
+		}
+	}
+	return &copied
+}
+
+// =============================================================================
+// Load - 記録ファイルを読み込む
+// =============================================================================
+//
+// 空行は読み飛ばします。壊れた行があれば、その行番号を付けたエラーを返します。
+func Load(path string) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineBytes)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNo, err)
+		}
+		if entry.Message == nil {
+			return nil, fmt.Errorf("%s:%d: missing msg", path, lineNo)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// Messages - entries のうち、clientID の接続の dir 向きのメッセージ（記録の順番どおり）
+//
+// 再生の結果（server.ReplayRecording）と記録時の送信メッセージを比べる時などに使います。
+func Messages(entries []Entry, clientID string, dir Direction) []*protocol.Message {
+	var msgs []*protocol.Message
+	for _, entry := range entries {
+		if entry.ClientID == clientID && entry.Direction == dir {
+			msgs = append(msgs, entry.Message)
+		}
+	}
+	return msgs
+}
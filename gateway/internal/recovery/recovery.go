@@ -0,0 +1,73 @@
+// =============================================================================
+// ファイル: recovery.go（パニックからの回復）
+// 概要: ゴルーチンやハンドラーで起きた panic をログに残し、プロセスを落とさずに続けるパッケージ
+//
+// 【なぜ必要か？】
+//
+//	Go では、どこか1つのゴルーチンで panic が起きて回復しなければ、プロセス全体が終了する。
+//	このゲートウェイは Payload を map[string]any で扱うため、1つの型アサーションの
+//	書き忘れ（v.(float64) のような1値の形）で、全クライアントとロボットの接続が切れてしまう。
+//	影響を受けたもの（1つの接続、1つのセンサー生成器）だけを止め、残りは動かし続ける。
+//
+// 【使い方】
+//
+//	// 関数の先頭で defer する（recover() は defer した関数の中でしか効かない）
+//	defer recovery.Recover(logger, "handle_message", func() { /* 後始末 */ })
+//
+//	// ゴルーチンを回復付きで起動する
+//	recovery.Go(logger, "mock_odom_generator", func() { ... })
+//
+// 回復した回数は Count() で取得でき、/debug/health に表示されます。
+// =============================================================================
+package recovery
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// panics: プロセス起動からの回復した panic の回数
+var panics atomic.Uint64
+
+// =============================================================================
+// Recover - panic が起きていれば回復し、スタックトレースと一緒にログに残す
+// =============================================================================
+//
+// 必ず defer recovery.Recover(...) の形で呼んでください
+// （別の関数の中から呼ぶと recover() が panic を捕まえられません）。
+// onPanic が nil でなければ、ログの後に呼びます（接続を閉じるなどの後始末に使います）。
+// panic が起きていなければ何もしません。
+func Recover(logger *zap.Logger, where string, onPanic func(), fields ...zap.Field) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	panics.Add(1)
+	logger.Error("Recovered from panic",
+		append([]zap.Field{
+			zap.String("where", where),
+			zap.String("panic", fmt.Sprint(v)),
+			zap.ByteString("stack", debug.Stack()),
+		}, fields...)...,
+	)
+	if onPanic != nil {
+		onPanic()
+	}
+}
+
+// Go - fn を回復付きのゴルーチンで実行する
+// fn が panic した場合はログに残してゴルーチンを終了します（再起動はしません）。
+func Go(logger *zap.Logger, where string, fn func()) {
+	go func() {
+		defer Recover(logger, where, nil)
+		fn()
+	}()
+}
+
+// Count - プロセス起動から回復した panic の回数を返す
+func Count() uint64 {
+	return panics.Load()
+}
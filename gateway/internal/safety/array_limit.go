@@ -0,0 +1,206 @@
+// =============================================================================
+// ファイル: array_limit.go
+// パッケージ: safety（安全機能パッケージ）
+//
+// 【このファイルの概要】
+// センサーデータとコマンドの Payload に含まれる配列の長さを制限します。
+//
+// 【なぜ必要？】
+// WebSocket のフレームサイズの上限があっても、その範囲内で数十万要素の配列は作れます。
+// バグのあるアダプターが巨大な ranges を出したり、クライアントが受け取った
+// センサーデータをそのまま送り返したりすると、配信のたびのエンコード時間と
+// メモリ（クライアント数 × 配列の大きさ）が膨らみます。
+// 配列の要素数に上限を設け、データの大きさと関係なく使うリソースを抑えます。
+//
+// 【上限を超えた時の扱い】
+//
+//	センサーデータ  truncate  先頭から上限までに切り詰めて配信する（デフォルト）
+//	                drop      サンプルごと捨てる
+//	コマンド        常に拒否する（切り詰めるとコマンドの意味が変わるため）
+//
+// どちらも警告ログに残します（センサーデータは同じロボット×トピックで間引きます）。
+// 入れ子の map や配列の中の配列も検査します。
+// =============================================================================
+package safety
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"go.uber.org/zap"
+)
+
+// ArrayLimitMode - 上限を超えた配列を含むセンサーデータの扱い
+type ArrayLimitMode string
+
+const (
+	ArrayLimitTruncate ArrayLimitMode = "truncate"
+	ArrayLimitDrop     ArrayLimitMode = "drop"
+)
+
+// =============================================================================
+// ArrayLimiter - 配列の長さの制限器
+// =============================================================================
+type ArrayLimiter struct {
+	maxLen int
+	mode   ArrayLimitMode
+
+	mu        sync.Mutex
+	oversized map[string]uint64 // "robot_id/topic" -> 上限を超えたサンプルの件数
+
+	logger *zap.Logger
+}
+
+// NewArrayLimiter - コンストラクタ
+//
+// maxLen は配列1つあたりの要素数の上限です（0 以下なら制限しない）。
+func NewArrayLimiter(maxLen int, mode ArrayLimitMode, logger *zap.Logger) *ArrayLimiter {
+	return &ArrayLimiter{
+		maxLen:    maxLen,
+		mode:      mode,
+		oversized: make(map[string]uint64),
+		logger:    logger,
+	}
+}
+
+// =============================================================================
+// CheckSensor - センサーデータの配列を検査する（配信してよければ true）
+// =============================================================================
+//
+// truncate モードでは data.Data を切り詰めたコピーに差し替えます
+// （アダプターが持っている map やスライスは書き換えません）。
+// l が nil なら何もせず true を返します。
+func (l *ArrayLimiter) CheckSensor(data *adapter.SensorData) bool {
+	if l == nil || l.maxLen <= 0 {
+		return true
+	}
+	limited, fields := l.limit(data.Data, "")
+	if len(fields) == 0 {
+		return true
+	}
+
+	key := data.RobotID + "/" + data.Topic
+	l.mu.Lock()
+	l.oversized[key]++
+	count := l.oversized[key]
+	l.mu.Unlock()
+	if count%sensorAnomalyLogEvery == 1 {
+		l.logger.Warn("Sensor data array exceeds the size limit",
+			zap.String("robot_id", data.RobotID),
+			zap.String("topic", data.Topic),
+			zap.Strings("fields", fields),
+			zap.Int("max_len", l.maxLen),
+			zap.String("mode", string(l.mode)),
+			zap.Uint64("count", count),
+		)
+	}
+
+	if l.mode == ArrayLimitDrop {
+		return false
+	}
+	data.Data = limited.(map[string]any)
+	return true
+}
+
+// CheckPayload - コマンドの Payload の配列を検査する（上限を超えていればエラー）
+// l が nil なら何もせず nil を返します。
+func (l *ArrayLimiter) CheckPayload(payload map[string]any) error {
+	if l == nil || l.maxLen <= 0 {
+		return nil
+	}
+	if _, fields := l.limit(payload, ""); len(fields) > 0 {
+		return fmt.Errorf("%s exceeds the maximum array length %d", fields[0], l.maxLen)
+	}
+	return nil
+}
+
+// Oversized - ロボット×トピックごとの、上限を超えたサンプルの件数のコピー
+func (l *ArrayLimiter) Oversized() map[string]uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make(map[string]uint64, len(l.oversized))
+	for k, n := range l.oversized {
+		out[k] = n
+	}
+	return out
+}
+
+// limit: 値に含まれる上限を超えた配列を切り詰める
+//
+//	out:    切り詰めた値（上限を超えた配列がなければ val そのもの）
+//	fields: 上限を超えた配列の場所（例: "ranges", "transforms[0].points"）
+//
+// 変更がある時だけ map やスライスをコピーするため、ふつうのデータでは割り当てが発生しません。
+func (l *ArrayLimiter) limit(val any, path string) (out any, fields []string) {
+	switch v := val.(type) {
+	case map[string]any:
+		var copied map[string]any
+		for key, item := range v {
+			limited, found := l.limit(item, joinArrayPath(path, key))
+			if len(found) == 0 {
+				continue
+			}
+			fields = append(fields, found...)
+			if copied == nil {
+				copied = make(map[string]any, len(v))
+				for k, orig := range v {
+					copied[k] = orig
+				}
+			}
+			copied[key] = limited
+		}
+		if copied == nil {
+			return val, fields
+		}
+		return copied, fields
+
+	case []any:
+		items := v
+		if len(items) > l.maxLen {
+			fields = append(fields, path)
+			items = items[:l.maxLen:l.maxLen]
+		}
+		var copied []any
+		for i, item := range items {
+			limited, found := l.limit(item, fmt.Sprintf("%s[%d]", path, i))
+			if len(found) == 0 {
+				continue
+			}
+			fields = append(fields, found...)
+			if copied == nil {
+				copied = make([]any, len(items))
+				copy(copied, items)
+			}
+			copied[i] = limited
+		}
+		if copied != nil {
+			return copied, fields
+		}
+		return items, fields
+
+	case []float64:
+		if len(v) > l.maxLen {
+			return v[:l.maxLen:l.maxLen], []string{path}
+		}
+	case []float32:
+		if len(v) > l.maxLen {
+			return v[:l.maxLen:l.maxLen], []string{path}
+		}
+	case []int:
+		if len(v) > l.maxLen {
+			return v[:l.maxLen:l.maxLen], []string{path}
+		}
+	case []byte:
+		// バイト列（画像など）は配列ではなく1つの値として扱う（大きさはフレームサイズで制限される）
+	}
+	return val, nil
+}
+
+// joinArrayPath: 入れ子のキーを "a.b" の形につなぐ
+func joinArrayPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
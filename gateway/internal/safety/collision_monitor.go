@@ -0,0 +1,271 @@
+// =============================================================================
+// ファイル: collision_monitor.go
+// パッケージ: safety（安全機能パッケージ）
+//
+// 【このファイルの概要】
+// 「衝突リスク監視（Collision Monitor）」を実装するファイルです。
+// LiDAR のスキャン（scan トピック）を見て、ロボットが障害物に向かって
+// 動かされていたら、自動で止めるためのきっかけ（コールバック）を出します。
+//
+// 【判定の仕組み】
+//
+//	指令された速度（linear_x, linear_y）の向き = 進行方向
+//	進行方向 ± coneHalfAngle の扇形の中に、stopDistance より近い測定値があれば「衝突リスク」
+//
+//	            進行方向
+//	               ↑
+//	        ＼  ・障害物（0.3m）  ／   ← 扇形の中で stopDistance（0.5m）より近い → リスク
+//	          ＼    |    ／
+//	            ＼  |  ／
+//	             [ロボット]
+//
+// 後退中は後ろ、横移動中は横が進行方向になります。その場での旋回だけ（並進速度 0）や、
+// 止まっている時は判定しません。スキャンの角度はロボットの前方が 0（反時計回りが正）で、
+// LiDAR の取り付けの向きがロボットと同じであることを前提にしています。
+//
+// 【止め方（CollisionAction）】
+//
+//	estop:    E-Stop を発動する（解除するまでコマンドを受け付けない）
+//	override: 速度 0 を送る（次の速度コマンドは受け付けるが、障害物に向かえば再び止める）
+//
+// 実際に止めるのはコールバック（server パッケージの NotifyCollisionRisk）です。
+// このパッケージはロボットへの送信を行わず、判定だけを担当します（SensorStallDetector と同じ分担）。
+// =============================================================================
+package safety
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/convert"
+	"go.uber.org/zap"
+)
+
+// CollisionAction - 衝突リスクを検出した時の止め方
+type CollisionAction string
+
+const (
+	// CollisionActionEStop: E-Stop を発動する（デフォルト）
+	CollisionActionEStop CollisionAction = "estop"
+	// CollisionActionOverride: 速度 0 で上書きする（E-Stop にはしない）
+	CollisionActionOverride CollisionAction = "override"
+)
+
+// ParseCollisionAction - 設定の文字列を CollisionAction に変換する
+func ParseCollisionAction(s string) (CollisionAction, error) {
+	switch action := CollisionAction(s); action {
+	case CollisionActionEStop, CollisionActionOverride:
+		return action, nil
+	}
+	return "", fmt.Errorf("invalid collision action %q: must be estop or override", s)
+}
+
+// minMovingSpeed: これより遅い並進速度は「止まっている」とみなす（m/s）
+const minMovingSpeed = 1e-3
+
+// =============================================================================
+// CollisionSettings - ロボットごとの監視の設定
+// =============================================================================
+type CollisionSettings struct {
+	// Enabled: このロボットを監視するか
+	Enabled bool
+	// StopDistance: 進行方向の障害物がこの距離（m）より近ければ止める
+	StopDistance float64
+}
+
+// =============================================================================
+// CollisionRisk - 検出した衝突リスク（コールバックに渡す）
+// =============================================================================
+type CollisionRisk struct {
+	RobotID      string
+	Distance     float64         // 進行方向で最も近い障害物までの距離（m）
+	Bearing      float64         // その障害物の方向（rad、ロボットの前方が 0、反時計回りが正）
+	Heading      float64         // 指令された進行方向（rad）
+	StopDistance float64         // 判定に使った距離（m）
+	Action       CollisionAction // 止め方
+
+	// New: 前回のスキャンではリスクがなかったか（アラートを1回だけ送るために使う）
+	// リスクが続く間もスキャンごとにコールバックを呼ぶため、止める処理は毎回、通知は New の時だけ行えます。
+	New bool
+}
+
+// =============================================================================
+// CollisionMonitor - 衝突リスク監視構造体
+// =============================================================================
+type CollisionMonitor struct {
+	mu            sync.Mutex
+	defaults      CollisionSettings            // 個別の設定がないロボットの設定
+	robots        map[string]CollisionSettings // robot_id -> 個別の設定
+	coneHalfAngle float64                      // 進行方向の扇形の半分の角度（rad）
+	action        CollisionAction
+	atRisk        map[string]bool // 前回のスキャンでリスクがあったロボット
+
+	// velocity: ロボットに指令している速度を返す関数（SetVelocitySource で設定）
+	velocity func(robotID string) adapter.Velocity
+
+	// onRisk: 衝突リスクを検出した時のコールバック（SetRiskCallback で設定）
+	onRisk func(risk CollisionRisk)
+
+	logger *zap.Logger
+}
+
+// NewCollisionMonitor - コンストラクタ
+// defaults: 個別の設定がないロボットの設定、coneHalfAngle: 扇形の半分の角度（rad）、action: 止め方
+func NewCollisionMonitor(defaults CollisionSettings, coneHalfAngle float64, action CollisionAction, logger *zap.Logger) *CollisionMonitor {
+	return &CollisionMonitor{
+		defaults:      defaults,
+		robots:        make(map[string]CollisionSettings),
+		coneHalfAngle: coneHalfAngle,
+		action:        action,
+		atRisk:        make(map[string]bool),
+		logger:        logger,
+	}
+}
+
+// SetRobotSettings - ロボットごとの設定（robot_id -> 設定）を置き換える
+func (m *CollisionMonitor) SetRobotSettings(settings map[string]CollisionSettings) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.robots = make(map[string]CollisionSettings, len(settings))
+	for robotID, s := range settings {
+		m.robots[robotID] = s
+	}
+}
+
+// Settings - ロボットに適用される設定を返す
+func (m *CollisionMonitor) Settings(robotID string) CollisionSettings {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.settingsLocked(robotID)
+}
+
+// SetVelocitySource - 指令している速度の取得元を設定する
+// Handler.LastVelocity をそのまま渡せる形です（E-Stop やウォッチドッグで止めると 0 に戻る）。
+// スキャンが届く前に一度だけ呼んでください。
+func (m *CollisionMonitor) SetVelocitySource(fn func(robotID string) adapter.Velocity) {
+	m.velocity = fn
+}
+
+// SetRiskCallback - 衝突リスクを検出した時に呼ぶ関数を設定する
+// スキャンが届く前に一度だけ呼んでください。
+func (m *CollisionMonitor) SetRiskCallback(fn func(risk CollisionRisk)) {
+	m.onRisk = fn
+}
+
+// =============================================================================
+// CheckScan - 1回分の LiDAR スキャンで衝突リスクを判定する
+// =============================================================================
+//
+// センサー転送処理がスキャン（data_type "lidar"）を受け取るたびに呼びます。
+// data は angle_min, angle_increment, range_min, range_max, ranges を持つスキャンのデータです。
+// リスクがあればコールバックを呼び、その内容と true を返します。
+func (m *CollisionMonitor) CheckScan(robotID string, data map[string]any) (CollisionRisk, bool) {
+	m.mu.Lock()
+	settings := m.settingsLocked(robotID)
+	m.mu.Unlock()
+	if !settings.Enabled || m.velocity == nil {
+		return CollisionRisk{}, false
+	}
+
+	risk, found := m.evaluate(robotID, settings, data)
+
+	m.mu.Lock()
+	wasAtRisk := m.atRisk[robotID]
+	if found {
+		m.atRisk[robotID] = true
+	} else {
+		delete(m.atRisk, robotID)
+	}
+	m.mu.Unlock()
+	if !found {
+		return CollisionRisk{}, false
+	}
+
+	risk.New = !wasAtRisk
+	if risk.New {
+		m.logger.Warn("Collision risk detected",
+			zap.String("robot_id", robotID),
+			zap.Float64("distance", risk.Distance),
+			zap.Float64("bearing", risk.Bearing),
+			zap.Float64("stop_distance", risk.StopDistance),
+			zap.String("action", string(risk.Action)),
+		)
+	}
+	if m.onRisk != nil {
+		m.onRisk(risk)
+	}
+	return risk, true
+}
+
+// evaluate: 進行方向の扇形の中で、最も近い stopDistance 未満の測定値を探す
+func (m *CollisionMonitor) evaluate(robotID string, settings CollisionSettings, data map[string]any) (CollisionRisk, bool) {
+	v := m.velocity(robotID)
+	if math.Hypot(v.LinearX, v.LinearY) < minMovingSpeed {
+		return CollisionRisk{}, false
+	}
+	heading := math.Atan2(v.LinearY, v.LinearX)
+
+	angleMin, _ := convert.ToFloat64(data["angle_min"])
+	increment, _ := convert.ToFloat64(data["angle_increment"])
+	rangeMin, _ := convert.ToFloat64(data["range_min"])
+	rangeMax, hasMax := convert.ToFloat64(data["range_max"])
+	ranges, ok := scanRanges(data["ranges"])
+	if !ok || increment == 0 {
+		return CollisionRisk{}, false
+	}
+
+	risk := CollisionRisk{RobotID: robotID, Heading: heading, StopDistance: settings.StopDistance, Action: m.action}
+	found := false
+	for i, r := range ranges {
+		// 欠損（range_min 未満）や測定範囲外、NaN の測定値は障害物として扱わない
+		if math.IsNaN(r) || r < rangeMin || (hasMax && r > rangeMax) || r >= settings.StopDistance {
+			continue
+		}
+		bearing := angleMin + float64(i)*increment
+		if math.Abs(angleDiff(bearing, heading)) > m.coneHalfAngle {
+			continue
+		}
+		if !found || r < risk.Distance {
+			risk.Distance = r
+			risk.Bearing = math.Remainder(bearing, 2*math.Pi)
+			found = true
+		}
+	}
+	return risk, found
+}
+
+// settingsLocked: ロボットに適用される設定（m.mu を保持した状態で呼ぶこと）
+func (m *CollisionMonitor) settingsLocked(robotID string) CollisionSettings {
+	if s, ok := m.robots[robotID]; ok {
+		return s
+	}
+	return m.defaults
+}
+
+// angleDiff: 角度 a と b の差を -π〜π の範囲で返す
+func angleDiff(a, b float64) float64 {
+	return math.Remainder(a-b, 2*math.Pi)
+}
+
+// scanRanges: スキャンの ranges を []float64 として読む
+// アダプターが直接作ったデータは []float64、デコードしたデータは []any で届きます。
+func scanRanges(v any) ([]float64, bool) {
+	switch values := v.(type) {
+	case []float64:
+		return values, true
+	case []any:
+		ranges := make([]float64, len(values))
+		for i, item := range values {
+			r, ok := convert.ToFloat64(item)
+			if !ok {
+				r = math.NaN() // 数値でない測定値は無効値として扱う
+			}
+			ranges[i] = r
+		}
+		return ranges, true
+	}
+	return nil, false
+}
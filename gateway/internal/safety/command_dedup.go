@@ -0,0 +1,238 @@
+// =============================================================================
+// ファイル: command_dedup.go（コマンドの重複排除）
+// 概要: 同じコマンドの再送による二重実行を防ぐ（べき等性の確保）
+// =============================================================================
+//
+// 【なぜ必要か？】
+// クライアントは ACK がタイムアウトすると、同じコマンドを再送することがあります。
+// 実際には最初のコマンドが届いていた場合、ロボットは同じ動作を2回行ってしまい、
+// 次のような事故につながります。
+//   - ナビゲーション目標: 到着後にもう一度同じ経路を走り出す
+//   - ドッキング: 離脱直後に再びドックへ戻る
+//
+// そのため再送は「実行せずに最初の ACK を返す」必要があります。
+//
+// 【仕組み】
+// クライアントがコマンドに一意な command_id を付けて送ると、
+// (送信者, ロボットID, コマンド種別, command_id) をキーに、最初の ACK を一定時間（ウィンドウ）記録します。
+// ウィンドウ内に同じキーのコマンドが来たら、記録した ACK をそのまま返します。
+// 送信者（ユーザーID）をキーに含めるのは、別のユーザーがたまたま同じ command_id を使った時に、
+// そのコマンドを実行せずに他人の ACK を返してしまわないためです。
+//
+// 【確認と予約を1回で行う】
+// 「記録があるか確認する」と「ACK を記録する」の間にはコマンドの実行が挟まります。
+// 別々に行うと、同時に届いた2つの再送がどちらも「記録なし」と判断されて両方実行されます。
+// そのため LookupOrReserve で、記録がなければその場で「実行中」として予約します。
+// 予約中に届いた同じコマンドは実行しません（DedupInProgress）。
+// 実行が ACK まで進めば Remember で ACK を記録し、途中で失敗したら Release で予約を取り消します
+// （取り消さないと、クライアントが失敗したコマンドを送り直せなくなります）。
+//
+// 【オプトイン】
+// 速度コマンドのように高頻度で、重複しても次の指令で上書きされるものには不要です。
+// そのため、重複排除する種別は設定（GATEWAY_CMD_DEDUP_TYPES）で選びます。
+// command_id を付けないコマンドは、従来どおり毎回実行されます。
+// =============================================================================
+package safety
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// dedupEntry: 記録済みコマンド1件分の情報
+type dedupEntry struct {
+	ackPayload map[string]any // 最初に返した ACK のペイロード（nil なら予約中 = 実行中）
+	expiresAt  time.Time      // この時刻を過ぎたら記録を破棄する
+}
+
+// DedupResult - LookupOrReserve の結果
+type DedupResult int
+
+const (
+	// DedupNew: 記録がなかったので予約した。呼び出し側がコマンドを実行する
+	DedupNew DedupResult = iota
+	// DedupReplay: 実行済みのコマンド。最初の ACK のペイロードを返す
+	DedupReplay
+	// DedupInProgress: 同じコマンドを実行中（予約済み）。実行しない
+	DedupInProgress
+)
+
+// =============================================================================
+// CommandDeduplicator - コマンド重複排除器
+// =============================================================================
+type CommandDeduplicator struct {
+	mu      sync.Mutex
+	entries map[string]*dedupEntry // "scope|robotID|command|commandID" -> 記録
+	types   map[string]bool        // 重複排除の対象となるコマンド種別
+	window  time.Duration          // 記録を保持する時間
+	logger  *zap.Logger
+}
+
+// =============================================================================
+// NewCommandDeduplicator - コンストラクタ
+// =============================================================================
+//
+// 引数:
+//   - window: 重複とみなす時間幅。0 以下なら重複排除は無効になります。
+//   - commandTypes: 対象とするコマンド種別（ACK の "command" と同じ名前。例: "nav_goal"）
+func NewCommandDeduplicator(window time.Duration, commandTypes []string, logger *zap.Logger) *CommandDeduplicator {
+	types := make(map[string]bool, len(commandTypes))
+	for _, t := range commandTypes {
+		types[t] = true
+	}
+	return &CommandDeduplicator{
+		entries: make(map[string]*dedupEntry),
+		types:   types,
+		window:  window,
+		logger:  logger,
+	}
+}
+
+// Enabled: 指定したコマンド種別が重複排除の対象かどうか
+func (d *CommandDeduplicator) Enabled(command string) bool {
+	return d.window > 0 && d.types[command]
+}
+
+// =============================================================================
+// Lookup - 記録済みのコマンドかを確認する
+// =============================================================================
+//
+// scope は送信者（ユーザーID）です。予約中（実行中）のコマンドは記録済みとはみなしません。
+// ウィンドウ内に同じコマンドが記録されていれば、最初の ACK ペイロードのコピーと true を返します。
+// コピーを返すのは、呼び出し側が ACK に項目を追加しても記録が変わらないようにするためです。
+func (d *CommandDeduplicator) Lookup(scope, robotID, command, commandID string) (map[string]any, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	e, ok := d.entries[dedupKey(scope, robotID, command, commandID)]
+	if !ok || e.ackPayload == nil || time.Now().After(e.expiresAt) {
+		return nil, false
+	}
+	return copyPayload(e.ackPayload), true
+}
+
+// =============================================================================
+// LookupOrReserve - 記録を確認し、なければ実行中として予約する
+// =============================================================================
+//
+// 確認と予約を1つのロックの中で行うため、同時に届いた同じコマンドのうち
+// DedupNew になるのは1つだけです。DedupReplay の時は最初の ACK ペイロードのコピーを返します。
+// DedupNew を受け取った呼び出し側は、Remember（成功）か Release（失敗）を必ず呼んでください。
+func (d *CommandDeduplicator) LookupOrReserve(scope, robotID, command, commandID string) (map[string]any, DedupResult) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key := dedupKey(scope, robotID, command, commandID)
+	now := time.Now()
+	if e, ok := d.entries[key]; ok && !now.After(e.expiresAt) {
+		if e.ackPayload == nil {
+			return nil, DedupInProgress
+		}
+		return copyPayload(e.ackPayload), DedupReplay
+	}
+
+	// 予約にも期限を付け、Release が呼ばれなくてもウィンドウ後には送り直せるようにする
+	d.entries[key] = &dedupEntry{expiresAt: now.Add(d.window)}
+	return nil, DedupNew
+}
+
+// =============================================================================
+// Remember - 実行したコマンドの ACK を記録する
+// =============================================================================
+//
+// 予約があればそれを ACK の記録に置き換えます。
+// 対象外の種別や command_id が空のコマンドは記録しません。
+func (d *CommandDeduplicator) Remember(scope, robotID, command, commandID string, ackPayload map[string]any) {
+	if commandID == "" || !d.Enabled(command) {
+		return
+	}
+
+	payload := copyPayload(ackPayload)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.entries[dedupKey(scope, robotID, command, commandID)] = &dedupEntry{
+		ackPayload: payload,
+		expiresAt:  time.Now().Add(d.window),
+	}
+}
+
+// =============================================================================
+// Release - 実行できなかったコマンドの予約を取り消す
+// =============================================================================
+//
+// 予約中の記録だけを削除します。既に Remember で ACK を記録していれば何もしません。
+func (d *CommandDeduplicator) Release(scope, robotID, command, commandID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key := dedupKey(scope, robotID, command, commandID)
+	if e, ok := d.entries[key]; ok && e.ackPayload == nil {
+		delete(d.entries, key)
+	}
+}
+
+// =============================================================================
+// StartCleanup - 期限切れの記録を定期的に削除するゴルーチンを起動する
+// =============================================================================
+//
+// 記録を消さないとメモリが増え続けるため、ウィンドウと同じ間隔で掃除します。
+// done チャネルが閉じられると停止します（OperationLock.StartCleanup と同じ形）。
+// wg が nil でなければゴルーチンを登録し、終了時に Done() します。
+func (d *CommandDeduplicator) StartCleanup(done <-chan struct{}, wg *sync.WaitGroup) {
+	if d.window <= 0 {
+		return
+	}
+	if wg != nil {
+		wg.Add(1)
+	}
+	go func() {
+		if wg != nil {
+			defer wg.Done()
+		}
+		ticker := time.NewTicker(d.window)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				d.cleanupExpired()
+			}
+		}
+	}()
+}
+
+// cleanupExpired: 期限切れの記録をまとめて削除する
+func (d *CommandDeduplicator) cleanupExpired() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	removed := 0
+	for key, e := range d.entries {
+		if now.After(e.expiresAt) {
+			delete(d.entries, key)
+			removed++
+		}
+	}
+	if removed > 0 {
+		d.logger.Debug("Expired command dedup entries cleaned up", zap.Int("count", removed))
+	}
+}
+
+// dedupKey: マップのキーを作る。"|" は送信者・ロボットID・コマンド種別に含まれない前提の区切り文字。
+func dedupKey(scope, robotID, command, commandID string) string {
+	return scope + "|" + robotID + "|" + command + "|" + commandID
+}
+
+// copyPayload: ACK ペイロードの浅いコピーを作る
+func copyPayload(payload map[string]any) map[string]any {
+	copied := make(map[string]any, len(payload))
+	for k, v := range payload {
+		copied[k] = v
+	}
+	return copied
+}
@@ -29,12 +29,18 @@ import (
 	// Goでは、外部通信を行う関数の第1引数にctx context.Contextを渡すのが慣例です。
 	"context"
 
+	// sort: 結果のロボットIDを並べ替える（ログ・アラートの順序を安定させる）
+	"sort"
+
 	// sync: 「同期（synchronization）」パッケージ
 	// 複数のゴルーチン（goroutine = Goの軽量スレッド）から
 	// 同じデータに安全にアクセスするための仕組みを提供します。
 	// ここではRWMutex（読み書きロック）を使います。
 	"sync"
 
+	// time: EmergencyStop 再試行の待ち時間
+	"time"
+
 	// adapter: 自作のアダプターパッケージ
 	// 様々な種類のロボット（ROS2、MQTT、gRPCなど）を
 	// 統一的なインターフェースで操作するためのパッケージです。
@@ -112,8 +118,19 @@ type EStopManager struct {
 	// 緊急停止の発動・解除などの重要なイベントをログに記録します。
 	// 安全機能なので、すべての操作を記録することが重要です。
 	logger *zap.Logger
+
+	// retryAttempts / retryBackoff: ActivateAll で EmergencyStop が失敗したときの
+	// 再試行回数と、最初の再試行までの待ち時間（SetRetryPolicy で変更できる）
+	retryAttempts int
+	retryBackoff  time.Duration
 }
 
+// ActivateAll で EmergencyStop が失敗したときの再試行のデフォルト
+const (
+	defaultEStopRetryAttempts = 2
+	defaultEStopRetryBackoff  = 100 * time.Millisecond
+)
+
 // =============================================================================
 // NewEStopManager - EStopManagerのコンストラクタ関数
 // =============================================================================
@@ -138,7 +155,10 @@ func NewEStopManager(registry *adapter.Registry, logger *zap.Logger) *EStopManag
 		// 必ず make() で初期化してから使います。
 		active:   make(map[string]bool),
 		registry: registry,
-		logger:   logger,
+		// 全体停止の遅れを抑えるため、再試行は短く少なめにする
+		retryAttempts: defaultEStopRetryAttempts,
+		retryBackoff:  defaultEStopRetryBackoff,
+		logger:        logger,
 	}
 }
 
@@ -226,6 +246,39 @@ func (e *EStopManager) Activate(ctx context.Context, robotID, userID, reason str
 	return nil
 }
 
+// =============================================================================
+// EStopAllResult - 全体緊急停止の結果
+// =============================================================================
+//
+// 【3つの状態】
+//   - Stopped:      EmergencyStop が成功したロボット
+//   - Failed:       再試行しても EmergencyStop が失敗したロボット。
+//     E-Stop 状態としては記録済み（以後のコマンドは拒否される）だが、
+//     ハードウェアはまだ動いている可能性がある
+//   - Disconnected: Failed のうち、切断によってコマンドの流れを断てたロボット
+//     （Failed の部分集合。Failed にあって Disconnected にないロボットは
+//     切断にも失敗しており、現場での物理的な対応が必要）
+//
+// どのスライスもロボットID順に並んでいます（ログやアラートを読みやすくするため）。
+type EStopAllResult struct {
+	Stopped      []string
+	Failed       []string
+	Disconnected []string
+}
+
+// =============================================================================
+// SetRetryPolicy - EmergencyStop 失敗時の再試行回数と待ち時間を設定する
+// =============================================================================
+//
+// ActivateAll は、EmergencyStop に失敗したロボットに対して attempts 回まで再試行します。
+// 1回目の再試行の前に backoff だけ待ち、以後は待ち時間を2倍ずつ延ばします。
+// attempts が 0 なら再試行せず、すぐに切断へエスカレーションします。
+// サーバー起動前（E-Stop を受け付ける前）に一度だけ呼んでください。
+func (e *EStopManager) SetRetryPolicy(attempts int, backoff time.Duration) {
+	e.retryAttempts = max(attempts, 0)
+	e.retryBackoff = backoff
+}
+
 // =============================================================================
 // ActivateAll - すべてのロボットを一斉に緊急停止する
 // =============================================================================
@@ -235,28 +288,20 @@ func (e *EStopManager) Activate(ctx context.Context, robotID, userID, reason str
 // 一つのボタンですべてを停止させるための機能です。
 // 「全体緊急停止」は安全システムの必須機能です。
 //
-// 【戻り値】
-// - int: 正常に停止できたロボットの数
-// - []string: 停止に失敗したロボットIDのリスト（スライス）
+// 【失敗したロボットの扱い】
+// 停止命令が届かなかったロボットは、E-Stop 状態として記録されていても
+// 実機は動き続けているかもしれません。そこで次の順にエスカレーションします。
+//  1. 短い待ち時間を挟んで EmergencyStop を再試行する（SetRetryPolicy）
+//  2. それでも失敗したら Disconnect を試み、以後のコマンドの流れを断つ
+//  3. 結果を EStopAllResult で返す（呼び出し側が失敗をオペレーターに通知する）
 //
-// 【スライス（slice）とは？】
-// []string はスライス型です。配列に似ていますが、サイズが可変です。
-// Pythonのリスト（list）に似ています。
-// append() で要素を追加できます。
-func (e *EStopManager) ActivateAll(ctx context.Context, userID, reason string) (int, []string) {
+// 【戻り値】
+// - EStopAllResult: 停止できた・失敗した・切断したロボットIDのリスト
+func (e *EStopManager) ActivateAll(ctx context.Context, userID, reason string) EStopAllResult {
 	// すべてのアクティブなアダプターを取得する
 	// 戻り値は map[string]RobotAdapter（ロボットID → アダプター のmap）
 	adapters := e.registry.GetAllActive()
 
-	// 停止成功カウンターと失敗リストを初期化
-	stopped := 0
-	var failed []string
-	// 【var と := の違い】
-	// var failed []string  → 変数を宣言する（初期値は nil）
-	// failed := []string{} → 変数を宣言と同時に空のスライスで初期化する
-	// ここでは var を使っているので、failed は nil（要素なし）で始まります。
-	// nil スライスでも append() は正常に動作します。
-
 	// --- すべてのロボットの緊急停止状態をmapに記録する ---
 	e.mu.Lock()
 	// range: mapやスライスをイテレーション（繰り返し）するキーワード
@@ -268,37 +313,81 @@ func (e *EStopManager) ActivateAll(ctx context.Context, userID, reason string) (
 	e.mu.Unlock()
 
 	// --- 各ロボットに緊急停止コマンドを送信する ---
-	// range で map をイテレーションし、各アダプターに停止命令を送ります
+	// 1回目は全ロボットへ待たずに送り、失敗したものだけを後で再試行します
+	// （1台の再試行待ちで、他のロボットの停止が遅れないようにするため）。
+	var result EStopAllResult
+	var pending []string
 	for robotID, adp := range adapters {
 		if err := adp.EmergencyStop(ctx); err != nil {
-			// 停止に失敗した場合
-
-			// logger.Error(): エラーレベルのログを出力する
-			// zap.Error(err): エラーオブジェクトをログに含める
 			e.logger.Error("Failed to E-Stop robot",
 				zap.String("robot_id", robotID),
 				zap.Error(err),
 			)
-			// 失敗リストにロボットIDを追加する
-			// append(): スライスに要素を追加するGoの組み込み関数
-			failed = append(failed, robotID)
+			pending = append(pending, robotID)
 		} else {
-			// 停止に成功した場合、カウンターを増やす
-			stopped++
+			result.Stopped = append(result.Stopped, robotID)
+		}
+	}
+
+	// --- 失敗したロボットを、待ち時間を延ばしながら再試行する ---
+	backoff := e.retryBackoff
+retry:
+	for attempt := 1; attempt <= e.retryAttempts && len(pending) > 0; attempt++ {
+		select {
+		case <-ctx.Done():
+			break retry // キャンセルされたら再試行を打ち切り、切断へ進む
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+
+		var still []string
+		for _, robotID := range pending {
+			if err := adapters[robotID].EmergencyStop(ctx); err != nil {
+				e.logger.Error("E-Stop retry failed",
+					zap.String("robot_id", robotID),
+					zap.Int("attempt", attempt),
+					zap.Error(err),
+				)
+				still = append(still, robotID)
+			} else {
+				result.Stopped = append(result.Stopped, robotID)
+			}
 		}
+		pending = still
 	}
 
+	// --- それでも止まらないロボットは切断して、コマンドの流れを断つ ---
+	// 切断できても実機が止まった保証はないので、Failed には残したままにします。
+	for _, robotID := range pending {
+		result.Failed = append(result.Failed, robotID)
+		if err := adapters[robotID].Disconnect(ctx); err != nil {
+			e.logger.Error("Failed to disconnect robot after E-Stop failure",
+				zap.String("robot_id", robotID),
+				zap.Error(err),
+			)
+			continue
+		}
+		e.logger.Warn("Disconnected robot after E-Stop failure",
+			zap.String("robot_id", robotID),
+		)
+		result.Disconnected = append(result.Disconnected, robotID)
+	}
+
+	sort.Strings(result.Stopped)
+	sort.Strings(result.Failed)
+	sort.Strings(result.Disconnected)
+
 	// 全体緊急停止の結果をログに出力する
 	// zap.Int(): int型の値をログに含める
 	e.logger.Warn("E-STOP ALL ACTIVATED",
 		zap.String("user_id", userID),
 		zap.String("reason", reason),
-		zap.Int("stopped", stopped),
-		zap.Int("failed", len(failed)),
+		zap.Int("stopped", len(result.Stopped)),
+		zap.Int("failed", len(result.Failed)),
+		zap.Int("disconnected", len(result.Disconnected)),
 	)
 
-	// 停止成功数と失敗ロボットIDのリストを返す
-	return stopped, failed
+	return result
 }
 
 // =============================================================================
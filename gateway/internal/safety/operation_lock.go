@@ -42,6 +42,9 @@ import (
 	// time.Duration（期間）、time.Time（時刻）、time.Ticker（定期タイマー）など。
 	"time"
 
+	// clock: 現在時刻の取得元（テストでは手で進める時計に差し替える）
+	"github.com/robot-ai-webapp/gateway/internal/clock"
+
 	// zap: 高性能ロガー（Uber社製）
 	// 構造化ログを出力します。ロックの取得・解放などのイベントを記録します。
 	"go.uber.org/zap"
@@ -68,6 +71,18 @@ type LockInfo struct {
 	// ExpiresAt: ロックの有効期限
 	// この時刻を過ぎると、ロックは自動的に無効になります。
 	ExpiresAt time.Time
+
+	// warned: 期限切れ前の警告を送ったか（延長するとリセットされる）
+	warned bool
+
+	// persistedAt: 最後に保存先（SetStore）へ書き込んだ時刻
+	persistedAt time.Time
+}
+
+// lockCooldown: 最大保持時間に達したユーザーが再取得できるようになる時刻
+type lockCooldown struct {
+	userID string
+	until  time.Time
 }
 
 // =============================================================================
@@ -94,11 +109,45 @@ type OperationLock struct {
 	// 3. メモリ効率が良い（大きな構造体をコピーしない）
 	locks map[string]*LockInfo // robot_id -> lock info
 
+	// userLocks: ユーザーIDから、そのユーザーがロックしているロボットIDの集合へのmap
+	// locks と常に同時に更新し、ユーザーごとの保持数を数えるのに使います。
+	userLocks map[string]map[string]bool // user_id -> robot_id -> true
+
+	// maxPerUser: 1人のユーザーが同時に保持できるロックの数（0 なら無制限）
+	// exemptUsers: maxPerUser の対象外とするユーザー（管理者など）
+	maxPerUser  int
+	exemptUsers map[string]bool
+
 	// timeout: ロックの有効期限（デフォルトの継続時間）
 	// time.Duration型は「期間」を表します。
 	// 例: 5 * time.Minute = 5分
 	timeout time.Duration
 
+	// maxHold: 1つのロックを保持し続けられる最大時間（0 なら無制限）
+	// 取得からこの時間を過ぎると、同じユーザーでも延長できません。
+	maxHold time.Duration
+
+	// cooldowns: 最大保持時間に達して期限切れになったロックの記録（robot_id -> 記録）
+	// 同じユーザーがすぐに取り直して独占し続けるのを防ぐため、
+	// タイムアウト1回分の間は、そのユーザーの再取得を拒否します。
+	cooldowns map[string]lockCooldown
+
+	// warnBefore: 期限切れの何秒前に警告するか（0 なら警告しない）
+	// onExpiring: 警告を送る関数（SetExpiryWarning で設定）
+	warnBefore time.Duration
+	onExpiring func(lock LockInfo, remaining time.Duration)
+
+	// clock: 現在時刻の取得元（デフォルトは実際の時計、テストでは SetClock で差し替える）
+	clock clock.Clock
+
+	// store: 再起動後もロックを引き継ぐための保存先（nil ならメモリ上だけ）
+	// storeOps: 保存先への書き込みを順番に行うためのキュー（operation_lock_store.go を参照）
+	store    LockStore
+	storeOps chan lockStoreOp
+
+	// heartbeat: クリーンアップのループが回るたびに呼ぶ関数（SetHeartbeat で設定、nil なら呼ばない）
+	heartbeat func()
+
 	// logger: ログ出力用のロガー
 	logger *zap.Logger
 }
@@ -118,13 +167,168 @@ func NewOperationLock(timeout time.Duration, logger *zap.Logger) *OperationLock
 	ol := &OperationLock{
 		// make(map[...]): mapを初期化する
 		// mapは使う前に必ず make() で初期化する必要があります。
-		locks:   make(map[string]*LockInfo),
-		timeout: timeout,
-		logger:  logger,
+		locks:       make(map[string]*LockInfo),
+		userLocks:   make(map[string]map[string]bool),
+		exemptUsers: make(map[string]bool),
+		cooldowns:   make(map[string]lockCooldown),
+		timeout:     timeout,
+		clock:       clock.Real{},
+		logger:      logger,
 	}
 	return ol
 }
 
+// =============================================================================
+// SetClock - 現在時刻の取得元を差し替える（テスト用）
+// =============================================================================
+//
+// clock.Mock を渡すと、実際に待たずに期限切れや最大保持時間を確かめられます。
+// 他のメソッドより前に呼んでください。
+func (o *OperationLock) SetClock(c clock.Clock) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.clock = c
+}
+
+// =============================================================================
+// SetMaxHold - ロックを保持し続けられる最大時間を設定する
+// =============================================================================
+//
+// 【なぜ必要？】
+// 同じユーザーが延長を繰り返すと、ロックを無期限に独占できてしまいます。
+// 最大保持時間を過ぎると延長は拒否され、ロックは期限切れで解放されます。
+// 有効期限も「取得時刻 + 最大保持時間」を超えないように切り詰めます。
+// 期限切れの後も、タイムアウト1回分の間は同じユーザーの再取得を拒否し、
+// 他のユーザーが操作権を得られるようにします。
+// 0 なら無制限（従来どおり）です。StartCleanup() の前に呼んでください。
+func (o *OperationLock) SetMaxHold(d time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.maxHold = d
+}
+
+// =============================================================================
+// SetMaxLocksPerUser - 1人のユーザーが同時に保持できるロックの数を設定する
+// =============================================================================
+//
+// 【なぜ必要？】
+// 複数のロボットを共有する研究室などで、1人がすべてのロボットをロックしてしまうと
+// 他の人が何も操作できません。上限に達したユーザーの新しいロックは拒否され、
+// どれかを解放（または期限切れに）すると再び取得できるようになります。
+// 保持中のロックの延長は上限に関係なく行えます。
+//
+// exempt に含まれるユーザー（管理者など）は上限の対象外です。
+// maxLocks が 0 なら無制限（従来どおり）です。
+func (o *OperationLock) SetMaxLocksPerUser(maxLocks int, exempt []string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.maxPerUser = maxLocks
+	o.exemptUsers = make(map[string]bool, len(exempt))
+	for _, userID := range exempt {
+		o.exemptUsers[userID] = true
+	}
+}
+
+// =============================================================================
+// SetExpiryWarning - 期限切れ前の警告を設定する
+// =============================================================================
+//
+// 【なぜ必要？】
+// ロックは期限が来ると自動で解放されるため、保持者は操作の途中で
+// 突然コントロールを失うことがあります。期限の before 前になったら
+// fn を一度だけ呼び、保持者の画面で「延長しますか？」と促せるようにします。
+//
+// fn はクリーンアップのゴルーチンから、内部のロックを持たない状態で呼ばれます。
+// before が 0 なら警告しません。StartCleanup() の前に呼んでください。
+func (o *OperationLock) SetExpiryWarning(before time.Duration, fn func(lock LockInfo, remaining time.Duration)) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.warnBefore = before
+	o.onExpiring = fn
+}
+
+// =============================================================================
+// SetHeartbeat - クリーンアップのループが回るたびに呼ぶ関数を設定する
+// =============================================================================
+//
+// heartbeat.Registry.Register の戻り値を渡します（間隔は CleanupInterval()）。
+// StartCleanup() の前に呼んでください。
+func (o *OperationLock) SetHeartbeat(fn func()) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.heartbeat = fn
+}
+
+// CleanupInterval - クリーンアップの実行間隔
+func (o *OperationLock) CleanupInterval() time.Duration {
+	return o.cleanupInterval()
+}
+
+// cleanupInterval: クリーンアップの実行間隔
+// 警告が有効な場合は、残り時間を秒単位で知らせられるよう1秒ごとに実行する。
+func (o *OperationLock) cleanupInterval() time.Duration {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	if o.warnBefore > 0 && o.onExpiring != nil {
+		return time.Second
+	}
+	return 10 * time.Second
+}
+
+// expiresAt: now に取得・延長したロックの有効期限（最大保持時間で切り詰める）
+func (o *OperationLock) expiresAt(now, acquiredAt time.Time) time.Time {
+	expires := now.Add(o.timeout)
+	if o.maxHold > 0 {
+		if limit := acquiredAt.Add(o.maxHold); expires.After(limit) {
+			return limit
+		}
+	}
+	return expires
+}
+
+// addLock / removeLock: locks と userLocks を同時に更新する（o.mu を保持した状態で呼ぶこと）
+func (o *OperationLock) addLock(lock *LockInfo) {
+	o.locks[lock.RobotID] = lock
+	robots, ok := o.userLocks[lock.UserID]
+	if !ok {
+		robots = make(map[string]bool)
+		o.userLocks[lock.UserID] = robots
+	}
+	robots[lock.RobotID] = true
+}
+
+func (o *OperationLock) removeLock(robotID string, lock *LockInfo) {
+	delete(o.locks, robotID)
+	if robots, ok := o.userLocks[lock.UserID]; ok {
+		delete(robots, robotID)
+		if len(robots) == 0 {
+			delete(o.userLocks, lock.UserID)
+		}
+	}
+}
+
+// heldBy: now の時点でユーザーが保持している有効なロックの数（o.mu を保持した状態で呼ぶこと）
+// 期限切れでまだクリーンアップされていないロックは数えない。
+func (o *OperationLock) heldBy(userID string, now time.Time) int {
+	held := 0
+	for robotID := range o.userLocks[userID] {
+		if lock, ok := o.locks[robotID]; ok && lock.ExpiresAt.After(now) {
+			held++
+		}
+	}
+	return held
+}
+
+// expire: 期限切れのロックを削除する（o.mu を保持した状態で呼ぶこと）
+// 最大保持時間に達して切れたロックなら、保持者の再取得を一定時間拒否する記録を残す。
+func (o *OperationLock) expire(robotID string, lock *LockInfo) {
+	o.removeLock(robotID, lock)
+	o.unpersistLock(robotID)
+	if o.maxHold > 0 && !lock.ExpiresAt.Before(lock.AcquiredAt.Add(o.maxHold)) {
+		o.cooldowns[robotID] = lockCooldown{userID: lock.UserID, until: lock.ExpiresAt.Add(o.timeout)}
+	}
+}
+
 // =============================================================================
 // StartCleanup - 期限切れロックの定期クリーンアップを開始する
 // =============================================================================
@@ -145,11 +349,26 @@ func NewOperationLock(timeout time.Duration, logger *zap.Logger) *OperationLock
 //
 // 使い方：
 //
+//	var wg sync.WaitGroup
 //	done := make(chan struct{})
-//	manager.StartCleanup(done)
+//	manager.StartCleanup(done, &wg)
 //	// ... アプリケーション実行中 ...
 //	close(done) // ← これでクリーンアップが停止する
-func (o *OperationLock) StartCleanup(done <-chan struct{}) {
+//	wg.Wait()   // ← ゴルーチンが実際に終了するまで待つ
+//
+// wg には起動したゴルーチンが登録され、終了時に Done() されます。
+// 終了を待つ必要がなければ nil を渡せます。
+func (o *OperationLock) StartCleanup(done <-chan struct{}, wg *sync.WaitGroup) {
+	if wg != nil {
+		wg.Add(1)
+	}
+	// 保存先が設定されていれば、書き込みゴルーチンも同じ done で止める
+	if o.store != nil {
+		if wg != nil {
+			wg.Add(1)
+		}
+		go o.runStore(done, wg)
+	}
 	// go func() { ... }()
 	//
 	// 【ゴルーチン（goroutine）とは？】
@@ -161,13 +380,18 @@ func (o *OperationLock) StartCleanup(done <-chan struct{}) {
 	// func() { ... }() は、名前のない関数をその場で定義して実行します。
 	// JavaScriptのアロー関数 (() => { ... })() に似ています。
 	go func() {
+		// ゴルーチン終了時に WaitGroup へ完了を通知する
+		if wg != nil {
+			defer wg.Done()
+		}
+
 		// time.NewTicker(): 一定間隔で信号を送り続けるタイマーを作成する
-		// 10秒ごとに ticker.C チャネルに現在時刻が送信されます。
+		// 10秒ごと（期限切れ前の警告が有効なら1秒ごと）に ticker.C チャネルに現在時刻が送信されます。
 		//
 		// 【Ticker vs Timer の違い】
 		// - Ticker: 繰り返し発火する（10秒ごと、10秒ごと、...）
 		// - Timer: 1回だけ発火する
-		ticker := time.NewTicker(10 * time.Second)
+		ticker := time.NewTicker(o.cleanupInterval())
 
 		// defer ticker.Stop(): ゴルーチン終了時にTickerを停止する
 		// Tickerは使い終わったら必ず Stop() する必要があります。
@@ -192,8 +416,13 @@ func (o *OperationLock) StartCleanup(done <-chan struct{}) {
 				// ゴルーチンを終了する（return でゴルーチンが終了）
 				return
 			case <-ticker.C:
-				// 10秒経過した場合、期限切れロックをクリーンアップする
+				// 一定時間経過した場合、期限切れロックをクリーンアップし、
+				// 期限が近いロックの保持者に警告する
 				o.cleanupExpired()
+				o.WarnExpiring(o.clock.Now())
+				if o.heartbeat != nil {
+					o.heartbeat()
+				}
 			}
 		}
 	}()
@@ -209,6 +438,7 @@ func (o *OperationLock) StartCleanup(done <-chan struct{}) {
 //     b. あり＋有効期限内＋別のユーザー → エラーを返す（他の人が使用中）
 //     c. あり＋期限切れ → 古いロックを削除して新規取得
 //  2. 既存のロックがない → 新規ロックを取得する
+//     ただし、ユーザーが既に上限（SetMaxLocksPerUser）の数のロックを保持していればエラー
 //
 // 【戻り値】
 // - *LockInfo: 取得（または延長）されたロック情報
@@ -223,8 +453,8 @@ func (o *OperationLock) Acquire(robotID, userID string) (*LockInfo, error) {
 	defer o.mu.Unlock()
 
 	// 現在時刻を取得する
-	// time.Now(): 現在の時刻をtime.Time型で返す
-	now := time.Now()
+	// o.clock.Now(): 現在の時刻をtime.Time型で返す（本番では time.Now() と同じ）
+	now := o.clock.Now()
 
 	// --- 既存のロックがあるか確認する ---
 
@@ -236,9 +466,17 @@ func (o *OperationLock) Acquire(robotID, userID string) (*LockInfo, error) {
 		// After(now): ExpiresAt が now より後かどうか → まだ有効
 		if existing.ExpiresAt.After(now) {
 			if existing.UserID == userID {
+				// 最大保持時間に達していたら、同じユーザーでも延長を拒否する
+				if o.maxHold > 0 && now.Sub(existing.AcquiredAt) >= o.maxHold {
+					return existing, fmt.Errorf("robot %s lock reached maximum hold time of %s; renewal refused until %s",
+						robotID, o.maxHold, existing.ExpiresAt.Format(time.RFC3339))
+				}
+
 				// 同じユーザーがロックを持っている場合 → ロックを延長する
 				// これにより、操作を続けている間はロックが期限切れにならない
-				existing.ExpiresAt = now.Add(o.timeout)
+				existing.ExpiresAt = o.expiresAt(now, existing.AcquiredAt)
+				existing.warned = false
+				o.persistLock(existing, now, false)
 
 				o.logger.Debug("Operation lock extended",
 					zap.String("robot_id", robotID),
@@ -256,7 +494,24 @@ func (o *OperationLock) Acquire(robotID, userID string) (*LockInfo, error) {
 			// 例: "2026-02-15T14:30:00+09:00"
 		}
 		// ロックが期限切れの場合 → 削除して新規取得に進む
-		delete(o.locks, robotID)
+		o.expire(robotID, existing)
+	}
+
+	// 最大保持時間に達したばかりのユーザーは、しばらく取り直せない
+	if cd, ok := o.cooldowns[robotID]; ok {
+		if cd.userID == userID && now.Before(cd.until) {
+			return nil, fmt.Errorf("robot %s lock reached maximum hold time; user %s may re-acquire after %s",
+				robotID, userID, cd.until.Format(time.RFC3339))
+		}
+		delete(o.cooldowns, robotID)
+	}
+
+	// 1人で同時に保持できるロックの数を超えていないか確認する（管理者などは対象外）
+	if o.maxPerUser > 0 && !o.exemptUsers[userID] {
+		if held := o.heldBy(userID, now); held >= o.maxPerUser {
+			return nil, fmt.Errorf("user %s already holds %d operation locks (max %d per user); release one before locking robot %s",
+				userID, held, o.maxPerUser, robotID)
+		}
 	}
 
 	// --- 新規ロックを取得する ---
@@ -267,13 +522,14 @@ func (o *OperationLock) Acquire(robotID, userID string) (*LockInfo, error) {
 		RobotID:    robotID,
 		UserID:     userID,
 		AcquiredAt: now,
-		// now.Add(o.timeout): 現在時刻にタイムアウト期間を加算する
+		// 現在時刻にタイムアウト期間を加算する（最大保持時間を超える場合は切り詰める）
 		// 例: 現在14:00 + 5分 → 14:05に期限切れ
-		ExpiresAt: now.Add(o.timeout),
+		ExpiresAt: o.expiresAt(now, now),
 	}
 
-	// mapにロック情報を保存する
-	o.locks[robotID] = lock
+	// mapにロック情報を保存する（ユーザーごとの集合も更新する）
+	o.addLock(lock)
+	o.persistLock(lock, now, true)
 
 	o.logger.Info("Operation lock acquired",
 		zap.String("robot_id", robotID),
@@ -316,7 +572,8 @@ func (o *OperationLock) Release(robotID, userID string) error {
 	}
 
 	// ロックを削除する（mapから除去）
-	delete(o.locks, robotID)
+	o.removeLock(robotID, lock)
+	o.unpersistLock(robotID)
 
 	o.logger.Info("Operation lock released",
 		zap.String("robot_id", robotID),
@@ -348,7 +605,7 @@ func (o *OperationLock) CheckLock(robotID, userID string) bool {
 
 	// ロックの所有者が一致し、かつ有効期限内であることを確認する
 	// &&: 論理AND演算子（両方trueの場合にtrueを返す）
-	return lock.UserID == userID && lock.ExpiresAt.After(time.Now())
+	return lock.UserID == userID && lock.ExpiresAt.After(o.clock.Now())
 }
 
 // =============================================================================
@@ -375,7 +632,7 @@ func (o *OperationLock) GetLockInfo(robotID string) *LockInfo {
 	// ロックが存在しない、または期限切れの場合は nil を返す
 	// ||: 論理OR演算子（どちらか一方がtrueならtrueを返す）
 	// Before(now): ExpiresAt が now より前 → 期限切れ
-	if !ok || lock.ExpiresAt.Before(time.Now()) {
+	if !ok || lock.ExpiresAt.Before(o.clock.Now()) {
 		return nil
 	}
 	return lock
@@ -401,7 +658,7 @@ func (o *OperationLock) cleanupExpired() {
 	o.mu.Lock()
 	defer o.mu.Unlock()
 
-	now := time.Now()
+	now := o.clock.Now()
 
 	// map をイテレーションしながら期限切れのロックを削除する
 	//
@@ -413,11 +670,52 @@ func (o *OperationLock) cleanupExpired() {
 		// Before(now): ロックの期限が現在時刻より前 → 期限切れ
 		if lock.ExpiresAt.Before(now) {
 			// 期限切れのロックを削除する
-			delete(o.locks, robotID)
+			o.expire(robotID, lock)
 			o.logger.Info("Expired operation lock cleaned up",
 				zap.String("robot_id", robotID),
 				zap.String("user_id", lock.UserID),
 			)
 		}
 	}
+
+	// 再取得の拒否期間が過ぎた記録も削除する
+	for robotID, cd := range o.cooldowns {
+		if !now.Before(cd.until) {
+			delete(o.cooldowns, robotID)
+		}
+	}
+}
+
+// =============================================================================
+// WarnExpiring - 期限が近いロックの保持者に警告する
+// =============================================================================
+//
+// StartCleanup() から定期的に呼び出されます。テストから直接呼ぶこともできます。
+// 残り時間が warnBefore 以下になったロックごとに、onExpiring を一度だけ呼びます。
+// 保持者が延長すると warned がリセットされ、次の期限の前に再び警告されます。
+func (o *OperationLock) WarnExpiring(now time.Time) {
+	o.mu.Lock()
+	if o.warnBefore <= 0 || o.onExpiring == nil {
+		o.mu.Unlock()
+		return
+	}
+	fn := o.onExpiring
+	var expiring []LockInfo
+	for _, lock := range o.locks {
+		remaining := lock.ExpiresAt.Sub(now)
+		if !lock.warned && remaining > 0 && remaining <= o.warnBefore {
+			lock.warned = true
+			expiring = append(expiring, *lock) // ロックの外で使うためコピーを渡す
+		}
+	}
+	o.mu.Unlock()
+
+	for _, lock := range expiring {
+		o.logger.Info("Operation lock expiring soon",
+			zap.String("robot_id", lock.RobotID),
+			zap.String("user_id", lock.UserID),
+			zap.Time("expires_at", lock.ExpiresAt),
+		)
+		fn(lock, lock.ExpiresAt.Sub(now))
+	}
 }
@@ -0,0 +1,234 @@
+// =============================================================================
+// ファイル: operation_lock_store.go
+// パッケージ: safety（安全機能パッケージ）
+//
+// 【このファイルの概要】
+// 操作ロックをゲートウェイの再起動後も引き継ぐための「永続化」を扱います。
+// 操作ロックはメモリ上にしかないため、再起動すると全ロボットのロックが消え、
+// 操作中のユーザーとは別の人がすぐに操作権を取れてしまいます。
+//
+// 【仕組み】
+//
+//	Acquire（新規・延長） ──→ 保存キュー ──→ 書き込みゴルーチン ──→ LockStore.SaveLock
+//	Release / 期限切れ    ──→ 保存キュー ──→ 書き込みゴルーチン ──→ LockStore.DeleteLock
+//	起動時 Restore()      ←── LockStore.LoadLocks（期限内のものだけ復元）
+//
+// ロックの延長は、保持者が Acquire を呼んだ時（op_lock の再送や、auto モードでロックが
+// 切れた後の操作コマンドによる取り直し）に起きます。保持中のロックを確認するだけの CheckLock
+// （ensureLock で速度コマンドごとに呼ばれる）は延長しないため、何も書き込みません。
+// 書き込みは保存キューを通して別のゴルーチンで行います（Redis が遅れても、op_lock や
+// コマンドの処理は遅れません）。キューへの追加は o.mu を保持した状態で行うので、
+// 書き込みの順番は操作の順番と一致します。
+// 延長の書き込みは、前回の書き込みからタイムアウトの 1/4 以上経った時だけ行います
+// （画面が op_lock を短い間隔で再送しても、Redis への書き込みが増えすぎないように）。
+//
+// 【時計のずれ】
+// 保存するのは「取得時刻」と「最後に延長した時刻」だけで、有効期限は保存しません。
+// 復元時に、現在の設定（タイムアウト・最大保持時間）でこのゲートウェイの時計から
+// 有効期限を計算し直します。書き込んだホストの時計が進んでいて未来の時刻が
+// 入っていた場合は、現在時刻として扱います。
+//
+// ストアを設定しなければ（Redis がない場合など）、従来どおりメモリ上だけで動きます。
+// =============================================================================
+package safety
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// lockStoreQueueSize: 保存キューの長さ（満杯なら書き込みを捨てて警告する）
+const lockStoreQueueSize = 256
+
+// lockStoreTimeout: 1回の書き込み・読み込みのタイムアウト
+const lockStoreTimeout = 2 * time.Second
+
+// =============================================================================
+// PersistedLock - 保存される操作ロックの情報
+// =============================================================================
+type PersistedLock struct {
+	RobotID    string    `json:"robot_id"`
+	UserID     string    `json:"user_id"`
+	AcquiredAt time.Time `json:"acquired_at"` // 取得した時刻
+	RenewedAt  time.Time `json:"renewed_at"`  // 最後に延長した時刻（取得時は AcquiredAt と同じ）
+}
+
+// =============================================================================
+// LockStore - 操作ロックの保存先のインターフェース
+// =============================================================================
+//
+// bridge.RedisPublisher が実装します。
+// ttl は保存先でレコードを自動削除してよい時間の目安です（削除の取りこぼし対策）。
+type LockStore interface {
+	SaveLock(ctx context.Context, lock PersistedLock, ttl time.Duration) error
+	DeleteLock(ctx context.Context, robotID string) error
+	LoadLocks(ctx context.Context) ([]PersistedLock, error)
+}
+
+// lockStoreOp: 保存キューに積む操作（delete が true なら削除、それ以外は保存）
+type lockStoreOp struct {
+	lock   PersistedLock
+	ttl    time.Duration
+	delete bool
+}
+
+// =============================================================================
+// SetStore - 操作ロックの保存先を設定する
+// =============================================================================
+//
+// Restore() と StartCleanup() の前に呼んでください。
+// 書き込みゴルーチンは StartCleanup() で起動します。
+func (o *OperationLock) SetStore(store LockStore) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.store = store
+	o.storeOps = make(chan lockStoreOp, lockStoreQueueSize)
+}
+
+// =============================================================================
+// Restore - 保存されている操作ロックを読み込む（起動時に1回だけ呼ぶ）
+// =============================================================================
+//
+// 期限切れのものは復元せず、保存先からも削除します。
+// 1人あたりのロック数の上限（SetMaxLocksPerUser）は適用しません
+// （再起動の前に正当に取得したロックなので）。
+//
+// 【戻り値】
+// - int: 復元したロックの数
+// - error: 読み込みに失敗した場合のエラー（その場合はロックなしで起動を続けてよい）
+func (o *OperationLock) Restore(ctx context.Context) (int, error) {
+	o.mu.RLock()
+	store := o.store
+	o.mu.RUnlock()
+	if store == nil {
+		return 0, nil
+	}
+
+	loadCtx, cancel := context.WithTimeout(ctx, lockStoreTimeout)
+	defer cancel()
+	saved, err := store.LoadLocks(loadCtx)
+	if err != nil {
+		return 0, err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	now := o.clock.Now()
+	restored := 0
+	for _, p := range saved {
+		// 書き込んだホストの時計が進んでいた場合に備え、未来の時刻は現在時刻として扱う
+		renewed := p.RenewedAt
+		if renewed.After(now) {
+			renewed = now
+		}
+		acquired := p.AcquiredAt
+		if acquired.After(renewed) {
+			acquired = renewed
+		}
+
+		expires := o.expiresAt(renewed, acquired)
+		if !expires.After(now) {
+			o.enqueueStore(lockStoreOp{lock: p, delete: true})
+			continue
+		}
+		if _, ok := o.locks[p.RobotID]; ok {
+			continue
+		}
+		o.addLock(&LockInfo{
+			RobotID:     p.RobotID,
+			UserID:      p.UserID,
+			AcquiredAt:  acquired,
+			ExpiresAt:   expires,
+			persistedAt: renewed,
+		})
+		restored++
+		o.logger.Info("Operation lock restored",
+			zap.String("robot_id", p.RobotID),
+			zap.String("user_id", p.UserID),
+			zap.Time("expires_at", expires),
+		)
+	}
+	return restored, nil
+}
+
+// persistLock: ロックの保存をキューに積む（o.mu を保持した状態で呼ぶこと）
+// force が false（延長）なら、前回の保存からタイムアウトの 1/4 経っていない時は何もしない。
+func (o *OperationLock) persistLock(lock *LockInfo, now time.Time, force bool) {
+	if o.store == nil {
+		return
+	}
+	if !force && now.Sub(lock.persistedAt) < o.timeout/4 {
+		return
+	}
+	lock.persistedAt = now
+	o.enqueueStore(lockStoreOp{
+		lock: PersistedLock{RobotID: lock.RobotID, UserID: lock.UserID, AcquiredAt: lock.AcquiredAt, RenewedAt: now},
+		// 次の延長が書き込まれないまま期限が来ても消えないよう、タイムアウト1回分の余裕を持たせる
+		ttl: lock.ExpiresAt.Sub(now) + o.timeout,
+	})
+}
+
+// unpersistLock: ロックの削除をキューに積む（o.mu を保持した状態で呼ぶこと）
+func (o *OperationLock) unpersistLock(robotID string) {
+	if o.store == nil {
+		return
+	}
+	o.enqueueStore(lockStoreOp{lock: PersistedLock{RobotID: robotID}, delete: true})
+}
+
+// enqueueStore: 保存キューに積む。満杯なら捨てて警告する（コマンドの処理を止めないため）。
+func (o *OperationLock) enqueueStore(op lockStoreOp) {
+	select {
+	case o.storeOps <- op:
+	default:
+		o.logger.Warn("Operation lock store queue full, dropping write",
+			zap.String("robot_id", op.lock.RobotID),
+			zap.Bool("delete", op.delete),
+		)
+	}
+}
+
+// runStore: 保存キューの操作を順番に保存先へ書き込む（StartCleanup から起動する）
+// done が閉じられたら、キューに残っている分を書き込んでから終了する。
+func (o *OperationLock) runStore(done <-chan struct{}, wg *sync.WaitGroup) {
+	if wg != nil {
+		defer wg.Done()
+	}
+	for {
+		select {
+		case op := <-o.storeOps:
+			o.writeStore(op)
+		case <-done:
+			for {
+				select {
+				case op := <-o.storeOps:
+					o.writeStore(op)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// writeStore: 1件の操作を保存先に書き込む
+func (o *OperationLock) writeStore(op lockStoreOp) {
+	ctx, cancel := context.WithTimeout(context.Background(), lockStoreTimeout)
+	defer cancel()
+	var err error
+	if op.delete {
+		err = o.store.DeleteLock(ctx, op.lock.RobotID)
+	} else {
+		err = o.store.SaveLock(ctx, op.lock, op.ttl)
+	}
+	if err != nil {
+		o.logger.Warn("Failed to persist operation lock",
+			zap.String("robot_id", op.lock.RobotID),
+			zap.Bool("delete", op.delete),
+			zap.Error(err),
+		)
+	}
+}
@@ -0,0 +1,189 @@
+// =============================================================================
+// ファイル: sensor_stall.go
+// パッケージ: safety（安全機能パッケージ）
+//
+// 【このファイルの概要】
+// 「センサー停止検出（Sensor Stall Detector）」を実装するファイルです。
+//
+// 【なぜ必要？】
+// アダプターが「接続中」のままでも、LiDAR のドライバーが固まるなどして
+// 特定のトピックのデータだけが届かなくなることがあります。
+// 画面上はロボットが生きているように見えるのに、実はスキャンが古いまま
+// ──という状態は、障害物を見落とす危険な故障です。
+//
+// この検出器は：
+// 1. ロボット×トピックごとに、最後にデータを受信した時刻を記録する
+// 2. 定期的に（500ミリ秒ごとに）チェックする
+// 3. 一定時間（silence）データがないトピックを「停止」とみなし、コールバックで通知する
+// 4. 再びデータが届いたら「復旧」として通知する
+//
+// 【TimeoutWatchdog との違い】
+// ウォッチドッグは「ユーザーからのコマンド」が途絶えたらロボットを止めます。
+// こちらは「ロボットからのセンサーデータ」が途絶えたことを知らせます（自動では止めません）。
+// =============================================================================
+package safety
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// sensorTopicState - 1つのロボット×トピックの状態
+type sensorTopicState struct {
+	lastSeen time.Time // 最後にデータを受信した時刻（ゲートウェイの時計）
+	stalled  bool      // 停止とみなしているか
+}
+
+// =============================================================================
+// SensorStallDetector - センサー停止検出構造体
+// =============================================================================
+type SensorStallDetector struct {
+	mu      sync.Mutex
+	topics  map[string]map[string]*sensorTopicState // robot_id -> topic -> state
+	silence time.Duration
+
+	// onChange: 停止・復旧を検出した時のコールバック（stalled が true なら停止）
+	onChange func(robotID, topic string, stalled bool)
+
+	logger *zap.Logger
+}
+
+// NewSensorStallDetector - コンストラクタ
+// silence: この時間データが届かなければ停止とみなす
+func NewSensorStallDetector(silence time.Duration, logger *zap.Logger) *SensorStallDetector {
+	return &SensorStallDetector{
+		topics:  make(map[string]map[string]*sensorTopicState),
+		silence: silence,
+		logger:  logger,
+	}
+}
+
+// SetStallCallback - 停止・復旧を検出した時に呼ぶ関数を設定する
+// Start() の前に一度だけ呼んでください。
+func (d *SensorStallDetector) SetStallCallback(fn func(robotID, topic string, stalled bool)) {
+	d.onChange = fn
+}
+
+// =============================================================================
+// Mark - センサーデータの受信を記録する
+// =============================================================================
+//
+// センサー転送処理（forwardSensorData）がデータを受け取るたびに呼びます。
+// 停止とみなしていたトピックなら、その場で復旧を通知します。
+func (d *SensorStallDetector) Mark(robotID, topic string) {
+	d.mu.Lock()
+	robot, ok := d.topics[robotID]
+	if !ok {
+		robot = make(map[string]*sensorTopicState)
+		d.topics[robotID] = robot
+	}
+	state, ok := robot[topic]
+	if !ok {
+		state = &sensorTopicState{}
+		robot[topic] = state
+	}
+	state.lastSeen = time.Now()
+	recovered := state.stalled
+	state.stalled = false
+	d.mu.Unlock()
+
+	if recovered {
+		d.logger.Info("Sensor topic recovered",
+			zap.String("robot_id", robotID),
+			zap.String("topic", topic),
+		)
+		if d.onChange != nil {
+			d.onChange(robotID, topic, false)
+		}
+	}
+}
+
+// RemoveRobot - ロボットの記録を削除する（ロボットを登録解除した時などに使う）
+func (d *SensorStallDetector) RemoveRobot(robotID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.topics, robotID)
+}
+
+// =============================================================================
+// Stalled - 停止とみなしているトピックの一覧を返す（昇順）
+// =============================================================================
+func (d *SensorStallDetector) Stalled(robotID string) []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	stalled := []string{}
+	for topic, state := range d.topics[robotID] {
+		if state.stalled {
+			stalled = append(stalled, topic)
+		}
+	}
+	sort.Strings(stalled)
+	return stalled
+}
+
+// =============================================================================
+// Start - 定期チェックのゴルーチンを起動する
+// =============================================================================
+//
+// ctx がキャンセルされると停止します（TimeoutWatchdog.Start と同じ形）。
+func (d *SensorStallDetector) Start(ctx context.Context, wg *sync.WaitGroup) {
+	if wg != nil {
+		wg.Add(1)
+	}
+	go func() {
+		if wg != nil {
+			defer wg.Done()
+		}
+		ticker := time.NewTicker(500 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				d.CheckStalls(now)
+			}
+		}
+	}()
+
+	d.logger.Info("Sensor stall detector started", zap.Duration("silence", d.silence))
+}
+
+// =============================================================================
+// CheckStalls - now の時点で新たに停止したトピックを検出して通知する
+// =============================================================================
+//
+// Start() のゴルーチンから呼ばれます。テストから直接呼ぶこともできます。
+// 同じトピックの停止は、復旧するまで一度だけ通知します。
+func (d *SensorStallDetector) CheckStalls(now time.Time) {
+	type stall struct{ robotID, topic string }
+	var newlyStalled []stall
+
+	d.mu.Lock()
+	for robotID, robot := range d.topics {
+		for topic, state := range robot {
+			if !state.stalled && now.Sub(state.lastSeen) > d.silence {
+				state.stalled = true
+				newlyStalled = append(newlyStalled, stall{robotID, topic})
+			}
+		}
+	}
+	d.mu.Unlock()
+
+	// コールバックはロックの外で呼ぶ（コールバック内で Stalled() を呼んでもデッドロックしない）
+	for _, s := range newlyStalled {
+		d.logger.Warn("Sensor topic stalled",
+			zap.String("robot_id", s.robotID),
+			zap.String("topic", s.topic),
+			zap.Duration("silence", d.silence),
+		)
+		if d.onChange != nil {
+			d.onChange(s.robotID, s.topic, true)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+// =============================================================================
+// ファイル: sensor_validation.go
+// パッケージ: safety（安全機能パッケージ）
+//
+// 【このファイルの概要】
+// 「センサーデータの検証（Sensor Validator）」を実装するファイルです。
+//
+// 【なぜ必要？】
+// バグのあるアダプターは、物理的にありえない値（マイナスのバッテリー残量、
+// NaN の位置、最大測定距離を超える LiDAR の距離など）を送ってくることがあります。
+// そのままクライアントや Redis に流すと、画面が壊れるだけでなく、
+// Redis のデータで学習する ML パイプラインにゴミが混ざります。
+//
+// 【検証する内容】
+//   - すべてのトピック: 数値が有限（NaN や ±Inf でない）
+//   - battery:          percentage が 0〜100
+//   - lidar:            ranges の各値が range_min〜range_max
+//
+// 【ポリシー（トピックごとに設定）】
+//
+//	off    検証しない
+//	flag   そのまま通し、Data["validation_errors"] に理由を付ける
+//	clamp  範囲外の値を範囲内に丸める（NaN や ±Inf は直せないので捨てる）
+//	drop   サンプルごと捨てる
+//
+// どのポリシーでも、異常はログに残します（同じロボット×トピックでは間引きます）。
+// =============================================================================
+package safety
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/convert"
+	"go.uber.org/zap"
+)
+
+// SensorValidationPolicy - 異常なサンプルの扱い
+type SensorValidationPolicy string
+
+const (
+	SensorValidationOff   SensorValidationPolicy = "off"
+	SensorValidationFlag  SensorValidationPolicy = "flag"
+	SensorValidationClamp SensorValidationPolicy = "clamp"
+	SensorValidationDrop  SensorValidationPolicy = "drop"
+)
+
+// SensorValidationAnyTopic: ポリシーの設定で「その他すべてのトピック」を表すキー
+const SensorValidationAnyTopic = "*"
+
+// sensorAnomalyLogEvery: 同じロボット×トピックの異常を、何件ごとにログに出すか
+const sensorAnomalyLogEvery = 100
+
+// ParseSensorValidationPolicy - 文字列をポリシーに変換する（不明な値ならエラー）
+func ParseSensorValidationPolicy(s string) (SensorValidationPolicy, error) {
+	switch p := SensorValidationPolicy(s); p {
+	case SensorValidationOff, SensorValidationFlag, SensorValidationClamp, SensorValidationDrop:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown sensor validation policy %q (expected off, flag, clamp or drop)", s)
+}
+
+// =============================================================================
+// SensorValidator - センサーデータの検証器
+// =============================================================================
+type SensorValidator struct {
+	policies map[string]SensorValidationPolicy // topic -> ポリシー（"*" はその他すべて）
+
+	mu        sync.Mutex
+	anomalies map[string]uint64 // "robot_id/topic" -> 異常の件数
+
+	logger *zap.Logger
+}
+
+// NewSensorValidator - コンストラクタ
+//
+// policies はトピック名からポリシーへの map です。"*" のポリシーは、
+// 個別に指定されていないトピックに使います（"*" も無ければ検証しません）。
+func NewSensorValidator(policies map[string]SensorValidationPolicy, logger *zap.Logger) *SensorValidator {
+	return &SensorValidator{
+		policies:  policies,
+		anomalies: make(map[string]uint64),
+		logger:    logger,
+	}
+}
+
+// policyFor: トピックに適用するポリシー
+func (v *SensorValidator) policyFor(topic string) SensorValidationPolicy {
+	if p, ok := v.policies[topic]; ok {
+		return p
+	}
+	if p, ok := v.policies[SensorValidationAnyTopic]; ok {
+		return p
+	}
+	return SensorValidationOff
+}
+
+// =============================================================================
+// Check - サンプルを検証し、ポリシーに従って直す（配信してよければ true）
+// =============================================================================
+//
+// flag と clamp では data.Data を書き換えます。アダプターが持っている map や
+// スライスには触れないよう、書き換える時はコピーしてから差し替えます。
+// v が nil なら何もせず true を返します。
+func (v *SensorValidator) Check(data *adapter.SensorData) bool {
+	if v == nil {
+		return true
+	}
+	policy := v.policyFor(data.Topic)
+	if policy == SensorValidationOff {
+		return true
+	}
+
+	problems, fixed, fixable := validateSensorData(data)
+	if len(problems) == 0 {
+		return true
+	}
+	v.logAnomaly(data, policy, problems)
+
+	switch policy {
+	case SensorValidationFlag:
+		flagged := make(map[string]any, len(data.Data)+1)
+		for k, val := range data.Data {
+			flagged[k] = val
+		}
+		flagged["validation_errors"] = problems
+		data.Data = flagged
+		return true
+	case SensorValidationClamp:
+		if !fixable {
+			return false
+		}
+		data.Data = fixed
+		return true
+	}
+	return false // drop
+}
+
+// logAnomaly: 異常をログに残す（同じロボット×トピックでは最初と、以後 sensorAnomalyLogEvery 件ごと）
+func (v *SensorValidator) logAnomaly(data *adapter.SensorData, policy SensorValidationPolicy, problems []string) {
+	key := data.RobotID + "/" + data.Topic
+	v.mu.Lock()
+	v.anomalies[key]++
+	n := v.anomalies[key]
+	v.mu.Unlock()
+
+	if n != 1 && n%sensorAnomalyLogEvery != 0 {
+		return
+	}
+	v.logger.Warn("Invalid sensor data",
+		zap.String("robot_id", data.RobotID),
+		zap.String("topic", data.Topic),
+		zap.String("policy", string(policy)),
+		zap.Strings("problems", problems),
+		zap.Uint64("count", n),
+	)
+}
+
+// Anomalies - ロボット×トピック（"robot_id/topic"）ごとの異常の件数
+func (v *SensorValidator) Anomalies() map[string]uint64 {
+	if v == nil {
+		return nil
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	out := make(map[string]uint64, len(v.anomalies))
+	for k, n := range v.anomalies {
+		out[k] = n
+	}
+	return out
+}
+
+// =============================================================================
+// validateSensorData - 物理的な制約を検査する
+// =============================================================================
+//
+// 戻り値:
+//
+//	problems: 見つかった異常の説明（なければ空）
+//	fixed:    範囲外の値を丸めた Data のコピー（異常がなければ nil）
+//	fixable:  丸めで直せるか（NaN や ±Inf があれば false）
+func validateSensorData(data *adapter.SensorData) (problems []string, fixed map[string]any, fixable bool) {
+	fixed = make(map[string]any, len(data.Data))
+	fixable = true
+
+	// すべての数値が有限か
+	for key, val := range data.Data {
+		fixed[key] = val
+		if !finiteValue(val) {
+			problems = append(problems, fmt.Sprintf("%s is not finite", key))
+			fixable = false
+		}
+	}
+
+	switch data.DataType {
+	case "battery":
+		if pct, ok := convert.ToFloat64(data.Data["percentage"]); ok && (pct < 0 || pct > 100) {
+			problems = append(problems, fmt.Sprintf("percentage %.1f is outside 0-100", pct))
+			fixed["percentage"] = math.Min(math.Max(pct, 0), 100)
+		}
+
+	case "lidar":
+		rangeMin, okMin := convert.ToFloat64(data.Data["range_min"])
+		rangeMax, okMax := convert.ToFloat64(data.Data["range_max"])
+		ranges, okRanges := data.Data["ranges"].([]float64)
+		if !okMin || !okMax || !okRanges {
+			break
+		}
+		if rangeMin > rangeMax {
+			problems = append(problems, fmt.Sprintf("range_min %.2f is greater than range_max %.2f", rangeMin, rangeMax))
+			fixable = false
+			break
+		}
+		out := 0
+		var clamped []float64
+		for i, r := range ranges {
+			if math.IsNaN(r) || math.IsInf(r, 0) || (r >= rangeMin && r <= rangeMax) {
+				continue // 非有限値は上の検査で報告済み
+			}
+			if clamped == nil {
+				clamped = make([]float64, len(ranges))
+				copy(clamped, ranges)
+			}
+			clamped[i] = math.Min(math.Max(r, rangeMin), rangeMax)
+			out++
+		}
+		if out > 0 {
+			problems = append(problems, fmt.Sprintf("%d ranges are outside %.2f-%.2f", out, rangeMin, rangeMax))
+			fixed["ranges"] = clamped
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil, nil, true
+	}
+	return problems, fixed, fixable
+}
+
+// finiteValue: 値（数値、数値のスライス）に NaN や ±Inf が含まれていないか
+func finiteValue(val any) bool {
+	switch v := val.(type) {
+	case float64:
+		return !math.IsNaN(v) && !math.IsInf(v, 0)
+	case float32:
+		return !math.IsNaN(float64(v)) && !math.IsInf(float64(v), 0)
+	case []float64:
+		for _, f := range v {
+			if math.IsNaN(f) || math.IsInf(f, 0) {
+				return false
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if !finiteValue(item) {
+				return false
+			}
+		}
+	}
+	return true
+}
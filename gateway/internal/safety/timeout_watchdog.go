@@ -46,6 +46,9 @@ import (
 	// タイムアウト時にロボットへ停止コマンドを送信するために使います。
 	"github.com/robot-ai-webapp/gateway/internal/adapter"
 
+	// clock: 現在時刻の取得元（テストでは手で進める時計に差し替える）
+	"github.com/robot-ai-webapp/gateway/internal/clock"
+
 	// zap: 高性能ロガー
 	// タイムアウトの検出や自動停止のイベントをログに記録します。
 	"go.uber.org/zap"
@@ -102,8 +105,26 @@ type TimeoutWatchdog struct {
 	// 例えば、「タイムアウトしたらWebSocketで通知を送る」という処理を
 	// コールバックとして登録できます。
 	onTimeout func(robotID string) // callback when timeout triggers
+
+	// clock: 現在時刻の取得元（デフォルトは実際の時計、テストでは SetClock で差し替える）
+	clock clock.Clock
+
+	// heartbeat: 監視ループが回るたびに呼ぶ関数（SetHeartbeat で設定、nil なら呼ばない）
+	heartbeat func()
+
+	// rampDown: タイムアウト時の減速時間（SetRampDown で設定、0 なら即時停止）
+	rampDown time.Duration
+	// currentVelocity: ロボットに直前に送った速度を返す関数（ランプダウンの始点）
+	currentVelocity func(robotID string) adapter.Velocity
+	// ramps: 進行中のランプダウンを打ち切る関数（robot_id -> cancel、mu で保護）
+	ramps map[string]context.CancelFunc
+	// estop: ランプダウンの各段階の前に E-Stop を確認するための参照（SetEStop で設定、nil なら確認しない）
+	estop *EStopManager
 }
 
+// WatchdogCheckInterval - 監視ループがタイムアウトを確認する間隔
+const WatchdogCheckInterval = 500 * time.Millisecond
+
 // =============================================================================
 // NewTimeoutWatchdog - TimeoutWatchdogのコンストラクタ
 // =============================================================================
@@ -128,12 +149,24 @@ func NewTimeoutWatchdog(
 	return &TimeoutWatchdog{
 		// make(map[...]): mapの初期化
 		lastCommand: make(map[string]time.Time),
+		ramps:       make(map[string]context.CancelFunc),
 		timeout:     timeout,
 		registry:    registry,
 		logger:      logger,
+		clock:       clock.Real{},
 	}
 }
 
+// =============================================================================
+// SetClock - 現在時刻の取得元を差し替える（テスト用）
+// =============================================================================
+//
+// clock.Mock を渡すと、実際に待たずにタイムアウトを確かめられます。
+// Start() や RecordCommand() より前に呼んでください。
+func (t *TimeoutWatchdog) SetClock(c clock.Clock) {
+	t.clock = c
+}
+
 // =============================================================================
 // SetTimeoutCallback - タイムアウト時のコールバック関数を設定する
 // =============================================================================
@@ -156,6 +189,16 @@ func (t *TimeoutWatchdog) SetTimeoutCallback(fn func(robotID string)) {
 	t.onTimeout = fn
 }
 
+// =============================================================================
+// SetHeartbeat - 監視ループが回るたびに呼ぶ関数を設定する
+// =============================================================================
+//
+// heartbeat.Registry.Register の戻り値を渡すと、/debug/health で
+// 監視ループが止まっていないかを確認できます。Start() より前に呼んでください。
+func (t *TimeoutWatchdog) SetHeartbeat(fn func()) {
+	t.heartbeat = fn
+}
+
 // =============================================================================
 // RecordCommand - コマンド送信時刻を記録する
 // =============================================================================
@@ -173,7 +216,9 @@ func (t *TimeoutWatchdog) RecordCommand(robotID string) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	// 現在時刻で最後のコマンド時刻を更新する
-	t.lastCommand[robotID] = time.Now()
+	t.lastCommand[robotID] = t.clock.Now()
+	// 新しいコマンドが届いたので、減速中ならそこで打ち切る
+	t.cancelRampDownLocked(robotID)
 }
 
 // =============================================================================
@@ -188,6 +233,7 @@ func (t *TimeoutWatchdog) RemoveRobot(robotID string) {
 	defer t.mu.Unlock()
 	// mapから削除して監視対象から外す
 	delete(t.lastCommand, robotID)
+	t.cancelRampDownLocked(robotID)
 }
 
 // =============================================================================
@@ -206,17 +252,29 @@ func (t *TimeoutWatchdog) RemoveRobot(robotID string) {
 // 親ctxがキャンセルされると、子ctxも自動的にキャンセルされます。
 // また、cancelFunc()を呼ぶと、子ctxだけがキャンセルされます。
 // これにより、ウォッチドッグを独立して停止できます。
-func (t *TimeoutWatchdog) Start(ctx context.Context) {
+//
+// 【wg（sync.WaitGroup）】
+// シャットダウン時に「監視ゴルーチンが本当に終わったか」を確認するために使います。
+// 起動前に Add(1) し、run() が戻った時に Done() します。nil なら登録しません。
+func (t *TimeoutWatchdog) Start(ctx context.Context, wg *sync.WaitGroup) {
 	// context.WithCancel(): キャンセル可能な子コンテキストを作成する
 	// watchCtx: 子コンテキスト（run()で使う）
 	// cancel: キャンセル関数（Stop()で使う）
 	watchCtx, cancel := context.WithCancel(ctx)
 	t.cancelFunc = cancel
 
-	// go t.run(watchCtx): 新しいゴルーチンでrun()を実行する
+	// 新しいゴルーチンでrun()を実行する
 	// 「go」キーワードを付けると、関数が並行に実行されます。
 	// メインの処理はブロック（停止）せず、すぐに次の行に進みます。
-	go t.run(watchCtx)
+	if wg != nil {
+		wg.Add(1)
+	}
+	go func() {
+		if wg != nil {
+			defer wg.Done()
+		}
+		t.run(watchCtx)
+	}()
 
 	// ウォッチドッグ開始のログを出力する
 	// zap.Duration(): time.Duration型の値をログに含める
@@ -256,8 +314,8 @@ func (t *TimeoutWatchdog) Stop() {
 // run() は小文字で始まるので、パッケージ外からは呼べません。
 // Start() が外部向けのAPI、run() は内部実装です。
 func (t *TimeoutWatchdog) run(ctx context.Context) {
-	// time.NewTicker(): 500ミリ秒ごとに信号を送るタイマーを作成
-	ticker := time.NewTicker(500 * time.Millisecond)
+	// time.NewTicker(): 500ミリ秒（WatchdogCheckInterval）ごとに信号を送るタイマーを作成
+	ticker := time.NewTicker(WatchdogCheckInterval)
 
 	// defer ticker.Stop(): ゴルーチン終了時にTickerをクリーンアップ
 	// リソースリーク防止のために必ず必要です。
@@ -282,22 +340,25 @@ func (t *TimeoutWatchdog) run(ctx context.Context) {
 			// このcase文が実行されます。
 			return
 
-		case now := <-ticker.C:
-			// ticker.C: 500ミリ秒ごとに現在時刻が送信されるチャネル
-			// now: 受信した時刻（time.Time型）
-			//
-			// 【変数代入付きの case】
-			// case now := <-ticker.C は、チャネルから受信した値を
-			// 変数 now に代入しています。
-			t.checkTimeouts(ctx, now)
+		case <-ticker.C:
+			// ticker.C: 500ミリ秒ごとに値が送信されるチャネル
+			// 受信した時刻ではなく t.clock.Now() を使うことで、
+			// テストで差し替えた時計とも同じ基準で判定できます。
+			t.CheckTimeouts(ctx, t.clock.Now())
+			if t.heartbeat != nil {
+				t.heartbeat()
+			}
 		}
 	}
 }
 
 // =============================================================================
-// checkTimeouts - タイムアウトしたロボットをチェックして停止する（内部関数）
+// CheckTimeouts - タイムアウトしたロボットをチェックして停止する
 // =============================================================================
 //
+// run() のループから呼ばれます。テストから直接呼ぶこともできます
+// （SetClock で渡した時計を進めてから呼べば、待たずにタイムアウトを確かめられます）。
+//
 // 【この関数の動作】
 // 1. 読み取りロックで、タイムアウトしたロボットのリストを作成する
 // 2. ロックを解放する（ネットワーク通信前にロックを外す → 重要！）
@@ -310,7 +371,7 @@ func (t *TimeoutWatchdog) run(ctx context.Context) {
 // これにより、ネットワーク通信中にロックを保持しないようにしています。
 // ネットワーク通信は時間がかかるため、ロック中に行うと
 // 他の処理がブロックされてしまいます。
-func (t *TimeoutWatchdog) checkTimeouts(ctx context.Context, now time.Time) {
+func (t *TimeoutWatchdog) CheckTimeouts(ctx context.Context, now time.Time) {
 	// --- ステップ1: 読み取りロックでタイムアウトロボットを特定する ---
 	t.mu.RLock()
 
@@ -350,35 +411,12 @@ func (t *TimeoutWatchdog) checkTimeouts(ctx context.Context, now time.Time) {
 		)
 
 		// --- ゼロ速度コマンドを送信してロボットを停止する ---
+		// SetRampDown で減速時間が設定されていれば、0 まで段階的に下げます（watchdog_ramp.go 参照）。
+		// 停止は「ベストエフォート（最善の努力）」なので、送信に失敗してもリトライはしません。
 
 		// レジストリからアダプターを取得する
 		if adp, ok := t.registry.GetAdapter(robotID); ok {
-			// SendCommand(): ロボットにコマンドを送信する
-			//
-			// 【_ = adp.SendCommand(...)】
-			// 戻り値のエラーを _ で無視しています。
-			// タイムアウト停止は「ベストエフォート（最善の努力）」なので、
-			// 失敗してもリトライはしません。
-			//
-			// adapter.Command: コマンドを表す構造体
-			// Type: "velocity" → 速度コマンド
-			// Payload: 具体的な速度値（すべてゼロ = 停止）
-			_ = adp.SendCommand(ctx, adapter.Command{
-				Type: "velocity",
-				// map[string]any: キーがstring、値が任意の型（any = interface{}）のmap
-				//
-				// 【any とは？】
-				// any は Go 1.18 で追加された interface{} のエイリアスです。
-				// 「どんな型でも受け入れる」という意味です。
-				// Python の Any、TypeScript の any に相当します。
-				// ここでは float64 の値を入れていますが、
-				// string や int など他の型も入れられます。
-				Payload: map[string]any{
-					"linear_x":  0.0, // 前後方向の速度 = 0（停止）
-					"linear_y":  0.0, // 左右方向の速度 = 0（停止）
-					"angular_z": 0.0, // 回転速度 = 0（停止）
-				},
-			})
+			t.stopRobot(ctx, robotID, adp)
 		}
 
 		// --- 監視対象から除外する ---
@@ -39,6 +39,9 @@ import (
 	// そのためにピタゴラスの定理（√(x² + y²)）を使います。
 	"math"
 
+	// sync: ロボットごとの上限（SetRobotLimit）を複数のゴルーチンから安全に読み書きするため
+	"sync"
+
 	// zap: 高性能ロガー
 	// 速度が制限された時にログを出力します。
 	"go.uber.org/zap"
@@ -50,9 +53,8 @@ import (
 //
 // 【この構造体の役割】
 // 速度コマンドを受け取り、設定された最大値を超えていたら制限します。
-// この構造体はスレッドセーフ（並行安全）ではありません。
-// なぜなら、設定値（maxLinearVel, maxAngularVel）は初期化後に変更されないからです。
-// 読み取り専用のデータはロックなしで安全にアクセスできます。
+// 全体の上限（maxLinearVel, maxAngularVel）は初期化後に変更されないため、ロックなしで読めます。
+// 実行中に変わるロボットごとの上限（robotLimits）だけを mu で保護します。
 type VelocityLimiter struct {
 	// maxLinearVel: 最大直進速度（m/s = メートル毎秒）
 	// 例: 1.0 → 1秒間に最大1メートル移動
@@ -62,10 +64,35 @@ type VelocityLimiter struct {
 	// 例: 1.57 → 約1秒で90度回転（π/2 ≈ 1.57）
 	maxAngularVel float64
 
+	// mode: 上限を超えた時の動作（クランプするか、拒否するか）
+	// デフォルト（ゼロ値の ""）は ClampModeClamp と同じ扱いです。
+	mode ClampMode
+
+	// robotLimits: ロボットごとに下げた上限（SetRobotLimit で設定）
+	mu          sync.RWMutex
+	robotLimits map[string]RobotLimit
+
 	// logger: ログ出力用のロガー
 	logger *zap.Logger
 }
 
+// =============================================================================
+// ClampMode - 上限を超える速度コマンドの扱い方
+// =============================================================================
+//
+// 【なぜ選べるようにする？】
+// 黙って速度を下げる（クランプ）と、クライアントは自分の送った値が
+// そのまま使われたと思い込むことがあります。運用によっては、
+// 上限を超えたコマンドは丸ごと拒否して、正しい値を送り直させる方が安全です。
+type ClampMode string
+
+const (
+	// ClampModeClamp: 上限まで下げて実行する（デフォルト）
+	ClampModeClamp ClampMode = "clamp"
+	// ClampModeReject: 実行せずに拒否する（LimitResult.Rejected が true になる）
+	ClampModeReject ClampMode = "reject"
+)
+
 // =============================================================================
 // NewVelocityLimiter - VelocityLimiterのコンストラクタ
 // =============================================================================
@@ -85,10 +112,60 @@ func NewVelocityLimiter(maxLinear, maxAngular float64, logger *zap.Logger) *Velo
 	return &VelocityLimiter{
 		maxLinearVel:  maxLinear,
 		maxAngularVel: maxAngular,
+		robotLimits:   make(map[string]RobotLimit),
 		logger:        logger,
 	}
 }
 
+// =============================================================================
+// RobotLimit - ロボットごとの速度上限
+// =============================================================================
+//
+// 人の近くで作業する時など、運用中に特定のロボットだけ速度を下げるために使います。
+// 全体の上限（NewVelocityLimiter の値）より上げることはできません。
+type RobotLimit struct {
+	MaxLinear  float64 `json:"max_linear"`  // 最大直進速度（m/s）
+	MaxAngular float64 `json:"max_angular"` // 最大回転速度（rad/s）
+}
+
+// SetRobotLimit - ロボットの上限を設定する（全体の上限を超える値は全体の上限に揃える）
+func (v *VelocityLimiter) SetRobotLimit(robotID string, limit RobotLimit) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.robotLimits[robotID] = RobotLimit{
+		MaxLinear:  min(limit.MaxLinear, v.maxLinearVel),
+		MaxAngular: min(limit.MaxAngular, v.maxAngularVel),
+	}
+}
+
+// ClearRobotLimit - ロボットの上限を解除し、全体の上限に戻す
+func (v *VelocityLimiter) ClearRobotLimit(robotID string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	delete(v.robotLimits, robotID)
+}
+
+// LimitsFor - ロボットに実際に適用される上限を返す（overridden はロボットごとの上限が設定されているか）
+func (v *VelocityLimiter) LimitsFor(robotID string) (limit RobotLimit, overridden bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if l, ok := v.robotLimits[robotID]; ok {
+		return l, true
+	}
+	return RobotLimit{MaxLinear: v.maxLinearVel, MaxAngular: v.maxAngularVel}, false
+}
+
+// RobotLimits - ロボットごとの上限が設定されているロボットと、その上限を返す（コピー）
+func (v *VelocityLimiter) RobotLimits() map[string]RobotLimit {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	limits := make(map[string]RobotLimit, len(v.robotLimits))
+	for id, l := range v.robotLimits {
+		limits[id] = l
+	}
+	return limits
+}
+
 // =============================================================================
 // VelocityInput - 速度入力を表す構造体
 // =============================================================================
@@ -129,8 +206,22 @@ type LimitResult struct {
 	LinearY  float64 // 制限後のY方向速度
 	AngularZ float64 // 制限後の回転速度
 	Clamped  bool    // 制限が行われたか（true = 制限された）
+	Rejected bool    // 拒否モードで上限を超えていたか（true なら実行してはいけない）
 }
 
+// =============================================================================
+// SetClampMode - 上限を超えた時の動作を設定する
+// =============================================================================
+//
+// 起動時（Limit を呼び始める前）に一度だけ呼んでください（ロックで保護していません）。
+func (v *VelocityLimiter) SetClampMode(mode ClampMode) {
+	v.mode = mode
+}
+
+// MaxLinear / MaxAngular - 設定されている上限値を返す（拒否時のメッセージなどに使う）
+func (v *VelocityLimiter) MaxLinear() float64  { return v.maxLinearVel }
+func (v *VelocityLimiter) MaxAngular() float64 { return v.maxAngularVel }
+
 // =============================================================================
 // Limit - 速度値を制限する
 // =============================================================================
@@ -149,6 +240,80 @@ type LimitResult struct {
 //   - LimitResult: 制限後の速度値と制限フラグ
 //     （ポインタではなく値を返している → 小さい構造体なのでコピーでOK）
 func (v *VelocityLimiter) Limit(input VelocityInput) LimitResult {
+	return v.limit(input, RobotLimit{MaxLinear: v.maxLinearVel, MaxAngular: v.maxAngularVel})
+}
+
+// LimitFor - robotID のロボットの上限（SetRobotLimit で下げていればその値）で Limit する
+func (v *VelocityLimiter) LimitFor(robotID string, input VelocityInput) LimitResult {
+	limit, _ := v.LimitsFor(robotID)
+	return v.limit(input, limit)
+}
+
+// limit - 指定した上限で速度値を制限する（Limit / LimitFor の本体）
+func (v *VelocityLimiter) limit(input VelocityInput, ceiling RobotLimit) LimitResult {
+	result := clamp(input, ceiling)
+
+	// =========================================================================
+	// 制限が行われた場合、デバッグログを出力する
+	// =========================================================================
+	//
+	// 【なぜ Debug レベル？】
+	// 速度制限は頻繁に発生する可能性があるため、
+	// Warn や Info ではなく Debug レベルで出力します。
+	// 本番環境ではDebugログを無効にすることで、ログの量を削減できます。
+	//
+	// 【ログのフィールド名の慣例】
+	// req_lx: requested linear_x（要求された直進X速度）
+	// out_lx: output linear_x（出力された直進X速度）
+	// 短い名前を使うことで、ログの可読性を高めています。
+	// 拒否モードでは、上限を超えていたら速度を変えずに「拒否」として返す
+	if result.Clamped && v.mode == ClampModeReject {
+		v.logger.Debug("Velocity rejected",
+			zap.Float64("req_lx", input.LinearX),
+			zap.Float64("req_ly", input.LinearY),
+			zap.Float64("req_az", input.AngularZ),
+		)
+		return LimitResult{
+			LinearX:  input.LinearX,
+			LinearY:  input.LinearY,
+			AngularZ: input.AngularZ,
+			Rejected: true,
+		}
+	}
+
+	if result.Clamped {
+		v.logger.Debug("Velocity clamped",
+			zap.Float64("req_lx", input.LinearX),
+			zap.Float64("req_ly", input.LinearY),
+			zap.Float64("req_az", input.AngularZ),
+			zap.Float64("out_lx", result.LinearX),
+			zap.Float64("out_ly", result.LinearY),
+			zap.Float64("out_az", result.AngularZ),
+		)
+	}
+
+	return result
+}
+
+// =============================================================================
+// Clamp - 動作モードに関係なく、速度値を上限までクランプする
+// =============================================================================
+//
+// Limit() と同じ計算ですが、拒否モードでも拒否せずに上限まで下げた値を返します。
+// 相対速度コマンド（velocity_delta）のように、累積した値を上限で頭打ちにしたい場合に使います。
+// ログは出力しません。
+func (v *VelocityLimiter) Clamp(input VelocityInput) LimitResult {
+	return clamp(input, RobotLimit{MaxLinear: v.maxLinearVel, MaxAngular: v.maxAngularVel})
+}
+
+// ClampFor - robotID のロボットの上限（SetRobotLimit で下げていればその値）で Clamp する
+func (v *VelocityLimiter) ClampFor(robotID string, input VelocityInput) LimitResult {
+	limit, _ := v.LimitsFor(robotID)
+	return clamp(input, limit)
+}
+
+// clamp - 指定した上限で速度値をクランプする（Clamp / ClampFor の本体）
+func clamp(input VelocityInput, ceiling RobotLimit) LimitResult {
 	// 入力値をそのまま結果にコピーする
 	// 制限が不要な場合は、この値がそのまま返されます。
 	result := LimitResult{
@@ -181,7 +346,7 @@ func (v *VelocityLimiter) Limit(input VelocityInput) LimitResult {
 	// ベクトル全体をスケールすることで、移動方向を変えずに速さだけを制限できます。
 	linearMag := math.Sqrt(input.LinearX*input.LinearX + input.LinearY*input.LinearY)
 
-	if linearMag > v.maxLinearVel {
+	if linearMag > ceiling.MaxLinear {
 		// 【スケールファクター（scale factor）の計算】
 		// scale = 最大速度 / 実際の速度
 		// 例: maxLinear=1.0, linearMag=2.0 → scale=0.5
@@ -191,7 +356,7 @@ func (v *VelocityLimiter) Limit(input VelocityInput) LimitResult {
 		// ユーザーが「右前方に進め」と指示した場合、
 		// 速さだけを制限して方向は変えないのが正しい動作です。
 		// XとYに同じスケールを掛けることで、ベクトルの方向が保たれます。
-		scale := v.maxLinearVel / linearMag
+		scale := ceiling.MaxLinear / linearMag
 		result.LinearX = input.LinearX * scale
 		result.LinearY = input.LinearY * scale
 		result.Clamped = true
@@ -208,41 +373,17 @@ func (v *VelocityLimiter) Limit(input VelocityInput) LimitResult {
 	//
 	// 回転速度は正（反時計回り）と負（時計回り）の両方があるため、
 	// 絶対値で比較して、方向（符号）を維持したまま制限します。
-	if math.Abs(input.AngularZ) > v.maxAngularVel {
+	if math.Abs(input.AngularZ) > ceiling.MaxAngular {
 		// 回転方向（符号）に応じて最大値を設定する
 		if input.AngularZ > 0 {
 			// 正の値 → 正の最大値に制限
-			result.AngularZ = v.maxAngularVel
+			result.AngularZ = ceiling.MaxAngular
 		} else {
 			// 負の値 → 負の最大値に制限
-			result.AngularZ = -v.maxAngularVel
+			result.AngularZ = -ceiling.MaxAngular
 		}
 		result.Clamped = true
 	}
 
-	// =========================================================================
-	// 制限が行われた場合、デバッグログを出力する
-	// =========================================================================
-	//
-	// 【なぜ Debug レベル？】
-	// 速度制限は頻繁に発生する可能性があるため、
-	// Warn や Info ではなく Debug レベルで出力します。
-	// 本番環境ではDebugログを無効にすることで、ログの量を削減できます。
-	//
-	// 【ログのフィールド名の慣例】
-	// req_lx: requested linear_x（要求された直進X速度）
-	// out_lx: output linear_x（出力された直進X速度）
-	// 短い名前を使うことで、ログの可読性を高めています。
-	if result.Clamped {
-		v.logger.Debug("Velocity clamped",
-			zap.Float64("req_lx", input.LinearX),
-			zap.Float64("req_ly", input.LinearY),
-			zap.Float64("req_az", input.AngularZ),
-			zap.Float64("out_lx", result.LinearX),
-			zap.Float64("out_ly", result.LinearY),
-			zap.Float64("out_az", result.AngularZ),
-		)
-	}
-
 	return result
 }
@@ -0,0 +1,189 @@
+// =============================================================================
+// ファイル: watchdog_ramp.go
+// 概要: ウォッチドッグのタイムアウト時に、速度を段階的に 0 へ下げる（ランプダウン）
+//
+// 【なぜ必要？】
+// タイムアウト時に速度 0 を1回送ると、速く走っているロボットは急停止します。
+// モーターやギアに負担がかかり、荷物を積んでいれば荷崩れの原因にもなります。
+//
+// 【仕組み】
+// SetRampDown で減速時間を設定すると、タイムアウトしたロボットに
+// 直前の速度から 0 まで少しずつ下げた速度を WatchdogRampStep ごとに送ります。
+//
+//	速度
+//	 0.4 ┤■
+//	 0.3 ┤  ■
+//	 0.2 ┤    ■
+//	 0.1 ┤      ■
+//	 0.0 ┤        ■ ← 最後は必ずちょうど 0
+//	     └──────────→ 時間（減速時間）
+//
+// ランプダウン中に新しいコマンドが届くと（RecordCommand）、残りの段階は送りません。
+// E-Stop やソフトストップなど、ロボットを止める操作も CancelRampDown で打ち切ります。
+// 打ち切りが間に合わなくても、E-Stop が発動していれば各段階の前に気づいて止まります（SetEStop）。
+// ウォッチドッグ自体が止まった時（シャットダウン）は、残りを飛ばしてすぐ 0 を送ります。
+//
+// 【デフォルトは即時停止】
+// 減速時間を設定しなければ、従来どおり速度 0 を1回送ります。
+// 減速中もロボットは動き続けるため、安全のため明示的に有効にした時だけ使います。
+// =============================================================================
+package safety
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"go.uber.org/zap"
+)
+
+// WatchdogRampStep - ランプダウン中に速度を送る間隔
+const WatchdogRampStep = 50 * time.Millisecond
+
+// =============================================================================
+// SetRampDown - タイムアウト時の減速時間を設定する
+// =============================================================================
+//
+// current は、ロボットに直前に送った速度を返す関数です（ランプダウンの始点になります）。
+// decel が 0 以下、または current が nil なら、即時停止（デフォルト）になります。
+// Start() より前に呼んでください。
+func (t *TimeoutWatchdog) SetRampDown(decel time.Duration, current func(robotID string) adapter.Velocity) {
+	t.rampDown = decel
+	t.currentVelocity = current
+}
+
+// SetEStop - ランプダウンの各段階の前に確認する E-Stop を設定する
+// E-Stop が発動したロボットには、減速途中の速度を送りません。Start() より前に呼んでください。
+func (t *TimeoutWatchdog) SetEStop(estop *EStopManager) {
+	t.estop = estop
+}
+
+// =============================================================================
+// CancelRampDown - 進行中のランプダウンを打ち切る
+// =============================================================================
+//
+// E-Stop、ソフトストップ、運用からの除外など、ロボットを止める操作から呼びます。
+// 打ち切らないと、止めた後も減速途中の（0 でない）速度が送られ続けます。
+// ランプダウン中でなければ何もしません。
+func (t *TimeoutWatchdog) CancelRampDown(robotID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cancelRampDownLocked(robotID)
+}
+
+// CancelAllRampDowns - すべてのロボットのランプダウンを打ち切る（全ロボットの E-Stop 用）
+func (t *TimeoutWatchdog) CancelAllRampDowns() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for robotID := range t.ramps {
+		t.cancelRampDownLocked(robotID)
+	}
+}
+
+// =============================================================================
+// RampDownSequence - ランプダウンで送る速度の並びを返す
+// =============================================================================
+//
+// WatchdogRampStep ごとに1つずつ送る速度で、v から直線的に下がり、最後はちょうど 0 です。
+// decel が WatchdogRampStep 以下なら、0 だけの即時停止になります。
+func RampDownSequence(v adapter.Velocity, decel time.Duration) []adapter.Velocity {
+	steps := max(int(math.Ceil(float64(decel)/float64(WatchdogRampStep))), 1)
+	seq := make([]adapter.Velocity, steps)
+	for i := 0; i < steps-1; i++ {
+		f := 1 - float64(i+1)/float64(steps)
+		seq[i] = adapter.Velocity{LinearX: v.LinearX * f, LinearY: v.LinearY * f, AngularZ: v.AngularZ * f}
+	}
+	return seq // 最後の要素はゼロ値（停止）のまま
+}
+
+// stopRobot: タイムアウトしたロボットを止める（ランプダウンが有効ならゴルーチンで段階的に）
+func (t *TimeoutWatchdog) stopRobot(ctx context.Context, robotID string, adp adapter.RobotAdapter) {
+	var v adapter.Velocity
+	if t.rampDown > 0 && t.currentVelocity != nil {
+		v = t.currentVelocity(robotID)
+	}
+	if v == (adapter.Velocity{}) || t.estopActive(robotID) {
+		sendStopVelocity(ctx, adp, adapter.Velocity{})
+		return
+	}
+
+	// 新しいコマンドで打ち切れるよう、キャンセル関数を登録してから始める
+	rampCtx, cancel := context.WithCancel(context.Background())
+	t.mu.Lock()
+	if prev, ok := t.ramps[robotID]; ok {
+		prev()
+	}
+	t.ramps[robotID] = cancel
+	t.mu.Unlock()
+
+	go t.runRampDown(ctx, rampCtx, robotID, adp, RampDownSequence(v, t.rampDown))
+}
+
+// runRampDown: seq の速度を WatchdogRampStep ごとに送る
+// rampCtx が閉じたら（新しいコマンドが届いた）そこで終わり、
+// ctx が閉じたら（ウォッチドッグの停止）残りを飛ばして 0 を送ります。
+func (t *TimeoutWatchdog) runRampDown(ctx, rampCtx context.Context, robotID string, adp adapter.RobotAdapter, seq []adapter.Velocity) {
+	defer t.finishRampDown(robotID, rampCtx)
+
+	ticker := time.NewTicker(WatchdogRampStep)
+	defer ticker.Stop()
+	for i, v := range seq {
+		if i > 0 {
+			select {
+			case <-rampCtx.Done():
+				t.logger.Debug("Watchdog ramp-down superseded by a new command", zap.String("robot_id", robotID))
+				return
+			case <-ctx.Done():
+				sendStopVelocity(context.Background(), adp, adapter.Velocity{})
+				return
+			case <-ticker.C:
+			}
+		}
+		// 待っている間に新しいコマンドが届いていたら、古い速度で上書きしない
+		if rampCtx.Err() != nil {
+			return
+		}
+		// E-Stop が発動していたら、減速途中の速度は送らない（停止は E-Stop が送っている）
+		if t.estopActive(robotID) {
+			t.logger.Debug("Watchdog ramp-down stopped by E-Stop", zap.String("robot_id", robotID))
+			return
+		}
+		sendStopVelocity(rampCtx, adp, v)
+	}
+}
+
+// estopActive: robotID の E-Stop が発動しているか（SetEStop がなければ false）
+func (t *TimeoutWatchdog) estopActive(robotID string) bool {
+	return t.estop != nil && t.estop.IsActive(robotID)
+}
+
+// finishRampDown: 終わったランプダウンの登録を外す（後から始まった別のランプダウンは残す）
+func (t *TimeoutWatchdog) finishRampDown(robotID string, rampCtx context.Context) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if cancel, ok := t.ramps[robotID]; ok && rampCtx.Err() == nil {
+		cancel()
+		delete(t.ramps, robotID)
+	}
+}
+
+// cancelRampDownLocked: 進行中のランプダウンを打ち切る（t.mu を保持した状態で呼ぶこと）
+func (t *TimeoutWatchdog) cancelRampDownLocked(robotID string) {
+	if cancel, ok := t.ramps[robotID]; ok {
+		cancel()
+		delete(t.ramps, robotID)
+	}
+}
+
+// sendStopVelocity: 停止のための速度コマンドを送る（ベストエフォート、失敗してもリトライしない）
+func sendStopVelocity(ctx context.Context, adp adapter.RobotAdapter, v adapter.Velocity) {
+	_ = adp.SendCommand(ctx, adapter.Command{
+		Type: "velocity",
+		Payload: map[string]any{
+			"linear_x":  v.LinearX,
+			"linear_y":  v.LinearY,
+			"angular_z": v.AngularZ,
+		},
+	})
+}
@@ -0,0 +1,128 @@
+// =============================================================================
+// ファイル: app_heartbeat.go
+// 概要: アプリケーション層のハートビート（server_heartbeat / heartbeat）
+//
+// 【WebSocket の Ping/Pong との違い】
+// Ping/Pong はブラウザが自動で答えるため、タブの JavaScript が固まっていても
+// 接続は「生きている」ように見えます。逆にクライアントからも、
+// ゲートウェイのイベントループが止まっていることは Ping/Pong では分かりません。
+//
+//	ゲートウェイ ──server_heartbeat（seq, server_time_ms）──→ 全クライアント
+//	クライアント ──heartbeat（または任意のメッセージ）──────→ ゲートウェイ
+//
+// クライアントは seq の抜けや届く間隔で、ゲートウェイ側の停止・配信の詰まりを検出できます。
+// タイムアウトを設定すると、その間メッセージが1通も届かないクライアントを切断します。
+// 受信時刻は HandleMessage がメッセージごとに記録し、/debug/health にも表示されます。
+// =============================================================================
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"go.uber.org/zap"
+)
+
+// appHeartbeatCloseReason: ハートビートが途絶えて切断する時に Close フレームで伝える原因
+const appHeartbeatCloseReason = "application heartbeat timeout"
+
+// =============================================================================
+// AppHeartbeat - ハートビートの送信と、途絶えたクライアントの切断
+// =============================================================================
+type AppHeartbeat struct {
+	hub      *Hub
+	codec    protocol.Codec
+	interval time.Duration // server_heartbeat を送る間隔（0 = 送らない）
+	timeout  time.Duration // この間メッセージのないクライアントを切断する（0 = 切断しない）
+	seq      uint64        // 送った server_heartbeat の通し番号（Tick からしか触らない）
+	logger   *zap.Logger
+}
+
+// NewAppHeartbeat - コンストラクタ
+// interval と timeout のどちらかは 0 より大きくしてください（両方 0 なら Start は何もしません）。
+func NewAppHeartbeat(hub *Hub, codec protocol.Codec, interval, timeout time.Duration, logger *zap.Logger) *AppHeartbeat {
+	return &AppHeartbeat{
+		hub:      hub,
+		codec:    codec,
+		interval: interval,
+		timeout:  timeout,
+		logger:   logger,
+	}
+}
+
+// =============================================================================
+// Start - 定期的に Tick を呼ぶゴルーチンを起動する
+// =============================================================================
+//
+// ctx がキャンセルされると停止します（FlowController.Start と同じ形）。
+// 周期は interval です。interval が 0（送信しない）の場合は、
+// 切断の判定が遅れすぎないよう timeout の半分の周期で確認します。
+func (a *AppHeartbeat) Start(ctx context.Context, wg *sync.WaitGroup) {
+	period := a.interval
+	if period <= 0 {
+		period = a.timeout / 2
+	}
+	if period <= 0 {
+		return
+	}
+	if wg != nil {
+		wg.Add(1)
+	}
+	go func() {
+		if wg != nil {
+			defer wg.Done()
+		}
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				a.Tick(now)
+			}
+		}
+	}()
+}
+
+// =============================================================================
+// Tick - ハートビートを1回送り、途絶えたクライアントを切断する
+// =============================================================================
+//
+// Start() のゴルーチンから呼ばれます。テストから直接呼ぶこともできます。
+// 同時に複数のゴルーチンから呼ばないでください（seq を保護していません）。
+// 戻り値は切断したクライアントの数です。
+func (a *AppHeartbeat) Tick(now time.Time) int {
+	if a.interval > 0 {
+		a.seq++
+		msg := protocol.NewMessage(protocol.MsgTypeServerHeartbeat, "")
+		msg.Payload["seq"] = a.seq
+		msg.Payload["server_time_ms"] = now.UnixMilli()
+		msg.Payload["interval_ms"] = a.interval.Milliseconds()
+		msg.Payload["timeout_ms"] = a.timeout.Milliseconds()
+		data, err := a.codec.Encode(msg)
+		if err != nil {
+			a.logger.Error("Failed to encode server heartbeat", zap.Error(err))
+		} else {
+			a.hub.BroadcastToAll(data)
+		}
+	}
+
+	if a.timeout <= 0 {
+		return 0
+	}
+	stale := a.hub.InactiveClients(now.Add(-a.timeout).UnixMilli())
+	for _, client := range stale {
+		a.logger.Warn("Disconnecting client after application heartbeat timeout",
+			zap.String("client_id", client.ID),
+			zap.String("user_id", client.UserID),
+			zap.Int64("last_activity_ms", client.lastAppActivity.Load()),
+			zap.Duration("timeout", a.timeout),
+		)
+		a.hub.Disconnect(client, websocket.CloseGoingAway, appHeartbeatCloseReason)
+	}
+	return len(stale)
+}
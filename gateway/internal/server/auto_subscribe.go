@@ -0,0 +1,68 @@
+// =============================================================================
+// ファイル: auto_subscribe.go
+// 概要: 認証時に自動で購読するロボットの範囲（auto_subscribe）を決める
+//
+// 【背景】
+// これまで auth は「robot_id が指定されていればそのロボットを購読する」だけで、
+// ログイン後の購読状態が robot_id の有無で暗黙に変わっていました。
+// 購読を subscribe で明示的に管理したいクライアントや、
+// すべてのロボットを監視したいクライアントもあります。
+//
+// 【モード】
+//
+//	none:   自動では購読しない（subscribe で明示的に購読する）
+//	single: auth の robot_id のロボットだけを購読する（デフォルト、従来の動作）
+//	all:    登録されているすべてのロボットを購読する
+//
+// サーバーのデフォルトは GATEWAY_AUTO_SUBSCRIBE で、auth の "auto_subscribe" で
+// 接続ごとに上書きできます。認証の応答の "subscriptions" に、購読したロボットが入ります。
+// =============================================================================
+package server
+
+import (
+	"sort"
+)
+
+// AutoSubscribeMode - 認証時に自動で購読するロボットの範囲
+type AutoSubscribeMode string
+
+const (
+	AutoSubscribeNone   AutoSubscribeMode = "none"   // 自動では購読しない
+	AutoSubscribeSingle AutoSubscribeMode = "single" // auth の robot_id のロボットだけ
+	AutoSubscribeAll    AutoSubscribeMode = "all"    // 登録されているすべてのロボット
+)
+
+// ParseAutoSubscribeMode - 文字列をモードに変換する（不明な値なら ok が false）
+func ParseAutoSubscribeMode(s string) (mode AutoSubscribeMode, ok bool) {
+	switch mode = AutoSubscribeMode(s); mode {
+	case AutoSubscribeNone, AutoSubscribeSingle, AutoSubscribeAll:
+		return mode, true
+	}
+	return "", false
+}
+
+// SetAutoSubscribe - auth で "auto_subscribe" が省略された時のモードを設定する
+// 設定しなければ AutoSubscribeSingle（robot_id のロボットだけ）です。
+// サーバー起動前（メッセージを受け付ける前）に一度だけ呼んでください。
+func (h *Handler) SetAutoSubscribe(mode AutoSubscribeMode) {
+	h.autoSubscribe = mode
+}
+
+// autoSubscribeRobots - モードに応じて、認証時に購読するロボットを返す
+func (h *Handler) autoSubscribeRobots(mode AutoSubscribeMode, robotID string) []string {
+	switch mode {
+	case AutoSubscribeSingle:
+		if robotID != "" {
+			return []string{robotID}
+		}
+	case AutoSubscribeAll:
+		active := h.registry.GetAllActive()
+		robots := make([]string, 0, len(active))
+		for id := range active {
+			robots = append(robots, id)
+		}
+		sort.Strings(robots)
+		return robots
+	}
+	return nil
+}
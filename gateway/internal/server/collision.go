@@ -0,0 +1,86 @@
+// =============================================================================
+// ファイル: collision.go
+// 概要: 衝突リスク（障害物に向かって動いている）を検出した時に、ロボットを止める処理
+//
+// 検出そのものは safety.CollisionMonitor が LiDAR のスキャンから行います。
+// ここでは、設定された止め方（estop / override）でロボットを止め、
+// safety_alert で全クライアントに知らせます。
+// =============================================================================
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/safety"
+	"go.uber.org/zap"
+)
+
+// collisionUserID: 衝突リスクによる E-Stop の発動者として記録する名前
+const collisionUserID = "collision_monitor"
+
+// =============================================================================
+// NotifyCollisionRisk - 衝突リスクのあるロボットを止め、安全アラートを配信する
+// =============================================================================
+//
+// safety.CollisionMonitor の SetRiskCallback にそのまま登録できる形です。
+//
+// 【止め方】
+//
+//	estop:    E-Stop を発動する（発動者は "collision_monitor"、estop_activated のアラートも送る）
+//	override: 速度 0 とナビゲーションの中止を送る（E-Stop がない構成でもこちらになる）
+//
+// 【アラートの形（Payload）】リスクが新しく始まった時だけ送ります。
+//
+//	{"type": "collision_risk", "distance": 0.32, "bearing": 0.05, "stop_distance": 0.5, "action": "estop"}
+func (h *Handler) NotifyCollisionRisk(risk safety.CollisionRisk) {
+	robotID := risk.RobotID
+	action := risk.Action
+
+	// 最小間隔で保留中の速度コマンドが、停止の後に送られないようにする
+	h.coalescer.Drop(robotID)
+	h.cancelRampDown(robotID)
+	if action == safety.CollisionActionEStop && h.estop != nil {
+		if !h.estop.IsActive(robotID) {
+			reason := fmt.Sprintf("collision risk: obstacle at %.2fm (stop distance %.2fm)", risk.Distance, risk.StopDistance)
+			if err := h.estop.Activate(context.Background(), robotID, collisionUserID, reason); err != nil {
+				h.logger.Error("Failed to activate E-Stop on collision risk",
+					zap.String("robot_id", robotID),
+					zap.Error(err),
+				)
+			} else {
+				h.ResetVelocityBaseline(robotID)
+
+				alert := protocol.NewMessage(protocol.MsgTypeSafetyAlert, robotID)
+				alert.Payload["type"] = "estop_activated"
+				alert.Payload["reason"] = reason
+				alert.Payload["user_id"] = collisionUserID
+				h.broadcastAlert(alert)
+			}
+		}
+	} else {
+		action = safety.CollisionActionOverride
+		if adp, ok := h.registry.GetAdapter(robotID); ok {
+			if err := stopRobot(adp, robotID); err != nil {
+				h.logger.Error("Failed to stop robot on collision risk",
+					zap.String("robot_id", robotID),
+					zap.Error(err),
+				)
+			}
+		}
+		h.setLastVelocity(robotID, adapter.Velocity{})
+	}
+
+	if !risk.New {
+		return
+	}
+	alert := protocol.NewMessage(protocol.MsgTypeSafetyAlert, robotID)
+	alert.Payload["type"] = "collision_risk"
+	alert.Payload["distance"] = risk.Distance
+	alert.Payload["bearing"] = risk.Bearing
+	alert.Payload["stop_distance"] = risk.StopDistance
+	alert.Payload["action"] = string(action)
+	h.broadcastAlert(alert)
+}
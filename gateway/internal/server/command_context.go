@@ -0,0 +1,92 @@
+// =============================================================================
+// ファイル: command_context.go
+// 概要: アダプターにコマンドを送る時の context（サーバーの寿命 + コマンドごとのタイムアウト）
+//
+// 【背景】
+// 速度コマンドなどは context.Background() で SendCommand を呼んでいました。
+// アダプター（ロボットとの通信）が固まると、その呼び出しは戻らず、
+// そのクライアントの readPump（メッセージの受信ループ）ごと止まってしまいます。
+// シャットダウンしても打ち切られないため、プロセスの終了も待たされます。
+//
+// 【仕組み】
+//
+//	SetCommandContext(サーバーの ctx, タイムアウト)
+//	    └─→ commandContext(): ctx から派生し、タイムアウトを付けた context
+//	            ├─ タイムアウト   → command_timeout のエラーを返す
+//	            └─ シャットダウン → 送信を打ち切る
+//
+// アダプターが ctx を無視して固まっても待ち続けないよう、SendCommand は
+// ゴルーチンで呼び、ctx が終わった時点で諦めます（diagnostics と同じ形）。
+// E-Stop と停止のコマンド（stop_all、切断時の停止）は、シャットダウン中こそ届けたいので対象外です。
+// =============================================================================
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"go.uber.org/zap"
+)
+
+// DefaultCommandTimeout - SetCommandContext を呼ばなかった時の、コマンドごとのタイムアウト
+const DefaultCommandTimeout = 2 * time.Second
+
+// SetCommandContext - アダプターへのコマンド送信に使う context の元とタイムアウトを設定する
+//
+// ctx にはサーバーの寿命の context（シャットダウンでキャンセルされるもの）を渡します。
+// timeout が 0 以下なら DefaultCommandTimeout を使います。
+// サーバー起動前（メッセージを受け付ける前）に一度だけ呼んでください。
+func (h *Handler) SetCommandContext(ctx context.Context, timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = DefaultCommandTimeout
+	}
+	h.baseCtx = ctx
+	h.cmdTimeout = timeout
+}
+
+// commandContext: コマンド1つ分の context を作る（使い終わったら cancel を呼ぶこと）
+func (h *Handler) commandContext() (context.Context, context.CancelFunc) {
+	base, timeout := h.baseCtx, h.cmdTimeout
+	if base == nil {
+		base = context.Background()
+	}
+	if timeout <= 0 {
+		timeout = DefaultCommandTimeout
+	}
+	return context.WithTimeout(base, timeout)
+}
+
+// sendToAdapter: コマンドを送り、ctx が終わったら結果を待たずに戻る
+// 結果のチャネルはバッファ付きなので、遅れて戻ったゴルーチンも終了できます。
+func sendToAdapter(ctx context.Context, adp adapter.RobotAdapter, cmd adapter.Command) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- adp.SendCommand(ctx, cmd)
+	}()
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// sendCommandError: コマンド送信の失敗をクライアントに伝える
+// タイムアウトは command_timeout のエラーコードで、シャットダウンによる中断はその旨を返します。
+func (h *Handler) sendCommandError(client *Client, robotID string, err error) {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		h.logger.Warn("Adapter command timed out",
+			zap.String("robot_id", robotID),
+			zap.Duration("timeout", h.cmdTimeout),
+		)
+		h.sendErrorCode(client, robotID, protocol.ErrCodeCommandTimeout, "Robot did not accept the command in time")
+	case errors.Is(err, context.Canceled):
+		h.sendError(client, robotID, "Command aborted: gateway is shutting down")
+	default:
+		h.sendError(client, robotID, "Command failed: "+err.Error())
+	}
+}
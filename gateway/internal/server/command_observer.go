@@ -0,0 +1,100 @@
+// =============================================================================
+// ファイル: command_observer.go
+// 概要: 観察者モード（操作者が送ったコマンドの写しを他のクライアントに配信する）
+//
+// 【なぜ必要か？】
+// 操作の研修や監督、模倣学習の「熟練者の操作を見る」UI では、
+// センサーデータだけでなく、操作者がロボットに何を指令したかを正確に見る必要があります。
+//
+// 【仕組み】
+//
+//	observe_commands（観察者か管理者） ──→ Hub.SetCommandObservation
+//	操作者のコマンドが受け付けられる    ──→ command_observed を観察者に配信（本人には送らない）
+//
+// 写しを送るのはコマンドが受け付けられた後（アダプターに届いた後）だけで、
+// 拒否されたコマンドは送りません。payload は速度制限や座標変換を適用した後の、
+// 実際にロボットへ送った値です。
+// =============================================================================
+package server
+
+import (
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"go.uber.org/zap"
+)
+
+// SetObserverUsers - コマンドを観察できるユーザーID（観察者）を設定する
+// 管理者（SetAdminUsers）は指定しなくても観察できます。
+// サーバー起動前（メッセージを受け付ける前）に一度だけ呼んでください。
+func (h *Handler) SetObserverUsers(users []string) {
+	h.observers = make(map[string]bool, len(users))
+	for _, u := range users {
+		h.observers[u] = true
+	}
+}
+
+// canObserve: 認証済みの観察者か管理者かどうか
+func (h *Handler) canObserve(client *Client) bool {
+	return client.Authenticated && (h.observers[client.UserID] || h.admins[client.UserID])
+}
+
+// =============================================================================
+// handleObserveCommands - ロボットのコマンドの観察を切り替える
+// =============================================================================
+//
+// 【Payload】
+//
+//	{"enabled": true}   // 観察する（省略時）
+//	{"enabled": false}  // 観察をやめる
+//
+// 応答は cmd_ack（command: "observe_commands"）です。
+func (h *Handler) handleObserveCommands(client *Client, msg *protocol.Message) {
+	if !client.Authenticated {
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeNotAuthenticated, "Not authenticated")
+		return
+	}
+	if !h.canObserve(client) {
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeForbidden, "observe_commands is only available to observer and admin users")
+		return
+	}
+	if msg.RobotID == "" {
+		h.sendError(client, "", "Missing robot_id")
+		return
+	}
+
+	enabled := true
+	if v, ok := msg.Payload["enabled"].(bool); ok {
+		enabled = v
+	}
+	h.hub.SetCommandObservation(client, msg.RobotID, enabled)
+
+	h.logger.Info("Command observation changed",
+		zap.String("client_id", client.ID),
+		zap.String("user_id", client.UserID),
+		zap.String("robot_id", msg.RobotID),
+		zap.Bool("enabled", enabled),
+	)
+
+	ack := protocol.NewMessage(protocol.MsgTypeCommandAck, msg.RobotID)
+	ack.Payload["command"] = "observe_commands"
+	ack.Payload["enabled"] = enabled
+	h.sendToClient(client, ack)
+}
+
+// notifyCommandObserved - 受け付けたコマンドの写しを、そのロボットの観察者に送る
+// 観察者が誰もいなければ何もしません（エンコードもしない）。
+func (h *Handler) notifyCommandObserved(client *Client, robotID, command string, payload map[string]any) {
+	if !h.hub.HasCommandObservers() {
+		return
+	}
+	observed := protocol.NewMessage(protocol.MsgTypeCommandObserved, robotID)
+	observed.UserID = client.UserID
+	observed.Payload["command"] = command
+	observed.Payload["payload"] = payload
+	observed.Payload["user_id"] = client.UserID
+	data, err := h.codec.Encode(observed)
+	if err != nil {
+		h.logger.Error("Failed to encode observed command", zap.String("robot_id", robotID), zap.Error(err))
+		return
+	}
+	h.hub.BroadcastCommandObserved(robotID, data, client)
+}
@@ -0,0 +1,102 @@
+// =============================================================================
+// ファイル: command_policy.go
+// 概要: ロボットごとに受け付けるコマンド種別の制限（コマンドのホワイトリスト）
+//
+// 【なぜ必要？】
+// 固定設置のセンサーステーションのように、速度指令やナビゲーション目標を
+// 決して受け取ってはいけないロボットがあります。クライアントが誤って送っても、
+// アダプターが拒否してくれることを当てにせず、ゲートウェイの入口で止めます。
+//
+// 【許可されるコマンドの決め方】
+// 1. ロボットの能力（Capabilities）が示すコマンドだけを許可する（デフォルト）
+// 2. 設定（GATEWAY_ROBOT_ALLOWED_COMMANDS）でロボットごとにさらに絞り込める
+//
+// 緊急停止は安全のため、どのロボットに対しても常に許可します（ここでは扱いません）。
+// =============================================================================
+package server
+
+import (
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"go.uber.org/zap"
+)
+
+// capabilityAllows: コマンド種別がロボットの能力で許可されているか
+func capabilityAllows(caps adapter.Capabilities, command string) bool {
+	switch command {
+	case "velocity":
+		return caps.SupportsVelocityControl
+	case "nav_goal", "nav_cancel":
+		return caps.SupportsNavigation
+	case "dock", "undock":
+		return caps.SupportsDocking
+	case "reset_pose":
+		return caps.SupportsPoseReset
+	default:
+		return true
+	}
+}
+
+// =============================================================================
+// SetAllowedCommands - ロボットごとに受け付けるコマンド種別を設定する
+// =============================================================================
+//
+// allowed はロボットIDからコマンド種別（"velocity", "nav_goal", "nav_cancel",
+// "dock", "undock", "reset_pose", "reset_error"）の一覧へのマップです。
+// 一覧にあっても能力（Capabilities）が対応していないコマンドは許可しません。
+// 空の一覧は「どのコマンドも受け付けない」（センサー専用）という意味です。
+// マップにないロボットは、能力が示すコマンドをすべて受け付けます。
+func (h *Handler) SetAllowedCommands(allowed map[string][]string) {
+	copied := make(map[string]map[string]bool, len(allowed))
+	for robotID, commands := range allowed {
+		set := make(map[string]bool, len(commands))
+		for _, command := range commands {
+			set[command] = true
+		}
+		copied[robotID] = set
+	}
+
+	h.allowedMu.Lock()
+	h.allowedCommands = copied
+	h.allowedMu.Unlock()
+}
+
+// =============================================================================
+// commandAllowed - ロボットがそのコマンド種別を受け付けるか確認する
+// =============================================================================
+//
+// 受け付けない場合は command_not_allowed（無効にしたロボットなら robot_disabled）の
+// エラーを返して false を返します。
+// 未登録のロボットは判断できないため true を返し、後の "Robot not found" に任せます。
+func (h *Handler) commandAllowed(client *Client, robotID, command string) bool {
+	// 運用から外したロボット（set_robot_enabled）は、どのコマンドも受け付けない
+	if !h.robotEnabled(client, robotID) {
+		return false
+	}
+
+	allowed := true
+
+	h.allowedMu.RLock()
+	if set, ok := h.allowedCommands[robotID]; ok && !set[command] {
+		allowed = false
+	}
+	h.allowedMu.RUnlock()
+
+	if allowed {
+		if adp, ok := h.registry.GetAdapter(robotID); ok {
+			allowed = capabilityAllows(adp.GetCapabilities(), command)
+		}
+	}
+	if allowed {
+		return true
+	}
+
+	h.logger.Warn("Command not allowed for robot",
+		zap.String("robot_id", robotID),
+		zap.String("command", command),
+		zap.String("client_id", client.ID),
+	)
+	h.sendErrorCode(client, robotID, protocol.ErrCodeCommandNotAllowed,
+		"Command "+command+" is not allowed for robot "+robotID)
+	return false
+}
@@ -0,0 +1,112 @@
+// =============================================================================
+// ファイル: debug_health.go
+// 概要: ゴルーチンとチャネルの健全性を返す診断用エンドポイント（/debug/health）
+//
+// 【なぜ必要？】
+// このゲートウェイはチャネルとゴルーチンを多用しているため、本番で起きる問題の多くは
+// 「どこかのゴルーチンが止まった」「あるクライアントの Send バッファが詰まった」です。
+// pprof ではゴルーチンのスタックは見えても、それが Hub.Run なのか、どのクライアントが
+// 詰まっているのかは読み解く必要があります。ここではそれをゲートウェイの言葉で返します。
+//
+// 【返す内容】
+//   - goroutines:   プロセス全体のゴルーチン数（増え続けていればリーク）
+//   - clients:      接続中のクライアント数
+//   - adapters:     登録済みのアダプターと接続状態（接続中のものはセンサーループが動いている）
+//   - send_buffers: クライアントごとの Send バッファの使用状況（使用率の高い順）
+//   - heartbeats:   常駐ゴルーチン（Hub.Run、ウォッチドッグ、クリーンアップ）の最後の Beat
+//   - recovered_panics: 起動してから回復した panic の回数（0 でなければログにスタックトレースがある）
+//
+// 止まっている常駐ゴルーチンが1つでもあれば、status を "degraded" にして 503 を返します。
+//
+// 【保護】
+// クライアントのユーザーIDなどを含むため、GATEWAY_DEBUG_HEALTH_ENABLED で有効にした時だけ
+// 公開し、Authorization: Bearer <GATEWAY_DEBUG_TOKEN> を付けたリクエストにだけ応答します。
+// =============================================================================
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/heartbeat"
+	"github.com/robot-ai-webapp/gateway/internal/recovery"
+)
+
+// DebugHealth - /debug/health のハンドラー
+type DebugHealth struct {
+	hub      *Hub
+	registry *adapter.Registry
+	beats    *heartbeat.Registry
+	token    string
+}
+
+// adapterHealth: 1つのアダプターの状態
+type adapterHealth struct {
+	RobotID   string `json:"robot_id"`
+	Adapter   string `json:"adapter"`
+	Connected bool   `json:"connected"`
+}
+
+// NewDebugHealth - DebugHealth のコンストラクタ
+//
+// token は Authorization ヘッダーで求める管理者用のトークンです（空なら常に拒否）。
+func NewDebugHealth(hub *Hub, registry *adapter.Registry, beats *heartbeat.Registry, token string) *DebugHealth {
+	return &DebugHealth{hub: hub, registry: registry, beats: beats, token: token}
+}
+
+// =============================================================================
+// Handler - 診断情報を JSON で返す HTTP ハンドラー
+// =============================================================================
+func (d *DebugHealth) Handler(w http.ResponseWriter, r *http.Request) {
+	if !d.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var adapters []adapterHealth
+	connected := 0
+	for robotID, adp := range d.registry.GetAllActive() {
+		a := adapterHealth{RobotID: robotID, Adapter: adp.Name(), Connected: adp.IsConnected()}
+		if a.Connected {
+			connected++
+		}
+		adapters = append(adapters, a)
+	}
+	sort.Slice(adapters, func(i, j int) bool { return adapters[i].RobotID < adapters[j].RobotID })
+
+	status, code := "ok", http.StatusOK
+	beats := d.beats.Snapshot()
+	for _, b := range beats {
+		if !b.Alive {
+			status, code = "degraded", http.StatusServiceUnavailable
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"status":             status,
+		"goroutines":         runtime.NumGoroutine(),
+		"clients":            d.hub.ClientCount(),
+		"connected_adapters": connected,
+		"adapters":           adapters,
+		"send_buffers":       d.hub.SendBuffers(),
+		"heartbeats":         beats,
+		"recovered_panics":   recovery.Count(),
+	})
+}
+
+// authorized: Authorization: Bearer <token> が一致するか（比較は一定時間で行う）
+func (d *DebugHealth) authorized(r *http.Request) bool {
+	if d.token == "" {
+		return false
+	}
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(got), []byte(d.token)) == 1
+}
@@ -0,0 +1,35 @@
+// =============================================================================
+// ファイル: describe.go
+// 概要: メッセージタイプの一覧を返す（describe メッセージと GET /schema）
+//
+// 中身は protocol.Describe() で、受信時の Payload の検証に使う定義と同じものです。
+// クライアントを作る前に一覧を見られるよう、どちらも認証は不要です。
+// =============================================================================
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+)
+
+// handleDescribe - describe メッセージに一覧を返す（認証は不要）
+func (h *Handler) handleDescribe(client *Client, msg *protocol.Message) {
+	desc := protocol.Describe()
+	resp := protocol.NewMessage(protocol.MsgTypeDescribe, "")
+	resp.Payload = map[string]any{
+		"protocol_version":     desc.ProtocolVersion,
+		"min_protocol_version": desc.MinProtocolVersion,
+		"messages":             desc.Messages,
+		"error_codes":          desc.ErrorCodes,
+	}
+	h.sendToClient(client, resp)
+}
+
+// SchemaHandler - GET /schema（describe と同じ一覧を JSON で返す。認証は不要）
+func (h *Handler) SchemaHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(protocol.Describe())
+}
@@ -0,0 +1,97 @@
+// =============================================================================
+// ファイル: diagnostics.go
+// 概要: get_diagnostics メッセージ（ロボットの診断情報の問い合わせ）の処理
+//
+// 保守用の画面が、モーター温度やエラーフラグ、ファームウェアのバージョンを
+// 表示するためのものです。ロボットへの問い合わせは時間がかかる（あるいは固まる）
+// ことがあるため、タイムアウトを設けてハンドラーが待たされ続けないようにします。
+// =============================================================================
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"go.uber.org/zap"
+)
+
+// diagnosticsTimeout: 診断情報の取得を待つ最大時間
+const diagnosticsTimeout = 2 * time.Second
+
+// diagnosticsResult: 診断情報を取得するゴルーチンの結果
+type diagnosticsResult struct {
+	diagnostics map[string]any
+	err         error
+}
+
+// =============================================================================
+// handleGetDiagnostics - ロボットの診断情報を返す
+// =============================================================================
+//
+// 【応答の形（Payload）】
+//
+//	対応している場合:   {"supported": true, "diagnostics": {"firmware_version": "...", ...}}
+//	対応していない場合: {"supported": false}
+//
+// 対応していない（ErrNotSupported）のは異常ではないため、エラーではなく通常の応答で返します。
+// 時間内に応答がなければ adapter_timeout のエラーを返します。
+//
+// 【なぜゴルーチンで呼ぶのか？】
+// アダプターが ctx のキャンセルを無視して固まった場合でも、ハンドラー
+// （＝そのクライアントの readPump）は diagnosticsTimeout で処理を打ち切れます。
+// 結果のチャネルはバッファ付きなので、遅れて返ってきたゴルーチンも終了できます。
+func (h *Handler) handleGetDiagnostics(client *Client, msg *protocol.Message) {
+	if !client.Authenticated {
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeNotAuthenticated, "Not authenticated")
+		return
+	}
+	if msg.RobotID == "" {
+		h.sendError(client, "", "robot_id is required")
+		return
+	}
+
+	adp, ok := h.registry.GetAdapter(msg.RobotID)
+	if !ok {
+		h.sendError(client, msg.RobotID, "Robot not found")
+		return
+	}
+	if !h.ensureConnected(client, msg.RobotID, adp) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), diagnosticsTimeout)
+	defer cancel()
+
+	resultCh := make(chan diagnosticsResult, 1)
+	go func() {
+		diagnostics, err := adp.GetDiagnostics(ctx)
+		resultCh <- diagnosticsResult{diagnostics: diagnostics, err: err}
+	}()
+
+	var result diagnosticsResult
+	select {
+	case result = <-resultCh:
+	case <-ctx.Done():
+		result.err = ctx.Err()
+	}
+
+	response := protocol.NewMessage(protocol.MsgTypeGetDiagnostics, msg.RobotID)
+	switch {
+	case errors.Is(result.err, adapter.ErrNotSupported):
+		response.Payload["supported"] = false
+	case errors.Is(result.err, context.DeadlineExceeded):
+		h.logger.Warn("Diagnostics request timed out", zap.String("robot_id", msg.RobotID))
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeAdapterTimeout, "Robot did not return diagnostics in time")
+		return
+	case result.err != nil:
+		h.sendError(client, msg.RobotID, "Failed to get diagnostics: "+result.err.Error())
+		return
+	default:
+		response.Payload["supported"] = true
+		response.Payload["diagnostics"] = result.diagnostics
+	}
+	h.sendToClient(client, response)
+}
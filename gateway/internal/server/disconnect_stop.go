@@ -0,0 +1,128 @@
+// =============================================================================
+// ファイル: disconnect_stop.go
+// 概要: 最後の操作者が切断した時に、ロボットを即座に停止させる
+//
+// 【なぜ必要？】
+// 操作者のブラウザが閉じられると、ロボットは最後に受け取った速度で動き続け、
+// ウォッチドッグのタイムアウト（数秒）が来るまで止まりません。
+// 一人で操作している一般的なケースでは、その切断を Hub が真っ先に知っているため、
+// 登録解除の時点で停止コマンド（速度 0）を送れば、制御されない時間をほぼなくせます。
+//
+// 【「操作していた」の判断】
+// 1. そのクライアントが、ロボットに最後に速度コマンドを送った
+// 2. 直前の速度が 0 ではない（既に止まっているロボットには送らない）
+// 3. 操作ロックの保持者の、ロボットを操作した他の接続が残っていない
+//
+// 3 は、同じユーザーが別のタブで操作を続けている場合や、
+// 別のユーザーが既にロックを取得して操作している場合に、勝手に止めないためです。
+// 「ユーザーの接続が残っているか」では判断しません。同じユーザーID の閲覧だけの接続
+// （例: 全員が同じトークンのユーザーID になる構成のビューア）が1つあるだけで止まらなくなるためです。
+// そのロボットに速度コマンドを送ったことがある接続（commanders）だけを数えます。
+// =============================================================================
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"go.uber.org/zap"
+)
+
+// =============================================================================
+// StopRobotsControlledBy - 切断したクライアントが操作していたロボットを停止する
+// =============================================================================
+//
+// Hub の SetUnregisterCallback にそのまま登録できる形です。
+// 停止はウォッチドッグのタイムアウトと同じくベストエフォートで、
+// 安全のための停止なので E-Stop や操作ロックのチェックは行いません。
+func (h *Handler) StopRobotsControlledBy(client *Client) {
+	// 最小間隔で保留中の、このクライアントの速度コマンドは送らない
+	h.coalescer.DropClient(client.ID)
+	for _, robotID := range h.controlledRobots(client.ID) {
+		if lock := h.opLock.GetLockInfo(robotID); lock != nil && h.commandedByUser(robotID, lock.UserID) {
+			continue
+		}
+
+		adp, ok := h.registry.GetAdapter(robotID)
+		if !ok {
+			continue
+		}
+		h.cancelRampDown(robotID)
+		err := adp.SendCommand(context.Background(), adapter.Command{
+			RobotID: robotID,
+			Type:    "velocity",
+			Payload: map[string]any{
+				"linear_x":  0.0,
+				"linear_y":  0.0,
+				"angular_z": 0.0,
+			},
+			Timestamp: time.Now().UnixMilli(),
+		})
+		if err != nil {
+			h.logger.Warn("Failed to stop robot after controller disconnected",
+				zap.String("robot_id", robotID),
+				zap.String("client_id", client.ID),
+				zap.Error(err),
+			)
+			continue
+		}
+		h.setLastVelocity(robotID, adapter.Velocity{})
+
+		h.logger.Info("Robot stopped after its controller disconnected",
+			zap.String("robot_id", robotID),
+			zap.String("client_id", client.ID),
+		)
+	}
+}
+
+// setController: ロボットに最後に速度コマンドを送ったクライアントを記録する
+// 速度コマンドを送ったことがある接続（commanders）にも加える。
+func (h *Handler) setController(robotID string, client *Client) {
+	h.lastVelMu.Lock()
+	defer h.lastVelMu.Unlock()
+	h.controllers[robotID] = client.ID
+	if h.commanders[robotID] == nil {
+		h.commanders[robotID] = make(map[string]string)
+	}
+	h.commanders[robotID][client.ID] = client.UserID
+}
+
+// commandedByUser: userID の接続のうち、robotID に速度コマンドを送ったことがあるものが残っているか
+func (h *Handler) commandedByUser(robotID, userID string) bool {
+	h.lastVelMu.Lock()
+	defer h.lastVelMu.Unlock()
+	for _, id := range h.commanders[robotID] {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// controlledRobots: clientID が最後に速度コマンドを送り、まだ動いているロボットの一覧
+// 返したロボットの記録は削除する（同じ切断で二度停止しない）。
+// 切断したクライアントは、すべてのロボットの commanders からも外す。
+func (h *Handler) controlledRobots(clientID string) []string {
+	h.lastVelMu.Lock()
+	defer h.lastVelMu.Unlock()
+
+	for robotID, clients := range h.commanders {
+		delete(clients, clientID)
+		if len(clients) == 0 {
+			delete(h.commanders, robotID)
+		}
+	}
+
+	var robots []string
+	for robotID, id := range h.controllers {
+		if id != clientID {
+			continue
+		}
+		delete(h.controllers, robotID)
+		if h.lastVel[robotID] != (adapter.Velocity{}) {
+			robots = append(robots, robotID)
+		}
+	}
+	return robots
+}
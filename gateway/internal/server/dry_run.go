@@ -0,0 +1,94 @@
+// =============================================================================
+// ファイル: dry_run.go
+// 概要: コマンドのプレビュー（dry_run）
+//
+// 【用途】
+// UI で「この速度は上限でどこまで削られるか」「今このロボットにドックを指示できるか」を
+// 実際に動かさずに確かめたい時に使います。コマンドの Payload に "dry_run": true を付けると、
+// 通常と同じ安全パイプライン（認証、E-Stop、操作ロック、速度制限、接続確認など）を通したうえで、
+// アダプターへ送る直前で止め、送るはずだった内容を ACK で返します。
+//
+//	→ {"type": "velocity_cmd", "robot_id": "robot-1",
+//	   "payload": {"linear_x": 3.0, "dry_run": true}}
+//	← {"type": "cmd_ack", "robot_id": "robot-1",
+//	   "payload": {"command": "velocity", "dry_run": true, "would_acquire_lock": true,
+//	               "result": {"linear_x": 1.0, "linear_y": 0, "angular_z": 0, "clamped": true}}}
+//
+// 受け付けられないコマンドは、通常と同じエラー（E-Stop、lock_required など）が返ります。
+//
+// 【副作用を起こさない】
+// ドライランでは、操作ロックの取得、アダプターへの送信、Redis への発行、ウォッチドッグ、
+// 最後の速度の記録、観察者への通知、重複排除の記録をすべて行いません。
+// 操作ロックは確認だけを行い、実行すれば取得されるかどうかを would_acquire_lock で返します。
+// would_acquire_lock は操作ロックを確認するコマンド（checkLock を通るもの）の ACK にだけ入ります。
+// nav_goal / nav_cancel は操作ロックを取らないため、入りません。
+// =============================================================================
+package server
+
+import (
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"go.uber.org/zap"
+)
+
+// dryRunKey: コマンドの Payload でドライランを指定するキー
+const dryRunKey = "dry_run"
+
+// isDryRun: Payload の "dry_run" フラグを読む（省略時・真偽値以外は false）
+func isDryRun(msg *protocol.Message) bool {
+	dryRun, _ := msg.Payload[dryRunKey].(bool)
+	return dryRun
+}
+
+// checkLock - 操作ロックの確認（ドライランなら取得しない）
+//
+// 通常のコマンドでは ensureLock と同じです。
+// ドライランでは、ロックを持っていなくても取得はせず、実行した場合に拒否されるか
+// （strict モード、または他のユーザーのロック）だけを確認します。
+// 操作を続けてよければ true を返します（false ならエラーは送信済み）。
+func (h *Handler) checkLock(client *Client, robotID string, dryRun bool) bool {
+	if !dryRun {
+		return h.ensureLock(client, robotID)
+	}
+	if h.opLock.CheckLock(robotID, client.UserID) {
+		return true
+	}
+
+	if h.lockMode == LockModeStrict {
+		h.sendErrorCode(client, robotID, protocol.ErrCodeLockRequired,
+			"Operation lock required: send op_lock before controlling the robot")
+		return false
+	}
+
+	// 他のユーザーのロックがあれば、実行しても Acquire が失敗する
+	if lock := h.opLock.GetLockInfo(robotID); lock != nil {
+		h.sendError(client, robotID, "Operation locked: locked by "+lock.UserID)
+		return false
+	}
+	return true
+}
+
+// sendDryRunAck - ドライランの結果を ACK で返す
+//
+// result には、実行していればアダプターに送られた内容（クランプ後の速度、変換後の目標など）を入れます。
+// usesLock は、そのコマンドが checkLock を通る（実行すれば操作ロックを取得しうる）かどうかです。
+// 重複排除には記録しません（同じ command_id で本番のコマンドを送れるように）。
+func (h *Handler) sendDryRunAck(client *Client, msg *protocol.Message, robotID, command string, usesLock bool, result map[string]any) {
+	ack := protocol.NewMessage(protocol.MsgTypeCommandAck, robotID)
+	ack.Payload["command"] = command
+	ack.Payload[dryRunKey] = true
+	ack.Payload["result"] = result
+	// auto モードで、まだロックを持っていなければ、実行した時にロックが取得される
+	if usesLock {
+		ack.Payload["would_acquire_lock"] = robotID != "" && !h.opLock.CheckLock(robotID, client.UserID)
+	}
+	if commandID, ok := msg.Payload["command_id"].(string); ok && commandID != "" {
+		ack.Payload["command_id"] = commandID
+	}
+
+	h.logger.Debug("Dry-run command evaluated",
+		zap.String("robot_id", robotID),
+		zap.String("command", command),
+		zap.String("user_id", client.UserID),
+	)
+	h.sendToClient(client, ack)
+}
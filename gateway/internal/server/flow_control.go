@@ -0,0 +1,169 @@
+// =============================================================================
+// ファイル: flow_control.go
+// 概要: クライアントの処理能力に合わせてセンサーの生成頻度を調整する（フロー制御）
+//
+// 【背景】
+// 全クライアントの処理が遅いと、Hub は Send バッファが溢れた分のデータを捨てます。
+// しかしアダプターの生成器は全速力で動き続けるため、
+// 「作っては捨てる」ために CPU を使い続けることになります。
+//
+// 【仕組み（背圧 / バックプレッシャー）】
+//
+//	Hub ──RobotPressure()──→ FlowController ──SetSampleRate()──→ Adapter
+//	 （混雑度を報告）          （倍率を決める）        （生成頻度を下げる）
+//
+// 一定間隔で混雑度を調べ、高ければ生成頻度の倍率を半分に、
+// 十分に下がれば倍に戻します。上げ下げの閾値を離しておく（ヒステリシス）ことで、
+// 閾値付近で頻度が細かく揺れ続けるのを防ぎます。
+//
+// 倍率を掛ける元（基準の頻度）は最初は標準の頻度ですが、管理者が set_sensor_rate で
+// 頻度を変えると SetBaseRate でその値に置き換わります。混雑中に 5Hz を指定すれば
+// 「5Hz × 倍率」になり、混雑が解消すると標準の頻度ではなく 5Hz に戻ります。
+//
+// 頻度を変えられないアダプター（adapter.SampleRateController 未実装）には何もしません。
+// =============================================================================
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"go.uber.org/zap"
+)
+
+// フロー制御のパラメータ
+const (
+	flowCheckInterval  = 1 * time.Second // 混雑度を調べる間隔
+	flowHighPressure   = 0.5             // これ以上で頻度を下げる（バッファの半分が埋まっている）
+	flowLowPressure    = 0.1             // これ以下で頻度を戻す
+	flowMinRateFactor  = 0.125           // 頻度の倍率の下限（標準の 1/8 まで）
+	flowRateFactorStep = 2.0             // 1回の調整で倍率を何倍（何分の1）にするか
+)
+
+// =============================================================================
+// FlowController - 1台のロボットの生成頻度を調整するコントローラー
+// =============================================================================
+type FlowController struct {
+	hub     *Hub
+	robotID string
+	rc      adapter.SampleRateController
+	logger  *zap.Logger
+
+	// base / factor は Adjust（Start のゴルーチン）と SetBaseRate（ハンドラー）の両方から触るため mu で守る
+	mu     sync.Mutex
+	base   map[string]float64 // トピック → 基準の頻度（Hz）。最初は標準の頻度
+	factor float64            // 現在の倍率（1.0 = 基準の頻度そのまま）
+}
+
+// NewFlowController - コンストラクタ
+// 基準の頻度は作成時に rc.NominalSampleRates()（標準の頻度）で初期化します。
+func NewFlowController(hub *Hub, robotID string, rc adapter.SampleRateController, logger *zap.Logger) *FlowController {
+	base := make(map[string]float64)
+	for topic, hz := range rc.NominalSampleRates() {
+		base[topic] = hz
+	}
+	return &FlowController{
+		hub:     hub,
+		robotID: robotID,
+		rc:      rc,
+		base:    base,
+		factor:  1.0,
+		logger:  logger,
+	}
+}
+
+// =============================================================================
+// Start - 定期的に混雑度を調べるゴルーチンを起動する
+// =============================================================================
+//
+// ctx がキャンセルされると停止します（TimeoutWatchdog.Start と同じ形）。
+// wg が nil でなければゴルーチンを登録し、終了時に Done() します。
+func (f *FlowController) Start(ctx context.Context, wg *sync.WaitGroup) {
+	if wg != nil {
+		wg.Add(1)
+	}
+	go func() {
+		if wg != nil {
+			defer wg.Done()
+		}
+		ticker := time.NewTicker(flowCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				f.Adjust()
+			}
+		}
+	}()
+}
+
+// =============================================================================
+// Adjust - 現在の混雑度から倍率を1段階調整し、調整後の倍率を返す
+// =============================================================================
+//
+// Start() のゴルーチンから呼ばれます。テストから直接呼ぶこともできます。
+// 倍率が変わった時は、すべてのトピックを「基準の頻度 × 倍率」に設定し直します。
+func (f *FlowController) Adjust() float64 {
+	pressure := f.hub.RobotPressure(f.robotID)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	next := f.factor
+	switch {
+	case pressure >= flowHighPressure && f.factor > flowMinRateFactor:
+		next = max(f.factor/flowRateFactorStep, flowMinRateFactor)
+	case pressure <= flowLowPressure && f.factor < 1.0:
+		next = min(f.factor*flowRateFactorStep, 1.0)
+	}
+	if next == f.factor {
+		return f.factor
+	}
+
+	for topic, hz := range f.base {
+		if err := f.rc.SetSampleRate(topic, hz*next); err != nil {
+			f.logger.Warn("Failed to change sample rate",
+				zap.String("robot_id", f.robotID),
+				zap.String("topic", topic),
+				zap.Error(err),
+			)
+		}
+	}
+
+	f.logger.Info("Sensor sample rate adjusted for client pressure",
+		zap.String("robot_id", f.robotID),
+		zap.Float64("pressure", pressure),
+		zap.Float64("rate_factor", next),
+	)
+	f.factor = next
+	return f.factor
+}
+
+// =============================================================================
+// SetBaseRate - トピックの基準の頻度を変更し、現在の倍率を掛けて適用する
+// =============================================================================
+//
+// set_sensor_rate から呼ばれます。アダプターが拒否した場合は基準の頻度を変えずにエラーを返します。
+// 戻り値は実際に設定した頻度（hz × 現在の倍率）です。
+func (f *FlowController) SetBaseRate(topic string, hz float64) (float64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	effective := hz * f.factor
+	if err := f.rc.SetSampleRate(topic, effective); err != nil {
+		return 0, err
+	}
+	f.base[topic] = hz
+	return effective, nil
+}
+
+// RateFactor - 現在の倍率を返す
+func (f *FlowController) RateFactor() float64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.factor
+}
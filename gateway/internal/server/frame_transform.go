@@ -0,0 +1,102 @@
+// =============================================================================
+// ファイル: frame_transform.go
+// 概要: ナビゲーション目標の座標系変換（表示用の座標系 → ロボットの座標系）
+//
+// 【背景】
+// フロントエンドは画面表示用の座標系で目標地点を指定することが多く、
+// それぞれのクライアントが座標変換を実装すると、計算式や原点の定義がずれていきます。
+// 変換をゲートウェイに集約し、設定（GATEWAY_NAV_FRAME_TRANSFORMS）を唯一の正とします。
+// =============================================================================
+package server
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/robot-ai-webapp/gateway/internal/convert"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+)
+
+// =============================================================================
+// FrameTransform - 2次元のアフィン変換（拡大縮小 → 回転 → 平行移動）
+// =============================================================================
+type FrameTransform struct {
+	TranslationX float64 // X方向の平行移動（m）
+	TranslationY float64 // Y方向の平行移動（m）
+	Rotation     float64 // 回転（rad、反時計回りが正）
+	Scale        float64 // 拡大縮小の倍率（0 は 1 とみなす）
+}
+
+// Apply - 点 (x, y) と向き yaw を変換先の座標系に変換する
+// 向きは回転の分だけずれ、拡大縮小・平行移動の影響は受けません。
+func (t FrameTransform) Apply(x, y, yaw float64) (float64, float64, float64) {
+	scale := t.Scale
+	if scale == 0 {
+		scale = 1
+	}
+	sin, cos := math.Sincos(t.Rotation)
+	return scale*(cos*x-sin*y) + t.TranslationX,
+		scale*(sin*x+cos*y) + t.TranslationY,
+		yaw + t.Rotation
+}
+
+// =============================================================================
+// SetNavFrameTransforms - ナビゲーション目標の座標変換を設定する
+// =============================================================================
+//
+// targetFrame はロボットの座標系、transforms は「変換元の frame_id → 変換」です。
+// 既存の設定はすべて置き換えられます。
+// targetFrame が空なら座標変換は行わず、frame_id に関係なく目標をそのまま扱います。
+func (h *Handler) SetNavFrameTransforms(targetFrame string, transforms map[string]FrameTransform) {
+	copied := make(map[string]FrameTransform, len(transforms))
+	for frame, t := range transforms {
+		copied[frame] = t
+	}
+
+	h.navMu.Lock()
+	h.navTargetFrame = targetFrame
+	h.navTransforms = copied
+	h.navMu.Unlock()
+}
+
+// transformNavGoal - 目標地点をロボットの座標系に変換する（msg.Payload を書き換える）
+//
+// frame_id が空、またはロボットの座標系と同じならそのままです。
+// 変換が設定されていない frame_id なら unknown_frame のエラーを返し、false を返します。
+// 向きは "oz"/"ow"（Z軸回りのクォータニオン）で表され、両方ある場合だけ回転させます。
+func (h *Handler) transformNavGoal(client *Client, msg *protocol.Message) bool {
+	frame, _ := msg.Payload["frame_id"].(string)
+
+	h.navMu.RLock()
+	target := h.navTargetFrame
+	t, ok := h.navTransforms[frame]
+	h.navMu.RUnlock()
+
+	if target == "" || frame == "" || frame == target {
+		return true
+	}
+	if !ok {
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeUnknownFrame,
+			fmt.Sprintf("No transform from frame %q to %q", frame, target))
+		return false
+	}
+
+	// クォータニオン (z, w) → yaw: yaw = 2 * atan2(z, w)
+	oz, hasOZ := msg.Payload["oz"]
+	ow, hasOW := msg.Payload["ow"]
+	z, _ := convert.ToFloat64(oz)
+	w, _ := convert.ToFloat64(ow)
+	yaw := 2 * math.Atan2(z, w)
+
+	x, _ := convert.ToFloat64(msg.Payload["x"])
+	y, _ := convert.ToFloat64(msg.Payload["y"])
+	x, y, yaw = t.Apply(x, y, yaw)
+	msg.Payload["x"] = x
+	msg.Payload["y"] = y
+	if hasOZ && hasOW {
+		msg.Payload["oz"] = math.Sin(yaw / 2)
+		msg.Payload["ow"] = math.Cos(yaw / 2)
+	}
+	msg.Payload["frame_id"] = target
+	return true
+}
@@ -0,0 +1,148 @@
+// =============================================================================
+// ファイル: group_command.go
+// 概要: グループ内の全ロボットへの一括操作（group_command）
+//
+// 【使い方】
+// GATEWAY_ROBOT_GROUPS でロボットにグループIDを付けておき、グループIDと操作を送ります。
+//
+//	→ {"type": "group_command", "payload": {"group": "site-1/zone-a", "action": "estop", "reason": "spill"}}
+//	← {"type": "cmd_ack", "payload": {"command": "group_command", "group": "site-1/zone-a", "action": "estop",
+//	   "results": [{"robot_id": "robot-1", "ok": true}, {"robot_id": "robot-2", "ok": false, "error": "..."}]}}
+//
+// 上位のグループ（例: "site-1"）を指定すると、下位のグループのロボットもすべて対象になります。
+//
+// 【操作と対象】
+//
+//	estop: グループ内の全ロボットを緊急停止する（単体の E-Stop と同じく、誰でも発動できる）
+//	stop:  速度 0 とナビゲーションの中止を送る（stop_all と同じソフトストップ）。
+//	       他のユーザーが操作ロックを持つロボットは止めず、結果にその旨を入れる
+//
+// どちらも安全のための操作なので、無効にしたロボット（set_robot_enabled）も対象です。
+// 1台の失敗で残りのロボットを止め損ねないよう、失敗しても最後まで続け、結果をロボットごとに返します。
+// =============================================================================
+package server
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"go.uber.org/zap"
+)
+
+// グループに対して行える操作（Payload の "action"）
+const (
+	groupActionEStop = "estop"
+	groupActionStop  = "stop"
+)
+
+// errLockedByOtherUser: stop の対象外（他のユーザーが操作ロックを持っている）であることを示すエラー
+var errLockedByOtherUser = errors.New("operation locked by another user")
+
+// =============================================================================
+// handleGroupCommand - グループ内の全ロボットに同じ操作を行う
+// =============================================================================
+func (h *Handler) handleGroupCommand(client *Client, msg *protocol.Message) {
+	if !client.Authenticated {
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeNotAuthenticated, "Not authenticated")
+		return
+	}
+
+	group, _ := msg.Payload["group"].(string)
+	action, _ := msg.Payload["action"].(string)
+	reason, _ := msg.Payload["reason"].(string)
+	if group == "" {
+		h.sendErrorCode(client, "", protocol.ErrCodeInvalidMessage, "Missing group")
+		return
+	}
+	if action != groupActionEStop && action != groupActionStop {
+		h.sendErrorCode(client, "", protocol.ErrCodeInvalidMessage,
+			"Invalid group action "+action+": must be estop or stop")
+		return
+	}
+	if !h.registry.HasGroup(group) {
+		h.sendErrorCode(client, "", protocol.ErrCodeUnknownGroup, "Unknown group: "+group)
+		return
+	}
+
+	adapters := h.registry.GetAdaptersByGroup(group)
+	robotIDs := make([]string, 0, len(adapters))
+	for robotID := range adapters {
+		robotIDs = append(robotIDs, robotID)
+	}
+	sort.Strings(robotIDs)
+
+	results := make([]map[string]any, 0, len(robotIDs))
+	failed := 0
+	for _, robotID := range robotIDs {
+		var err error
+		if action == groupActionEStop {
+			err = h.groupEStop(client, robotID, group, reason)
+		} else {
+			err = h.groupStop(client, robotID, adapters[robotID])
+		}
+
+		result := map[string]any{"robot_id": robotID, "ok": err == nil}
+		if err != nil {
+			failed++
+			result["error"] = err.Error()
+			h.logger.Warn("Group command failed for robot",
+				zap.String("group", group),
+				zap.String("action", action),
+				zap.String("robot_id", robotID),
+				zap.Error(err),
+			)
+		}
+		results = append(results, result)
+	}
+
+	h.logger.Info("Group command executed",
+		zap.String("group", group),
+		zap.String("action", action),
+		zap.String("user_id", client.UserID),
+		zap.Int("robots", len(robotIDs)),
+		zap.Int("failed", failed),
+	)
+
+	ack := protocol.NewMessage(protocol.MsgTypeCommandAck, "")
+	ack.Payload["command"] = "group_command"
+	ack.Payload["group"] = group
+	ack.Payload["action"] = action
+	ack.Payload["results"] = results
+	h.sendToClient(client, ack)
+}
+
+// groupEStop: 1台を緊急停止し、単体の E-Stop と同じく安全アラートを配信する
+func (h *Handler) groupEStop(client *Client, robotID, group, reason string) error {
+	// 最小間隔で保留中の速度コマンドが、停止の後に送られないようにする
+	h.coalescer.Drop(robotID)
+	h.cancelRampDown(robotID)
+	if err := h.estop.Activate(context.Background(), robotID, client.UserID, reason); err != nil {
+		return err
+	}
+	h.ResetVelocityBaseline(robotID)
+
+	alert := protocol.NewMessage(protocol.MsgTypeSafetyAlert, robotID)
+	alert.Payload["type"] = "estop_activated"
+	alert.Payload["reason"] = reason
+	alert.Payload["user_id"] = client.UserID
+	alert.Payload["group"] = group
+	h.broadcastAlert(alert)
+	return nil
+}
+
+// groupStop: 1台をソフトストップする（他のユーザーが操作ロックを持っていれば止めない）
+func (h *Handler) groupStop(client *Client, robotID string, adp adapter.RobotAdapter) error {
+	if lock := h.opLock.GetLockInfo(robotID); lock != nil && lock.UserID != client.UserID {
+		return errLockedByOtherUser
+	}
+	h.coalescer.Drop(robotID)
+	h.cancelRampDown(robotID)
+	if err := stopRobot(adp, robotID); err != nil {
+		return err
+	}
+	h.setLastVelocity(robotID, adapter.Velocity{})
+	return nil
+}
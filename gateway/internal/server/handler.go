@@ -12,11 +12,18 @@
 // 対応するメッセージ:
 //   - auth:         認証（ログイン）
 //   - velocity:     速度コマンド（ロボットの移動制御）
+//   - velocity_preset: 名前付き速度プリセット（速度コマンドに展開）
 //   - estop:        緊急停止
 //   - nav_goal:     ナビゲーション目標地点の設定
 //   - nav_cancel:   ナビゲーションのキャンセル
 //   - op_lock:      操作ロックの取得
 //   - op_unlock:    操作ロックの解放
+//   - dock:         充電ドックへのドッキング
+//   - undock:       充電ドックからの離脱
+//   - reset_error:  故障（ERROR状態）のリセット
+//   - reset_pose:   シミュレーターの姿勢リセット（管理者向け）
+//   - health_status: ゲートウェイと購読中ロボットのヘルス状態の問い合わせ
+//   - odometry_path: Redis に記録済みのオドメトリから通った経路を問い合わせ
 //   - ping:         接続確認
 //
 // 【安全パイプライン - 速度コマンドの処理フロー】
@@ -38,17 +45,41 @@ import (
 	// context.Background() でルートcontextを作成します。
 	"context"
 
+	// "errors": errors.Is() でアダプターが返したエラーの種類を判定するために使用。
+	"errors"
+
+	// "fmt": ハンドラー登録時や、不正なフィールドのエラーメッセージの作成に使用。
+	"fmt"
+
+	// "math": reset_pose の座標が有限の数値か（NaN / Inf でないか）の確認に使用。
+	"math"
+
+	// "sync": 追加ハンドラーのマップを複数のゴルーチンから安全に読み書きするために使用。
+	"sync"
+
 	// "time": タイムスタンプの取得やRFC3339形式への変換に使用。
 	"time"
 
+	// metrics: メッセージタイプ別の処理時間・エラー数の集計。
+	"github.com/robot-ai-webapp/gateway/internal/metrics"
+
+	// recorder: 接続のメッセージをファイルに記録するセッションレコーダー（session_record.go）。
+	"github.com/robot-ai-webapp/gateway/internal/recorder"
+
 	// adapter: ロボットアダプターのインターフェースと型定義。
 	// Command（コマンド）、SensorData（センサーデータ）の構造体を使います。
 	"github.com/robot-ai-webapp/gateway/internal/adapter"
 
+	// convert: Payload の値（any 型）を数値に変換するヘルパー。
+	"github.com/robot-ai-webapp/gateway/internal/convert"
+
 	// protocol: メッセージプロトコルの定義。
 	// メッセージタイプの定数（MsgTypeAuth等）とメッセージ構造体を提供します。
 	"github.com/robot-ai-webapp/gateway/internal/protocol"
 
+	// recovery: ハンドラーで起きた panic を回復し、その接続だけを閉じるために使います。
+	"github.com/robot-ai-webapp/gateway/internal/recovery"
+
 	// safety: 安全機能パッケージ。
 	// E-Stop（緊急停止）、速度制限、タイムアウトウォッチドッグ、操作ロックを提供します。
 	"github.com/robot-ai-webapp/gateway/internal/safety"
@@ -100,9 +131,15 @@ type RedisPublisher interface {
 // velLimit:  速度制限の管理
 // watchdog:  タイムアウトウォッチドッグ（コマンドが来なくなったら停止）
 // opLock:    操作ロック（複数ユーザーの排他制御）
+// dedup:     コマンドの重複排除（command_id 付きの再送を二重実行しない）
 // publisher: Redisへのデータ配信
 // codec:     メッセージのエンコード/デコード
 // logger:    ログ出力
+// startedAt: ハンドラーの作成時刻（ヘルス状態の稼働時間の起点）
+// custom:    RegisterHandler() で登録された追加のメッセージハンドラー
+// presets:   SetVelocityPresets() で設定された名前付き速度プリセット
+// metrics:   SetMessageMetrics() で設定された処理時間メトリクス（nil なら記録しない）
+// navTransforms: SetNavFrameTransforms() で設定されたナビゲーション目標の座標変換
 
 // Handler processes incoming WebSocket messages
 type Handler struct {
@@ -112,9 +149,108 @@ type Handler struct {
 	velLimit  *safety.VelocityLimiter
 	watchdog  *safety.TimeoutWatchdog
 	opLock    *safety.OperationLock
-	publisher RedisPublisher
-	codec     *protocol.Codec
+	dedup     *safety.CommandDeduplicator
+	codec     protocol.Codec
 	logger    *zap.Logger
+	startedAt time.Time
+
+	// publisher: Redis への配信先（nil なら配信しない）。
+	// 起動後に Redis が復旧すると SetPublisher で差し替わるため、publisherMu で守る。
+	publisherMu sync.RWMutex
+	publisher   RedisPublisher
+
+	customMu sync.RWMutex
+	custom   map[protocol.MessageType]MessageHandlerFunc
+
+	presetsMu sync.RWMutex
+	presets   map[string]adapter.Velocity
+
+	// lastVel: ロボットごとの直前に指令した速度（制限後）。velocity_delta の基準になる
+	// controllers: ロボットごとの最後に速度コマンドを送ったクライアントのID（lastVelMu で保護）
+	// commanders: ロボットごとの、速度コマンドを送ったことがある接続中のクライアント（ID → ユーザーID、lastVelMu で保護）
+	lastVelMu   sync.Mutex
+	lastVel     map[string]adapter.Velocity
+	controllers map[string]string
+	commanders  map[string]map[string]string
+
+	metrics *metrics.MessageMetrics
+
+	stallDetector *safety.SensorStallDetector
+
+	navMu          sync.RWMutex
+	navTargetFrame string
+	navTransforms  map[string]FrameTransform
+
+	// allowedCommands: ロボットごとに受け付けるコマンド種別（SetAllowedCommands で設定）
+	allowedMu       sync.RWMutex
+	allowedCommands map[string]map[string]bool
+
+	// sessions: 短い切断の間のデータを溜めて再送する（SetSessionBuffer で設定、nil なら無効）
+	sessions *SessionBuffer
+
+	// admins: 管理者専用の機能（log_stream、set_speed_limit）を使えるユーザーID（SetAdminUsers で設定）
+	admins map[string]bool
+
+	// observers: コマンドの観察（observe_commands）を使えるユーザーID（SetObserverUsers で設定、管理者は常に可）
+	observers map[string]bool
+
+	// autoSubscribe: auth で "auto_subscribe" が省略された時のモード（SetAutoSubscribe で設定）
+	autoSubscribe AutoSubscribeMode
+
+	// coalescer: 速度コマンドの最小間隔（SetVelocityMinInterval で設定、nil なら無効）
+	coalescer *velocityCoalescer
+
+	// lockMode: ロックを持たずに操作コマンドを送った時の扱い（SetLockMode で設定、ゼロ値は auto）
+	lockMode LockMode
+
+	// sensorCache: sensor_request に答えるための最新のセンサー値（SetSensorCache で設定、nil なら使わない）
+	sensorCache *SensorCache
+
+	// flowControllers: ロボットごとのフロー制御（SetFlowController で設定）。
+	// set_sensor_rate の頻度を、フロー制御の倍率を掛ける基準として渡すために使う
+	flowMu          sync.RWMutex
+	flowControllers map[string]*FlowController
+
+	// arrayLimit: 受信したメッセージの Payload の配列の長さの制限（SetArrayLimiter で設定、nil なら制限しない）
+	arrayLimit *safety.ArrayLimiter
+
+	// robotMetrics: 指令した速度のゲージ（SetRobotMetrics で設定、nil なら記録しない）
+	robotMetrics *metrics.RobotMetrics
+
+	// sessionRecorder / recordAll: セッションの記録（SetSessionRecorder で設定、nil なら記録しない）
+	sessionRecorder *recorder.Recorder
+	recordAll       bool
+
+	// baseCtx / cmdTimeout: アダプターへのコマンド送信の context の元とタイムアウト（SetCommandContext で設定）
+	baseCtx    context.Context
+	cmdTimeout time.Duration
+}
+
+// =============================================================================
+// MessageHandlerFunc - 追加のメッセージハンドラーの型
+// =============================================================================
+//
+// RegisterHandler() に渡す関数の形です。組み込みのハンドラー（handleXxx）と
+// 同じく、送信元クライアントと受信したメッセージを受け取ります。
+type MessageHandlerFunc func(client *Client, msg *protocol.Message)
+
+// safetyCriticalTypes: 上書きに明示的な許可が必要なメッセージタイプ
+//
+// 認証・緊急停止と、ロボットを動かす・止めるコマンドは、安全チェック（E-Stop、操作ロック、
+// 速度制限、ウォッチドッグ、能力チェック）を組み込みハンドラーが担っているため、
+// うっかり置き換えられると危険です。
+var safetyCriticalTypes = map[protocol.MessageType]bool{
+	protocol.MsgTypeAuth:            true,
+	protocol.MsgTypeEmergencyStop:   true,
+	protocol.MsgTypeVelocityCommand: true,
+	protocol.MsgTypeVelocityPreset:  true,
+	protocol.MsgTypeVelocityDelta:   true,
+	protocol.MsgTypeStopAll:         true,
+	protocol.MsgTypeGroupCommand:    true,
+	protocol.MsgTypeNavigationGoal:  true,
+	protocol.MsgTypeDock:            true,
+	protocol.MsgTypeUndock:          true,
+	protocol.MsgTypeResetPose:       true,
 }
 
 // =============================================================================
@@ -138,6 +274,7 @@ func NewHandler(
 	velLimit *safety.VelocityLimiter,
 	watchdog *safety.TimeoutWatchdog,
 	opLock *safety.OperationLock,
+	dedup *safety.CommandDeduplicator,
 	publisher RedisPublisher,
 	logger *zap.Logger,
 ) *Handler {
@@ -148,10 +285,145 @@ func NewHandler(
 		velLimit:  velLimit,
 		watchdog:  watchdog,
 		opLock:    opLock,
+		dedup:     dedup,
 		publisher: publisher,
 		codec:     protocol.NewCodec(),
 		logger:    logger,
+		startedAt: time.Now(),
+		custom:    make(map[protocol.MessageType]MessageHandlerFunc),
+		lastVel:   make(map[string]adapter.Velocity),
+
+		controllers: make(map[string]string),
+		commanders:  make(map[string]map[string]string),
+
+		autoSubscribe: AutoSubscribeSingle,
+	}
+}
+
+// =============================================================================
+// RegisterHandler - メッセージハンドラーを追加登録する
+// =============================================================================
+//
+// 【なぜ必要か？】
+// HandleMessage の switch 文は、新しいメッセージタイプを足すたびに
+// このファイルを編集する必要があります（拡張に対して閉じている）。
+// RegisterHandler で登録したハンドラーは switch より先に参照されるため、
+// 利用側のコードだけで実験的なメッセージタイプを追加できます。
+//
+// 【安全上の制限】
+// auth / estop / velocity_cmd は上書きできず、エラーを返します。
+// どうしても置き換える必要がある場合は ForceRegisterHandler を使います。
+func (h *Handler) RegisterHandler(msgType protocol.MessageType, fn MessageHandlerFunc) error {
+	if safetyCriticalTypes[msgType] {
+		return fmt.Errorf("message type %q is safety-critical; use ForceRegisterHandler to override", msgType)
+	}
+	return h.ForceRegisterHandler(msgType, fn)
+}
+
+// ForceRegisterHandler - 安全上重要なメッセージタイプも含めてハンドラーを登録する
+//
+// 組み込みの安全チェックを自前で再実装する場合にのみ使ってください。
+// 上書きしたことが後から分かるよう、警告ログを残します。
+func (h *Handler) ForceRegisterHandler(msgType protocol.MessageType, fn MessageHandlerFunc) error {
+	if fn == nil {
+		return fmt.Errorf("handler for message type %q must not be nil", msgType)
 	}
+
+	h.customMu.Lock()
+	h.custom[msgType] = fn
+	h.customMu.Unlock()
+
+	if safetyCriticalTypes[msgType] {
+		h.logger.Warn("Safety-critical message handler overridden", zap.String("type", string(msgType)))
+	} else {
+		h.logger.Info("Custom message handler registered", zap.String("type", string(msgType)))
+	}
+	return nil
+}
+
+// =============================================================================
+// SetVelocityPresets - 名前付き速度プリセットを設定する
+// =============================================================================
+//
+// 「ゆっくり前進」「その場で左旋回」のように繰り返し使う速度を、
+// デプロイごとにサーバー側で定義しておくための仕組みです。
+// クライアントは速度の値を持たずに名前だけを送ればよく、
+// 安全確認済みの動作をサーバーで一元管理できます。
+// 既存のプリセットはすべて置き換えられます。
+func (h *Handler) SetVelocityPresets(presets map[string]adapter.Velocity) {
+	copied := make(map[string]adapter.Velocity, len(presets))
+	for name, v := range presets {
+		copied[name] = v
+	}
+
+	h.presetsMu.Lock()
+	h.presets = copied
+	h.presetsMu.Unlock()
+}
+
+// =============================================================================
+// SetMessageMetrics - メッセージ処理のメトリクス記録を有効にする
+// =============================================================================
+//
+// 設定すると、HandleMessage がメッセージタイプごとに処理時間とエラー応答の有無を
+// 記録します。サーバー起動前（メッセージを受け付ける前）に一度だけ呼んでください。
+func (h *Handler) SetMessageMetrics(m *metrics.MessageMetrics) {
+	h.metrics = m
+}
+
+// SetRobotMetrics - ロボットに送った速度を /metrics のゲージに記録する
+// サーバー起動前（メッセージを受け付ける前）に一度だけ呼んでください。
+func (h *Handler) SetRobotMetrics(m *metrics.RobotMetrics) {
+	h.robotMetrics = m
+}
+
+// =============================================================================
+// SetArrayLimiter - 受信したメッセージの配列の長さの制限を有効にする
+// =============================================================================
+//
+// 設定すると、Payload に上限を超える配列を含むメッセージを invalid_message で拒否します
+// （E-Stop は配列を読まないため、止められなくならないよう対象外です）。
+// サーバー起動前（メッセージを受け付ける前）に一度だけ呼んでください。
+func (h *Handler) SetArrayLimiter(l *safety.ArrayLimiter) {
+	h.arrayLimit = l
+}
+
+// =============================================================================
+// SetPublisher - Redis への配信先を差し替える
+// =============================================================================
+//
+// 起動時に Redis へ接続できず nil で始めた場合に、復旧したパブリッシャーを設定します
+// （bridge.Reconnector）。他の Set* と違い、メッセージの処理中に呼んでも安全です。
+func (h *Handler) SetPublisher(p RedisPublisher) {
+	h.publisherMu.Lock()
+	h.publisher = p
+	h.publisherMu.Unlock()
+}
+
+// currentPublisher: 現在の Redis への配信先（なければ nil）
+func (h *Handler) currentPublisher() RedisPublisher {
+	h.publisherMu.RLock()
+	defer h.publisherMu.RUnlock()
+	return h.publisher
+}
+
+// =============================================================================
+// SetAdminUsers - 管理者のユーザーIDを設定する
+// =============================================================================
+//
+// 管理者だけが log_stream（ログの購読）と set_speed_limit（速度上限の変更）を使えます。
+// 設定しなければ（空なら）誰も使えません。
+// サーバー起動前（メッセージを受け付ける前）に一度だけ呼んでください。
+func (h *Handler) SetAdminUsers(users []string) {
+	h.admins = make(map[string]bool, len(users))
+	for _, u := range users {
+		h.admins[u] = true
+	}
+}
+
+// isAdmin - 認証済みの管理者かどうか
+func (h *Handler) isAdmin(client *Client) bool {
+	return client.Authenticated && h.admins[client.UserID]
 }
 
 // =============================================================================
@@ -173,11 +445,71 @@ func NewHandler(
 
 // HandleMessage routes messages to the appropriate handler
 func (h *Handler) HandleMessage(client *Client, msg *protocol.Message) {
+	// ハンドラーで panic が起きても、プロセスごと落とさずにこの接続だけを閉じる
+	// （型アサーションの書き忘れなど。readPump が panicked を見て切断する）
+	defer recovery.Recover(h.logger, "handle_message", func() { client.panicked.Store(true) },
+		zap.String("client_id", client.ID),
+		zap.String("type", string(msg.Type)),
+	)
+
+	// どのメッセージもクライアントが動いている証拠になる（アプリケーション層のハートビート）
+	client.lastAppActivity.Store(time.Now().UnixMilli())
+
+	// メトリクスが有効なら、振り分け全体の処理時間を計測する。
+	// エラー応答の有無は、処理前後のエラー送信数の差で判定する。
+	// タイプはクライアントが自由に送れる文字列なので、プロトコルに定義のないものは "unknown" にまとめる。
+	if h.metrics != nil {
+		start := time.Now()
+		errorsBefore := client.errorsSent.Load()
+		label := metrics.UnknownType
+		if _, ok := protocol.SchemaFor(msg.Type); ok {
+			label = string(msg.Type)
+		}
+		defer func() {
+			failed := client.errorsSent.Load() != errorsBefore
+			h.metrics.Observe(label, time.Since(start), failed)
+		}()
+	}
+
+	// 巨大な配列を含むメッセージは、どのハンドラーにも渡さずに拒否する
+	if msg.Type != protocol.MsgTypeEmergencyStop {
+		if err := h.arrayLimit.CheckPayload(msg.Payload); err != nil {
+			h.logger.Warn("Rejected message with an oversized array",
+				zap.String("client_id", client.ID),
+				zap.String("type", string(msg.Type)),
+				zap.Error(err),
+			)
+			h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeInvalidMessage, "Invalid message: "+err.Error())
+			return
+		}
+	}
+
+	// RegisterHandler で登録されたハンドラーを優先する
+	h.customMu.RLock()
+	fn, ok := h.custom[msg.Type]
+	h.customMu.RUnlock()
+	if ok {
+		fn(client, msg)
+		return
+	}
+
+	// Payload のフィールドの型を、describe で公開しているスキーマと同じ定義で検証する
+	if schema, ok := protocol.SchemaFor(msg.Type); ok && msg.Type != protocol.MsgTypeEmergencyStop {
+		if err := schema.ValidatePayload(msg.Payload); err != nil {
+			h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeInvalidMessage, "Invalid message: "+err.Error())
+			return
+		}
+	}
+
 	switch msg.Type {
 	case protocol.MsgTypeAuth:
 		h.handleAuth(client, msg)
 	case protocol.MsgTypeVelocityCommand:
 		h.handleVelocityCommand(client, msg)
+	case protocol.MsgTypeVelocityPreset:
+		h.handleVelocityPreset(client, msg)
+	case protocol.MsgTypeVelocityDelta:
+		h.handleVelocityDelta(client, msg)
 	case protocol.MsgTypeEmergencyStop:
 		h.handleEStop(client, msg)
 	case protocol.MsgTypeNavigationGoal:
@@ -188,8 +520,46 @@ func (h *Handler) HandleMessage(client *Client, msg *protocol.Message) {
 		h.handleOperationLock(client, msg)
 	case protocol.MsgTypeOperationUnlock:
 		h.handleOperationUnlock(client, msg)
+	case protocol.MsgTypeDock, protocol.MsgTypeUndock:
+		h.handleDock(client, msg)
+	case protocol.MsgTypeResetError:
+		h.handleResetError(client, msg)
+	case protocol.MsgTypeResetPose:
+		h.handleResetPose(client, msg)
+	case protocol.MsgTypeHealthStatus:
+		h.handleHealthStatus(client, msg)
+	case protocol.MsgTypeOdometryPath:
+		h.handleOdometryPath(client, msg)
+	case protocol.MsgTypeGetDiagnostics:
+		h.handleGetDiagnostics(client, msg)
+	case protocol.MsgTypeLinkStats:
+		h.handleLinkStats(client, msg)
+	case protocol.MsgTypeSubscribeAlerts:
+		h.handleSubscribeAlerts(client, msg)
+	case protocol.MsgTypeLogStream:
+		h.handleLogStream(client, msg)
+	case protocol.MsgTypeSetSpeedLimit:
+		h.handleSetSpeedLimit(client, msg)
+	case protocol.MsgTypeSensorRequest:
+		h.handleSensorRequest(client, msg)
+	case protocol.MsgTypeStopAll:
+		h.handleStopAll(client, msg)
+	case protocol.MsgTypeGroupCommand:
+		h.handleGroupCommand(client, msg)
 	case protocol.MsgTypePing:
 		h.sendPong(client, msg)
+	case protocol.MsgTypeHeartbeat:
+		// 受信時刻は先頭で記録済み。応答は返さない。
+	case protocol.MsgTypeObserveCommands:
+		h.handleObserveCommands(client, msg)
+	case protocol.MsgTypeSetSensorRate:
+		h.handleSetSensorRate(client, msg)
+	case protocol.MsgTypeSetRobotEnabled:
+		h.handleSetRobotEnabled(client, msg)
+	case protocol.MsgTypeRecordSession:
+		h.handleRecordSession(client, msg)
+	case protocol.MsgTypeDescribe:
+		h.handleDescribe(client, msg)
 	default:
 		h.logger.Warn("Unknown message type", zap.String("type", string(msg.Type)))
 	}
@@ -203,8 +573,8 @@ func (h *Handler) HandleMessage(client *Client, msg *protocol.Message) {
 // 1. PayloadからJWTトークンを取得
 // 2. トークンを検証（現在はプレースホルダー）
 // 3. クライアントの認証状態を更新
-// 4. 指定されたロボットへの購読を設定
-// 5. 接続状態レスポンスを返送
+// 4. auto_subscribe のモードに応じてロボットの購読を設定（auto_subscribe.go）
+// 5. 接続状態レスポンスを返送（"subscriptions" に購読中のロボット）
 //
 // 【型アサーション msg.Payload["token"].(string)】
 // msg.Payload は map[string]any 型なので、取得した値は any 型です。
@@ -223,7 +593,31 @@ func (h *Handler) handleAuth(client *Client, msg *protocol.Message) {
 	token, _ := msg.Payload["token"].(string)
 	if token == "" {
 		// トークンが空なら認証失敗
-		h.sendError(client, msg.RobotID, "Missing auth token")
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeNotAuthenticated, "Missing auth token")
+		return
+	}
+
+	// "auto_subscribe" が指定されていれば、サーバーのデフォルトより優先する
+	mode := h.autoSubscribe
+	if v, ok := msg.Payload["auto_subscribe"].(string); ok {
+		if mode, ok = ParseAutoSubscribeMode(v); !ok {
+			h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeInvalidMessage,
+				"Invalid auto_subscribe: "+v+" (expected none, single or all)")
+			return
+		}
+	}
+
+	// "latest_only_topics" のトピックは、溜まった分ではなく最新のサンプルだけを受け取る（latest_only.go）
+	latestOnly, ok := stringList(msg.Payload["latest_only_topics"])
+	if !ok {
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeInvalidMessage,
+			"Invalid latest_only_topics: expected an array of topic names")
+		return
+	}
+
+	// "protocol_version" でプロトコルのバージョンを取り決める（protocol_version.go）
+	version, versionWarning, ok := h.negotiateProtocolVersion(client, msg)
+	if !ok {
 		return
 	}
 
@@ -233,12 +627,29 @@ func (h *Handler) handleAuth(client *Client, msg *protocol.Message) {
 	// 現在はプレースホルダー（仮）実装です。
 	client.UserID = "user-from-token" // Placeholder
 	client.Authenticated = true
+	client.ProtocolVersion = version
+
+	// Auto-subscribe according to the mode
+	// モードに応じて、ロボットのデータ購読を開始
+	// "sensor_batch": true なら、これらの購読のセンサーデータを
+	// 一定時間分まとめた sensor_batch メッセージで受け取る（オプトイン）
+	batch, _ := msg.Payload["sensor_batch"].(bool)
+	for _, robotID := range h.autoSubscribeRobots(mode, msg.RobotID) {
+		h.hub.SubscribeClient(client, robotID)
+		if batch {
+			h.hub.SetSensorBatching(client, robotID, true)
+		}
+		if len(latestOnly) > 0 {
+			h.hub.SetLatestOnly(client, robotID, latestOnly)
+		}
+	}
 
-	// Auto-subscribe to default robot if specified
-	// ロボットIDが指定されていたら、そのロボットのデータ購読を開始
-	if msg.RobotID != "" {
-		h.hub.SubscribeClient(client, msg.RobotID)
+	// 短い切断からの再接続なら、切断前の購読を復元する（SetSessionBuffer が有効な場合）
+	if client.SessionID == "" {
+		client.SessionID = client.ID
 	}
+	sessionID, _ := msg.Payload["session_id"].(string)
+	replay, resumed := h.resumeSession(client, sessionID)
 
 	h.logger.Info("Client authenticated",
 		zap.String("client_id", client.ID),
@@ -251,7 +662,23 @@ func (h *Handler) handleAuth(client *Client, msg *protocol.Message) {
 	response := protocol.NewMessage(protocol.MsgTypeConnectionStatus, msg.RobotID)
 	response.Payload["authenticated"] = true
 	response.Payload["client_id"] = client.ID
+	response.Payload["subscriptions"] = h.hub.SubscribedRobots(client)
+	response.Payload["protocol_version"] = version
+	response.Payload["min_protocol_version"] = protocol.MinProtocolVersion
+	response.Payload["max_protocol_version"] = protocol.ProtocolVersion
+	if versionWarning != "" {
+		response.Payload["protocol_warning"] = versionWarning
+	}
+	if h.sessions != nil {
+		response.Payload["session_id"] = client.SessionID
+		response.Payload["resumed"] = resumed
+	}
 	h.sendToClient(client, response)
+
+	// 切断中に溜めたデータは、応答の後に届いた順で再送する
+	for _, data := range replay {
+		h.hub.SendToClient(client, data)
+	}
 }
 
 // =============================================================================
@@ -266,22 +693,41 @@ func (h *Handler) handleAuth(client *Client, msg *protocol.Message) {
 //
 //  1. 認証チェック     - ログイン済みか？
 //  2. ロボットID確認   - どのロボットへのコマンドか？
-//  3. E-Stopチェック   - 緊急停止中でないか？
-//  4. 操作ロック確認   - 他のユーザーが操作中でないか？
-//  5. 速度制限適用     - 安全な範囲内に速度を制限
-//  6. コマンド送信     - アダプター経由でロボットに送信
-//  7. ウォッチドッグ記録 - タイムアウト監視のために記録
-//  8. Redis配信       - 他のサービスにコマンドを通知
-//  9. ACK返送         - クライアントに確認応答を返す
+//  3. 速度値の検証     - 速度が数値として読めるか？
+//  4. E-Stopチェック   - 緊急停止中でないか？
+//  5. 操作ロック確認   - 他のユーザーが操作中でないか？
+//  6. 速度制限適用     - 安全な範囲内に速度を制限
+//  7. コマンド送信     - アダプター経由でロボットに送信
+//  8. ウォッチドッグ記録 - タイムアウト監視のために記録
+//  9. Redis配信       - 他のサービスにコマンドを通知
+//  10. ACK返送        - クライアントに確認応答を返す
 //
 // 各段階で問題が検出されると、エラーメッセージを返して処理を中断（early return）します。
 // これは「ガード節（guard clause）」パターンと呼ばれ、ネストを深くせずに
 // エラーチェックを行う手法です。
 func (h *Handler) handleVelocityCommand(client *Client, msg *protocol.Message) {
+	// "partial": true なら、省略した成分を直前の指令速度のまま保つ（velocity_partial.go）
+	partial, ok := partialVelocity(msg.Payload)
+	if !ok {
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeInvalidMessage, "Invalid velocity command: partial must be a boolean")
+		return
+	}
+	if partial {
+		h.handlePartialVelocity(client, msg)
+		return
+	}
+	h.runVelocityCommand(client, msg, false)
+}
+
+// runVelocityCommand - 速度コマンドの安全パイプライン本体
+//
+// preClamped は、呼び出し側（velocity_delta）が既に上限でクランプしたかどうかです。
+// true なら、パイプラインでクランプされなくても ACK の clamped を true にします。
+func (h *Handler) runVelocityCommand(client *Client, msg *protocol.Message, preClamped bool) {
 	// ===== 段階1: 認証チェック =====
 	// ログインしていないユーザーからのコマンドは拒否します。
 	if !client.Authenticated {
-		h.sendError(client, msg.RobotID, "Not authenticated")
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeNotAuthenticated, "Not authenticated")
 		return
 	}
 
@@ -292,7 +738,28 @@ func (h *Handler) handleVelocityCommand(client *Client, msg *protocol.Message) {
 		return
 	}
 
-	// ===== 段階3: E-Stop（緊急停止）チェック =====
+	// ===== 段階3: 速度値の抽出と検証 =====
+	// Payload から3つの速度成分を取得します（velocityInput はこのファイルの末尾で定義）。
+	// 速度値が壊れたコマンドは、ロックを取るなどの副作用の前に拒否する
+	input, err := velocityInput(msg.Payload)
+	if err != nil {
+		h.sendErrorCode(client, robotID, protocol.ErrCodeInvalidMessage, "Invalid velocity command: "+err.Error())
+		return
+	}
+
+	// 速度指令を受け付けないロボット（センサー専用など）には送らない
+	if !h.commandAllowed(client, robotID, "velocity") {
+		return
+	}
+
+	// 再送されたコマンド（同じ command_id）なら実行せず、最初の ACK を返す
+	commandID, release, replayed := h.replayDuplicate(client, robotID, "velocity", msg)
+	if replayed {
+		return
+	}
+	defer release()
+
+	// ===== 段階4: E-Stop（緊急停止）チェック =====
 	// Check E-Stop
 	// E-Stopが有効になっている場合、全てのコマンドを拒否します。
 	// 安全のため、E-Stopは最も優先度が高いチェックです。
@@ -301,7 +768,7 @@ func (h *Handler) handleVelocityCommand(client *Client, msg *protocol.Message) {
 		return
 	}
 
-	// ===== 段階4: 操作ロック確認 =====
+	// ===== 段階5: 操作ロック確認 =====
 	// 【操作ロックとは？】
 	// 複数のユーザーが同時に同じロボットを操作するのを防ぐ仕組みです。
 	// 例えば、ユーザーAがロボットを操作中にユーザーBが同じロボットを
@@ -309,24 +776,12 @@ func (h *Handler) handleVelocityCommand(client *Client, msg *protocol.Message) {
 	//
 	// CheckLock(): このユーザーがロックを持っているか確認
 	// Acquire(): ロックの取得を試みる（既に他のユーザーが持っていたらエラー）
+	// ロックを持っていない時に取得を試みるか、拒否するかは lockMode で決まります（lock_mode.go）。
 	// Check operation lock
-	if !h.opLock.CheckLock(robotID, client.UserID) {
-		// Try to acquire lock
-		// ロックを持っていない場合、取得を試みる
-		if _, err := h.opLock.Acquire(robotID, client.UserID); err != nil {
-			h.sendError(client, robotID, "Operation locked: "+err.Error())
-			return
-		}
-	}
-
-	// ===== 段階5: 速度値の抽出 =====
-	// Extract velocity values
-	// Payload から3つの速度成分を取得します。
-	// toFloat() は any型 → float64 への安全な変換関数（このファイルの末尾で定義）
-	input := safety.VelocityInput{
-		LinearX:  toFloat(msg.Payload["linear_x"]),  // 前進/後退速度
-		LinearY:  toFloat(msg.Payload["linear_y"]),  // 横方向速度
-		AngularZ: toFloat(msg.Payload["angular_z"]), // 回転速度
+	// ドライランでは確認だけを行い、ロックは取得しない（dry_run.go）
+	dryRun := isDryRun(msg)
+	if !h.checkLock(client, robotID, dryRun) {
+		return
 	}
 
 	// ===== 段階6: 速度制限の適用 =====
@@ -337,7 +792,18 @@ func (h *Handler) handleVelocityCommand(client *Client, msg *protocol.Message) {
 	//
 	// limited.Clamped が true なら、速度が制限されたことを示します。
 	// Apply velocity limiting
-	limited := h.velLimit.Limit(input)
+	// set_speed_limit でロボットの上限が下げられていれば、その上限で制限する
+	limited := h.velLimit.LimitFor(robotID, input)
+
+	// 拒否モード（GATEWAY_VELOCITY_CLAMP_MODE=reject）では、上限を超えたコマンドは
+	// 実行せずに velocity_out_of_range を返し、正しい値を送り直してもらう
+	if limited.Rejected {
+		ceiling, _ := h.velLimit.LimitsFor(robotID)
+		h.sendErrorCode(client, robotID, protocol.ErrCodeVelocityOutOfRange,
+			fmt.Sprintf("Velocity out of range: max linear %.2f m/s, max angular %.2f rad/s",
+				ceiling.MaxLinear, ceiling.MaxAngular))
+		return
+	}
 
 	// ===== 段階7: アダプターの取得とコマンド送信 =====
 	// 【レジストリパターン】
@@ -353,7 +819,52 @@ func (h *Handler) handleVelocityCommand(client *Client, msg *protocol.Message) {
 		h.sendError(client, robotID, "Robot not found")
 		return
 	}
+	// 切断中のアダプターに送っても届かないため、成功扱いにせず拒否する
+	if !h.ensureConnected(client, robotID, adp) {
+		return
+	}
+
+	// ドライランなら、送るはずだった速度を返してここで終わる
+	if dryRun {
+		h.sendDryRunAck(client, msg, robotID, "velocity", true, map[string]any{
+			"linear_x":  limited.LinearX,
+			"linear_y":  limited.LinearY,
+			"angular_z": limited.AngularZ,
+			"clamped":   limited.Clamped || preClamped,
+		})
+		return
+	}
+
+	// 前回の送信から最小間隔（SetVelocityMinInterval）が経っていなければ保留にする。
+	// 保留中に次のコマンドが来たら置き換わり、間隔が経った時に最新のものだけが送られる。
+	if h.coalescer.Defer(robotID, client.ID, func() { h.flushVelocity(client, robotID, adp, limited) }) {
+		ack := protocol.NewMessage(protocol.MsgTypeCommandAck, robotID)
+		ack.Payload["command"] = "velocity"
+		ack.Payload["clamped"] = limited.Clamped || preClamped
+		ack.Payload["coalesced"] = true
+		h.sendCommandAck(client, ack, commandID)
+		return
+	}
+
+	if err := h.sendVelocity(client, robotID, adp, limited); err != nil {
+		h.sendCommandError(client, robotID, err)
+		return
+	}
+
+	// ===== 段階10: ACK（確認応答）をクライアントに返送 =====
+	// コマンドが正常に処理されたことをクライアントに通知します。
+	// "clamped" フィールドで、速度が制限されたかどうかも伝えます。
+	// Send ack
+	ack := protocol.NewMessage(protocol.MsgTypeCommandAck, robotID)
+	ack.Payload["command"] = "velocity"
+	ack.Payload["clamped"] = limited.Clamped || preClamped
+	h.sendCommandAck(client, ack, commandID)
+}
 
+// sendVelocity - 制限後の速度をアダプターに送り、送った後の記録を行う
+//
+// runVelocityCommand と、最小間隔で保留されていたコマンドの送信（flushVelocity）から呼ばれます。
+func (h *Handler) sendVelocity(client *Client, robotID string, adp adapter.RobotAdapter, limited safety.LimitResult) error {
 	// コマンド構造体を作成
 	cmd := adapter.Command{
 		RobotID: robotID,
@@ -366,17 +877,24 @@ func (h *Handler) handleVelocityCommand(client *Client, msg *protocol.Message) {
 		Timestamp: time.Now().UnixMilli(), // ミリ秒単位のタイムスタンプ
 	}
 
-	// 【context.Background()】
-	// 最もベースとなる空のcontextを作成します。
-	// キャンセルやタイムアウトの設定がないシンプルなcontextです。
-	// 短時間で完了する処理にはこれで十分です。
-	ctx := context.Background()
+	// 【commandContext()】
+	// サーバーの寿命の context から派生し、タイムアウトを付けた context です。
+	// アダプターが固まっても、タイムアウトかシャットダウンで送信を打ち切れます
+	// （command_context.go 参照）。
+	ctx, cancel := h.commandContext()
+	defer cancel()
+
+	// ウォッチドッグの減速中なら先に打ち切る（減速の次の段階で、このコマンドを上書きされないように）
+	h.cancelRampDown(robotID)
 
 	// アダプターにコマンドを送信
-	if err := adp.SendCommand(ctx, cmd); err != nil {
-		h.sendError(client, robotID, "Command failed: "+err.Error())
-		return
+	if err := sendToAdapter(ctx, adp, cmd); err != nil {
+		return err
 	}
+	// 相対速度コマンド（velocity_delta）の基準として、送信した速度を記録する
+	h.setLastVelocity(robotID, adapter.Velocity{LinearX: limited.LinearX, LinearY: limited.LinearY, AngularZ: limited.AngularZ})
+	// 切断時の即時停止（StopRobotsControlledBy）のため、誰が操作したかを記録する
+	h.setController(robotID, client)
 
 	// ===== 段階8: ウォッチドッグにコマンドを記録 =====
 	// 【ウォッチドッグ（タイムアウト監視）とは？】
@@ -391,22 +909,74 @@ func (h *Handler) handleVelocityCommand(client *Client, msg *protocol.Message) {
 	// publisher が nil の場合（Redisが設定されていない場合）はスキップします。
 	//
 	// 【_ でエラーを無視】
-	// _ = h.publisher.PublishCommand(...) は「エラーがあっても無視する」という意味です。
+	// _ = publisher.PublishCommand(...) は「エラーがあっても無視する」という意味です。
 	// Redis配信は「ベストエフォート（最善努力）」で行い、失敗してもコマンド自体は
 	// 実行済みなので、ここではエラーを無視します。
 	// Publish to Redis
-	if h.publisher != nil {
-		_ = h.publisher.PublishCommand(ctx, robotID, cmd)
+	if publisher := h.currentPublisher(); publisher != nil {
+		_ = publisher.PublishCommand(ctx, robotID, cmd)
 	}
 
-	// ===== 段階10: ACK（確認応答）をクライアントに返送 =====
-	// コマンドが正常に処理されたことをクライアントに通知します。
-	// "clamped" フィールドで、速度が制限されたかどうかも伝えます。
-	// Send ack
-	ack := protocol.NewMessage(protocol.MsgTypeCommandAck, robotID)
-	ack.Payload["command"] = "velocity"
-	ack.Payload["clamped"] = limited.Clamped
-	h.sendToClient(client, ack)
+	// ===== 段階10: 観察者にコマンドの写しを配信 =====
+	// observe_commands で観察しているクライアントに、実際に送った速度を届けます。
+	h.notifyCommandObserved(client, robotID, cmd.Type, cmd.Payload)
+	return nil
+}
+
+// flushVelocity - 最小間隔で保留されていた速度コマンドを送る（タイマーから呼ばれる）
+//
+// 保留している間に E-Stop が発動していたら送りません。
+func (h *Handler) flushVelocity(client *Client, robotID string, adp adapter.RobotAdapter, limited safety.LimitResult) {
+	if h.estop.IsActive(robotID) {
+		h.logger.Debug("Dropped coalesced velocity command: E-Stop is active", zap.String("robot_id", robotID))
+		return
+	}
+	if err := h.sendVelocity(client, robotID, adp, limited); err != nil {
+		h.sendCommandError(client, robotID, err)
+	}
+}
+
+// =============================================================================
+// handleVelocityPreset - 名前付き速度プリセットの処理
+// =============================================================================
+//
+// Payload の "preset" で指定されたプリセットを速度コマンドに展開し、
+// handleVelocityCommand に渡します。そのため E-Stop、操作ロック、速度制限、
+// ウォッチドッグなどの安全チェックは、通常の速度コマンドとまったく同じです。
+// 未定義の名前には unknown_preset のエラーコードを返します。
+func (h *Handler) handleVelocityPreset(client *Client, msg *protocol.Message) {
+	if !client.Authenticated {
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeNotAuthenticated, "Not authenticated")
+		return
+	}
+
+	name, _ := msg.Payload["preset"].(string)
+	h.presetsMu.RLock()
+	v, ok := h.presets[name]
+	h.presetsMu.RUnlock()
+	if !ok {
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeUnknownPreset,
+			fmt.Sprintf("Unknown velocity preset: %q", name))
+		return
+	}
+
+	expanded := protocol.NewMessage(protocol.MsgTypeVelocityCommand, msg.RobotID)
+	expanded.Payload["linear_x"] = v.LinearX
+	expanded.Payload["linear_y"] = v.LinearY
+	expanded.Payload["angular_z"] = v.AngularZ
+	// 再送の重複排除が効くよう、command_id は引き継ぐ
+	if commandID, ok := msg.Payload["command_id"]; ok {
+		expanded.Payload["command_id"] = commandID
+	}
+	if isDryRun(msg) {
+		expanded.Payload[dryRunKey] = true
+	}
+
+	h.logger.Debug("Velocity preset expanded",
+		zap.String("robot_id", msg.RobotID),
+		zap.String("preset", name),
+	)
+	h.handleVelocityCommand(client, expanded)
 }
 
 // =============================================================================
@@ -426,11 +996,11 @@ func (h *Handler) handleVelocityCommand(client *Client, msg *protocol.Message) {
 // - false: E-Stopを解除（復帰）
 //
 // 【ブロードキャスト】
-// E-Stop発動/解除時は、全クライアントに安全アラートを配信します。
-// これにより、全てのユーザーがE-Stopの状態変化を把握できます。
+// E-Stop発動/解除時は、購読者とアラート購読者に安全アラートを配信します。
+// これにより、そのロボットに関わるユーザーがE-Stopの状態変化を把握できます。
 func (h *Handler) handleEStop(client *Client, msg *protocol.Message) {
 	if !client.Authenticated {
-		h.sendError(client, msg.RobotID, "Not authenticated")
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeNotAuthenticated, "Not authenticated")
 		return
 	}
 
@@ -443,6 +1013,10 @@ func (h *Handler) handleEStop(client *Client, msg *protocol.Message) {
 
 	if activate {
 		// 【E-Stopの有効化】
+		// 最小間隔で保留中の速度コマンドと、ウォッチドッグの減速途中の速度は、
+		// 停止の後に送られないよう先に止める（RobotID が空なら全ロボット）
+		h.coalescer.Drop(msg.RobotID)
+		h.cancelRampDown(msg.RobotID)
 		if msg.RobotID != "" {
 			// Single robot E-Stop
 			// 特定のロボットのみ緊急停止
@@ -450,14 +1024,36 @@ func (h *Handler) handleEStop(client *Client, msg *protocol.Message) {
 				h.sendError(client, msg.RobotID, "E-Stop failed: "+err.Error())
 				return
 			}
+
+			// 切断中のロボットには停止指令が届いていない可能性がある。
+			// E-Stop 状態は記録済み（再接続後もコマンドを拒否する）なので中断はせず、
+			// 発動者にはその旨をエラーコード付きで知らせる。
+			if adp, ok := h.registry.GetAdapter(msg.RobotID); ok {
+				h.ensureConnected(client, msg.RobotID, adp)
+			}
 		} else {
 			// All robots E-Stop
 			// 全てのロボットを緊急停止
-			h.estop.ActivateAll(ctx, client.UserID, reason)
+			result := h.estop.ActivateAll(ctx, client.UserID, reason)
+
+			// 停止を確認できなかったロボットがあれば、全員に知らせて
+			// 現場での物理的な対応（非常停止ボタンなど）を促す
+			if len(result.Failed) > 0 {
+				failedAlert := protocol.NewMessage(protocol.MsgTypeSafetyAlert, "")
+				failedAlert.Payload["type"] = "estop_failed"
+				failedAlert.Payload["failed"] = result.Failed
+				failedAlert.Payload["disconnected"] = result.Disconnected
+				failedAlert.Payload["user_id"] = client.UserID
+				h.broadcastAlert(failedAlert)
+			}
 		}
 
+		// ロボットは停止したので、相対速度コマンドの基準も 0 に戻す
+		// （解除後の velocity_delta が停止前の速度から再開しないように）
+		h.ResetVelocityBaseline(msg.RobotID)
+
 		// Broadcast safety alert
-		// 安全アラートを全クライアントにブロードキャスト
+		// 安全アラートを購読者とアラート購読者にブロードキャスト
 		alert := protocol.NewMessage(protocol.MsgTypeSafetyAlert, msg.RobotID)
 		alert.Payload["type"] = "estop_activated"
 		alert.Payload["reason"] = reason
@@ -492,7 +1088,39 @@ func (h *Handler) handleEStop(client *Client, msg *protocol.Message) {
 // 将来的にはROSのmove_baseやNav2への連携が実装される予定です。
 func (h *Handler) handleNavigationGoal(client *Client, msg *protocol.Message) {
 	if !client.Authenticated {
-		h.sendError(client, msg.RobotID, "Not authenticated")
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeNotAuthenticated, "Not authenticated")
+		return
+	}
+
+	if !h.commandAllowed(client, msg.RobotID, "nav_goal") {
+		return
+	}
+
+	commandID, release, replayed := h.replayDuplicate(client, msg.RobotID, "nav_goal", msg)
+	if replayed {
+		return
+	}
+	defer release()
+
+	// 登録済みのロボットが切断中なら、目標を受け付けない
+	if adp, ok := h.registry.GetAdapter(msg.RobotID); ok && !h.ensureConnected(client, msg.RobotID, adp) {
+		return
+	}
+
+	// 目標地点をロボットの座標系に変換する（変換できない座標系なら拒否）
+	if !h.transformNavGoal(client, msg) {
+		return
+	}
+
+	// ドライランなら、変換後の目標を返してここで終わる
+	if isDryRun(msg) {
+		goal := make(map[string]any, len(msg.Payload))
+		for key, value := range msg.Payload {
+			if key != dryRunKey && key != "command_id" {
+				goal[key] = value
+			}
+		}
+		h.sendDryRunAck(client, msg, msg.RobotID, "nav_goal", false, goal)
 		return
 	}
 
@@ -502,9 +1130,11 @@ func (h *Handler) handleNavigationGoal(client *Client, msg *protocol.Message) {
 		zap.Any("payload", msg.Payload),
 	)
 
+	h.notifyCommandObserved(client, msg.RobotID, "nav_goal", msg.Payload)
+
 	ack := protocol.NewMessage(protocol.MsgTypeCommandAck, msg.RobotID)
 	ack.Payload["command"] = "nav_goal"
-	h.sendToClient(client, ack)
+	h.sendCommandAck(client, ack, commandID)
 }
 
 // =============================================================================
@@ -515,7 +1145,16 @@ func (h *Handler) handleNavigationGoal(client *Client, msg *protocol.Message) {
 // ロボットはその場で停止します。
 func (h *Handler) handleNavigationCancel(client *Client, msg *protocol.Message) {
 	if !client.Authenticated {
-		h.sendError(client, msg.RobotID, "Not authenticated")
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeNotAuthenticated, "Not authenticated")
+		return
+	}
+
+	if !h.commandAllowed(client, msg.RobotID, "nav_cancel") {
+		return
+	}
+
+	if isDryRun(msg) {
+		h.sendDryRunAck(client, msg, msg.RobotID, "nav_cancel", false, map[string]any{})
 		return
 	}
 
@@ -523,6 +1162,8 @@ func (h *Handler) handleNavigationCancel(client *Client, msg *protocol.Message)
 		zap.String("robot_id", msg.RobotID),
 	)
 
+	h.notifyCommandObserved(client, msg.RobotID, "nav_cancel", msg.Payload)
+
 	ack := protocol.NewMessage(protocol.MsgTypeCommandAck, msg.RobotID)
 	ack.Payload["command"] = "nav_cancel"
 	h.sendToClient(client, ack)
@@ -547,7 +1188,7 @@ func (h *Handler) handleNavigationCancel(client *Client, msg *protocol.Message)
 // RFC3339は "2026-02-15T14:30:00Z" のような形式です。
 func (h *Handler) handleOperationLock(client *Client, msg *protocol.Message) {
 	if !client.Authenticated {
-		h.sendError(client, msg.RobotID, "Not authenticated")
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeNotAuthenticated, "Not authenticated")
 		return
 	}
 
@@ -568,6 +1209,33 @@ func (h *Handler) handleOperationLock(client *Client, msg *protocol.Message) {
 	h.sendToClient(client, response)
 }
 
+// =============================================================================
+// NotifyLockExpiring - 操作ロックの保持者に、期限切れが近いことを知らせる
+// =============================================================================
+//
+// OperationLock.SetExpiryWarning() のコールバックとして登録します。
+// 保持者の全接続に lock_status を送り、画面で延長（op_lock の再送）を促せるようにします。
+//
+// 【警告の形（Payload）】
+//
+//	{"locked": true, "user_id": "user-1", "expires_at": "2026-02-15T14:30:00Z",
+//	 "warning": "expiring", "seconds_remaining": 30}
+func (h *Handler) NotifyLockExpiring(lock safety.LockInfo, remaining time.Duration) {
+	warning := protocol.NewMessage(protocol.MsgTypeLockStatus, lock.RobotID)
+	warning.Payload["locked"] = true
+	warning.Payload["user_id"] = lock.UserID
+	warning.Payload["expires_at"] = lock.ExpiresAt.Format(time.RFC3339)
+	warning.Payload["warning"] = "expiring"
+	warning.Payload["seconds_remaining"] = int(remaining.Round(time.Second).Seconds())
+
+	data, err := h.codec.Encode(warning)
+	if err != nil {
+		h.logger.Error("Failed to encode lock warning", zap.Error(err))
+		return
+	}
+	h.hub.SendToUser(lock.UserID, data)
+}
+
 // =============================================================================
 // handleOperationUnlock - 操作ロックの解放処理
 // =============================================================================
@@ -577,7 +1245,7 @@ func (h *Handler) handleOperationLock(client *Client, msg *protocol.Message) {
 // 解放が成功すると、他のユーザーがそのロボットを操作できるようになります。
 func (h *Handler) handleOperationUnlock(client *Client, msg *protocol.Message) {
 	if !client.Authenticated {
-		h.sendError(client, msg.RobotID, "Not authenticated")
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeNotAuthenticated, "Not authenticated")
 		return
 	}
 
@@ -594,6 +1262,501 @@ func (h *Handler) handleOperationUnlock(client *Client, msg *protocol.Message) {
 	h.sendToClient(client, response)
 }
 
+// =============================================================================
+// handleDock - ドッキング／離脱コマンドの処理
+// =============================================================================
+//
+// 【ドッキングとは？】
+// ロボットが自分で充電ドック（充電ステーション）まで移動し、
+// 電極を接触させて充電を始める動作です。離脱（undock）はその逆です。
+//
+// 【能力（Capabilities）によるゲート】
+// すべてのロボットがドックを持っているわけではありません。
+// GetCapabilities().SupportsDocking が false のロボットには送信せず、
+// エラーを返します。UIはこの能力フラグを見てボタンの表示を切り替えます。
+//
+// 【安全チェック】
+// ドッキングはロボットが自律的に動くコマンドなので、速度コマンドと同様に
+// E-Stop と操作ロックを確認します。
+// ただしユーザーが継続的に指令を送るものではないため、
+// ウォッチドッグ（RecordCommand）には記録しません。
+func (h *Handler) handleDock(client *Client, msg *protocol.Message) {
+	if !client.Authenticated {
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeNotAuthenticated, "Not authenticated")
+		return
+	}
+
+	robotID := msg.RobotID
+	if robotID == "" {
+		h.sendError(client, "", "Missing robot_id")
+		return
+	}
+
+	// msg.Type は "dock" か "undock" のどちらか。
+	// そのままアダプターのコマンドタイプとして使います。
+	cmdType := string(msg.Type)
+	if !h.commandAllowed(client, robotID, cmdType) {
+		return
+	}
+
+	// ドッキングの二重実行は特に危険なので、再送なら最初の ACK を返すだけにする
+	commandID, release, replayed := h.replayDuplicate(client, robotID, cmdType, msg)
+	if replayed {
+		return
+	}
+	defer release()
+
+	if h.estop.IsActive(robotID) {
+		h.sendError(client, robotID, "E-Stop is active")
+		return
+	}
+
+	dryRun := isDryRun(msg)
+	if !h.checkLock(client, robotID, dryRun) {
+		return
+	}
+
+	adp, ok := h.registry.GetAdapter(robotID)
+	if !ok {
+		h.sendError(client, robotID, "Robot not found")
+		return
+	}
+	if !h.ensureConnected(client, robotID, adp) {
+		return
+	}
+
+	// 能力チェック: ドッキング非対応のロボットには送らない
+	if !adp.GetCapabilities().SupportsDocking {
+		h.sendError(client, robotID, "Robot does not support docking")
+		return
+	}
+
+	if dryRun {
+		h.sendDryRunAck(client, msg, robotID, cmdType, true, map[string]any{})
+		return
+	}
+
+	cmd := adapter.Command{
+		RobotID:   robotID,
+		Type:      cmdType,
+		Payload:   map[string]any{},
+		Timestamp: time.Now().UnixMilli(),
+	}
+
+	ctx, cancel := h.commandContext()
+	defer cancel()
+	if err := sendToAdapter(ctx, adp, cmd); err != nil {
+		h.sendCommandError(client, robotID, err)
+		return
+	}
+
+	if publisher := h.currentPublisher(); publisher != nil {
+		_ = publisher.PublishCommand(ctx, robotID, cmd)
+	}
+	h.notifyCommandObserved(client, robotID, cmd.Type, cmd.Payload)
+
+	h.logger.Info("Dock command sent",
+		zap.String("robot_id", robotID),
+		zap.String("command", cmdType),
+		zap.String("user_id", client.UserID),
+	)
+
+	ack := protocol.NewMessage(protocol.MsgTypeCommandAck, robotID)
+	ack.Payload["command"] = cmdType
+	h.sendCommandAck(client, ack, commandID)
+}
+
+// =============================================================================
+// handleResetError - 故障（ERROR状態）のリセット処理
+// =============================================================================
+//
+// 【なぜ必要か？】
+// ロボットが故障（例: バッテリー切れ）するとERROR状態になり、
+// 以前は再接続しない限り復帰できませんでした。
+// このメッセージでアダプターの ClearFault() を呼び、操作可能な状態に戻します。
+//
+// 【故障が継続中の場合】
+// アダプターは adapter.ErrFaultActive を返してリセットを拒否します。
+// 原因が残ったまま復帰させると危険なので、ここでもエラーとしてクライアントに返します。
+//
+// 【誰がリセットできるか】
+// 故障を解除するとロボットが再び動ける状態になるので、管理者か、
+// そのロボットの操作ロックを持つ操作者だけが実行できます（ロックの自動取得はしません）。
+// 他のコマンドと同じく、無効にしたロボット（set_robot_enabled）と
+// コマンドのホワイトリスト（GATEWAY_ROBOT_ALLOWED_COMMANDS）も確認します。
+//
+// 【状態変化の通知】
+// リセットに成功したら robot_status を購読者とアラート購読者に配信し、
+// 各画面のロボット状態を "idle" に更新させます。
+func (h *Handler) handleResetError(client *Client, msg *protocol.Message) {
+	if !client.Authenticated {
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeNotAuthenticated, "Not authenticated")
+		return
+	}
+
+	robotID := msg.RobotID
+	if robotID == "" {
+		h.sendError(client, "", "Missing robot_id")
+		return
+	}
+
+	if !h.commandAllowed(client, robotID, "reset_error") {
+		return
+	}
+
+	if !h.isAdmin(client) && !h.opLock.CheckLock(robotID, client.UserID) {
+		h.sendErrorCode(client, robotID, protocol.ErrCodeLockRequired,
+			"reset_error requires the operation lock for this robot (or admin rights)")
+		return
+	}
+
+	// E-Stop は故障とは別の安全機構なので、リセットでは解除しません
+	if h.estop.IsActive(robotID) {
+		h.sendError(client, robotID, "E-Stop is active")
+		return
+	}
+
+	adp, ok := h.registry.GetAdapter(robotID)
+	if !ok {
+		h.sendError(client, robotID, "Robot not found")
+		return
+	}
+	if !h.ensureConnected(client, robotID, adp) {
+		return
+	}
+
+	ctx, cancel := h.commandContext()
+	defer cancel()
+	if err := adp.ClearFault(ctx); err != nil {
+		if errors.Is(err, adapter.ErrFaultActive) {
+			h.sendError(client, robotID, "Reset refused: "+err.Error())
+		} else {
+			h.sendError(client, robotID, "Reset failed: "+err.Error())
+		}
+		return
+	}
+
+	h.logger.Info("Robot error state reset",
+		zap.String("robot_id", robotID),
+		zap.String("user_id", client.UserID),
+	)
+
+	status := protocol.NewMessage(protocol.MsgTypeRobotStatus, robotID)
+	status.Payload["state"] = "idle"
+	status.Payload["reset_by"] = client.UserID
+	h.broadcastAlert(status)
+
+	ack := protocol.NewMessage(protocol.MsgTypeCommandAck, robotID)
+	ack.Payload["command"] = "reset_error"
+	h.sendToClient(client, ack)
+}
+
+// =============================================================================
+// handleResetPose - 姿勢リセットの処理（管理者向け）
+// =============================================================================
+//
+// 【用途】
+// シミュレーター上のロボットを指定の位置・向き（省略時は原点）に戻します。
+// 同じ初期状態からテストを繰り返せるので、シナリオをスクリプト化しやすくなります。
+//
+// 【管理者向けコマンドについて】
+// ロボットを瞬間移動させるので、管理者（GATEWAY_ADMIN_USERS）だけが実行できます。
+// 管理者でも、他のユーザーが操作中のロボットは動かさないよう操作ロックを確認します。
+// 対応していないロボット（SupportsPoseReset が false）には送信しません。
+//
+// 【x / y / theta】
+// 省略したキーは 0（原点・東向き）として扱います。
+// 数値に変換できない値は、原点へ動かさずに invalid_message で拒否します。
+func (h *Handler) handleResetPose(client *Client, msg *protocol.Message) {
+	if !client.Authenticated {
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeNotAuthenticated, "Not authenticated")
+		return
+	}
+
+	robotID := msg.RobotID
+	if robotID == "" {
+		h.sendError(client, "", "Missing robot_id")
+		return
+	}
+
+	if !h.isAdmin(client) {
+		h.sendErrorCode(client, robotID, protocol.ErrCodeForbidden, "reset_pose is only available to admin users")
+		return
+	}
+
+	if !h.commandAllowed(client, robotID, "reset_pose") {
+		return
+	}
+
+	// 受け付けるキーだけをコピーして渡す（不要なキーをアダプターに流さない）
+	payload := make(map[string]any, 4)
+	for _, key := range []string{"x", "y", "theta"} {
+		v, err := optionalFloat(msg.Payload, key)
+		if err != nil {
+			h.sendErrorCode(client, robotID, protocol.ErrCodeInvalidMessage, "Invalid reset_pose: "+err.Error())
+			return
+		}
+		payload[key] = v
+	}
+	if reset, ok := msg.Payload["reset_battery"].(bool); ok {
+		payload["reset_battery"] = reset
+	}
+
+	dryRun := isDryRun(msg)
+	if !h.checkLock(client, robotID, dryRun) {
+		return
+	}
+
+	adp, ok := h.registry.GetAdapter(robotID)
+	if !ok {
+		h.sendError(client, robotID, "Robot not found")
+		return
+	}
+	if !h.ensureConnected(client, robotID, adp) {
+		return
+	}
+
+	if !adp.GetCapabilities().SupportsPoseReset {
+		h.sendError(client, robotID, "Robot does not support pose reset")
+		return
+	}
+
+	if dryRun {
+		h.sendDryRunAck(client, msg, robotID, "reset_pose", true, payload)
+		return
+	}
+
+	cmd := adapter.Command{
+		RobotID:   robotID,
+		Type:      "reset_pose",
+		Payload:   payload,
+		Timestamp: time.Now().UnixMilli(),
+	}
+
+	ctx, cancel := h.commandContext()
+	defer cancel()
+	if err := sendToAdapter(ctx, adp, cmd); err != nil {
+		h.sendCommandError(client, robotID, err)
+		return
+	}
+
+	if publisher := h.currentPublisher(); publisher != nil {
+		_ = publisher.PublishCommand(ctx, robotID, cmd)
+	}
+	h.notifyCommandObserved(client, robotID, cmd.Type, cmd.Payload)
+
+	h.logger.Info("Robot pose reset",
+		zap.String("robot_id", robotID),
+		zap.String("user_id", client.UserID),
+		zap.Any("pose", payload),
+	)
+
+	ack := protocol.NewMessage(protocol.MsgTypeCommandAck, robotID)
+	ack.Payload["command"] = "reset_pose"
+	h.sendToClient(client, ack)
+}
+
+// optionalFloat: payload[key] を有限の数値として取り出す（キーがなければ 0）
+func optionalFloat(payload map[string]any, key string) (float64, error) {
+	raw, ok := payload[key]
+	if !ok || raw == nil {
+		return 0, nil
+	}
+	v, ok := convert.ToFloat64(raw)
+	if !ok || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("%s must be a finite number, got %v", key, raw)
+	}
+	return v, nil
+}
+
+// =============================================================================
+// handleHealthStatus - ゲートウェイとロボットのヘルス状態をまとめて返す
+// =============================================================================
+//
+// 【なぜ1つのメッセージにまとめる？】
+// ステータスバーを表示するには、ゲートウェイの状態（/health）、ロボットの接続状態、
+// E-Stop、ロック保持者など、本来は何度も問い合わせが必要な情報が必要です。
+// それらを1回の往復で返し、フロントエンドが一貫したスナップショットを得られるようにします。
+//
+// 【応答の形（Payload）】
+//
+//	{
+//	  "gateway": {"uptime_sec": 3600, "clients": 3, "redis": "ok"},
+//	  "robots": {
+//	    "robot-1": {"registered": true, "connected": true, "last_sensor_timestamp": 1739600000000,
+//	                "estop_active": false, "lock_holder": "user-1", "stalled_topics": ["scan"]}
+//	  }
+//	}
+//
+// robots には、このクライアントが購読しているロボットだけを含めます。
+// last_sensor_timestamp はまだ受信していなければ null、lock_holder はロックがなければ空文字列です。
+// stalled_topics はセンサー停止検出が有効な場合のみ含めます（停止中のトピックがなければ空配列）。
+func (h *Handler) handleHealthStatus(client *Client, msg *protocol.Message) {
+	if !client.Authenticated {
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeNotAuthenticated, "Not authenticated")
+		return
+	}
+
+	robots := make(map[string]any)
+	for _, robotID := range h.hub.SubscribedRobots(client) {
+		status := map[string]any{
+			"registered":            false,
+			"connected":             false,
+			"last_sensor_timestamp": nil,
+			"estop_active":          h.estop.IsActive(robotID),
+			"lock_holder":           "",
+		}
+		if adp, ok := h.registry.GetAdapter(robotID); ok {
+			status["registered"] = true
+			status["connected"] = adp.IsConnected()
+		}
+		if ts, ok := h.hub.LastSensorTimestamp(robotID); ok {
+			status["last_sensor_timestamp"] = ts
+		}
+		if lock := h.opLock.GetLockInfo(robotID); lock != nil {
+			status["lock_holder"] = lock.UserID
+		}
+		if h.stallDetector != nil {
+			status["stalled_topics"] = h.stallDetector.Stalled(robotID)
+		}
+		robots[robotID] = status
+	}
+
+	response := protocol.NewMessage(protocol.MsgTypeHealthStatus, msg.RobotID)
+	response.Payload["gateway"] = map[string]any{
+		"uptime_sec": int64(time.Since(h.startedAt).Seconds()),
+		"clients":    h.hub.ClientCount(),
+		"redis":      h.redisStatus(),
+	}
+	response.Payload["robots"] = robots
+	h.sendToClient(client, response)
+}
+
+// redisPinger: 疎通確認ができる publisher（bridge.RedisPublisher が満たす）
+type redisPinger interface {
+	Ping(ctx context.Context) error
+}
+
+// redisStatus: Redis の状態を "ok" / "error" / "disabled" / "unknown" で返す
+//
+// Redis なしで起動した場合（publisher が nil）は "disabled" です。
+// 問い合わせでクライアントを長く待たせないよう、Ping は1秒で打ち切ります。
+func (h *Handler) redisStatus() string {
+	publisher := h.currentPublisher()
+	if publisher == nil {
+		return "disabled"
+	}
+	pinger, ok := publisher.(redisPinger)
+	if !ok {
+		return "unknown"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := pinger.Ping(ctx); err != nil {
+		h.logger.Warn("Redis health check failed", zap.Error(err))
+		return "error"
+	}
+	return "ok"
+}
+
+// =============================================================================
+// ensureConnected - アダプターが接続中かを確認する
+// =============================================================================
+//
+// 【なぜ必要か？】
+// ロボットが切断されていても、レジストリにはアダプターが残っています。
+// 確認せずにコマンドを送ると、届かないのに ACK を返してしまいます。
+//
+// 切断中なら、送信者に robot_disconnected のエラーを返し、
+// そのロボットの購読者全員に接続状態（conn_status）を配信して false を返します。
+// クライアントはこの通知を見てコマンドの送信を止められます。
+func (h *Handler) ensureConnected(client *Client, robotID string, adp adapter.RobotAdapter) bool {
+	if adp.IsConnected() {
+		return true
+	}
+
+	h.logger.Warn("Command rejected: robot disconnected",
+		zap.String("robot_id", robotID),
+		zap.String("client_id", client.ID),
+	)
+	h.sendErrorCode(client, robotID, protocol.ErrCodeRobotDisconnected, "Robot is disconnected")
+
+	status := protocol.NewMessage(protocol.MsgTypeConnectionStatus, robotID)
+	status.Payload["robot_connected"] = false
+	if data, err := h.codec.Encode(status); err == nil {
+		h.hub.BroadcastToRobot(robotID, data)
+	}
+	return false
+}
+
+// =============================================================================
+// replayDuplicate - 再送コマンドの検出と ACK の再送
+// =============================================================================
+//
+// 【べき等性（idempotency）とは？】
+// 同じ操作を何度行っても結果が1回行った時と同じになる性質です。
+// クライアントがペイロードに command_id を付けると、重複排除の対象種別では
+// 同じ command_id のコマンドが一定時間内に再送されても実行されなくなります。
+//
+// 記録は送信者（ユーザーID）ごとです。記録がなければその場で「実行中」として予約するため、
+// 同時に届いた同じコマンドが両方実行されることはありません。
+// 実行中の同じコマンドには command_in_progress のエラーを返します。
+//
+// 戻り値:
+//   - commandID: ペイロードの command_id（無ければ空文字列）。sendCommandAck に渡す。
+//   - release: 予約を取り消す関数。呼び出し側は defer で呼ぶ（ACK を記録した後なら何もしない）。
+//   - replayed: true なら再送として応答済みなので、呼び出し側は処理を終える。
+func (h *Handler) replayDuplicate(client *Client, robotID, command string, msg *protocol.Message) (string, func(), bool) {
+	commandID, _ := msg.Payload["command_id"].(string)
+	// ドライランは実行しないので、過去の ACK を返さずにそのまま評価する（予約もしない）
+	if commandID == "" || h.dedup == nil || !h.dedup.Enabled(command) || isDryRun(msg) {
+		return commandID, func() {}, false
+	}
+
+	payload, result := h.dedup.LookupOrReserve(client.UserID, robotID, command, commandID)
+	switch result {
+	case safety.DedupNew:
+		return commandID, func() { h.dedup.Release(client.UserID, robotID, command, commandID) }, false
+	case safety.DedupInProgress:
+		h.sendErrorCode(client, robotID, protocol.ErrCodeCommandInProgress,
+			"Command "+commandID+" is still being executed")
+		return commandID, func() {}, true
+	}
+
+	h.logger.Info("Duplicate command ignored",
+		zap.String("robot_id", robotID),
+		zap.String("command", command),
+		zap.String("command_id", commandID),
+	)
+
+	ack := protocol.NewMessage(protocol.MsgTypeCommandAck, robotID)
+	for k, v := range payload {
+		ack.Payload[k] = v
+	}
+	// 再送への応答であることをクライアントが区別できるようにする
+	ack.Payload["duplicate"] = true
+	h.sendToClient(client, ack)
+	return commandID, func() {}, true
+}
+
+// =============================================================================
+// sendCommandAck - コマンドの ACK を送り、重複排除用に記録するヘルパー
+// =============================================================================
+//
+// command_id が付いていれば ACK にも含めて返し、どのコマンドへの応答かを
+// クライアントが対応付けられるようにします。
+func (h *Handler) sendCommandAck(client *Client, ack *protocol.Message, commandID string) {
+	if commandID != "" {
+		ack.Payload["command_id"] = commandID
+		if h.dedup != nil {
+			command, _ := ack.Payload["command"].(string)
+			h.dedup.Remember(client.UserID, ack.RobotID, command, commandID, ack.Payload)
+		}
+	}
+	h.sendToClient(client, ack)
+}
+
 // =============================================================================
 // sendError - エラーメッセージの送信ヘルパー
 // =============================================================================
@@ -607,6 +1770,19 @@ func (h *Handler) handleOperationUnlock(client *Client, msg *protocol.Message) {
 func (h *Handler) sendError(client *Client, robotID, errMsg string) {
 	msg := protocol.NewMessage(protocol.MsgTypeError, robotID)
 	msg.Error = errMsg
+	client.errorsSent.Add(1)
+	h.sendToClient(client, msg)
+}
+
+// sendErrorCode - エラーコード（protocol.ErrCode*）付きのエラーを送信する
+// クライアントが文章ではなくコードで処理を分岐できるよう、Payload["code"] に入れます。
+// コードに復旧方法が決まっていれば、action と retry_after_ms も付けます（protocol/error_hints.go）。
+func (h *Handler) sendErrorCode(client *Client, robotID, code, errMsg string) {
+	msg := protocol.NewMessage(protocol.MsgTypeError, robotID)
+	msg.Error = errMsg
+	msg.Payload["code"] = code
+	msg.SetRecoveryHint(code)
+	client.errorsSent.Add(1)
 	h.sendToClient(client, msg)
 }
 
@@ -645,14 +1821,15 @@ func (h *Handler) sendToClient(client *Client, msg *protocol.Message) {
 }
 
 // =============================================================================
-// broadcastAlert - 全クライアントへのアラート配信
+// broadcastAlert - 安全アラートの配信
 // =============================================================================
 //
-// E-Stopの発動/解除など、全ユーザーに通知すべき安全アラートを
-// 接続中の全クライアントに配信します。
+// E-Stopの発動/解除など、関係するユーザーに通知すべき安全アラートを配信します。
+// 配信先は、アラート購読者（subscribe_alerts）と msg.RobotID のロボットの購読者です。
+// RobotID が空のアラート（全ロボットの E-Stop など）は全クライアントに届きます。
 //
 // 【ブロードキャストとは？】
-// 全ての接続先に同じメッセージを送信することです。
+// 複数の接続先に同じメッセージを送信することです。
 // テレビの放送（broadcast）と同じ概念です。
 func (h *Handler) broadcastAlert(msg *protocol.Message) {
 	data, err := h.codec.Encode(msg)
@@ -660,32 +1837,73 @@ func (h *Handler) broadcastAlert(msg *protocol.Message) {
 		h.logger.Error("Failed to encode alert", zap.Error(err))
 		return
 	}
-	h.hub.BroadcastToAll(data)
+	h.hub.BroadcastAlert(msg.RobotID, data)
 }
 
 // =============================================================================
-// toFloat - any型からfloat64への安全な型変換ヘルパー関数
+// handleSubscribeAlerts - 安全アラートだけの購読（alerts-only）を切り替える
 // =============================================================================
 //
-// 【型スイッチ（type switch）による安全な型変換】
-// JSONデコード時の数値型は通常float64ですが、他の数値型で来る可能性もあります。
-// この関数はどの型で来ても安全にfloat64に変換します。
+// 監視用のダッシュボードは、大量のセンサーデータは要らないが、
+// 全ロボットの E-Stop・センサー停止などのアラートは見逃したくありません。
+// このメッセージで購読すると、ロボットを購読しなくてもアラートだけが届きます。
 //
-// 【mock_adapter.go の toFloat64 と同じ？】
-// はい、ほぼ同じ関数ですが、パッケージが異なるため別途定義が必要です。
-// Go言語では、パッケージを超えた関数共有にはexport（大文字開始）が必要ですが、
-// このようなユーティリティ関数は各パッケージのローカルに持つことも一般的です。
-func toFloat(v any) float64 {
-	switch val := v.(type) {
-	case float64:
-		return val
-	case float32:
-		return float64(val)
-	case int:
-		return float64(val)
-	case int64:
-		return float64(val)
-	default:
-		return 0.0
+// Payload の "enabled" が false なら購読を解除します（省略時は true）。
+// 応答は cmd_ack（command: "subscribe_alerts", enabled: 現在の状態）です。
+func (h *Handler) handleSubscribeAlerts(client *Client, msg *protocol.Message) {
+	if !client.Authenticated {
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeNotAuthenticated, "Not authenticated")
+		return
+	}
+
+	enabled := true
+	if v, ok := msg.Payload["enabled"].(bool); ok {
+		enabled = v
+	}
+	h.hub.SetAlertSubscription(client, enabled)
+
+	h.logger.Info("Alert subscription changed",
+		zap.String("client_id", client.ID),
+		zap.Bool("enabled", enabled),
+	)
+
+	ack := protocol.NewMessage(protocol.MsgTypeCommandAck, "")
+	ack.Payload["command"] = "subscribe_alerts"
+	ack.Payload["enabled"] = enabled
+	h.sendToClient(client, ack)
+}
+
+// =============================================================================
+// velocityInput - Payload から速度の3成分を取り出す
+// =============================================================================
+//
+// 省略した成分は 0 として扱います（前進だけなら linear_x だけを送ればよい）。
+// ただし、数値に変換できない値（"fast" や null など）と、3成分すべての省略はエラーです。
+// 以前はどちらも黙って 0 になり、壊れたコマンドが「停止」として実行されていました。
+func velocityInput(payload map[string]any) (safety.VelocityInput, error) {
+	var input safety.VelocityInput
+	fields := []struct {
+		key string
+		dst *float64
+	}{
+		{"linear_x", &input.LinearX},
+		{"linear_y", &input.LinearY},
+		{"angular_z", &input.AngularZ},
+	}
+
+	present := 0
+	for _, f := range fields {
+		v, ok := payload[f.key]
+		if !ok {
+			continue
+		}
+		present++
+		if *f.dst, ok = convert.ToFloat64(v); !ok {
+			return input, fmt.Errorf("%s must be a number, got %v", f.key, v)
+		}
+	}
+	if present == 0 {
+		return input, errors.New("at least one of linear_x, linear_y, angular_z is required")
 	}
+	return input, nil
 }
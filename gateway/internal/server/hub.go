@@ -49,12 +49,30 @@ import (
 	//   読み取りが多い場合（センサーデータの配信など）に性能が向上します。
 	"sync"
 
+	// "sync/atomic": クライアントごとのエラー送信数をロックなしで数えるために使用。
+	"sync/atomic"
+
+	// "sort": スライスの並べ替え（購読ロボット一覧を安定した順序で返すため）
+	"sort"
+
+	// "time": Run() の heartbeat の間隔に使用
+	"time"
+
 	// "github.com/gorilla/websocket": WebSocket接続のオブジェクト型（*websocket.Conn）を使用。
 	// Client構造体でWebSocket接続を保持するために必要です。
 	"github.com/gorilla/websocket"
 
+	// protocol: クライアントごとのフォーマット（Client.Codec）の型
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+
+	// recovery: Run() のイベント処理で起きた panic を回復し、ループを止めないために使用
+	"github.com/robot-ai-webapp/gateway/internal/recovery"
+
 	// zap: 構造化ログライブラリ
 	"go.uber.org/zap"
+
+	// zapcore: ログレベルの型（BroadcastLog の購読条件の判定に使う）
+	"go.uber.org/zap/zapcore"
 )
 
 // =============================================================================
@@ -82,6 +100,20 @@ type Client struct {
 	// 認証前は空文字列（""）です。操作ロックのチェックなどに使用されます。
 	UserID string
 
+	// Codec: このクライアントとの読み書きに使うフォーマット（接続時に選ばれる）
+	// nil なら AutoCodec（MessagePack → JSON の順にデコードを試す）を使います。
+	// Send に入れるのはサーバー内部の形式（MessagePack）で、writePump が送信直前に変換します。
+	Codec protocol.Codec
+
+	// SessionID: 再接続時にセッションを再開するための識別子（認証時に設定）
+	// 最初は ID と同じで、セッションを再開した接続では再開したセッションのIDを引き継ぎます。
+	SessionID string
+
+	// ProtocolVersion: 認証時に取り決めたプロトコルのバージョン（認証前は 0）
+	// メッセージの形がバージョンで異なる場合、ハンドラーはこの値で読み方を分けます
+	// （protocol/version.go 参照）。
+	ProtocolVersion int
+
 	// Conn: WebSocket接続オブジェクト
 	// 【*websocket.Conn とは？】
 	// gorilla/websocket ライブラリが提供する WebSocket接続の構造体へのポインタです。
@@ -125,6 +157,74 @@ type Client struct {
 	// Subscriptions の更新（SubscribeClient）は頻繁ではないため、
 	// RWMutex よりもシンプルな Mutex で十分です。
 	mu sync.Mutex
+
+	// sensorBatch: センサーデータをまとめて受け取る（sensor_batch）ロボットIDの集合
+	// 購読ごとのオプトインです。SetSensorBatching() で設定し、mu で保護します。
+	sensorBatch map[string]bool
+
+	// latest: latest-only で受け取る（ロボット, トピック）ごとの、1件だけ入るスロット
+	// 購読ごと・トピックごとのオプトインです。SetLatestOnly() で設定し、mu で保護します（latest_only.go 参照）。
+	latest map[latestKey]chan []byte
+
+	// sensorQueue: センサーデータ専用の送信キュー（overflow.go 参照）
+	// Send と分けることで、溜まったセンサーデータが安全アラートや ACK を押し出さないようにします。
+	// HandleWebSocket が作成します。nil ならセンサーデータも Send に入れます（writePump のないテスト用のクライアント）。
+	sensorQueue chan []byte
+
+	// overflowed: 送信キューが溢れて切断が決まったか（overflow.go 参照）
+	overflowed atomic.Bool
+
+	// dropped: 送信キューが満杯で捨てたメッセージの数
+	dropped atomic.Uint64
+
+	// latestReady: latest-only のスロットにサンプルが入ったことを writePump に知らせるチャネル（容量1）
+	// HandleWebSocket が作成します。nil なら知らせません（writePump のないテスト用のクライアント）。
+	latestReady chan struct{}
+
+	// alertSubscriber: ロボットの購読に関係なく、全ロボットの安全アラートを受け取るか
+	// 監視ダッシュボード向けのオプトインです。SetAlertSubscription() で設定し、mu で保護します。
+	alertSubscriber bool
+
+	// logFilter: ログの購読条件（管理者向けの log_stream）。nil なら購読していない。
+	// SetLogSubscription() で設定し、mu で保護します。
+	logFilter *LogFilter
+
+	// observing: コマンドを観察している（command_observed を受け取る）ロボットIDの集合
+	// 操作者が送ったコマンドの写しを受け取る観察者モード。SetCommandObservation() で設定し、mu で保護します。
+	observing map[string]bool
+
+	// errorsSent: このクライアントに返したエラーメッセージの累計
+	// HandleMessage がメッセージ処理の前後で比較し、
+	// 「そのメッセージがエラーで終わったか」をメトリクスに記録するために使います。
+	errorsSent atomic.Uint64
+
+	// lastAppActivity: 最後にアプリケーション層でメッセージを処理した時刻（Unix ミリ秒）
+	// WebSocket の Pong はトランスポートの生存しか示さないため、クライアントのイベントループが
+	// 止まっていないかは、メッセージ（heartbeat など）が届いているかで判断します。
+	// Register() で登録時刻を入れ、HandleMessage がメッセージごとに更新します（AppHeartbeat 参照）。
+	lastAppActivity atomic.Int64
+
+	// panicked: このクライアントのメッセージの処理中に panic が起きたか
+	// HandleMessage が回復時に立て、readPump がそれを見て接続を閉じます（1011 internal error）。
+	panicked atomic.Bool
+
+	// recording: この接続のメッセージをセッションレコーダーに記録するか（record_session で切り替える）
+	// readPump と writePump の両方から読むため atomic です（session_record.go 参照）。
+	recording atomic.Bool
+
+	// consecutiveErrors: 連続してエラーになったメッセージの数（エラーバジェット用）
+	// readPump（1つのゴルーチン）からしか触らないため、ロックは不要です。
+	consecutiveErrors int
+
+	// closed: Hub が Send を閉じたか（Hub.mu で保護）
+	// 閉じた後に購読の索引へ追加され、閉じたチャネルに送信してしまうのを防ぎます。
+	closed bool
+
+	// closeCode / closeReason: 切断時に Close フレームに入れるコードと理由（Disconnect / CloseAll で設定）
+	// Send を閉じる前に Hub.mu を保持して書き込み、writePump は Send が閉じたのを見てから読むため、
+	// writePump 側のロックは不要です。closeCode が 0 なら正常終了（1000）を送ります。
+	closeCode   int
+	closeReason string
 }
 
 // =============================================================================
@@ -150,6 +250,16 @@ type Hub struct {
 	// 値: Client構造体へのポインタ
 	clients map[string]*Client // client_id -> client
 
+	// subscribers: ロボットごとの購読クライアントの索引（robot_id -> client_id -> client）
+	// センサーデータは高頻度で届くため、配信のたびに全クライアントを調べると
+	// 「接続数 × サンプル数」の処理になります。索引を引けば購読者の数だけで済みます。
+	// mu で保護し、SubscribeClient / UnsubscribeClient / 登録解除で更新します。
+	subscribers map[string]map[string]*Client
+
+	// latched: ロボットごとに保持しているラッチトピックの最新メッセージ（robot_id -> topic -> 送信データ）
+	// 購読した時点で送ります（latched_topics.go 参照）。mu で保護します。
+	latched map[string]map[string][]byte
+
 	// register: クライアント登録用チャネル
 	// 新しいクライアントが接続した時に、このチャネルに送信されます。
 	// 【バッファなしチャネル（unbuffered channel）】
@@ -173,10 +283,41 @@ type Hub struct {
 	// clients マップへの並行アクセスを保護します。
 	mu sync.RWMutex
 
+	// lastSensor: ロボットごとの最終センサーデータのタイムスタンプ（ミリ秒）
+	// センサーデータは高頻度で届くため、mu とは別のロック（sensorMu）で保護し、
+	// 配信処理（BroadcastToRobot の RLock）と競合しないようにしています。
+	lastSensor map[string]int64
+	sensorMu   sync.Mutex
+
+	// onUnregister: クライアントの登録解除後に呼ぶ関数（SetUnregisterCallback で設定、mu で保護）
+	onUnregister func(client *Client)
+
+	// logSubscribers: ログを購読しているクライアントの数
+	// ログを書くたびに参照されるため、ロックなしで読めるよう atomic にしています。
+	logSubscribers atomic.Int32
+
+	// commandObservers: コマンドの観察（クライアント×ロボット）の数
+	// コマンドのたびに参照され、誰もいなければ写しのエンコード自体を省くため atomic にしています。
+	commandObservers atomic.Int32
+
+	// overflow: 送信キューが満杯の時の、メッセージの種類ごとの方針（SetOverflowPolicies で設定）
+	overflow OverflowPolicies
+
+	// droppedSensor / droppedCritical / droppedBulk: 送信キューが満杯で捨てたメッセージの数（種類ごと）
+	droppedSensor   atomic.Uint64
+	droppedCritical atomic.Uint64
+	droppedBulk     atomic.Uint64
+
+	// heartbeat: Run() のループが回るたびに呼ぶ関数（SetHeartbeat で設定、nil なら呼ばない）
+	heartbeat func()
+
 	// logger: 構造化ログ出力
 	logger *zap.Logger
 }
 
+// HubHeartbeatInterval - Run() が heartbeat を呼ぶ間隔
+const HubHeartbeatInterval = time.Second
+
 // =============================================================================
 // NewHub - Hubのコンストラクタ
 // =============================================================================
@@ -198,7 +339,11 @@ func NewHub(logger *zap.Logger) *Hub {
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 		broadcast:  make(chan []byte, 256),
+		lastSensor: make(map[string]int64),
 		logger:     logger,
+
+		subscribers: make(map[string]map[string]*Client),
+		latched:     make(map[string]map[string][]byte),
 	}
 }
 
@@ -216,6 +361,7 @@ func NewHub(logger *zap.Logger) *Hub {
 
 // Register adds a client to the hub
 func (h *Hub) Register(client *Client) {
+	client.lastAppActivity.Store(time.Now().UnixMilli())
 	h.register <- client
 }
 
@@ -230,6 +376,36 @@ func (h *Hub) Unregister(client *Client) {
 	h.unregister <- client
 }
 
+// =============================================================================
+// =============================================================================
+// Disconnect - 理由を付けてクライアントを切断する
+// =============================================================================
+//
+// code と reason は、writePump が最後に送る Close フレームに入ります
+// （例: websocket.ClosePolicyViolation, "error budget exceeded"）。
+// クライアントはこれを見て、切断の原因をログに出したり再接続するか決めたりできます。
+// 既に切断されていれば、先に決まった理由を優先します。
+func (h *Hub) Disconnect(client *Client, code int, reason string) {
+	h.mu.Lock()
+	if !client.closed {
+		client.closeCode, client.closeReason = code, reason
+	}
+	h.mu.Unlock()
+	h.Unregister(client)
+}
+
+// SetUnregisterCallback - クライアントの登録解除後に呼ぶ関数を設定する
+// =============================================================================
+//
+// 切断したクライアントが操作していたロボットを止める、といった後始末に使います。
+// fn は別のゴルーチンで呼ばれるため、ロボットへの送信が遅くても
+// Run() のイベントループは止まりません。
+func (h *Hub) SetUnregisterCallback(fn func(client *Client)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.onUnregister = fn
+}
+
 // =============================================================================
 // Run - Hubのメインイベントループ
 // =============================================================================
@@ -254,75 +430,115 @@ func (h *Hub) Unregister(client *Client) {
 // ランダムに1つが選ばれます（公平性のため）。
 // すべてのチャネルにデータがなければ、データが来るまでブロックします。
 
+// =============================================================================
+// SetHeartbeat - Run() のループが回っていることを知らせる関数を設定する
+// =============================================================================
+//
+// heartbeat.Registry.Register の戻り値を渡します（間隔は HubHeartbeatInterval）。
+// go hub.Run() より前に呼んでください。
+func (h *Hub) SetHeartbeat(fn func()) {
+	h.heartbeat = fn
+}
+
 // Run starts the hub's main event loop
 func (h *Hub) Run() {
+	// ループが止まっていないことを示すため、定期的に heartbeat を呼ぶ
+	// （登録・解除の処理で詰まっていれば、この case も回らなくなる）
+	ticker := time.NewTicker(HubHeartbeatInterval)
+	defer ticker.Stop()
+
+	// 1つのイベントの処理で panic が起きても、ループ（全クライアントへの配信）は止めない
 	for {
-		select {
-		case client := <-h.register:
-			// 【クライアントの登録】
-			// clients マップにクライアントを追加します。
-			h.mu.Lock()
-			h.clients[client.ID] = client
-			h.mu.Unlock()
-
-			// 登録ログを出力
-			// len(h.clients) で現在の接続数を表示します。
-			h.logger.Info("Client registered",
-				zap.String("client_id", client.ID),
-				zap.Int("total_clients", len(h.clients)),
-			)
-
-		case client := <-h.unregister:
-			// 【クライアントの登録解除】
-			h.mu.Lock()
-			// マップにクライアントが存在するか確認
-			// 【カンマOKパターン】
-			// _, ok := h.clients[client.ID]
-			// マップの値は使わないので _ で無視し、存在するかを ok で確認します。
-			if _, ok := h.clients[client.ID]; ok {
-				// マップからクライアントを削除
-				delete(h.clients, client.ID)
-				// 【close(client.Send)】
-				// Sendチャネルを閉じます。チャネルを閉じると:
-				// - 以降の送信はpanicを起こす
-				// - 受信側（writePump）は即座に値を受け取り、ok=falseが返る
-				// - これにより writePump が正常に終了する
-				//
-				// 【重要】チャネルは送信側が閉じるのがGoの慣例です。
-				// 受信側が閉じると、他の送信者がpanicを起こす可能性があります。
-				close(client.Send)
-			}
-			h.mu.Unlock()
-
-			h.logger.Info("Client unregistered",
-				zap.String("client_id", client.ID),
-				zap.Int("total_clients", len(h.clients)),
-			)
-
-		case message := <-h.broadcast:
-			// 【全クライアントへのブロードキャスト】
-			// 読み取りロック（RLock）で clients マップを参照します。
-			// 複数のブロードキャストが同時に実行されても安全です。
-			h.mu.RLock()
-			for _, client := range h.clients {
-				// 各クライアントの Send チャネルにメッセージを送信
-				select {
-				case client.Send <- message:
-					// 正常に送信成功
-				default:
-					// 【default: チャネルのバッファが満杯】
-					// クライアントの処理が遅く、Sendバッファが溢れている場合。
-					// メッセージをドロップ（破棄）して次のクライアントに進みます。
-					// これにより、1つの遅いクライアントがシステム全体を
-					// ブロックすることを防ぎます。
-					// Client too slow, skip
-				}
-			}
-			h.mu.RUnlock()
+		h.runOnce(ticker)
+	}
+}
+
+// runOnce: イベントを1つ待って処理する（Run() のループの1回分）
+//
+// panic はここで回復してログに残し、Run() は次のイベントを待ちます。
+// ロックを持ったまま panic してもロックが外れるよう、ロックを取る処理は
+// registerClient / unregisterClient / BroadcastToAll に分けて defer で外しています。
+func (h *Hub) runOnce(ticker *time.Ticker) {
+	defer recovery.Recover(h.logger, "hub_run", nil)
+
+	select {
+	case <-ticker.C:
+		if h.heartbeat != nil {
+			h.heartbeat()
 		}
+
+	case client := <-h.register:
+		// 【クライアントの登録】
+		total := h.registerClient(client)
+
+		// 登録ログを出力
+		h.logger.Info("Client registered",
+			zap.String("client_id", client.ID),
+			zap.Int("total_clients", total),
+		)
+
+	case client := <-h.unregister:
+		// 【クライアントの登録解除】
+		total := h.unregisterClient(client)
+
+		h.logger.Info("Client unregistered",
+			zap.String("client_id", client.ID),
+			zap.Int("total_clients", total),
+		)
+
+	case message := <-h.broadcast:
+		// 【全クライアントへのブロードキャスト】
+		// 読み取りロック（RLock）で clients マップを参照します。
+		// critical の方針が block の時は、バッファが満杯の（処理が遅い）クライアントごとに
+		// ロックを持ったまま最大 BlockTimeout 待つため、その間は他の配信や Register / Unregister も止まります。
+		// デフォルトの方針（disconnect）では待たずに切断します（overflow.go）。
+		h.BroadcastToAll(message)
 	}
 }
 
+// registerClient: clients マップにクライアントを追加し、登録後の接続数を返す
+func (h *Hub) registerClient(client *Client) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.clients[client.ID] = client
+	// len(h.clients) で現在の接続数を数えます。
+	// CloseAll() など他のゴルーチンも clients を変更するため、ロック中に数えます。
+	return len(h.clients)
+}
+
+// unregisterClient: クライアントを clients マップと購読の索引から削除し、削除後の接続数を返す
+func (h *Hub) unregisterClient(client *Client) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// マップにクライアントが存在するか確認
+	// 【カンマOKパターン】
+	// _, ok := h.clients[client.ID]
+	// マップの値は使わないので _ で無視し、存在するかを ok で確認します。
+	if _, ok := h.clients[client.ID]; ok {
+		// マップからクライアントを削除
+		delete(h.clients, client.ID)
+		client.closed = true
+		// 【close(client.Send)】
+		// Sendチャネルを閉じます。チャネルを閉じると:
+		// - 以降の送信はpanicを起こす
+		// - 受信側（writePump）は即座に値を受け取り、ok=falseが返る
+		// - これにより writePump が正常に終了する
+		//
+		// 【重要】チャネルは送信側が閉じるのがGoの慣例です。
+		// 受信側が閉じると、他の送信者がpanicを起こす可能性があります。
+		close(client.Send)
+		// 後始末のコールバックは、clients から削除した後に呼ぶ
+		// （コールバック内の UserConnected などに、切断したクライアントが含まれない）
+		if h.onUnregister != nil {
+			onUnregister := h.onUnregister
+			recovery.Go(h.logger, "hub_unregister_callback", func() { onUnregister(client) })
+		}
+	}
+	// 購読の索引からも削除する（登録が処理される前に購読したクライアントも含めて）
+	h.removeSubscriberLocked(client)
+	return len(h.clients)
+}
+
 // =============================================================================
 // BroadcastToRobot - 特定のロボットの購読者にメッセージを配信
 // =============================================================================
@@ -345,22 +561,14 @@ func (h *Hub) BroadcastToRobot(robotID string, data []byte) {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
-	for _, client := range h.clients {
-		// 【マップの参照: client.Subscriptions[robotID]】
-		// マップに存在しないキーを参照すると、ゼロ値（boolの場合はfalse）が返ります。
-		// つまり、購読していないクライアントでは false が返り、if文をスキップします。
-		if client.Subscriptions[robotID] {
-			select {
-			case client.Send <- data:
-				// 送信成功
-			default:
-				// バッファ満杯の警告ログ
-				// 頻繁に発生する場合、クライアントの処理速度に問題があります
-				h.logger.Warn("Client send buffer full",
-					zap.String("client_id", client.ID),
-				)
-			}
-		}
+	// 購読の索引（subscribers）から、そのロボットの購読者だけを取り出して送る。
+	// 【マップの参照: h.subscribers[robotID]】
+	// マップに存在しないキーを参照すると、ゼロ値（mapの場合はnil）が返ります。
+	// nil のマップを range してもループが0回回るだけなので、購読者がいなければ何もしません。
+	for _, client := range h.subscribers[robotID] {
+		// 満杯の時の扱いは critical の方針に従う（overflow.go）。
+		// 捨てた時は警告ログを出します。頻繁に出る場合、クライアントの処理速度に問題があります。
+		h.enqueue(client, ClassCritical, data, true)
 	}
 }
 
@@ -381,12 +589,102 @@ func (h *Hub) BroadcastToAll(data []byte) {
 	defer h.mu.RUnlock()
 
 	for _, client := range h.clients {
+		h.enqueue(client, ClassCritical, data, true)
+	}
+}
+
+// =============================================================================
+// BroadcastAlert - 安全アラートを、関係するクライアントにだけ配信する
+// =============================================================================
+//
+// 【配信先】
+// - アラート購読者（SetAlertSubscription で有効化）: 全ロボットのアラートを受け取る
+// - そのロボットの購読者: 自分が見ているロボットのアラートを受け取る
+// - robotID が空（全ロボット対象の E-Stop など）: 全クライアントに配信する
+//
+// 以前はすべてのアラートを BroadcastToAll で送っていたため、
+// 関係のないロボットのアラートまで全員に届いていました。
+
+// BroadcastAlert sends an alert to alert subscribers and the robot's subscribers
+func (h *Hub) BroadcastAlert(robotID string, data []byte) {
+	if robotID == "" {
+		h.BroadcastToAll(data)
+		return
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, client := range h.clients {
+		client.mu.Lock()
+		match := client.alertSubscriber || client.Subscriptions[robotID]
+		client.mu.Unlock()
+		if !match {
+			continue
+		}
+		h.enqueue(client, ClassCritical, data, true)
+	}
+}
+
+// =============================================================================
+// SendToUser - 指定したユーザーの全接続にメッセージを送信
+// =============================================================================
+//
+// 同じユーザーが複数のタブで接続している場合は、そのすべてに送ります。
+// ロックの期限切れ警告のように、接続ではなくユーザーに宛てた通知に使います。
+
+// SendToUser sends a message to every client authenticated as the user
+func (h *Hub) SendToUser(userID string, data []byte) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, client := range h.clients {
+		if client.UserID != userID {
+			continue
+		}
+		h.enqueue(client, ClassCritical, data, true)
+	}
+}
+
+// =============================================================================
+// CloseAll - 全クライアントに最後のメッセージを送り、接続を閉じる
+// =============================================================================
+//
+// サーバーの停止時に使います。各クライアントの Send に data を入れてから
+// Send を閉じるため、writePump は data を送り終えた後、reason を付けた
+// Close フレーム（1001 Going Away: サーバーが去る）を送って接続を閉じます。
+// 閉じたクライアントは clients から削除されます（登録解除のコールバックは呼びません）。
+func (h *Hub) CloseAll(data []byte, reason string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for id, client := range h.clients {
 		select {
 		case client.Send <- data:
 		default:
-			// バッファ満杯の場合、メッセージをドロップ
+			// バッファが満杯のクライアントには予告を送れないが、接続は閉じる
+		}
+		client.closeCode, client.closeReason = websocket.CloseGoingAway, reason
+		close(client.Send)
+		client.closed = true
+		delete(h.clients, id)
+		h.removeSubscriberLocked(client)
+	}
+	h.logger.Info("All clients closed", zap.String("reason", reason))
+}
+
+// =============================================================================
+// UserConnected - ユーザーの接続が1つでも残っているか
+// =============================================================================
+func (h *Hub) UserConnected(userID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, client := range h.clients {
+		if client.UserID == userID {
+			return true
 		}
 	}
+	return false
 }
 
 // =============================================================================
@@ -397,22 +695,20 @@ func (h *Hub) BroadcastToAll(data []byte) {
 // 特定のクライアントだけにメッセージを送ります。
 // 主にコマンドのACK（確認応答）やエラーメッセージの送信に使います。
 //
-// 【select + default パターン】
-// チャネルが満杯の場合はブロックせず、警告ログを出力します。
-// 重要なメッセージ（ACKなど）がドロップされる可能性がありますが、
-// システムの安定性（ブロックしないこと）を優先しています。
+// 【満杯の時】
+// ACK やエラーは critical の方針（SetOverflowPolicies）に従います。
+// デフォルトは以前と同じく捨てて警告ログを出すだけですが、block / disconnect にすると
+// 黙って捨てることはなくなります（overflow.go 参照）。
+// 切断済み（Send が閉じられた）のクライアントには何もしません。
 
 // SendToClient sends a message to a specific client
 func (h *Hub) SendToClient(client *Client, data []byte) {
-	select {
-	case client.Send <- data:
-		// 正常に送信キューに追加
-	default:
-		// Sendチャネルのバッファ（256個）が満杯
-		h.logger.Warn("Client send buffer full",
-			zap.String("client_id", client.ID),
-		)
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if client.closed {
+		return
 	}
+	h.enqueue(client, ClassCritical, data, true)
 }
 
 // =============================================================================
@@ -428,13 +724,18 @@ func (h *Hub) SendToClient(client *Client, data []byte) {
 // アクセスを保護しています。Hub.mu（sync.RWMutex）とは別のロックです。
 //
 // 【なぜ別のロック？】
-// Hub のミューテックスはクライアントの追加/削除を保護するためのものです。
+// Hub のミューテックスはクライアントの追加/削除と購読の索引（subscribers）を保護するためのものです。
 // 個々のクライアントの Subscriptions マップは、クライアント固有のデータなので、
 // クライアント自身のミューテックスで保護します。
-// これにより、異なるクライアントの購読変更が互いにブロックしません（きめ細かいロック）。
+// 両方を取る時は、デッドロックしないよう必ず Hub → クライアントの順に取ります。
+// 購読の変更は認証時などに限られるため、Hub のロックを取ってもセンサー配信の妨げにはなりません。
 
 // SubscribeClient subscribes a client to a robot's data
 func (h *Hub) SubscribeClient(client *Client, robotID string) {
+	// 購読の索引を更新するため Hub のロックも取る（順序は Hub → クライアント）
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
 	// クライアント固有のロックを取得
 	client.mu.Lock()
 	defer client.mu.Unlock()
@@ -442,8 +743,446 @@ func (h *Hub) SubscribeClient(client *Client, robotID string) {
 	// 購読マップにロボットIDを追加（true = 購読中）
 	client.Subscriptions[robotID] = true
 
+	// 購読の索引に追加する（既に切断したクライアントは追加しない）
+	if !client.closed {
+		robot, ok := h.subscribers[robotID]
+		if !ok {
+			robot = make(map[string]*Client)
+			h.subscribers[robotID] = robot
+		}
+		robot[client.ID] = client
+		h.sendLatchedLocked(client, robotID)
+	}
+
 	h.logger.Info("Client subscribed to robot",
 		zap.String("client_id", client.ID),
 		zap.String("robot_id", robotID),
 	)
 }
+
+// =============================================================================
+// UnsubscribeClient - クライアントのロボット購読を解除する
+// =============================================================================
+//
+// そのロボットのまとめ送り（sensor_batch）と latest-only の指定も一緒に解除します。
+func (h *Hub) UnsubscribeClient(client *Client, robotID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	client.mu.Lock()
+	delete(client.Subscriptions, robotID)
+	delete(client.sensorBatch, robotID)
+	client.clearLatestLocked(robotID)
+	client.mu.Unlock()
+
+	if robot, ok := h.subscribers[robotID]; ok {
+		delete(robot, client.ID)
+		if len(robot) == 0 {
+			delete(h.subscribers, robotID)
+		}
+	}
+}
+
+// removeSubscriberLocked: 購読の索引からクライアントを取り除く（h.mu を保持した状態で呼ぶこと）
+func (h *Hub) removeSubscriberLocked(client *Client) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	if client.logFilter != nil {
+		client.logFilter = nil
+		h.logSubscribers.Add(-1)
+	}
+	h.commandObservers.Add(-int32(len(client.observing)))
+	client.observing = nil
+	for robotID := range client.Subscriptions {
+		if robot, ok := h.subscribers[robotID]; ok {
+			delete(robot, client.ID)
+			if len(robot) == 0 {
+				delete(h.subscribers, robotID)
+			}
+		}
+	}
+}
+
+// =============================================================================
+// RemoveRobot - 削除されたロボットの購読をすべてのクライアントから取り除く
+// =============================================================================
+//
+// data（通常は removed: true の conn_status）を購読者に送ってから、
+// 各クライアントの Subscriptions とまとめ送り・latest-only の指定、購読の索引、ラッチしたメッセージ、最終センサー時刻を削除します。
+// 後から同じ ID のロボットが登録されても、古い購読が残って勝手にデータが届くことはありません。
+// 購読を取り除いたクライアントの数を返します。
+func (h *Hub) RemoveRobot(robotID string, data []byte) int {
+	h.mu.Lock()
+	removed := 0
+	for _, client := range h.clients {
+		client.mu.Lock()
+		subscribed := client.Subscriptions[robotID]
+		delete(client.Subscriptions, robotID)
+		delete(client.sensorBatch, robotID)
+		client.clearLatestLocked(robotID)
+		if client.observing[robotID] {
+			delete(client.observing, robotID)
+			h.commandObservers.Add(-1)
+		}
+		client.mu.Unlock()
+		if !subscribed {
+			continue
+		}
+		removed++
+		if data != nil && !client.closed {
+			h.enqueue(client, ClassCritical, data, true)
+		}
+	}
+	delete(h.subscribers, robotID)
+	delete(h.latched, robotID)
+	h.mu.Unlock()
+
+	h.sensorMu.Lock()
+	delete(h.lastSensor, robotID)
+	h.sensorMu.Unlock()
+
+	h.logger.Info("Removed robot subscriptions",
+		zap.String("robot_id", robotID),
+		zap.Int("clients", removed),
+	)
+	return removed
+}
+
+// =============================================================================
+// SetSensorBatching - 購読中のロボットのセンサーデータをまとめて受け取るか設定する
+// =============================================================================
+//
+// 有効にすると、そのロボットのセンサーデータは1サンプルずつではなく、
+// SensorBatcher が一定時間分をまとめた sensor_batch メッセージで届きます。
+func (h *Hub) SetSensorBatching(client *Client, robotID string, enabled bool) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	if enabled {
+		if client.sensorBatch == nil {
+			client.sensorBatch = make(map[string]bool)
+		}
+		client.sensorBatch[robotID] = true
+	} else {
+		delete(client.sensorBatch, robotID)
+	}
+}
+
+// =============================================================================
+// SetAlertSubscription - 全ロボットの安全アラートの購読（alerts-only）を切り替える
+// =============================================================================
+//
+// 有効にしたクライアントは、センサーデータを購読していなくても
+// BroadcastAlert() で配信されるすべてのアラートを受け取ります。
+func (h *Hub) SetAlertSubscription(client *Client, enabled bool) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	client.alertSubscriber = enabled
+}
+
+// =============================================================================
+// SetLogSubscription - ログの購読（管理者向けの log_stream）を切り替える
+// =============================================================================
+//
+// filter が nil なら購読を解除します。既に切断したクライアントは購読できません。
+func (h *Hub) SetLogSubscription(client *Client, filter *LogFilter) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	if client.closed {
+		return
+	}
+	switch {
+	case client.logFilter == nil && filter != nil:
+		h.logSubscribers.Add(1)
+	case client.logFilter != nil && filter == nil:
+		h.logSubscribers.Add(-1)
+	}
+	client.logFilter = filter
+}
+
+// =============================================================================
+// SetCommandObservation - ロボットのコマンドの観察（観察者モード）を切り替える
+// =============================================================================
+//
+// 有効にしたクライアントは、そのロボットに受け付けられたコマンドの写しを
+// BroadcastCommandObserved() で受け取ります。既に切断したクライアントは観察できません。
+func (h *Hub) SetCommandObservation(client *Client, robotID string, enabled bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	if client.closed {
+		return
+	}
+	switch {
+	case enabled && !client.observing[robotID]:
+		if client.observing == nil {
+			client.observing = make(map[string]bool)
+		}
+		client.observing[robotID] = true
+		h.commandObservers.Add(1)
+	case !enabled && client.observing[robotID]:
+		delete(client.observing, robotID)
+		h.commandObservers.Add(-1)
+	}
+}
+
+// HasCommandObservers - コマンドを観察しているクライアントがいるかを返す
+func (h *Hub) HasCommandObservers() bool {
+	return h.commandObservers.Load() > 0
+}
+
+// BroadcastCommandObserved - コマンドの写しを、そのロボットを観察しているクライアントに送る
+// コマンドを送った本人（exclude）には送りません（本人には cmd_ack が届くため）。
+func (h *Hub) BroadcastCommandObserved(robotID string, data []byte, exclude *Client) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, client := range h.clients {
+		if client == exclude {
+			continue
+		}
+		client.mu.Lock()
+		match := client.observing[robotID]
+		client.mu.Unlock()
+		if !match {
+			continue
+		}
+		h.enqueue(client, ClassBulk, data, true)
+	}
+}
+
+// HasLogSubscribers - ログを購読しているクライアントがいるかを返す
+// 誰もいなければ、LogStream はログのコピー自体を作りません。
+func (h *Hub) HasLogSubscribers() bool {
+	return h.logSubscribers.Load() > 0
+}
+
+// BroadcastLog - ログを、購読条件（レベル・ロボットID）の合うクライアントに送る
+//
+// 送信バッファが満杯でも警告ログは出しません
+// （そのログがまた配信されて満杯になる、という連鎖を防ぐため）。
+func (h *Hub) BroadcastLog(level zapcore.Level, robotID string, data []byte) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, client := range h.clients {
+		client.mu.Lock()
+		match := client.logFilter != nil && client.logFilter.matches(level, robotID)
+		client.mu.Unlock()
+		if !match {
+			continue
+		}
+		h.enqueue(client, ClassBulk, data, false)
+	}
+}
+
+// =============================================================================
+// BroadcastSensorData - センサーデータを受け取り方の合う購読者にだけ配信する
+// =============================================================================
+//
+// batched が false なら1サンプルずつ受け取る購読者に、
+// true ならまとめて受け取る購読者（SetSensorBatching で有効化）に送ります。
+// 同じデータが両方の形で二重に届かないようにするためのものです。
+// topic はクライアント向けのトピック名で、latest-only（SetLatestOnly）の判定に使います（バッチは ""）。
+func (h *Hub) BroadcastSensorData(robotID, topic string, data []byte, batched bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, client := range h.subscribers[robotID] {
+		client.mu.Lock()
+		match := client.sensorBatch[robotID] == batched
+		client.mu.Unlock()
+		if !match {
+			continue
+		}
+		h.deliverSensor(client, robotID, topic, data)
+	}
+}
+
+// BroadcastSensorSample - 1サンプルのセンサーデータを、まとめ送りの指定に関係なく全購読者に配信する
+//
+// まとめ送り（SensorBatcher）を使わない構成で使います。
+// BroadcastToRobot と同じ相手に送りますが、latest-only のトピックはスロット経由で届けます。
+func (h *Hub) BroadcastSensorSample(robotID, topic string, data []byte) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, client := range h.subscribers[robotID] {
+		h.deliverSensor(client, robotID, topic, data)
+	}
+}
+
+// HasBatchedSubscribers - センサーデータをまとめて受け取る購読者がいるかを返す
+// 誰もいなければ、SensorBatcher はバッチのエンコード自体を省略します。
+func (h *Hub) HasBatchedSubscribers(robotID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, client := range h.subscribers[robotID] {
+		client.mu.Lock()
+		batched := client.sensorBatch[robotID]
+		client.mu.Unlock()
+		if batched {
+			return true
+		}
+	}
+	return false
+}
+
+// =============================================================================
+// RobotPressure - ロボットの購読者全体の「混雑度」を返す
+// =============================================================================
+//
+// 各購読者の Send バッファの使用率（0.0 = 空, 1.0 = 満杯）を調べ、その最小値を返します。
+//
+// 【なぜ最小値？】
+// 1人でも速いクライアントがいれば、その人には全頻度のデータを届けるべきです。
+// 最小値が高い＝「全員が遅い」時だけ、生成側に頻度を下げてもらいます（FlowController 参照）。
+// 購読者がいない場合は 0.0 を返します（Redis への記録は全頻度で続けるため）。
+func (h *Hub) RobotPressure(robotID string) float64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	pressure := -1.0
+	for _, client := range h.subscribers[robotID] {
+		// センサーデータ専用のキューがあれば、そちらの混雑度を見る（overflow.go）
+		queue := client.Send
+		if client.sensorQueue != nil {
+			queue = client.sensorQueue
+		}
+		if cap(queue) == 0 {
+			continue
+		}
+
+		// len(ch): チャネルに溜まっている未送信メッセージの数
+		// cap(ch): チャネルのバッファ容量
+		fill := float64(len(queue)) / float64(cap(queue))
+		if pressure < 0 || fill < pressure {
+			pressure = fill
+		}
+	}
+	if pressure < 0 {
+		return 0
+	}
+	return pressure
+}
+
+// =============================================================================
+// ClientCount - 接続中のクライアント数を返す
+// =============================================================================
+func (h *Hub) ClientCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients)
+}
+
+// =============================================================================
+// InactiveClients - 指定時刻より前からメッセージを送ってこないクライアントを返す
+// =============================================================================
+//
+// cutoffMs は Unix ミリ秒。AppHeartbeat が、ハートビートの途絶えたクライアントを探すのに使います。
+// 切断はロックを外してから呼び出し側で行ってください（Disconnect は h.mu を取ります）。
+func (h *Hub) InactiveClients(cutoffMs int64) []*Client {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var stale []*Client
+	for _, client := range h.clients {
+		if client.lastAppActivity.Load() < cutoffMs {
+			stale = append(stale, client)
+		}
+	}
+	return stale
+}
+
+// =============================================================================
+// SendBuffers - 接続中の各クライアントの Send バッファの使用状況を返す
+// =============================================================================
+//
+// /debug/health で、詰まっているクライアント（writePump が止まっている等）を探すのに使います。
+// 使用率の高い順に並べて返します。
+func (h *Hub) SendBuffers() []SendBufferStat {
+	h.mu.RLock()
+	stats := make([]SendBufferStat, 0, len(h.clients))
+	for _, client := range h.clients {
+		stat := SendBufferStat{ClientID: client.ID, UserID: client.UserID, Len: len(client.Send), Cap: cap(client.Send),
+			SensorLen: len(client.sensorQueue), SensorCap: cap(client.sensorQueue), Dropped: client.dropped.Load(),
+			LastAppActivityMs: client.lastAppActivity.Load()}
+		if stat.Cap > 0 {
+			stat.Fill = float64(stat.Len) / float64(stat.Cap)
+		}
+		stats = append(stats, stat)
+	}
+	h.mu.RUnlock()
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Fill != stats[j].Fill {
+			return stats[i].Fill > stats[j].Fill
+		}
+		return stats[i].ClientID < stats[j].ClientID
+	})
+	return stats
+}
+
+// SendBufferStat - 1つのクライアントの Send バッファの使用状況
+type SendBufferStat struct {
+	ClientID string  `json:"client_id"`
+	UserID   string  `json:"user_id,omitempty"`
+	Len      int     `json:"len"`  // 未送信のメッセージ数
+	Cap      int     `json:"cap"`  // バッファの容量
+	Fill     float64 `json:"fill"` // 使用率（0.0 = 空, 1.0 = 満杯）
+
+	// SensorLen / SensorCap: センサーデータ専用のキューの未送信数と容量（キューがなければ 0）
+	SensorLen int `json:"sensor_len"`
+	SensorCap int `json:"sensor_cap"`
+	// Dropped: 送信キューが満杯で捨てたメッセージの数（overflow.go）
+	Dropped uint64 `json:"dropped"`
+
+	// LastAppActivityMs: 最後にアプリケーション層のメッセージを受け取った時刻（Unix ミリ秒、未受信なら接続時刻）
+	LastAppActivityMs int64 `json:"last_app_activity_ms"`
+}
+
+// =============================================================================
+// MarkSensorData / LastSensorTimestamp - ロボットの最終センサー時刻の記録と参照
+// =============================================================================
+//
+// センサー転送処理（forwardSensorData）がデータを配信するたびに MarkSensorData を呼びます。
+// ヘルス状態の問い合わせでは、最終時刻が古ければ「データが途絶えている」と判断できます。
+
+// MarkSensorData: ロボットからセンサーデータを受信した時刻（ミリ秒）を記録する
+func (h *Hub) MarkSensorData(robotID string, timestampMs int64) {
+	h.sensorMu.Lock()
+	h.lastSensor[robotID] = timestampMs
+	h.sensorMu.Unlock()
+}
+
+// LastSensorTimestamp: 最終センサー時刻（ミリ秒）を返す。まだ受信していなければ false。
+func (h *Hub) LastSensorTimestamp(robotID string) (int64, bool) {
+	h.sensorMu.Lock()
+	defer h.sensorMu.Unlock()
+	ts, ok := h.lastSensor[robotID]
+	return ts, ok
+}
+
+// =============================================================================
+// SubscribedRobots - クライアントが購読しているロボットIDの一覧を返す
+// =============================================================================
+//
+// 順序を安定させるため、ロボットIDの昇順に並べて返します。
+func (h *Hub) SubscribedRobots(client *Client) []string {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	robots := make([]string, 0, len(client.Subscriptions))
+	for robotID, subscribed := range client.Subscriptions {
+		if subscribed {
+			robots = append(robots, robotID)
+		}
+	}
+	sort.Strings(robots)
+	return robots
+}
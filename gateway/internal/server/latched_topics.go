@@ -0,0 +1,44 @@
+// =============================================================================
+// ファイル: latched_topics.go
+// 概要: 一度しか届かないトピックの最新メッセージを保持し、購読時に送る（ROS の latch と同じ）
+//
+// 【なぜ必要？】
+// tf_static（センサーの取り付け位置などの静的な座標変換）は、ロボットの接続時に
+// 一度だけ送られます。ゲートウェイはその時点の購読者にしか配信しないため、
+// 後から画面を開いたクライアントは TF ツリーを組み立てられませんでした。
+//
+// 【仕組み】
+//
+//	SensorFanout ──Latch()──→ Hub.latched（ロボット×トピックごとに最新の1件）
+//	SubscribeClient ──sendLatchedLocked()──→ 購読したクライアントにすぐ送る
+//
+// 保持するのはエンコード済みの sensor_data なので、送るときに作り直す必要はありません。
+// ロボットが削除されると（RemoveRobot）保持していたメッセージも捨てます。
+// =============================================================================
+package server
+
+// latchedTopics: 最新のメッセージを保持しておくトピック（アダプターが付けた内部の名前）
+var latchedTopics = map[string]bool{
+	"tf_static": true,
+}
+
+// Latch - ロボットのラッチトピックの最新メッセージを保持する
+// topic はクライアント向けの名前（付け替え後）です。
+func (h *Hub) Latch(robotID, topic string, data []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	robot, ok := h.latched[robotID]
+	if !ok {
+		robot = make(map[string][]byte)
+		h.latched[robotID] = robot
+	}
+	robot[topic] = data
+}
+
+// sendLatchedLocked: 保持しているラッチトピックのメッセージを client に送る（h.mu を保持した状態で呼ぶこと）
+func (h *Hub) sendLatchedLocked(client *Client, robotID string) {
+	for _, data := range h.latched[robotID] {
+		h.enqueue(client, ClassSensor, data, true)
+	}
+}
@@ -0,0 +1,147 @@
+// =============================================================================
+// ファイル: latest_only.go
+// 概要: センサーデータの「最新値だけ」配信（latest-only）
+//
+// 【背景】
+// オドメトリのようなトピックでは、クライアントが知りたいのは「今どこにいるか」です。
+// 遅いクライアントの Send バッファに 20Hz のサンプルが溜まると、画面は古い姿勢を
+// 順番に再生することになり、実際の位置からどんどん遅れていきます。
+//
+// 【仕組み】
+// latest-only を有効にした（クライアント, ロボット, トピック）ごとに、1件だけ入るスロット
+// （容量1のチャネル）を用意します。新しいサンプルは Send バッファではなくスロットに入り、
+// まだ送られていない前のサンプルがあれば置き換えます。
+//
+//	通常:        sample1 → sample2 → sample3 → Send [s1 s2 s3]  （溜まった分を順に送る）
+//	latest-only: sample1 → sample2 → sample3 → スロット [s3]    （最新の1件だけ送る）
+//
+// writePump はスロットにサンプルが入ると起こされ、Send の順番を待たずに送ります。
+// トピックごとのオプトインで、指定しないトピックは従来どおり Send バッファ経由で届きます。
+// =============================================================================
+package server
+
+import "sort"
+
+// latestKey: latest-only のスロットを識別するキー（ロボットID とクライアント向けのトピック名）
+type latestKey struct {
+	robotID string
+	topic   string
+}
+
+// =============================================================================
+// SetLatestOnly - 購読中のロボットのトピックを latest-only で受け取るよう設定する
+// =============================================================================
+//
+// topics はクライアント向けのトピック名（付け替え後の名前）です。
+// そのロボットの設定を置き換え、空なら latest-only をやめます（送っていない最新値は捨てます）。
+// 購読の解除やロボットの削除でも一緒に解除されます。
+func (h *Hub) SetLatestOnly(client *Client, robotID string, topics []string) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	client.clearLatestLocked(robotID)
+	if len(topics) == 0 {
+		return
+	}
+	if client.latest == nil {
+		client.latest = make(map[latestKey]chan []byte)
+	}
+	for _, topic := range topics {
+		client.latest[latestKey{robotID: robotID, topic: topic}] = make(chan []byte, 1)
+	}
+}
+
+// LatestOnlyTopics - latest-only で受け取っているロボットのトピック（名前順）
+func (h *Hub) LatestOnlyTopics(client *Client, robotID string) []string {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	var topics []string
+	for key := range client.latest {
+		if key.robotID == robotID {
+			topics = append(topics, key.topic)
+		}
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// deliverSensor: 1件のセンサーデータを client に渡す
+//
+// latest-only のトピックならスロットの中身を置き換え、そうでなければ送信キューに入れます
+// （センサーデータの方針に従う。overflow.go 参照）。
+// 送信キューに入れる場合は Send が閉じられていないこと（h.mu を保持していること）が前提です。
+func (h *Hub) deliverSensor(client *Client, robotID, topic string, data []byte) {
+	client.mu.Lock()
+	slot, ok := client.latest[latestKey{robotID: robotID, topic: topic}]
+	if ok {
+		// 送っていない古いサンプルがあれば捨ててから入れる（client.mu で入れ替えを直列化）
+		select {
+		case <-slot:
+		default:
+		}
+		slot <- data
+	}
+	client.mu.Unlock()
+
+	if ok {
+		// writePump を起こす（既に起こしてあれば何もしない）
+		select {
+		case client.latestReady <- struct{}{}:
+		default:
+		}
+		return
+	}
+
+	h.enqueue(client, ClassSensor, data, true)
+}
+
+// TakeLatest - latest-only のスロットに入っているサンプルをすべて取り出す
+//
+// writePump が latestReady で起こされた時に呼び、取り出したものを順に送ります。
+// 取り出したスロットは空になり、次のサンプルを待ちます。
+func (c *Client) TakeLatest() [][]byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var out [][]byte
+	for _, slot := range c.latest {
+		select {
+		case data := <-slot:
+			out = append(out, data)
+		default:
+		}
+	}
+	return out
+}
+
+// clearLatestLocked: robotID の latest-only の設定とスロットを消す（c.mu を保持した状態で呼ぶこと）
+func (c *Client) clearLatestLocked(robotID string) {
+	for key := range c.latest {
+		if key.robotID == robotID {
+			delete(c.latest, key)
+		}
+	}
+}
+
+// stringList: Payload の文字列の配列を読む（省略時は nil、配列でない・文字列以外を含むなら ok=false）
+// デコード結果は []any（MessagePack / JSON）ですが、サーバー内で作った []string も受け付けます。
+func stringList(v any) (list []string, ok bool) {
+	switch items := v.(type) {
+	case nil:
+		return nil, true
+	case []string:
+		return items, true
+	case []any:
+		list = make([]string, 0, len(items))
+		for _, item := range items {
+			s, ok := item.(string)
+			if !ok || s == "" {
+				return nil, false
+			}
+			list = append(list, s)
+		}
+		return list, true
+	}
+	return nil, false
+}
@@ -0,0 +1,102 @@
+// =============================================================================
+// ファイル: link_stats.go
+// 概要: link_stats メッセージ（ロボットとの回線品質の問い合わせ）の処理
+//
+// 遠隔操作の画面が「回線が悪くなっている」ことをオペレーターに示すためのものです。
+// 操作が効かなくなる前に、遅延の増加やパケットロスに気づけるようにします。
+// get_diagnostics と同じく、ロボットへの問い合わせにはタイムアウトを設けます。
+// =============================================================================
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"go.uber.org/zap"
+)
+
+// linkStatsTimeout: 回線品質の取得を待つ最大時間
+const linkStatsTimeout = 2 * time.Second
+
+// linkStatsResult: 回線品質を取得するゴルーチンの結果
+type linkStatsResult struct {
+	stats adapter.LinkStats
+	err   error
+}
+
+// =============================================================================
+// handleLinkStats - ロボットとの回線品質を返す
+// =============================================================================
+//
+// 【応答の形（Payload）】
+//
+//	対応している場合:   {"supported": true, "rtt_ms": 12.5, "packet_loss": 0.02,
+//	                     "last_contact_ms": 1700000000000, "contact_age_ms": 40, "estimated": true}
+//	対応していない場合: {"supported": false}
+//
+// last_contact_ms は最後にロボットから応答があった時刻（Unix ミリ秒）、contact_age_ms は
+// そこからの経過時間です。一度も応答がなければどちらも省略します。
+// estimated が true の値は実測ではなく、コマンドやセンサーのタイミングからの推定値です。
+func (h *Handler) handleLinkStats(client *Client, msg *protocol.Message) {
+	if !client.Authenticated {
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeNotAuthenticated, "Not authenticated")
+		return
+	}
+	if msg.RobotID == "" {
+		h.sendError(client, "", "robot_id is required")
+		return
+	}
+
+	adp, ok := h.registry.GetAdapter(msg.RobotID)
+	if !ok {
+		h.sendError(client, msg.RobotID, "Robot not found")
+		return
+	}
+	if !h.ensureConnected(client, msg.RobotID, adp) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), linkStatsTimeout)
+	defer cancel()
+
+	// アダプターが固まっても打ち切れるよう、ゴルーチンで呼ぶ（handleGetDiagnostics と同じ）
+	resultCh := make(chan linkStatsResult, 1)
+	go func() {
+		stats, err := adp.GetLinkStats(ctx)
+		resultCh <- linkStatsResult{stats: stats, err: err}
+	}()
+
+	var result linkStatsResult
+	select {
+	case result = <-resultCh:
+	case <-ctx.Done():
+		result.err = ctx.Err()
+	}
+
+	response := protocol.NewMessage(protocol.MsgTypeLinkStats, msg.RobotID)
+	switch {
+	case errors.Is(result.err, adapter.ErrNotSupported):
+		response.Payload["supported"] = false
+	case errors.Is(result.err, context.DeadlineExceeded):
+		h.logger.Warn("Link stats request timed out", zap.String("robot_id", msg.RobotID))
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeAdapterTimeout, "Robot did not return link stats in time")
+		return
+	case result.err != nil:
+		h.sendError(client, msg.RobotID, "Failed to get link stats: "+result.err.Error())
+		return
+	default:
+		stats := result.stats
+		response.Payload["supported"] = true
+		response.Payload["rtt_ms"] = stats.RoundTripMs
+		response.Payload["packet_loss"] = stats.PacketLoss
+		response.Payload["estimated"] = stats.Estimated
+		if !stats.LastContact.IsZero() {
+			response.Payload["last_contact_ms"] = stats.LastContact.UnixMilli()
+			response.Payload["contact_age_ms"] = time.Since(stats.LastContact).Milliseconds()
+		}
+	}
+	h.sendToClient(client, response)
+}
@@ -0,0 +1,73 @@
+// =============================================================================
+// ファイル: lock_mode.go
+// 概要: ロックを持たずに操作コマンドを送った時の扱い（auto / strict）
+//
+// 【背景】
+// 速度コマンド・dock / undock・reset_pose は、送信者がロックを持っていなければ
+// その場でロックを取得していました。空いているロボットなら、コマンドを1つ送るだけで
+// 誰でも操作権を取れるため、共有の環境では「うっかり他人のロボットを動かす」原因になります。
+//
+// 【モード】
+//
+//	auto:   ロックを持っていなければ取得して実行する（デフォルト、従来の動作）
+//	strict: 先に op_lock でロックを取得していなければ、lock_required のエラーで拒否する
+//
+// どちらのモードでも、他のユーザーが保持しているロックは奪えません。
+// =============================================================================
+package server
+
+import (
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"go.uber.org/zap"
+)
+
+// LockMode - ロックを持たずに操作コマンドを送った時の扱い
+type LockMode string
+
+const (
+	LockModeAuto   LockMode = "auto"   // その場でロックを取得する
+	LockModeStrict LockMode = "strict" // 明示的な op_lock を求める
+)
+
+// ParseLockMode - 文字列をモードに変換する（不明な値なら ok が false）
+func ParseLockMode(s string) (mode LockMode, ok bool) {
+	switch mode = LockMode(s); mode {
+	case LockModeAuto, LockModeStrict:
+		return mode, true
+	}
+	return "", false
+}
+
+// SetLockMode - ロックを持たずに操作コマンドを送った時の扱いを設定する
+// 設定しなければ LockModeAuto（従来の動作）です。
+// サーバー起動前（メッセージを受け付ける前）に一度だけ呼んでください。
+func (h *Handler) SetLockMode(mode LockMode) {
+	h.lockMode = mode
+}
+
+// ensureLock - client のユーザーが robotID のロックを持っているようにする
+//
+// 持っていなければ、auto ではその場で取得し、strict では lock_required のエラーを返します。
+// 操作を続けてよければ true を返します（false ならエラーは送信済み）。
+func (h *Handler) ensureLock(client *Client, robotID string) bool {
+	if h.opLock.CheckLock(robotID, client.UserID) {
+		return true
+	}
+
+	if h.lockMode == LockModeStrict {
+		h.logger.Info("Command rejected: operation lock required",
+			zap.String("robot_id", robotID),
+			zap.String("user_id", client.UserID),
+		)
+		h.sendErrorCode(client, robotID, protocol.ErrCodeLockRequired,
+			"Operation lock required: send op_lock before controlling the robot")
+		return false
+	}
+
+	// ロックを持っていない場合、取得を試みる
+	if _, err := h.opLock.Acquire(robotID, client.UserID); err != nil {
+		h.sendError(client, robotID, "Operation locked: "+err.Error())
+		return false
+	}
+	return true
+}
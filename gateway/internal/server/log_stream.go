@@ -0,0 +1,239 @@
+// =============================================================================
+// ファイル: log_stream.go
+// 概要: ゲートウェイのログを、管理者のWebSocketクライアントへリアルタイムに配信する
+//
+// 【背景】
+// 現場での不具合調査のたびに SSH でサーバーに入り、ログを追うのは手間がかかります。
+// 管理者が UI からログを購読（log_stream）できれば、その場で原因を追えます。
+//
+// 【仕組み】
+//
+//	zap.Logger ──(Tee)──→ 通常の出力（stdout）
+//	               └────→ logStreamCore ──(チャネル)──→ LogStream の配信ゴルーチン ──→ Hub.BroadcastLog
+//
+// logStreamCore はログをチャネルに入れるだけで、満杯なら捨てます（ブロックしない）。
+// クライアントが遅くても、ログを書いた側（ロボット制御の処理）が待たされることはありません。
+// 購読者がいない間は Enabled が false になり、ログのコピーも作りません。
+// =============================================================================
+package server
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// LogFilter - ログ購読の絞り込み条件
+type LogFilter struct {
+	Level   zapcore.Level // このレベル以上のログだけを受け取る
+	RobotID string        // 空でなければ、robot_id フィールドがこのロボットのログだけを受け取る
+}
+
+// matches - ログがこの条件に合うか
+func (f *LogFilter) matches(level zapcore.Level, robotID string) bool {
+	return level >= f.Level && (f.RobotID == "" || f.RobotID == robotID)
+}
+
+// logRecord - チャネルで配信ゴルーチンに渡す1件のログ
+type logRecord struct {
+	entry   zapcore.Entry
+	fields  map[string]any
+	robotID string
+}
+
+// =============================================================================
+// LogStream - ログをチャネル経由で購読者に配信する
+// =============================================================================
+//
+// ロガーより先に作り（WrapCore でロガーに組み込む）、Hub ができてから Start します。
+type LogStream struct {
+	entries chan logRecord
+	hub     atomic.Pointer[Hub] // Start で設定される配信先
+	codec   protocol.Codec
+	dropped atomic.Uint64 // チャネルが満杯で捨てたログの数
+}
+
+// NewLogStream - LogStream を作成する
+// bufferSize は配信待ちにできるログの数で、これを超えた分は捨てます。
+func NewLogStream(bufferSize int) *LogStream {
+	return &LogStream{
+		entries: make(chan logRecord, bufferSize),
+		codec:   protocol.NewCodec(),
+	}
+}
+
+// WrapCore - ロガーのコアに、ログ配信用のコアを並べる（zap.WrapCore に渡す）
+// 配信されるのは、元のコアで有効なレベルのログだけです（GATEWAY_LOG_LEVEL に従う）。
+func (s *LogStream) WrapCore(core zapcore.Core) zapcore.Core {
+	return zapcore.NewTee(core, &logStreamCore{LevelEnabler: core, stream: s})
+}
+
+// Dropped - チャネルが満杯で捨てたログの数を返す
+func (s *LogStream) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// =============================================================================
+// Start - チャネルのログを購読者に配信するゴルーチンを開始する
+// =============================================================================
+//
+// 配信中はログを出しません（配信のログがさらに配信される、という連鎖を防ぐため）。
+func (s *LogStream) Start(ctx context.Context, hub *Hub, wg *sync.WaitGroup) {
+	s.hub.Store(hub)
+	if wg != nil {
+		wg.Add(1)
+	}
+	go func() {
+		if wg != nil {
+			defer wg.Done()
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case rec := <-s.entries:
+				s.deliver(hub, rec)
+			}
+		}
+	}()
+}
+
+// deliver - 1件のログを log_entry メッセージにして、条件の合う購読者に送る
+func (s *LogStream) deliver(hub *Hub, rec logRecord) {
+	msg := protocol.NewMessage(protocol.MsgTypeLogEntry, rec.robotID)
+	msg.Payload["level"] = rec.entry.Level.String()
+	msg.Payload["time_ms"] = rec.entry.Time.UnixMilli()
+	msg.Payload["logger"] = rec.entry.LoggerName
+	msg.Payload["message"] = rec.entry.Message
+	if rec.entry.Caller.Defined {
+		msg.Payload["caller"] = rec.entry.Caller.TrimmedPath()
+	}
+	msg.Payload["fields"] = rec.fields
+
+	data, err := s.codec.Encode(msg)
+	if err != nil {
+		return
+	}
+	hub.BroadcastLog(rec.entry.Level, rec.robotID, data)
+}
+
+// =============================================================================
+// logStreamCore - ログをチャネルに入れる zapcore.Core
+// =============================================================================
+//
+// 【Go言語の知識: zapcore.Core インターフェース】
+//
+//	Enabled: そのレベルのログを処理するか
+//	With:    固定のフィールド（logger.With(...)）を付けたコアを返す
+//	Check:   処理するなら、このコアを CheckedEntry に登録する
+//	Write:   実際にログを書き出す
+//	Sync:    バッファを書き出す
+type logStreamCore struct {
+	zapcore.LevelEnabler
+	stream *LogStream
+	fields []zapcore.Field
+}
+
+func (c *logStreamCore) Enabled(lvl zapcore.Level) bool {
+	hub := c.stream.hub.Load()
+	return hub != nil && hub.HasLogSubscribers() && c.LevelEnabler.Enabled(lvl)
+}
+
+func (c *logStreamCore) With(fields []zapcore.Field) zapcore.Core {
+	// 元のスライスを書き換えないよう、コピーしてから追加する
+	merged := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	merged = append(merged, c.fields...)
+	merged = append(merged, fields...)
+	return &logStreamCore{LevelEnabler: c.LevelEnabler, stream: c.stream, fields: merged}
+}
+
+func (c *logStreamCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *logStreamCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range c.fields {
+		f.AddTo(enc)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	robotID, _ := enc.Fields["robot_id"].(string)
+
+	// 満杯なら捨てる（ログを書いた側を待たせない）
+	select {
+	case c.stream.entries <- logRecord{entry: ent, fields: enc.Fields, robotID: robotID}:
+	default:
+		c.stream.dropped.Add(1)
+	}
+	return nil
+}
+
+func (c *logStreamCore) Sync() error {
+	return nil
+}
+
+// =============================================================================
+// handleLogStream - ハンドラーとの接続
+// =============================================================================
+
+// handleLogStream - ログの購読（管理者のみ）を切り替える
+//
+// 【Payload】
+//
+//	{"enabled": true, "level": "warn"}  // warn 以上を購読（robot_id を指定するとそのロボットのログだけ）
+//	{"enabled": false}                  // 購読を解除
+//
+// level の省略時は "info" です。応答は cmd_ack（command: "log_stream"）で、
+// 以後のログは log_entry メッセージで届きます。
+func (h *Handler) handleLogStream(client *Client, msg *protocol.Message) {
+	if !client.Authenticated {
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeNotAuthenticated, "Not authenticated")
+		return
+	}
+	if !h.isAdmin(client) {
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeForbidden, "log_stream is only available to admin users")
+		return
+	}
+
+	enabled := true
+	if v, ok := msg.Payload["enabled"].(bool); ok {
+		enabled = v
+	}
+
+	var filter *LogFilter
+	if enabled {
+		levelName, _ := msg.Payload["level"].(string)
+		if levelName == "" {
+			levelName = "info"
+		}
+		level, err := zapcore.ParseLevel(levelName)
+		if err != nil {
+			h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeInvalidMessage, "Invalid log level: "+levelName)
+			return
+		}
+		filter = &LogFilter{Level: level, RobotID: msg.RobotID}
+	}
+	h.hub.SetLogSubscription(client, filter)
+
+	h.logger.Info("Log stream subscription changed",
+		zap.String("client_id", client.ID),
+		zap.Bool("enabled", enabled),
+	)
+
+	ack := protocol.NewMessage(protocol.MsgTypeCommandAck, msg.RobotID)
+	ack.Payload["command"] = "log_stream"
+	ack.Payload["enabled"] = enabled
+	if filter != nil {
+		ack.Payload["level"] = filter.Level.String()
+	}
+	h.sendToClient(client, ack)
+}
@@ -0,0 +1,108 @@
+// =============================================================================
+// ファイル: odometry_path.go
+// 概要: odometry_path メッセージ（ロボットが通った経路の問い合わせ）の処理
+//
+// Redis に記録済みのオドメトリを時間範囲で読み出し、姿勢の列として返します。
+// UI の経路オーバーレイ表示のためのもので、フロントエンドが長時間
+// sensor_data を購読して溜め込む必要がなくなります。
+// =============================================================================
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/bridge"
+	"github.com/robot-ai-webapp/gateway/internal/convert"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"go.uber.org/zap"
+)
+
+// 経路の問い合わせのパラメータ
+const (
+	defaultPathWindow    = 60 * time.Second // from_ms を省略した時の範囲（直近60秒）
+	maxPathWindow        = time.Hour        // 一度に読み出せる範囲の上限
+	defaultPathMaxPoints = 500              // max_points を省略した時の点数
+	maxPathMaxPoints     = 2000             // max_points の上限
+	pathQueryTimeout     = 3 * time.Second  // Redis の読み出しのタイムアウト
+)
+
+// odometryPathReader - 記録済みのオドメトリを読み出せる Publisher（RedisPublisher が実装）
+type odometryPathReader interface {
+	OdometryPath(ctx context.Context, robotID string, from, to time.Time, maxPoints int) ([]bridge.PathPose, error)
+}
+
+// =============================================================================
+// handleOdometryPath - ロボットが通った経路を返す
+// =============================================================================
+//
+// 【リクエストの Payload（すべて省略可）】
+//
+//	from_ms / to_ms: 時間範囲（Unix ミリ秒）。省略時は直近60秒。範囲は最大1時間
+//	max_points:      返す点の上限（省略時 500、最大 2000）。超える分は等間隔に間引く
+//
+// 【応答の形（Payload）】
+//
+//	{"poses": [{"t": 1739600000000, "x": 1.2, "y": 0.4, "theta": 0.1}, ...], "count": 120}
+//
+// Redis なしで起動している場合は記録がないため、エラーを返します。
+func (h *Handler) handleOdometryPath(client *Client, msg *protocol.Message) {
+	if !client.Authenticated {
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeNotAuthenticated, "Not authenticated")
+		return
+	}
+	if msg.RobotID == "" {
+		h.sendError(client, "", "robot_id is required")
+		return
+	}
+
+	reader, ok := h.currentPublisher().(odometryPathReader)
+	if !ok {
+		h.sendError(client, msg.RobotID, "Odometry history is unavailable without Redis")
+		return
+	}
+
+	to := time.Now()
+	if v, ok := msg.Payload["to_ms"]; ok {
+		ms, _ := convert.ToFloat64(v)
+		to = time.UnixMilli(int64(ms))
+	}
+	from := to.Add(-defaultPathWindow)
+	if v, ok := msg.Payload["from_ms"]; ok {
+		ms, _ := convert.ToFloat64(v)
+		from = time.UnixMilli(int64(ms))
+	}
+	if !from.Before(to) || to.Sub(from) > maxPathWindow {
+		h.sendError(client, msg.RobotID, "Invalid time range: from_ms must be before to_ms and within 1 hour")
+		return
+	}
+
+	maxPoints := defaultPathMaxPoints
+	if v, ok := msg.Payload["max_points"]; ok {
+		n, _ := convert.ToFloat64(v)
+		maxPoints = min(max(int(n), 2), maxPathMaxPoints)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pathQueryTimeout)
+	defer cancel()
+	poses, err := reader.OdometryPath(ctx, msg.RobotID, from, to, maxPoints)
+	if err != nil {
+		h.logger.Warn("Failed to read odometry path",
+			zap.String("robot_id", msg.RobotID),
+			zap.Error(err),
+		)
+		h.sendError(client, msg.RobotID, "Failed to read odometry history")
+		return
+	}
+
+	// MessagePack でそのまま送れるよう、map のスライスに詰め替える
+	out := make([]map[string]any, len(poses))
+	for i, p := range poses {
+		out[i] = map[string]any{"t": p.Timestamp, "x": p.X, "y": p.Y, "theta": p.Theta}
+	}
+
+	response := protocol.NewMessage(protocol.MsgTypeOdometryPath, msg.RobotID)
+	response.Payload["poses"] = out
+	response.Payload["count"] = len(out)
+	h.sendToClient(client, response)
+}
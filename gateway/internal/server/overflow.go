@@ -0,0 +1,224 @@
+// =============================================================================
+// ファイル: overflow.go
+// 概要: クライアントへの送信キューが満杯になった時の扱い（メッセージの種類ごとの方針）
+//
+// 【背景】
+// 以前は、Send バッファが満杯になると種類に関係なく「新しいメッセージを捨てる」だけでした。
+// センサーデータで埋まったクライアントには、E-Stop のアラートや ACK まで届かなくなります。
+// しかも捨てたことはクライアントにはわかりません。
+//
+// 【メッセージの種類（MessageClass）と方針（OverflowPolicy）】
+//
+//	種類       対象                                              選べる方針
+//	sensor     sensor_data / sensor_batch                        drop_oldest, drop_newest, disconnect
+//	critical   安全アラート、ACK、エラー、状態の通知、問い合わせの応答  block, disconnect, drop_newest
+//	bulk       ログ（log_entry）、コマンドの写し（command_observed）  drop_newest, disconnect
+//
+//	drop_oldest: 溜まっている一番古いものを捨てて入れる（最新のデータを優先する）
+//	drop_newest: 入れずに捨てる（以前の動作）
+//	block:       空くまで最大 BlockTimeout 待ち、空かなければ切断する（黙って捨てない）
+//	             待つ間は Hub のロックを持ったままなので、他のクライアントへの配信や
+//	             接続・切断の登録も止まる。全員への配信では遅いクライアントの数だけ待ちが重なる
+//	disconnect:  すぐに切断する（クライアントは再接続して状態を取り直す）
+//
+// 【センサーデータ専用のキュー】
+// WebSocket の接続（HandleWebSocket）には、Send とは別にセンサーデータ専用のキュー
+// （sensorQueue）を作ります。センサーデータはこちらに入るため、どれだけ溜まっても
+// Send（アラートや ACK）を押し出しません。drop_oldest はこのキューをリングバッファのように使います。
+// sensorQueue のないクライアント（テスト用など）には Send に入れ、drop_oldest は drop_newest として扱います
+// （Send の古いものを捨てると、アラートを捨ててしまうため）。
+// 2つのキューの間では順番は保証されません（センサーデータ同士、その他同士の順番は保たれます）。
+//
+// 【切断】
+// 切断は Close フレーム 1013（Try Again Later）、理由 "send buffer overflow" で行います。
+// 配信処理は Hub のロックを持っているため、切断は別のゴルーチンで行います。
+// 切断が決まったクライアントへのそれ以降のメッセージは、待たずに捨てます。
+// =============================================================================
+package server
+
+import (
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/robot-ai-webapp/gateway/internal/recovery"
+	"go.uber.org/zap"
+)
+
+// MessageClass - 送信キューが満杯の時の扱いを決めるメッセージの種類
+type MessageClass string
+
+const (
+	// ClassSensor: センサーデータ（sensor_data / sensor_batch）
+	ClassSensor MessageClass = "sensor"
+	// ClassCritical: 安全アラート、ACK、エラー、状態の通知、問い合わせの応答
+	ClassCritical MessageClass = "critical"
+	// ClassBulk: ログやコマンドの写しなど、失っても困らない大量のメッセージ
+	ClassBulk MessageClass = "bulk"
+)
+
+// OverflowPolicy - 送信キューが満杯の時の方針
+type OverflowPolicy string
+
+const (
+	// OverflowDropNewest: 新しいメッセージを捨てる（デフォルト、以前の動作）
+	OverflowDropNewest OverflowPolicy = "drop_newest"
+	// OverflowDropOldest: 一番古いメッセージを捨てて新しいものを入れる（sensor のみ）
+	OverflowDropOldest OverflowPolicy = "drop_oldest"
+	// OverflowBlock: 最大 BlockTimeout 待ち、空かなければ切断する（critical のみ）
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowDisconnect: すぐに切断する
+	OverflowDisconnect OverflowPolicy = "disconnect"
+)
+
+// overflowCloseReason: 送信キューが溢れて切断する時の Close フレームの理由
+const overflowCloseReason = "send buffer overflow"
+
+// sensorQueueSize: WebSocket の接続ごとのセンサーデータ専用キューの長さ（Send と同じ）
+const sensorQueueSize = 256
+
+// =============================================================================
+// OverflowPolicies - メッセージの種類ごとの方針
+// =============================================================================
+//
+// 空の方針は drop_newest として扱います（ゼロ値は以前の動作と同じ）。
+type OverflowPolicies struct {
+	Sensor   OverflowPolicy
+	Critical OverflowPolicy
+	Bulk     OverflowPolicy
+
+	// BlockTimeout: block の方針で、キューが空くのを待つ最長時間
+	// 配信処理は Hub のロックを持ったまま待ち、その間は全クライアントへの配信と
+	// Register / Unregister が止まります。遅いクライアントが複数いると待ちはその数だけ重なるため、
+	// 短く（数十ミリ秒）してください。
+	BlockTimeout time.Duration
+}
+
+// policy: 種類に対応する方針（空なら drop_newest）
+func (p OverflowPolicies) policy(class MessageClass) OverflowPolicy {
+	var policy OverflowPolicy
+	switch class {
+	case ClassSensor:
+		policy = p.Sensor
+	case ClassCritical:
+		policy = p.Critical
+	case ClassBulk:
+		policy = p.Bulk
+	}
+	if policy == "" {
+		return OverflowDropNewest
+	}
+	return policy
+}
+
+// SetOverflowPolicies - 送信キューが満杯の時の方針を設定する
+// Run の前（クライアントが接続する前）に一度だけ呼んでください。
+func (h *Hub) SetOverflowPolicies(p OverflowPolicies) {
+	h.overflow = p
+}
+
+// DroppedMessages - 送信キューが満杯で捨てたメッセージの数（種類ごと、起動からの累計）
+func (h *Hub) DroppedMessages(class MessageClass) uint64 {
+	switch class {
+	case ClassSensor:
+		return h.droppedSensor.Load()
+	case ClassCritical:
+		return h.droppedCritical.Load()
+	case ClassBulk:
+		return h.droppedBulk.Load()
+	}
+	return 0
+}
+
+// =============================================================================
+// enqueue - 1件のメッセージを、種類の方針に従って client の送信キューに入れる
+// =============================================================================
+//
+// h.mu を保持した状態（読み取りロックでよい）で呼んでください（Send が閉じられていないことの保証）。
+// warn が false なら、捨てた時の警告ログを出しません（ログ配信の連鎖を防ぐため）。
+// キューに入れられたら true を返します。
+func (h *Hub) enqueue(client *Client, class MessageClass, data []byte, warn bool) bool {
+	// 切断が決まったクライアントは待たずに捨てる（block で何度も待たないように）
+	if client.overflowed.Load() {
+		h.countDropped(client, class)
+		return false
+	}
+
+	queue := client.Send
+	if class == ClassSensor && client.sensorQueue != nil {
+		queue = client.sensorQueue
+	}
+	select {
+	case queue <- data:
+		return true
+	default:
+	}
+
+	switch policy := h.overflow.policy(class); {
+	case policy == OverflowDropOldest && queue != client.Send:
+		// リングバッファのように、古いものを捨てて入れる。
+		// 他の配信ワーカーも同時に入れることがあるため、入るまで繰り返す。
+		for {
+			select {
+			case <-queue:
+				h.countDropped(client, class)
+			default:
+			}
+			select {
+			case queue <- data:
+				return true
+			default:
+			}
+		}
+
+	case policy == OverflowBlock:
+		timer := time.NewTimer(h.overflow.BlockTimeout)
+		defer timer.Stop()
+		select {
+		case queue <- data:
+			return true
+		case <-timer.C:
+		}
+		h.disconnectOverflowed(client, class)
+
+	case policy == OverflowDisconnect:
+		h.disconnectOverflowed(client, class)
+
+	default:
+		if warn {
+			h.logger.Warn("Client send buffer full",
+				zap.String("client_id", client.ID),
+				zap.String("class", string(class)),
+			)
+		}
+	}
+	h.countDropped(client, class)
+	return false
+}
+
+// countDropped: 捨てたメッセージを数える
+func (h *Hub) countDropped(client *Client, class MessageClass) {
+	client.dropped.Add(1)
+	switch class {
+	case ClassSensor:
+		h.droppedSensor.Add(1)
+	case ClassCritical:
+		h.droppedCritical.Add(1)
+	case ClassBulk:
+		h.droppedBulk.Add(1)
+	}
+}
+
+// disconnectOverflowed: 送信キューが溢れたクライアントを切断する（1回だけ）
+// 呼び出し元は h.mu を持っているため、Disconnect（h.mu を取る）は別のゴルーチンで行う。
+func (h *Hub) disconnectOverflowed(client *Client, class MessageClass) {
+	if !client.overflowed.CompareAndSwap(false, true) {
+		return
+	}
+	h.logger.Warn("Disconnecting client: send buffer overflow",
+		zap.String("client_id", client.ID),
+		zap.String("class", string(class)),
+	)
+	recovery.Go(h.logger, "hub_overflow_disconnect", func() {
+		h.Disconnect(client, websocket.CloseTryAgainLater, overflowCloseReason)
+	})
+}
@@ -0,0 +1,59 @@
+// =============================================================================
+// ファイル: protocol_version.go
+// 概要: 認証時のプロトコルのバージョンの取り決め（auth の "protocol_version"）
+//
+// 取り決めのルールは protocol.NegotiateVersion を参照してください。
+// 取り決めたバージョンは Client.ProtocolVersion に入り、conn_status で
+// 対応範囲と一緒にクライアントへ返します。
+// =============================================================================
+package server
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/robot-ai-webapp/gateway/internal/convert"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"go.uber.org/zap"
+)
+
+// negotiateProtocolVersion: auth の "protocol_version" から使うバージョンを決める
+// 受け付けられなければエラーを送り、ok=false を返します（クライアントは未認証のまま）。
+func (h *Handler) negotiateProtocolVersion(client *Client, msg *protocol.Message) (version int, warning string, ok bool) {
+	requested := 0
+	if v, present := msg.Payload["protocol_version"]; present {
+		f, isNumber := convert.ToFloat64(v)
+		if !isNumber || f < 1 || f != math.Trunc(f) || f > math.MaxInt32 {
+			h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeInvalidMessage,
+				fmt.Sprintf("Invalid protocol_version: %v (expected a positive integer)", v))
+			return 0, "", false
+		}
+		requested = int(f)
+	}
+
+	version, warning, err := protocol.NegotiateVersion(requested)
+	var unsupported *protocol.UnsupportedVersionError
+	if errors.As(err, &unsupported) {
+		h.logger.Warn("Client protocol version not supported",
+			zap.String("client_id", client.ID),
+			zap.Int("requested", requested),
+		)
+		resp := protocol.NewMessage(protocol.MsgTypeError, msg.RobotID)
+		resp.Error = err.Error()
+		resp.Payload["code"] = protocol.ErrCodeUnsupportedProtocolVersion
+		resp.Payload["min_protocol_version"] = protocol.MinProtocolVersion
+		resp.Payload["max_protocol_version"] = protocol.ProtocolVersion
+		client.errorsSent.Add(1)
+		h.sendToClient(client, resp)
+		return 0, "", false
+	}
+	if warning != "" {
+		h.logger.Info("Client protocol version negotiated with a warning",
+			zap.String("client_id", client.ID),
+			zap.Int("requested", requested),
+			zap.Int("version", version),
+		)
+	}
+	return version, warning, true
+}
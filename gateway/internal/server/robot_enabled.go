@@ -0,0 +1,104 @@
+// =============================================================================
+// ファイル: robot_enabled.go
+// 概要: ロボットを一時的に運用から外す／戻す（set_robot_enabled、管理者のみ）
+//
+// 【仕組み】
+//
+//	set_robot_enabled {"enabled": false} ──→ ロボットを止める（速度 0 とナビゲーションの中止）
+//	                                     ──→ Registry.SetEnabled（以後のコマンドは robot_disabled で拒否）
+//	                                     ──→ safety_alert（"robot_enabled_changed"）で購読者に通知
+//
+// アダプターの接続はそのままなので、{"enabled": true} ですぐに運用へ戻せます。
+// センサーデータの転送を止めるかは設定で選びます（SensorFanout.SetRobotEnabled）。
+// E-Stop と stop_all は、無効にしたロボットにも常に届きます。
+// =============================================================================
+package server
+
+import (
+	"errors"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"go.uber.org/zap"
+)
+
+// =============================================================================
+// handleSetRobotEnabled - ロボットを有効／無効にする
+// =============================================================================
+//
+// 【Payload】
+//
+//	{"enabled": false}  // 運用から外す
+//	{"enabled": true}   // 運用に戻す
+//
+// 応答は cmd_ack（command: "set_robot_enabled"）で、現在の状態（"enabled"）が入ります。
+// 同じ状態を指定した場合も ack を返しますが、通知は送りません。
+func (h *Handler) handleSetRobotEnabled(client *Client, msg *protocol.Message) {
+	if !client.Authenticated {
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeNotAuthenticated, "Not authenticated")
+		return
+	}
+	if !h.isAdmin(client) {
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeForbidden, "set_robot_enabled is only available to admin users")
+		return
+	}
+	robotID := msg.RobotID
+	if robotID == "" {
+		h.sendError(client, "", "Missing robot_id")
+		return
+	}
+	enabled, ok := msg.Payload["enabled"].(bool)
+	if !ok {
+		h.sendErrorCode(client, robotID, protocol.ErrCodeInvalidMessage, "set_robot_enabled requires enabled (true or false)")
+		return
+	}
+
+	// 先に無効にしてコマンドを締め出してから止める
+	// （止めてから無効にすると、その間に届いた速度コマンドで動き出しうる）。
+	changed, err := h.registry.SetEnabled(robotID, enabled)
+	if errors.Is(err, adapter.ErrAdapterNotFound) {
+		h.sendError(client, robotID, "Robot not found")
+		return
+	}
+	if changed && !enabled {
+		// 保留中の速度コマンドとウォッチドッグの減速途中の速度が、停止の後に送られないようにする
+		h.coalescer.Drop(robotID)
+		h.cancelRampDown(robotID)
+		if adp, ok := h.registry.GetAdapter(robotID); ok {
+			if err := stopRobot(adp, robotID); err != nil {
+				h.logger.Warn("Failed to stop robot while disabling it",
+					zap.String("robot_id", robotID),
+					zap.Error(err),
+				)
+			}
+		}
+		h.setLastVelocity(robotID, adapter.Velocity{})
+	}
+
+	if changed {
+		h.logger.Warn("Robot enabled state changed by operator",
+			zap.String("robot_id", robotID),
+			zap.String("user_id", client.UserID),
+			zap.Bool("enabled", enabled),
+		)
+		alert := protocol.NewMessage(protocol.MsgTypeSafetyAlert, robotID)
+		alert.Payload["type"] = "robot_enabled_changed"
+		alert.Payload["enabled"] = enabled
+		alert.Payload["user_id"] = client.UserID
+		h.broadcastAlert(alert)
+	}
+
+	ack := protocol.NewMessage(protocol.MsgTypeCommandAck, robotID)
+	ack.Payload["command"] = "set_robot_enabled"
+	ack.Payload["enabled"] = enabled
+	h.sendToClient(client, ack)
+}
+
+// robotEnabled: ロボットが有効か確認する（無効なら robot_disabled のエラーを返して false）
+func (h *Handler) robotEnabled(client *Client, robotID string) bool {
+	if h.registry.IsEnabled(robotID) {
+		return true
+	}
+	h.sendErrorCode(client, robotID, protocol.ErrCodeRobotDisabled, "Robot "+robotID+" is disabled")
+	return false
+}
@@ -0,0 +1,119 @@
+// =============================================================================
+// ファイル: robot_event.go
+// 概要: ロボットが自分から知らせるイベント（adapter.RobotEvent）をオペレーターに届ける処理
+//
+// 【流れ】
+//
+//	アダプター.EventChannel() → NotifyRobotEvent
+//	  ├─ robot_event としてそのロボットの購読者に配信
+//	  ├─ Redis のイベントストリーム（robot:events）に記録
+//	  └─ 物理 E-Stop（estop_pressed）なら、ソフトウェアの E-Stop もラッチする
+//
+// 本体の非常停止ボタンが押されても、ゲートウェイの E-Stop が解除されたままだと、
+// ボタンを戻した瞬間に保留中のコマンドで動き出しかねません。
+// そのためソフトウェアの E-Stop もラッチし、解除はオペレーターの操作（estop の activate: false）に任せます。
+// ボタンの解除（estop_released）では、ソフトウェアの E-Stop は解除しません。
+// =============================================================================
+package server
+
+import (
+	"context"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"go.uber.org/zap"
+)
+
+// robotEventUserID: 物理 E-Stop によるソフトウェアの E-Stop の発動者として記録する名前
+const robotEventUserID = "robot"
+
+// robotEventPublisher: ロボットからのイベントを記録できる publisher（bridge.RedisPublisher が満たす）
+type robotEventPublisher interface {
+	PublishRobotEvent(ctx context.Context, robotID string, ev adapter.RobotEvent) error
+}
+
+// =============================================================================
+// NotifyRobotEvent - ロボットからのイベントを配信・記録し、物理 E-Stop をラッチする
+// =============================================================================
+//
+// 【配信の形（Payload）】
+//
+//	{"event_type": "bumper_hit", "severity": "warning", "message": "...", "data": {...}, "timestamp": 1700000000000}
+//
+// timestamp はミリ秒です（adapter.RobotEvent.Timestamp はナノ秒）。
+func (h *Handler) NotifyRobotEvent(ctx context.Context, ev adapter.RobotEvent) {
+	robotID := ev.RobotID
+	h.logger.Info("Robot event",
+		zap.String("robot_id", robotID),
+		zap.String("type", ev.Type),
+		zap.String("severity", ev.Severity),
+	)
+
+	if ev.Type == adapter.EventEStopPressed {
+		h.latchPhysicalEStop(ctx, robotID, ev)
+	}
+
+	msg := protocol.NewMessage(protocol.MsgTypeRobotEvent, robotID)
+	msg.Payload["event_type"] = ev.Type
+	msg.Payload["severity"] = ev.Severity
+	if ev.Message != "" {
+		msg.Payload["message"] = ev.Message
+	}
+	if ev.Data != nil {
+		msg.Payload["data"] = ev.Data
+	}
+	msg.Payload["timestamp"] = ev.Timestamp / 1e6
+	if data, err := h.codec.Encode(msg); err == nil {
+		h.hub.BroadcastToRobot(robotID, data)
+	} else {
+		h.logger.Error("Failed to encode robot event", zap.Error(err))
+	}
+
+	if publisher, ok := h.currentPublisher().(robotEventPublisher); ok {
+		if err := publisher.PublishRobotEvent(ctx, robotID, ev); err != nil {
+			h.logger.Warn("Failed to publish robot event",
+				zap.String("robot_id", robotID),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// latchPhysicalEStop: 本体の非常停止ボタンに合わせて、ソフトウェアの E-Stop を発動する
+// 既に発動中なら何もしません（発動者と理由は最初のものを残す）。
+// 停止コマンドの送信に失敗しても E-Stop はラッチされているので、estop_activated は必ず配信し、
+// 失敗の内容を stop_error に入れて現場の確認を促します。
+func (h *Handler) latchPhysicalEStop(ctx context.Context, robotID string, ev adapter.RobotEvent) {
+	// 最小間隔で保留中の速度コマンドが、停止の後に送られないようにする
+	h.coalescer.Drop(robotID)
+	h.cancelRampDown(robotID)
+	if h.estop == nil || h.estop.IsActive(robotID) {
+		return
+	}
+
+	reason := "physical E-Stop pressed"
+	if ev.Message != "" {
+		reason += ": " + ev.Message
+	}
+	err := h.estop.Activate(ctx, robotID, robotEventUserID, reason)
+	if err != nil {
+		h.logger.Error("Failed to send E-Stop on physical E-Stop",
+			zap.String("robot_id", robotID),
+			zap.Bool("latched", h.estop.IsActive(robotID)),
+			zap.Error(err),
+		)
+		if !h.estop.IsActive(robotID) {
+			return
+		}
+	}
+	h.ResetVelocityBaseline(robotID)
+
+	alert := protocol.NewMessage(protocol.MsgTypeSafetyAlert, robotID)
+	alert.Payload["type"] = "estop_activated"
+	alert.Payload["reason"] = reason
+	alert.Payload["user_id"] = robotEventUserID
+	if err != nil {
+		alert.Payload["stop_error"] = err.Error()
+	}
+	h.broadcastAlert(alert)
+}
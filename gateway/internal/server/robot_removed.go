@@ -0,0 +1,56 @@
+// =============================================================================
+// ファイル: robot_removed.go
+// 概要: レジストリからロボットが削除された時の後始末
+//
+// 【なぜ必要？】
+// ロボットがレジストリから削除されても、購読していたクライアントの Subscriptions と
+// Hub の購読の索引には、そのロボットが残り続けていました。
+// 画面は「接続中のロボット」を表示し続け、同じ ID のロボットが後から登録されると、
+// 購読し直していないのにデータが届き始めます。
+//
+// 【仕組み】
+//
+//	Registry.RemoveAdapter ──(SetRemoveCallback)──→ Handler.RobotRemoved
+//	    ├─→ 保留中の速度コマンド・相対速度の基準・操作者の記録・最新のセンサー値・速度のゲージを破棄
+//	    └─→ Hub.RemoveRobot: 購読者に conn_status（removed: true）を送り、全クライアントの購読から削除
+//
+// =============================================================================
+package server
+
+import (
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"go.uber.org/zap"
+)
+
+// =============================================================================
+// RobotRemoved - 削除されたロボットの購読を後始末する
+// =============================================================================
+//
+// 購読者には次の conn_status が届きます。クライアントはこれを見て、
+// そのロボットを画面から消すことができます。
+//
+//	{"robot_connected": false, "removed": true}
+func (h *Handler) RobotRemoved(robotID string) {
+	h.coalescer.Drop(robotID)
+	h.lastVelMu.Lock()
+	delete(h.lastVel, robotID)
+	delete(h.controllers, robotID)
+	delete(h.commanders, robotID)
+	h.lastVelMu.Unlock()
+	if h.sensorCache != nil {
+		h.sensorCache.RemoveRobot(robotID)
+	}
+	if h.robotMetrics != nil {
+		h.robotMetrics.RemoveRobot(robotID)
+	}
+
+	status := protocol.NewMessage(protocol.MsgTypeConnectionStatus, robotID)
+	status.Payload["robot_connected"] = false
+	status.Payload["removed"] = true
+	data, err := h.codec.Encode(status)
+	if err != nil {
+		h.logger.Error("Failed to encode robot removal", zap.String("robot_id", robotID), zap.Error(err))
+		data = nil // 通知できなくても、購読の後始末は行う
+	}
+	h.hub.RemoveRobot(robotID, data)
+}
@@ -0,0 +1,232 @@
+// =============================================================================
+// ファイル: selftest.go
+// 概要: 起動時のセルフテスト（トラフィックを受け付ける前に重要な経路を確認する）
+//
+// 【なぜ必要か？】
+// 速度上限の設定ミスやアダプターの登録漏れは、実際にユーザーがロボットを
+// 操作しようとした時に初めて発覚しがちです。起動直後に一度だけ、
+// 本番と同じ安全コンポーネントを使って一連の流れを試しておけば、
+// 本番のトラフィックが来る前に設定ミスに気づけます。
+//
+// 【確認する内容】
+//
+//	velocity_pipeline（重要）: 一時的なモックロボットに速度 0 のコマンドを送り、
+//	                           認証→E-Stop→操作ロック→速度制限→送信→ACK が通るか
+//	sensor_data（重要）     : 一時的なモックロボットからセンサーデータが届くか
+//	redis（重要でない）     : Redis が設定されていれば Ping が通るか
+//
+// 重要な確認が失敗した場合、呼び出し側（main）は起動を中止します。
+// 重要でない確認の失敗は警告ログだけを残します（Redis なしでも動作できるため）。
+//
+// 一時的なロボットは専用の Hub・ハンドラーで扱うため、実際のクライアントや
+// Redis にセルフテストのコマンドが流れることはありません。終了時に後片付けします。
+// =============================================================================
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/safety"
+	"go.uber.org/zap"
+)
+
+// セルフテストのパラメータ
+const (
+	selfTestAdapterType = "mock"     // 一時的なロボットに使うアダプターの種類
+	selfTestUserID      = "selftest" // 操作ロックを取得するユーザーID
+	selfTestTimeout     = 5 * time.Second
+)
+
+// =============================================================================
+// SelfTestCheck - 1項目の確認結果
+// =============================================================================
+type SelfTestCheck struct {
+	Name     string // 確認項目の名前（例: "velocity_pipeline"）
+	Critical bool   // 失敗したら起動を中止すべきか
+	Skipped  bool   // 対象が設定されていないため確認しなかったか
+	Err      error  // 失敗した理由（成功・スキップなら nil）
+}
+
+// SelfTestReport - セルフテスト全体の結果
+type SelfTestReport struct {
+	Checks []SelfTestCheck
+}
+
+// Passed - 重要な確認がすべて成功したかを返す
+func (r SelfTestReport) Passed() bool {
+	for _, c := range r.Checks {
+		if c.Critical && c.Err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// =============================================================================
+// SelfTest - 起動時のセルフテスト
+// =============================================================================
+type SelfTest struct {
+	registry  *adapter.Registry
+	estop     *safety.EStopManager
+	velLimit  *safety.VelocityLimiter
+	watchdog  *safety.TimeoutWatchdog
+	opLock    *safety.OperationLock
+	publisher RedisPublisher
+	logger    *zap.Logger
+}
+
+// NewSelfTest - コンストラクタ
+// 本番のハンドラーと同じ安全コンポーネントを渡します。publisher は Redis なしなら nil です。
+func NewSelfTest(
+	registry *adapter.Registry,
+	estop *safety.EStopManager,
+	velLimit *safety.VelocityLimiter,
+	watchdog *safety.TimeoutWatchdog,
+	opLock *safety.OperationLock,
+	publisher RedisPublisher,
+	logger *zap.Logger,
+) *SelfTest {
+	return &SelfTest{
+		registry:  registry,
+		estop:     estop,
+		velLimit:  velLimit,
+		watchdog:  watchdog,
+		opLock:    opLock,
+		publisher: publisher,
+		logger:    logger,
+	}
+}
+
+// =============================================================================
+// Run - すべての確認を実行し、結果を返す
+// =============================================================================
+//
+// 各確認の結果はログにも出力します（成功は Info、失敗は重要度に応じて Error / Warn）。
+func (s *SelfTest) Run(ctx context.Context) SelfTestReport {
+	ctx, cancel := context.WithTimeout(ctx, selfTestTimeout)
+	defer cancel()
+
+	var report SelfTestReport
+	pipelineErr, sensorErr := s.checkRobotPaths(ctx)
+	report.Checks = append(report.Checks,
+		SelfTestCheck{Name: "velocity_pipeline", Critical: true, Err: pipelineErr},
+		SelfTestCheck{Name: "sensor_data", Critical: true, Err: sensorErr},
+		s.checkRedis(ctx),
+	)
+
+	for _, c := range report.Checks {
+		switch {
+		case c.Skipped:
+			s.logger.Info("Self-test check skipped", zap.String("check", c.Name))
+		case c.Err == nil:
+			s.logger.Info("Self-test check passed", zap.String("check", c.Name))
+		case c.Critical:
+			s.logger.Error("Self-test check failed", zap.String("check", c.Name), zap.Error(c.Err))
+		default:
+			s.logger.Warn("Self-test check failed (non-critical)", zap.String("check", c.Name), zap.Error(c.Err))
+		}
+	}
+	return report
+}
+
+// checkRobotPaths - 一時的なモックロボットで、速度コマンドとセンサーデータの経路を確認する
+// 戻り値は (速度コマンドの経路のエラー, センサーデータの経路のエラー) です。
+func (s *SelfTest) checkRobotPaths(ctx context.Context) (error, error) {
+	robotID := fmt.Sprintf("selftest-%d", time.Now().UnixNano())
+
+	adp, err := s.registry.CreateAdapter(robotID, selfTestAdapterType)
+	if err != nil {
+		err = fmt.Errorf("create %s adapter: %w", selfTestAdapterType, err)
+		return err, err
+	}
+	defer s.teardown(robotID, adp)
+
+	if err := adp.Connect(ctx, map[string]any{}); err != nil {
+		err = fmt.Errorf("connect %s adapter: %w", selfTestAdapterType, err)
+		return err, err
+	}
+
+	return s.checkVelocityPipeline(robotID), waitForSensorData(ctx, adp)
+}
+
+// checkVelocityPipeline - 速度 0 のコマンドを本番と同じ安全パイプラインに通し、ACK を確認する
+// 専用の Hub・ハンドラー（dedup と publisher は nil）を使うため、外部には何も流れません。
+func (s *SelfTest) checkVelocityPipeline(robotID string) error {
+	h := NewHandler(NewHub(s.logger), s.registry, s.estop, s.velLimit, s.watchdog, s.opLock, nil, nil, s.logger)
+	client := &Client{
+		ID:            "selftest-client",
+		UserID:        selfTestUserID,
+		Send:          make(chan []byte, 4),
+		Subscriptions: map[string]bool{},
+		Authenticated: true,
+	}
+
+	msg := protocol.NewMessage(protocol.MsgTypeVelocityCommand, robotID)
+	msg.Payload["linear_x"] = 0.0
+	msg.Payload["linear_y"] = 0.0
+	msg.Payload["angular_z"] = 0.0
+	h.HandleMessage(client, msg)
+
+	select {
+	case data := <-client.Send:
+		resp, err := h.codec.Decode(data)
+		if err != nil {
+			return fmt.Errorf("decode response: %w", err)
+		}
+		if resp.Type != protocol.MsgTypeCommandAck {
+			return fmt.Errorf("expected %s, got %s: %s", protocol.MsgTypeCommandAck, resp.Type, resp.Error)
+		}
+		return nil
+	default:
+		return errors.New("no response to velocity command")
+	}
+}
+
+// waitForSensorData - アダプターからセンサーデータが1件届くまで待つ
+func waitForSensorData(ctx context.Context, adp adapter.RobotAdapter) error {
+	select {
+	case _, ok := <-adp.SensorDataChannel():
+		if !ok {
+			return errors.New("sensor data channel closed")
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("no sensor data received: %w", ctx.Err())
+	}
+}
+
+// checkRedis - Redis が設定されていれば Ping で疎通を確認する（重要でない確認）
+func (s *SelfTest) checkRedis(ctx context.Context) SelfTestCheck {
+	check := SelfTestCheck{Name: "redis"}
+	if s.publisher == nil {
+		check.Skipped = true
+		return check
+	}
+	pinger, ok := s.publisher.(redisPinger)
+	if !ok {
+		check.Skipped = true
+		return check
+	}
+	check.Err = pinger.Ping(ctx)
+	return check
+}
+
+// teardown - 一時的なロボットに関する状態をすべて片付ける
+// ウォッチドッグと操作ロックにも記録が残るため、アダプターと合わせて削除します。
+func (s *SelfTest) teardown(robotID string, adp adapter.RobotAdapter) {
+	if s.watchdog != nil {
+		s.watchdog.RemoveRobot(robotID)
+	}
+	if s.opLock != nil {
+		_ = s.opLock.Release(robotID, selfTestUserID)
+	}
+	if err := adp.Disconnect(context.Background()); err != nil {
+		s.logger.Warn("Failed to disconnect self-test adapter", zap.String("robot_id", robotID), zap.Error(err))
+	}
+	s.registry.RemoveAdapter(robotID)
+}
@@ -0,0 +1,144 @@
+// =============================================================================
+// ファイル: sensor_batch.go
+// 概要: 連続するセンサーサンプルを一定時間ごとに1通の sensor_batch メッセージにまとめる
+//
+// 【背景】
+// 50Hz の IMU や 20Hz のオドメトリを1サンプル1メッセージで送ると、
+// データ本体よりもメッセージごとのオーバーヘッド（MessagePack のヘッダー、
+// WebSocket のフレーム、書き込みのシステムコール）の方が大きくなります。
+//
+// 【仕組み】
+//
+//	forwardSensorData ──Add()──→ SensorBatcher ──(window ごと)──→ Hub.BroadcastSensorData(batched=true)
+//
+// window（例: 50ms）の間に届いたサンプルを溜めておき、まとめて1通で送ります。
+// まとめ送りは購読ごとのオプトインで、有効にしていないクライアントには
+// 従来どおり1サンプルずつ届きます（Hub.SetSensorBatching 参照）。
+// =============================================================================
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"go.uber.org/zap"
+)
+
+// maxSensorBatchSamples: 1通のバッチに入れるサンプル数の上限
+// window が長すぎる・生成頻度が高すぎる場合でも、メッセージが巨大にならないようにする。
+const maxSensorBatchSamples = 256
+
+// =============================================================================
+// SensorBatcher - ロボットごとにセンサーサンプルを溜めてまとめて配信する
+// =============================================================================
+type SensorBatcher struct {
+	hub    *Hub
+	codec  protocol.Codec
+	window time.Duration
+	logger *zap.Logger
+
+	mu      sync.Mutex
+	pending map[string][]map[string]any // robotID → 未送信のサンプル
+}
+
+// NewSensorBatcher - コンストラクタ
+// window はまとめる時間幅です。Start() のゴルーチンがこの間隔で Flush() します。
+func NewSensorBatcher(hub *Hub, window time.Duration, logger *zap.Logger) *SensorBatcher {
+	return &SensorBatcher{
+		hub:     hub,
+		codec:   protocol.NewCodec(),
+		window:  window,
+		logger:  logger,
+		pending: make(map[string][]map[string]any),
+	}
+}
+
+// =============================================================================
+// Start - window ごとに Flush() するゴルーチンを起動する
+// =============================================================================
+//
+// ctx がキャンセルされると、溜まっている分を送ってから停止します。
+// wg が nil でなければゴルーチンを登録し、終了時に Done() します。
+func (b *SensorBatcher) Start(ctx context.Context, wg *sync.WaitGroup) {
+	if wg != nil {
+		wg.Add(1)
+	}
+	go func() {
+		if wg != nil {
+			defer wg.Done()
+		}
+		ticker := time.NewTicker(b.window)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				b.Flush()
+				return
+			case <-ticker.C:
+				b.Flush()
+			}
+		}
+	}()
+}
+
+// =============================================================================
+// Add - サンプルを1つ溜める
+// =============================================================================
+//
+// sample は sensor_data の Payload と同じ形（data_type, frame_id, data）に
+// topic と timestamp を加えたものです。
+// 上限（maxSensorBatchSamples）に達したら、window を待たずにその場で送ります。
+func (b *SensorBatcher) Add(robotID string, sample map[string]any) {
+	b.mu.Lock()
+	b.pending[robotID] = append(b.pending[robotID], sample)
+	var full []map[string]any
+	if len(b.pending[robotID]) >= maxSensorBatchSamples {
+		full = b.pending[robotID]
+		delete(b.pending, robotID)
+	}
+	b.mu.Unlock()
+
+	if full != nil {
+		b.send(robotID, full)
+	}
+}
+
+// =============================================================================
+// Flush - 溜まっている全ロボットのサンプルを送る
+// =============================================================================
+//
+// Start() のゴルーチンから呼ばれます。テストから直接呼ぶこともできます。
+func (b *SensorBatcher) Flush() {
+	b.mu.Lock()
+	pending := b.pending
+	b.pending = make(map[string][]map[string]any, len(pending))
+	b.mu.Unlock()
+
+	for robotID, samples := range pending {
+		b.send(robotID, samples)
+	}
+}
+
+// send - サンプルを sensor_batch メッセージにして、まとめ送りの購読者に配信する
+// まとめ送りの購読者がいなければ、エンコードせずに捨てます。
+func (b *SensorBatcher) send(robotID string, samples []map[string]any) {
+	if len(samples) == 0 || !b.hub.HasBatchedSubscribers(robotID) {
+		return
+	}
+
+	msg := protocol.NewMessage(protocol.MsgTypeSensorBatch, robotID)
+	msg.Payload["samples"] = samples
+	msg.Payload["count"] = len(samples)
+
+	encoded, err := b.codec.Encode(msg)
+	if err != nil {
+		b.logger.Error("Failed to encode sensor batch",
+			zap.String("robot_id", robotID),
+			zap.Error(err),
+		)
+		return
+	}
+	b.hub.BroadcastSensorData(robotID, "", encoded, true)
+}
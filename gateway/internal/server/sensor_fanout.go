@@ -0,0 +1,314 @@
+// =============================================================================
+// ファイル: sensor_fanout.go
+// 概要: センサーデータを WebSocket 配信と Redis 永続化に振り分けるワーカープール
+//
+// 【背景】
+// 以前の forwardSensorData は、ロボットごとの1つのゴルーチンで
+// 「エンコード → WebSocket 配信 → Redis 発行」を順番に行っていました。
+// Redis への書き込みが遅いと、次のサンプルの WebSocket 配信までその分だけ遅れます。
+// 遠隔操作の画面にとって、永続化の遅れで映像やオドメトリが遅れるのは本末転倒です。
+//
+// 【仕組み】
+//
+//	forwardSensorData ──Submit()──→ ワーカー（robot_id で選ぶ）──→ エンコード（1回だけ）
+//	                                     │                       ├─→ Hub / SensorBatcher / SessionBuffer
+//	                                     │                       └─→ 停止検出・最終受信時刻
+//	                                     └──(満杯なら捨てる)──→ 永続化キュー ──→ Redis
+//
+// 同じロボットのサンプルは常に同じワーカーが処理するため、配信の順番は入れ替わりません。
+// 永続化キューは上限付きで、Redis が詰まって満杯になったら捨てます（DroppedPersist で数える）。
+// WebSocket 配信は永続化を待たないので、Redis の遅れがリアルタイム配信を遅らせることはありません。
+// =============================================================================
+package server
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/convert"
+	"github.com/robot-ai-webapp/gateway/internal/metrics"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/safety"
+	"go.uber.org/zap"
+)
+
+// sensorWorkerQueue: ワーカーごとの受付キューの長さ
+// 満杯なら Submit が待つ（アダプターのチャネルで従来どおり背圧がかかる）。
+const sensorWorkerQueue = 64
+
+// persistDropLogEvery: 永続化キューが満杯で捨てた時、何件ごとに警告を出すか
+const persistDropLogEvery = 100
+
+// SensorPersister - センサーデータの永続化先（bridge.RedisPublisher が満たす）
+type SensorPersister interface {
+	PublishSensorData(ctx context.Context, robotID string, data adapter.SensorData) error
+}
+
+// =============================================================================
+// SensorFanout - センサーデータの振り分けを行うワーカープール
+// =============================================================================
+//
+// Set* はすべて Start の前に呼んでください。
+type SensorFanout struct {
+	hub    *Hub
+	codec  protocol.Codec
+	logger *zap.Logger
+
+	batcher       *SensorBatcher
+	stallDetector *safety.SensorStallDetector
+	collision     *safety.CollisionMonitor
+	topicRemap    TopicRemap
+	sessionBuffer *SessionBuffer
+	cache         *SensorCache
+	robotMetrics  *metrics.RobotMetrics
+	robotEnabled  func(robotID string) bool
+
+	workers []chan adapter.SensorData // ワーカーごとの受付キュー
+	persist chan adapter.SensorData   // 永続化キュー（上限付き）
+
+	persistDropped atomic.Uint64
+
+	// persister: 永続化先（nil なら永続化しない）。Redis の復旧で実行中に差し替わるため persisterMu で守る。
+	persisterMu sync.RWMutex
+	persister   SensorPersister
+}
+
+// NewSensorFanout - コンストラクタ
+// workers はワーカー数（1未満なら1）、persistQueue は永続化キューの長さです。
+func NewSensorFanout(hub *Hub, codec protocol.Codec, workers, persistQueue int, logger *zap.Logger) *SensorFanout {
+	f := &SensorFanout{
+		hub:     hub,
+		codec:   codec,
+		logger:  logger,
+		workers: make([]chan adapter.SensorData, max(workers, 1)),
+		persist: make(chan adapter.SensorData, max(persistQueue, 1)),
+	}
+	for i := range f.workers {
+		f.workers[i] = make(chan adapter.SensorData, sensorWorkerQueue)
+	}
+	return f
+}
+
+// SetBatcher - まとめ送り（sensor_batch）を希望した購読者向けの SensorBatcher を設定する
+func (f *SensorFanout) SetBatcher(b *SensorBatcher) { f.batcher = b }
+
+// SetStallDetector - トピックごとの受信時刻を記録する停止検出器を設定する
+func (f *SensorFanout) SetStallDetector(d *safety.SensorStallDetector) { f.stallDetector = d }
+
+// SetCollisionMonitor - LiDAR のスキャンで衝突リスクを判定する監視を設定する
+func (f *SensorFanout) SetCollisionMonitor(m *safety.CollisionMonitor) { f.collision = m }
+
+// SetTopicRemap - クライアント向けのトピック名の付け替えを設定する
+func (f *SensorFanout) SetTopicRemap(r TopicRemap) { f.topicRemap = r }
+
+// SetSessionBuffer - 切断中のセッションにデータを溜める SessionBuffer を設定する
+func (f *SensorFanout) SetSessionBuffer(b *SessionBuffer) { f.sessionBuffer = b }
+
+// SetSensorCache - sensor_request のために、トピックごとの最新のサンプルを保持する
+func (f *SensorFanout) SetSensorCache(c *SensorCache) { f.cache = c }
+
+// SetRobotMetrics - オドメトリで報告された速度を /metrics のゲージに記録する
+func (f *SensorFanout) SetRobotMetrics(m *metrics.RobotMetrics) { f.robotMetrics = m }
+
+// SetRobotEnabled - 運用から外したロボットのセンサーデータを捨てる（enabled が false を返すロボット）
+// 設定しなければ、無効にしたロボットのデータもそのまま転送します。
+func (f *SensorFanout) SetRobotEnabled(enabled func(robotID string) bool) { f.robotEnabled = enabled }
+
+// SetPersister - 永続化先を設定する（設定しなければ永続化しない）
+// Start の後に呼んでもよく、その時点から永続化を始めます（Redis の復旧時）。
+func (f *SensorFanout) SetPersister(p SensorPersister) {
+	f.persisterMu.Lock()
+	f.persister = p
+	f.persisterMu.Unlock()
+}
+
+// currentPersister: 現在の永続化先（なければ nil）
+func (f *SensorFanout) currentPersister() SensorPersister {
+	f.persisterMu.RLock()
+	defer f.persisterMu.RUnlock()
+	return f.persister
+}
+
+// DroppedPersist - 永続化キューが満杯で捨てたサンプルの数を返す
+func (f *SensorFanout) DroppedPersist() uint64 {
+	return f.persistDropped.Load()
+}
+
+// =============================================================================
+// Start - ワーカーと永続化のゴルーチンを起動する
+// =============================================================================
+//
+// ctx がキャンセルされると停止します。キューに残ったサンプルは捨てます。
+// wg が nil でなければゴルーチンを登録し、終了時に Done() します。
+func (f *SensorFanout) Start(ctx context.Context, wg *sync.WaitGroup) {
+	run := func(fn func()) {
+		if wg != nil {
+			wg.Add(1)
+		}
+		go func() {
+			if wg != nil {
+				defer wg.Done()
+			}
+			fn()
+		}()
+	}
+
+	for _, queue := range f.workers {
+		run(func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case data := <-queue:
+					f.dispatch(data)
+				}
+			}
+		})
+	}
+	// 永続化先が後から設定されることがあるため、永続化のゴルーチンは常に起動する
+	// （永続化先がない間は dispatch がキューに入れないので、何もせず待つだけ）。
+	run(func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case data := <-f.persist:
+				persister := f.currentPersister()
+				if persister == nil {
+					continue
+				}
+				if err := persister.PublishSensorData(ctx, data.RobotID, data); err != nil {
+					f.logger.Debug("Failed to persist sensor data",
+						zap.String("robot_id", data.RobotID),
+						zap.Error(err),
+					)
+				}
+			}
+		}
+	})
+}
+
+// =============================================================================
+// Submit - サンプルをロボットのワーカーに渡す
+// =============================================================================
+//
+// data.RobotID でワーカーを選びます。ワーカーのキューが満杯なら空くまで待ち、
+// ctx がキャンセルされたら false を返します。
+func (f *SensorFanout) Submit(ctx context.Context, data adapter.SensorData) bool {
+	select {
+	case f.workers[f.workerIndex(data.RobotID)] <- data:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// workerIndex - ロボットIDからワーカーを選ぶ（同じロボットは常に同じワーカー）
+func (f *SensorFanout) workerIndex(robotID string) int {
+	if len(f.workers) == 1 {
+		return 0
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(robotID))
+	return int(h.Sum32() % uint32(len(f.workers)))
+}
+
+// dispatch - 1件のサンプルをエンコードして配信し、永続化キューに入れる
+func (f *SensorFanout) dispatch(data adapter.SensorData) {
+	robotID := data.RobotID
+
+	// 運用から外したロボット（set_robot_enabled）のデータは、どこにも渡さない。
+	// データ自体は届いているので、センサー停止としては扱わない。
+	if f.robotEnabled != nil && !f.robotEnabled(robotID) {
+		if f.stallDetector != nil && !latchedTopics[data.Topic] {
+			f.stallDetector.Mark(robotID, data.Topic)
+		}
+		return
+	}
+
+	// --- 衝突リスクの判定 ---
+	// 購読者がいなくても判定するため、転送より先に行う。
+	if f.collision != nil && data.DataType == "lidar" {
+		f.collision.CheckScan(robotID, data.Data)
+	}
+
+	// --- WebSocket クライアントへの転送 ---
+	// トピック名はクライアント向けの名前に付け替える（Redis と停止検出は内部の名前のまま）。
+	clientTopic := f.topicRemap.Apply(robotID, data.Topic)
+	msg := protocol.NewMessage(protocol.MsgTypeSensorData, robotID)
+	msg.Topic = clientTopic
+	msg.Payload = map[string]any{
+		"data_type": data.DataType,
+		"frame_id":  data.FrameID,
+		"data":      data.Data,
+	}
+
+	// エンコードは1回だけ行い、同じバイト列をすべての購読者に送る
+	encoded, err := f.codec.Encode(msg)
+	if err != nil {
+		f.logger.Error("Failed to encode sensor data", zap.Error(err))
+	} else {
+		// まとめ送りが有効なら、1サンプルずつ送るのはまとめ送りを希望していない購読者だけで、
+		// 希望した購読者には batcher が window ごとにまとめて送る。
+		if f.batcher != nil {
+			f.hub.BroadcastSensorData(robotID, clientTopic, encoded, false)
+			f.batcher.Add(robotID, map[string]any{
+				"topic":     clientTopic,
+				"data_type": data.DataType,
+				"frame_id":  data.FrameID,
+				"data":      data.Data,
+				"timestamp": data.Timestamp,
+			})
+		} else {
+			f.hub.BroadcastSensorSample(robotID, clientTopic, encoded)
+		}
+		// tf_static のように一度しか届かないトピックは、後から購読したクライアントにも送れるよう保持する。
+		if latchedTopics[data.Topic] {
+			f.hub.Latch(robotID, clientTopic, encoded)
+		}
+		// 切断中のセッションにも溜めておく（再接続したときに再送する）。
+		if f.sessionBuffer != nil {
+			f.sessionBuffer.Record(robotID, clientTopic, encoded)
+		}
+	}
+
+	// 単発の問い合わせ（sensor_request）に答えられるよう、最新のサンプルを残す。
+	if f.cache != nil {
+		f.cache.Put(robotID, clientTopic, data)
+	}
+
+	// オドメトリなら、ロボットが報告した速度をゲージに残す。
+	if f.robotMetrics != nil && data.DataType == "odometry" {
+		vx, _ := convert.ToFloat64(data.Data["velocity_x"])
+		vy, _ := convert.ToFloat64(data.Data["velocity_y"])
+		wz, _ := convert.ToFloat64(data.Data["angular_z"])
+		f.robotMetrics.SetReportedVelocity(robotID, vx, vy, wz)
+	}
+
+	// 最終センサー時刻を記録（health_status の応答で使う）。
+	f.hub.MarkSensorData(robotID, data.Timestamp)
+	// トピックごとの受信時刻を記録（途絶えたらセンサー停止として検出される）。
+	// ラッチトピックは一度しか届かないのが正常なので、停止検出の対象にしない。
+	if f.stallDetector != nil && !latchedTopics[data.Topic] {
+		f.stallDetector.Mark(robotID, data.Topic)
+	}
+
+	// --- Redis への永続化 ---
+	// 永続化のゴルーチンに任せ、満杯なら待たずに捨てる。
+	if f.currentPersister() == nil {
+		return
+	}
+	select {
+	case f.persist <- data:
+	default:
+		if n := f.persistDropped.Add(1); n%persistDropLogEvery == 1 {
+			f.logger.Warn("Sensor persistence queue full, dropping samples",
+				zap.String("robot_id", robotID),
+				zap.Uint64("dropped_total", n),
+			)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+// =============================================================================
+// ファイル: sensor_rate.go
+// 概要: ロボットのセンサーの送信頻度を実行中に変更する（set_sensor_rate、管理者のみ）
+//
+// 【背景】
+// 回線が細い現場では帯域を絞るために頻度を下げたく、ML の実験では
+// サンプリング周期がモデルの精度にどう効くかを試したくなります。
+// 再接続せずに、アダプターの SampleRateController で生成頻度を変えます。
+//
+//	set_sensor_rate {"rates": {"scan": 5}} ──→ 頻度を変えられるトピックか・最大の頻度以下かを検証
+//	                                       ──→ SampleRateController.SetSampleRate（トピックごと）
+//
+// 変更はアダプターが接続している間だけ有効で、再接続すると標準の頻度に戻ります。
+// フロー制御（FlowController）が有効なロボットでは、指定した頻度がフロー制御の基準になり、
+// 実際の頻度は「指定した頻度 × 現在の倍率」です（混雑が解消すると指定した頻度に戻ります）。
+// =============================================================================
+package server
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/convert"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"go.uber.org/zap"
+)
+
+// =============================================================================
+// handleSetSensorRate - センサーの送信頻度を変更する
+// =============================================================================
+//
+// 【Payload】
+//
+//	{"rates": {"scan": 5, "imu": 100}}  // トピックごとの頻度（Hz）
+//	{"reset": true}                     // すべてのトピックを標準の頻度に戻す
+//
+// 応答は cmd_ack（command: "set_sensor_rate"）で、変更したトピックの頻度（"rates"）が入ります。
+// フロー制御が有効なロボットでは、現在の倍率（"rate_factor"）も入ります。
+// 1つでも不正な値があれば、どのトピックも変更しません。
+func (h *Handler) handleSetSensorRate(client *Client, msg *protocol.Message) {
+	if !client.Authenticated {
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeNotAuthenticated, "Not authenticated")
+		return
+	}
+	if !h.isAdmin(client) {
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeForbidden, "set_sensor_rate is only available to admin users")
+		return
+	}
+	robotID := msg.RobotID
+	if robotID == "" {
+		h.sendError(client, "", "Missing robot_id")
+		return
+	}
+	adp, ok := h.registry.GetAdapter(robotID)
+	if !ok {
+		h.sendError(client, robotID, "Robot not found")
+		return
+	}
+	rc, ok := adp.(adapter.SampleRateController)
+	if !ok {
+		h.sendErrorCode(client, robotID, protocol.ErrCodeCommandNotAllowed, "Robot "+robotID+" does not support changing sensor rates")
+		return
+	}
+
+	nominal := rc.NominalSampleRates()
+	rates := make(map[string]float64)
+	if reset, _ := msg.Payload["reset"].(bool); reset {
+		rates = nominal
+	} else {
+		requested, _ := msg.Payload["rates"].(map[string]any)
+		if len(requested) == 0 {
+			h.sendErrorCode(client, robotID, protocol.ErrCodeInvalidMessage, "set_sensor_rate requires rates or reset")
+			return
+		}
+		maxRates := rc.MaxSampleRates()
+		for topic, v := range requested {
+			if _, ok := nominal[topic]; !ok {
+				h.sendErrorCode(client, robotID, protocol.ErrCodeInvalidMessage,
+					fmt.Sprintf("The rate of topic %q cannot be changed", topic))
+				return
+			}
+			hz, ok := convert.ToFloat64(v)
+			if !ok || hz <= 0 {
+				h.sendErrorCode(client, robotID, protocol.ErrCodeInvalidMessage,
+					fmt.Sprintf("The rate of topic %q must be a positive number, got %v", topic, v))
+				return
+			}
+			if maxHz, ok := maxRates[topic]; ok && hz > maxHz {
+				h.sendErrorCode(client, robotID, protocol.ErrCodeSensorRateOutOfRange,
+					fmt.Sprintf("The rate of topic %q must not exceed %g Hz, got %g", topic, maxHz, hz))
+				return
+			}
+			rates[topic] = hz
+		}
+	}
+
+	// 検証済みなので通常は失敗しないが、アダプターが拒否したトピックは応答から外す
+	topics := make([]string, 0, len(rates))
+	for topic := range rates {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	fc := h.flowController(robotID)
+	applied := make(map[string]any, len(rates))
+	for _, topic := range topics {
+		var err error
+		if fc != nil {
+			_, err = fc.SetBaseRate(topic, rates[topic])
+		} else {
+			err = rc.SetSampleRate(topic, rates[topic])
+		}
+		if err != nil {
+			h.logger.Warn("Failed to change sensor rate",
+				zap.String("robot_id", robotID),
+				zap.String("topic", topic),
+				zap.Error(err),
+			)
+			continue
+		}
+		applied[topic] = rates[topic]
+	}
+
+	h.logger.Info("Sensor rates changed by operator",
+		zap.String("robot_id", robotID),
+		zap.String("user_id", client.UserID),
+		zap.Any("rates", applied),
+	)
+
+	ack := protocol.NewMessage(protocol.MsgTypeCommandAck, robotID)
+	ack.Payload["command"] = "set_sensor_rate"
+	ack.Payload["rates"] = applied
+	if fc != nil {
+		ack.Payload["rate_factor"] = fc.RateFactor()
+	}
+	h.sendToClient(client, ack)
+}
+
+// =============================================================================
+// SetFlowController - ロボットのフロー制御を登録する
+// =============================================================================
+//
+// 登録すると、set_sensor_rate で指定した頻度がフロー制御の基準の頻度になります。
+// 登録しないロボットでは、set_sensor_rate はアダプターの頻度を直接変更します。
+// サーバー起動前に呼ぶこと。
+func (h *Handler) SetFlowController(robotID string, fc *FlowController) {
+	h.flowMu.Lock()
+	defer h.flowMu.Unlock()
+	if h.flowControllers == nil {
+		h.flowControllers = make(map[string]*FlowController)
+	}
+	h.flowControllers[robotID] = fc
+}
+
+// flowController: ロボットのフロー制御を返す（未登録なら nil）
+func (h *Handler) flowController(robotID string) *FlowController {
+	h.flowMu.RLock()
+	defer h.flowMu.RUnlock()
+	return h.flowControllers[robotID]
+}
@@ -0,0 +1,182 @@
+// =============================================================================
+// ファイル: sensor_request.go
+// 概要: sensor_request メッセージ（センサーの最新値の単発の問い合わせ）の処理
+//
+// 【なぜ必要？】
+// 「今のバッテリー残量は？」のように、たまに1つの値が欲しいだけのクライアントもいます。
+// そのために購読すると、LiDAR などの高頻度のデータまで届き続けてしまいます。
+//
+// 【仕組み】
+//
+//	SensorFanout ──Put()──→ SensorCache（ロボット×トピックごとに最新の1件）
+//	sensor_request ──→ SensorCache にあればそれを返す（source: "cache"）
+//	               └─→ なければ、アダプターが SensorReader ならその場で読む（source: "adapter"）
+//
+// 応答はふだんの sensor_data と同じ形で、問い合わせたクライアントにだけ送ります。
+// 購読の状態は変わりません。
+// =============================================================================
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"go.uber.org/zap"
+)
+
+// sensorReadTimeout: アダプターにその場で読ませる時に待つ最大時間
+const sensorReadTimeout = 2 * time.Second
+
+// =============================================================================
+// SensorCache - ロボット×トピックごとの最新のセンサーデータ
+// =============================================================================
+//
+// トピックはクライアント向けの名前（GATEWAY_TOPIC_REMAP で付け替えた後）で保持します。
+// 1サンプルごとに書き込まれるため、ロックは書き込みだけの短いものにしています。
+type SensorCache struct {
+	mu      sync.RWMutex
+	samples map[string]map[string]adapter.SensorData // robot_id -> topic -> 最新のサンプル
+
+	// remap: クライアント向けの名前からアダプターの名前に戻すための対応表
+	remap TopicRemap
+}
+
+// NewSensorCache - コンストラクタ
+// remap には SensorFanout に設定したものと同じ対応表を渡してください（nil なら付け替えなし）。
+func NewSensorCache(remap TopicRemap) *SensorCache {
+	return &SensorCache{
+		samples: make(map[string]map[string]adapter.SensorData),
+		remap:   remap,
+	}
+}
+
+// Put - サンプルを最新値として保持する（topic はクライアント向けの名前）
+func (c *SensorCache) Put(robotID, topic string, data adapter.SensorData) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	robot, ok := c.samples[robotID]
+	if !ok {
+		robot = make(map[string]adapter.SensorData)
+		c.samples[robotID] = robot
+	}
+	robot[topic] = data
+}
+
+// Latest - ロボットのトピックの最新値を返す
+func (c *SensorCache) Latest(robotID, topic string) (adapter.SensorData, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	data, ok := c.samples[robotID][topic]
+	return data, ok
+}
+
+// RemoveRobot - ロボットの最新値をすべて捨てる（レジストリから削除された時）
+func (c *SensorCache) RemoveRobot(robotID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.samples, robotID)
+}
+
+// SetSensorCache - sensor_request に答えるための最新値の保持先を設定する
+// SensorFanout.SetSensorCache と同じものを渡してください。設定しなければ、
+// アダプターがその場で読める（SensorReader）場合だけ答えます。
+// サーバー起動前（メッセージを受け付ける前）に一度だけ呼んでください。
+func (h *Handler) SetSensorCache(c *SensorCache) {
+	h.sensorCache = c
+}
+
+// =============================================================================
+// handleSensorRequest - センサーの最新値を1回だけ返す
+// =============================================================================
+//
+// 【応答の形（sensor_data の Payload）】
+//
+//	{"data_type": "battery", "frame_id": "base_link", "data": {...},
+//	 "timestamp": 1700000000000, "source": "cache"}
+//
+// timestamp はサンプルが作られた時刻（ミリ秒）です。キャッシュの値は古いことがあるため、
+// 鮮度が大事なクライアントはこれを見て判断してください。
+func (h *Handler) handleSensorRequest(client *Client, msg *protocol.Message) {
+	if !client.Authenticated {
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeNotAuthenticated, "Not authenticated")
+		return
+	}
+	if msg.RobotID == "" || msg.Topic == "" {
+		h.sendError(client, msg.RobotID, "robot_id and topic are required")
+		return
+	}
+	adp, ok := h.registry.GetAdapter(msg.RobotID)
+	if !ok {
+		h.sendError(client, msg.RobotID, "Robot not found")
+		return
+	}
+
+	if h.sensorCache != nil {
+		if data, ok := h.sensorCache.Latest(msg.RobotID, msg.Topic); ok {
+			h.sendSensorReply(client, msg, data, "cache")
+			return
+		}
+	}
+
+	data, err := h.readSensor(adp, msg.RobotID, msg.Topic)
+	switch {
+	case err == nil:
+		h.sendSensorReply(client, msg, data, "adapter")
+	case errors.Is(err, context.DeadlineExceeded):
+		h.logger.Warn("Sensor read timed out", zap.String("robot_id", msg.RobotID), zap.String("topic", msg.Topic))
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeAdapterTimeout, "Robot did not return sensor data in time")
+	default:
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeNoSensorData,
+			"No sensor data available for topic "+msg.Topic)
+	}
+}
+
+// readSensor: アダプターにその場で読ませる（対応していなければ ErrNotSupported）
+// diagnostics と同じく、固まったアダプターを待ち続けないようゴルーチンで呼びます。
+func (h *Handler) readSensor(adp adapter.RobotAdapter, robotID, topic string) (adapter.SensorData, error) {
+	reader, ok := adp.(adapter.SensorReader)
+	if !ok || !adp.IsConnected() {
+		return adapter.SensorData{}, adapter.ErrNotSupported
+	}
+	internal := topic
+	if h.sensorCache != nil {
+		internal = h.sensorCache.remap.Reverse(robotID, topic)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sensorReadTimeout)
+	defer cancel()
+
+	type result struct {
+		data adapter.SensorData
+		err  error
+	}
+	resultCh := make(chan result, 1)
+	go func() {
+		data, err := reader.ReadSensor(ctx, internal)
+		resultCh <- result{data: data, err: err}
+	}()
+	select {
+	case r := <-resultCh:
+		return r.data, r.err
+	case <-ctx.Done():
+		return adapter.SensorData{}, ctx.Err()
+	}
+}
+
+// sendSensorReply: sensor_request の応答（sensor_data）を送る
+func (h *Handler) sendSensorReply(client *Client, req *protocol.Message, data adapter.SensorData, source string) {
+	reply := protocol.NewMessage(protocol.MsgTypeSensorData, req.RobotID)
+	reply.Topic = req.Topic
+	reply.Payload = map[string]any{
+		"data_type": data.DataType,
+		"frame_id":  data.FrameID,
+		"data":      data.Data,
+		"timestamp": data.Timestamp,
+		"source":    source,
+	}
+	h.sendToClient(client, reply)
+}
@@ -0,0 +1,45 @@
+// =============================================================================
+// ファイル: sensor_stall.go
+// 概要: センサー停止（sensor stall）の検出結果をクライアントに知らせる処理
+//
+// 検出そのものは safety.SensorStallDetector が行います。ここでは、
+// 停止・復旧を safety_alert として全クライアントに配信し、
+// health_status の応答に停止中のトピックを含めるための接続を行います。
+// =============================================================================
+package server
+
+import (
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/safety"
+)
+
+// =============================================================================
+// SetStallDetector - センサー停止検出器を設定する
+// =============================================================================
+//
+// 設定すると、health_status の応答に各ロボットの stalled_topics が含まれます。
+// 検出器のコールバックには NotifySensorStall を登録してください。
+// サーバー起動前（メッセージを受け付ける前）に一度だけ呼んでください。
+func (h *Handler) SetStallDetector(d *safety.SensorStallDetector) {
+	h.stallDetector = d
+}
+
+// =============================================================================
+// NotifySensorStall - センサーの停止・復旧を安全アラートとして配信する
+// =============================================================================
+//
+// 【アラートの形（Payload）】
+//
+//	{"type": "sensor_stalled", "topic": "scan"}    // 停止
+//	{"type": "sensor_recovered", "topic": "scan"}  // 復旧
+//
+// E-Stop のアラートと同じく、そのロボットの購読者とアラート購読者（subscribe_alerts）に送ります。
+func (h *Handler) NotifySensorStall(robotID, topic string, stalled bool) {
+	alert := protocol.NewMessage(protocol.MsgTypeSafetyAlert, robotID)
+	alert.Payload["type"] = "sensor_recovered"
+	if stalled {
+		alert.Payload["type"] = "sensor_stalled"
+	}
+	alert.Payload["topic"] = topic
+	h.broadcastAlert(alert)
+}
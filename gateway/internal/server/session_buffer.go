@@ -0,0 +1,229 @@
+// =============================================================================
+// ファイル: session_buffer.go
+// 概要: 短い切断の間に届いたセンサーデータを溜めておき、再接続時に再送する
+//
+// 【背景】
+// Wi-Fi の瞬断などでクライアントが数秒切断すると、その間のセンサーデータは届かず、
+// 再接続後の UI にはグラフや地図の「抜け」が残ります。
+//
+// 【仕組み】
+//
+//	切断 ──ParkSession()──→ SessionBuffer（セッションIDごとに購読とデータを保持）
+//	forwardSensorData ──Record()──→ 購読していたロボットのデータをトピックごとに最新 N 件まで溜める
+//	再接続 + auth{"session_id": ...} ──Resume()──→ 購読を復元し、溜めたデータを届いた順に再送
+//
+// セッションIDは認証の応答（connection_status の "session_id"）でクライアントに渡します。
+// 猶予時間（grace）を過ぎたセッションは破棄され、再接続しても通常の新規接続になります。
+// 溜めるのは1サンプルずつの sensor_data だけで、まとめ送り（sensor_batch）の設定は復元しません。
+// =============================================================================
+package server
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/clock"
+	"go.uber.org/zap"
+)
+
+// =============================================================================
+// SessionBuffer - 切断中のセッションごとにセンサーデータを溜める
+// =============================================================================
+//
+// メモリの上限は「切断中のセッション数 × 購読トピック数 × depth」メッセージです。
+// セッションは grace の間しか保持しないため、切断が続いても増え続けることはありません。
+type SessionBuffer struct {
+	mu       sync.Mutex
+	depth    int           // トピックごとに溜める最新メッセージの数
+	grace    time.Duration // 切断から再開までの猶予時間
+	clock    clock.Clock
+	sessions map[string]*parkedSession // session_id -> 切断中のセッション
+	seq      uint64                    // 再送の順序を保つための通し番号
+	logger   *zap.Logger
+}
+
+// parkedSession - 切断中のセッション
+type parkedSession struct {
+	userID  string
+	robots  []string                     // 切断時に購読していたロボット
+	expires time.Time                    // これを過ぎたら再開できない
+	topics  map[string][]bufferedMessage // "robot_id/topic" -> 最新 depth 件
+}
+
+// bufferedMessage - 溜めたメッセージ（エンコード済み）と届いた順番
+type bufferedMessage struct {
+	seq  uint64
+	data []byte
+}
+
+// NewSessionBuffer - SessionBuffer を作成する
+// depth はトピックごとに溜めるメッセージ数、grace は再接続を待つ時間です。
+func NewSessionBuffer(depth int, grace time.Duration, logger *zap.Logger) *SessionBuffer {
+	return &SessionBuffer{
+		depth:    depth,
+		grace:    grace,
+		clock:    clock.Real{},
+		sessions: make(map[string]*parkedSession),
+		logger:   logger,
+	}
+}
+
+// SetClock - 現在時刻の取得元を差し替える（テスト用）
+func (b *SessionBuffer) SetClock(c clock.Clock) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.clock = c
+}
+
+// =============================================================================
+// Park - 切断したセッションを保持し、以後のデータを溜め始める
+// =============================================================================
+//
+// 購読していたロボットがなければ、溜めるものがないので保持しません。
+func (b *SessionBuffer) Park(sessionID, userID string, robots []string) {
+	if sessionID == "" || len(robots) == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.clock.Now()
+	b.pruneLocked(now)
+	b.sessions[sessionID] = &parkedSession{
+		userID:  userID,
+		robots:  robots,
+		expires: now.Add(b.grace),
+		topics:  make(map[string][]bufferedMessage),
+	}
+	b.logger.Debug("Session parked for resume",
+		zap.String("session_id", sessionID),
+		zap.Strings("robots", robots),
+	)
+}
+
+// =============================================================================
+// Record - ロボットのセンサーデータを、そのロボットを購読していた切断中のセッションに溜める
+// =============================================================================
+//
+// トピックごとに最新 depth 件だけを残し、古いものから捨てます。
+func (b *SessionBuffer) Record(robotID, topic string, data []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.sessions) == 0 {
+		return
+	}
+	now := b.clock.Now()
+	b.pruneLocked(now)
+
+	b.seq++
+	key := robotID + "/" + topic
+	for _, s := range b.sessions {
+		if !containsString(s.robots, robotID) {
+			continue
+		}
+		buf := append(s.topics[key], bufferedMessage{seq: b.seq, data: data})
+		if len(buf) > b.depth {
+			// 先頭を詰めて捨てる（スライスの後ろへずらし続けて配列が伸び続けないように）
+			buf = append(buf[:0], buf[len(buf)-b.depth:]...)
+		}
+		s.topics[key] = buf
+	}
+}
+
+// =============================================================================
+// Resume - 切断中のセッションを再開し、購読していたロボットと溜めたデータを返す
+// =============================================================================
+//
+// 同じユーザーで、猶予時間内に再開した場合だけ ok が true になります。
+// 一度呼ぶとセッションは破棄されます（同じセッションを二重に再開させない）。
+// messages は届いた順に並んでいます。
+func (b *SessionBuffer) Resume(sessionID, userID string) (robots []string, messages [][]byte, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s, found := b.sessions[sessionID]
+	if !found {
+		return nil, nil, false
+	}
+	delete(b.sessions, sessionID)
+	if s.userID != userID || !b.clock.Now().Before(s.expires) {
+		return nil, nil, false
+	}
+
+	var buffered []bufferedMessage
+	for _, buf := range s.topics {
+		buffered = append(buffered, buf...)
+	}
+	sort.Slice(buffered, func(i, j int) bool { return buffered[i].seq < buffered[j].seq })
+
+	messages = make([][]byte, len(buffered))
+	for i, m := range buffered {
+		messages[i] = m.data
+	}
+	return s.robots, messages, true
+}
+
+// pruneLocked - 猶予時間を過ぎたセッションを破棄する（mu を保持して呼ぶ）
+func (b *SessionBuffer) pruneLocked(now time.Time) {
+	for id, s := range b.sessions {
+		if !now.Before(s.expires) {
+			delete(b.sessions, id)
+		}
+	}
+}
+
+// containsString - スライスに値が含まれるか
+func containsString(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// =============================================================================
+// SetSessionBuffer / ParkSession - ハンドラーとの接続
+// =============================================================================
+
+// SetSessionBuffer - 再接続時の再送に使う SessionBuffer を設定する
+// 設定すると、認証の応答に "session_id" が含まれ、auth の "session_id" で再開できます。
+// ParkSession を Hub の登録解除コールバックから呼んでください。
+// サーバー起動前（メッセージを受け付ける前）に一度だけ呼んでください。
+func (h *Handler) SetSessionBuffer(b *SessionBuffer) {
+	h.sessions = b
+}
+
+// ParkSession - 切断したクライアントのセッションを、再接続に備えて保持する
+// 認証済みで、ロボットを購読していたクライアントだけが対象です。
+func (h *Handler) ParkSession(client *Client) {
+	if h.sessions == nil || !client.Authenticated {
+		return
+	}
+	h.sessions.Park(client.SessionID, client.UserID, h.hub.SubscribedRobots(client))
+}
+
+// resumeSession - auth の "session_id" で指定されたセッションを再開する
+// 再開できたら購読を復元し、再送するメッセージを返します（応答を送った後に再送する）。
+func (h *Handler) resumeSession(client *Client, sessionID string) ([][]byte, bool) {
+	if h.sessions == nil || sessionID == "" {
+		return nil, false
+	}
+	robots, messages, ok := h.sessions.Resume(sessionID, client.UserID)
+	if !ok {
+		return nil, false
+	}
+	client.SessionID = sessionID
+	for _, robotID := range robots {
+		h.hub.SubscribeClient(client, robotID)
+	}
+	h.logger.Info("Session resumed",
+		zap.String("client_id", client.ID),
+		zap.String("session_id", sessionID),
+		zap.Int("replayed", len(messages)),
+	)
+	return messages, true
+}
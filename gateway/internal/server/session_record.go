@@ -0,0 +1,186 @@
+// =============================================================================
+// ファイル: session_record.go
+// 概要: 接続のメッセージの記録（record_session、管理者のみ）と、記録の再生
+//
+// 【記録する対象】
+//
+//	全接続:     GATEWAY_SESSION_RECORD_ALL=true（SetSessionRecorder の recordAll）
+//	接続ごと:   管理者が record_session {"client_id": "...", "enabled": true} で開始・停止する
+//
+// 受信したメッセージはデコードした直後（readPump）、送信したメッセージは書き込んだ直後
+// （writePump）に記録します。送信しきれずに捨てたメッセージは記録されません。
+// 記録の形式とローテーションは recorder パッケージを参照してください。
+//
+// 【再生（ReplayRecording）】
+// 記録した受信メッセージを、新しく作ったハンドラーに同じ順番で送り直し、返ってきた応答を集めます。
+// 記録時の送信メッセージ（recorder.Messages で取り出す）と比べれば、変更で応答が
+// 変わっていないかを確かめる回帰テストになります。ロボットから届くセンサーデータなど、
+// 受信メッセージへの応答でないものは再生されません。
+// =============================================================================
+package server
+
+import (
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/recorder"
+	"go.uber.org/zap"
+)
+
+// replaySendBuffer: 再生用のクライアントの Send バッファ（1メッセージへの応答を溜めきれる大きさ）
+const replaySendBuffer = 256
+
+// =============================================================================
+// SetSessionRecorder - セッションレコーダーを設定する
+// =============================================================================
+//
+// recordAll が true なら全接続を記録し、false なら record_session で指定した接続だけを記録します。
+// rec が nil なら記録は無効で、record_session は session_recording_disabled で拒否されます。
+// サーバー起動前に一度だけ呼んでください。
+func (h *Handler) SetSessionRecorder(rec *recorder.Recorder, recordAll bool) {
+	h.sessionRecorder = rec
+	h.recordAll = recordAll
+}
+
+// recording: client のメッセージを記録するか
+func (h *Handler) recording(client *Client) bool {
+	return h.sessionRecorder != nil && (h.recordAll || client.recording.Load())
+}
+
+// recordInbound: 受信したメッセージを記録する（記録の対象でなければ何もしない）
+func (h *Handler) recordInbound(client *Client, msg *protocol.Message) {
+	if h.recording(client) {
+		h.sessionRecorder.Record(client.ID, recorder.DirectionIn, msg)
+	}
+}
+
+// recordOutbound: 送信したメッセージ（サーバー内部の形式）をデコードして記録する
+// デコードは記録の対象の接続だけで行うため、記録していない接続の送信には負荷がかかりません。
+func (h *Handler) recordOutbound(client *Client, internal []byte) {
+	if !h.recording(client) {
+		return
+	}
+	msg, err := protocol.MsgpackCodec{}.Decode(internal)
+	if err != nil {
+		h.logger.Warn("Failed to decode message for session recording",
+			zap.String("client_id", client.ID),
+			zap.Error(err),
+		)
+		return
+	}
+	h.sessionRecorder.Record(client.ID, recorder.DirectionOut, msg)
+}
+
+// =============================================================================
+// handleRecordSession - 接続の記録を開始・停止する
+// =============================================================================
+//
+// 【Payload】
+//
+//	{"client_id": "20260101...-abcd", "enabled": true}  // その接続の記録を開始する
+//	{"enabled": false}                                  // 自分の接続の記録を停止する
+//
+// 応答は cmd_ack（command: "record_session"）で、対象の "client_id" と現在の状態（"enabled"）が入ります。
+// 全接続を記録している（GATEWAY_SESSION_RECORD_ALL）間は、停止しても記録は続きます（"record_all": true）。
+func (h *Handler) handleRecordSession(client *Client, msg *protocol.Message) {
+	if !client.Authenticated {
+		h.sendErrorCode(client, "", protocol.ErrCodeNotAuthenticated, "Not authenticated")
+		return
+	}
+	if !h.isAdmin(client) {
+		h.sendErrorCode(client, "", protocol.ErrCodeForbidden, "record_session is only available to admin users")
+		return
+	}
+	if h.sessionRecorder == nil {
+		h.sendErrorCode(client, "", protocol.ErrCodeSessionRecordingDisabled, "Session recording is not configured")
+		return
+	}
+
+	enabled := true
+	if v, ok := msg.Payload["enabled"]; ok {
+		b, isBool := v.(bool)
+		if !isBool {
+			h.sendErrorCode(client, "", protocol.ErrCodeInvalidMessage, "enabled must be true or false")
+			return
+		}
+		enabled = b
+	}
+
+	target := client
+	if id, _ := msg.Payload["client_id"].(string); id != "" && id != client.ID {
+		other, ok := h.hub.ClientByID(id)
+		if !ok {
+			h.sendErrorCode(client, "", protocol.ErrCodeInvalidMessage, "Client not found: "+id)
+			return
+		}
+		target = other
+	}
+
+	// 送信の記録は writePump が書き込んだ時に判定するため、開始の ack は記録に残り、停止の ack は残らない
+	target.recording.Store(enabled)
+	h.logger.Info("Session recording changed",
+		zap.String("client_id", target.ID),
+		zap.Bool("enabled", enabled),
+		zap.String("user_id", client.UserID),
+	)
+
+	ack := protocol.NewMessage(protocol.MsgTypeCommandAck, "")
+	ack.Payload["command"] = "record_session"
+	ack.Payload["client_id"] = target.ID
+	ack.Payload["enabled"] = enabled
+	ack.Payload["record_all"] = h.recordAll
+	h.sendToClient(client, ack)
+}
+
+// ClientByID - 接続中のクライアントを ID で探す
+func (h *Hub) ClientByID(id string) (*Client, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	client, ok := h.clients[id]
+	return client, ok
+}
+
+// =============================================================================
+// ReplayRecording - 記録した受信メッセージを h に送り直し、接続ごとの応答を返す
+// =============================================================================
+//
+// 記録の接続ごとに、同じ ID の接続を作って受信メッセージ（dir "in"）を記録の順番で処理します。
+// 戻り値は接続ID → 処理中に送られたメッセージ（順番どおり）です。
+// h は再生のために新しく作ったもの（記録時と同じ設定、同じロボット）を渡してください。
+func ReplayRecording(h *Handler, entries []recorder.Entry) map[string][]*protocol.Message {
+	codec := protocol.NewCodec()
+	clients := make(map[string]*Client)
+	replies := make(map[string][]*protocol.Message)
+
+	for _, entry := range entries {
+		if entry.Direction != recorder.DirectionIn {
+			continue
+		}
+		client, ok := clients[entry.ClientID]
+		if !ok {
+			client = &Client{
+				ID:            entry.ClientID,
+				SessionID:     entry.ClientID,
+				Send:          make(chan []byte, replaySendBuffer),
+				Subscriptions: make(map[string]bool),
+			}
+			clients[entry.ClientID] = client
+		}
+
+		h.HandleMessage(client, entry.Message)
+
+		// この受信メッセージへの応答を、届いた順に集める
+		for drained := false; !drained; {
+			select {
+			case data := <-client.Send:
+				msg, err := codec.Decode(data)
+				if err != nil {
+					h.logger.Warn("Failed to decode replayed reply", zap.String("client_id", client.ID), zap.Error(err))
+					continue
+				}
+				replies[entry.ClientID] = append(replies[entry.ClientID], msg)
+			default:
+				drained = true
+			}
+		}
+	}
+	return replies
+}
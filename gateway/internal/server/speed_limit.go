@@ -0,0 +1,181 @@
+// =============================================================================
+// ファイル: speed_limit.go
+// 概要: ロボットごとの速度上限を実行中に変更する（管理者のみ）
+//
+// 【背景】
+// 人の近くで作業させる時など、監督者が一時的に特定のロボットの速度を落としたい場面があります。
+// 設定（GATEWAY_MAX_LINEAR_VEL など）を変えて再デプロイするのでは間に合いません。
+//
+// 【仕組み】
+//
+//	set_speed_limit（管理者） ──→ VelocityLimiter.SetRobotLimit ──→ 以後の速度コマンドをその上限で制限
+//	                         └──→ safety_alert（"speed_limit_changed"）で購読者に通知
+//	GET /speed-limits ──→ 各ロボットに実際に適用されている上限
+//
+// 変更はゲートウェイが動いている間だけ有効で、再起動すると設定の上限に戻ります。
+// 設定の上限とロボットのハードウェアの上限（Capabilities）を超える値には上げられません。
+// =============================================================================
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/robot-ai-webapp/gateway/internal/convert"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/safety"
+	"go.uber.org/zap"
+)
+
+// =============================================================================
+// handleSetSpeedLimit - ロボットの速度上限を変更する
+// =============================================================================
+//
+// 【Payload】
+//
+//	{"max_linear": 0.3, "max_angular": 0.5}  // 上限を下げる（省略した方は現在の値のまま）
+//	{"reset": true}                          // 設定の上限に戻す
+//
+// 応答は cmd_ack（command: "set_speed_limit"）で、適用された上限が入ります。
+func (h *Handler) handleSetSpeedLimit(client *Client, msg *protocol.Message) {
+	if !client.Authenticated {
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeNotAuthenticated, "Not authenticated")
+		return
+	}
+	if !h.isAdmin(client) {
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeForbidden, "set_speed_limit is only available to admin users")
+		return
+	}
+	robotID := msg.RobotID
+	if robotID == "" {
+		h.sendError(client, "", "Missing robot_id")
+		return
+	}
+	adp, ok := h.registry.GetAdapter(robotID)
+	if !ok {
+		h.sendError(client, robotID, "Robot not found")
+		return
+	}
+
+	reset, _ := msg.Payload["reset"].(bool)
+	if reset {
+		h.velLimit.ClearRobotLimit(robotID)
+	} else {
+		limit, _ := h.velLimit.LimitsFor(robotID)
+		caps := adp.GetCapabilities()
+		fields := []struct {
+			key      string
+			dst      *float64
+			gateway  float64 // 設定の上限
+			hardware float64 // ロボットの上限（0 なら不明）
+		}{
+			{"max_linear", &limit.MaxLinear, h.velLimit.MaxLinear(), caps.MaxLinearVelocity},
+			{"max_angular", &limit.MaxAngular, h.velLimit.MaxAngular(), caps.MaxAngularVelocity},
+		}
+
+		present := 0
+		for _, f := range fields {
+			v, ok := msg.Payload[f.key]
+			if !ok {
+				continue
+			}
+			present++
+			value, ok := convert.ToFloat64(v)
+			if !ok || value <= 0 {
+				h.sendErrorCode(client, robotID, protocol.ErrCodeInvalidMessage,
+					fmt.Sprintf("%s must be a positive number, got %v", f.key, v))
+				return
+			}
+			if f.hardware > 0 && value > f.hardware {
+				h.sendErrorCode(client, robotID, protocol.ErrCodeVelocityOutOfRange,
+					fmt.Sprintf("%s %.2f exceeds the robot's hardware limit %.2f", f.key, value, f.hardware))
+				return
+			}
+			if value > f.gateway {
+				h.sendErrorCode(client, robotID, protocol.ErrCodeVelocityOutOfRange,
+					fmt.Sprintf("%s %.2f exceeds the gateway limit %.2f", f.key, value, f.gateway))
+				return
+			}
+			*f.dst = value
+		}
+		if present == 0 {
+			h.sendErrorCode(client, robotID, protocol.ErrCodeInvalidMessage,
+				"set_speed_limit requires max_linear, max_angular or reset")
+			return
+		}
+		h.velLimit.SetRobotLimit(robotID, limit)
+	}
+
+	limit, overridden := h.velLimit.LimitsFor(robotID)
+	h.logger.Warn("Robot speed limit changed",
+		zap.String("robot_id", robotID),
+		zap.String("user_id", client.UserID),
+		zap.Float64("max_linear", limit.MaxLinear),
+		zap.Float64("max_angular", limit.MaxAngular),
+		zap.Bool("overridden", overridden),
+	)
+
+	// 操作者が「急に遅くなった」と戸惑わないよう、購読者に知らせる
+	alert := protocol.NewMessage(protocol.MsgTypeSafetyAlert, robotID)
+	alert.Payload["type"] = "speed_limit_changed"
+	alert.Payload["max_linear"] = limit.MaxLinear
+	alert.Payload["max_angular"] = limit.MaxAngular
+	alert.Payload["overridden"] = overridden
+	alert.Payload["user_id"] = client.UserID
+	h.broadcastAlert(alert)
+
+	ack := protocol.NewMessage(protocol.MsgTypeCommandAck, robotID)
+	ack.Payload["command"] = "set_speed_limit"
+	ack.Payload["max_linear"] = limit.MaxLinear
+	ack.Payload["max_angular"] = limit.MaxAngular
+	ack.Payload["overridden"] = overridden
+	h.sendToClient(client, ack)
+}
+
+// =============================================================================
+// SpeedLimitsHandler - 各ロボットに適用されている速度上限を返すHTTPハンドラー
+// =============================================================================
+//
+// 【レスポンス】
+//
+//	{
+//	  "default": {"max_linear": 1.0, "max_angular": 2.0},
+//	  "robots": {
+//	    "robot-1": {"max_linear": 0.3, "max_angular": 2.0, "overridden": true,
+//	                "hardware_max_linear": 1.0, "hardware_max_angular": 2.0}
+//	  }
+//	}
+//
+// robots には、登録されているロボットと、上限を変更したロボットが入ります。
+func (h *Handler) SpeedLimitsHandler(w http.ResponseWriter, r *http.Request) {
+	type robotLimit struct {
+		safety.RobotLimit
+		Overridden         bool    `json:"overridden"`
+		HardwareMaxLinear  float64 `json:"hardware_max_linear,omitempty"`
+		HardwareMaxAngular float64 `json:"hardware_max_angular,omitempty"`
+	}
+
+	robots := make(map[string]robotLimit)
+	for robotID := range h.velLimit.RobotLimits() {
+		limit, overridden := h.velLimit.LimitsFor(robotID)
+		robots[robotID] = robotLimit{RobotLimit: limit, Overridden: overridden}
+	}
+	for robotID, adp := range h.registry.GetAllActive() {
+		limit, overridden := h.velLimit.LimitsFor(robotID)
+		caps := adp.GetCapabilities()
+		robots[robotID] = robotLimit{
+			RobotLimit:         limit,
+			Overridden:         overridden,
+			HardwareMaxLinear:  caps.MaxLinearVelocity,
+			HardwareMaxAngular: caps.MaxAngularVelocity,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"default": safety.RobotLimit{MaxLinear: h.velLimit.MaxLinear(), MaxAngular: h.velLimit.MaxAngular()},
+		"robots":  robots,
+	})
+}
@@ -0,0 +1,45 @@
+// =============================================================================
+// ファイル: stats.go
+// 概要: 接続数とアダプターの台数を返す HTTP エンドポイント（/stats）
+//
+// /metrics（Prometheus 形式）と違い、人やスクリプトがそのまま読める JSON で返します。
+// アダプタータイプごとの台数と上限（GATEWAY_ADAPTER_TYPE_LIMITS）を並べて見られるので、
+// プロビジョニングが上限に近づいていないかの確認に使えます。
+// =============================================================================
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// =============================================================================
+// StatsHandler - 接続数とアダプターの台数を返すHTTPハンドラー
+// =============================================================================
+//
+// 【レスポンス】
+//
+//	{
+//	  "clients": 3,
+//	  "robots": 2,
+//	  "adapters_by_type": {"mock": 2},
+//	  "adapter_type_limits": {"mock": 5}
+//	}
+//
+// adapter_type_limits には上限を設定したタイプだけが入ります（ないタイプは無制限）。
+func (h *Handler) StatsHandler(w http.ResponseWriter, r *http.Request) {
+	counts := h.registry.TypeCounts()
+	robots := 0
+	for _, n := range counts {
+		robots += n
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"clients":             h.hub.ClientCount(),
+		"robots":              robots,
+		"adapters_by_type":    counts,
+		"adapter_type_limits": h.registry.TypeLimits(),
+	})
+}
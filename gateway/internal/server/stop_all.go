@@ -0,0 +1,148 @@
+// =============================================================================
+// ファイル: stop_all.go
+// 概要: 自分が操作している全ロボットを止める「ソフトストップ」（stop_all）
+//
+// 【E-Stop との違い】
+// E-Stop はラッチ（保持）されるため、解除（activate: false）するまで一切の速度コマンドを拒否します。
+// 「いったん全部止めて」という日常的な場面には重すぎるため、stop_all は次のように動きます。
+//
+//	              止める対象             E-Stop フラグ   再開
+//	estop         指定したロボット        立てる          明示的な解除が必要
+//	stop_all      自分が操作中のロボット   立てない        次の速度コマンドですぐ再開
+//
+// 【「自分が操作中」の判断】
+// 1. 操作ロックを自分（同じユーザー）が保持している
+// 2. ロックが無く、この接続が最後に速度コマンドを送った
+//
+// 他のユーザーがロックを保持しているロボットは止めません。
+// =============================================================================
+package server
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"go.uber.org/zap"
+)
+
+// stopAllFailure: 停止できなかったロボット
+type stopAllFailure struct {
+	RobotID string `json:"robot_id"`
+	Error   string `json:"error"`
+}
+
+// =============================================================================
+// handleStopAll - 操作中の全ロボットに速度 0 とナビゲーションの中止を送る
+// =============================================================================
+//
+// 応答は cmd_ack（command: "stop_all"）で、止めたロボット（"stopped"）と
+// 止められなかったロボット（"failed"、理由付き）の一覧が入ります。
+// 安全のための停止なので、コマンドポリシーや E-Stop のチェックは行いません。
+func (h *Handler) handleStopAll(client *Client, msg *protocol.Message) {
+	if !client.Authenticated {
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeNotAuthenticated, "Not authenticated")
+		return
+	}
+
+	stopped := []string{}
+	failed := []stopAllFailure{}
+	for _, robotID := range h.robotsControlledByUser(client) {
+		adp, ok := h.registry.GetAdapter(robotID)
+		if !ok {
+			continue
+		}
+		// 最小間隔で保留中のコマンドが、停止の後に送られないようにする
+		h.coalescer.Drop(robotID)
+		h.cancelRampDown(robotID)
+		if err := stopRobot(adp, robotID); err != nil {
+			h.logger.Warn("Failed to stop robot",
+				zap.String("robot_id", robotID),
+				zap.String("user_id", client.UserID),
+				zap.Error(err),
+			)
+			failed = append(failed, stopAllFailure{RobotID: robotID, Error: err.Error()})
+			continue
+		}
+		h.setLastVelocity(robotID, adapter.Velocity{})
+		stopped = append(stopped, robotID)
+	}
+
+	h.logger.Info("Stop all requested",
+		zap.String("user_id", client.UserID),
+		zap.Strings("stopped", stopped),
+		zap.Int("failed", len(failed)),
+	)
+
+	ack := protocol.NewMessage(protocol.MsgTypeCommandAck, "")
+	ack.Payload["command"] = "stop_all"
+	ack.Payload["stopped"] = stopped
+	ack.Payload["failed"] = failed
+	h.sendToClient(client, ack)
+}
+
+// cancelRampDown: ウォッチドッグが減速中（ランプダウン）なら打ち切る（robotID が空なら全ロボット）
+//
+// ロボットを止める操作の前に呼びます。打ち切らないと、止めた後も
+// 減速途中の（0 でない）速度がウォッチドッグから送られ続けます。
+func (h *Handler) cancelRampDown(robotID string) {
+	if h.watchdog == nil {
+		return
+	}
+	if robotID == "" {
+		h.watchdog.CancelAllRampDowns()
+		return
+	}
+	h.watchdog.CancelRampDown(robotID)
+}
+
+// stopRobot: 速度 0 を送り、進行中のナビゲーション（ドックへの移動など）を中止する
+func stopRobot(adp adapter.RobotAdapter, robotID string) error {
+	now := time.Now().UnixMilli()
+	err := adp.SendCommand(context.Background(), adapter.Command{
+		RobotID: robotID,
+		Type:    "velocity",
+		Payload: map[string]any{
+			"linear_x":  0.0,
+			"linear_y":  0.0,
+			"angular_z": 0.0,
+		},
+		Timestamp: now,
+	})
+	if err != nil {
+		return err
+	}
+	return adp.SendCommand(context.Background(), adapter.Command{
+		RobotID:   robotID,
+		Type:      "nav_cancel",
+		Payload:   map[string]any{},
+		Timestamp: now,
+	})
+}
+
+// robotsControlledByUser: client のユーザーが操作しているロボットの一覧（ID順）
+func (h *Handler) robotsControlledByUser(client *Client) []string {
+	h.lastVelMu.Lock()
+	lastController := make(map[string]string, len(h.controllers))
+	for robotID, clientID := range h.controllers {
+		lastController[robotID] = clientID
+	}
+	h.lastVelMu.Unlock()
+
+	var robots []string
+	for robotID := range h.registry.GetAllActive() {
+		if lock := h.opLock.GetLockInfo(robotID); lock != nil {
+			if lock.UserID == client.UserID {
+				robots = append(robots, robotID)
+			}
+			continue
+		}
+		if lastController[robotID] == client.ID {
+			robots = append(robots, robotID)
+		}
+	}
+	sort.Strings(robots)
+	return robots
+}
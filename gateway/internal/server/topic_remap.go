@@ -0,0 +1,34 @@
+// =============================================================================
+// ファイル: topic_remap.go
+// 概要: センサーデータのトピック名の付け替え（アダプターの名前 → クライアント向けの名前）
+//
+// 【背景】
+// モックは "scan" や "odom" のようなトピック名を使いますが、実機のロボットや
+// フロントエンドは "/robot1/laser" のような別の命名を期待することがあります。
+// 配信の直前で名前を付け替えれば、アダプターにもクライアントにも手を入れずに
+// 両者の命名規則を切り離せます（設定: GATEWAY_TOPIC_REMAP）。
+// =============================================================================
+package server
+
+// TopicRemap - ロボットごとのトピック名の対応表（robot_id -> 内部のトピック名 -> クライアント向けの名前）
+// nil や空でも使えます（その場合は何も付け替えません）。
+type TopicRemap map[string]map[string]string
+
+// Apply - クライアントに配信するトピック名を返す
+// 対応表にないロボット・トピックは、元の名前のまま返します。
+func (r TopicRemap) Apply(robotID, topic string) string {
+	if name, ok := r[robotID][topic]; ok {
+		return name
+	}
+	return topic
+}
+
+// Reverse - クライアント向けの名前から、アダプターが使う内部のトピック名を返す（Apply の逆）
+func (r TopicRemap) Reverse(robotID, clientTopic string) string {
+	for internal, name := range r[robotID] {
+		if name == clientTopic {
+			return internal
+		}
+	}
+	return clientTopic
+}
@@ -0,0 +1,157 @@
+// =============================================================================
+// ファイル: velocity_coalesce.go
+// 概要: 速度コマンドの最小間隔（チャタリング防止）
+//
+// 【なぜ必要？】
+// UI のループから 200Hz のように、ロボットが反映できるより速く速度コマンドを
+// 送ってくるクライアントがあります。すべてをアダプターに流すと、帯域と
+// アダプターの処理を無駄に使います。
+//
+// 【仕組み】
+// ロボットごとに、前回アダプターに送ってから最小間隔（GATEWAY_VELOCITY_MIN_INTERVAL_MS）が
+// 経っていなければ、コマンドはすぐには送らず「保留」にします。保留中に次のコマンドが
+// 来たら置き換えます（最新のものが勝つ）。間隔が経った時点で、保留中の最後のコマンドを送ります。
+//
+//	t=0ms  cmd A ──→ すぐ送信
+//	t=2ms  cmd B ──→ 保留
+//	t=4ms  cmd C ──→ 保留（B を置き換え）
+//	t=10ms        ──→ C を送信（最小間隔 10ms）
+//
+// レート制限とは違い、エラーは返しません。保留にしたコマンドにも、
+// "coalesced": true を付けた ACK をすぐに返します。
+//
+// 【安全】
+// E-Stop は速度コマンドとは別のメッセージなので、この間隔の対象外です。
+// E-Stop の発動と操作者の切断では保留中のコマンドを捨て、
+// 保留中のコマンドを送る直前にも E-Stop を確認し直します。
+// =============================================================================
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+// velocityCoalescer: ロボットごとの速度コマンドの最小間隔を管理する
+type velocityCoalescer struct {
+	interval time.Duration
+
+	mu     sync.Mutex
+	robots map[string]*coalesceState
+}
+
+// coalesceState: 1台のロボットの送信状況
+type coalesceState struct {
+	lastSent time.Time   // 最後にアダプターへ送った時刻
+	pending  func()      // 保留中のコマンドを送る関数（nil なら保留なし）
+	owner    string      // 保留中のコマンドを送ったクライアントのID
+	timer    *time.Timer // 保留中のコマンドを送るタイマー
+	gen      uint64      // タイマーの世代（取り消し後に古いタイマーが発火しても送らないため）
+}
+
+func newVelocityCoalescer(interval time.Duration) *velocityCoalescer {
+	return &velocityCoalescer{interval: interval, robots: make(map[string]*coalesceState)}
+}
+
+// =============================================================================
+// SetVelocityMinInterval - 速度コマンドの最小間隔を設定する
+// =============================================================================
+//
+// 0 なら無効（従来どおり、受け付けた速度コマンドをすぐに送る）です。
+// 速度コマンドを受け付ける前（起動時）に呼んでください。
+func (h *Handler) SetVelocityMinInterval(d time.Duration) {
+	if d <= 0 {
+		h.coalescer = nil
+		return
+	}
+	h.coalescer = newVelocityCoalescer(d)
+}
+
+// Defer: 最小間隔が経っていなければ send を保留にして true を返す
+// false なら、呼び出し側がすぐに送る（送った時刻として今を記録する）。
+// c が nil（無効）なら常に false。
+func (c *velocityCoalescer) Defer(robotID, clientID string, send func()) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	st, ok := c.robots[robotID]
+	if !ok {
+		st = &coalesceState{}
+		c.robots[robotID] = st
+	}
+	now := time.Now()
+	if st.timer == nil && now.Sub(st.lastSent) >= c.interval {
+		st.lastSent = now
+		return false
+	}
+
+	// 最新のものが勝つ: 保留中のコマンドを置き換える
+	st.pending = send
+	st.owner = clientID
+	if st.timer == nil {
+		st.gen++
+		gen := st.gen
+		st.timer = time.AfterFunc(st.lastSent.Add(c.interval).Sub(now), func() { c.flush(robotID, gen) })
+	}
+	return true
+}
+
+// flush: 保留中のコマンドを送る（タイマーから呼ばれる）
+func (c *velocityCoalescer) flush(robotID string, gen uint64) {
+	c.mu.Lock()
+	st := c.robots[robotID]
+	if st.gen != gen { // 取り消されたタイマー
+		c.mu.Unlock()
+		return
+	}
+	send := st.pending
+	st.pending, st.owner, st.timer = nil, "", nil
+	if send == nil {
+		c.mu.Unlock()
+		return
+	}
+	st.lastSent = time.Now()
+	c.mu.Unlock()
+
+	send()
+}
+
+// Drop: ロボットの保留中のコマンドを捨てる（robotID が空なら全ロボット）
+func (c *velocityCoalescer) Drop(robotID string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, st := range c.robots {
+		if robotID == "" || id == robotID {
+			st.cancel()
+		}
+	}
+}
+
+// DropClient: クライアントが送った保留中のコマンドを捨てる（切断時）
+func (c *velocityCoalescer) DropClient(clientID string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, st := range c.robots {
+		if st.pending != nil && st.owner == clientID {
+			st.cancel()
+		}
+	}
+}
+
+// cancel: 保留中のコマンドとタイマーを取り消す（c.mu を保持した状態で呼ぶこと）
+func (st *coalesceState) cancel() {
+	if st.timer != nil {
+		st.timer.Stop()
+		st.gen++
+	}
+	st.pending, st.owner, st.timer = nil, "", nil
+}
@@ -0,0 +1,118 @@
+// =============================================================================
+// ファイル: velocity_delta.go
+// 概要: velocity_delta メッセージ（相対速度コマンド）の処理
+//
+// キーボード操作の UI は「前進を 0.1 m/s 速く」のように差分で指令したいことが多く、
+// クライアント側で現在の速度を覚えておくのは、複数タブや再接続で食い違いの元になります。
+// ゲートウェイがロボットごとに直前の指令速度を覚えておき、差分を加算して
+// 通常の速度コマンドと同じ安全パイプラインに通します。
+// =============================================================================
+package server
+
+import (
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/safety"
+	"go.uber.org/zap"
+)
+
+// =============================================================================
+// handleVelocityDelta - 相対速度コマンドの処理
+// =============================================================================
+//
+// 【リクエストの Payload（省略した成分は 0、ただし少なくとも1つは必要）】
+//
+//	linear_x / linear_y / angular_z: 直前の指令速度に加える差分
+//
+// 【累積値のクランプ】
+// 差分を足し続けると上限を超えるため、加算後の値は速度制限の上限で頭打ちにします
+// （GATEWAY_VELOCITY_CLAMP_MODE=reject でも拒否はしません）。頭打ちにした場合は
+// ACK の clamped が true になります。E-Stop、操作ロックなどのチェックは
+// handleVelocityCommand とまったく同じです。
+func (h *Handler) handleVelocityDelta(client *Client, msg *protocol.Message) {
+	if !client.Authenticated {
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeNotAuthenticated, "Not authenticated")
+		return
+	}
+	if msg.RobotID == "" {
+		h.sendError(client, "", "Missing robot_id")
+		return
+	}
+
+	delta, err := velocityInput(msg.Payload)
+	if err != nil {
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeInvalidMessage, "Invalid velocity delta: "+err.Error())
+		return
+	}
+
+	base := h.LastVelocity(msg.RobotID)
+	clamped := h.velLimit.ClampFor(msg.RobotID, safety.VelocityInput{
+		LinearX:  base.LinearX + delta.LinearX,
+		LinearY:  base.LinearY + delta.LinearY,
+		AngularZ: base.AngularZ + delta.AngularZ,
+	})
+
+	expanded := protocol.NewMessage(protocol.MsgTypeVelocityCommand, msg.RobotID)
+	expanded.Payload["linear_x"] = clamped.LinearX
+	expanded.Payload["linear_y"] = clamped.LinearY
+	expanded.Payload["angular_z"] = clamped.AngularZ
+	// 再送の重複排除が効くよう、command_id は引き継ぐ
+	if commandID, ok := msg.Payload["command_id"]; ok {
+		expanded.Payload["command_id"] = commandID
+	}
+	if isDryRun(msg) {
+		expanded.Payload[dryRunKey] = true
+	}
+
+	h.logger.Debug("Velocity delta applied",
+		zap.String("robot_id", msg.RobotID),
+		zap.Float64("linear_x", clamped.LinearX),
+		zap.Float64("angular_z", clamped.AngularZ),
+		zap.Bool("clamped", clamped.Clamped),
+	)
+	h.runVelocityCommand(client, expanded, clamped.Clamped)
+}
+
+// =============================================================================
+// ResetVelocityBaseline - 相対速度コマンドの基準を 0 に戻す
+// =============================================================================
+//
+// ロボットが止まった時（E-Stop、ウォッチドッグのタイムアウト）に呼びます。
+// robotID が空なら全ロボットの基準を戻します（全体 E-Stop 用）。
+// ウォッチドッグの SetTimeoutCallback にそのまま登録できる形です。
+func (h *Handler) ResetVelocityBaseline(robotID string) {
+	h.lastVelMu.Lock()
+	defer h.lastVelMu.Unlock()
+
+	if robotID == "" {
+		if h.robotMetrics != nil {
+			for id := range h.lastVel {
+				h.robotMetrics.SetCommandedVelocity(id, 0, 0, 0)
+			}
+		}
+		clear(h.lastVel)
+		return
+	}
+	delete(h.lastVel, robotID)
+	if h.robotMetrics != nil {
+		h.robotMetrics.SetCommandedVelocity(robotID, 0, 0, 0)
+	}
+}
+
+// setLastVelocity: ロボットに送信した速度（制限後）を記録する（/metrics のゲージにも反映する）
+func (h *Handler) setLastVelocity(robotID string, v adapter.Velocity) {
+	h.lastVelMu.Lock()
+	h.lastVel[robotID] = v
+	h.lastVelMu.Unlock()
+	if h.robotMetrics != nil {
+		h.robotMetrics.SetCommandedVelocity(robotID, v.LinearX, v.LinearY, v.AngularZ)
+	}
+}
+
+// LastVelocity - 直前に送信した速度を返す（まだ送っていなければ 0）
+// ウォッチドッグの SetRampDown にそのまま渡せる形です（減速の始点になる）。
+func (h *Handler) LastVelocity(robotID string) adapter.Velocity {
+	h.lastVelMu.Lock()
+	defer h.lastVelMu.Unlock()
+	return h.lastVel[robotID]
+}
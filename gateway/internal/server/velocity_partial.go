@@ -0,0 +1,72 @@
+// =============================================================================
+// ファイル: velocity_partial.go
+// 概要: 一部の成分だけを更新する速度コマンド（velocity_cmd の "partial": true）
+//
+// 通常の velocity_cmd では、省略した成分は 0 になります（linear_x だけ送れば、回転は止まる）。
+// ジョイスティックとスライダーを別々に送る UI などは「回転だけ変えて、前進はそのまま」にしたいため、
+// "partial": true を付けたコマンドでは、省略した成分を直前の指令速度のまま保ちます。
+// 省略の意味が変わるので、フラグで明示した時だけこの扱いにします。
+//
+// 【velocity_delta との違い】
+// velocity_delta は差分を加算し、partial は指定した成分を絶対値で置き換えます。
+// どちらも直前の指令速度（LastVelocity）を基準にし、合成した結果を
+// 通常の速度コマンドと同じ安全パイプライン（runVelocityCommand）に通します。
+// =============================================================================
+package server
+
+import (
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"go.uber.org/zap"
+)
+
+// partialVelocityKey: 省略した成分を直前の値のまま保つことを示す Payload のフラグ
+const partialVelocityKey = "partial"
+
+// =============================================================================
+// handlePartialVelocity - "partial": true の速度コマンドの処理
+// =============================================================================
+//
+// 指定した成分はそのまま、省略した成分は直前に送信した速度（制限後）で埋めます。
+// まだ速度を送っていないロボット、または E-Stop やウォッチドッグで止まった後は、基準は 0 です。
+// 最小間隔で保留中のコマンドは基準に含まれません（送信した速度だけが基準になる）。
+func (h *Handler) handlePartialVelocity(client *Client, msg *protocol.Message) {
+	// 数値でない成分や、3成分すべての省略は通常のコマンドと同じく拒否する
+	if _, err := velocityInput(msg.Payload); err != nil {
+		h.sendErrorCode(client, msg.RobotID, protocol.ErrCodeInvalidMessage, "Invalid velocity command: "+err.Error())
+		return
+	}
+
+	base := h.LastVelocity(msg.RobotID)
+	merged := protocol.NewMessage(protocol.MsgTypeVelocityCommand, msg.RobotID)
+	for key, value := range msg.Payload {
+		if key != partialVelocityKey {
+			merged.Payload[key] = value
+		}
+	}
+	for key, value := range map[string]float64{
+		"linear_x":  base.LinearX,
+		"linear_y":  base.LinearY,
+		"angular_z": base.AngularZ,
+	} {
+		if _, ok := merged.Payload[key]; !ok {
+			merged.Payload[key] = value
+		}
+	}
+
+	h.logger.Debug("Partial velocity merged",
+		zap.String("robot_id", msg.RobotID),
+		zap.Any("linear_x", merged.Payload["linear_x"]),
+		zap.Any("angular_z", merged.Payload["angular_z"]),
+	)
+	h.runVelocityCommand(client, merged, false)
+}
+
+// partialVelocity: Payload の "partial" フラグを読む（省略時は false、真偽値以外は ok=false）
+func partialVelocity(payload map[string]any) (partial, ok bool) {
+	v, exists := payload[partialVelocityKey]
+	if !exists {
+		return false, true
+	}
+	partial, ok = v.(bool)
+	return partial, ok
+}
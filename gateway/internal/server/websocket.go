@@ -29,10 +29,28 @@ package server
 // インポートセクション
 // =============================================================================
 import (
+	// "context": サーバー停止時（Shutdown）の待ち時間の制御に使います。
+	"context"
+
+	// "errors": 読み取りエラーが無応答によるタイムアウトかを判定する（errors.As）
+	"errors"
+
+	// "fmt": 対応していないフォーマットを指定されたときのエラーメッセージ
+	"fmt"
+
+	// "net": タイムアウトのエラー（net.Error）の判定に使います。
+	"net"
+
 	// "net/http": HTTPサーバー機能を提供する標準パッケージ。
 	// WebSocketの最初の接続（HTTPアップグレード）や、ヘルスチェックに使います。
 	"net/http"
 
+	// "strings": 対応フォーマット名の一覧をエラーメッセージ用に連結する
+	"strings"
+
+	// "sync": writePump の終了を待つ WaitGroup に使います。
+	"sync"
+
 	// "time": 時間関連の機能。タイムアウトやPing間隔の設定に使います。
 	"time"
 
@@ -41,6 +59,9 @@ import (
 	// 接続のアップグレード、メッセージの送受信、Ping/Pongなどを提供します。
 	"github.com/gorilla/websocket"
 
+	// metrics: クライアントへの送信量の集計（SetEgressMetrics）
+	"github.com/robot-ai-webapp/gateway/internal/metrics"
+
 	// protocol: 独自メッセージフォーマットのエンコード/デコードを行うパッケージ。
 	// WebSocket上でやり取りするメッセージの構造と変換を定義しています。
 	"github.com/robot-ai-webapp/gateway/internal/protocol"
@@ -97,9 +118,9 @@ type WebSocketServer struct {
 	// handler: 受信したメッセージをビジネスロジックに振り分ける
 	handler *Handler
 
-	// codec: メッセージのシリアライズ/デシリアライズ
-	// バイナリデータ ↔ 構造化メッセージ の変換を行います
-	codec *protocol.Codec
+	// codec: サーバー内部の形式（MessagePack）でのエンコード
+	// クライアントとの読み書きには、接続ごとに選ばれた Client.Codec を使います
+	codec protocol.Codec
 
 	// upgrader: HTTP接続をWebSocket接続にアップグレードするための設定
 	// 【websocket.Upgrader】
@@ -108,29 +129,53 @@ type WebSocketServer struct {
 
 	// logger: 構造化ログ出力
 	logger *zap.Logger
+
+	// errorBudget: 何回連続でエラーになったら切断するか（0 なら切断しない）
+	// SetErrorBudget() で設定します。
+	errorBudget int
+
+	// writers: 動作中の writePump の数（Shutdown で送信し終わるのを待つため）
+	writers sync.WaitGroup
+
+	// egress: メッセージタイプ×ロボットごとの送信量（SetEgressMetrics で設定、nil なら数えない）
+	egress *metrics.EgressMetrics
 }
 
+// defaultWSBufferSize: バッファサイズを指定しなかった時の読み書きバッファ（バイト）
+// 一般的なメッセージ（コマンド、1サンプル分のセンサーデータ）には十分な大きさです。
+const defaultWSBufferSize = 4096
+
 // =============================================================================
 // NewWebSocketServer - WebSocketサーバーのコンストラクタ
 // =============================================================================
 //
-// 各コンポーネント（hub, handler, logger）を受け取り、
+// 各コンポーネント（hub, handler, logger）とバッファサイズを受け取り、
 // WebSocketサーバーを初期化して返します。
 //
 // 【websocket.Upgrader の設定】
 //   - ReadBufferSize/WriteBufferSize: 読み書きバッファのサイズ（バイト単位）
-//     4096バイト（4KB）は一般的なメッセージサイズに十分です。
+//     GATEWAY_WS_READ_BUFFER_SIZE / GATEWAY_WS_WRITE_BUFFER_SIZE で設定します。
+//     0 以下なら defaultWSBufferSize（4096バイト = 4KB）を使います。
+//     バッファは接続ごとに確保されるため、大きくするとシステムコールは減りますが、
+//     「接続数 × (読み + 書き)」だけメモリが増えます（例: 64KB ずつ × 1000接続 ≒ 128MB）。
 //   - CheckOrigin: CORS（クロスオリジン）チェック関数
 //     開発環境では全てのオリジンを許可（return true）しています。
 //     本番環境ではセキュリティのため、特定のオリジンのみ許可すべきです。
-func NewWebSocketServer(hub *Hub, handler *Handler, logger *zap.Logger) *WebSocketServer {
+func NewWebSocketServer(hub *Hub, handler *Handler, readBufferSize, writeBufferSize int, logger *zap.Logger) *WebSocketServer {
+	if readBufferSize <= 0 {
+		readBufferSize = defaultWSBufferSize
+	}
+	if writeBufferSize <= 0 {
+		writeBufferSize = defaultWSBufferSize
+	}
+
 	return &WebSocketServer{
 		hub:     hub,
 		handler: handler,
 		codec:   protocol.NewCodec(),
 		upgrader: websocket.Upgrader{
-			ReadBufferSize:  4096,
-			WriteBufferSize: 4096,
+			ReadBufferSize:  readBufferSize,
+			WriteBufferSize: writeBufferSize,
 			// 【CheckOrigin関数】
 			// クロスオリジンリクエストを許可するかどうかを判定する関数
 			// *http.Request を受け取り、bool を返す無名関数（クロージャ）です。
@@ -139,6 +184,8 @@ func NewWebSocketServer(hub *Hub, handler *Handler, logger *zap.Logger) *WebSock
 			CheckOrigin: func(r *http.Request) bool {
 				return true // Allow all origins in dev
 			},
+			// Sec-WebSocket-Protocol で要求されたフォーマットのうち、対応しているものを選んで返す
+			Subprotocols: protocol.CodecNames(),
 		},
 		logger: logger,
 	}
@@ -164,30 +211,75 @@ func NewWebSocketServer(hub *Hub, handler *Handler, logger *zap.Logger) *WebSock
 // Go標準のHTTPハンドラーのパラメータです。
 // - w: レスポンスを書き込むためのインターフェース
 // - r: クライアントからのHTTPリクエスト情報
+// =============================================================================
+// SetErrorBudget - クライアントごとのエラーバジェットを設定する
+// =============================================================================
+//
+// 【なぜ必要か？】
+// 不正なメッセージを送り続けるクライアント（バグでループしている等）に
+// エラー応答を返し続けると、ゲートウェイの資源を無駄に使い続けます。
+// 連続して budget 回エラーになったら、最後のエラーを送って切断します。
+// 正常に処理できたメッセージが1つでもあれば、カウントは0に戻ります。
+//
+// 緊急停止（estop）のメッセージはカウントの対象外です（成功・失敗どちらもカウントを変えない）。
+// budget が 0 以下ならエラーバジェットは無効です。サーバー起動前に呼んでください。
+func (s *WebSocketServer) SetErrorBudget(budget int) {
+	s.errorBudget = budget
+}
+
+// =============================================================================
+// SetEgressMetrics - クライアントへの送信量を集計する
+// =============================================================================
+//
+// 書き出したメッセージごとに、メッセージタイプとロボットIDを見出しだけ読んで
+// （protocol.PeekHeader）、送ったバイト数を加算します。サーバー起動前に呼んでください。
+func (s *WebSocketServer) SetEgressMetrics(m *metrics.EgressMetrics) {
+	s.egress = m
+}
+
 func (s *WebSocketServer) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	// 【Upgrade - HTTPからWebSocketへの切り替え】
 	// HTTPの「101 Switching Protocols」レスポンスを送信し、
 	// 接続をWebSocketプロトコルに切り替えます。
 	// 失敗した場合（ブラウザがWebSocketをサポートしないなど）、エラーを返します。
+	// 【フォーマット（Codec）の選択】
+	// ?format= で対応していないフォーマットを指定された場合は、接続前に 400 で断ります。
+	codec, err := requestedCodec(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	conn, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		s.logger.Error("WebSocket upgrade failed", zap.Error(err))
 		return
 	}
+	// Sec-WebSocket-Protocol で合意したフォーマットがあれば、そちらを優先する
+	if c, ok := protocol.LookupCodec(conn.Subprotocol()); ok {
+		codec = c
+	}
 
 	// 【Clientの作成】
 	// 新しく接続したクライアントの情報を保持する構造体を作成します。
 	// - ID: 一意な識別子（日時+ランダム文字列で生成）
 	// - Conn: WebSocket接続オブジェクト（メッセージの送受信に使用）
 	// - Send: 送信バッファ用のバッファ付きチャネル（256メッセージ分）
+	// - sensorQueue: センサーデータ専用の送信キュー（満杯の時の扱いは overflow.go）
 	// - Subscriptions: どのロボットのデータを購読するかのマップ
 	client := &Client{
 		ID:            generateClientID(),
 		Conn:          conn,
 		Send:          make(chan []byte, 256),
 		Subscriptions: make(map[string]bool),
+		Codec:         codec,
+		sensorQueue:   make(chan []byte, sensorQueueSize),
+		latestReady:   make(chan struct{}, 1),
 	}
 
+	// writePump の数は、Hub に登録する（CloseAll の対象になる）前に数えておく
+	s.writers.Add(1)
+
 	// Hubにクライアントを登録（他のゴルーチンからも参照可能にする）
 	s.hub.Register(client)
 
@@ -196,6 +288,7 @@ func (s *WebSocketServer) HandleWebSocket(w http.ResponseWriter, r *http.Request
 	s.logger.Info("WebSocket client connected",
 		zap.String("client_id", client.ID),
 		zap.String("remote_addr", conn.RemoteAddr().String()),
+		zap.String("format", codec.Name()),
 	)
 
 	// 【ゴルーチンでポンプを起動】
@@ -210,6 +303,41 @@ func (s *WebSocketServer) HandleWebSocket(w http.ResponseWriter, r *http.Request
 	go s.readPump(client)
 }
 
+// =============================================================================
+// Shutdown - 全クライアントに停止を予告してから接続を閉じる
+// =============================================================================
+//
+// 【なぜ必要？】
+// httpServer.Shutdown() は WebSocket の接続（ハイジャック済み）を閉じないため、
+// プロセスの終了とともに接続が突然切れ、クライアントには原因がわかりません。
+// 先に server_shutdown メッセージを送り、1001（Going Away）の Close フレームで閉じれば、
+// クライアントは「サーバーを再起動中」と表示して再接続を予定できます。
+//
+// 全クライアントの writePump が送信を終えるか、ctx が終わるまで待ちます。
+// main のシャットダウン処理で、httpServer.Shutdown() より前に呼んでください。
+func (s *WebSocketServer) Shutdown(ctx context.Context, reason string) error {
+	msg := protocol.NewMessage(protocol.MsgTypeServerShutdown, "")
+	msg.Payload["reason"] = reason
+	msg.Payload["reconnect"] = true
+	data, err := s.codec.Encode(msg)
+	if err != nil {
+		return err
+	}
+	s.hub.CloseAll(data, reason)
+
+	done := make(chan struct{})
+	go func() {
+		s.writers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // =============================================================================
 // readPump - クライアントからのメッセージ読み取りポンプ
 // =============================================================================
@@ -234,11 +362,17 @@ func (s *WebSocketServer) readPump(client *Client) {
 	// 関数終了時に実行される処理を登録します。
 	// 無名関数（クロージャ）を使って複数の処理をまとめています。
 	// これにより、どのような原因で関数が終了しても確実にクリーンアップされます。
+	// closeCode / closeReason: ゲートウェイの判断で切断する時に、Close フレームで伝える原因
+	// closeCode が 0 でなければ接続をすぐには閉じず、writePump が残りのメッセージと
+	// Close フレームを送り終えてから閉じる（クライアントは切断の原因を知ることができる）。
+	closeCode, closeReason := 0, ""
 	defer func() {
 		// Hubからクライアントを登録解除
-		s.hub.Unregister(client)
-		// WebSocket接続を閉じる
-		client.Conn.Close()
+		s.hub.Disconnect(client, closeCode, closeReason)
+		// WebSocket接続を閉じる（相手が閉じた・回線が切れた場合は、Close フレームを送れないので即座に）
+		if closeCode == 0 {
+			client.Conn.Close()
+		}
 	}()
 
 	// 【接続の読み取り設定】
@@ -281,6 +415,11 @@ func (s *WebSocketServer) readPump(client *Client) {
 					zap.Error(err),
 				)
 			}
+			// Pong が pongWait の間届かなかった（無応答）なら、その旨を伝えて閉じる
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				closeCode, closeReason = websocket.CloseGoingAway, "idle timeout"
+			}
 			// エラーが発生したらループを抜ける → defer でクリーンアップ
 			return
 		}
@@ -289,13 +428,19 @@ func (s *WebSocketServer) readPump(client *Client) {
 		// 受信したバイナリデータを、protocol.Message 構造体に変換します。
 		// JSONやProtobufなどのフォーマットで送られてきたデータを解析します。
 		// Decode the message
-		msg, err := s.codec.Decode(data)
+		msg, err := clientCodec(client).Decode(data)
 		if err != nil {
 			s.logger.Error("Message decode error",
 				zap.String("client_id", client.ID),
 				zap.Error(err),
 			)
-			// デコードエラーの場合は接続を切らず、次のメッセージを待つ
+			// デコードエラーの場合は接続を切らず、エラーを返して次のメッセージを待つ
+			// （ただし連続しすぎた場合はエラーバジェットで切断する）
+			s.handler.sendErrorCode(client, "", protocol.ErrCodeInvalidMessage, "Invalid message: "+err.Error())
+			if s.recordResult(client, true) {
+				closeCode, closeReason = websocket.ClosePolicyViolation, "error budget exceeded"
+				return
+			}
 			continue
 		}
 
@@ -303,11 +448,56 @@ func (s *WebSocketServer) readPump(client *Client) {
 		// デコードされたメッセージを Handler に渡して処理します。
 		// Handler はメッセージの種類（速度コマンド、緊急停止など）に応じて
 		// 適切な処理を行います（handler.go で実装）。
+		// 処理中にエラー応答を返したかどうかは、エラー送信数の変化で判定します。
 		// Handle the message
+		// セッションの記録（対象の接続だけ、session_record.go）
+		s.handler.recordInbound(client, msg)
+		errorsBefore := client.errorsSent.Load()
 		s.handler.HandleMessage(client, msg)
+
+		// 処理中に panic した（HandleMessage が回復した）接続は、状態が壊れている可能性があるため閉じる
+		if client.panicked.Load() {
+			closeCode, closeReason = websocket.CloseInternalServerErr, "internal error"
+			return
+		}
+
+		// 緊急停止はエラーバジェットの対象外（失敗しても切断しない）
+		if msg.Type == protocol.MsgTypeEmergencyStop {
+			continue
+		}
+		if s.recordResult(client, client.errorsSent.Load() != errorsBefore) {
+			closeCode, closeReason = websocket.ClosePolicyViolation, "error budget exceeded"
+			return
+		}
 	}
 }
 
+// =============================================================================
+// recordResult - メッセージの処理結果をエラーバジェットに反映する
+// =============================================================================
+//
+// failed が true なら連続エラー数を増やし、false なら0に戻します。
+// バジェットを超えたら最後のエラーを送り、true（切断すべき）を返します。
+func (s *WebSocketServer) recordResult(client *Client, failed bool) bool {
+	if !failed {
+		client.consecutiveErrors = 0
+		return false
+	}
+
+	client.consecutiveErrors++
+	if s.errorBudget <= 0 || client.consecutiveErrors < s.errorBudget {
+		return false
+	}
+
+	s.logger.Warn("Disconnecting client: error budget exceeded",
+		zap.String("client_id", client.ID),
+		zap.Int("consecutive_errors", client.consecutiveErrors),
+		zap.Int("error_budget", s.errorBudget),
+	)
+	s.handler.sendErrorCode(client, "", protocol.ErrCodeErrorBudgetExceeded, "Too many consecutive errors; disconnecting")
+	return true
+}
+
 // =============================================================================
 // writePump - クライアントへのメッセージ書き込みポンプ
 // =============================================================================
@@ -321,9 +511,11 @@ func (s *WebSocketServer) readPump(client *Client) {
 // そのため、全ての書き込みを1つのゴルーチン（writePump）に集約し、
 // チャネル（client.Send）経由でメッセージを受け取ります。
 //
-// 【select文による2つのイベント監視】
+// 【select文による4つのイベント監視】
 // 1. client.Send チャネルからメッセージが来たら送信
-// 2. ticker.C からPing間隔が来たらPingを送信
+// 2. センサーデータ専用のキュー（sensorQueue）からデータが来たら送信（overflow.go）
+// 3. latest-only のスロットにサンプルが入ったら、最新のサンプルを送信（latest_only.go）
+// 4. ticker.C からPing間隔が来たらPingを送信
 func (s *WebSocketServer) writePump(client *Client) {
 	// Pingを定期的に送信するためのタイマー
 	ticker := time.NewTicker(pingPeriod)
@@ -331,9 +523,16 @@ func (s *WebSocketServer) writePump(client *Client) {
 	defer func() {
 		ticker.Stop()
 		client.Conn.Close()
+		s.writers.Done()
 	}()
 
 	// 【無限ループ + select で2つのイベントを監視】
+	codec := clientCodec(client)
+	frameType := websocket.BinaryMessage
+	if codec.Name() == protocol.FormatJSON {
+		frameType = websocket.TextMessage
+	}
+
 	for {
 		select {
 		case message, ok := <-client.Send:
@@ -346,20 +545,38 @@ func (s *WebSocketServer) writePump(client *Client) {
 
 			if !ok {
 				// チャネルが閉じられた → クライアントに切断メッセージを送信
-				// CloseMessage は WebSocket の正常終了を示すフレームです
-				client.Conn.WriteMessage(websocket.CloseMessage, nil)
+				// CloseMessage は WebSocket の終了を示すフレームです。
+				// 切断を決めた処理が Hub.Disconnect / CloseAll で設定したコードと理由を付けて送ります
+				// （例: 1001 "server shutting down"、1008 "error budget exceeded"）。
+				// 設定がなければ正常終了（1000）です。
+				code := client.closeCode
+				if code == 0 {
+					code = websocket.CloseNormalClosure
+				}
+				client.Conn.WriteMessage(websocket.CloseMessage,
+					websocket.FormatCloseMessage(code, client.closeReason))
 				return
 			}
 
-			// 【BinaryMessageでメッセージを送信】
-			// WebSocketには「テキストメッセージ」と「バイナリメッセージ」があります。
-			// バイナリメッセージはProtobufやMessagePackなどの効率的な
-			// シリアライゼーション形式に適しています。
-			if err := client.Conn.WriteMessage(websocket.BinaryMessage, message); err != nil {
+			if !s.writeMessage(client, codec, frameType, message) {
 				// 書き込みエラー → 接続に問題があるので終了
 				return
 			}
 
+		case message := <-client.sensorQueue:
+			// センサーデータは Send とは別のキューで届く（溜まってもアラートや ACK を押し出さない）
+			if !s.writeMessage(client, codec, frameType, message) {
+				return
+			}
+
+		case <-client.latestReady:
+			// latest-only のトピックは Send バッファの順番を待たず、最新のサンプルだけを送る
+			for _, internal := range client.TakeLatest() {
+				if !s.writeMessage(client, codec, frameType, internal) {
+					return
+				}
+			}
+
 		case <-ticker.C:
 			// 【Pingメッセージの送信】
 			// 定期的にPingを送信して、接続が生きているか確認します。
@@ -374,6 +591,40 @@ func (s *WebSocketServer) writePump(client *Client) {
 	}
 }
 
+// writeMessage - サーバー内部の形式のメッセージを、クライアントのフォーマットに変換して書き込む
+//
+// 書き込みに失敗したら false を返します（接続に問題があるので writePump を終了する）。
+// 変換に失敗したメッセージはログに残して読み飛ばし、true を返します。
+func (s *WebSocketServer) writeMessage(client *Client, codec protocol.Codec, frameType int, internal []byte) bool {
+	// 【クライアントのフォーマットへの変換】
+	// Send に入っているのはサーバー内部の形式（MessagePack）です。
+	// 別のフォーマットを選んだクライアントには、ここで変換してから送ります。
+	message, err := protocol.Transcode(internal, codec)
+	if err != nil {
+		s.logger.Error("Failed to transcode message",
+			zap.String("client_id", client.ID),
+			zap.Error(err),
+		)
+		return true
+	}
+
+	// 【BinaryMessage / TextMessage でメッセージを送信】
+	// WebSocketには「テキストメッセージ」と「バイナリメッセージ」があります。
+	// バイナリメッセージはProtobufやMessagePackなどの効率的な
+	// シリアライゼーション形式に適しています。JSON はテキストメッセージで送ります。
+	client.Conn.SetWriteDeadline(time.Now().Add(writeWait))
+	if err := client.Conn.WriteMessage(frameType, message); err != nil {
+		return false
+	}
+	s.handler.recordOutbound(client, internal)
+	// 送信量の集計（タイプとロボットは内部の形式の見出しから読む）
+	if s.egress != nil {
+		msgType, robotID := protocol.PeekHeader(internal)
+		s.egress.Add(string(msgType), robotID, len(message))
+	}
+	return true
+}
+
 // =============================================================================
 // HealthHandler - ヘルスチェック用HTTPハンドラー
 // =============================================================================
@@ -396,6 +647,39 @@ func (s *WebSocketServer) HealthHandler(w http.ResponseWriter, r *http.Request)
 	w.Write([]byte(`{"status":"ok","service":"gateway"}`))
 }
 
+// =============================================================================
+// requestedCodec / clientCodec - クライアントのフォーマット（Codec）
+// =============================================================================
+//
+// 【フォーマットの指定方法（優先順）】
+//  1. Sec-WebSocket-Protocol ヘッダー（例: new WebSocket(url, ["json"])）
+//  2. クエリパラメータ ?format=json（ヘッダーを付けられないクライアント向け）
+//  3. どちらもなければ AutoCodec（MessagePack → JSON の順にデコードを試す従来の動作）
+//
+// フォーマットを指定したクライアントは、そのフォーマットだけでデコードされるため、
+// 受信のたびにフォーマットを推測し直すオーバーヘッドがありません。
+
+// requestedCodec は ?format= で指定されたフォーマットを返す（指定がなければ AutoCodec）
+func requestedCodec(r *http.Request) (protocol.Codec, error) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		return protocol.NewCodec(), nil
+	}
+	codec, ok := protocol.LookupCodec(format)
+	if !ok {
+		return nil, fmt.Errorf("unsupported format %q (supported: %s)", format, strings.Join(protocol.CodecNames(), ", "))
+	}
+	return codec, nil
+}
+
+// clientCodec はクライアントの Codec を返す（未設定なら AutoCodec）
+func clientCodec(client *Client) protocol.Codec {
+	if client.Codec == nil {
+		return protocol.NewCodec()
+	}
+	return client.Codec
+}
+
 // =============================================================================
 // generateClientID - 一意なクライアントIDを生成する関数
 // =============================================================================
@@ -0,0 +1,75 @@
+// =============================================================================
+// ファイル: version.go（ビルド情報）
+// 概要: ゲートウェイのバージョン・Gitコミット・ビルド日時を保持するパッケージ
+//
+// 【なぜ必要か？】
+//
+//	現場で不具合が起きた時、「どのビルドが動いていたか」が分からないと
+//	原因のコミットを特定できない。/version エンドポイントと起動ログで
+//	デプロイ済みのビルドを確認できるようにする。
+//
+// 【値の埋め込み方: -ldflags "-X"】
+//
+//	下の変数はビルド時にリンカーで上書きする。ソースコードを変更する必要はない。
+//
+//	go build -ldflags "\
+//	  -X github.com/robot-ai-webapp/gateway/internal/version.Version=1.2.0 \
+//	  -X github.com/robot-ai-webapp/gateway/internal/version.GitCommit=$(git rev-parse --short HEAD) \
+//	  -X github.com/robot-ai-webapp/gateway/internal/version.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)" \
+//	  ./cmd/gateway/
+//
+//	-X は「文字列型のパッケージ変数」にしか使えないため、const ではなく var にしている。
+//
+// =============================================================================
+package version
+
+import (
+	"encoding/json"
+	"net/http"
+
+	// runtime: Go ランタイムの情報を取得する標準ライブラリ。
+	// runtime.Version() でビルドに使った Go のバージョン（例: "go1.22.5"）が分かる。
+	"runtime"
+)
+
+// ビルド時に -ldflags "-X" で埋め込まれる値。
+// 埋め込まれなかった場合（go run など）は開発ビルドとして既定値のままになる。
+var (
+	Version   = "dev"     // リリースバージョン（例: "1.2.0"）
+	GitCommit = "unknown" // ビルド元のGitコミットハッシュ
+	BuildTime = "unknown" // ビルド日時（UTC, RFC3339）
+)
+
+// =============================================================================
+// Info: バージョン情報をまとめた構造体（/version のレスポンス形式）
+// =============================================================================
+type Info struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"git_commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get: 現在のバイナリのバージョン情報を返す
+func Get() Info {
+	return Info{
+		Version:   Version,
+		GitCommit: GitCommit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+}
+
+// =============================================================================
+// Handler: /version エンドポイントのHTTPハンドラー
+//
+// レスポンス例:
+//
+//	{"version":"1.2.0","git_commit":"a1b2c3d","build_time":"2026-10-15T09:00:00Z","go_version":"go1.22.5"}
+//
+// =============================================================================
+func Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(Get())
+}
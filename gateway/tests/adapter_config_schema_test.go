@@ -0,0 +1,90 @@
+// =============================================================================
+// ファイル: adapter_config_schema_test.go
+// 概要: アダプター設定のスキーマ検証（ConfigSchema）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/adapter/mock"
+	"go.uber.org/zap"
+)
+
+// TestConfigSchema_ValidateAppliesDefaults は省略されたキーにデフォルト値が入り、元の config が変わらないことをテストする
+func TestConfigSchema_ValidateAppliesDefaults(t *testing.T) {
+	// Arrange
+	schema := adapter.ConfigSchema{
+		"host": {Type: adapter.ConfigString, Required: true},
+		"port": {Type: adapter.ConfigNumber, Default: 9090},
+	}
+	config := map[string]any{"host": "10.0.0.1"}
+
+	// Act
+	got, err := schema.Validate(config)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got["host"] != "10.0.0.1" || got["port"] != 9090 {
+		t.Errorf("Expected host and the default port, got %v", got)
+	}
+	if _, ok := config["port"]; ok {
+		t.Error("Expected the original config to be left unchanged")
+	}
+}
+
+// TestConfigSchema_ValidateReportsAllProblems は未知のキー・必須キーの欠落・型の違いがまとめて報告されることをテストする
+func TestConfigSchema_ValidateReportsAllProblems(t *testing.T) {
+	// Arrange: host をタイプミスし、port に数値でない文字列を渡す
+	schema := adapter.ConfigSchema{
+		"host": {Type: adapter.ConfigString, Required: true},
+		"port": {Type: adapter.ConfigNumber},
+	}
+
+	// Act
+	_, err := schema.Validate(map[string]any{"hots": "10.0.0.1", "port": "http"})
+
+	// Assert
+	if err == nil {
+		t.Fatal("Expected an error for an invalid config")
+	}
+	for _, want := range []string{`unknown config key "hots"`, `missing required config key "host"`, `config key "port" must be a number`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected the error to contain %q, got %v", want, err)
+		}
+	}
+}
+
+// TestMockAdapter_ConnectRejectsInvalidConfig はモックアダプターが不正な設定での接続を拒否することをテストする
+func TestMockAdapter_ConnectRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]any
+		want   string
+	}{
+		{"typo'd key", map[string]any{"enabled_topic": "odom"}, `unknown config key "enabled_topic"`},
+		{"wrong type", map[string]any{"velocity_ramp_ms": true}, `config key "velocity_ramp_ms" must be a number`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			m := mock.NewMockAdapter(zap.NewNop())
+
+			// Act
+			err := m.Connect(context.Background(), tt.config)
+
+			// Assert
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Expected an error containing %q, got %v", tt.want, err)
+			}
+			if m.IsConnected() {
+				t.Error("Expected the adapter to stay disconnected")
+			}
+		})
+	}
+}
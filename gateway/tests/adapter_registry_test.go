@@ -0,0 +1,73 @@
+// =============================================================================
+// ファイル: adapter_registry_test.go
+// 概要: アダプターレジストリ（同じロボットIDの二重作成・差し替え）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"go.uber.org/zap"
+)
+
+// TestRegistry_CreateAdapterDuplicateID は同じロボットIDでの二重作成がエラーになり、
+// 既存のアダプターがそのまま残ることをテストする
+func TestRegistry_CreateAdapterDuplicateID(t *testing.T) {
+	// Arrange
+	registry := setupMockRegistry(zap.NewNop())
+	first, err := registry.CreateAdapter("robot-1", "mock")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Act
+	second, err := registry.CreateAdapter("robot-1", "mock")
+
+	// Assert
+	if !errors.Is(err, adapter.ErrAdapterExists) {
+		t.Fatalf("Expected ErrAdapterExists, got %v", err)
+	}
+	if second != nil {
+		t.Error("Expected no adapter to be returned on collision")
+	}
+	if got, _ := registry.GetAdapter("robot-1"); got != first {
+		t.Error("Expected the original adapter to remain registered")
+	}
+}
+
+// TestRegistry_ReplaceAdapterDisconnectsOld は差し替え時に古いアダプターが切断されることをテストする
+func TestRegistry_ReplaceAdapterDisconnectsOld(t *testing.T) {
+	// Arrange: 接続済みのアダプター
+	registry := setupMockRegistry(zap.NewNop())
+	old := connectRobot(t, registry, "robot-1", "mock", map[string]any{"enabled_topics": "battery"})
+
+	// Act
+	replaced, err := registry.ReplaceAdapter(context.Background(), "robot-1", "mock")
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if old.IsConnected() {
+		t.Error("Expected the old adapter to be disconnected")
+	}
+	if got, _ := registry.GetAdapter("robot-1"); got != replaced || got == old {
+		t.Error("Expected the new adapter to be registered")
+	}
+}
+
+// TestRegistry_ReplaceAdapterUnknownType は未知のタイプでは既存のアダプターに触れないことをテストする
+func TestRegistry_ReplaceAdapterUnknownType(t *testing.T) {
+	registry := setupMockRegistry(zap.NewNop())
+	old := connectRobot(t, registry, "robot-1", "mock", map[string]any{"enabled_topics": "battery"})
+
+	if _, err := registry.ReplaceAdapter(context.Background(), "robot-1", "ros2"); err == nil {
+		t.Fatal("Expected an error for an unknown adapter type")
+	}
+	if got, _ := registry.GetAdapter("robot-1"); got != old || !old.IsConnected() {
+		t.Error("Expected the original adapter to stay registered and connected")
+	}
+}
@@ -0,0 +1,129 @@
+// =============================================================================
+// ファイル: adapter_type_limit_test.go
+// 概要: アダプタータイプごとの作成数の上限（Registry.SetTypeLimits、/stats）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/config"
+	"go.uber.org/zap"
+)
+
+// TestAdapterTypeLimit_RejectsCreateAtLimit は上限に達したタイプだけが作れなくなることをテストする
+func TestAdapterTypeLimit_RejectsCreateAtLimit(t *testing.T) {
+	// Arrange
+	registry := setupTypePolicyRegistry()
+	registry.SetTypeLimits(map[string]int{"mock": 2})
+	for _, id := range []string{"robot-1", "robot-2"} {
+		if _, err := registry.CreateAdapter(id, "mock"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// Act
+	_, err := registry.CreateAdapter("robot-3", "mock")
+
+	// Assert
+	if !errors.Is(err, adapter.ErrAdapterTypeLimit) {
+		t.Fatalf("Expected ErrAdapterTypeLimit, got %v", err)
+	}
+	if _, ok := registry.GetAdapter("robot-3"); ok {
+		t.Error("Expected no adapter to be registered")
+	}
+	if _, err := registry.CreateAdapter("robot-3", "sim"); err != nil {
+		t.Errorf("Expected types without a limit to be unlimited, got %v", err)
+	}
+}
+
+// TestAdapterTypeLimit_RemoveAndReplaceFreeSlots は削除と差し替えで台数が正しく数え直されることをテストする
+func TestAdapterTypeLimit_RemoveAndReplaceFreeSlots(t *testing.T) {
+	// Arrange
+	registry := setupTypePolicyRegistry()
+	registry.SetTypeLimits(map[string]int{"mock": 1})
+	if _, err := registry.CreateAdapter("robot-1", "mock"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Act & Assert: 同じロボットを同じタイプで差し替えるのは上限に数えない
+	if _, err := registry.ReplaceAdapter(context.Background(), "robot-1", "mock"); err != nil {
+		t.Fatalf("Expected replacing the same robot to be allowed, got %v", err)
+	}
+	// 別のロボットを mock に差し替えるのは上限を超える
+	if _, err := registry.ReplaceAdapter(context.Background(), "robot-2", "mock"); !errors.Is(err, adapter.ErrAdapterTypeLimit) {
+		t.Fatalf("Expected ErrAdapterTypeLimit, got %v", err)
+	}
+
+	// Act & Assert: 別タイプに差し替えると mock の枠が空く
+	if _, err := registry.ReplaceAdapter(context.Background(), "robot-1", "sim"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := registry.CreateAdapter("robot-2", "mock"); err != nil {
+		t.Fatalf("Expected the mock slot to be free after replacing, got %v", err)
+	}
+
+	// Act & Assert: 削除しても枠が空く
+	registry.RemoveAdapter("robot-2")
+	if got := registry.TypeCounts(); got["mock"] != 0 || got["sim"] != 1 {
+		t.Errorf("Expected mock=0 sim=1, got %v", got)
+	}
+	if _, err := registry.CreateAdapter("robot-3", "mock"); err != nil {
+		t.Errorf("Expected the mock slot to be free after removing, got %v", err)
+	}
+}
+
+// TestAdapterTypeLimit_StatsReportsCountsAndLimits は /stats がタイプごとの台数と上限を返すことをテストする
+func TestAdapterTypeLimit_StatsReportsCountsAndLimits(t *testing.T) {
+	// Arrange
+	registry := setupMockRegistry(zap.NewNop())
+	registry.SetTypeLimits(map[string]int{"mock": 5})
+	h, _ := setupPolicyHandler(t, registry, "mock")
+	if _, err := registry.CreateAdapter("robot-2", "mock"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Act
+	rec := httptest.NewRecorder()
+	h.StatsHandler(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+
+	// Assert
+	var body struct {
+		Robots            int            `json:"robots"`
+		AdaptersByType    map[string]int `json:"adapters_by_type"`
+		AdapterTypeLimits map[string]int `json:"adapter_type_limits"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if body.Robots != 2 || body.AdaptersByType["mock"] != 2 || body.AdapterTypeLimits["mock"] != 5 {
+		t.Errorf("Expected 2 mock robots with a limit of 5, got %+v", body)
+	}
+}
+
+// TestAdapterTypeLimit_ConfigParsing は GATEWAY_ADAPTER_TYPE_LIMITS の解析をテストする
+func TestAdapterTypeLimit_ConfigParsing(t *testing.T) {
+	// Arrange & Act
+	t.Setenv("GATEWAY_ADAPTER_TYPE_LIMITS", "ros2=100, mock=5")
+	cfg, err := config.Load()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Server.AdapterTypeLimits["ros2"] != 100 || cfg.Server.AdapterTypeLimits["mock"] != 5 {
+		t.Errorf("Expected ros2=100 mock=5, got %v", cfg.Server.AdapterTypeLimits)
+	}
+
+	// 台数が 0 以下なら起動を失敗させる
+	t.Setenv("GATEWAY_ADAPTER_TYPE_LIMITS", "ros2=0")
+	if _, err := config.Load(); err == nil {
+		t.Error("Expected an error for a zero limit")
+	}
+}
@@ -0,0 +1,100 @@
+// =============================================================================
+// ファイル: adapter_type_policy_test.go
+// 概要: アダプタータイプの許可リスト／拒否リスト（Registry.SetTypePolicy）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/adapter/mock"
+	"github.com/robot-ai-webapp/gateway/internal/config"
+	"go.uber.org/zap"
+)
+
+// setupTypePolicyRegistry: "mock" と "sim" のファクトリを登録したレジストリを作る
+func setupTypePolicyRegistry() *adapter.Registry {
+	registry := adapter.NewRegistry(zap.NewNop())
+	registry.RegisterFactory("mock", mock.Factory)
+	registry.RegisterFactory("sim", mock.Factory)
+	return registry
+}
+
+// TestAdapterTypePolicy_DenyRejectsRegisteredType は拒否したタイプはファクトリがあっても作れないことをテストする
+func TestAdapterTypePolicy_DenyRejectsRegisteredType(t *testing.T) {
+	// Arrange
+	registry := setupTypePolicyRegistry()
+	registry.SetTypePolicy(nil, []string{"mock"})
+
+	// Act
+	_, err := registry.CreateAdapter("robot-1", "mock")
+
+	// Assert
+	if !errors.Is(err, adapter.ErrAdapterTypeNotAllowed) {
+		t.Fatalf("Expected ErrAdapterTypeNotAllowed, got %v", err)
+	}
+	if _, ok := registry.GetAdapter("robot-1"); ok {
+		t.Error("Expected no adapter to be registered")
+	}
+	if _, err := registry.CreateAdapter("robot-2", "sim"); err != nil {
+		t.Errorf("Expected other types to be allowed, got %v", err)
+	}
+}
+
+// TestAdapterTypePolicy_AllowListRestrictsTypes は許可リストにないタイプが作れず、一覧にも出ないことをテストする
+func TestAdapterTypePolicy_AllowListRestrictsTypes(t *testing.T) {
+	// Arrange
+	registry := setupTypePolicyRegistry()
+	registry.SetTypePolicy([]string{"sim"}, nil)
+
+	// Act
+	_, mockErr := registry.CreateAdapter("robot-1", "mock")
+	_, replaceErr := registry.ReplaceAdapter(context.Background(), "robot-1", "mock")
+	types := registry.ListFactories()
+
+	// Assert
+	if !errors.Is(mockErr, adapter.ErrAdapterTypeNotAllowed) || !errors.Is(replaceErr, adapter.ErrAdapterTypeNotAllowed) {
+		t.Errorf("Expected mock to be rejected, got %v / %v", mockErr, replaceErr)
+	}
+	if !slices.Equal(types, []string{"sim"}) {
+		t.Errorf("Expected ListFactories to return only sim, got %v", types)
+	}
+}
+
+// TestAdapterTypePolicy_DenyOverridesAllow は両方に書かれたタイプは拒否されることをテストする
+func TestAdapterTypePolicy_DenyOverridesAllow(t *testing.T) {
+	registry := setupTypePolicyRegistry()
+	registry.SetTypePolicy([]string{"mock", "sim"}, []string{"mock"})
+
+	if registry.TypeAllowed("mock") || !registry.TypeAllowed("sim") {
+		t.Errorf("Expected mock denied and sim allowed, got mock=%v sim=%v",
+			registry.TypeAllowed("mock"), registry.TypeAllowed("sim"))
+	}
+}
+
+// TestAdapterTypePolicy_SelfTestRequiresMock はセルフテストを有効にしたまま mock を拒否すると設定エラーになることをテストする
+func TestAdapterTypePolicy_SelfTestRequiresMock(t *testing.T) {
+	// Arrange
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg.Server.AdapterTypesDeny = []string{"mock"}
+
+	// Act & Assert: セルフテストが有効なら拒否される
+	cfg.Server.SelfTestEnabled = true
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "GATEWAY_SELFTEST_ENABLED") {
+		t.Errorf("Expected a self-test error, got %v", err)
+	}
+
+	// Act & Assert: セルフテストを無効にすれば通る
+	cfg.Server.SelfTestEnabled = false
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected the config to be valid, got %v", err)
+	}
+}
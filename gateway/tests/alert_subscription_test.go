@@ -0,0 +1,54 @@
+// =============================================================================
+// ファイル: alert_subscription_test.go
+// 概要: 安全アラートだけの購読（subscribe_alerts）と、アラートの配信先のテストコード
+// =============================================================================
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// TestAlertSubscription_RoutesAlerts はアラートがアラート購読者とロボットの購読者にだけ届くことをテストする
+func TestAlertSubscription_RoutesAlerts(t *testing.T) {
+	// Arrange: アラート購読者・robot-1 の購読者・robot-2 の購読者
+	logger := zap.NewNop()
+	hub := server.NewHub(logger)
+	go hub.Run()
+	newClient := func(id string) *server.Client {
+		c := &server.Client{ID: id, Send: make(chan []byte, 4), Subscriptions: map[string]bool{}, Authenticated: true}
+		hub.Register(c)
+		return c
+	}
+	monitor, viewer1, viewer2 := newClient("monitor"), newClient("viewer-1"), newClient("viewer-2")
+	hub.SubscribeClient(viewer1, "robot-1")
+	hub.SubscribeClient(viewer2, "robot-2")
+	for i := 0; hub.ClientCount() < 3 && i < 100; i++ { // Run() が登録を反映するまで待つ
+		time.Sleep(time.Millisecond)
+	}
+	h := server.NewHandler(hub, setupMockRegistry(logger), nil, nil, nil, nil, nil, nil, logger)
+
+	h.HandleMessage(monitor, protocol.NewMessage(protocol.MsgTypeSubscribeAlerts, ""))
+	ack, err := protocol.NewCodec().Decode(<-monitor.Send)
+	if err != nil || ack.Type != protocol.MsgTypeCommandAck {
+		t.Fatalf("Expected cmd_ack for subscribe_alerts, got %v (%v)", ack, err)
+	}
+
+	// Act: robot-1 のアラート
+	h.NotifySensorStall("robot-1", "scan", true)
+
+	// Assert
+	if len(monitor.Send) != 1 {
+		t.Errorf("Expected the alert subscriber to receive the alert, got %d messages", len(monitor.Send))
+	}
+	if len(viewer1.Send) != 1 {
+		t.Errorf("Expected the robot-1 subscriber to receive the alert, got %d messages", len(viewer1.Send))
+	}
+	if len(viewer2.Send) != 0 {
+		t.Errorf("Expected the robot-2 subscriber not to receive the alert, got %d messages", len(viewer2.Send))
+	}
+}
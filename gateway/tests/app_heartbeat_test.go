@@ -0,0 +1,125 @@
+// =============================================================================
+// ファイル: app_heartbeat_test.go
+// 概要: アプリケーション層のハートビート（server_heartbeat / heartbeat）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/safety"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// setupHeartbeatHub: Run() 中の Hub に n 個のクライアントを登録する
+func setupHeartbeatHub(t *testing.T, n int) (*server.Hub, []*server.Client) {
+	t.Helper()
+	hub := server.NewHub(zap.NewNop())
+	go hub.Run()
+	clients := make([]*server.Client, n)
+	for i := range clients {
+		clients[i] = &server.Client{ID: fmt.Sprintf("client-%d", i+1), UserID: "user-1", Send: make(chan []byte, 8), Subscriptions: map[string]bool{}}
+		hub.Register(clients[i])
+	}
+	for i := 0; hub.ClientCount() < n && i < 100; i++ { // Run() が登録を反映するまで待つ
+		time.Sleep(time.Millisecond)
+	}
+	return hub, clients
+}
+
+// TestAppHeartbeat_BroadcastsSequencedHeartbeats は全クライアントに通し番号付きのハートビートが届くことをテストする
+func TestAppHeartbeat_BroadcastsSequencedHeartbeats(t *testing.T) {
+	// Arrange
+	hub, clients := setupHeartbeatHub(t, 2)
+	hb := server.NewAppHeartbeat(hub, protocol.NewCodec(), 15*time.Second, 0, zap.NewNop())
+	now := time.Now()
+
+	// Act
+	hb.Tick(now)
+	hb.Tick(now.Add(15 * time.Second))
+
+	// Assert: 各クライアントに seq 1, 2 が順に届く
+	for _, client := range clients {
+		for want := 1; want <= 2; want++ {
+			msg, err := protocol.NewCodec().Decode(<-client.Send)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if msg.Type != protocol.MsgTypeServerHeartbeat {
+				t.Fatalf("Expected server_heartbeat, got %s", msg.Type)
+			}
+			// 数値の型はコーデックによって異なるため、文字列にして比べる
+			if fmt.Sprint(msg.Payload["seq"]) != fmt.Sprint(want) {
+				t.Errorf("Expected seq %d for %s, got %v", want, client.ID, msg.Payload["seq"])
+			}
+			if fmt.Sprint(msg.Payload["interval_ms"]) != "15000" || fmt.Sprint(msg.Payload["timeout_ms"]) != "0" {
+				t.Errorf("Expected interval_ms 15000 and timeout_ms 0, got %v", msg.Payload)
+			}
+		}
+	}
+}
+
+// TestAppHeartbeat_DisconnectsSilentClients はタイムアウトを過ぎてもメッセージのないクライアントが切断されることをテストする
+func TestAppHeartbeat_DisconnectsSilentClients(t *testing.T) {
+	// Arrange: 送信しない設定（interval 0）で、タイムアウトだけを有効にする
+	hub, _ := setupHeartbeatHub(t, 2)
+	hb := server.NewAppHeartbeat(hub, protocol.NewCodec(), 0, time.Minute, zap.NewNop())
+
+	// Act & Assert: 登録直後はまだタイムアウトしていない
+	if n := hb.Tick(time.Now()); n != 0 {
+		t.Fatalf("Expected no client to be disconnected yet, got %d", n)
+	}
+
+	// Act: タイムアウトを過ぎた時刻で確認する
+	n := hb.Tick(time.Now().Add(2 * time.Minute))
+
+	// Assert
+	if n != 2 {
+		t.Fatalf("Expected 2 clients to be disconnected, got %d", n)
+	}
+	for i := 0; hub.ClientCount() > 0 && i < 100; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if hub.ClientCount() != 0 {
+		t.Errorf("Expected silent clients to be unregistered, %d remain", hub.ClientCount())
+	}
+}
+
+// TestAppHeartbeat_ClientHeartbeatKeepsConnection は heartbeat を送ったクライアントは切断されず、応答も返らないことをテストする
+func TestAppHeartbeat_ClientHeartbeatKeepsConnection(t *testing.T) {
+	// Arrange
+	logger := zap.NewNop()
+	hub, clients := setupHeartbeatHub(t, 2)
+	active, silent := clients[0], clients[1]
+	h := server.NewHandler(hub, setupMockRegistry(logger),
+		safety.NewEStopManager(nil, logger),
+		safety.NewVelocityLimiter(1.0, 2.0, logger),
+		nil, safety.NewOperationLock(time.Minute, logger),
+		nil, nil, logger)
+	hb := server.NewAppHeartbeat(hub, protocol.NewCodec(), 0, 50*time.Millisecond, logger)
+	time.Sleep(60 * time.Millisecond)
+
+	// Act: 未認証のクライアントが heartbeat を送る
+	h.HandleMessage(active, protocol.NewMessage(protocol.MsgTypeHeartbeat, ""))
+	n := hb.Tick(time.Now())
+
+	// Assert
+	if n != 1 {
+		t.Fatalf("Expected only the silent client to be disconnected, got %d", n)
+	}
+	select {
+	case data := <-active.Send:
+		t.Fatalf("Expected no reply to heartbeat, got %s", data)
+	default:
+	}
+	for i := 0; hub.ClientCount() > 1 && i < 100; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if _, ok := <-silent.Send; ok {
+		t.Error("Expected the silent client's send channel to be closed")
+	}
+}
@@ -0,0 +1,100 @@
+// =============================================================================
+// ファイル: array_limit_test.go
+// 概要: 配列の長さの制限（safety.ArrayLimiter）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"testing"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/safety"
+	"go.uber.org/zap"
+)
+
+// lidarSample: 指定した要素数の ranges を持つ LiDAR のサンプル
+func lidarSample(n int) adapter.SensorData {
+	return adapter.SensorData{
+		RobotID:  "robot-1",
+		Topic:    "scan",
+		DataType: "lidar",
+		Data:     map[string]any{"ranges": make([]float64, n), "range_max": 12.0},
+	}
+}
+
+// TestArrayLimit_TruncatesSensorArrays は上限を超えた配列が切り詰められ、元のデータは変わらないことをテストする
+func TestArrayLimit_TruncatesSensorArrays(t *testing.T) {
+	// Arrange
+	limiter := safety.NewArrayLimiter(100, safety.ArrayLimitTruncate, zap.NewNop())
+	data := lidarSample(1000)
+	original := data.Data
+
+	// Act
+	ok := limiter.CheckSensor(&data)
+
+	// Assert
+	if !ok {
+		t.Fatal("Expected the sample to be forwarded")
+	}
+	if got := len(data.Data["ranges"].([]float64)); got != 100 {
+		t.Errorf("Expected 100 ranges, got %d", got)
+	}
+	if got := len(original["ranges"].([]float64)); got != 1000 {
+		t.Errorf("Expected the adapter's data to be untouched, got %d ranges", got)
+	}
+	if limiter.Oversized()["robot-1/scan"] != 1 {
+		t.Errorf("Expected the oversized sample to be counted, got %v", limiter.Oversized())
+	}
+}
+
+// TestArrayLimit_DropModeDropsSample は drop モードではサンプルごと捨てられることをテストする
+func TestArrayLimit_DropModeDropsSample(t *testing.T) {
+	limiter := safety.NewArrayLimiter(100, safety.ArrayLimitDrop, zap.NewNop())
+	oversized := lidarSample(101)
+	normal := lidarSample(100)
+
+	if limiter.CheckSensor(&oversized) {
+		t.Error("Expected the oversized sample to be dropped")
+	}
+	if !limiter.CheckSensor(&normal) {
+		t.Error("Expected a sample within the limit to be forwarded")
+	}
+}
+
+// TestArrayLimit_ChecksNestedArrays は入れ子の配列も検査されることをテストする
+func TestArrayLimit_ChecksNestedArrays(t *testing.T) {
+	// Arrange: transforms[0].points が上限を超える
+	limiter := safety.NewArrayLimiter(3, safety.ArrayLimitTruncate, zap.NewNop())
+	data := adapter.SensorData{RobotID: "robot-1", Topic: "path", Data: map[string]any{
+		"transforms": []any{map[string]any{"points": []any{1.0, 2.0, 3.0, 4.0}}},
+	}}
+
+	// Act
+	limiter.CheckSensor(&data)
+
+	// Assert
+	transforms := data.Data["transforms"].([]any)
+	points := transforms[0].(map[string]any)["points"].([]any)
+	if len(points) != 3 {
+		t.Errorf("Expected 3 nested points, got %d", len(points))
+	}
+}
+
+// TestArrayLimit_RejectsOversizedCommand は上限を超える配列を含むコマンドが invalid_message で拒否されることをテストする
+func TestArrayLimit_RejectsOversizedCommand(t *testing.T) {
+	// Arrange
+	h, client := setupPolicyHandler(t, setupMockRegistry(zap.NewNop()), "mock")
+	h.SetArrayLimiter(safety.NewArrayLimiter(10, safety.ArrayLimitTruncate, zap.NewNop()))
+	msg := protocol.NewMessage(protocol.MsgTypeVelocityCommand, "robot-1")
+	msg.Payload["linear_x"] = 0.2
+	msg.Payload["ranges"] = make([]any, 11)
+
+	// Act
+	resp := sendAndDecode(t, h, client, msg)
+
+	// Assert
+	if resp.Type != protocol.MsgTypeError || resp.Payload["code"] != protocol.ErrCodeInvalidMessage {
+		t.Errorf("Expected invalid_message error, got %s %v", resp.Type, resp.Payload)
+	}
+}
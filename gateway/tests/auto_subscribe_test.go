@@ -0,0 +1,92 @@
+// =============================================================================
+// ファイル: auto_subscribe_test.go
+// 概要: 認証時の自動購読（auto_subscribe）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"testing"
+
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// TestAutoSubscribe_Modes はモードごとに購読されるロボットと、応答の subscriptions をテストする
+func TestAutoSubscribe_Modes(t *testing.T) {
+	tests := []struct {
+		name        string
+		serverMode  server.AutoSubscribeMode
+		payloadMode string // 空なら auth で指定しない
+		want        []string
+	}{
+		{"default is single", "", "", []string{"robot-1"}},
+		{"server none", server.AutoSubscribeNone, "", nil},
+		{"server all", server.AutoSubscribeAll, "", []string{"robot-1", "robot-2"}},
+		{"payload overrides server", server.AutoSubscribeNone, "all", []string{"robot-1", "robot-2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange: robot-1 と robot-2 が登録されている
+			logger := zap.NewNop()
+			registry := setupMockRegistry(logger)
+			for _, id := range []string{"robot-1", "robot-2"} {
+				if _, err := registry.CreateAdapter(id, "mock"); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			hub := server.NewHub(logger)
+			h := server.NewHandler(hub, registry, nil, nil, nil, nil, nil, nil, logger)
+			if tt.serverMode != "" {
+				h.SetAutoSubscribe(tt.serverMode)
+			}
+			auth := protocol.NewMessage(protocol.MsgTypeAuth, "robot-1")
+			auth.Payload["token"] = "token"
+			if tt.payloadMode != "" {
+				auth.Payload["auto_subscribe"] = tt.payloadMode
+			}
+			client := &server.Client{ID: "client-1", Send: make(chan []byte, 8), Subscriptions: map[string]bool{}}
+
+			// Act
+			resp := sendAndDecode(t, h, client, auth)
+
+			// Assert
+			got := hub.SubscribedRobots(client)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected subscriptions %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("Expected subscriptions %v, got %v", tt.want, got)
+				}
+			}
+			reported, _ := resp.Payload["subscriptions"].([]any)
+			if len(reported) != len(tt.want) {
+				t.Errorf("Expected the auth response to report %v, got %v", tt.want, resp.Payload["subscriptions"])
+			}
+		})
+	}
+}
+
+// TestAutoSubscribe_RejectsUnknownMode は不明なモードでは認証しないことをテストする
+func TestAutoSubscribe_RejectsUnknownMode(t *testing.T) {
+	// Arrange
+	logger := zap.NewNop()
+	h := server.NewHandler(server.NewHub(logger), setupMockRegistry(logger), nil, nil, nil, nil, nil, nil, logger)
+	auth := protocol.NewMessage(protocol.MsgTypeAuth, "robot-1")
+	auth.Payload["token"] = "token"
+	auth.Payload["auto_subscribe"] = "some"
+	client := &server.Client{ID: "client-1", Send: make(chan []byte, 8), Subscriptions: map[string]bool{}}
+
+	// Act
+	resp := sendAndDecode(t, h, client, auth)
+
+	// Assert
+	if resp.Type != protocol.MsgTypeError || resp.Payload["code"] != protocol.ErrCodeInvalidMessage {
+		t.Errorf("Expected an invalid_message error, got %v", resp.Payload)
+	}
+	if client.Authenticated {
+		t.Error("Expected the client not to be authenticated")
+	}
+}
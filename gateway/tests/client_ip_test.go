@@ -0,0 +1,68 @@
+// =============================================================================
+// ファイル: client_ip_test.go
+// 概要: ClientIPResolver（プロキシ背後でのクライアントIP解決）のテストコード
+// =============================================================================
+//
+// 【何を確かめるのか？】
+// X-Forwarded-For は誰でも書けるヘッダーです。
+// 「信頼済みプロキシ経由のときだけ信用する」というルールが守られていないと、
+// IPを偽装してレート制限をすり抜けられてしまいます。
+// =============================================================================
+package tests
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/robot-ai-webapp/gateway/internal/middleware"
+)
+
+// TestClientIP_NoTrustedProxies はデフォルト（誰も信頼しない）の動作をテストする
+// ヘッダーが付いていても無視し、接続元のIP（ポートなし）を返すことを確認する。
+func TestClientIP_NoTrustedProxies(t *testing.T) {
+	// Arrange: 信頼するプロキシなし
+	resolver, err := middleware.NewClientIPResolver(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	req := httptest.NewRequest("GET", "/health", nil)
+	req.RemoteAddr = "203.0.113.5:54321"
+	req.Header.Set("X-Forwarded-For", "1.2.3.4")
+
+	// Act
+	ip := resolver.ClientIP(req)
+
+	// Assert: 偽装されたヘッダーではなく接続元が使われる
+	if ip != "203.0.113.5" {
+		t.Errorf("Expected 203.0.113.5, got %s", ip)
+	}
+}
+
+// TestClientIP_TrustedProxyChain は信頼済みプロキシ経由の解決をテストする
+// 右から辿って信頼済みプロキシを読み飛ばし、最初の信頼していないIPを返す。
+// 一番左の値はクライアントが偽装できるので、採用されてはいけない。
+func TestClientIP_TrustedProxyChain(t *testing.T) {
+	// Arrange: 10.0.0.0/8 のロードバランサーを信頼する
+	resolver, err := middleware.NewClientIPResolver([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	req := httptest.NewRequest("GET", "/health", nil)
+	req.RemoteAddr = "10.0.0.2:443"
+	req.Header.Set("X-Forwarded-For", "9.9.9.9, 198.51.100.7, 10.0.0.3")
+
+	// Act
+	ip := resolver.ClientIP(req)
+
+	// Assert: 10.0.0.3（信頼済み）を飛ばした 198.51.100.7 が実クライアント
+	if ip != "198.51.100.7" {
+		t.Errorf("Expected 198.51.100.7, got %s", ip)
+	}
+}
+
+// TestClientIP_InvalidConfig は設定ミスが起動時にエラーになることをテストする
+func TestClientIP_InvalidConfig(t *testing.T) {
+	if _, err := middleware.NewClientIPResolver([]string{"not-an-ip"}); err == nil {
+		t.Error("Expected error for invalid trusted proxy, got nil")
+	}
+}
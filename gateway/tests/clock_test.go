@@ -0,0 +1,123 @@
+// =============================================================================
+// ファイル: clock_test.go
+// 概要: 差し替え可能な時計（clock.Mock）を使った、時間依存ロジックのテスト
+// =============================================================================
+//
+// 【何を確かめるのか？】
+// ロックの期限切れ・ウォッチドッグのタイムアウト・レート制限のウィンドウは、
+// 本来なら数秒〜1分待たないと確かめられません。
+// clock.Mock を注入して時計を手で進めれば、待たずに・毎回同じ結果でテストできます。
+// =============================================================================
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/robot-ai-webapp/gateway/internal/clock"
+	"github.com/robot-ai-webapp/gateway/internal/middleware"
+	"github.com/robot-ai-webapp/gateway/internal/safety"
+)
+
+// testEpoch - テストで使う固定の開始時刻
+var testEpoch = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// TestOperationLock_ExpiresWithMockClock - タイムアウトちょうどまでは有効で、過ぎたら失効するテスト
+func TestOperationLock_ExpiresWithMockClock(t *testing.T) {
+	// Arrange
+	clk := clock.NewMock(testEpoch)
+	lock := safety.NewOperationLock(5*time.Minute, zap.NewNop())
+	lock.SetClock(clk)
+	if _, err := lock.Acquire("robot-1", "user-1"); err != nil {
+		t.Fatalf("Failed to acquire lock: %v", err)
+	}
+
+	// Act & Assert: 期限の直前はまだ有効
+	clk.Advance(5*time.Minute - time.Second)
+	if !lock.CheckLock("robot-1", "user-1") {
+		t.Fatal("Expected the lock to be valid before the timeout")
+	}
+
+	// Act & Assert: 期限を過ぎると失効し、別のユーザーが取得できる
+	clk.Advance(2 * time.Second)
+	if lock.CheckLock("robot-1", "user-1") {
+		t.Error("Expected the lock to have expired after the timeout")
+	}
+	if _, err := lock.Acquire("robot-1", "user-2"); err != nil {
+		t.Errorf("Expected another user to acquire the expired lock, got %v", err)
+	}
+}
+
+// TestTimeoutWatchdog_TimesOutWithMockClock - コマンドが途絶えたロボットだけを停止するテスト
+func TestTimeoutWatchdog_TimesOutWithMockClock(t *testing.T) {
+	// Arrange
+	logger := zap.NewNop()
+	registry := setupMockRegistry(logger)
+	clk := clock.NewMock(testEpoch)
+	watchdog := safety.NewTimeoutWatchdog(time.Second, registry, logger)
+	watchdog.SetClock(clk)
+
+	var timedOut []string
+	watchdog.SetTimeoutCallback(func(robotID string) {
+		timedOut = append(timedOut, robotID)
+	})
+	watchdog.RecordCommand("robot-1")
+
+	// Act & Assert: タイムアウト前は何も起きない
+	clk.Advance(500 * time.Millisecond)
+	watchdog.CheckTimeouts(context.Background(), clk.Now())
+	if len(timedOut) != 0 {
+		t.Fatalf("Expected no timeout yet, got %v", timedOut)
+	}
+
+	// Act & Assert: タイムアウトを過ぎると1回だけ通知される
+	clk.Advance(time.Second)
+	watchdog.CheckTimeouts(context.Background(), clk.Now())
+	watchdog.CheckTimeouts(context.Background(), clk.Now())
+	if len(timedOut) != 1 || timedOut[0] != "robot-1" {
+		t.Errorf("Expected exactly one timeout for robot-1, got %v", timedOut)
+	}
+}
+
+// TestRateLimiter_WindowResetsWithMockClock - 上限に達したあと、1分経つとトークンが戻るテスト
+func TestRateLimiter_WindowResetsWithMockClock(t *testing.T) {
+	// Arrange: 1分あたり2リクエストまで
+	clk := clock.NewMock(testEpoch)
+	limiter := middleware.NewRateLimiter(2, nil, zap.NewNop())
+	limiter.SetClock(clk)
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	request := func() int {
+		req := httptest.NewRequest("GET", "/health", nil)
+		req.RemoteAddr = "203.0.113.5:54321"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Act & Assert: 3回目は拒否される
+	for i := 0; i < 2; i++ {
+		if code := request(); code != http.StatusOK {
+			t.Fatalf("Request %d: expected 200, got %d", i+1, code)
+		}
+	}
+	if code := request(); code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 at the limit, got %d", code)
+	}
+
+	// Act & Assert: ウィンドウ内ではまだ拒否、1分経てば再び許可される
+	clk.Advance(59 * time.Second)
+	if code := request(); code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 before the window resets, got %d", code)
+	}
+	clk.Advance(2 * time.Second)
+	if code := request(); code != http.StatusOK {
+		t.Errorf("Expected 200 after the window resets, got %d", code)
+	}
+}
@@ -0,0 +1,84 @@
+// =============================================================================
+// ファイル: codec_negotiation_test.go
+// 概要: 接続時のフォーマット（Codec）の選択のテストコード
+// =============================================================================
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// startCodecServer: WebSocket サーバーを起動し、ws:// の URL を返す
+func startCodecServer(t *testing.T) string {
+	t.Helper()
+	logger := zap.NewNop()
+	hub := server.NewHub(logger)
+	go hub.Run()
+	ws := server.NewWebSocketServer(hub, server.NewHandler(hub, nil, nil, nil, nil, nil, nil, nil, logger), 0, 0, logger)
+	srv := httptest.NewServer(http.HandlerFunc(ws.HandleWebSocket))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+// TestCodecNegotiation_JSONSubprotocol は Sec-WebSocket-Protocol: json で JSON のテキストフレームになることをテストする
+func TestCodecNegotiation_JSONSubprotocol(t *testing.T) {
+	// Arrange
+	dialer := websocket.Dialer{Subprotocols: []string{protocol.FormatJSON}}
+	conn, _, err := dialer.Dial(startCodecServer(t), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if conn.Subprotocol() != protocol.FormatJSON {
+		t.Fatalf("Expected the json subprotocol to be selected, got %q", conn.Subprotocol())
+	}
+
+	// Act: JSON で認証する
+	auth := protocol.NewMessage(protocol.MsgTypeAuth, "")
+	auth.Payload["token"] = "token"
+	data, _ := json.Marshal(auth)
+	if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Assert: 応答は JSON のテキストフレームで届く
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	frameType, reply, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if frameType != websocket.TextMessage {
+		t.Errorf("Expected a text frame, got %d", frameType)
+	}
+	var resp protocol.Message
+	if err := json.Unmarshal(reply, &resp); err != nil {
+		t.Fatalf("Expected a JSON reply, got %v", err)
+	}
+	if resp.Type != protocol.MsgTypeConnectionStatus || resp.Payload["authenticated"] != true {
+		t.Errorf("Expected an authenticated connection_status, got %s %v", resp.Type, resp.Payload)
+	}
+}
+
+// TestCodecNegotiation_UnknownFormatRejected は対応していない ?format= が 400 で断られることをテストする
+func TestCodecNegotiation_UnknownFormatRejected(t *testing.T) {
+	// Act
+	_, resp, err := websocket.DefaultDialer.Dial(startCodecServer(t)+"?format=xml", nil)
+
+	// Assert
+	if err == nil {
+		t.Fatal("Expected the handshake to fail for an unknown format")
+	}
+	if resp == nil || resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 Bad Request, got %v", resp)
+	}
+}
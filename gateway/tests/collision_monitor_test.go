@@ -0,0 +1,182 @@
+// =============================================================================
+// ファイル: collision_monitor_test.go
+// 概要: 衝突リスク監視（CollisionMonitor / NotifyCollisionRisk）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/config"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/safety"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// collisionScan: 全周 3m の壁に、前方（0°付近）だけ 0.3m の障害物があるスキャン（モックの LiDAR と同じ形）
+func collisionScan() map[string]any {
+	ranges := make([]float64, 360)
+	for i := range ranges {
+		ranges[i] = 3.0
+	}
+	for _, i := range []int{358, 359, 0, 1, 2} {
+		ranges[i] = 0.3
+	}
+	ranges[180] = 0 // 欠損（range_min 未満）は障害物として扱わない
+	return map[string]any{
+		"angle_min":       0.0,
+		"angle_max":       2 * math.Pi,
+		"angle_increment": math.Pi / 180,
+		"range_min":       0.1,
+		"range_max":       12.0,
+		"ranges":          ranges,
+	}
+}
+
+// newCollisionMonitor: robot-1 に vel の速度を指令している監視を作る
+func newCollisionMonitor(vel *adapter.Velocity) *safety.CollisionMonitor {
+	m := safety.NewCollisionMonitor(safety.CollisionSettings{Enabled: true, StopDistance: 0.5}, math.Pi/6, safety.CollisionActionEStop, zap.NewNop())
+	m.SetVelocitySource(func(string) adapter.Velocity { return *vel })
+	return m
+}
+
+// TestCollisionMonitor_DetectsObstacleInHeading は進行方向の近い障害物だけがリスクになることをテストする
+func TestCollisionMonitor_DetectsObstacleInHeading(t *testing.T) {
+	// Arrange
+	vel := adapter.Velocity{LinearX: 0.3}
+	m := newCollisionMonitor(&vel)
+	var risks []safety.CollisionRisk
+	m.SetRiskCallback(func(r safety.CollisionRisk) { risks = append(risks, r) })
+
+	// Act & Assert: 前進中は前方 0.3m の障害物でリスク
+	risk, ok := m.CheckScan("robot-1", collisionScan())
+	if !ok || risk.Distance != 0.3 || !risk.New || risk.Action != safety.CollisionActionEStop {
+		t.Fatalf("Expected a new risk at 0.3m, got %+v (ok=%v)", risk, ok)
+	}
+	if math.Abs(risk.Bearing) > math.Pi/90 {
+		t.Errorf("Expected the obstacle straight ahead, got bearing %v", risk.Bearing)
+	}
+
+	// リスクが続く間もコールバックは呼ばれるが、New は最初の1回だけ
+	if risk, ok := m.CheckScan("robot-1", collisionScan()); !ok || risk.New {
+		t.Errorf("Expected a continuing risk without New, got %+v (ok=%v)", risk, ok)
+	}
+	if len(risks) != 2 {
+		t.Errorf("Expected the callback on every risky scan, got %d calls", len(risks))
+	}
+
+	// 後退・横移動・その場での旋回では前方の障害物はリスクにならない
+	for _, v := range []adapter.Velocity{{LinearX: -0.3}, {LinearY: 0.3}, {AngularZ: 1.0}} {
+		vel = v
+		if risk, ok := m.CheckScan("robot-1", collisionScan()); ok {
+			t.Errorf("Expected no risk for velocity %+v, got %+v", v, risk)
+		}
+	}
+
+	// リスクがなくなった後に再び前進すると、新しいリスクとして通知される
+	vel = adapter.Velocity{LinearX: 0.3}
+	if risk, ok := m.CheckScan("robot-1", collisionScan()); !ok || !risk.New {
+		t.Errorf("Expected a new risk after it cleared, got %+v (ok=%v)", risk, ok)
+	}
+}
+
+// TestCollisionMonitor_PerRobotSettings はロボットごとの無効化と停止距離が使われることをテストする
+func TestCollisionMonitor_PerRobotSettings(t *testing.T) {
+	// Arrange
+	vel := adapter.Velocity{LinearX: 0.3}
+	m := newCollisionMonitor(&vel)
+	m.SetRobotSettings(map[string]safety.CollisionSettings{
+		"robot-off":   {Enabled: false, StopDistance: 0.5},
+		"robot-short": {Enabled: true, StopDistance: 0.2},
+	})
+
+	// Act & Assert
+	if _, ok := m.CheckScan("robot-off", collisionScan()); ok {
+		t.Error("Expected no risk for a disabled robot")
+	}
+	if _, ok := m.CheckScan("robot-short", collisionScan()); ok {
+		t.Error("Expected no risk when the obstacle is beyond the robot's stop distance")
+	}
+	if _, ok := m.CheckScan("robot-1", collisionScan()); !ok {
+		t.Error("Expected the default settings for a robot without overrides")
+	}
+}
+
+// TestCollisionRisk_ActivatesEStopAndAlerts は estop の止め方で E-Stop が発動し、アラートが配信されることをテストする
+func TestCollisionRisk_ActivatesEStopAndAlerts(t *testing.T) {
+	// Arrange
+	logger := zap.NewNop()
+	hub := server.NewHub(logger)
+	go hub.Run()
+	client := &server.Client{ID: "client-1", Send: make(chan []byte, 4), Authenticated: true}
+	hub.Register(client)
+	for i := 0; hub.ClientCount() == 0 && i < 100; i++ { // Run() が登録を反映するまで待つ
+		time.Sleep(time.Millisecond)
+	}
+	hub.SetAlertSubscription(client, true)
+	registry := setupMockRegistry(logger)
+	estop := safety.NewEStopManager(registry, logger)
+	h := server.NewHandler(hub, registry, estop, nil, nil, nil, nil, nil, logger)
+
+	// Act
+	h.NotifyCollisionRisk(safety.CollisionRisk{
+		RobotID: "robot-1", Distance: 0.3, StopDistance: 0.5, Action: safety.CollisionActionEStop, New: true,
+	})
+
+	// Assert: E-Stop が発動し、estop_activated と collision_risk のアラートが届く
+	if !estop.IsActive("robot-1") {
+		t.Fatal("Expected E-Stop to be active")
+	}
+	var types []any
+	for len(types) < 2 {
+		select {
+		case data := <-client.Send:
+			msg, err := protocol.NewCodec().Decode(data)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			types = append(types, msg.Payload["type"])
+			if msg.Payload["type"] == "collision_risk" && msg.Payload["action"] != "estop" {
+				t.Errorf("Expected action estop, got %v", msg.Payload)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected 2 alerts, got %v", types)
+		}
+	}
+	if types[0] != "estop_activated" || types[1] != "collision_risk" {
+		t.Errorf("Expected estop_activated then collision_risk, got %v", types)
+	}
+}
+
+// TestConfig_CollisionStopRobots は GATEWAY_COLLISION_STOP_ROBOTS の解析をテストする
+func TestConfig_CollisionStopRobots(t *testing.T) {
+	// Arrange
+	t.Setenv("GATEWAY_COLLISION_STOP_ROBOTS", "robot-1=0.8, robot-2=off, robot-3=on")
+
+	// Act
+	cfg, err := config.Load()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	robots := cfg.Safety.CollisionStopRobots
+	if robots["robot-1"] != (config.CollisionStopRobot{Enabled: true, StopDistance: 0.8}) ||
+		robots["robot-2"].Enabled || !robots["robot-3"].Enabled {
+		t.Errorf("Unexpected per-robot settings: %+v", robots)
+	}
+	if !cfg.Safety.CollisionStopActive() {
+		t.Error("Expected the monitor to be active when a robot enables it")
+	}
+
+	for _, bad := range []string{"robot-1", "robot-1=-1", "robot-1=maybe"} {
+		t.Setenv("GATEWAY_COLLISION_STOP_ROBOTS", bad)
+		if _, err := config.Load(); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+// =============================================================================
+// ファイル: command_consumer_test.go
+// 概要: コマンドストリームのコンシューマー（エントリの解析と、XACK / pending の判断）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/robot-ai-webapp/gateway/internal/bridge"
+	"go.uber.org/zap"
+)
+
+// fakeCommandStream: 1つのコンシューマーグループを真似る CommandStreamClient のテスト用実装
+// entries[:next] が配送済みで、そのうち XACK されていないものが pending。
+type fakeCommandStream struct {
+	mu      sync.Mutex
+	entries []redis.XMessage
+	next    int
+	pending map[string]bool
+	acked   []string
+}
+
+func newFakeCommandStream(entries ...redis.XMessage) *fakeCommandStream {
+	return &fakeCommandStream{entries: entries, pending: make(map[string]bool)}
+}
+
+// deliverToPreviousRun: 先頭 n 件を、前回の起動で配送済み・未確認（pending）にする
+func (f *fakeCommandStream) deliverToPreviousRun(n int) {
+	for _, m := range f.entries[:n] {
+		f.pending[m.ID] = true
+	}
+	f.next = n
+}
+
+func (f *fakeCommandStream) XReadGroup(ctx context.Context, a *redis.XReadGroupArgs) *redis.XStreamSliceCmd {
+	id := a.Streams[1]
+	f.mu.Lock()
+	var out []redis.XMessage
+	if id == ">" {
+		for f.next < len(f.entries) && len(out) < int(a.Count) {
+			m := f.entries[f.next]
+			f.next++
+			f.pending[m.ID] = true
+			out = append(out, m)
+		}
+	} else {
+		// "0" は自分の pending を先頭から、それ以外は指定した ID より後ろの pending を返す
+		after := id == "0"
+		for _, m := range f.entries[:f.next] {
+			if after && f.pending[m.ID] && len(out) < int(a.Count) {
+				out = append(out, m)
+			}
+			if m.ID == id {
+				after = true
+			}
+		}
+	}
+	f.mu.Unlock()
+
+	if len(out) == 0 && id == ">" {
+		// 新着が無い: Block の間待ったことにする
+		select {
+		case <-ctx.Done():
+		case <-time.After(5 * time.Millisecond):
+		}
+		return redis.NewXStreamSliceCmdResult(nil, redis.Nil)
+	}
+	return redis.NewXStreamSliceCmdResult([]redis.XStream{{Stream: a.Streams[0], Messages: out}}, nil)
+}
+
+func (f *fakeCommandStream) XAutoClaim(ctx context.Context, _ *redis.XAutoClaimArgs) *redis.XAutoClaimCmd {
+	cmd := redis.NewXAutoClaimCmd(ctx)
+	cmd.SetVal(nil, "0-0")
+	return cmd
+}
+
+func (f *fakeCommandStream) XAck(_ context.Context, _, _ string, ids ...string) *redis.IntCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, id := range ids {
+		delete(f.pending, id)
+		f.acked = append(f.acked, id)
+	}
+	return redis.NewIntResult(int64(len(ids)), nil)
+}
+
+func (f *fakeCommandStream) Close() error { return nil }
+
+// state: XACK された ID と、pending に残っている件数を返す
+func (f *fakeCommandStream) state() ([]string, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.acked...), len(f.pending)
+}
+
+// handledCommands: コールバックが受け取ったコマンドを記録する
+type handledCommands struct {
+	mu   sync.Mutex
+	cmds []bridge.CommandMessage
+}
+
+func (h *handledCommands) add(cmd bridge.CommandMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.cmds = append(h.cmds, cmd)
+}
+
+func (h *handledCommands) list() []bridge.CommandMessage {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]bridge.CommandMessage(nil), h.cmds...)
+}
+
+// commandEntry: RedisPublisher.PublishCommand が書くのと同じ形のエントリを作る
+func commandEntry(id, robotID, cmdType, timestamp, payload string) redis.XMessage {
+	return redis.XMessage{ID: id, Values: map[string]any{
+		"robot_id": robotID, "type": cmdType, "timestamp": timestamp, "codec": "json", "payload": payload,
+	}}
+}
+
+// runConsumer: コンシューマーを起動し、停止して終了を待つ関数を返す
+func runConsumer(t *testing.T, stream *fakeCommandStream, handler bridge.CommandHandlerFunc) func() {
+	t.Helper()
+	consumer, err := bridge.NewCommandConsumerWithClient(stream, "command-logger", "worker-1", handler, zap.NewNop())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	consumer.Start(ctx, &wg)
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+// TestCommandConsumer_ParsesAndAcksHandledCommands はエントリが元の型に戻ってコールバックに渡され、
+// 成功したら XACK されることをテストする
+func TestCommandConsumer_ParsesAndAcksHandledCommands(t *testing.T) {
+	// Arrange: JSON と MessagePack のペイロード
+	codec, packed, err := bridge.EncodePayload(bridge.PayloadCodecMsgpack, map[string]any{"ranges": []float64{0.5, 1.5}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	msgpackEntry := commandEntry("2-0", "robot-2", "scan", "", string(packed))
+	msgpackEntry.Values["codec"] = codec
+	stream := newFakeCommandStream(
+		commandEntry("1-0", "robot-1", "velocity", "1700000000000", `{"linear_x":0.5}`),
+		msgpackEntry,
+	)
+	handled := &handledCommands{}
+
+	// Act
+	stop := runConsumer(t, stream, func(_ context.Context, cmd bridge.CommandMessage) error {
+		handled.add(cmd)
+		return nil
+	})
+	waitFor(t, func() bool { acked, _ := stream.state(); return len(acked) == 2 })
+	stop()
+
+	// Assert
+	cmds := handled.list()
+	if len(cmds) != 2 {
+		t.Fatalf("Expected 2 handled commands, got %+v", cmds)
+	}
+	first := cmds[0]
+	if first.ID != "1-0" || first.RobotID != "robot-1" || first.Type != "velocity" ||
+		first.Timestamp != 1700000000000 || first.Payload["linear_x"] != 0.5 {
+		t.Errorf("Expected the velocity command to be parsed, got %+v", first)
+	}
+	if ranges, ok := cmds[1].Payload["ranges"].([]any); !ok || len(ranges) != 2 || cmds[1].Timestamp != 0 {
+		t.Errorf("Expected the msgpack payload without a timestamp, got %+v", cmds[1])
+	}
+	if _, pending := stream.state(); pending != 0 {
+		t.Errorf("Expected nothing left pending, got %d", pending)
+	}
+}
+
+// TestCommandConsumer_LeavesFailedCommandsPending はコールバックがエラーを返したエントリを XACK しないことをテストする
+func TestCommandConsumer_LeavesFailedCommandsPending(t *testing.T) {
+	// Arrange
+	stream := newFakeCommandStream(
+		commandEntry("1-0", "robot-1", "velocity", "1", `{}`),
+		commandEntry("2-0", "robot-1", "velocity", "2", `{}`),
+	)
+	handled := &handledCommands{}
+
+	// Act: 1件目だけ失敗させる
+	stop := runConsumer(t, stream, func(_ context.Context, cmd bridge.CommandMessage) error {
+		handled.add(cmd)
+		if cmd.ID == "1-0" {
+			return errors.New("database unavailable")
+		}
+		return nil
+	})
+	waitFor(t, func() bool { return len(handled.list()) == 2 })
+	stop()
+
+	// Assert: 失敗したものは pending に残り、後続は止まらずに処理される
+	acked, pending := stream.state()
+	if len(acked) != 1 || acked[0] != "2-0" || pending != 1 {
+		t.Errorf("Expected only 2-0 acked and 1-0 pending, got acked=%v pending=%d", acked, pending)
+	}
+}
+
+// TestCommandConsumer_AcksMalformedEntriesWithoutHandling は壊れたエントリをコールバックに渡さず、
+// 確認済みにして読み飛ばすことをテストする
+func TestCommandConsumer_AcksMalformedEntriesWithoutHandling(t *testing.T) {
+	// Arrange
+	unknownCodec := commandEntry("4-0", "robot-1", "velocity", "4", `{}`)
+	unknownCodec.Values["codec"] = "xml"
+	stream := newFakeCommandStream(
+		commandEntry("1-0", "", "velocity", "1", `{}`),
+		commandEntry("2-0", "robot-1", "velocity", "not-a-number", `{}`),
+		commandEntry("3-0", "robot-1", "velocity", "3", `{"linear_x":`),
+		unknownCodec,
+		commandEntry("5-0", "robot-1", "velocity", "5", `{}`),
+	)
+	handled := &handledCommands{}
+
+	// Act
+	stop := runConsumer(t, stream, func(_ context.Context, cmd bridge.CommandMessage) error {
+		handled.add(cmd)
+		return nil
+	})
+	waitFor(t, func() bool { acked, _ := stream.state(); return len(acked) == 5 })
+	stop()
+
+	// Assert: コールバックに届くのは正しい 5-0 だけで、壊れたものも pending に残らない
+	cmds := handled.list()
+	if len(cmds) != 1 || cmds[0].ID != "5-0" {
+		t.Errorf("Expected only 5-0 to be handled, got %+v", cmds)
+	}
+	if _, pending := stream.state(); pending != 0 {
+		t.Errorf("Expected malformed entries not to stay pending, got %d", pending)
+	}
+}
+
+// TestCommandConsumer_RecoversPendingOnRestart は前回 XACK できなかったエントリを起動時に先に処理し、
+// 失敗し続けるものは読み直し続けないことをテストする
+func TestCommandConsumer_RecoversPendingOnRestart(t *testing.T) {
+	// Arrange: 1-0 と 2-0 は前回の起動で配送済み・未確認、3-0 は新着
+	stream := newFakeCommandStream(
+		commandEntry("1-0", "robot-1", "velocity", "1", `{}`),
+		commandEntry("2-0", "robot-1", "velocity", "2", `{}`),
+		commandEntry("3-0", "robot-1", "velocity", "3", `{}`),
+	)
+	stream.deliverToPreviousRun(2)
+	handled := &handledCommands{}
+
+	// Act: 2-0 は今回も失敗する
+	stop := runConsumer(t, stream, func(_ context.Context, cmd bridge.CommandMessage) error {
+		handled.add(cmd)
+		if cmd.ID == "2-0" {
+			return errors.New("still failing")
+		}
+		return nil
+	})
+	waitFor(t, func() bool { return len(handled.list()) >= 3 })
+	time.Sleep(20 * time.Millisecond) // 読み直しが起きるならこの間に起きる
+	stop()
+
+	// Assert
+	cmds := handled.list()
+	if len(cmds) != 3 || cmds[0].ID != "1-0" || cmds[1].ID != "2-0" || cmds[2].ID != "3-0" {
+		t.Fatalf("Expected pending 1-0, 2-0 before new 3-0, each once, got %+v", cmds)
+	}
+	acked, pending := stream.state()
+	if len(acked) != 2 || acked[0] != "1-0" || acked[1] != "3-0" || pending != 1 {
+		t.Errorf("Expected 1-0 and 3-0 acked and 2-0 still pending, got acked=%v pending=%d", acked, pending)
+	}
+}
+
+// TestCommandConsumer_RejectsMissingArguments はグループ名・コンシューマー名・コールバックの欠落を拒否することをテストする
+func TestCommandConsumer_RejectsMissingArguments(t *testing.T) {
+	// Arrange
+	stream := newFakeCommandStream()
+	handler := func(context.Context, bridge.CommandMessage) error { return nil }
+
+	// Act & Assert
+	if _, err := bridge.NewCommandConsumerWithClient(stream, "", "worker-1", handler, zap.NewNop()); err == nil {
+		t.Error("Expected an error without a group")
+	}
+	if _, err := bridge.NewCommandConsumerWithClient(stream, "command-logger", "", handler, zap.NewNop()); err == nil {
+		t.Error("Expected an error without a consumer name")
+	}
+	if _, err := bridge.NewCommandConsumerWithClient(stream, "command-logger", "worker-1", nil, zap.NewNop()); err == nil {
+		t.Error("Expected an error without a handler")
+	}
+}
@@ -0,0 +1,180 @@
+// =============================================================================
+// ファイル: command_dedup_test.go
+// 概要: コマンド重複排除（CommandDeduplicator）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/safety"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// TestCommandDedup_ReplaysOriginalAck は同じ command_id の再送で
+// 最初の ACK ペイロードが返されることをテストする
+func TestCommandDedup_ReplaysOriginalAck(t *testing.T) {
+	// Arrange: nav_goal だけを対象に、1分間記録する
+	dedup := safety.NewCommandDeduplicator(time.Minute, []string{"nav_goal"}, zap.NewNop())
+	dedup.Remember("user-1", "robot-1", "nav_goal", "cmd-1", map[string]any{"command": "nav_goal"})
+
+	// Act
+	payload, ok := dedup.Lookup("user-1", "robot-1", "nav_goal", "cmd-1")
+
+	// Assert
+	if !ok {
+		t.Fatal("Expected command to be detected as duplicate")
+	}
+	if payload["command"] != "nav_goal" {
+		t.Errorf("Expected original ack payload, got %v", payload)
+	}
+
+	// 別のロボットへの、または別のユーザーからの同じ command_id は重複ではない
+	if _, ok := dedup.Lookup("user-1", "robot-2", "nav_goal", "cmd-1"); ok {
+		t.Error("Expected command for another robot not to be a duplicate")
+	}
+	if _, ok := dedup.Lookup("user-2", "robot-1", "nav_goal", "cmd-1"); ok {
+		t.Error("Expected command from another user not to be a duplicate")
+	}
+}
+
+// TestCommandDedup_OptInPerType は対象外の種別が記録されないことをテストする
+func TestCommandDedup_OptInPerType(t *testing.T) {
+	dedup := safety.NewCommandDeduplicator(time.Minute, []string{"nav_goal"}, zap.NewNop())
+
+	// velocity は対象外なので記録されず、毎回実行される
+	dedup.Remember("user-1", "robot-1", "velocity", "cmd-1", map[string]any{"command": "velocity"})
+	if _, ok := dedup.Lookup("user-1", "robot-1", "velocity", "cmd-1"); ok {
+		t.Error("Expected velocity command not to be deduplicated")
+	}
+}
+
+// TestCommandDedup_WindowExpires はウィンドウ経過後に再実行できることをテストする
+func TestCommandDedup_WindowExpires(t *testing.T) {
+	dedup := safety.NewCommandDeduplicator(20*time.Millisecond, []string{"dock"}, zap.NewNop())
+	dedup.Remember("user-1", "robot-1", "dock", "cmd-1", map[string]any{"command": "dock"})
+
+	time.Sleep(30 * time.Millisecond)
+
+	if _, ok := dedup.Lookup("user-1", "robot-1", "dock", "cmd-1"); ok {
+		t.Error("Expected entry to expire after the dedup window")
+	}
+}
+
+// TestCommandDedup_ReserveIsAtomic は同時に届いた同じコマンドのうち、実行されるのが1つだけであることをテストする
+func TestCommandDedup_ReserveIsAtomic(t *testing.T) {
+	// Arrange
+	dedup := safety.NewCommandDeduplicator(time.Minute, []string{"dock"}, zap.NewNop())
+	var (
+		mu      sync.Mutex
+		results = map[safety.DedupResult]int{}
+		wg      sync.WaitGroup
+	)
+
+	// Act: 同じ command_id を 20 並列で確認する
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, result := dedup.LookupOrReserve("user-1", "robot-1", "dock", "cmd-1")
+			mu.Lock()
+			results[result]++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	// Assert
+	if results[safety.DedupNew] != 1 || results[safety.DedupInProgress] != 19 {
+		t.Errorf("Expected exactly one reservation and 19 in-progress results, got %v", results)
+	}
+}
+
+// TestCommandDedup_ReleaseAllowsRetry は予約を取り消すと同じコマンドを送り直せ、
+// ACK を記録した後の取り消しは何もしないことをテストする
+func TestCommandDedup_ReleaseAllowsRetry(t *testing.T) {
+	// Arrange: 1回目は失敗して取り消す
+	dedup := safety.NewCommandDeduplicator(time.Minute, []string{"dock"}, zap.NewNop())
+	dedup.LookupOrReserve("user-1", "robot-1", "dock", "cmd-1")
+	dedup.Release("user-1", "robot-1", "dock", "cmd-1")
+
+	// Act: 2回目は成功して ACK を記録する
+	_, retry := dedup.LookupOrReserve("user-1", "robot-1", "dock", "cmd-1")
+	dedup.Remember("user-1", "robot-1", "dock", "cmd-1", map[string]any{"command": "dock"})
+	dedup.Release("user-1", "robot-1", "dock", "cmd-1")
+	payload, replay := dedup.LookupOrReserve("user-1", "robot-1", "dock", "cmd-1")
+
+	// Assert
+	if retry != safety.DedupNew {
+		t.Errorf("Expected the released command to be executable again, got %v", retry)
+	}
+	if replay != safety.DedupReplay || payload["command"] != "dock" {
+		t.Errorf("Expected the recorded ack to be replayed, got %v %v", replay, payload)
+	}
+}
+
+// TestCommandDedup_ScopedPerUser は別のユーザーが同じ command_id を使っても、他人の ACK を返さずに実行することをテストする
+func TestCommandDedup_ScopedPerUser(t *testing.T) {
+	// Arrange: user-1 と user-2 がどちらも操作できる（ロックなし、auto モード）
+	dedup := safety.NewCommandDeduplicator(time.Minute, []string{"velocity"}, zap.NewNop())
+	env := newTestHandler(t, withDedup(dedup))
+	other := &server.Client{ID: "client-2", UserID: "user-2", Send: make(chan []byte, 8), Subscriptions: map[string]bool{}, Authenticated: true}
+	velocity := func(client *server.Client) *protocol.Message {
+		msg := protocol.NewMessage(protocol.MsgTypeVelocityCommand, "robot-1")
+		msg.Payload["linear_x"] = 0.1
+		msg.Payload["command_id"] = "cmd-1"
+		return sendAndDecode(t, env.h, client, msg)
+	}
+
+	// Act
+	first := velocity(env.client)
+	replayed := velocity(env.client)
+	env.opLock.Release("robot-1", "user-1")
+	fromOther := velocity(other)
+
+	// Assert: 同じユーザーの再送だけが duplicate になる
+	if first.Type != protocol.MsgTypeCommandAck || first.Payload["duplicate"] != nil {
+		t.Fatalf("Expected the first command to be executed, got %s %v", first.Type, first.Payload)
+	}
+	if replayed.Payload["duplicate"] != true {
+		t.Errorf("Expected the resend to be replayed, got %v", replayed.Payload)
+	}
+	if fromOther.Type != protocol.MsgTypeCommandAck || fromOther.Payload["duplicate"] != nil {
+		t.Errorf("Expected another user's command to be executed, got %s %v", fromOther.Type, fromOther.Payload)
+	}
+}
+
+// TestCommandDedup_FailedCommandCanBeResent は実行できなかったコマンドの予約が残らず、
+// 同じ command_id で送り直せることをテストする
+func TestCommandDedup_FailedCommandCanBeResent(t *testing.T) {
+	// Arrange: E-Stop 中のロボット
+	dedup := safety.NewCommandDeduplicator(time.Minute, []string{"velocity"}, zap.NewNop())
+	env := newTestHandler(t, withDedup(dedup))
+	if err := env.estop.Activate(context.Background(), "robot-1", "user-1", "test"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	velocity := func() *protocol.Message {
+		msg := protocol.NewMessage(protocol.MsgTypeVelocityCommand, "robot-1")
+		msg.Payload["linear_x"] = 0.1
+		msg.Payload["command_id"] = "cmd-1"
+		return sendAndDecode(t, env.h, env.client, msg)
+	}
+
+	// Act: E-Stop で拒否された後、解除して同じ command_id で送り直す
+	rejected := velocity()
+	env.estop.Release("robot-1", "user-1")
+	resent := velocity()
+
+	// Assert
+	if rejected.Type != protocol.MsgTypeError {
+		t.Fatalf("Expected the command to be rejected during E-Stop, got %s %v", rejected.Type, rejected.Payload)
+	}
+	if resent.Type != protocol.MsgTypeCommandAck || resent.Payload["duplicate"] != nil {
+		t.Errorf("Expected the resent command to be executed, got %s %v", resent.Type, resent.Payload)
+	}
+}
@@ -0,0 +1,96 @@
+// =============================================================================
+// ファイル: command_observer_test.go
+// 概要: 観察者モード（observe_commands / command_observed）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/convert"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+)
+
+// setupObserverHandler: robot-1（接続済み）と、Hub に登録した操作者（user-1）・観察者（viewer-1）を用意する
+func setupObserverHandler(t *testing.T) (h *server.Handler, operator, observer *server.Client) {
+	t.Helper()
+	env := newTestHandler(t, withRunningHub())
+	env.h.SetObserverUsers([]string{"viewer-1"})
+
+	observer = &server.Client{ID: "client-2", UserID: "viewer-1", Send: make(chan []byte, 8), Subscriptions: map[string]bool{}, Authenticated: true}
+	registerClients(t, env.hub, env.client, observer)
+	return env.h, env.client, observer
+}
+
+// TestCommandObserver_ObserverReceivesAcceptedCommands は受け付けたコマンドの写しが観察者だけに届くことをテストする
+func TestCommandObserver_ObserverReceivesAcceptedCommands(t *testing.T) {
+	// Arrange
+	h, operator, observer := setupObserverHandler(t)
+	observe := protocol.NewMessage(protocol.MsgTypeObserveCommands, "robot-1")
+	if ack := sendAndDecode(t, h, observer, observe); ack.Type != protocol.MsgTypeCommandAck {
+		t.Fatalf("Expected observe_commands to be acknowledged, got %s (%s)", ack.Type, ack.Error)
+	}
+
+	// Act: 上限（1.0 m/s）を超える速度を送る
+	cmd := protocol.NewMessage(protocol.MsgTypeVelocityCommand, "robot-1")
+	cmd.Payload["linear_x"] = 1.5
+	ack := sendAndDecode(t, h, operator, cmd)
+
+	// Assert: 観察者には制限後の速度が届き、操作者には ack だけが届く
+	if ack.Type != protocol.MsgTypeCommandAck {
+		t.Fatalf("Expected the command to be accepted, got %s (%s)", ack.Type, ack.Error)
+	}
+	select {
+	case data := <-observer.Send:
+		msg, err := protocol.NewCodec().Decode(data)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if msg.Type != protocol.MsgTypeCommandObserved || msg.Payload["command"] != "velocity" || msg.Payload["user_id"] != "user-1" {
+			t.Fatalf("Expected an observed velocity command from user-1, got %s %v", msg.Type, msg.Payload)
+		}
+		payload, _ := msg.Payload["payload"].(map[string]any)
+		if x, _ := convert.ToFloat64(payload["linear_x"]); x != 1.0 {
+			t.Errorf("Expected the limited velocity 1.0, got %v", payload["linear_x"])
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the observer to receive the command")
+	}
+	if len(operator.Send) != 0 {
+		t.Errorf("Expected the operator not to receive its own command, got %d messages", len(operator.Send))
+	}
+}
+
+// TestCommandObserver_RejectedCommandsAreNotObserved は拒否されたコマンドが観察者に届かないことをテストする
+func TestCommandObserver_RejectedCommandsAreNotObserved(t *testing.T) {
+	// Arrange
+	h, operator, observer := setupObserverHandler(t)
+	sendAndDecode(t, h, observer, protocol.NewMessage(protocol.MsgTypeObserveCommands, "robot-1"))
+
+	// Act: 速度の成分がない（invalid_message）
+	resp := sendAndDecode(t, h, operator, protocol.NewMessage(protocol.MsgTypeVelocityCommand, "robot-1"))
+
+	// Assert
+	if resp.Type != protocol.MsgTypeError {
+		t.Fatalf("Expected the command to be rejected, got %s", resp.Type)
+	}
+	if len(observer.Send) != 0 {
+		t.Errorf("Expected no observed command, got %d messages", len(observer.Send))
+	}
+}
+
+// TestCommandObserver_RequiresObserverRole は観察者でも管理者でもないユーザーは観察できないことをテストする
+func TestCommandObserver_RequiresObserverRole(t *testing.T) {
+	// Arrange
+	h, operator, _ := setupObserverHandler(t)
+
+	// Act
+	resp := sendAndDecode(t, h, operator, protocol.NewMessage(protocol.MsgTypeObserveCommands, "robot-1"))
+
+	// Assert
+	if resp.Payload["code"] != protocol.ErrCodeForbidden {
+		t.Errorf("Expected forbidden, got %s %v", resp.Type, resp.Payload)
+	}
+}
@@ -0,0 +1,94 @@
+// =============================================================================
+// ファイル: command_policy_test.go
+// 概要: ロボットごとのコマンドのホワイトリスト（command_not_allowed）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"testing"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/adapter/mock"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// sensorOnlyAdapter: センサーデータを出すだけで、動作のコマンドには対応しないロボット
+type sensorOnlyAdapter struct {
+	*mock.MockAdapter
+}
+
+func (a *sensorOnlyAdapter) GetCapabilities() adapter.Capabilities {
+	return adapter.Capabilities{SupportsEStop: true, SensorTopics: []string{"scan"}}
+}
+
+// setupPolicyHandler: adapterType のロボット robot-1（接続済み）とハンドラーを作る
+func setupPolicyHandler(t *testing.T, registry *adapter.Registry, adapterType string) (*server.Handler, *server.Client) {
+	t.Helper()
+	env := newTestHandler(t, withRegistry(registry, adapterType))
+	return env.h, env.client
+}
+
+// sendAndDecode: メッセージを処理させ、応答を1つ読む
+func sendAndDecode(t *testing.T, h *server.Handler, client *server.Client, msg *protocol.Message) *protocol.Message {
+	t.Helper()
+	h.HandleMessage(client, msg)
+	resp, err := protocol.NewCodec().Decode(<-client.Send)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return resp
+}
+
+// TestCommandPolicy_SensorOnlyRobotRejectsMotion は能力のないコマンドが拒否されることをテストする
+func TestCommandPolicy_SensorOnlyRobotRejectsMotion(t *testing.T) {
+	// Arrange
+	registry := adapter.NewRegistry(zap.NewNop())
+	registry.RegisterFactory("sensor_only", func(logger *zap.Logger) adapter.RobotAdapter {
+		return &sensorOnlyAdapter{MockAdapter: mock.NewMockAdapter(logger)}
+	})
+	h, client := setupPolicyHandler(t, registry, "sensor_only")
+
+	velocity := protocol.NewMessage(protocol.MsgTypeVelocityCommand, "robot-1")
+	velocity.Payload["linear_x"] = 0.5
+	goal := protocol.NewMessage(protocol.MsgTypeNavigationGoal, "robot-1")
+	goal.Payload["x"] = 1.0
+
+	// Act & Assert: 速度指令とナビゲーション目標は command_not_allowed で拒否される
+	for _, msg := range []*protocol.Message{velocity, goal} {
+		resp := sendAndDecode(t, h, client, msg)
+		if resp.Type != protocol.MsgTypeError || resp.Payload["code"] != protocol.ErrCodeCommandNotAllowed {
+			t.Errorf("Expected command_not_allowed for %s, got %s %v", msg.Type, resp.Type, resp.Payload)
+		}
+	}
+
+	// Assert: 緊急停止は常に受け付ける（成功時はアラートの配信だけで、送信元へのエラーはない）
+	h.HandleMessage(client, protocol.NewMessage(protocol.MsgTypeEmergencyStop, "robot-1"))
+	select {
+	case data := <-client.Send:
+		if resp, _ := protocol.NewCodec().Decode(data); resp != nil && resp.Type == protocol.MsgTypeError {
+			t.Errorf("Expected E-Stop to be accepted, got error %s", resp.Error)
+		}
+	default:
+	}
+}
+
+// TestCommandPolicy_ConfiguredWhitelist は設定でコマンドを絞り込めることをテストする
+func TestCommandPolicy_ConfiguredWhitelist(t *testing.T) {
+	// Arrange: モック（全能力あり）でも、設定で速度指令だけに絞る
+	h, client := setupPolicyHandler(t, setupMockRegistry(zap.NewNop()), "mock")
+	h.SetAllowedCommands(map[string][]string{"robot-1": {"velocity"}})
+
+	velocity := protocol.NewMessage(protocol.MsgTypeVelocityCommand, "robot-1")
+	velocity.Payload["linear_x"] = 0.5
+
+	// Act & Assert
+	if resp := sendAndDecode(t, h, client, velocity); resp.Type != protocol.MsgTypeCommandAck {
+		t.Errorf("Expected velocity to be accepted, got %s (%s)", resp.Type, resp.Error)
+	}
+	resp := sendAndDecode(t, h, client, protocol.NewMessage(protocol.MsgTypeDock, "robot-1"))
+	if resp.Payload["code"] != protocol.ErrCodeCommandNotAllowed {
+		t.Errorf("Expected command_not_allowed for dock, got %s %v", resp.Type, resp.Payload)
+	}
+}
@@ -0,0 +1,100 @@
+// =============================================================================
+// ファイル: command_timeout_test.go
+// 概要: アダプターへのコマンド送信のタイムアウトとシャットダウン時の中断のテストコード
+// =============================================================================
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/adapter/mock"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"go.uber.org/zap"
+)
+
+// hangingAdapter: SendCommand が ctx を無視して戻らないロボットを模したアダプター
+type hangingAdapter struct {
+	*mock.MockAdapter
+	release chan struct{}
+}
+
+func (a *hangingAdapter) SendCommand(context.Context, adapter.Command) error {
+	<-a.release
+	return nil
+}
+
+// setupHangingRegistry: 固まるアダプターを "hanging" として登録する（テスト終了時に解放する）
+func setupHangingRegistry(t *testing.T) *adapter.Registry {
+	t.Helper()
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	registry := adapter.NewRegistry(zap.NewNop())
+	registry.RegisterFactory("hanging", func(logger *zap.Logger) adapter.RobotAdapter {
+		return &hangingAdapter{MockAdapter: mock.NewMockAdapter(logger), release: release}
+	})
+	return registry
+}
+
+// TestCommandTimeout_VelocityReturnsCommandTimeout はアダプターが応答しない時に command_timeout が返ることをテストする
+func TestCommandTimeout_VelocityReturnsCommandTimeout(t *testing.T) {
+	// Arrange
+	h, client := setupPolicyHandler(t, setupHangingRegistry(t), "hanging")
+	h.SetCommandContext(context.Background(), 50*time.Millisecond)
+	msg := protocol.NewMessage(protocol.MsgTypeVelocityCommand, "robot-1")
+	msg.Payload["linear_x"] = 0.3
+
+	// Act
+	start := time.Now()
+	resp := sendAndDecode(t, h, client, msg)
+
+	// Assert: タイムアウトで諦め、command_timeout を返す
+	if resp.Type != protocol.MsgTypeError || resp.Payload["code"] != protocol.ErrCodeCommandTimeout {
+		t.Fatalf("Expected command_timeout error, got %s %v", resp.Type, resp.Payload)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected to give up after the timeout, took %v", elapsed)
+	}
+}
+
+// TestCommandTimeout_ShutdownAbortsCommand はサーバーの ctx がキャンセルされると送信が打ち切られることをテストする
+func TestCommandTimeout_ShutdownAbortsCommand(t *testing.T) {
+	// Arrange: タイムアウトは長く、サーバーの ctx はキャンセル済み
+	h, client := setupPolicyHandler(t, setupHangingRegistry(t), "hanging")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	h.SetCommandContext(ctx, time.Minute)
+	msg := protocol.NewMessage(protocol.MsgTypeVelocityCommand, "robot-1")
+	msg.Payload["linear_x"] = 0.3
+
+	// Act
+	resp := sendAndDecode(t, h, client, msg)
+
+	// Assert: タイムアウトを待たずに中断のエラーが返る
+	if resp.Type != protocol.MsgTypeError || resp.Payload["code"] == protocol.ErrCodeCommandTimeout {
+		t.Fatalf("Expected an abort error, got %s %v", resp.Type, resp.Payload)
+	}
+	if !strings.Contains(resp.Error, "shutting down") {
+		t.Errorf("Expected shutdown message, got %q", resp.Error)
+	}
+}
+
+// TestCommandTimeout_RespondingAdapterSucceeds は応答するアダプターでは通常どおり ack が返ることをテストする
+func TestCommandTimeout_RespondingAdapterSucceeds(t *testing.T) {
+	// Arrange
+	h, client := setupPolicyHandler(t, setupMockRegistry(zap.NewNop()), "mock")
+	h.SetCommandContext(context.Background(), time.Second)
+	msg := protocol.NewMessage(protocol.MsgTypeVelocityCommand, "robot-1")
+	msg.Payload["linear_x"] = 0.3
+
+	// Act
+	resp := sendAndDecode(t, h, client, msg)
+
+	// Assert
+	if resp.Type == protocol.MsgTypeError {
+		t.Fatalf("Expected success, got error %v", resp.Payload)
+	}
+}
@@ -0,0 +1,60 @@
+// =============================================================================
+// ファイル: config_validate_test.go
+// 概要: 設定値の検証（Config.Validate）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/robot-ai-webapp/gateway/internal/config"
+)
+
+// TestConfigValidate_DefaultsAreValid はデフォルト設定が検証を通ることをテストする
+func TestConfigValidate_DefaultsAreValid(t *testing.T) {
+	// Arrange
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Act & Assert
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected the defaults to be valid, got %v", err)
+	}
+}
+
+// TestConfigValidate_ReportsEveryProblem は不正な設定がすべて、環境変数名付きで報告されることをテストする
+func TestConfigValidate_ReportsEveryProblem(t *testing.T) {
+	// Arrange: タイムアウト 0、速度上限が負、警告がロックの期限より後、不明なログレベル
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg.Safety.CommandTimeoutSec = 0
+	cfg.Safety.MaxLinearVelocity = -1
+	cfg.Safety.OperationLockWarnSec = cfg.Safety.OperationLockTimeoutSec
+	cfg.Logging.Level = "verbose"
+
+	// Act
+	err = cfg.Validate()
+
+	// Assert
+	if err == nil {
+		t.Fatal("Expected validation to fail")
+	}
+	for _, name := range []string{
+		"GATEWAY_CMD_TIMEOUT_SEC",
+		"GATEWAY_MAX_LINEAR_VEL",
+		"GATEWAY_OPERATION_LOCK_WARN_SEC",
+		"GATEWAY_LOG_LEVEL",
+	} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("Expected the error to mention %s, got %v", name, err)
+		}
+	}
+	if strings.Contains(err.Error(), "GATEWAY_MAX_ANGULAR_VEL") {
+		t.Errorf("Expected valid settings not to be reported, got %v", err)
+	}
+}
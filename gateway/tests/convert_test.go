@@ -0,0 +1,88 @@
+// =============================================================================
+// ファイル: convert_test.go
+// 概要: 値の型変換（convert.ToFloat64）と、速度コマンドの値の検証のテストコード
+// =============================================================================
+package tests
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/robot-ai-webapp/gateway/internal/convert"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"go.uber.org/zap"
+)
+
+// TestToFloat64 は数値型・文字列の変換と、変換できない値の判定をテストする
+func TestToFloat64(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  any
+		want   float64
+		wantOK bool
+	}{
+		{"float64", 0.5, 0.5, true},
+		{"float32", float32(0.25), 0.25, true},
+		{"int", -3, -3, true},
+		{"int8 (msgpack)", int8(-1), -1, true},
+		{"uint16 (msgpack)", uint16(300), 300, true},
+		{"uint64", uint64(7), 7, true},
+		{"zero is a real value", 0.0, 0, true},
+		{"json.Number", json.Number("1.5"), 1.5, true},
+		{"numeric string", " 0.75 ", 0.75, true},
+		{"non-numeric string", "fast", 0, false},
+		{"empty string", "", 0, false},
+		{"NaN string", "NaN", 0, false},
+		{"infinity", math.Inf(1), 0, false},
+		{"nil", nil, 0, false},
+		{"bool", true, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := convert.ToFloat64(tt.input)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("ToFloat64(%#v) = (%v, %v), want (%v, %v)", tt.input, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+// TestVelocityCommand_RejectsInvalidValues は数値でない速度や速度のないコマンドが拒否されることをテストする
+func TestVelocityCommand_RejectsInvalidValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload map[string]any
+		wantAck bool
+	}{
+		{"numeric string is accepted", map[string]any{"linear_x": "0.5"}, true},
+		{"omitted components are zero", map[string]any{"angular_z": 0.1}, true},
+		{"non-numeric value", map[string]any{"linear_x": "fast"}, false},
+		{"null value", map[string]any{"linear_x": 0.5, "angular_z": nil}, false},
+		{"no velocity fields", map[string]any{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			h, client := setupPolicyHandler(t, setupMockRegistry(zap.NewNop()), "mock")
+			msg := protocol.NewMessage(protocol.MsgTypeVelocityCommand, "robot-1")
+			msg.Payload = tt.payload
+
+			// Act
+			resp := sendAndDecode(t, h, client, msg)
+
+			// Assert
+			if tt.wantAck {
+				if resp.Type != protocol.MsgTypeCommandAck {
+					t.Errorf("Expected cmd_ack, got %s (%s)", resp.Type, resp.Error)
+				}
+				return
+			}
+			if resp.Type != protocol.MsgTypeError || resp.Payload["code"] != protocol.ErrCodeInvalidMessage {
+				t.Errorf("Expected an invalid_message error, got %s %v", resp.Type, resp.Payload)
+			}
+		})
+	}
+}
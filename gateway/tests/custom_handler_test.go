@@ -0,0 +1,70 @@
+// =============================================================================
+// ファイル: custom_handler_test.go
+// 概要: Handler.RegisterHandler（追加メッセージハンドラーの登録）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"testing"
+
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// TestRegisterHandler_DispatchesCustomType は登録したハンドラーが呼ばれることをテストする
+func TestRegisterHandler_DispatchesCustomType(t *testing.T) {
+	// Arrange: 依存コンポーネントは使わないので nil で作成する
+	h := server.NewHandler(nil, nil, nil, nil, nil, nil, nil, nil, zap.NewNop())
+	called := false
+	err := h.RegisterHandler("experimental", func(c *server.Client, m *protocol.Message) {
+		called = true
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Act
+	h.HandleMessage(&server.Client{}, protocol.NewMessage("experimental", "robot-1"))
+
+	// Assert
+	if !called {
+		t.Error("Expected custom handler to be called")
+	}
+}
+
+// TestRegisterHandler_RejectsSafetyCritical は estop などの上書きが拒否されることをテストする
+func TestRegisterHandler_RejectsSafetyCritical(t *testing.T) {
+	h := server.NewHandler(nil, nil, nil, nil, nil, nil, nil, nil, zap.NewNop())
+	noop := func(c *server.Client, m *protocol.Message) {}
+
+	if err := h.RegisterHandler(protocol.MsgTypeEmergencyStop, noop); err == nil {
+		t.Error("Expected error when overriding estop handler")
+	}
+
+	// 明示的に許可した場合は登録できる
+	if err := h.ForceRegisterHandler(protocol.MsgTypeEmergencyStop, noop); err != nil {
+		t.Errorf("Expected ForceRegisterHandler to succeed, got %v", err)
+	}
+}
+
+// TestRegisterHandler_RejectsMotionCommands はロボットを動かす・止めるコマンドも Force なしでは置き換えられないことをテストする
+func TestRegisterHandler_RejectsMotionCommands(t *testing.T) {
+	// Arrange
+	h := server.NewHandler(nil, nil, nil, nil, nil, nil, nil, nil, zap.NewNop())
+	noop := func(c *server.Client, m *protocol.Message) {}
+
+	for _, msgType := range []protocol.MessageType{
+		protocol.MsgTypeStopAll, protocol.MsgTypeGroupCommand, protocol.MsgTypeVelocityPreset,
+		protocol.MsgTypeVelocityDelta, protocol.MsgTypeNavigationGoal, protocol.MsgTypeDock,
+		protocol.MsgTypeUndock, protocol.MsgTypeResetPose,
+	} {
+		// Act
+		err := h.RegisterHandler(msgType, noop)
+
+		// Assert
+		if err == nil {
+			t.Errorf("Expected error when overriding %s handler", msgType)
+		}
+	}
+}
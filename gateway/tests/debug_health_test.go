@@ -0,0 +1,90 @@
+// =============================================================================
+// ファイル: debug_health_test.go
+// 概要: 診断用エンドポイント（/debug/health）と heartbeat のテストコード
+// =============================================================================
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/clock"
+	"github.com/robot-ai-webapp/gateway/internal/heartbeat"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// debugHealthBody: /debug/health の応答のうちテストで見る部分
+type debugHealthBody struct {
+	Status      string                  `json:"status"`
+	Clients     int                     `json:"clients"`
+	SendBuffers []server.SendBufferStat `json:"send_buffers"`
+	Heartbeats  []heartbeat.Status      `json:"heartbeats"`
+}
+
+// TestDebugHealth_ReportsBuffersAndStalledGoroutines はトークンが必要で、詰まったバッファと止まったゴルーチンを報告することをテストする
+func TestDebugHealth_ReportsBuffersAndStalledGoroutines(t *testing.T) {
+	// Arrange: Send バッファが半分埋まったクライアントと、1秒ごとに Beat するはずのワーカー
+	hub := server.NewHub(zap.NewNop())
+	go hub.Run()
+	client := &server.Client{ID: "client-1", UserID: "user-1", Send: make(chan []byte, 4), Subscriptions: map[string]bool{}}
+	hub.Register(client)
+	for i := 0; hub.ClientCount() < 1 && i < 100; i++ { // Run() が登録を反映するまで待つ
+		time.Sleep(time.Millisecond)
+	}
+	client.Send <- []byte("a")
+	client.Send <- []byte("b")
+
+	clk := clock.NewMock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	beats := heartbeat.NewRegistry()
+	beats.SetClock(clk)
+	beat := beats.Register("worker", time.Second)
+	beat()
+
+	debug := server.NewDebugHealth(hub, setupMockRegistry(zap.NewNop()), beats, "secret")
+	get := func(token string) (*httptest.ResponseRecorder, debugHealthBody) {
+		req := httptest.NewRequest("GET", "/debug/health", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		debug.Handler(rec, req)
+		var body debugHealthBody
+		if rec.Code != http.StatusUnauthorized {
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		return rec, body
+	}
+
+	// Act & Assert: トークンがなければ、または違えば 401
+	if rec, _ := get(""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a token, got %d", rec.Code)
+	}
+	if rec, _ := get("wrong"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with a wrong token, got %d", rec.Code)
+	}
+
+	// 正しいトークンならバッファの使用状況と生存確認が返る
+	rec, body := get("secret")
+	if rec.Code != http.StatusOK || body.Status != "ok" || body.Clients != 1 {
+		t.Fatalf("Expected 200 ok with 1 client, got %d %+v", rec.Code, body)
+	}
+	if len(body.SendBuffers) != 1 || body.SendBuffers[0].Len != 2 || body.SendBuffers[0].Fill != 0.5 {
+		t.Errorf("Expected client-1's buffer to be half full, got %+v", body.SendBuffers)
+	}
+	if len(body.Heartbeats) != 1 || !body.Heartbeats[0].Alive {
+		t.Errorf("Expected the worker to be alive, got %+v", body.Heartbeats)
+	}
+
+	// ワーカーが Beat しないまま間隔の3倍を超えると、止まっているとみなされる
+	clk.Advance(5 * time.Second)
+	rec, body = get("secret")
+	if rec.Code != http.StatusServiceUnavailable || body.Status != "degraded" || body.Heartbeats[0].Alive {
+		t.Errorf("Expected 503 degraded with a stalled worker, got %d %+v", rec.Code, body)
+	}
+}
@@ -0,0 +1,92 @@
+// =============================================================================
+// ファイル: describe_test.go
+// 概要: メッセージタイプの一覧（describe / GET /schema）と Payload の型検証のテストコード
+// =============================================================================
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// TestDescribe_UnauthenticatedClientGetsSchema は未認証でも describe に一覧が返ることをテストする
+func TestDescribe_UnauthenticatedClientGetsSchema(t *testing.T) {
+	// Arrange
+	h, _ := setupPolicyHandler(t, setupMockRegistry(zap.NewNop()), "mock")
+	client := &server.Client{ID: "client-2", Send: make(chan []byte, 8)}
+
+	// Act
+	resp := sendAndDecode(t, h, client, protocol.NewMessage(protocol.MsgTypeDescribe, ""))
+
+	// Assert
+	if resp.Type != protocol.MsgTypeDescribe {
+		t.Fatalf("Expected describe, got %s (%s)", resp.Type, resp.Error)
+	}
+	messages, _ := resp.Payload["messages"].([]any)
+	var velocity map[string]any
+	for _, m := range messages {
+		if entry, _ := m.(map[string]any); entry["type"] == string(protocol.MsgTypeVelocityCommand) {
+			velocity = entry
+		}
+	}
+	if velocity == nil {
+		t.Fatal("Expected velocity_cmd to be listed")
+	}
+	if velocity["direction"] != string(protocol.DirectionClientToGateway) || velocity["requires_auth"] != true {
+		t.Errorf("Expected velocity_cmd to be an authenticated client message, got %v", velocity)
+	}
+	if codes, _ := resp.Payload["error_codes"].([]any); len(codes) == 0 {
+		t.Error("Expected error codes to be listed")
+	}
+}
+
+// TestDescribe_SchemaEndpointMatchesDefinitions は GET /schema が受信検証と同じ定義を返すことをテストする
+func TestDescribe_SchemaEndpointMatchesDefinitions(t *testing.T) {
+	// Arrange
+	h, _ := setupPolicyHandler(t, setupMockRegistry(zap.NewNop()), "mock")
+	rec := httptest.NewRecorder()
+
+	// Act
+	h.SchemaHandler(rec, httptest.NewRequest(http.MethodGet, "/schema", nil))
+
+	// Assert
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	var desc protocol.Description
+	if err := json.NewDecoder(rec.Body).Decode(&desc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want, _ := protocol.SchemaFor(protocol.MsgTypeAuth)
+	for _, m := range desc.Messages {
+		if m.Type == protocol.MsgTypeAuth {
+			if len(m.Fields) != len(want.Fields) || m.Fields[0].Name != "token" || !m.Fields[0].Required {
+				t.Errorf("Expected auth fields %v, got %v", want.Fields, m.Fields)
+			}
+			return
+		}
+	}
+	t.Error("Expected auth to be listed")
+}
+
+// TestDescribe_WrongFieldTypeIsRejected はスキーマと型の合わないフィールドが invalid_message になることをテストする
+func TestDescribe_WrongFieldTypeIsRejected(t *testing.T) {
+	// Arrange
+	h, client := setupPolicyHandler(t, setupMockRegistry(zap.NewNop()), "mock")
+	msg := protocol.NewMessage(protocol.MsgTypeVelocityCommand, "robot-1")
+	msg.Payload = map[string]any{"linear_x": "fast"}
+
+	// Act
+	resp := sendAndDecode(t, h, client, msg)
+
+	// Assert
+	if resp.Type != protocol.MsgTypeError || resp.Payload["code"] != protocol.ErrCodeInvalidMessage {
+		t.Fatalf("Expected invalid_message, got %s %v", resp.Type, resp.Payload)
+	}
+}
@@ -0,0 +1,65 @@
+// =============================================================================
+// ファイル: diagnostics_test.go
+// 概要: get_diagnostics（ロボットの診断情報の問い合わせ）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/adapter/mock"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"go.uber.org/zap"
+)
+
+// noDiagnosticsAdapter: 診断情報に対応していないロボットを模したアダプター
+type noDiagnosticsAdapter struct {
+	*mock.MockAdapter
+}
+
+func (a *noDiagnosticsAdapter) GetDiagnostics(context.Context) (map[string]any, error) {
+	return nil, adapter.ErrNotSupported
+}
+
+// requestDiagnostics: 接続済みの robot-1 に get_diagnostics を送り、応答を返す
+func requestDiagnostics(t *testing.T, registry *adapter.Registry, adapterType string) *protocol.Message {
+	t.Helper()
+	env := newTestHandler(t, withRegistry(registry, adapterType))
+	resp := sendAndDecode(t, env.h, env.client, protocol.NewMessage(protocol.MsgTypeGetDiagnostics, "robot-1"))
+	if resp.Type != protocol.MsgTypeGetDiagnostics {
+		t.Fatalf("Expected get_diagnostics, got %s (%s)", resp.Type, resp.Error)
+	}
+	return resp
+}
+
+// TestGetDiagnostics_ReturnsMockDiagnostics はモックの診断情報が返ることをテストする
+func TestGetDiagnostics_ReturnsMockDiagnostics(t *testing.T) {
+	resp := requestDiagnostics(t, setupMockRegistry(zap.NewNop()), "mock")
+
+	if supported, _ := resp.Payload["supported"].(bool); !supported {
+		t.Fatalf("Expected supported=true, got %v", resp.Payload)
+	}
+	diagnostics, _ := resp.Payload["diagnostics"].(map[string]any)
+	if diagnostics["firmware_version"] == nil || diagnostics["motor_temp_left"] == nil {
+		t.Errorf("Expected firmware_version and motor temps, got %v", diagnostics)
+	}
+}
+
+// TestGetDiagnostics_NotSupported は非対応のアダプターでは supported=false が返ることをテストする
+func TestGetDiagnostics_NotSupported(t *testing.T) {
+	registry := adapter.NewRegistry(zap.NewNop())
+	registry.RegisterFactory("no_diag", func(logger *zap.Logger) adapter.RobotAdapter {
+		return &noDiagnosticsAdapter{MockAdapter: mock.NewMockAdapter(logger)}
+	})
+
+	resp := requestDiagnostics(t, registry, "no_diag")
+
+	if supported, ok := resp.Payload["supported"].(bool); !ok || supported {
+		t.Errorf("Expected supported=false, got %v", resp.Payload)
+	}
+	if _, ok := resp.Payload["diagnostics"]; ok {
+		t.Errorf("Expected no diagnostics field, got %v", resp.Payload["diagnostics"])
+	}
+}
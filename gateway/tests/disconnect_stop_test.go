@@ -0,0 +1,157 @@
+// =============================================================================
+// ファイル: disconnect_stop_test.go
+// 概要: 操作者の切断時の即時停止（StopRobotsControlledBy）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/adapter/mock"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/safety"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// recordingAdapter: 送られた速度コマンドを記録するモックアダプター
+type recordingAdapter struct {
+	*mock.MockAdapter
+	mu       sync.Mutex
+	commands []adapter.Command
+}
+
+func (a *recordingAdapter) SendCommand(ctx context.Context, cmd adapter.Command) error {
+	a.mu.Lock()
+	a.commands = append(a.commands, cmd)
+	a.mu.Unlock()
+	return a.MockAdapter.SendCommand(ctx, cmd)
+}
+
+func (a *recordingAdapter) last() (adapter.Command, int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.commands) == 0 {
+		return adapter.Command{}, 0
+	}
+	return a.commands[len(a.commands)-1], len(a.commands)
+}
+
+// setupDisconnectStop: 接続済みの robot-1 と、切断時の停止を登録したハンドラーを作る
+func setupDisconnectStop(t *testing.T) (*server.Hub, *server.Handler, *safety.OperationLock, *recordingAdapter) {
+	t.Helper()
+	rec := &recordingAdapter{MockAdapter: mock.NewMockAdapter(zap.NewNop())}
+	registry := adapter.NewRegistry(zap.NewNop())
+	registry.RegisterFactory("recording", func(*zap.Logger) adapter.RobotAdapter { return rec })
+	env := newTestHandler(t, withRegistry(registry, "recording"), withRunningHub())
+	env.hub.SetUnregisterCallback(env.h.StopRobotsControlledBy)
+	return env.hub, env.h, env.opLock, rec
+}
+
+// drive: client を登録し、robot-1 に前進コマンドを送る
+func drive(t *testing.T, hub *server.Hub, h *server.Handler, client *server.Client) {
+	t.Helper()
+	hub.Register(client)
+	msg := protocol.NewMessage(protocol.MsgTypeVelocityCommand, "robot-1")
+	msg.Payload["linear_x"] = 0.5
+	h.HandleMessage(client, msg)
+	if resp, _ := protocol.NewCodec().Decode(<-client.Send); resp.Type != protocol.MsgTypeCommandAck {
+		t.Fatalf("Expected cmd_ack, got %s (%s)", resp.Type, resp.Error)
+	}
+}
+
+// driveAndDisconnect: client から robot-1 に前進コマンドを送ってから切断する
+func driveAndDisconnect(t *testing.T, hub *server.Hub, h *server.Handler, client *server.Client) {
+	t.Helper()
+	drive(t, hub, h, client)
+	hub.Unregister(client)
+}
+
+// waitForStop: robot-1 に n 件目のコマンドとして速度 0 が届くのを待つ
+func waitForStop(t *testing.T, rec *recordingAdapter, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		cmd, got := rec.last()
+		if got == n && cmd.Payload["linear_x"] == 0.0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected a zero-velocity command after disconnect, got %d commands (last %v)", got, cmd.Payload)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestStopOnDisconnect_SoleControllerStopsRobot は唯一の操作者が切断するとすぐ停止することをテストする
+func TestStopOnDisconnect_SoleControllerStopsRobot(t *testing.T) {
+	// Arrange
+	hub, h, opLock, rec := setupDisconnectStop(t)
+	client := &server.Client{ID: "client-1", UserID: "user-1", Send: make(chan []byte, 8), Authenticated: true}
+
+	// Act: 操作して切断する（ロックの保持者 user-1 の接続は残らない）
+	driveAndDisconnect(t, hub, h, client)
+
+	// Assert: 速度 0 のコマンドが届く
+	waitForStop(t, rec, 2)
+	if opLock.GetLockInfo("robot-1") == nil {
+		t.Error("Expected the lock to be left to expire normally")
+	}
+}
+
+// TestStopOnDisconnect_LockHolderStillConnected はロック保持者の、操作した別の接続が残っていれば止めないことをテストする
+func TestStopOnDisconnect_LockHolderStillConnected(t *testing.T) {
+	// Arrange: 同じユーザーの別タブも robot-1 を操作し、接続したまま
+	hub, h, _, rec := setupDisconnectStop(t)
+	other := &server.Client{ID: "client-2", UserID: "user-1", Send: make(chan []byte, 8), Authenticated: true}
+	drive(t, hub, h, other)
+	client := &server.Client{ID: "client-1", UserID: "user-1", Send: make(chan []byte, 8), Authenticated: true}
+
+	// Act
+	driveAndDisconnect(t, hub, h, client)
+
+	// Assert: 切断の処理が終わっても、速度コマンドは2タブの2回だけ
+	for hub.ClientCount() != 1 {
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if _, n := rec.last(); n != 2 {
+		t.Errorf("Expected no stop command while the lock holder is still controlling, got %d commands", n)
+	}
+}
+
+// TestStopOnDisconnect_ViewerWithSameUserDoesNotSuppress は同じユーザーIDでも、
+// 操作していない接続（ビューア）が残っているだけなら停止することをテストする
+func TestStopOnDisconnect_ViewerWithSameUserDoesNotSuppress(t *testing.T) {
+	// Arrange: 同じユーザーIDで、速度コマンドを送らないビューアが接続したまま
+	hub, h, _, rec := setupDisconnectStop(t)
+	viewer := &server.Client{ID: "viewer-1", UserID: "user-1", Send: make(chan []byte, 8), Authenticated: true}
+	hub.Register(viewer)
+	client := &server.Client{ID: "client-1", UserID: "user-1", Send: make(chan []byte, 8), Authenticated: true}
+
+	// Act
+	driveAndDisconnect(t, hub, h, client)
+
+	// Assert
+	waitForStop(t, rec, 2)
+}
+
+// TestStopOnDisconnect_ForgetsDisconnectedControllers は切断済みの操作者を「操作中の接続」として数えないことをテストする
+func TestStopOnDisconnect_ForgetsDisconnectedControllers(t *testing.T) {
+	// Arrange: 同じユーザーの別タブが操作して切断し、その停止まで済んでいる
+	hub, h, _, rec := setupDisconnectStop(t)
+	other := &server.Client{ID: "client-2", UserID: "user-1", Send: make(chan []byte, 8), Authenticated: true}
+	driveAndDisconnect(t, hub, h, other)
+	waitForStop(t, rec, 2)
+	client := &server.Client{ID: "client-1", UserID: "user-1", Send: make(chan []byte, 8), Authenticated: true}
+
+	// Act
+	driveAndDisconnect(t, hub, h, client)
+
+	// Assert: client-2 は残っていないので、client-1 の切断で停止する
+	waitForStop(t, rec, 4)
+}
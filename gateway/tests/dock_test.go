@@ -0,0 +1,128 @@
+// =============================================================================
+// ファイル: dock_test.go
+// 概要: ドッキング（dock / undock）と、モックのドッキングの模擬のテストコード
+// =============================================================================
+package tests
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/adapter/mock"
+	"github.com/robot-ai-webapp/gateway/internal/convert"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"go.uber.org/zap"
+)
+
+// noDockAdapter: ドッキングに対応しない（SupportsDocking が false の）ロボット
+type noDockAdapter struct {
+	adapter.RobotAdapter
+}
+
+func (n *noDockAdapter) GetCapabilities() adapter.Capabilities {
+	caps := n.RobotAdapter.GetCapabilities()
+	caps.SupportsDocking = false
+	return caps
+}
+
+// setupDockHandler: odom と battery を生成し、ドックから x=0.3m 離れた robot-1 を作る
+func setupDockHandler(t *testing.T, opts ...testHandlerOption) *testEnv {
+	t.Helper()
+	opts = append([]testHandlerOption{withConnectConfig(map[string]any{"enabled_topics": "odom,battery"})}, opts...)
+	env := newTestHandler(t, opts...)
+	cmd := adapter.Command{RobotID: "robot-1", Type: "reset_pose", Payload: map[string]any{"x": 0.3}}
+	if err := env.adp.SendCommand(context.Background(), cmd); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return env
+}
+
+// sendDock: dock / undock を送り、応答を返す
+func sendDock(t *testing.T, env *testEnv, msgType protocol.MessageType) *protocol.Message {
+	t.Helper()
+	return sendAndDecode(t, env.h, env.client, protocol.NewMessage(msgType, "robot-1"))
+}
+
+// readSensor: アダプターから topic の現在の値を読む
+func readSensor(t *testing.T, adp adapter.RobotAdapter, topic string) map[string]any {
+	t.Helper()
+	data, err := adp.(adapter.SensorReader).ReadSensor(context.Background(), topic)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return data.Data
+}
+
+// distanceFromDock: オドメトリの位置とドック（原点）との距離
+func distanceFromDock(t *testing.T, adp adapter.RobotAdapter) float64 {
+	t.Helper()
+	odom := readSensor(t, adp, "odom")
+	x, _ := convert.ToFloat64(odom["position_x"])
+	y, _ := convert.ToFloat64(odom["position_y"])
+	return math.Hypot(x, y)
+}
+
+// TestDock_ReachesDockAndCharges は dock でロボットがドックまで移動し、充電中になることをテストする
+func TestDock_ReachesDockAndCharges(t *testing.T) {
+	// Arrange
+	env := setupDockHandler(t)
+
+	// Act
+	resp := sendDock(t, env, protocol.MsgTypeDock)
+
+	// Assert: ACK の後、ドックに着いて充電が始まる
+	if resp.Type != protocol.MsgTypeCommandAck || resp.Payload["command"] != "dock" {
+		t.Fatalf("Expected dock to be acknowledged, got %s %v", resp.Type, resp.Payload)
+	}
+	waitFor(t, func() bool { return readSensor(t, env.adp, "battery")["charging"] == true })
+	if d := distanceFromDock(t, env.adp); d > 0.05 {
+		t.Errorf("Expected the robot to be at the dock while charging, got %.3f m away", d)
+	}
+}
+
+// TestDock_UndockStopsChargingAndBacksOff は undock で充電が終わり、ドックから離れた位置に移ることをテストする
+func TestDock_UndockStopsChargingAndBacksOff(t *testing.T) {
+	// Arrange: ドッキング済みのロボット
+	env := setupDockHandler(t)
+	sendDock(t, env, protocol.MsgTypeDock)
+	waitFor(t, func() bool { return readSensor(t, env.adp, "battery")["charging"] == true })
+
+	// Act
+	resp := sendDock(t, env, protocol.MsgTypeUndock)
+
+	// Assert
+	if resp.Type != protocol.MsgTypeCommandAck || resp.Payload["command"] != "undock" {
+		t.Fatalf("Expected undock to be acknowledged, got %s %v", resp.Type, resp.Payload)
+	}
+	if readSensor(t, env.adp, "battery")["charging"] != false {
+		t.Error("Expected charging to stop after undock")
+	}
+	if d := distanceFromDock(t, env.adp); math.Abs(d-0.3) > 0.05 {
+		t.Errorf("Expected the robot to back off about 0.3 m from the dock, got %.3f m", d)
+	}
+}
+
+// TestDock_RejectsRobotWithoutDocking はドッキング非対応のロボットへの dock をアダプターに送らずに拒否することをテストする
+func TestDock_RejectsRobotWithoutDocking(t *testing.T) {
+	// Arrange
+	registry := setupMockRegistry(zap.NewNop())
+	registry.RegisterFactory("no-dock", func(l *zap.Logger) adapter.RobotAdapter {
+		return &noDockAdapter{RobotAdapter: mock.Factory(l)}
+	})
+	env := setupDockHandler(t, withRegistry(registry, "no-dock"))
+
+	// Act
+	resp := sendDock(t, env, protocol.MsgTypeDock)
+
+	// Assert: 能力のないコマンドとして command_not_allowed が返り、ロボットは動き出さない
+	if resp.Type != protocol.MsgTypeError || resp.Payload["code"] != protocol.ErrCodeCommandNotAllowed {
+		t.Fatalf("Expected command_not_allowed, got %s %q %v", resp.Type, resp.Error, resp.Payload)
+	}
+	time.Sleep(200 * time.Millisecond) // ドックに向かうならこの間に動く
+	if d := distanceFromDock(t, env.adp.(*noDockAdapter).RobotAdapter); d != 0.3 {
+		t.Errorf("Expected the robot to stay 0.3 m from the dock, got %.3f m", d)
+	}
+}
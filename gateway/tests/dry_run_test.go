@@ -0,0 +1,156 @@
+// =============================================================================
+// ファイル: dry_run_test.go
+// 概要: コマンドのプレビュー（dry_run）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/safety"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// setupDryRunHandler: 接続済みの mock ロボット "robot-1" と、E-Stop・操作ロックを外から操作できるハンドラーを作る
+func setupDryRunHandler(t *testing.T, dedup *safety.CommandDeduplicator) (*server.Handler, *server.Client, *safety.EStopManager, *safety.OperationLock) {
+	t.Helper()
+	env := newTestHandler(t, withDedup(dedup))
+	return env.h, env.client, env.estop, env.opLock
+}
+
+// TestDryRun_VelocityReturnsClampedValuesWithoutSending はドライランの速度コマンドがクランプ後の値を返し、送信もロック取得もしないことをテストする
+func TestDryRun_VelocityReturnsClampedValuesWithoutSending(t *testing.T) {
+	// Arrange
+	h, client, _, opLock := setupDryRunHandler(t, nil)
+	msg := protocol.NewMessage(protocol.MsgTypeVelocityCommand, "robot-1")
+	msg.Payload["linear_x"] = 3.0
+	msg.Payload["angular_z"] = 0.5
+	msg.Payload["dry_run"] = true
+
+	// Act
+	resp := sendAndDecode(t, h, client, msg)
+
+	// Assert
+	if resp.Type != protocol.MsgTypeCommandAck || resp.Payload["dry_run"] != true {
+		t.Fatalf("Expected a dry-run cmd_ack, got %s %v (%s)", resp.Type, resp.Payload, resp.Error)
+	}
+	result, ok := resp.Payload["result"].(map[string]any)
+	if !ok {
+		t.Fatalf("Expected a result object, got %v", resp.Payload["result"])
+	}
+	if fmt.Sprint(result["linear_x"]) != "1" || result["clamped"] != true {
+		t.Errorf("Expected linear_x clamped to 1, got %v", result)
+	}
+	if resp.Payload["would_acquire_lock"] != true {
+		t.Errorf("Expected would_acquire_lock true, got %v", resp.Payload["would_acquire_lock"])
+	}
+	if opLock.GetLockInfo("robot-1") != nil {
+		t.Error("Expected no operation lock to be taken by a dry run")
+	}
+	if v := h.LastVelocity("robot-1"); v.LinearX != 0 || v.AngularZ != 0 {
+		t.Errorf("Expected no velocity to be sent, last velocity is %+v", v)
+	}
+}
+
+// TestDryRun_EStopStillRejects はドライランでも E-Stop 中のコマンドが拒否されることをテストする
+func TestDryRun_EStopStillRejects(t *testing.T) {
+	// Arrange
+	h, client, estop, _ := setupDryRunHandler(t, nil)
+	if err := estop.Activate(context.Background(), "robot-1", "admin", "test"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	msg := protocol.NewMessage(protocol.MsgTypeVelocityCommand, "robot-1")
+	msg.Payload["linear_x"] = 0.2
+	msg.Payload["dry_run"] = true
+
+	// Act
+	resp := sendAndDecode(t, h, client, msg)
+
+	// Assert
+	if resp.Type != protocol.MsgTypeError || resp.Error != "E-Stop is active" {
+		t.Errorf("Expected an E-Stop error, got %s %v (%s)", resp.Type, resp.Payload, resp.Error)
+	}
+}
+
+// TestDryRun_ReportsOtherUsersLock はドライランでも他のユーザーのロックがあれば拒否されることをテストする
+func TestDryRun_ReportsOtherUsersLock(t *testing.T) {
+	// Arrange
+	h, client, _, opLock := setupDryRunHandler(t, nil)
+	if _, err := opLock.Acquire("robot-1", "user-2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	msg := protocol.NewMessage(protocol.MsgTypeDock, "robot-1")
+	msg.Payload["dry_run"] = true
+
+	// Act
+	resp := sendAndDecode(t, h, client, msg)
+
+	// Assert
+	if resp.Type != protocol.MsgTypeError {
+		t.Fatalf("Expected an error while another user holds the lock, got %s %v", resp.Type, resp.Payload)
+	}
+	if lock := opLock.GetLockInfo("robot-1"); lock == nil || lock.UserID != "user-2" {
+		t.Errorf("Expected user-2 to keep the lock, got %+v", lock)
+	}
+}
+
+// TestDryRun_NotRecordedForDeduplication はドライランの ACK が重複排除に記録されず、同じ command_id で本番を実行できることをテストする
+func TestDryRun_NotRecordedForDeduplication(t *testing.T) {
+	// Arrange
+	h, client, _, _ := setupDryRunHandler(t, safety.NewCommandDeduplicator(time.Minute, []string{"velocity"}, zap.NewNop()))
+	velocity := func(dryRun bool) *protocol.Message {
+		msg := protocol.NewMessage(protocol.MsgTypeVelocityCommand, "robot-1")
+		msg.Payload["linear_x"] = 0.2
+		msg.Payload["command_id"] = "cmd-1"
+		if dryRun {
+			msg.Payload["dry_run"] = true
+		}
+		return sendAndDecode(t, h, client, msg)
+	}
+
+	// Act
+	preview := velocity(true)
+	resp := velocity(false)
+
+	// Assert
+	if preview.Payload["dry_run"] != true || preview.Payload["command_id"] != "cmd-1" {
+		t.Fatalf("Expected a dry-run ack echoing command_id, got %v", preview.Payload)
+	}
+	if resp.Type != protocol.MsgTypeCommandAck || resp.Payload["dry_run"] != nil || resp.Payload["duplicate"] != nil {
+		t.Errorf("Expected the real command to run, got %s %v", resp.Type, resp.Payload)
+	}
+	if v := h.LastVelocity("robot-1"); v.LinearX != 0.2 {
+		t.Errorf("Expected the real command to be sent, last velocity is %+v", v)
+	}
+}
+
+// TestDryRun_NavigationDoesNotReportLock は操作ロックを取らない nav_goal / nav_cancel のドライランに
+// would_acquire_lock が入らないことをテストする
+func TestDryRun_NavigationDoesNotReportLock(t *testing.T) {
+	// Arrange
+	h, client, _, _ := setupDryRunHandler(t, nil)
+	goal := protocol.NewMessage(protocol.MsgTypeNavigationGoal, "robot-1")
+	goal.Payload["x"] = 1.0
+	goal.Payload["y"] = 2.0
+	goal.Payload["dry_run"] = true
+	cancel := protocol.NewMessage(protocol.MsgTypeNavigationCancel, "robot-1")
+	cancel.Payload["dry_run"] = true
+
+	for _, msg := range []*protocol.Message{goal, cancel} {
+		// Act
+		resp := sendAndDecode(t, h, client, msg)
+
+		// Assert
+		if resp.Type != protocol.MsgTypeCommandAck || resp.Payload["dry_run"] != true {
+			t.Fatalf("Expected a dry-run cmd_ack for %s, got %s %v (%s)", msg.Type, resp.Type, resp.Payload, resp.Error)
+		}
+		if _, ok := resp.Payload["would_acquire_lock"]; ok {
+			t.Errorf("Expected no would_acquire_lock for %s, got %v", msg.Type, resp.Payload)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// =============================================================================
+// ファイル: egress_metrics_test.go
+// 概要: クライアントへの送信量（メッセージタイプ×ロボットごと）のメトリクスのテストコード
+// =============================================================================
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/robot-ai-webapp/gateway/internal/metrics"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// TestPeekHeader_ReadsTypeAndRobot は内部の形式のバイト列から type と robot_id だけを読めることをテストする
+func TestPeekHeader_ReadsTypeAndRobot(t *testing.T) {
+	// Arrange: 大きな payload を持つ sensor_data
+	msg := protocol.NewMessage(protocol.MsgTypeSensorData, "robot-1")
+	msg.Topic = "scan"
+	msg.Payload["data"] = map[string]any{"ranges": make([]float64, 360)}
+	data, err := protocol.NewCodec().Encode(msg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Act
+	msgType, robotID := protocol.PeekHeader(data)
+
+	// Assert
+	if msgType != protocol.MsgTypeSensorData || robotID != "robot-1" {
+		t.Errorf("Expected sensor_data for robot-1, got %q %q", msgType, robotID)
+	}
+	if msgType, robotID := protocol.PeekHeader([]byte("not msgpack")); msgType != "" || robotID != "" {
+		t.Errorf("Expected empty values for invalid data, got %q %q", msgType, robotID)
+	}
+}
+
+// TestEgressMetrics_CountsBytesWrittenToClients は書き出したバイト数がタイプ別に /metrics に出ることをテストする
+func TestEgressMetrics_CountsBytesWrittenToClients(t *testing.T) {
+	// Arrange: 送信量を数える WebSocket サーバーと、JSON を選んだクライアント
+	logger := zap.NewNop()
+	hub := server.NewHub(logger)
+	go hub.Run()
+	ws := server.NewWebSocketServer(hub, server.NewHandler(hub, nil, nil, nil, nil, nil, nil, nil, logger), 0, 0, logger)
+	egress := metrics.NewEgressMetrics()
+	ws.SetEgressMetrics(egress)
+	mm := metrics.NewMessageMetrics()
+	mm.AddCollector(egress)
+	srv := httptest.NewServer(http.HandlerFunc(ws.HandleWebSocket))
+	t.Cleanup(srv.Close)
+	dialer := websocket.Dialer{Subprotocols: []string{protocol.FormatJSON}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	// Act: 認証して connection_status を受け取る
+	auth := protocol.NewMessage(protocol.MsgTypeAuth, "")
+	auth.Payload["token"] = "token"
+	data, _ := json.Marshal(auth)
+	if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, reply, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Assert: クライアントが受け取った JSON のバイト数と同じ値が数えられている
+	want := fmt.Sprintf(`gateway_egress_bytes_total{type="conn_status",robot_id=""} %d`, len(reply))
+	var out string
+	for i := 0; i < 100; i++ { // 書き出し後の集計が反映されるまで待つ
+		rec := httptest.NewRecorder()
+		mm.Handler(rec, httptest.NewRequest("GET", "/metrics", nil))
+		body, _ := io.ReadAll(rec.Body)
+		if out = string(body); strings.Contains(out, want) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if !strings.Contains(out, want) || !strings.Contains(out, `gateway_egress_messages_total{type="conn_status",robot_id=""} 1`) {
+		t.Errorf("Expected metrics output to contain %q, got:\n%s", want, out)
+	}
+}
@@ -0,0 +1,111 @@
+// =============================================================================
+// ファイル: error_budget_test.go
+// 概要: クライアントごとのエラーバジェット（連続エラーでの切断）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// dialTestServer: エラーバジェット付きの WebSocket サーバーを立ち上げて接続する
+func dialTestServer(t *testing.T, budget int) *websocket.Conn {
+	t.Helper()
+	logger := zap.NewNop()
+	hub := server.NewHub(logger)
+	go hub.Run()
+	h := server.NewHandler(hub, nil, nil, nil, nil, nil, nil, nil, logger)
+	ws := server.NewWebSocketServer(hub, h, 0, 0, logger)
+	ws.SetErrorBudget(budget)
+
+	srv := httptest.NewServer(http.HandlerFunc(ws.HandleWebSocket))
+	t.Cleanup(srv.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readErrorCode: 次のメッセージを読み、エラーコードを返す
+func readErrorCode(t *testing.T, conn *websocket.Conn) string {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	msg, err := protocol.NewCodec().Decode(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	code, _ := msg.Payload["code"].(string)
+	return code
+}
+
+// TestErrorBudget_DisconnectsAfterConsecutiveErrors は連続エラーで最後のエラーを送って切断することをテストする
+func TestErrorBudget_DisconnectsAfterConsecutiveErrors(t *testing.T) {
+	// Arrange
+	conn := dialTestServer(t, 3)
+
+	// Act: デコードできないメッセージを3回送る
+	for i := 0; i < 3; i++ {
+		if err := conn.WriteMessage(websocket.BinaryMessage, []byte{0xc1}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// Assert: 2回は invalid_message、3回目の後に error_budget_exceeded が届き、切断される
+	for i := 0; i < 3; i++ {
+		if code := readErrorCode(t, conn); code != protocol.ErrCodeInvalidMessage {
+			t.Fatalf("Expected invalid_message, got %q", code)
+		}
+	}
+	if code := readErrorCode(t, conn); code != protocol.ErrCodeErrorBudgetExceeded {
+		t.Fatalf("Expected error_budget_exceeded, got %q", code)
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err := conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != websocket.ClosePolicyViolation || closeErr.Text != "error budget exceeded" {
+		t.Errorf("Expected a policy-violation closure with the reason, got %v", err)
+	}
+}
+
+// TestErrorBudget_ResetsOnSuccess は正常なメッセージで連続エラー数が0に戻ることをテストする
+func TestErrorBudget_ResetsOnSuccess(t *testing.T) {
+	conn := dialTestServer(t, 2)
+	ping, err := protocol.NewCodec().Encode(protocol.NewMessage(protocol.MsgTypePing, ""))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// エラー → 成功（ping）→ エラー の順なら、連続エラーは最大1回で切断されない
+	for _, data := range [][]byte{{0xc1}, ping, {0xc1}, ping} {
+		if err := conn.WriteMessage(websocket.BinaryMessage, data); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	codes := make([]string, 0, 4)
+	for i := 0; i < 4; i++ {
+		codes = append(codes, readErrorCode(t, conn))
+	}
+	for _, code := range codes {
+		if code == protocol.ErrCodeErrorBudgetExceeded {
+			t.Fatalf("Expected no disconnect, got codes %v", codes)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+// =============================================================================
+// ファイル: error_hints_test.go
+// 概要: エラー応答の復旧のヒント（action / retry_after_ms）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// TestErrorHints_NotAuthenticatedAsksForReauth は未認証のコマンドに reauth のヒントが付くことをテストする
+func TestErrorHints_NotAuthenticatedAsksForReauth(t *testing.T) {
+	// Arrange
+	h, _ := setupPolicyHandler(t, setupMockRegistry(zap.NewNop()), "mock")
+	client := &server.Client{ID: "client-2", Send: make(chan []byte, 8)}
+	msg := protocol.NewMessage(protocol.MsgTypeVelocityCommand, "robot-1")
+	msg.Payload["linear_x"] = 0.2
+
+	// Act
+	resp := sendAndDecode(t, h, client, msg)
+
+	// Assert
+	if resp.Payload["code"] != protocol.ErrCodeNotAuthenticated {
+		t.Fatalf("Expected not_authenticated, got %v", resp.Payload)
+	}
+	if resp.Action != protocol.ActionReauth || resp.RetryAfterMs != 0 {
+		t.Errorf("Expected action=reauth without delay, got %q %d", resp.Action, resp.RetryAfterMs)
+	}
+}
+
+// TestErrorHints_NoHintForUnrecoverableErrors は送り直しても成功しないエラーにはヒントが付かないことをテストする
+func TestErrorHints_NoHintForUnrecoverableErrors(t *testing.T) {
+	// Arrange
+	h, client := setupPolicyHandler(t, setupMockRegistry(zap.NewNop()), "mock")
+	msg := protocol.NewMessage(protocol.MsgTypeVelocityCommand, "robot-1")
+	msg.Payload["linear_x"] = "fast"
+
+	// Act
+	resp := sendAndDecode(t, h, client, msg)
+
+	// Assert
+	if resp.Payload["code"] != protocol.ErrCodeInvalidMessage {
+		t.Fatalf("Expected invalid_message, got %v", resp.Payload)
+	}
+	if resp.Action != "" || resp.RetryAfterMs != 0 {
+		t.Errorf("Expected no hint, got %q %d", resp.Action, resp.RetryAfterMs)
+	}
+}
+
+// TestErrorHints_RetryHintIsEncoded はヒントが JSON のフィールドとして出力されることをテストする
+func TestErrorHints_RetryHintIsEncoded(t *testing.T) {
+	// Arrange
+	msg := protocol.NewMessage(protocol.MsgTypeError, "robot-1")
+	msg.Payload["code"] = protocol.ErrCodeAdapterTimeout
+	msg.SetRecoveryHint(protocol.ErrCodeAdapterTimeout)
+
+	// Act
+	data, err := protocol.JSONCodec{}.Encode(msg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Assert
+	if decoded["action"] != protocol.ActionRetry || decoded["retry_after_ms"] != float64(1000) {
+		t.Errorf("Expected action=retry and retry_after_ms=1000, got %v", decoded)
+	}
+}
@@ -0,0 +1,100 @@
+// =============================================================================
+// ファイル: estop_all_test.go
+// 概要: 全体緊急停止（ActivateAll）で停止に失敗したロボットの扱いのテストコード
+// =============================================================================
+package tests
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/adapter/mock"
+	"github.com/robot-ai-webapp/gateway/internal/safety"
+	"go.uber.org/zap"
+)
+
+// failingEStopAdapter: 最初の failures 回だけ EmergencyStop に失敗するモックアダプター
+// （failures が負なら常に失敗する）。stuck なら Disconnect にも失敗する。
+type failingEStopAdapter struct {
+	*mock.MockAdapter
+	mu       sync.Mutex
+	failures int
+	attempts int
+	stuck    bool
+}
+
+func (a *failingEStopAdapter) EmergencyStop(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.attempts++
+	if a.failures < 0 || a.attempts <= a.failures {
+		return errors.New("estop line not responding")
+	}
+	return a.MockAdapter.EmergencyStop(ctx)
+}
+
+func (a *failingEStopAdapter) Disconnect(ctx context.Context) error {
+	if a.stuck {
+		return errors.New("link stuck")
+	}
+	return a.MockAdapter.Disconnect(ctx)
+}
+
+// TestEStopActivateAll_RetriesAndEscalates - 再試行で止まったもの・切断したもの・切断もできないものを区別するテスト
+func TestEStopActivateAll_RetriesAndEscalates(t *testing.T) {
+	// Arrange: 正常・1回だけ失敗・常に失敗・切断もできない の4台
+	logger := zap.NewNop()
+	registry := adapter.NewRegistry(logger)
+	robots := map[string]*failingEStopAdapter{
+		"robot-ok":     {failures: 0},
+		"robot-flaky":  {failures: 1},
+		"robot-broken": {failures: -1},
+		"robot-stuck":  {failures: -1, stuck: true},
+	}
+	for robotID, adp := range robots {
+		adp.MockAdapter = mock.NewMockAdapter(logger)
+		registry.RegisterFactory(robotID, func(*zap.Logger) adapter.RobotAdapter { return adp })
+		if _, err := registry.CreateAdapter(robotID, robotID); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := adp.Connect(context.Background(), map[string]any{"enabled_topics": "battery"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		t.Cleanup(func() { _ = adp.MockAdapter.Disconnect(context.Background()) })
+	}
+	estop := safety.NewEStopManager(registry, logger)
+	estop.SetRetryPolicy(2, time.Millisecond)
+
+	// Act
+	result := estop.ActivateAll(context.Background(), "user-1", "test")
+
+	// Assert: 再試行で止まったロボットは Stopped に入る
+	if want := []string{"robot-flaky", "robot-ok"}; !reflect.DeepEqual(result.Stopped, want) {
+		t.Errorf("Expected stopped %v, got %v", want, result.Stopped)
+	}
+	// 止まらなかったロボットは Failed に残り、切断できたものだけが Disconnected に入る
+	if want := []string{"robot-broken", "robot-stuck"}; !reflect.DeepEqual(result.Failed, want) {
+		t.Errorf("Expected failed %v, got %v", want, result.Failed)
+	}
+	if want := []string{"robot-broken"}; !reflect.DeepEqual(result.Disconnected, want) {
+		t.Errorf("Expected disconnected %v, got %v", want, result.Disconnected)
+	}
+	if robots["robot-broken"].IsConnected() {
+		t.Error("Expected robot-broken to be disconnected to cut off command flow")
+	}
+	// 初回 + 再試行2回
+	if got := robots["robot-broken"].attempts; got != 3 {
+		t.Errorf("Expected 3 E-Stop attempts for robot-broken, got %d", got)
+	}
+	// 失敗したロボットも E-Stop 状態として記録されている
+	for robotID := range robots {
+		if !estop.IsActive(robotID) {
+			t.Errorf("Expected %s to be flagged as E-Stop active", robotID)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// =============================================================================
+// ファイル: flow_control_test.go
+// 概要: FlowController（クライアントの混雑に応じた生成頻度の調整）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"testing"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter/mock"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// TestFlowController_ThrottlesAndRecovers は全購読者が遅い時に頻度を下げ、解消後に戻すことをテストする
+func TestFlowController_ThrottlesAndRecovers(t *testing.T) {
+	// Arrange: robot-1 を購読する1クライアント（Send バッファ 4）
+	hub := server.NewHub(zap.NewNop())
+	go hub.Run()
+	client := &server.Client{
+		ID:            "slow-client",
+		Send:          make(chan []byte, 4),
+		Subscriptions: map[string]bool{},
+	}
+	hub.Register(client)
+	hub.SubscribeClient(client, "robot-1")
+
+	m := mock.NewMockAdapter(zap.NewNop())
+	fc := server.NewFlowController(hub, "robot-1", m, zap.NewNop())
+
+	// Act: バッファを満杯にして調整
+	for i := 0; i < cap(client.Send); i++ {
+		client.Send <- []byte("x")
+	}
+	throttled := fc.Adjust()
+
+	// バッファを空にして再調整
+	for len(client.Send) > 0 {
+		<-client.Send
+	}
+	recovered := fc.Adjust()
+
+	// Assert
+	if throttled != 0.5 {
+		t.Errorf("Expected rate factor 0.5 under pressure, got %v", throttled)
+	}
+	if recovered != 1.0 {
+		t.Errorf("Expected rate factor 1.0 after recovery, got %v", recovered)
+	}
+}
+
+// TestMockSetSampleRate_Validation は未知のトピックや不正な頻度が拒否されることをテストする
+func TestMockSetSampleRate_Validation(t *testing.T) {
+	m := mock.NewMockAdapter(zap.NewNop())
+
+	if err := m.SetSampleRate("battery", 1); err == nil {
+		t.Error("Expected error for battery topic, got nil")
+	}
+	if err := m.SetSampleRate("scan", 0); err == nil {
+		t.Error("Expected error for zero rate, got nil")
+	}
+	if err := m.SetSampleRate("scan", 2); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// TestHubRobotPressure_NoSubscribers は購読者がいなければ混雑度 0 を返すことをテストする
+func TestHubRobotPressure_NoSubscribers(t *testing.T) {
+	hub := server.NewHub(zap.NewNop())
+
+	if p := hub.RobotPressure("robot-1"); p != 0 {
+		t.Errorf("Expected pressure 0 without subscribers, got %v", p)
+	}
+}
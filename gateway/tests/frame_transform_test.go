@@ -0,0 +1,74 @@
+// =============================================================================
+// ファイル: frame_transform_test.go
+// 概要: ナビゲーション目標の座標系変換のテストコード
+// =============================================================================
+package tests
+
+import (
+	"math"
+	"testing"
+
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// setupNavHandler: "display" → "map" の変換（90度回転 + X方向に1m）を設定したハンドラーを作る
+func setupNavHandler() (*server.Handler, *server.Client) {
+	logger := zap.NewNop()
+	h := server.NewHandler(server.NewHub(logger), setupMockRegistry(logger), nil, nil, nil, nil, nil, nil, logger)
+	h.SetNavFrameTransforms("map", map[string]server.FrameTransform{
+		"display": {TranslationX: 1, Rotation: math.Pi / 2},
+	})
+	client := &server.Client{ID: "client-1", Send: make(chan []byte, 4), Authenticated: true}
+	return h, client
+}
+
+// TestNavGoal_TransformedToTargetFrame は目標地点がロボットの座標系に変換されることをテストする
+func TestNavGoal_TransformedToTargetFrame(t *testing.T) {
+	// Arrange: display 座標系の (1, 0)、向きは 0 rad
+	h, client := setupNavHandler()
+	msg := protocol.NewMessage(protocol.MsgTypeNavigationGoal, "robot-1")
+	msg.Payload["frame_id"] = "display"
+	msg.Payload["x"] = 1.0
+	msg.Payload["y"] = 0.0
+	msg.Payload["oz"] = 0.0
+	msg.Payload["ow"] = 1.0
+
+	// Act
+	h.HandleMessage(client, msg)
+
+	// Assert: 90度回転で (0, 1)、平行移動で (1, 1)。向きは 90度（oz = ow = √2/2）
+	resp, err := protocol.NewCodec().Decode(<-client.Send)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Type != protocol.MsgTypeCommandAck {
+		t.Fatalf("Expected cmd_ack, got %s (%s)", resp.Type, resp.Error)
+	}
+	x, y := msg.Payload["x"].(float64), msg.Payload["y"].(float64)
+	if math.Abs(x-1) > 1e-9 || math.Abs(y-1) > 1e-9 || msg.Payload["frame_id"] != "map" {
+		t.Errorf("Expected (1, 1) in map, got (%v, %v) in %v", x, y, msg.Payload["frame_id"])
+	}
+	if oz := msg.Payload["oz"].(float64); math.Abs(oz-math.Sqrt2/2) > 1e-9 {
+		t.Errorf("Expected oz %v, got %v", math.Sqrt2/2, oz)
+	}
+}
+
+// TestNavGoal_UnknownFrameRejected は変換が設定されていない座標系の目標が拒否されることをテストする
+func TestNavGoal_UnknownFrameRejected(t *testing.T) {
+	h, client := setupNavHandler()
+	msg := protocol.NewMessage(protocol.MsgTypeNavigationGoal, "robot-1")
+	msg.Payload["frame_id"] = "camera"
+	msg.Payload["x"] = 1.0
+
+	h.HandleMessage(client, msg)
+
+	resp, err := protocol.NewCodec().Decode(<-client.Send)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Payload["code"] != protocol.ErrCodeUnknownFrame {
+		t.Errorf("Expected unknown_frame error, got %s %v", resp.Type, resp.Payload)
+	}
+}
@@ -0,0 +1,77 @@
+// =============================================================================
+// ファイル: health_status_test.go
+// 概要: health_status（ゲートウェイとロボットのヘルス状態の問い合わせ）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/safety"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// TestHealthStatus_ReportsSubscribedRobots は購読中ロボットの状態がまとめて返ることをテストする
+func TestHealthStatus_ReportsSubscribedRobots(t *testing.T) {
+	// Arrange: robot-1 を登録し、最終センサー時刻とロックを設定
+	logger := zap.NewNop()
+	registry := setupMockRegistry(logger)
+	if _, err := registry.CreateAdapter("robot-1", "mock"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	hub := server.NewHub(logger)
+	opLock := safety.NewOperationLock(time.Minute, logger)
+	if _, err := opLock.Acquire("robot-1", "user-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	h := server.NewHandler(hub, registry, safety.NewEStopManager(registry, logger), nil, nil, opLock, nil, nil, logger)
+
+	client := &server.Client{
+		ID:            "client-1",
+		Send:          make(chan []byte, 4),
+		Subscriptions: map[string]bool{"robot-1": true},
+		Authenticated: true,
+	}
+	hub.MarkSensorData("robot-1", 1234)
+
+	// Act
+	h.HandleMessage(client, protocol.NewMessage(protocol.MsgTypeHealthStatus, ""))
+
+	// Assert
+	resp, err := protocol.NewCodec().Decode(<-client.Send)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Type != protocol.MsgTypeHealthStatus {
+		t.Fatalf("Expected health_status, got %s (%s)", resp.Type, resp.Error)
+	}
+	gateway, _ := resp.Payload["gateway"].(map[string]any)
+	if gateway["redis"] != "disabled" {
+		t.Errorf("Expected redis disabled, got %v", gateway["redis"])
+	}
+	robots, _ := resp.Payload["robots"].(map[string]any)
+	robot, _ := robots["robot-1"].(map[string]any)
+	if robot["registered"] != true || robot["lock_holder"] != "user-1" {
+		t.Errorf("Unexpected robot status: %v", robot)
+	}
+}
+
+// TestHealthStatus_RequiresAuthentication は未認証の問い合わせがエラーになることをテストする
+func TestHealthStatus_RequiresAuthentication(t *testing.T) {
+	hub := server.NewHub(zap.NewNop())
+	h := server.NewHandler(hub, nil, nil, nil, nil, nil, nil, nil, zap.NewNop())
+	client := &server.Client{ID: "client-1", Send: make(chan []byte, 1)}
+
+	h.HandleMessage(client, protocol.NewMessage(protocol.MsgTypeHealthStatus, ""))
+
+	resp, err := protocol.NewCodec().Decode(<-client.Send)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Type != protocol.MsgTypeError {
+		t.Errorf("Expected error response, got %s", resp.Type)
+	}
+}
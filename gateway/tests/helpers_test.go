@@ -36,6 +36,10 @@ package tests
 // 複数のパッケージをまとめてインポートする場合は () で囲む。
 // =============================================================================
 import (
+	"context"
+	"testing"
+	"time"
+
 	// adapter パッケージ: ロボットアダプターの登録・管理機能を提供
 	// Registry（レジストリ）は、利用可能なロボットアダプターを管理する仕組み
 	"github.com/robot-ai-webapp/gateway/internal/adapter"
@@ -47,6 +51,10 @@ import (
 	// 偽物を使ってテストする。これを「モック」と呼ぶ。
 	"github.com/robot-ai-webapp/gateway/internal/adapter/mock"
 
+	// safety / server パッケージ: newTestHandler で組み立てるハンドラーと安全機構
+	"github.com/robot-ai-webapp/gateway/internal/safety"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+
 	// zap パッケージ: Uber社が開発した高性能ロギングライブラリ
 	// 【ロギングとは？】
 	// プログラムの動作状況を記録すること。デバッグや問題調査に使う。
@@ -104,3 +112,152 @@ func setupMockRegistry(logger *zap.Logger) *adapter.Registry {
 	// これにより、他のテスト関数でこのレジストリを使える
 	return registry
 }
+
+// =============================================================================
+// connectRobot - ロボットを作成して接続する関数
+// =============================================================================
+//
+// registry に robotID のアダプター（adapterType）を作り、config で接続します。
+// テストの終了時には自動で切断します（t.Cleanup）。
+func connectRobot(t *testing.T, registry *adapter.Registry, robotID, adapterType string, config map[string]any) adapter.RobotAdapter {
+	t.Helper()
+	adp, err := registry.CreateAdapter(robotID, adapterType)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := adp.Connect(context.Background(), config); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { _ = adp.Disconnect(context.Background()) })
+	return adp
+}
+
+// =============================================================================
+// newTestHandler - 接続済みの robot-1 とハンドラー、認証済みクライアントを作る関数
+// =============================================================================
+//
+// 【既定の構成】
+// - robot-1: モックアダプター（battery のみ）で接続済み
+// - 安全機構: E-Stop、速度制限（1.0 m/s, 2.0 rad/s）、ウォッチドッグ（1分）、操作ロック（1分）
+// - client: client-1 / user-1 の認証済みクライアント（Hub には登録しない）
+//
+// 【オプション（testHandlerOption）】
+// テストごとに違う部分だけを with... で変更します。
+//
+//	env := newTestHandler(t, withVelocityLimits(0.5, 1.0), withAdmin())
+//	resp := sendAndDecode(t, env.h, env.client, msg)
+func newTestHandler(t *testing.T, opts ...testHandlerOption) *testEnv {
+	t.Helper()
+	logger := zap.NewNop()
+	cfg := testHandlerConfig{
+		adapterType: "mock",
+		connect:     map[string]any{"enabled_topics": "battery"},
+		maxLinear:   1.0,
+		maxAngular:  2.0,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.registry == nil {
+		cfg.registry = setupMockRegistry(logger)
+	}
+
+	env := &testEnv{
+		hub:      server.NewHub(logger),
+		registry: cfg.registry,
+		estop:    safety.NewEStopManager(cfg.registry, logger),
+		opLock:   safety.NewOperationLock(time.Minute, logger),
+		watchdog: safety.NewTimeoutWatchdog(time.Minute, cfg.registry, logger),
+		client:   &server.Client{ID: "client-1", UserID: "user-1", Send: make(chan []byte, 8), Subscriptions: map[string]bool{}, Authenticated: true},
+	}
+	// 呼び出し側がレジストリに robot-1 を用意済みなら、それをそのまま使う
+	if adp, ok := cfg.registry.GetAdapter("robot-1"); ok {
+		env.adp = adp
+	} else {
+		env.adp = connectRobot(t, cfg.registry, "robot-1", cfg.adapterType, cfg.connect)
+	}
+	if cfg.runHub {
+		go env.hub.Run()
+	}
+
+	env.h = server.NewHandler(env.hub, cfg.registry, env.estop,
+		safety.NewVelocityLimiter(cfg.maxLinear, cfg.maxAngular, logger),
+		env.watchdog, env.opLock, cfg.dedup, nil, logger)
+	if cfg.admin {
+		env.h.SetAdminUsers([]string{env.client.UserID})
+	}
+	return env
+}
+
+// testEnv: newTestHandler が作ったハンドラーと、テストから状態を確かめる部品
+type testEnv struct {
+	h        *server.Handler
+	client   *server.Client
+	hub      *server.Hub
+	registry *adapter.Registry
+	adp      adapter.RobotAdapter
+	estop    *safety.EStopManager
+	opLock   *safety.OperationLock
+	watchdog *safety.TimeoutWatchdog
+}
+
+// testHandlerConfig: newTestHandler の構成（testHandlerOption で変更する）
+type testHandlerConfig struct {
+	registry    *adapter.Registry
+	adapterType string
+	connect     map[string]any
+	maxLinear   float64
+	maxAngular  float64
+	dedup       *safety.CommandDeduplicator
+	runHub      bool
+	admin       bool
+}
+
+// testHandlerOption: newTestHandler の構成を変更する関数
+type testHandlerOption func(*testHandlerConfig)
+
+// withRegistry: registry を使い、robot-1 が無ければ adapterType で作る
+func withRegistry(registry *adapter.Registry, adapterType string) testHandlerOption {
+	return func(c *testHandlerConfig) {
+		c.registry = registry
+		c.adapterType = adapterType
+	}
+}
+
+// withConnectConfig: robot-1 の接続設定（既定は battery のみ）を置き換える
+func withConnectConfig(config map[string]any) testHandlerOption {
+	return func(c *testHandlerConfig) { c.connect = config }
+}
+
+// withVelocityLimits: 速度制限の上限を変更する
+func withVelocityLimits(maxLinear, maxAngular float64) testHandlerOption {
+	return func(c *testHandlerConfig) {
+		c.maxLinear = maxLinear
+		c.maxAngular = maxAngular
+	}
+}
+
+// withDedup: command_id による重複排除を有効にする
+func withDedup(dedup *safety.CommandDeduplicator) testHandlerOption {
+	return func(c *testHandlerConfig) { c.dedup = dedup }
+}
+
+// withRunningHub: Hub の Run() を起動する（配信やクライアントの登録を確かめるテスト用）
+func withRunningHub() testHandlerOption {
+	return func(c *testHandlerConfig) { c.runHub = true }
+}
+
+// withAdmin: client（user-1）を管理者にする
+func withAdmin() testHandlerOption {
+	return func(c *testHandlerConfig) { c.admin = true }
+}
+
+// registerClients: 起動済みの Hub にクライアントを登録し、反映されるまで待つ
+func registerClients(t *testing.T, hub *server.Hub, clients ...*server.Client) {
+	t.Helper()
+	want := hub.ClientCount() + len(clients)
+	for _, c := range clients {
+		hub.Register(c)
+	}
+	waitFor(t, func() bool { return hub.ClientCount() == want })
+}
@@ -0,0 +1,61 @@
+// =============================================================================
+// ファイル: hub_broadcast_bench_test.go
+// 概要: ロボット宛てのブロードキャスト（BroadcastToRobot）のベンチマーク
+//
+// 1000クライアントが接続し、各ロボットを数クライアントだけが購読している大規模な構成で、
+// 1サンプルの配信にかかる時間を測ります。購読の索引により、接続数ではなく
+// 購読者の数に比例することを確認します（subscribers の値を変えて比較）。
+//
+//	go test ./tests/ -run '^$' -bench BroadcastToRobot
+//
+// =============================================================================
+package tests
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// setupBroadcastHub: clients 個のクライアントを接続し、先頭の subscribers 個だけが robot-1 を購読する
+func setupBroadcastHub(b *testing.B, clients, subscribers int) *server.Hub {
+	b.Helper()
+	hub := server.NewHub(zap.NewNop())
+	go hub.Run()
+	for i := 0; i < clients; i++ {
+		client := &server.Client{
+			ID:            fmt.Sprintf("client-%d", i),
+			Send:          make(chan []byte, 1),
+			Subscriptions: make(map[string]bool),
+		}
+		hub.Register(client)
+		// 購読していないクライアントも、それぞれ別のロボットを購読している
+		robotID := fmt.Sprintf("robot-%d", i+2)
+		if i < subscribers {
+			robotID = "robot-1"
+		}
+		hub.SubscribeClient(client, robotID)
+	}
+	for hub.ClientCount() != clients {
+		time.Sleep(time.Millisecond)
+	}
+	return hub
+}
+
+// BenchmarkBroadcastToRobot_1000Clients は購読者の数ごとに1サンプルの配信時間を測る
+// （送信バッファは1件で満杯になり、以降は破棄の経路を通るが、どの購読者数でも条件は同じ）
+func BenchmarkBroadcastToRobot_1000Clients(b *testing.B) {
+	data := []byte("sensor sample")
+	for _, subscribers := range []int{1, 5, 50} {
+		b.Run(fmt.Sprintf("subscribers=%d", subscribers), func(b *testing.B) {
+			hub := setupBroadcastHub(b, 1000, subscribers)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				hub.BroadcastToRobot("robot-1", data)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+// =============================================================================
+// ファイル: latest_only_test.go
+// 概要: センサーデータの「最新値だけ」配信（Hub.SetLatestOnly / latest_only_topics）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"testing"
+
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// setupLatestOnlyHub: robot-1 を購読し、odom を latest-only で受け取るクライアントを持つ Hub を作る
+func setupLatestOnlyHub(t *testing.T) (*server.Hub, *server.Client) {
+	t.Helper()
+	hub := server.NewHub(zap.NewNop())
+	client := &server.Client{ID: "client-1", Send: make(chan []byte, 16), Subscriptions: map[string]bool{}}
+	hub.SubscribeClient(client, "robot-1")
+	hub.SetLatestOnly(client, "robot-1", []string{"odom"})
+	return hub, client
+}
+
+// TestLatestOnly_KeepsOnlyNewestSample は latest-only のトピックでは未送信のサンプルが最新のもので置き換わることをテストする
+func TestLatestOnly_KeepsOnlyNewestSample(t *testing.T) {
+	// Arrange
+	hub, client := setupLatestOnlyHub(t)
+
+	// Act: クライアントが送信に追いつく前に3サンプル届く（1つはまとめ送りを使わない構成の経路）
+	hub.BroadcastSensorData("robot-1", "odom", []byte("odom-1"), false)
+	hub.BroadcastSensorData("robot-1", "odom", []byte("odom-2"), false)
+	hub.BroadcastSensorSample("robot-1", "odom", []byte("odom-3"))
+
+	// Assert: Send バッファには溜まらず、スロットに最新の1件だけが残る
+	if got := len(client.Send); got != 0 {
+		t.Errorf("Expected no queued odom samples, got %d", got)
+	}
+	latest := client.TakeLatest()
+	if len(latest) != 1 || string(latest[0]) != "odom-3" {
+		t.Fatalf("Expected only odom-3 to be pending, got %q", latest)
+	}
+	if again := client.TakeLatest(); len(again) != 0 {
+		t.Errorf("Expected the slot to be empty after taking it, got %q", again)
+	}
+}
+
+// TestLatestOnly_OtherTopicsAreQueued は latest-only を指定していないトピックが従来どおり Send バッファに溜まることをテストする
+func TestLatestOnly_OtherTopicsAreQueued(t *testing.T) {
+	// Arrange
+	hub, client := setupLatestOnlyHub(t)
+
+	// Act
+	hub.BroadcastSensorData("robot-1", "scan", []byte("scan-1"), false)
+	hub.BroadcastSensorData("robot-1", "scan", []byte("scan-2"), false)
+
+	// Assert
+	if got := len(client.Send); got != 2 {
+		t.Errorf("Expected 2 queued scan samples, got %d", got)
+	}
+	if latest := client.TakeLatest(); len(latest) != 0 {
+		t.Errorf("Expected nothing in the latest-only slots, got %q", latest)
+	}
+}
+
+// TestLatestOnly_ClearedOnUnsubscribe は購読の解除で latest-only の指定も消えることをテストする
+func TestLatestOnly_ClearedOnUnsubscribe(t *testing.T) {
+	// Arrange
+	hub, client := setupLatestOnlyHub(t)
+
+	// Act
+	hub.UnsubscribeClient(client, "robot-1")
+	hub.SubscribeClient(client, "robot-1")
+	hub.BroadcastSensorData("robot-1", "odom", []byte("odom-1"), false)
+
+	// Assert
+	if topics := hub.LatestOnlyTopics(client, "robot-1"); len(topics) != 0 {
+		t.Errorf("Expected latest-only to be cleared, got %v", topics)
+	}
+	if got := len(client.Send); got != 1 {
+		t.Errorf("Expected odom to be queued after re-subscribing, got %d", got)
+	}
+}
+
+// TestLatestOnly_AuthOptIn は auth の latest_only_topics で自動購読したロボットに latest-only が設定されることをテストする
+func TestLatestOnly_AuthOptIn(t *testing.T) {
+	// Arrange
+	logger := zap.NewNop()
+	registry := setupMockRegistry(logger)
+	if _, err := registry.CreateAdapter("robot-1", "mock"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	hub := server.NewHub(logger)
+	h := server.NewHandler(hub, registry, nil, nil, nil, nil, nil, nil, logger)
+	auth := func(topics any) (*protocol.Message, *server.Client) {
+		msg := protocol.NewMessage(protocol.MsgTypeAuth, "robot-1")
+		msg.Payload["token"] = "token"
+		msg.Payload["latest_only_topics"] = topics
+		client := &server.Client{ID: "client-1", Send: make(chan []byte, 8), Subscriptions: map[string]bool{}}
+		return sendAndDecode(t, h, client, msg), client
+	}
+
+	// Act
+	resp, client := auth([]any{"odom"})
+
+	// Assert
+	if resp.Type != protocol.MsgTypeConnectionStatus {
+		t.Fatalf("Expected conn_status, got %s (%s)", resp.Type, resp.Error)
+	}
+	if topics := hub.LatestOnlyTopics(client, "robot-1"); len(topics) != 1 || topics[0] != "odom" {
+		t.Errorf("Expected odom to be latest-only, got %v", topics)
+	}
+
+	// 文字列の配列でなければ拒否する
+	if resp, _ := auth("odom"); resp.Payload["code"] != protocol.ErrCodeInvalidMessage {
+		t.Errorf("Expected invalid_message for a non-array value, got %s %v", resp.Type, resp.Payload)
+	}
+}
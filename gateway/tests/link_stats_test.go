@@ -0,0 +1,128 @@
+// =============================================================================
+// ファイル: link_stats_test.go
+// 概要: 回線品質（GetLinkStats / link_stats メッセージ）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/adapter/mock"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"go.uber.org/zap"
+)
+
+// noLinkStatsAdapter: 回線品質に対応していないロボットを模したアダプター
+type noLinkStatsAdapter struct {
+	*mock.MockAdapter
+}
+
+func (a *noLinkStatsAdapter) GetLinkStats(context.Context) (adapter.LinkStats, error) {
+	return adapter.LinkStats{}, adapter.ErrNotSupported
+}
+
+// connectMockWithLink: link_* の設定で接続したモックアダプターを返す
+func connectMockWithLink(t *testing.T, config map[string]any) *mock.MockAdapter {
+	t.Helper()
+	adp := mock.NewMockAdapter(zap.NewNop())
+	config["enabled_topics"] = "battery"
+	if err := adp.Connect(context.Background(), config); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { _ = adp.Disconnect(context.Background()) })
+	return adp
+}
+
+// TestMockLinkStats_EstimatesFromConfig はモックが設定どおりの推定値を返すことをテストする
+func TestMockLinkStats_EstimatesFromConfig(t *testing.T) {
+	// Arrange
+	before := time.Now()
+	adp := connectMockWithLink(t, map[string]any{"link_rtt_ms": 20})
+
+	// Act
+	stats, err := adp.GetLinkStats(context.Background())
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.RoundTripMs != 20 || stats.PacketLoss != 0 || !stats.Estimated {
+		t.Errorf("Expected an estimated 20ms RTT without loss, got %+v", stats)
+	}
+	if stats.LastContact.Before(before) {
+		t.Errorf("Expected last contact to be set on connect, got %v", stats.LastContact)
+	}
+}
+
+// TestMockLinkStats_InjectedLossRaisesLatency は注入したパケットロスで遅延も増えることをテストする
+func TestMockLinkStats_InjectedLossRaisesLatency(t *testing.T) {
+	// Arrange
+	adp := connectMockWithLink(t, map[string]any{"link_rtt_ms": 20, "link_loss_rate": 0.1})
+
+	// Act
+	stats, err := adp.GetLinkStats(context.Background())
+
+	// Assert: ロス 10% で基準の 2 倍
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.PacketLoss != 0.1 || stats.RoundTripMs != 40 {
+		t.Errorf("Expected 10%% loss and a 40ms RTT, got %+v", stats)
+	}
+
+	// 範囲外のロス率は接続時に拒否する
+	bad := mock.NewMockAdapter(zap.NewNop())
+	if err := bad.Connect(context.Background(), map[string]any{"link_loss_rate": 1.5}); err == nil {
+		t.Error("Expected an error for link_loss_rate above 1")
+	}
+}
+
+// requestLinkStats: 接続済みの robot-1 に link_stats を送り、応答を返す
+func requestLinkStats(t *testing.T, registry *adapter.Registry, adapterType string) *protocol.Message {
+	t.Helper()
+	env := newTestHandler(t, withRegistry(registry, adapterType),
+		withConnectConfig(map[string]any{"enabled_topics": "battery", "link_loss_rate": 0.05}))
+	resp := sendAndDecode(t, env.h, env.client, protocol.NewMessage(protocol.MsgTypeLinkStats, "robot-1"))
+	if resp.Type != protocol.MsgTypeLinkStats {
+		t.Fatalf("Expected link_stats, got %s (%s)", resp.Type, resp.Error)
+	}
+	return resp
+}
+
+// TestLinkStats_ReturnsMockStats は link_stats がモックの推定値を返すことをテストする
+func TestLinkStats_ReturnsMockStats(t *testing.T) {
+	// Act
+	resp := requestLinkStats(t, setupMockRegistry(zap.NewNop()), "mock")
+
+	// Assert
+	if supported, _ := resp.Payload["supported"].(bool); !supported {
+		t.Fatalf("Expected supported=true, got %v", resp.Payload)
+	}
+	if fmt.Sprint(resp.Payload["packet_loss"]) != "0.05" || resp.Payload["estimated"] != true {
+		t.Errorf("Expected estimated 5%% loss, got %v", resp.Payload)
+	}
+	if resp.Payload["rtt_ms"] == nil || resp.Payload["last_contact_ms"] == nil || resp.Payload["contact_age_ms"] == nil {
+		t.Errorf("Expected rtt and last contact fields, got %v", resp.Payload)
+	}
+}
+
+// TestLinkStats_NotSupported は非対応のアダプターでは supported=false が返ることをテストする
+func TestLinkStats_NotSupported(t *testing.T) {
+	// Arrange
+	registry := adapter.NewRegistry(zap.NewNop())
+	registry.RegisterFactory("no_link", func(logger *zap.Logger) adapter.RobotAdapter {
+		return &noLinkStatsAdapter{MockAdapter: mock.NewMockAdapter(logger)}
+	})
+
+	// Act
+	resp := requestLinkStats(t, registry, "no_link")
+
+	// Assert
+	if supported, ok := resp.Payload["supported"].(bool); !ok || supported {
+		t.Errorf("Expected supported=false, got %v", resp.Payload)
+	}
+}
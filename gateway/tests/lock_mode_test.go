@@ -0,0 +1,55 @@
+// =============================================================================
+// ファイル: lock_mode_test.go
+// 概要: ロックを持たない操作コマンドの扱い（SetLockMode の auto / strict）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"testing"
+
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// TestLockMode_StrictRequiresExplicitLock は strict モードでロックを持たない速度コマンドが lock_required で拒否されることをテストする
+func TestLockMode_StrictRequiresExplicitLock(t *testing.T) {
+	// Arrange
+	h, client := setupPolicyHandler(t, setupMockRegistry(zap.NewNop()), "mock")
+	h.SetLockMode(server.LockModeStrict)
+	velocity := func() *protocol.Message {
+		msg := protocol.NewMessage(protocol.MsgTypeVelocityCommand, "robot-1")
+		msg.Payload["linear_x"] = 0.2
+		return sendAndDecode(t, h, client, msg)
+	}
+
+	// Act & Assert: ロックを取得する前は拒否される
+	resp := velocity()
+	if resp.Type != protocol.MsgTypeError || resp.Payload["code"] != protocol.ErrCodeLockRequired {
+		t.Fatalf("Expected a lock_required error, got %s %v", resp.Type, resp.Payload)
+	}
+
+	// op_lock で取得した後は受け付けられる
+	if resp := sendAndDecode(t, h, client, protocol.NewMessage(protocol.MsgTypeOperationLock, "robot-1")); resp.Type != protocol.MsgTypeLockStatus {
+		t.Fatalf("Expected lock_status, got %s (%s)", resp.Type, resp.Error)
+	}
+	if resp := velocity(); resp.Type != protocol.MsgTypeCommandAck {
+		t.Errorf("Expected cmd_ack after acquiring the lock, got %s (%s)", resp.Type, resp.Error)
+	}
+}
+
+// TestLockMode_AutoAcquiresLock は auto モード（デフォルト）では速度コマンドでロックが取得されることをテストする
+func TestLockMode_AutoAcquiresLock(t *testing.T) {
+	// Arrange
+	h, client := setupPolicyHandler(t, setupMockRegistry(zap.NewNop()), "mock")
+	msg := protocol.NewMessage(protocol.MsgTypeVelocityCommand, "robot-1")
+	msg.Payload["linear_x"] = 0.2
+
+	// Act
+	resp := sendAndDecode(t, h, client, msg)
+
+	// Assert
+	if resp.Type != protocol.MsgTypeCommandAck {
+		t.Errorf("Expected cmd_ack in auto mode, got %s (%s)", resp.Type, resp.Error)
+	}
+}
@@ -0,0 +1,85 @@
+// =============================================================================
+// ファイル: log_stream_test.go
+// 概要: 管理者へのログ配信（log_stream）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// TestLogStream_DeliversFilteredLogsToAdmins は管理者だけが購読でき、条件に合うログだけが届くことをテストする
+func TestLogStream_DeliversFilteredLogsToAdmins(t *testing.T) {
+	// Arrange: admin だけが管理者
+	hub := server.NewHub(zap.NewNop())
+	go hub.Run()
+	newClient := func(id, userID string) *server.Client {
+		c := &server.Client{ID: id, UserID: userID, Send: make(chan []byte, 8), Subscriptions: map[string]bool{}, Authenticated: true}
+		hub.Register(c)
+		return c
+	}
+	admin, operator := newClient("client-1", "admin"), newClient("client-2", "operator")
+	for i := 0; hub.ClientCount() < 2 && i < 100; i++ { // Run() が登録を反映するまで待つ
+		time.Sleep(time.Millisecond)
+	}
+	h := server.NewHandler(hub, setupMockRegistry(zap.NewNop()), nil, nil, nil, nil, nil, nil, zap.NewNop())
+	h.SetAdminUsers([]string{"admin"})
+
+	stream := server.NewLogStream(16)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream.Start(ctx, hub, nil)
+	logger := zap.New(stream.WrapCore(zapcore.NewCore(
+		zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(io.Discard), zapcore.DebugLevel)))
+
+	subscribe := protocol.NewMessage(protocol.MsgTypeLogStream, "robot-1")
+	subscribe.Payload["level"] = "warn"
+
+	// Act & Assert: 管理者でなければ forbidden
+	resp := sendAndDecode(t, h, operator, subscribe)
+	if resp.Type != protocol.MsgTypeError || resp.Payload["code"] != protocol.ErrCodeForbidden {
+		t.Fatalf("Expected a forbidden error for a non-admin, got %v", resp.Payload)
+	}
+	resp = sendAndDecode(t, h, admin, subscribe)
+	if resp.Type != protocol.MsgTypeCommandAck || resp.Payload["level"] != "warn" {
+		t.Fatalf("Expected cmd_ack for log_stream, got %v", resp.Payload)
+	}
+
+	// Act: レベルかロボットが合わないログの後に、合うログを1件出す
+	logger.Info("info is below the filter", zap.String("robot_id", "robot-1"))
+	logger.Warn("other robot", zap.String("robot_id", "robot-2"))
+	logger.Warn("motor overheating", zap.String("robot_id", "robot-1"), zap.Float64("temp_c", 81.5))
+
+	// Assert: 合うログだけが log_entry として届く
+	select {
+	case data := <-admin.Send:
+		entry, err := protocol.NewCodec().Decode(data)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if entry.Type != protocol.MsgTypeLogEntry || entry.RobotID != "robot-1" {
+			t.Fatalf("Expected a log_entry for robot-1, got %s/%s", entry.Type, entry.RobotID)
+		}
+		if entry.Payload["message"] != "motor overheating" || entry.Payload["level"] != "warn" {
+			t.Errorf("Expected the robot-1 warning, got %v", entry.Payload)
+		}
+		fields, _ := entry.Payload["fields"].(map[string]any)
+		if fields["temp_c"] != 81.5 {
+			t.Errorf("Expected the log fields to be included, got %v", entry.Payload["fields"])
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the matching log to be delivered")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if len(admin.Send) != 0 || len(operator.Send) != 0 {
+		t.Errorf("Expected no other log entries, got %d (admin) and %d (operator)", len(admin.Send), len(operator.Send))
+	}
+}
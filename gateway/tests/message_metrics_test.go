@@ -0,0 +1,106 @@
+// =============================================================================
+// ファイル: message_metrics_test.go
+// 概要: メッセージタイプ別の処理時間・エラー数メトリクスのテストコード
+// =============================================================================
+package tests
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/robot-ai-webapp/gateway/internal/metrics"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// TestMessageMetrics_RecordsDurationAndErrors はタイプ別に処理回数とエラー数が記録されることをテストする
+func TestMessageMetrics_RecordsDurationAndErrors(t *testing.T) {
+	// Arrange: メトリクス付きのハンドラーと未認証クライアント
+	hub := server.NewHub(zap.NewNop())
+	h := server.NewHandler(hub, nil, nil, nil, nil, nil, nil, nil, zap.NewNop())
+	m := metrics.NewMessageMetrics()
+	h.SetMessageMetrics(m)
+	client := &server.Client{ID: "client-1", Send: make(chan []byte, 4)}
+
+	// Act: ping は成功、未認証の health_status はエラーになる
+	h.HandleMessage(client, protocol.NewMessage(protocol.MsgTypePing, ""))
+	h.HandleMessage(client, protocol.NewMessage(protocol.MsgTypeHealthStatus, ""))
+
+	rec := httptest.NewRecorder()
+	m.Handler(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	out := string(body)
+
+	// Assert
+	for _, want := range []string{
+		`gateway_message_duration_seconds_count{type="ping"} 1`,
+		`gateway_message_duration_seconds_count{type="health_status"} 1`,
+		`gateway_message_errors_total{type="ping"} 0`,
+		`gateway_message_errors_total{type="health_status"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected metrics output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+// TestMessageMetrics_UnknownTypesShareOneLabel はプロトコルに定義のないタイプが
+// クライアントの送った文字列のままではなく "unknown" にまとめられることをテストする
+func TestMessageMetrics_UnknownTypesShareOneLabel(t *testing.T) {
+	// Arrange
+	hub := server.NewHub(zap.NewNop())
+	h := server.NewHandler(hub, nil, nil, nil, nil, nil, nil, nil, zap.NewNop())
+	m := metrics.NewMessageMetrics()
+	h.SetMessageMetrics(m)
+	client := &server.Client{ID: "client-1", Send: make(chan []byte, 4)}
+
+	// Act: 制御文字や非 ASCII を含む、でたらめなタイプを送る
+	h.HandleMessage(client, protocol.NewMessage(protocol.MessageType("bad\x00é"), ""))
+	h.HandleMessage(client, protocol.NewMessage(protocol.MessageType("junk-2"), ""))
+	h.HandleMessage(client, protocol.NewMessage(protocol.MsgTypePing, ""))
+
+	rec := httptest.NewRecorder()
+	m.Handler(rec, httptest.NewRequest("GET", "/metrics", nil))
+	out := rec.Body.String()
+
+	// Assert
+	for _, want := range []string{
+		`gateway_message_duration_seconds_count{type="unknown"} 2`,
+		`gateway_message_duration_seconds_count{type="ping"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected metrics output to contain %q, got:\n%s", want, out)
+		}
+	}
+	for _, bad := range []string{"junk-2", `\x00`, `é`} {
+		if strings.Contains(out, bad) {
+			t.Errorf("Expected no %q in metrics output, got:\n%s", bad, out)
+		}
+	}
+}
+
+// TestMessageMetrics_EscapesLabelValues はラベル値が exposition format の3つのエスケープだけで書き出されることをテストする
+func TestMessageMetrics_EscapesLabelValues(t *testing.T) {
+	// Arrange
+	m := metrics.NewMessageMetrics()
+
+	// Act
+	m.Observe("a\"b\\c\nd", 0, false)
+	m.Observe("é", 0, false)
+	rec := httptest.NewRecorder()
+	m.Handler(rec, httptest.NewRequest("GET", "/metrics", nil))
+	out := rec.Body.String()
+
+	// Assert: 非 ASCII は UTF-8 のまま書き出す
+	for _, want := range []string{
+		`gateway_message_errors_total{type="a\"b\\c\nd"} 0`,
+		`gateway_message_errors_total{type="é"} 0`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected metrics output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+// =============================================================================
+// ファイル: mock_topics_test.go
+// 概要: モックアダプターの enabled_topics（生成するセンサーの選択）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter/mock"
+	"go.uber.org/zap"
+)
+
+// TestMockEnabledTopics_ReflectedInCapabilities は選んだトピックだけが能力情報に載ることをテストする
+func TestMockEnabledTopics_ReflectedInCapabilities(t *testing.T) {
+	// Arrange
+	m := mock.NewMockAdapter(zap.NewNop())
+	ctx := context.Background()
+
+	// Act: odom と battery だけを有効にして接続
+	if err := m.Connect(ctx, map[string]any{"enabled_topics": "battery, odom"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer m.Disconnect(ctx)
+
+	// Assert
+	got := m.GetCapabilities().SensorTopics
+	if !slices.Equal(got, []string{"odom", "battery"}) {
+		t.Errorf("Expected [odom battery], got %v", got)
+	}
+}
+
+// TestMockEnabledTopics_RejectsUnknown は未知のトピック名で接続が失敗することをテストする
+func TestMockEnabledTopics_RejectsUnknown(t *testing.T) {
+	m := mock.NewMockAdapter(zap.NewNop())
+	err := m.Connect(context.Background(), map[string]any{"enabled_topics": []string{"odom", "camera"}})
+	if err == nil {
+		t.Error("Expected error for unknown topic, got nil")
+	}
+}
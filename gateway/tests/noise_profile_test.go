@@ -0,0 +1,73 @@
+// =============================================================================
+// ファイル: noise_profile_test.go
+// 概要: モックアダプターのノイズプロファイル読み込み・検証のテストコード
+// =============================================================================
+//
+// 【t.TempDir() について】
+// テストごとに一時ディレクトリを作り、テスト終了後に自動で削除してくれます。
+// プロファイルファイルをテストの中で書き出すために使います。
+// =============================================================================
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter/mock"
+)
+
+// writeProfileFile はテスト用のプロファイルファイルを一時ディレクトリに書き出す
+func writeProfileFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write profile file: %v", err)
+	}
+	return path
+}
+
+// TestLoadNoiseProfiles_JSONAndYAML は両形式から同じ値が読めることをテストする
+func TestLoadNoiseProfiles_JSONAndYAML(t *testing.T) {
+	// Arrange
+	jsonPath := writeProfileFile(t, "profiles.json",
+		`{"profiles": {"default": {"seed": 42, "lidar": {"std_dev": 0.05, "dropout_rate": 0.1}}}}`)
+	yamlPath := writeProfileFile(t, "profiles.yaml", `
+profiles:
+  default:
+    seed: 42
+    lidar:
+      std_dev: 0.05
+      dropout_rate: 0.1
+`)
+
+	for _, path := range []string{jsonPath, yamlPath} {
+		// Act
+		profiles, err := mock.LoadNoiseProfiles(path)
+
+		// Assert
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", path, err)
+		}
+		p := profiles["default"]
+		if p.Seed != 42 || p.LiDAR.StdDev != 0.05 || p.LiDAR.DropoutRate != 0.1 {
+			t.Errorf("%s: unexpected profile: %+v", path, p)
+		}
+	}
+}
+
+// TestLoadNoiseProfiles_RejectsInvalid は不正な値が読み込み時に拒否されることをテストする
+func TestLoadNoiseProfiles_RejectsInvalid(t *testing.T) {
+	cases := map[string]string{
+		"negative_std_dev": `{"profiles": {"bad": {"imu": {"std_dev": -1}}}}`,
+		"dropout_over_one": `{"profiles": {"bad": {"odometry": {"dropout_rate": 1.5}}}}`,
+		"no_profiles":      `{"profiles": {}}`,
+	}
+
+	for name, content := range cases {
+		path := writeProfileFile(t, name+".json", content)
+		if _, err := mock.LoadNoiseProfiles(path); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// =============================================================================
+// ファイル: odometry_drift_test.go
+// 概要: モックアダプターのオドメトリドリフト（累積誤差）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/adapter/mock"
+	"go.uber.org/zap"
+)
+
+// TestOdometryDrift_EstimateRunsAhead は距離の系統誤差で推定位置が真の位置より先に進むことをテストする
+func TestOdometryDrift_EstimateRunsAhead(t *testing.T) {
+	// Arrange: 10% 多く進んだと推定するモック（odom だけ生成）
+	m := mock.NewMockAdapter(zap.NewNop())
+	ctx := context.Background()
+	err := m.Connect(ctx, map[string]any{
+		"enabled_topics":       "odom",
+		"odom_drift_per_meter": 0.1,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer m.Disconnect(ctx)
+
+	// Act: 前進させてしばらく待つ
+	_ = m.SendCommand(ctx, adapter.Command{Type: "velocity", Payload: map[string]any{"linear_x": 0.5}})
+	time.Sleep(300 * time.Millisecond)
+
+	// Assert: 最新のオドメトリで推定位置 > 真の位置
+	var last adapter.SensorData
+	for len(m.SensorDataChannel()) > 0 {
+		last = <-m.SensorDataChannel()
+	}
+	est, _ := last.Data["position_x"].(float64)
+	truth, ok := last.Data["true_position_x"].(float64)
+	if !ok {
+		t.Fatalf("Expected true_position_x in odometry, got %v", last.Data)
+	}
+	if truth <= 0 || est <= truth {
+		t.Errorf("Expected estimate ahead of truth, got estimate=%v truth=%v", est, truth)
+	}
+}
+
+// TestOdometryDrift_DisabledByDefault はドリフト未設定なら真の位置を追加しないことをテストする
+func TestOdometryDrift_DisabledByDefault(t *testing.T) {
+	m := mock.NewMockAdapter(zap.NewNop())
+	ctx := context.Background()
+	if err := m.Connect(ctx, map[string]any{"enabled_topics": "odom"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer m.Disconnect(ctx)
+
+	data := <-m.SensorDataChannel()
+	if _, ok := data.Data["true_position_x"]; ok {
+		t.Errorf("Expected no true_position_x without drift, got %v", data.Data)
+	}
+}
+
+// TestOdometryDrift_DockingHeadingOverwriteIsNotDrift はドックへの向き合わせで向きが -π 付近から π 付近へ
+// 書き換わっても、ほぼ 2π の回転としてドリフトを加えないことをテストする
+func TestOdometryDrift_DockingHeadingOverwriteIsNotDrift(t *testing.T) {
+	// Arrange: 回転を 50% 多く推定するモックを、ドックの東 0.3m・ほぼ西向き（-π 寄り）に置く
+	m := mock.NewMockAdapter(zap.NewNop())
+	ctx := context.Background()
+	err := m.Connect(ctx, map[string]any{
+		"enabled_topics":        "odom,battery",
+		"odom_drift_per_radian": 0.5,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer m.Disconnect(ctx)
+	_ = m.SendCommand(ctx, adapter.Command{Type: "reset_pose", Payload: map[string]any{"x": 0.3, "theta": -3.1}})
+
+	// Act: ドックに着くまで待つ
+	_ = m.SendCommand(ctx, adapter.Command{Type: "dock", Payload: map[string]any{}})
+	waitFor(t, func() bool {
+		battery, _ := m.ReadSensor(ctx, "battery")
+		return battery.Data["charging"] == true
+	})
+
+	// Assert: 推定の向きと位置が真の姿勢とずれていない（向き合わせの分は誤差にしない）
+	odom, err := m.ReadSensor(ctx, "odom")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	est, _ := odom.Data["orientation_z"].(float64)
+	truth, _ := odom.Data["true_orientation_z"].(float64)
+	if d := math.Abs(math.Remainder(est-truth, 2*math.Pi)); d > 0.01 {
+		t.Errorf("Expected the estimated heading to match the truth, got estimate=%v truth=%v", est, truth)
+	}
+	if x, _ := odom.Data["position_x"].(float64); math.Abs(x) > 0.06 {
+		t.Errorf("Expected the estimated position to reach the dock, got x=%v", x)
+	}
+}
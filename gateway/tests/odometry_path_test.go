@@ -0,0 +1,79 @@
+// =============================================================================
+// ファイル: odometry_path_test.go
+// 概要: odometry_path（記録済みオドメトリからの経路の問い合わせ）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/bridge"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// fakePathPublisher: 固定の経路を返す RedisPublisher のテスト用実装
+type fakePathPublisher struct {
+	gotMaxPoints int
+}
+
+func (f *fakePathPublisher) PublishSensorData(context.Context, string, adapter.SensorData) error {
+	return nil
+}
+
+func (f *fakePathPublisher) PublishCommand(context.Context, string, adapter.Command) error {
+	return nil
+}
+
+func (f *fakePathPublisher) OdometryPath(_ context.Context, _ string, _, _ time.Time, maxPoints int) ([]bridge.PathPose, error) {
+	f.gotMaxPoints = maxPoints
+	return []bridge.PathPose{{Timestamp: 1, X: 0, Y: 0}, {Timestamp: 2, X: 1, Y: 0.5}}, nil
+}
+
+// TestOdometryPath_ReturnsPoses は記録済みの経路が姿勢の列で返ることをテストする
+func TestOdometryPath_ReturnsPoses(t *testing.T) {
+	// Arrange
+	publisher := &fakePathPublisher{}
+	h := server.NewHandler(server.NewHub(zap.NewNop()), nil, nil, nil, nil, nil, nil, publisher, zap.NewNop())
+	client := &server.Client{ID: "client-1", Send: make(chan []byte, 1), Authenticated: true}
+	msg := protocol.NewMessage(protocol.MsgTypeOdometryPath, "robot-1")
+	msg.Payload["max_points"] = 100000
+
+	// Act
+	h.HandleMessage(client, msg)
+
+	// Assert: 点数の上限は 2000 に丸められ、2点がそのまま返る
+	resp, err := protocol.NewCodec().Decode(<-client.Send)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Type != protocol.MsgTypeOdometryPath {
+		t.Fatalf("Expected odometry_path, got %s (%s)", resp.Type, resp.Error)
+	}
+	if poses, _ := resp.Payload["poses"].([]any); len(poses) != 2 {
+		t.Errorf("Expected 2 poses, got %v", resp.Payload["poses"])
+	}
+	if publisher.gotMaxPoints != 2000 {
+		t.Errorf("Expected max_points clamped to 2000, got %d", publisher.gotMaxPoints)
+	}
+}
+
+// TestOdometryPath_UnavailableWithoutRedis は Redis なしではエラーになることをテストする
+func TestOdometryPath_UnavailableWithoutRedis(t *testing.T) {
+	h := server.NewHandler(server.NewHub(zap.NewNop()), nil, nil, nil, nil, nil, nil, nil, zap.NewNop())
+	client := &server.Client{ID: "client-1", Send: make(chan []byte, 1), Authenticated: true}
+
+	h.HandleMessage(client, protocol.NewMessage(protocol.MsgTypeOdometryPath, "robot-1"))
+
+	resp, err := protocol.NewCodec().Decode(<-client.Send)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Type != protocol.MsgTypeError {
+		t.Errorf("Expected error response, got %s", resp.Type)
+	}
+}
@@ -0,0 +1,135 @@
+// =============================================================================
+// ファイル: operation_lock_store_test.go
+// 概要: 操作ロックの永続化（SetStore / Restore）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/clock"
+	"github.com/robot-ai-webapp/gateway/internal/safety"
+	"go.uber.org/zap"
+)
+
+// memoryLockStore: メモリ上の保存先（Redis の代わり）
+type memoryLockStore struct {
+	mu    sync.Mutex
+	locks map[string]safety.PersistedLock
+}
+
+func newMemoryLockStore() *memoryLockStore {
+	return &memoryLockStore{locks: make(map[string]safety.PersistedLock)}
+}
+
+func (s *memoryLockStore) SaveLock(ctx context.Context, lock safety.PersistedLock, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.locks[lock.RobotID] = lock
+	return nil
+}
+
+func (s *memoryLockStore) DeleteLock(ctx context.Context, robotID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.locks, robotID)
+	return nil
+}
+
+func (s *memoryLockStore) LoadLocks(ctx context.Context) ([]safety.PersistedLock, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var locks []safety.PersistedLock
+	for _, lock := range s.locks {
+		locks = append(locks, lock)
+	}
+	return locks, nil
+}
+
+func (s *memoryLockStore) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.locks)
+}
+
+// TestOperationLock_SurvivesRestart は保存したロックが再起動後に期限内なら復元されることをテストする
+func TestOperationLock_SurvivesRestart(t *testing.T) {
+	// Arrange: タイムアウト5分。user-1 が robot-1 と robot-2 をロックし、robot-2 は解放する
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := newMemoryLockStore()
+	before := safety.NewOperationLock(5*time.Minute, zap.NewNop())
+	before.SetClock(clock.NewMock(start))
+	before.SetStore(store)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	before.StartCleanup(done, &wg)
+
+	if _, err := before.Acquire("robot-1", "user-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := before.Acquire("robot-2", "user-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := before.Release("robot-2", "user-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	close(done)
+	wg.Wait() // 停止時にキューに残った書き込みも反映される
+	if store.count() != 1 {
+		t.Fatalf("Expected only robot-1 to be persisted, got %d locks", store.count())
+	}
+
+	// Act: 4分後に再起動する
+	clk := clock.NewMock(start.Add(4 * time.Minute))
+	after := safety.NewOperationLock(5*time.Minute, zap.NewNop())
+	after.SetClock(clk)
+	after.SetStore(store)
+	restored, err := after.Restore(context.Background())
+
+	// Assert: 残り1分のロックとして復元され、他のユーザーは取得できない
+	if err != nil || restored != 1 {
+		t.Fatalf("Expected 1 restored lock, got %d (err=%v)", restored, err)
+	}
+	if !after.CheckLock("robot-1", "user-1") {
+		t.Error("Expected user-1 to still hold robot-1 after restart")
+	}
+	if _, err := after.Acquire("robot-1", "user-2"); err == nil {
+		t.Error("Expected user-2 to be refused while the restored lock is active")
+	}
+	if info := after.GetLockInfo("robot-1"); info == nil || !info.ExpiresAt.Equal(start.Add(5*time.Minute)) {
+		t.Errorf("Expected the restored lock to expire at %v, got %+v", start.Add(5*time.Minute), info)
+	}
+}
+
+// TestOperationLock_RestoreRecomputesExpiry は期限切れのロックを復元せず、未来の時刻を現在時刻として扱うことをテストする
+func TestOperationLock_RestoreRecomputesExpiry(t *testing.T) {
+	// Arrange: robot-1 は6分前に延長されたきり（期限切れ）、robot-2 は時計の進んだホストが書き込んだ
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := newMemoryLockStore()
+	store.locks["robot-1"] = safety.PersistedLock{
+		RobotID: "robot-1", UserID: "user-1", AcquiredAt: now.Add(-10 * time.Minute), RenewedAt: now.Add(-6 * time.Minute),
+	}
+	store.locks["robot-2"] = safety.PersistedLock{
+		RobotID: "robot-2", UserID: "user-2", AcquiredAt: now.Add(time.Hour), RenewedAt: now.Add(time.Hour),
+	}
+	lock := safety.NewOperationLock(5*time.Minute, zap.NewNop())
+	lock.SetClock(clock.NewMock(now))
+	lock.SetStore(store)
+
+	// Act
+	restored, err := lock.Restore(context.Background())
+
+	// Assert: robot-2 だけが「今からタイムアウト1回分」のロックとして復元される
+	if err != nil || restored != 1 {
+		t.Fatalf("Expected 1 restored lock, got %d (err=%v)", restored, err)
+	}
+	if lock.GetLockInfo("robot-1") != nil {
+		t.Error("Expected the expired robot-1 lock not to be restored")
+	}
+	if info := lock.GetLockInfo("robot-2"); info == nil || !info.ExpiresAt.Equal(now.Add(5*time.Minute)) {
+		t.Errorf("Expected robot-2 to expire 5 minutes from now, got %+v", info)
+	}
+}
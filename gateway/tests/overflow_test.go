@@ -0,0 +1,188 @@
+// =============================================================================
+// ファイル: overflow_test.go
+// 概要: 送信キューが満杯の時の、メッセージの種類ごとの方針（OverflowPolicies）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/config"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// fullClient: Hub に登録し、Send バッファを埋めたクライアントを返す
+func fullClient(t *testing.T, hub *server.Hub) *server.Client {
+	t.Helper()
+	client := &server.Client{ID: "client-1", Send: make(chan []byte, 1), Subscriptions: make(map[string]bool)}
+	hub.Register(client)
+	waitFor(t, func() bool { return hub.ClientCount() == 1 })
+	hub.SendToClient(client, []byte("first"))
+	return client
+}
+
+// waitFor: cond が true になるまで待つ（2秒でタイムアウト）
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the condition")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestOverflow_DefaultDropsNewestAndCounts は方針を設定しなければ以前どおり新しいメッセージを捨て、数えることをテストする
+func TestOverflow_DefaultDropsNewestAndCounts(t *testing.T) {
+	// Arrange
+	hub := server.NewHub(zap.NewNop())
+	go hub.Run()
+	client := fullClient(t, hub)
+
+	// Act
+	hub.SendToClient(client, []byte("second"))
+
+	// Assert: 先に入っていたものが残り、クライアントは接続したまま
+	if got := string(<-client.Send); got != "first" {
+		t.Errorf("Expected the queued message to be kept, got %q", got)
+	}
+	if got := hub.DroppedMessages(server.ClassCritical); got != 1 {
+		t.Errorf("Expected 1 dropped critical message, got %d", got)
+	}
+	if hub.ClientCount() != 1 {
+		t.Errorf("Expected the client to stay connected")
+	}
+}
+
+// TestOverflow_CriticalDisconnectsInsteadOfDropping は critical=disconnect で溢れたクライアントを切断することをテストする
+func TestOverflow_CriticalDisconnectsInsteadOfDropping(t *testing.T) {
+	// Arrange
+	hub := server.NewHub(zap.NewNop())
+	hub.SetOverflowPolicies(server.OverflowPolicies{Critical: server.OverflowDisconnect})
+	go hub.Run()
+	client := fullClient(t, hub)
+
+	// Act
+	hub.SendToClient(client, []byte("estop_activated"))
+
+	// Assert: 黙って捨てずに切断する
+	waitFor(t, func() bool { return hub.ClientCount() == 0 })
+}
+
+// TestOverflow_CriticalBlocksBriefly は critical=block で、待っている間に空けばメッセージが届くことをテストする
+func TestOverflow_CriticalBlocksBriefly(t *testing.T) {
+	// Arrange
+	hub := server.NewHub(zap.NewNop())
+	hub.SetOverflowPolicies(server.OverflowPolicies{Critical: server.OverflowBlock, BlockTimeout: time.Second})
+	go hub.Run()
+	client := fullClient(t, hub)
+
+	// Act: 送信を待っている間にクライアントが1件読む
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		<-client.Send
+	}()
+	hub.SendToClient(client, []byte("ack"))
+
+	// Assert
+	if got := string(<-client.Send); got != "ack" {
+		t.Errorf("Expected the blocked message to be delivered, got %q", got)
+	}
+	if hub.ClientCount() != 1 || hub.DroppedMessages(server.ClassCritical) != 0 {
+		t.Errorf("Expected no disconnect and no drop, got %d clients, %d dropped",
+			hub.ClientCount(), hub.DroppedMessages(server.ClassCritical))
+	}
+}
+
+// TestOverflow_CriticalBlockTimesOutAndDisconnects は critical=block で空かなければ切断することをテストする
+func TestOverflow_CriticalBlockTimesOutAndDisconnects(t *testing.T) {
+	// Arrange
+	hub := server.NewHub(zap.NewNop())
+	hub.SetOverflowPolicies(server.OverflowPolicies{Critical: server.OverflowBlock, BlockTimeout: 10 * time.Millisecond})
+	go hub.Run()
+	client := fullClient(t, hub)
+
+	// Act
+	hub.SendToClient(client, []byte("ack"))
+
+	// Assert
+	waitFor(t, func() bool { return hub.ClientCount() == 0 })
+}
+
+// TestOverflow_SensorDropOldestNeverEvictsAlerts はセンサー専用のキューがないクライアントでは、
+// drop_oldest でも Send に溜まったアラートを捨てないことをテストする
+func TestOverflow_SensorDropOldestNeverEvictsAlerts(t *testing.T) {
+	// Arrange
+	hub := server.NewHub(zap.NewNop())
+	hub.SetOverflowPolicies(server.OverflowPolicies{Sensor: server.OverflowDropOldest})
+	go hub.Run()
+	client := fullClient(t, hub)
+	hub.SubscribeClient(client, "robot-1")
+
+	// Act
+	hub.BroadcastSensorSample("robot-1", "odom", []byte("sample"))
+
+	// Assert
+	if got := string(<-client.Send); got != "first" {
+		t.Errorf("Expected the alert to be kept, got %q", got)
+	}
+	if got := hub.DroppedMessages(server.ClassSensor); got != 1 {
+		t.Errorf("Expected the sensor sample to be dropped, got %d dropped", got)
+	}
+}
+
+// TestConfig_OverflowPolicy は GATEWAY_OVERFLOW_POLICY の解析と検証をテストする
+func TestConfig_OverflowPolicy(t *testing.T) {
+	// Arrange & Act
+	t.Setenv("GATEWAY_OVERFLOW_POLICY", "sensor=disconnect, critical=drop_newest")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Assert
+	if cfg.Server.OverflowPolicies["sensor"] != "disconnect" || cfg.Server.OverflowPolicies["critical"] != "drop_newest" {
+		t.Errorf("Expected the configured policies, got %v", cfg.Server.OverflowPolicies)
+	}
+	if _, ok := cfg.Server.OverflowPolicies["bulk"]; ok {
+		t.Errorf("Expected bulk to be unset, got %v", cfg.Server.OverflowPolicies)
+	}
+
+	// 書式の誤りは Load で、種類に合わない方針は Validate で拒否する
+	for _, bad := range []string{"sensor", "video=drop_newest", "sensor=drop_oldest,sensor=disconnect"} {
+		t.Setenv("GATEWAY_OVERFLOW_POLICY", bad)
+		if _, err := config.Load(); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+	t.Setenv("GATEWAY_OVERFLOW_POLICY", "critical=drop_oldest")
+	cfg, err = config.Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "GATEWAY_OVERFLOW_POLICY") {
+		t.Errorf("Expected critical=drop_oldest to be rejected, got %v", err)
+	}
+}
+
+// TestConfig_OverflowPolicyDefault はデフォルトで critical が block ではなく disconnect になることをテストする
+// （block は Hub のロックを持ったまま待つため、1台の遅いクライアントで全体が遅れる）
+func TestConfig_OverflowPolicyDefault(t *testing.T) {
+	// Arrange & Act
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Assert
+	want := map[string]string{"sensor": "drop_oldest", "critical": "disconnect", "bulk": "drop_newest"}
+	for class, policy := range want {
+		if cfg.Server.OverflowPolicies[class] != policy {
+			t.Errorf("Expected %s=%s by default, got %v", class, policy, cfg.Server.OverflowPolicies)
+		}
+	}
+}
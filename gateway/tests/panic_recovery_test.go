@@ -0,0 +1,117 @@
+// =============================================================================
+// ファイル: panic_recovery_test.go
+// 概要: ハンドラーと Hub の panic からの回復（internal/recovery）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/recovery"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// msgTypePanic: テスト用の、処理すると panic するメッセージタイプ
+const msgTypePanic protocol.MessageType = "test_panic"
+
+// TestPanicRecovery_HandlerPanicClosesOnlyThatConnection はハンドラーの panic でその接続だけが閉じられることをテストする
+func TestPanicRecovery_HandlerPanicClosesOnlyThatConnection(t *testing.T) {
+	// Arrange: 1値の型アサーションで panic するハンドラーを登録する
+	logger := zap.NewNop()
+	hub := server.NewHub(logger)
+	go hub.Run()
+	h := server.NewHandler(hub, nil, nil, nil, nil, nil, nil, nil, logger)
+	if err := h.RegisterHandler(msgTypePanic, func(client *server.Client, msg *protocol.Message) {
+		_ = msg.Payload["value"].(float64)
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ws := server.NewWebSocketServer(hub, h, 0, 0, logger)
+	srv := httptest.NewServer(http.HandlerFunc(ws.HandleWebSocket))
+	t.Cleanup(srv.Close)
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	victim, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { victim.Close() })
+	bystander, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { bystander.Close() })
+	before := recovery.Count()
+
+	// Act
+	data, err := protocol.NewCodec().Encode(protocol.NewMessage(msgTypePanic, ""))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := victim.WriteMessage(websocket.BinaryMessage, data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Assert: panic した接続は 1011 で閉じられる
+	_ = victim.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err = victim.ReadMessage()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != websocket.CloseInternalServerErr {
+		t.Fatalf("Expected an internal-error closure, got %v", err)
+	}
+	if recovery.Count() <= before {
+		t.Error("Expected the panic to be counted")
+	}
+
+	// Assert: 他の接続は動き続ける（ping に pong が返る）
+	ping, err := protocol.NewCodec().Encode(protocol.NewMessage(protocol.MsgTypePing, ""))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := bystander.WriteMessage(websocket.BinaryMessage, ping); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = bystander.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, reply, err := bystander.ReadMessage()
+	if err != nil {
+		t.Fatalf("Expected the other connection to stay open, got %v", err)
+	}
+	if msg, err := protocol.NewCodec().Decode(reply); err != nil || msg.Type != protocol.MsgTypePong {
+		t.Errorf("Expected pong, got %v (%v)", msg, err)
+	}
+}
+
+// TestPanicRecovery_HubSurvivesCallbackPanic は登録解除のコールバックが panic しても Hub が動き続けることをテストする
+func TestPanicRecovery_HubSurvivesCallbackPanic(t *testing.T) {
+	// Arrange
+	hub := server.NewHub(zap.NewNop())
+	hub.SetUnregisterCallback(func(*server.Client) { panic("callback failed") })
+	go hub.Run()
+	first := &server.Client{ID: "client-1", Send: make(chan []byte, 8), Subscriptions: map[string]bool{}}
+	hub.Register(first)
+
+	// Act
+	hub.Unregister(first)
+	second := &server.Client{ID: "client-2", Send: make(chan []byte, 8), Subscriptions: map[string]bool{}}
+	hub.Register(second)
+
+	// Assert: 2つ目の登録が処理される
+	for i := 0; hub.ClientCount() != 1 && i < 100; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if hub.ClientCount() != 1 {
+		t.Fatalf("Expected the hub to keep serving registrations, got %d clients", hub.ClientCount())
+	}
+	hub.BroadcastToAll([]byte("hello"))
+	if got := string(<-second.Send); got != "hello" {
+		t.Errorf("Expected the broadcast to reach the new client, got %q", got)
+	}
+}
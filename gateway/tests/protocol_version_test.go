@@ -0,0 +1,108 @@
+// =============================================================================
+// ファイル: protocol_version_test.go
+// 概要: プロトコルのバージョンの取り決め（NegotiateVersion / auth の protocol_version）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// TestNegotiateVersion はバージョンの取り決めのルールをテストする
+func TestNegotiateVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		requested   int
+		wantVersion int
+		wantWarning bool
+		wantErr     bool
+	}{
+		{"current", protocol.ProtocolVersion, protocol.ProtocolVersion, false, false},
+		{"not sent", 0, 1, true, false},
+		{"newer client", protocol.ProtocolVersion + 1, protocol.ProtocolVersion, true, false},
+		{"below minimum", -1, 0, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			version, warning, err := protocol.NegotiateVersion(tt.requested)
+
+			// Assert
+			var unsupported *protocol.UnsupportedVersionError
+			if tt.wantErr != errors.As(err, &unsupported) {
+				t.Fatalf("Expected error=%v, got %v", tt.wantErr, err)
+			}
+			if version != tt.wantVersion || (warning != "") != tt.wantWarning {
+				t.Errorf("Expected version %d (warning=%v), got %d %q", tt.wantVersion, tt.wantWarning, version, warning)
+			}
+		})
+	}
+}
+
+// authWithVersion: protocol_version を付けて auth を送り、応答とクライアントを返す（v が nil なら付けない）
+func authWithVersion(t *testing.T, v any) (*protocol.Message, *server.Client) {
+	t.Helper()
+	logger := zap.NewNop()
+	h := server.NewHandler(server.NewHub(logger), setupMockRegistry(logger), nil, nil, nil, nil, nil, nil, logger)
+	client := &server.Client{ID: "client-1", Send: make(chan []byte, 4)}
+	msg := protocol.NewMessage(protocol.MsgTypeAuth, "")
+	msg.Payload["token"] = "token"
+	if v != nil {
+		msg.Payload["protocol_version"] = v
+	}
+	return sendAndDecode(t, h, client, msg), client
+}
+
+// TestAuth_NegotiatesProtocolVersion は conn_status に取り決めたバージョンと対応範囲が返ることをテストする
+func TestAuth_NegotiatesProtocolVersion(t *testing.T) {
+	// Act
+	resp, client := authWithVersion(t, protocol.ProtocolVersion)
+
+	// Assert
+	if resp.Type != protocol.MsgTypeConnectionStatus {
+		t.Fatalf("Expected conn_status, got %s (%s)", resp.Type, resp.Error)
+	}
+	if client.ProtocolVersion != protocol.ProtocolVersion {
+		t.Errorf("Expected the client to store version %d, got %d", protocol.ProtocolVersion, client.ProtocolVersion)
+	}
+	if fmt.Sprint(resp.Payload["protocol_version"]) != fmt.Sprint(protocol.ProtocolVersion) ||
+		fmt.Sprint(resp.Payload["min_protocol_version"]) != fmt.Sprint(protocol.MinProtocolVersion) ||
+		fmt.Sprint(resp.Payload["max_protocol_version"]) != fmt.Sprint(protocol.ProtocolVersion) {
+		t.Errorf("Expected the negotiated version and the supported range, got %v", resp.Payload)
+	}
+	if _, ok := resp.Payload["protocol_warning"]; ok {
+		t.Errorf("Expected no warning for the current version, got %v", resp.Payload["protocol_warning"])
+	}
+
+	// 送らなかったクライアントは警告付きで受け付ける
+	if resp, _ := authWithVersion(t, nil); resp.Type != protocol.MsgTypeConnectionStatus || resp.Payload["protocol_warning"] == nil {
+		t.Errorf("Expected conn_status with a warning when protocol_version is omitted, got %s %v", resp.Type, resp.Payload)
+	}
+}
+
+// TestAuth_RejectsUnsupportedProtocolVersion は範囲外・不正なバージョンでは認証されないことをテストする
+func TestAuth_RejectsUnsupportedProtocolVersion(t *testing.T) {
+	// Act
+	resp, client := authWithVersion(t, 0)
+
+	// Assert: 0 は不正な値（送らない場合は省略する）
+	if resp.Payload["code"] != protocol.ErrCodeInvalidMessage || client.Authenticated {
+		t.Errorf("Expected invalid_message and no authentication, got %s %v", resp.Type, resp.Payload)
+	}
+	if resp, _ := authWithVersion(t, "v1"); resp.Payload["code"] != protocol.ErrCodeInvalidMessage {
+		t.Errorf("Expected invalid_message for a non-numeric version, got %s %v", resp.Type, resp.Payload)
+	}
+
+	// 範囲外のエラーは対応範囲を示す
+	err := &protocol.UnsupportedVersionError{Requested: 99}
+	if want := fmt.Sprintf("supported: %d-%d", protocol.MinProtocolVersion, protocol.ProtocolVersion); !strings.Contains(err.Error(), want) {
+		t.Errorf("Expected the error to name the supported range %q, got %q", want, err.Error())
+	}
+}
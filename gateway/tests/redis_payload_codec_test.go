@@ -0,0 +1,61 @@
+// =============================================================================
+// ファイル: redis_payload_codec_test.go
+// 概要: Redis レコードの payload のエンコード方式（json / msgpack / auto）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"testing"
+
+	"github.com/robot-ai-webapp/gateway/internal/bridge"
+)
+
+// TestPayloadCodec_AutoUsesMsgpackForNumericArrays は auto で数値の配列だけ MessagePack になり、精度を保って復元できることをテストする
+func TestPayloadCodec_AutoUsesMsgpackForNumericArrays(t *testing.T) {
+	// Arrange
+	scan := map[string]any{"range_max": 12.0, "ranges": []float64{3.0512345678901234, 0.1}}
+	battery := map[string]any{"percentage": 80.0}
+
+	// Act
+	scanCodec, scanPayload, err := bridge.EncodePayload(bridge.PayloadCodecAuto, scan)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	batteryCodec, _, err := bridge.EncodePayload(bridge.PayloadCodecAuto, battery)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Assert: LiDAR は msgpack、バッテリーは json
+	if scanCodec != "msgpack" || batteryCodec != "json" {
+		t.Fatalf("Expected msgpack for the scan and json for the battery, got %s and %s", scanCodec, batteryCodec)
+	}
+	var decoded struct {
+		Ranges []float64 `json:"ranges"`
+	}
+	record := map[string]any{"codec": scanCodec, "payload": string(scanPayload)}
+	if err := bridge.DecodePayload(record, &decoded); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(decoded.Ranges) != 2 || decoded.Ranges[0] != 3.0512345678901234 {
+		t.Errorf("Expected the ranges to be restored exactly, got %v", decoded.Ranges)
+	}
+}
+
+// TestPayloadCodec_DecodesLegacyRecordsAsJSON は codec フィールドのない古いレコードを JSON として読むことをテストする
+func TestPayloadCodec_DecodesLegacyRecordsAsJSON(t *testing.T) {
+	// Arrange
+	record := map[string]any{"payload": `{"linear_x":0.5}`}
+
+	// Act
+	var payload map[string]any
+	err := bridge.DecodePayload(record, &payload)
+
+	// Assert
+	if err != nil || payload["linear_x"] != 0.5 {
+		t.Errorf("Expected the legacy JSON payload to be decoded, got %v (%v)", payload, err)
+	}
+	if err := bridge.DecodePayload(map[string]any{"codec": "xml", "payload": "<a/>"}, &payload); err == nil {
+		t.Error("Expected an error for an unknown codec")
+	}
+}
@@ -0,0 +1,98 @@
+// =============================================================================
+// ファイル: redis_reconnect_test.go
+// 概要: Redis の復旧（Reconnector と、実行中の配信先の差し替え）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/bridge"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// recordingPersister: 受け取ったサンプルを記録する永続化先
+type recordingPersister struct {
+	got chan adapter.SensorData
+}
+
+func (p *recordingPersister) PublishSensorData(_ context.Context, _ string, data adapter.SensorData) error {
+	p.got <- data
+	return nil
+}
+
+// TestRedisReconnect_HandlerUsesPublisherSetAtRuntime は後から設定した配信先をハンドラーが使うことをテストする
+func TestRedisReconnect_HandlerUsesPublisherSetAtRuntime(t *testing.T) {
+	// Arrange: Redis なしで起動したハンドラー
+	h := server.NewHandler(server.NewHub(zap.NewNop()), nil, nil, nil, nil, nil, nil, nil, zap.NewNop())
+	client := &server.Client{ID: "client-1", Send: make(chan []byte, 2), Authenticated: true}
+	before := sendAndDecode(t, h, client, protocol.NewMessage(protocol.MsgTypeOdometryPath, "robot-1"))
+
+	// Act: Redis が復旧した
+	h.SetPublisher(&fakePathPublisher{})
+	after := sendAndDecode(t, h, client, protocol.NewMessage(protocol.MsgTypeOdometryPath, "robot-1"))
+
+	// Assert
+	if before.Type != protocol.MsgTypeError {
+		t.Errorf("Expected an error without Redis, got %s", before.Type)
+	}
+	if after.Type != protocol.MsgTypeOdometryPath {
+		t.Errorf("Expected odometry_path after Redis returned, got %s (%s)", after.Type, after.Error)
+	}
+}
+
+// TestRedisReconnect_FanoutPersistsAfterPersisterIsSet は Start 後に設定した永続化先へ書き込むことをテストする
+func TestRedisReconnect_FanoutPersistsAfterPersisterIsSet(t *testing.T) {
+	// Arrange: 永続化先なしで起動した配信ワーカー
+	logger := zap.NewNop()
+	fanout := server.NewSensorFanout(server.NewHub(logger), protocol.NewCodec(), 1, 4, logger)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fanout.Start(ctx, nil)
+
+	// Act: Redis が復旧してから届いたサンプル
+	persister := &recordingPersister{got: make(chan adapter.SensorData, 1)}
+	fanout.SetPersister(persister)
+	fanout.Submit(ctx, adapter.SensorData{RobotID: "robot-1", Topic: "battery", Data: map[string]any{"percentage": 80.0}})
+
+	// Assert
+	select {
+	case data := <-persister.got:
+		if data.Topic != "battery" {
+			t.Errorf("Expected the battery sample, got %s", data.Topic)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the sample to be persisted after the persister was set")
+	}
+}
+
+// TestRedisReconnect_KeepsRetryingUntilStopped は接続できない間は試み続け、ctx で止まることをテストする
+func TestRedisReconnect_KeepsRetryingUntilStopped(t *testing.T) {
+	// Arrange: 誰も待ち受けていないポート
+	r := bridge.NewReconnector("redis://127.0.0.1:1/0", 10*time.Millisecond, zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	connected := false
+
+	// Act
+	r.Start(ctx, &wg, func(*bridge.RedisPublisher) { connected = true })
+	for i := 0; r.Attempts() < 3 && i < 200; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	wg.Wait()
+
+	// Assert
+	if r.Attempts() < 3 {
+		t.Errorf("Expected repeated attempts, got %d", r.Attempts())
+	}
+	if connected || r.Publisher() != nil {
+		t.Error("Expected no publisher while Redis is unreachable")
+	}
+}
@@ -0,0 +1,137 @@
+// =============================================================================
+// ファイル: reset_error_test.go
+// 概要: 故障のリセット（reset_error / ClearFault）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/adapter/mock"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"go.uber.org/zap"
+)
+
+// rechargeBattery: reset_pose の reset_battery でモックのバッテリーを満充電に戻す
+func rechargeBattery(t *testing.T, adp adapter.RobotAdapter) {
+	t.Helper()
+	cmd := adapter.Command{RobotID: "robot-1", Type: "reset_pose", Payload: map[string]any{"reset_battery": true}}
+	if err := adp.SendCommand(context.Background(), cmd); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// resetError: reset_error を送り、応答を返す
+func resetError(t *testing.T, env *testEnv) *protocol.Message {
+	t.Helper()
+	return sendAndDecode(t, env.h, env.client, protocol.NewMessage(protocol.MsgTypeResetError, "robot-1"))
+}
+
+// TestMockClearFault_RefusesWhileBatteryDepleted はバッテリー切れのまま故障を解除できず、
+// 充電後に解除すると速度コマンドを再び受け付けることをテストする
+func TestMockClearFault_RefusesWhileBatteryDepleted(t *testing.T) {
+	// Arrange
+	adp := mock.NewMockAdapter(zap.NewNop())
+	if err := adp.Connect(context.Background(), map[string]any{"enabled_topics": "battery"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { _ = adp.Disconnect(context.Background()) })
+	velocity := adapter.Command{RobotID: "robot-1", Type: "velocity", Payload: map[string]any{"linear_x": 0.1}}
+
+	// Act
+	adp.DrainBattery()
+	faulted := adp.SendCommand(context.Background(), velocity)
+	refused := adp.ClearFault(context.Background())
+	rechargeBattery(t, adp)
+	cleared := adp.ClearFault(context.Background())
+
+	// Assert
+	if !errors.Is(faulted, adapter.ErrFaultActive) {
+		t.Errorf("Expected velocity to be refused while faulted, got %v", faulted)
+	}
+	if !errors.Is(refused, adapter.ErrFaultActive) {
+		t.Errorf("Expected ClearFault to be refused while the battery is empty, got %v", refused)
+	}
+	if cleared != nil {
+		t.Fatalf("Expected ClearFault to succeed after recharging, got %v", cleared)
+	}
+	if err := adp.SendCommand(context.Background(), velocity); err != nil {
+		t.Errorf("Expected velocity to be accepted after the reset, got %v", err)
+	}
+}
+
+// TestResetError_LockHolderClearsFaultAfterCauseIsGone はロックを持つ操作者のリセットが、
+// 原因が残る間は拒否され、原因が取り除かれた後は受け付けられることをテストする
+func TestResetError_LockHolderClearsFaultAfterCauseIsGone(t *testing.T) {
+	// Arrange: user-1 がロックを持つ、バッテリー切れのロボット
+	env := newTestHandler(t)
+	if _, err := env.opLock.Acquire("robot-1", "user-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	adp := env.adp.(*mock.MockAdapter)
+	adp.DrainBattery()
+
+	// Act
+	refused := resetError(t, env)
+	rechargeBattery(t, adp)
+	accepted := resetError(t, env)
+
+	// Assert
+	if refused.Type != protocol.MsgTypeError {
+		t.Errorf("Expected the reset to be refused while the fault is active, got %s %v", refused.Type, refused.Payload)
+	}
+	if accepted.Type != protocol.MsgTypeCommandAck || accepted.Payload["command"] != "reset_error" {
+		t.Errorf("Expected reset_error to be acknowledged, got %s %v", accepted.Type, accepted.Payload)
+	}
+	if err := adp.ClearFault(context.Background()); err != nil {
+		t.Errorf("Expected the fault to be cleared, got %v", err)
+	}
+}
+
+// TestResetError_RequiresLockOrAdmin はロックを持たない一般ユーザーのリセットを拒否し、管理者なら受け付けることをテストする
+func TestResetError_RequiresLockOrAdmin(t *testing.T) {
+	// Arrange: 他のユーザーがロックを持つロボット
+	env := newTestHandler(t)
+	if _, err := env.opLock.Acquire("robot-1", "user-2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Act
+	denied := resetError(t, env)
+	env.h.SetAdminUsers([]string{"user-1"})
+	allowed := resetError(t, env)
+
+	// Assert
+	if denied.Type != protocol.MsgTypeError || denied.Payload["code"] != protocol.ErrCodeLockRequired {
+		t.Errorf("Expected lock_required, got %s %v", denied.Type, denied.Payload)
+	}
+	if allowed.Type != protocol.MsgTypeCommandAck {
+		t.Errorf("Expected the admin's reset to be acknowledged, got %s %v", allowed.Type, allowed.Payload)
+	}
+}
+
+// TestResetError_HonorsCommandPolicy は無効にしたロボットとホワイトリストにない場合に reset_error を拒否することをテストする
+func TestResetError_HonorsCommandPolicy(t *testing.T) {
+	// Arrange
+	env := newTestHandler(t, withAdmin())
+
+	// Act: ホワイトリストに reset_error がない
+	env.h.SetAllowedCommands(map[string][]string{"robot-1": {"velocity"}})
+	notAllowed := resetError(t, env)
+	env.h.SetAllowedCommands(nil)
+	if _, err := env.registry.SetEnabled("robot-1", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	disabled := resetError(t, env)
+
+	// Assert
+	if notAllowed.Payload["code"] != protocol.ErrCodeCommandNotAllowed {
+		t.Errorf("Expected command_not_allowed, got %s %v", notAllowed.Type, notAllowed.Payload)
+	}
+	if disabled.Payload["code"] != protocol.ErrCodeRobotDisabled {
+		t.Errorf("Expected robot_disabled, got %s %v", disabled.Type, disabled.Payload)
+	}
+}
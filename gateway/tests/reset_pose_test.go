@@ -0,0 +1,98 @@
+// =============================================================================
+// ファイル: reset_pose_test.go
+// 概要: 姿勢リセット（reset_pose）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/convert"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+)
+
+// resetPose: reset_pose を送り、応答を返す
+func resetPose(t *testing.T, env *testEnv, payload map[string]any) *protocol.Message {
+	t.Helper()
+	msg := protocol.NewMessage(protocol.MsgTypeResetPose, "robot-1")
+	for k, v := range payload {
+		msg.Payload[k] = v
+	}
+	return sendAndDecode(t, env.h, env.client, msg)
+}
+
+// TestResetPose_RequiresAdmin は管理者でないユーザーの reset_pose を forbidden で拒否することをテストする
+func TestResetPose_RequiresAdmin(t *testing.T) {
+	// Arrange: 操作ロックなし（auto モード）のロボットと、管理者でないユーザー
+	env := newTestHandler(t)
+
+	// Act
+	resp := resetPose(t, env, map[string]any{"x": 5.0})
+
+	// Assert: 拒否され、ロックも取得しない
+	if resp.Type != protocol.MsgTypeError || resp.Payload["code"] != protocol.ErrCodeForbidden {
+		t.Errorf("Expected forbidden, got %s %v", resp.Type, resp.Payload)
+	}
+	if lock := env.opLock.GetLockInfo("robot-1"); lock != nil {
+		t.Errorf("Expected no lock to be taken, got %+v", lock)
+	}
+}
+
+// TestResetPose_RejectsInvalidCoordinates は数値でない座標を原点として扱わずに拒否することをテストする
+func TestResetPose_RejectsInvalidCoordinates(t *testing.T) {
+	// Arrange
+	env := newTestHandler(t, withAdmin())
+
+	for name, payload := range map[string]map[string]any{
+		"string": {"x": "abc"},
+		"NaN":    {"y": math.NaN()},
+		"Inf":    {"theta": math.Inf(1)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Act
+			resp := resetPose(t, env, payload)
+
+			// Assert
+			if resp.Type != protocol.MsgTypeError || resp.Payload["code"] != protocol.ErrCodeInvalidMessage {
+				t.Errorf("Expected invalid_message, got %s %v", resp.Type, resp.Payload)
+			}
+		})
+	}
+}
+
+// TestResetPose_EmitsOdometryAtNewPose は管理者の reset_pose で新しい姿勢のオドメトリがすぐに届き、
+// 省略したキーは 0 になることをテストする
+func TestResetPose_EmitsOdometryAtNewPose(t *testing.T) {
+	// Arrange: オドメトリを送るロボット
+	env := newTestHandler(t, withAdmin(), withConnectConfig(map[string]any{"enabled_topics": "odom"}))
+	for len(env.adp.SensorDataChannel()) > 0 { // それまでの定期送信を捨てる
+		<-env.adp.SensorDataChannel()
+	}
+
+	// Act: y は省略する
+	resp := resetPose(t, env, map[string]any{"x": 2.0, "theta": 0.5})
+
+	// Assert
+	if resp.Type != protocol.MsgTypeCommandAck || resp.Payload["command"] != "reset_pose" {
+		t.Fatalf("Expected reset_pose to be acknowledged, got %s %v", resp.Type, resp.Payload)
+	}
+	deadline := time.After(time.Second)
+	for {
+		select {
+		case data := <-env.adp.SensorDataChannel():
+			if data.Topic != "odom" {
+				continue
+			}
+			x, _ := convert.ToFloat64(data.Data["position_x"])
+			y, _ := convert.ToFloat64(data.Data["position_y"])
+			theta, _ := convert.ToFloat64(data.Data["orientation_z"])
+			if x == 2.0 && y == 0 && theta == 0.5 {
+				return
+			}
+		case <-deadline:
+			t.Fatal("Expected an odometry sample at the reset pose")
+		}
+	}
+}
@@ -0,0 +1,54 @@
+// =============================================================================
+// ファイル: robot_disconnected_test.go
+// 概要: 切断中のロボットへのコマンドが robot_disconnected で拒否されることのテストコード
+// =============================================================================
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/safety"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// TestVelocityCommand_RejectedWhenDisconnected は未接続のアダプターへの速度コマンドが拒否されることをテストする
+func TestVelocityCommand_RejectedWhenDisconnected(t *testing.T) {
+	// Arrange: 登録済みだが Connect していないモックロボット
+	logger := zap.NewNop()
+	registry := setupMockRegistry(logger)
+	if _, err := registry.CreateAdapter("robot-1", "mock"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	h := server.NewHandler(
+		server.NewHub(logger), registry,
+		safety.NewEStopManager(registry, logger),
+		safety.NewVelocityLimiter(1.0, 1.0, logger),
+		nil,
+		safety.NewOperationLock(time.Minute, logger),
+		nil, nil, logger,
+	)
+	client := &server.Client{
+		ID:            "client-1",
+		UserID:        "user-1",
+		Send:          make(chan []byte, 4),
+		Subscriptions: map[string]bool{},
+		Authenticated: true,
+	}
+	msg := protocol.NewMessage(protocol.MsgTypeVelocityCommand, "robot-1")
+	msg.Payload["linear_x"] = 0.5
+
+	// Act
+	h.HandleMessage(client, msg)
+
+	// Assert
+	resp, err := protocol.NewCodec().Decode(<-client.Send)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Type != protocol.MsgTypeError || resp.Payload["code"] != protocol.ErrCodeRobotDisconnected {
+		t.Errorf("Expected robot_disconnected error, got %s %v", resp.Type, resp.Payload)
+	}
+}
@@ -0,0 +1,137 @@
+// =============================================================================
+// ファイル: robot_enabled_test.go
+// 概要: ロボットの一時的な無効化（set_robot_enabled）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// setRobotEnabled: set_robot_enabled を送り、応答を返す
+func setRobotEnabled(t *testing.T, h *server.Handler, client *server.Client, enabled bool) *protocol.Message {
+	t.Helper()
+	msg := protocol.NewMessage(protocol.MsgTypeSetRobotEnabled, "robot-1")
+	msg.Payload["enabled"] = enabled
+	return sendAndDecode(t, h, client, msg)
+}
+
+// TestRobotEnabled_DisabledRobotRejectsCommands は無効にしたロボットへのコマンドが robot_disabled になり、戻せば通ることをテストする
+func TestRobotEnabled_DisabledRobotRejectsCommands(t *testing.T) {
+	// Arrange
+	registry := setupMockRegistry(zap.NewNop())
+	h, client := setupPolicyHandler(t, registry, "mock")
+	h.SetAdminUsers([]string{"user-1"})
+	velocity := func() *protocol.Message {
+		msg := protocol.NewMessage(protocol.MsgTypeVelocityCommand, "robot-1")
+		msg.Payload["linear_x"] = 0.2
+		return sendAndDecode(t, h, client, msg)
+	}
+
+	// Act
+	disabled := setRobotEnabled(t, h, client, false)
+	rejected := velocity()
+	setRobotEnabled(t, h, client, true)
+	accepted := velocity()
+
+	// Assert
+	if disabled.Type != protocol.MsgTypeCommandAck || disabled.Payload["enabled"] != false {
+		t.Fatalf("Expected an ack with enabled=false, got %s %v", disabled.Type, disabled.Payload)
+	}
+	if rejected.Type != protocol.MsgTypeError || rejected.Payload["code"] != protocol.ErrCodeRobotDisabled {
+		t.Errorf("Expected robot_disabled, got %s %v", rejected.Type, rejected.Payload)
+	}
+	if accepted.Type != protocol.MsgTypeCommandAck {
+		t.Errorf("Expected the command to be accepted after re-enabling, got %s (%s)", accepted.Type, accepted.Error)
+	}
+	if _, ok := registry.GetAdapter("robot-1"); !ok {
+		t.Error("Expected the adapter to stay registered while disabled")
+	}
+}
+
+// TestRobotEnabled_RequiresAdmin は管理者以外は無効にできないことをテストする
+func TestRobotEnabled_RequiresAdmin(t *testing.T) {
+	// Arrange
+	registry := setupMockRegistry(zap.NewNop())
+	h, client := setupPolicyHandler(t, registry, "mock")
+
+	// Act
+	resp := setRobotEnabled(t, h, client, false)
+
+	// Assert
+	if resp.Payload["code"] != protocol.ErrCodeForbidden {
+		t.Errorf("Expected forbidden, got %s %v", resp.Type, resp.Payload)
+	}
+	if !registry.IsEnabled("robot-1") {
+		t.Error("Expected the robot to stay enabled")
+	}
+}
+
+// TestRobotEnabled_RemovingAdapterClearsState はアダプターを削除すると無効の状態も消えることをテストする
+func TestRobotEnabled_RemovingAdapterClearsState(t *testing.T) {
+	// Arrange
+	registry := setupMockRegistry(zap.NewNop())
+	if _, err := registry.CreateAdapter("robot-1", "mock"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := registry.SetEnabled("robot-1", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Act
+	registry.RemoveAdapter("robot-1")
+
+	// Assert
+	if !registry.IsEnabled("robot-1") {
+		t.Error("Expected a removed robot to start enabled again")
+	}
+	if _, err := registry.SetEnabled("robot-1", false); err != adapter.ErrAdapterNotFound {
+		t.Errorf("Expected ErrAdapterNotFound for an unknown robot, got %v", err)
+	}
+}
+
+// TestRobotEnabled_FanoutPausesDisabledRobot は無効にしたロボットのセンサーデータが配信されないことをテストする
+func TestRobotEnabled_FanoutPausesDisabledRobot(t *testing.T) {
+	// Arrange
+	logger := zap.NewNop()
+	hub := server.NewHub(logger)
+	go hub.Run()
+	client := &server.Client{ID: "client-1", Send: make(chan []byte, 4), Subscriptions: map[string]bool{}, Authenticated: true}
+	hub.Register(client)
+	for i := 0; hub.ClientCount() < 1 && i < 100; i++ { // Run() が登録を反映するまで待つ
+		time.Sleep(time.Millisecond)
+	}
+	hub.SubscribeClient(client, "robot-1")
+	hub.SubscribeClient(client, "robot-2")
+
+	fanout := server.NewSensorFanout(hub, protocol.NewCodec(), 1, 4, logger)
+	fanout.SetRobotEnabled(func(robotID string) bool { return robotID != "robot-1" })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fanout.Start(ctx, nil)
+
+	// Act: 無効な robot-1、有効な robot-2 の順に渡す（同じワーカーが順に処理する）
+	fanout.Submit(ctx, adapter.SensorData{RobotID: "robot-1", Topic: "battery", Data: map[string]any{}})
+	fanout.Submit(ctx, adapter.SensorData{RobotID: "robot-2", Topic: "battery", Data: map[string]any{}})
+
+	// Assert: 届くのは robot-2 のデータだけ
+	select {
+	case data := <-client.Send:
+		msg, err := protocol.NewCodec().Decode(data)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if msg.RobotID != "robot-2" {
+			t.Errorf("Expected only robot-2 data, got %s", msg.RobotID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected robot-2 data to be delivered")
+	}
+}
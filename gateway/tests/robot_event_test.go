@@ -0,0 +1,176 @@
+// =============================================================================
+// ファイル: robot_event_test.go
+// 概要: ロボットからのイベント（EventChannel / robot_event / 物理 E-Stop のラッチ）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/adapter/mock"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/safety"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// fakeEventPublisher: 受け取ったイベントを記録する RedisPublisher のテスト用実装
+type fakeEventPublisher struct {
+	events []adapter.RobotEvent
+}
+
+func (f *fakeEventPublisher) PublishSensorData(context.Context, string, adapter.SensorData) error {
+	return nil
+}
+
+func (f *fakeEventPublisher) PublishCommand(context.Context, string, adapter.Command) error {
+	return nil
+}
+
+func (f *fakeEventPublisher) PublishRobotEvent(_ context.Context, _ string, ev adapter.RobotEvent) error {
+	f.events = append(f.events, ev)
+	return nil
+}
+
+// setupEventHandler: robot-1 を購読したクライアントと、E-Stop 付きのハンドラーを用意する
+func setupEventHandler(t *testing.T) (*server.Handler, *server.Client, *safety.EStopManager) {
+	t.Helper()
+	env := newTestHandler(t, withRunningHub())
+	registerClients(t, env.hub, env.client)
+	env.hub.SubscribeClient(env.client, "robot-1")
+	return env.h, env.client, env.estop
+}
+
+// receive: client に届いたメッセージを順に n 件読む
+func receive(t *testing.T, client *server.Client, n int) []*protocol.Message {
+	t.Helper()
+	var msgs []*protocol.Message
+	for len(msgs) < n {
+		select {
+		case data := <-client.Send:
+			msg, err := protocol.NewCodec().Decode(data)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			msgs = append(msgs, msg)
+		case <-time.After(time.Second):
+			t.Fatalf("Expected %d messages, got %d", n, len(msgs))
+		}
+	}
+	return msgs
+}
+
+// TestMockEmitEvent_DeliversOnEventChannel はモックの合成イベントが既定値付きで EventChannel に届くことをテストする
+func TestMockEmitEvent_DeliversOnEventChannel(t *testing.T) {
+	// Arrange
+	adp := mock.NewMockAdapter(zap.NewNop())
+
+	// Act
+	err := adp.EmitEvent(adapter.RobotEvent{Type: adapter.EventBumperHit, Data: map[string]any{"side": "front"}})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	select {
+	case ev := <-adp.EventChannel():
+		if ev.Severity != adapter.EventSeverityWarning || ev.Timestamp == 0 || ev.Data["side"] != "front" {
+			t.Errorf("Expected a warning with a timestamp and the data, got %+v", ev)
+		}
+	default:
+		t.Fatal("Expected the event on the event channel")
+	}
+	if len(adp.SensorDataChannel()) != 0 {
+		t.Error("Expected events not to be mixed into sensor data")
+	}
+}
+
+// TestRobotEvent_ForwardsToSubscribersAndPublishes はイベントが購読者に robot_event で届き、Redis に記録されることをテストする
+func TestRobotEvent_ForwardsToSubscribersAndPublishes(t *testing.T) {
+	// Arrange
+	h, client, estop := setupEventHandler(t)
+	publisher := &fakeEventPublisher{}
+	h.SetPublisher(publisher)
+
+	// Act
+	h.NotifyRobotEvent(context.Background(), adapter.RobotEvent{
+		RobotID: "robot-1", Type: adapter.EventPayloadDetected, Severity: adapter.EventSeverityInfo,
+		Data: map[string]any{"present": true}, Timestamp: 1_700_000_000_000_000_000,
+	})
+
+	// Assert
+	msg := receive(t, client, 1)[0]
+	if msg.Type != protocol.MsgTypeRobotEvent || msg.Payload["event_type"] != adapter.EventPayloadDetected ||
+		fmt.Sprint(msg.Payload["timestamp"]) != "1700000000000" {
+		t.Errorf("Expected a robot_event with a millisecond timestamp, got %s %v", msg.Type, msg.Payload)
+	}
+	if len(publisher.events) != 1 || publisher.events[0].Type != adapter.EventPayloadDetected {
+		t.Errorf("Expected the event to be published, got %+v", publisher.events)
+	}
+	if estop.IsActive("robot-1") {
+		t.Error("Expected no E-Stop for a payload event")
+	}
+}
+
+// TestRobotEvent_PhysicalEStopLatchesSoftwareEStop は物理 E-Stop でソフトウェアの E-Stop がラッチされ、
+// ボタンの解除では解除されないことをテストする
+func TestRobotEvent_PhysicalEStopLatchesSoftwareEStop(t *testing.T) {
+	// Arrange
+	h, client, estop := setupEventHandler(t)
+
+	// Act
+	h.NotifyRobotEvent(context.Background(), adapter.RobotEvent{
+		RobotID: "robot-1", Type: adapter.EventEStopPressed, Severity: adapter.EventSeverityCritical,
+	})
+
+	// Assert: estop_activated のアラートの後に robot_event が届く
+	if !estop.IsActive("robot-1") {
+		t.Fatal("Expected the software E-Stop to be latched")
+	}
+	msgs := receive(t, client, 2)
+	if msgs[0].Payload["type"] != "estop_activated" || msgs[0].Payload["user_id"] != "robot" {
+		t.Errorf("Expected an estop_activated alert by robot, got %v", msgs[0].Payload)
+	}
+	if msgs[1].Type != protocol.MsgTypeRobotEvent || msgs[1].Payload["event_type"] != adapter.EventEStopPressed {
+		t.Errorf("Expected the robot_event, got %s %v", msgs[1].Type, msgs[1].Payload)
+	}
+
+	// ボタンを戻しても、オペレーターが解除するまで E-Stop は続く
+	h.NotifyRobotEvent(context.Background(), adapter.RobotEvent{RobotID: "robot-1", Type: adapter.EventEStopReleased})
+	if !estop.IsActive("robot-1") {
+		t.Error("Expected the software E-Stop to stay latched after the button is released")
+	}
+}
+
+// TestRobotEvent_PhysicalEStopAlertsEvenIfStopFails はアダプターへの停止コマンドが失敗しても、
+// ラッチした E-Stop の estop_activated を失敗の内容付きで配信することをテストする
+func TestRobotEvent_PhysicalEStopAlertsEvenIfStopFails(t *testing.T) {
+	// Arrange: EmergencyStop に常に失敗するロボット
+	logger := zap.NewNop()
+	registry := adapter.NewRegistry(logger)
+	registry.RegisterFactory("failing", func(*zap.Logger) adapter.RobotAdapter {
+		return &failingEStopAdapter{MockAdapter: mock.NewMockAdapter(logger), failures: -1}
+	})
+	env := newTestHandler(t, withRegistry(registry, "failing"), withRunningHub())
+	registerClients(t, env.hub, env.client)
+	env.hub.SubscribeClient(env.client, "robot-1")
+	h, client, estop := env.h, env.client, env.estop
+
+	// Act
+	h.NotifyRobotEvent(context.Background(), adapter.RobotEvent{
+		RobotID: "robot-1", Type: adapter.EventEStopPressed, Severity: adapter.EventSeverityCritical,
+	})
+
+	// Assert
+	if !estop.IsActive("robot-1") {
+		t.Fatal("Expected the software E-Stop to be latched")
+	}
+	alert := receive(t, client, 2)[0]
+	if alert.Payload["type"] != "estop_activated" || alert.Payload["stop_error"] == nil {
+		t.Errorf("Expected an estop_activated alert with the stop error, got %v", alert.Payload)
+	}
+}
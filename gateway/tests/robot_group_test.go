@@ -0,0 +1,154 @@
+// =============================================================================
+// ファイル: robot_group_test.go
+// 概要: ロボットのグループ（SetRobotGroups / GetAdaptersByGroup）と group_command のテストコード
+// =============================================================================
+package tests
+
+import (
+	"testing"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/config"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/safety"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// setupGroupRegistry: site-1/zone-a に robot-1, robot-2、site-1/zone-b に robot-3 を置いたレジストリを作る
+func setupGroupRegistry(t *testing.T) *adapter.Registry {
+	t.Helper()
+	registry := setupMockRegistry(zap.NewNop())
+	registry.SetRobotGroups(map[string][]string{
+		"robot-1": {"site-1/zone-a"},
+		"robot-2": {"site-1/zone-a", "team-red"},
+		"robot-3": {"site-1/zone-b"},
+		"robot-9": {"site-2"}, // 設定だけあり、アダプターは作らない
+	})
+	for _, robotID := range []string{"robot-1", "robot-2", "robot-3"} {
+		connectRobot(t, registry, robotID, "mock", map[string]any{"enabled_topics": "battery"})
+	}
+	return registry
+}
+
+// TestRobotGroups_HierarchicalMembership は上位のグループが下位のグループのロボットを含むことをテストする
+func TestRobotGroups_HierarchicalMembership(t *testing.T) {
+	// Arrange
+	registry := setupGroupRegistry(t)
+
+	// Act & Assert
+	cases := map[string]int{"site-1/zone-a": 2, "site-1": 3, "team-red": 1, "site-2": 0, "site": 0}
+	for group, want := range cases {
+		if got := len(registry.GetAdaptersByGroup(group)); got != want {
+			t.Errorf("Expected %d robots in %q, got %d", want, group, got)
+		}
+	}
+	if !registry.HasGroup("site-2") || !registry.HasGroup("site-1") {
+		t.Error("Expected configured groups and their parents to exist")
+	}
+	if registry.HasGroup("site") {
+		t.Error("Expected a name prefix that is not a parent group not to exist")
+	}
+}
+
+// setupGroupHandler: グループ付きのレジストリでハンドラーを作る
+func setupGroupHandler(t *testing.T) (*server.Handler, *server.Client, *safety.EStopManager, *safety.OperationLock) {
+	t.Helper()
+	env := newTestHandler(t, withRegistry(setupGroupRegistry(t), "mock"))
+	return env.h, env.client, env.estop, env.opLock
+}
+
+// groupCommand: group_command を送り、応答を読む
+func groupCommand(t *testing.T, h *server.Handler, client *server.Client, group, action string) *protocol.Message {
+	t.Helper()
+	msg := protocol.NewMessage(protocol.MsgTypeGroupCommand, "")
+	msg.Payload["group"] = group
+	msg.Payload["action"] = action
+	return sendAndDecode(t, h, client, msg)
+}
+
+// TestGroupCommand_EStopsEveryRobotInGroup はグループの E-Stop が下位のグループを含む全ロボットに効くことをテストする
+func TestGroupCommand_EStopsEveryRobotInGroup(t *testing.T) {
+	// Arrange
+	h, client, estop, _ := setupGroupHandler(t)
+
+	// Act
+	resp := groupCommand(t, h, client, "site-1", "estop")
+
+	// Assert
+	if resp.Type != protocol.MsgTypeCommandAck {
+		t.Fatalf("Expected cmd_ack, got %s (%s)", resp.Type, resp.Error)
+	}
+	results, _ := resp.Payload["results"].([]any)
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 per-robot results, got %v", resp.Payload["results"])
+	}
+	for _, robotID := range []string{"robot-1", "robot-2", "robot-3"} {
+		if !estop.IsActive(robotID) {
+			t.Errorf("Expected E-Stop to be active for %s", robotID)
+		}
+	}
+}
+
+// TestGroupCommand_StopSkipsRobotsLockedByOthers は stop が他のユーザーのロックを持つロボットを止めないことをテストする
+func TestGroupCommand_StopSkipsRobotsLockedByOthers(t *testing.T) {
+	// Arrange
+	h, client, _, opLock := setupGroupHandler(t)
+	if _, err := opLock.Acquire("robot-2", "user-2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Act
+	resp := groupCommand(t, h, client, "site-1/zone-a", "stop")
+
+	// Assert: ID 順に robot-1（成功）、robot-2（失敗）
+	results, _ := resp.Payload["results"].([]any)
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 per-robot results, got %v", resp.Payload["results"])
+	}
+	first, _ := results[0].(map[string]any)
+	second, _ := results[1].(map[string]any)
+	if first["robot_id"] != "robot-1" || first["ok"] != true {
+		t.Errorf("Expected robot-1 to be stopped, got %v", first)
+	}
+	if second["robot_id"] != "robot-2" || second["ok"] != false || second["error"] == nil {
+		t.Errorf("Expected robot-2 to be skipped with an error, got %v", second)
+	}
+}
+
+// TestGroupCommand_RejectsUnknownGroupAndAction は未設定のグループと未対応の操作が拒否されることをテストする
+func TestGroupCommand_RejectsUnknownGroupAndAction(t *testing.T) {
+	// Arrange
+	h, client, _, _ := setupGroupHandler(t)
+
+	// Act & Assert
+	if resp := groupCommand(t, h, client, "site-3", "estop"); resp.Payload["code"] != protocol.ErrCodeUnknownGroup {
+		t.Errorf("Expected unknown_group, got %s %v", resp.Type, resp.Payload)
+	}
+	if resp := groupCommand(t, h, client, "site-1", "dock"); resp.Payload["code"] != protocol.ErrCodeInvalidMessage {
+		t.Errorf("Expected invalid_message for an unsupported action, got %s %v", resp.Type, resp.Payload)
+	}
+}
+
+// TestRobotGroups_ConfigParsing は GATEWAY_ROBOT_GROUPS の解析をテストする
+func TestRobotGroups_ConfigParsing(t *testing.T) {
+	// Arrange
+	t.Setenv("GATEWAY_ROBOT_GROUPS", "robot-1=site-1/zone-a, robot-2=site-1/zone-a|team-red")
+
+	// Act
+	cfg, err := config.Load()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := cfg.Server.RobotGroups["robot-2"]; len(got) != 2 || got[1] != "team-red" {
+		t.Errorf("Expected robot-2 in two groups, got %v", got)
+	}
+
+	// 空の階層を含むグループIDは起動時に拒否する
+	t.Setenv("GATEWAY_ROBOT_GROUPS", "robot-1=site-1//zone-a")
+	if _, err := config.Load(); err == nil {
+		t.Error("Expected an error for an empty group segment")
+	}
+}
@@ -0,0 +1,118 @@
+// =============================================================================
+// ファイル: robot_metrics_test.go
+// 概要: ロボットごとの速度と E-Stop の状態のゲージ（metrics.RobotMetrics）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/metrics"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// scrapeRobotMetrics: RobotMetrics の出力を文字列で返す
+func scrapeRobotMetrics(m *metrics.RobotMetrics) string {
+	var buf bytes.Buffer
+	m.WritePrometheus(&buf)
+	return buf.String()
+}
+
+// TestRobotMetrics_CommandedVelocityFollowsHandler は送った速度と停止がゲージに反映されることをテストする
+func TestRobotMetrics_CommandedVelocityFollowsHandler(t *testing.T) {
+	// Arrange: 上限 1.0 m/s のハンドラー
+	h, client := setupPolicyHandler(t, setupMockRegistry(zap.NewNop()), "mock")
+	robotMetrics := metrics.NewRobotMetrics()
+	h.SetRobotMetrics(robotMetrics)
+	msg := protocol.NewMessage(protocol.MsgTypeVelocityCommand, "robot-1")
+	msg.Payload["linear_x"] = 3.0
+	msg.Payload["angular_z"] = 0.5
+
+	// Act
+	sendAndDecode(t, h, client, msg)
+	moving := scrapeRobotMetrics(robotMetrics)
+	h.ResetVelocityBaseline("robot-1") // ウォッチドッグのタイムアウトと同じ
+	stopped := scrapeRobotMetrics(robotMetrics)
+
+	// Assert: 速度制限の後の値が出て、停止後は 0 になる
+	for _, line := range []string{
+		`gateway_robot_commanded_velocity{robot_id="robot-1",axis="linear_x"} 1`,
+		`gateway_robot_commanded_velocity{robot_id="robot-1",axis="angular_z"} 0.5`,
+	} {
+		if !strings.Contains(moving, line) {
+			t.Errorf("Expected %q in output:\n%s", line, moving)
+		}
+	}
+	if want := `gateway_robot_commanded_velocity{robot_id="robot-1",axis="linear_x"} 0`; !strings.Contains(stopped, want) {
+		t.Errorf("Expected %q after the stop, got:\n%s", want, stopped)
+	}
+}
+
+// TestRobotMetrics_ReportedVelocityFromOdometry はオドメトリの速度がゲージに反映されることをテストする
+func TestRobotMetrics_ReportedVelocityFromOdometry(t *testing.T) {
+	// Arrange
+	logger := zap.NewNop()
+	robotMetrics := metrics.NewRobotMetrics()
+	fanout := server.NewSensorFanout(server.NewHub(logger), protocol.NewCodec(), 1, 1, logger)
+	fanout.SetRobotMetrics(robotMetrics)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fanout.Start(ctx, nil)
+
+	// Act
+	fanout.Submit(ctx, adapter.SensorData{
+		RobotID:  "robot-1",
+		Topic:    "odom",
+		DataType: "odometry",
+		Data:     map[string]any{"velocity_x": 0.25, "velocity_y": 0.0, "angular_z": -0.1},
+	})
+
+	// Assert: ワーカーが処理するまで待つ
+	want := `gateway_robot_reported_velocity{robot_id="robot-1",axis="linear_x"} 0.25`
+	var out string
+	for i := 0; i < 100; i++ {
+		if out = scrapeRobotMetrics(robotMetrics); strings.Contains(out, want) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if !strings.Contains(out, want) || !strings.Contains(out, `axis="angular_z"} -0.1`) {
+		t.Errorf("Expected reported velocities, got:\n%s", out)
+	}
+}
+
+// TestRobotMetrics_EStopAndRemoval は E-Stop の状態が出ること、削除したロボットの系列が消えることをテストする
+func TestRobotMetrics_EStopAndRemoval(t *testing.T) {
+	// Arrange: robot-1 だけが E-Stop 中
+	robotMetrics := metrics.NewRobotMetrics()
+	robotMetrics.SetEStopSource(
+		func() []string { return []string{"robot-2", "robot-1"} },
+		func(robotID string) bool { return robotID == "robot-1" },
+	)
+	robotMetrics.SetCommandedVelocity("robot-1", 0.3, 0, 0)
+
+	// Act
+	before := scrapeRobotMetrics(robotMetrics)
+	robotMetrics.RemoveRobot("robot-1")
+	after := scrapeRobotMetrics(robotMetrics)
+
+	// Assert
+	for _, line := range []string{
+		`gateway_robot_estop_active{robot_id="robot-1"} 1`,
+		`gateway_robot_estop_active{robot_id="robot-2"} 0`,
+	} {
+		if !strings.Contains(before, line) {
+			t.Errorf("Expected %q in output:\n%s", line, before)
+		}
+	}
+	if strings.Contains(after, "gateway_robot_commanded_velocity{") {
+		t.Errorf("Expected the removed robot's series to be gone, got:\n%s", after)
+	}
+}
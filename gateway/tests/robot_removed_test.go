@@ -0,0 +1,64 @@
+// =============================================================================
+// ファイル: robot_removed_test.go
+// 概要: ロボット削除時の購読の後始末（Registry.SetRemoveCallback / Handler.RobotRemoved）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/safety"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// TestRobotRemoved_NotifiesAndPrunesSubscriptions は削除されたロボットの購読者に通知が届き、購読が取り除かれることをテストする
+func TestRobotRemoved_NotifiesAndPrunesSubscriptions(t *testing.T) {
+	// Arrange: robot-1 を購読しているクライアント
+	registry := setupMockRegistry(zap.NewNop())
+	if _, err := registry.CreateAdapter("robot-1", "mock"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	logger := zap.NewNop()
+	hub := server.NewHub(logger)
+	go hub.Run()
+	h := server.NewHandler(hub, registry,
+		safety.NewEStopManager(registry, logger),
+		safety.NewVelocityLimiter(1.0, 2.0, logger),
+		safety.NewTimeoutWatchdog(time.Minute, registry, logger),
+		safety.NewOperationLock(time.Minute, logger),
+		nil, nil, logger)
+	registry.SetRemoveCallback(h.RobotRemoved)
+
+	client := &server.Client{ID: "client-1", UserID: "user-1", Send: make(chan []byte, 8), Subscriptions: map[string]bool{}}
+	hub.Register(client)
+	for i := 0; hub.ClientCount() < 1 && i < 100; i++ { // Run() が登録を反映するまで待つ
+		time.Sleep(time.Millisecond)
+	}
+	hub.SubscribeClient(client, "robot-1")
+
+	// Act
+	registry.RemoveAdapter("robot-1")
+
+	// Assert: removed: true の conn_status が届き、購読が消えている
+	msg, err := protocol.NewCodec().Decode(<-client.Send)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if msg.Type != protocol.MsgTypeConnectionStatus || msg.Payload["removed"] != true || msg.Payload["robot_connected"] != false {
+		t.Errorf("Expected a removed conn_status, got %s %v", msg.Type, msg.Payload)
+	}
+	if robots := hub.SubscribedRobots(client); len(robots) != 0 {
+		t.Errorf("Expected no subscriptions left, got %v", robots)
+	}
+
+	// 同じ ID のロボットのデータが後から来ても、購読し直していなければ届かない
+	hub.BroadcastSensorData("robot-1", "odom", []byte("stale"), false)
+	select {
+	case data := <-client.Send:
+		t.Errorf("Expected no data after removal, got %q", data)
+	default:
+	}
+}
@@ -37,6 +37,10 @@ import (
 	//   t.Fatal()  - テスト失敗を記録し、そのテストを即座に中断する
 	//   t.Fatalf() - フォーマット付きでテスト失敗を記録（即座に中断）
 	"testing"
+	"time"
+
+	// clock パッケージ: 手で進められる時計（期限切れを待たずにテストする）
+	"github.com/robot-ai-webapp/gateway/internal/clock"
 
 	// safety パッケージ: ロボットの安全機能（速度制限、ロック、緊急停止）
 	"github.com/robot-ai-webapp/gateway/internal/safety"
@@ -192,6 +196,30 @@ func TestVelocityLimiter_ClampAngular(t *testing.T) {
 	}
 }
 
+// =============================================================================
+// TestVelocityLimiter_RejectMode - 拒否モード：上限超過は実行せずに拒否するテスト
+// =============================================================================
+func TestVelocityLimiter_RejectMode(t *testing.T) {
+	// Arrange（準備）
+	logger := zap.NewNop()
+	limiter := safety.NewVelocityLimiter(1.0, 2.0, logger)
+	limiter.SetClampMode(safety.ClampModeReject)
+
+	// Act（実行）
+	over := limiter.Limit(safety.VelocityInput{LinearX: 2.0})
+	within := limiter.Limit(safety.VelocityInput{LinearX: 0.5})
+
+	// Assert（検証）
+	// 上限超過は拒否され、値はクランプされずにそのまま返る
+	if !over.Rejected || over.Clamped || over.LinearX != 2.0 {
+		t.Errorf("Expected rejection without clamping, got %+v", over)
+	}
+	// 上限以内はそのまま通る
+	if within.Rejected {
+		t.Errorf("Expected no rejection within limits, got %+v", within)
+	}
+}
+
 // =============================================================================
 // TestOperationLock_AcquireRelease - 操作ロック：取得と解放のテスト
 // =============================================================================
@@ -308,6 +336,102 @@ func TestOperationLock_AcquireRelease(t *testing.T) {
 	}
 }
 
+// =============================================================================
+// TestOperationLock_ExpiryWarningAndMaxHold - 操作ロック：期限切れ前の警告と最大保持時間のテスト
+// =============================================================================
+func TestOperationLock_ExpiryWarningAndMaxHold(t *testing.T) {
+	// Arrange（準備）: タイムアウト 60秒、期限の 10秒前に警告、最大保持 90秒
+	lock := safety.NewOperationLock(60*time.Second, zap.NewNop())
+	lock.SetMaxHold(90 * time.Second)
+	var warned []time.Duration
+	lock.SetExpiryWarning(10*time.Second, func(info safety.LockInfo, remaining time.Duration) {
+		warned = append(warned, remaining)
+	})
+	info, err := lock.Acquire("robot-1", "user-1")
+	if err != nil {
+		t.Fatalf("Failed to acquire lock: %v", err)
+	}
+
+	// Act & Assert: 期限の 20秒前は警告しない、5秒前に1回だけ警告する
+	lock.WarnExpiring(info.ExpiresAt.Add(-20 * time.Second))
+	lock.WarnExpiring(info.ExpiresAt.Add(-5 * time.Second))
+	lock.WarnExpiring(info.ExpiresAt.Add(-4 * time.Second))
+	if len(warned) != 1 || warned[0] != 5*time.Second {
+		t.Fatalf("Expected one warning with 5s remaining, got %v", warned)
+	}
+
+	// Assert: 延長後の有効期限は「取得時刻 + 最大保持時間」を超えない
+	extended, err := lock.Acquire("robot-1", "user-1")
+	if err != nil {
+		t.Fatalf("Expected renewal within max hold, got %v", err)
+	}
+	if limit := extended.AcquiredAt.Add(90 * time.Second); extended.ExpiresAt.After(limit) {
+		t.Errorf("Expected expiry capped at %v, got %v", limit, extended.ExpiresAt)
+	}
+}
+
+// TestOperationLock_MaxHoldRefusesRenewal - 最大保持時間を過ぎたら延長を拒否するテスト
+func TestOperationLock_MaxHoldRefusesRenewal(t *testing.T) {
+	// Arrange: 最大保持時間をごく短くする（タイムアウトより先に到達する）
+	clk := clock.NewMock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	lock := safety.NewOperationLock(60*time.Second, zap.NewNop())
+	lock.SetClock(clk)
+	lock.SetMaxHold(20 * time.Second)
+	if _, err := lock.Acquire("robot-1", "user-1"); err != nil {
+		t.Fatalf("Failed to acquire lock: %v", err)
+	}
+
+	// Act: 最大保持時間が過ぎてから取り直す（待たずに時計を進める）
+	clk.Advance(30 * time.Second)
+	_, err := lock.Acquire("robot-1", "user-1")
+
+	// Assert: 同じユーザーは取り直せず、別のユーザーは取得できる
+	if err == nil {
+		t.Error("Expected re-acquisition to be refused after max hold time")
+	}
+	if lock.CheckLock("robot-1", "user-1") {
+		t.Error("Expected the lock to have expired at the max hold time")
+	}
+	if _, err := lock.Acquire("robot-1", "user-2"); err != nil {
+		t.Errorf("Expected another user to acquire the lock, got %v", err)
+	}
+}
+
+// TestOperationLock_MaxLocksPerUser - ユーザーごとのロック数の上限と、解放で枠が空くことのテスト
+func TestOperationLock_MaxLocksPerUser(t *testing.T) {
+	// Arrange: 1人2台まで。admin は対象外
+	lock := safety.NewOperationLock(60*time.Second, zap.NewNop())
+	lock.SetMaxLocksPerUser(2, []string{"admin"})
+	for _, robotID := range []string{"robot-1", "robot-2"} {
+		if _, err := lock.Acquire(robotID, "user-1"); err != nil {
+			t.Fatalf("Failed to acquire %s: %v", robotID, err)
+		}
+	}
+
+	// Act & Assert: 上限ちょうどで3台目は拒否されるが、保持中のロックの延長はできる
+	if _, err := lock.Acquire("robot-3", "user-1"); err == nil {
+		t.Error("Expected the third lock to be refused at the per-user limit")
+	}
+	if _, err := lock.Acquire("robot-1", "user-1"); err != nil {
+		t.Errorf("Expected renewal to be allowed at the limit, got %v", err)
+	}
+
+	// Act & Assert: 1台解放すると枠が空き、3台目を取得できる
+	if err := lock.Release("robot-2", "user-1"); err != nil {
+		t.Fatalf("Failed to release lock: %v", err)
+	}
+	if _, err := lock.Acquire("robot-3", "user-1"); err != nil {
+		t.Errorf("Expected acquisition after release, got %v", err)
+	}
+
+	// Assert: 対象外のユーザーは上限を超えて取得できる
+	for _, robotID := range []string{"robot-4", "robot-5", "robot-6"} {
+		if _, err := lock.Acquire(robotID, "admin"); err != nil {
+			t.Errorf("Expected exempt user to acquire %s, got %v", robotID, err)
+		}
+	}
+}
+
 // =============================================================================
 // TestEStopManager - 緊急停止（E-Stop）機能のテスト
 // =============================================================================
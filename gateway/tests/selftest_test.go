@@ -0,0 +1,63 @@
+// =============================================================================
+// ファイル: selftest_test.go
+// 概要: 起動時のセルフテスト（SelfTest）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/safety"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// newSelfTest: 指定したレジストリと本番相当の安全コンポーネントでセルフテストを作る
+func newSelfTest(registry *adapter.Registry, logger *zap.Logger) *server.SelfTest {
+	return server.NewSelfTest(
+		registry,
+		safety.NewEStopManager(registry, logger),
+		safety.NewVelocityLimiter(1.0, 2.0, logger),
+		safety.NewTimeoutWatchdog(time.Minute, registry, logger),
+		safety.NewOperationLock(time.Minute, logger),
+		nil, logger,
+	)
+}
+
+// TestSelfTest_PassesAndCleansUp は正常な構成で成功し、一時的なロボットが残らないことをテストする
+func TestSelfTest_PassesAndCleansUp(t *testing.T) {
+	// Arrange
+	logger := zap.NewNop()
+	registry := setupMockRegistry(logger)
+
+	// Act
+	report := newSelfTest(registry, logger).Run(context.Background())
+
+	// Assert
+	if !report.Passed() {
+		t.Fatalf("Expected self-test to pass, got %+v", report.Checks)
+	}
+	for _, c := range report.Checks {
+		if c.Name == "redis" && !c.Skipped {
+			t.Errorf("Expected redis check to be skipped without a publisher, got %+v", c)
+		}
+	}
+	if active := registry.GetAllActive(); len(active) != 0 {
+		t.Errorf("Expected self-test robot to be removed, got %v", active)
+	}
+}
+
+// TestSelfTest_FailsWithoutMockAdapter はモックアダプターが登録されていないと失敗することをテストする
+func TestSelfTest_FailsWithoutMockAdapter(t *testing.T) {
+	logger := zap.NewNop()
+	registry := adapter.NewRegistry(logger)
+
+	report := newSelfTest(registry, logger).Run(context.Background())
+
+	if report.Passed() {
+		t.Error("Expected self-test to fail without a mock adapter factory")
+	}
+}
@@ -0,0 +1,105 @@
+// =============================================================================
+// ファイル: sensor_batch_test.go
+// 概要: SensorBatcher（センサーデータのまとめ送り）のテストコードとベンチマーク
+// =============================================================================
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// setupBatchHub: robot-1 を購読する2クライアント（1つはまとめ送りを希望）を持つ Hub を作る
+func setupBatchHub(t testing.TB, bufSize int) (*server.Hub, *server.Client, *server.Client) {
+	t.Helper()
+	hub := server.NewHub(zap.NewNop())
+	go hub.Run()
+
+	perSample := &server.Client{ID: "per-sample", Send: make(chan []byte, bufSize), Subscriptions: map[string]bool{}}
+	batched := &server.Client{ID: "batched", Send: make(chan []byte, bufSize), Subscriptions: map[string]bool{}}
+	for _, c := range []*server.Client{perSample, batched} {
+		hub.Register(c)
+		hub.SubscribeClient(c, "robot-1")
+	}
+	hub.SetSensorBatching(batched, "robot-1", true)
+
+	// Register はチャネル経由で非同期に処理されるため、登録完了を待つ
+	deadline := time.Now().Add(time.Second)
+	for hub.ClientCount() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	return hub, perSample, batched
+}
+
+// forwardSample: forwardSensorData と同じ形で1サンプルを配信する
+func forwardSample(hub *server.Hub, batcher *server.SensorBatcher, i int) {
+	hub.BroadcastSensorData("robot-1", "odom", []byte("sample"), false)
+	batcher.Add("robot-1", map[string]any{"topic": "imu", "data": map[string]any{"seq": i}})
+}
+
+// TestSensorBatcher_CombinesSamplesForOptedInClients はまとめ送りの購読者にだけ1通で届くことをテストする
+func TestSensorBatcher_CombinesSamplesForOptedInClients(t *testing.T) {
+	// Arrange
+	hub, perSample, batched := setupBatchHub(t, 64)
+	batcher := server.NewSensorBatcher(hub, time.Hour, zap.NewNop())
+
+	// Act: 10サンプルを転送してからまとめて送る
+	for i := 0; i < 10; i++ {
+		forwardSample(hub, batcher, i)
+	}
+	batcher.Flush()
+
+	// Assert: 1サンプルずつの購読者には10通、まとめ送りの購読者には1通
+	if got := len(perSample.Send); got != 10 {
+		t.Errorf("Expected 10 per-sample messages, got %d", got)
+	}
+	if got := len(batched.Send); got != 1 {
+		t.Fatalf("Expected 1 batch message, got %d", got)
+	}
+	resp, err := protocol.NewCodec().Decode(<-batched.Send)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	samples, _ := resp.Payload["samples"].([]any)
+	if resp.Type != protocol.MsgTypeSensorBatch || len(samples) != 10 {
+		t.Errorf("Expected sensor_batch with 10 samples, got %s with %d", resp.Type, len(samples))
+	}
+}
+
+// BenchmarkSensorBatch_Writes は1サンプルあたりのクライアントへの書き込み数を比較する
+//
+// go test ./tests -bench SensorBatch -run '^$'
+// で実行すると、per-sample では writes/sample が 1、
+// 50ms 分（IMU 50Hz なら約2〜3サンプル、全トピック合計で約4サンプル）をまとめると
+// その分だけ書き込みが減ることを確認できる。
+func BenchmarkSensorBatch_Writes(b *testing.B) {
+	const samplesPerWindow = 4
+
+	hub, perSample, batched := setupBatchHub(b, samplesPerWindow+1)
+	batcher := server.NewSensorBatcher(hub, time.Hour, zap.NewNop())
+
+	var perSampleWrites, batchedWrites int
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		forwardSample(hub, batcher, i)
+		if (i+1)%samplesPerWindow == 0 {
+			batcher.Flush()
+		}
+		for len(perSample.Send) > 0 {
+			<-perSample.Send
+			perSampleWrites++
+		}
+		for len(batched.Send) > 0 {
+			<-batched.Send
+			batchedWrites++
+		}
+	}
+	b.StopTimer()
+
+	b.ReportMetric(float64(perSampleWrites)/float64(b.N), "per-sample-writes/sample")
+	b.ReportMetric(float64(batchedWrites)/float64(b.N), "batched-writes/sample")
+}
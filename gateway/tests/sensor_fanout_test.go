@@ -0,0 +1,83 @@
+// =============================================================================
+// ファイル: sensor_fanout_test.go
+// 概要: センサーデータの配信ワーカー（SensorFanout）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/convert"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// blockingPersister: release が閉じられるまで書き込みが終わらない永続化先（詰まった Redis の代わり）
+type blockingPersister struct {
+	release chan struct{}
+}
+
+func (p *blockingPersister) PublishSensorData(ctx context.Context, robotID string, data adapter.SensorData) error {
+	select {
+	case <-p.release:
+	case <-ctx.Done():
+	}
+	return nil
+}
+
+// TestSensorFanout_SlowPersistenceDoesNotDelayDelivery は永続化が詰まっても配信が順番どおりすぐ届くことをテストする
+func TestSensorFanout_SlowPersistenceDoesNotDelayDelivery(t *testing.T) {
+	// Arrange: 永続化キューは2件、永続化先は詰まったまま
+	logger := zap.NewNop()
+	hub := server.NewHub(logger)
+	go hub.Run()
+	client := &server.Client{ID: "client-1", Send: make(chan []byte, 16), Subscriptions: map[string]bool{}, Authenticated: true}
+	hub.Register(client)
+	for i := 0; hub.ClientCount() < 1 && i < 100; i++ { // Run() が登録を反映するまで待つ
+		time.Sleep(time.Millisecond)
+	}
+	hub.SubscribeClient(client, "robot-1")
+
+	persister := &blockingPersister{release: make(chan struct{})}
+	defer close(persister.release)
+	fanout := server.NewSensorFanout(hub, protocol.NewCodec(), 2, 2, logger)
+	fanout.SetPersister(persister)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fanout.Start(ctx, nil)
+
+	// Act: 10件のサンプルを渡す
+	const samples = 10
+	for i := 0; i < samples; i++ {
+		fanout.Submit(ctx, adapter.SensorData{
+			RobotID: "robot-1",
+			Topic:   "odom",
+			Data:    map[string]any{"seq": i},
+		})
+	}
+
+	// Assert: すべてのサンプルが届いた順に配信され、永続化できない分は捨てられる
+	for i := 0; i < samples; i++ {
+		select {
+		case data := <-client.Send:
+			msg, err := protocol.NewCodec().Decode(data)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			payload, _ := msg.Payload["data"].(map[string]any)
+			if seq, _ := convert.ToFloat64(payload["seq"]); int(seq) != i {
+				t.Fatalf("Expected sample %d, got %v", i, payload["seq"])
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected sample %d to be delivered while persistence is stalled", i)
+		}
+	}
+	// 永続化中の1件とキューの2件を除いた分は捨てられている
+	if dropped := fanout.DroppedPersist(); dropped < samples-3 {
+		t.Errorf("Expected at least %d samples to be dropped from persistence, got %d", samples-3, dropped)
+	}
+}
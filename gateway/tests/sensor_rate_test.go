@@ -0,0 +1,252 @@
+// =============================================================================
+// ファイル: sensor_rate_test.go
+// 概要: センサーの送信頻度の変更（set_sensor_rate）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/adapter/mock"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// fixedRateAdapter: 送信頻度を変更できない（SampleRateController を実装しない）ロボット
+type fixedRateAdapter struct {
+	adapter.RobotAdapter
+}
+
+// recordingRateAdapter: 最後に設定された送信頻度をトピックごとに記録するモック
+type recordingRateAdapter struct {
+	*mock.MockAdapter
+	mu    sync.Mutex
+	rates map[string]float64
+}
+
+func (r *recordingRateAdapter) SetSampleRate(topic string, hz float64) error {
+	if err := r.MockAdapter.SetSampleRate(topic, hz); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rates[topic] = hz
+	return nil
+}
+
+// rate: topic に最後に設定された頻度を返す（未設定なら 0）
+func (r *recordingRateAdapter) rate(topic string) float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rates[topic]
+}
+
+// setupSensorRateHandler: scan と imu を生成する robot-1 と、管理者 user-1 のクライアントを作る
+func setupSensorRateHandler(t *testing.T, adapterType string) (*server.Handler, *server.Client, adapter.RobotAdapter) {
+	t.Helper()
+	registry := setupMockRegistry(zap.NewNop())
+	registry.RegisterFactory("fixed", func(l *zap.Logger) adapter.RobotAdapter {
+		return &fixedRateAdapter{RobotAdapter: mock.Factory(l)}
+	})
+	env := newTestHandler(t, withRegistry(registry, adapterType),
+		withConnectConfig(map[string]any{"enabled_topics": "scan,imu"}), withAdmin())
+	return env.h, env.client, env.adp
+}
+
+// setSensorRate: set_sensor_rate を送り、応答を返す
+func setSensorRate(t *testing.T, h *server.Handler, client *server.Client, payload map[string]any) *protocol.Message {
+	t.Helper()
+	msg := protocol.NewMessage(protocol.MsgTypeSetSensorRate, "robot-1")
+	for k, v := range payload {
+		msg.Payload[k] = v
+	}
+	return sendAndDecode(t, h, client, msg)
+}
+
+// TestSensorRate_ChangesEmissionRate は指定した頻度でセンサーデータが届くようになることをテストする
+func TestSensorRate_ChangesEmissionRate(t *testing.T) {
+	// Arrange
+	h, client, adp := setupSensorRateHandler(t, "mock")
+
+	// Act: imu（標準 50Hz）を 5Hz に下げる
+	resp := setSensorRate(t, h, client, map[string]any{"rates": map[string]any{"imu": 5.0}})
+
+	// Assert
+	if resp.Type != protocol.MsgTypeCommandAck {
+		t.Fatalf("Expected cmd_ack, got %s (%s)", resp.Type, resp.Error)
+	}
+	if rates, _ := resp.Payload["rates"].(map[string]any); rates["imu"] != 5.0 {
+		t.Fatalf("Expected imu to be set to 5 Hz, got %v", resp.Payload["rates"])
+	}
+	// 変更前の周期のサンプルを読み捨ててから、1秒間の imu の件数を数える
+	time.Sleep(100 * time.Millisecond)
+	for len(adp.SensorDataChannel()) > 0 {
+		<-adp.SensorDataChannel()
+	}
+	imu := 0
+	deadline := time.After(time.Second)
+	for done := false; !done; {
+		select {
+		case data := <-adp.SensorDataChannel():
+			if data.Topic == "imu" {
+				imu++
+			}
+		case <-deadline:
+			done = true
+		}
+	}
+	if imu < 3 || imu > 8 {
+		t.Errorf("Expected about 5 imu samples in one second, got %d", imu)
+	}
+}
+
+// TestSensorRate_RejectsRateAboveMax はセンサーの最大を超える頻度を拒否し、どのトピックも変えないことをテストする
+func TestSensorRate_RejectsRateAboveMax(t *testing.T) {
+	// Arrange
+	h, client, adp := setupSensorRateHandler(t, "mock")
+
+	// Act: scan の最大（40Hz）を超える値を、正しい imu の値と一緒に送る
+	resp := setSensorRate(t, h, client, map[string]any{"rates": map[string]any{"scan": 1000.0, "imu": 5.0}})
+
+	// Assert
+	if resp.Type != protocol.MsgTypeError || resp.Payload["code"] != protocol.ErrCodeSensorRateOutOfRange {
+		t.Fatalf("Expected sensor_rate_out_of_range, got %s %v", resp.Type, resp.Payload)
+	}
+	// imu も変わっていない（標準の 50Hz のまま）: 0.2 秒で 5 件以上届く
+	for len(adp.SensorDataChannel()) > 0 {
+		<-adp.SensorDataChannel()
+	}
+	time.Sleep(200 * time.Millisecond)
+	imu := 0
+	for len(adp.SensorDataChannel()) > 0 {
+		if data := <-adp.SensorDataChannel(); data.Topic == "imu" {
+			imu++
+		}
+	}
+	if imu < 5 {
+		t.Errorf("Expected imu to keep its nominal rate, got %d samples in 200ms", imu)
+	}
+}
+
+// TestSensorRate_RejectsInvalidRequests は管理者以外、未知のトピック、対応していないロボットを拒否することをテストする
+func TestSensorRate_RejectsInvalidRequests(t *testing.T) {
+	tests := []struct {
+		name        string
+		adapterType string
+		userID      string
+		payload     map[string]any
+		wantCode    string
+	}{
+		{"not admin", "mock", "user-2", map[string]any{"rates": map[string]any{"imu": 5.0}}, protocol.ErrCodeForbidden},
+		{"unknown topic", "mock", "user-1", map[string]any{"rates": map[string]any{"battery": 1.0}}, protocol.ErrCodeInvalidMessage},
+		{"zero rate", "mock", "user-1", map[string]any{"rates": map[string]any{"imu": 0.0}}, protocol.ErrCodeInvalidMessage},
+		{"empty", "mock", "user-1", map[string]any{}, protocol.ErrCodeInvalidMessage},
+		{"unsupported robot", "fixed", "user-1", map[string]any{"rates": map[string]any{"imu": 5.0}}, protocol.ErrCodeCommandNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			h, client, _ := setupSensorRateHandler(t, tt.adapterType)
+			client.UserID = tt.userID
+
+			// Act
+			resp := setSensorRate(t, h, client, tt.payload)
+
+			// Assert
+			if resp.Type != protocol.MsgTypeError || resp.Payload["code"] != tt.wantCode {
+				t.Errorf("Expected error %s, got %s %v", tt.wantCode, resp.Type, resp.Payload)
+			}
+		})
+	}
+}
+
+// setupFlowControlledRobot: 頻度を記録する robot-1 とそのフロー制御、robot-1 を購読する遅いクライアントを作る
+func setupFlowControlledRobot(t *testing.T) (*testEnv, *recordingRateAdapter, *server.FlowController, *server.Client) {
+	t.Helper()
+	registry := setupMockRegistry(zap.NewNop())
+	registry.RegisterFactory("recording", func(l *zap.Logger) adapter.RobotAdapter {
+		return &recordingRateAdapter{MockAdapter: mock.NewMockAdapter(l), rates: map[string]float64{}}
+	})
+	env := newTestHandler(t, withRegistry(registry, "recording"), withRunningHub(), withAdmin(),
+		withConnectConfig(map[string]any{"enabled_topics": "imu"}))
+	adp := env.adp.(*recordingRateAdapter)
+	fc := server.NewFlowController(env.hub, "robot-1", adp, zap.NewNop())
+	env.h.SetFlowController("robot-1", fc)
+	slow := &server.Client{ID: "slow-client", Send: make(chan []byte, 4), Subscriptions: map[string]bool{}}
+	registerClients(t, env.hub, slow)
+	env.hub.SubscribeClient(slow, "robot-1")
+	return env, adp, fc, slow
+}
+
+// fillSend: クライアントの Send バッファを満杯にする（混雑度 1）
+func fillSend(client *server.Client) {
+	for len(client.Send) < cap(client.Send) {
+		client.Send <- []byte("x")
+	}
+}
+
+// drainSend: クライアントの Send バッファを空にする（混雑度 0）
+func drainSend(client *server.Client) {
+	for len(client.Send) > 0 {
+		<-client.Send
+	}
+}
+
+// TestSensorRate_FlowControlScalesOperatorRate は set_sensor_rate の頻度がフロー制御の基準になり、
+// 倍率の調整で標準の頻度に上書きされないことをテストする
+func TestSensorRate_FlowControlScalesOperatorRate(t *testing.T) {
+	// Arrange
+	env, adp, fc, slow := setupFlowControlledRobot(t)
+
+	// Act: imu（標準 50Hz）を 5Hz にしてから、混雑 → 解消の順に調整する
+	resp := setSensorRate(t, env.h, env.client, map[string]any{"rates": map[string]any{"imu": 5.0}})
+	fillSend(slow)
+	fc.Adjust()
+	throttled := adp.rate("imu")
+	drainSend(slow)
+	fc.Adjust()
+	recovered := adp.rate("imu")
+
+	// Assert
+	if resp.Type != protocol.MsgTypeCommandAck || resp.Payload["rate_factor"] != 1.0 {
+		t.Fatalf("Expected cmd_ack with rate_factor 1, got %s %v", resp.Type, resp.Payload)
+	}
+	if throttled != 2.5 {
+		t.Errorf("Expected imu at 5 Hz x 0.5 under pressure, got %v", throttled)
+	}
+	if recovered != 5.0 {
+		t.Errorf("Expected imu back at the operator's 5 Hz, got %v", recovered)
+	}
+}
+
+// TestSensorRate_ResetUnderFlowControlAppliesFactor は混雑中の reset が「標準の頻度 × 現在の倍率」になり、
+// 解消後に標準の頻度へ戻ることをテストする
+func TestSensorRate_ResetUnderFlowControlAppliesFactor(t *testing.T) {
+	// Arrange: 混雑で倍率 0.5 になったフロー制御
+	env, adp, fc, slow := setupFlowControlledRobot(t)
+	setSensorRate(t, env.h, env.client, map[string]any{"rates": map[string]any{"imu": 5.0}})
+	fillSend(slow)
+	fc.Adjust()
+
+	// Act
+	resp := setSensorRate(t, env.h, env.client, map[string]any{"reset": true})
+	reset := adp.rate("imu")
+	drainSend(slow)
+	fc.Adjust()
+	recovered := adp.rate("imu")
+
+	// Assert: 応答は基準の頻度と現在の倍率を返す
+	if rates, _ := resp.Payload["rates"].(map[string]any); rates["imu"] != 50.0 || resp.Payload["rate_factor"] != 0.5 {
+		t.Errorf("Expected imu 50 Hz at rate_factor 0.5 in the ack, got %v", resp.Payload)
+	}
+	if reset != 25.0 {
+		t.Errorf("Expected the reset to apply 50 Hz x 0.5, got %v", reset)
+	}
+	if recovered != 50.0 {
+		t.Errorf("Expected imu back at the nominal 50 Hz, got %v", recovered)
+	}
+}
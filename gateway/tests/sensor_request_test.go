@@ -0,0 +1,97 @@
+// =============================================================================
+// ファイル: sensor_request_test.go
+// 概要: sensor_request（センサーの最新値の単発の問い合わせ）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// sensorRequest: robot-1 の topic を問い合わせるメッセージを作る
+func sensorRequest(topic string) *protocol.Message {
+	msg := protocol.NewMessage(protocol.MsgTypeSensorRequest, "robot-1")
+	msg.Topic = topic
+	return msg
+}
+
+// TestSensorRequest_ReturnsCachedSample は配信経路で保持した最新値が、付け替え後の名前で返ることをテストする
+func TestSensorRequest_ReturnsCachedSample(t *testing.T) {
+	// Arrange: odom を "/robot1/odom" に付け替えて配信する
+	logger := zap.NewNop()
+	h, client := setupPolicyHandler(t, setupMockRegistry(logger), "mock")
+	remap := server.TopicRemap{"robot-1": {"odom": "/robot1/odom"}}
+	cache := server.NewSensorCache(remap)
+	h.SetSensorCache(cache)
+	fanout := server.NewSensorFanout(server.NewHub(logger), protocol.NewCodec(), 1, 1, logger)
+	fanout.SetTopicRemap(remap)
+	fanout.SetSensorCache(cache)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fanout.Start(ctx, nil)
+	fanout.Submit(ctx, adapter.SensorData{
+		RobotID:   "robot-1",
+		Topic:     "odom",
+		DataType:  "odometry",
+		Timestamp: 123,
+		Data:      map[string]any{"position_x": 1.5},
+	})
+	for i := 0; i < 100; i++ { // ワーカーが保持するまで待つ
+		if _, ok := cache.Latest("robot-1", "/robot1/odom"); ok {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Act
+	resp := sendAndDecode(t, h, client, sensorRequest("/robot1/odom"))
+
+	// Assert
+	if resp.Type != protocol.MsgTypeSensorData || resp.Topic != "/robot1/odom" {
+		t.Fatalf("Expected sensor_data for /robot1/odom, got %s %q (%s)", resp.Type, resp.Topic, resp.Error)
+	}
+	data, _ := resp.Payload["data"].(map[string]any)
+	if resp.Payload["source"] != "cache" || data["position_x"] != 1.5 {
+		t.Errorf("Expected the cached sample, got %v", resp.Payload)
+	}
+	if ts, ok := resp.Payload["timestamp"].(int64); !ok || ts != 123 {
+		t.Errorf("Expected the sample timestamp 123, got %v", resp.Payload["timestamp"])
+	}
+}
+
+// TestSensorRequest_ReadsAdapterWhenNotCached は最新値がない時にアダプターからその場で読むことをテストする
+func TestSensorRequest_ReadsAdapterWhenNotCached(t *testing.T) {
+	// Arrange: battery だけが有効な robot-1（定期送信はまだ届いていない）
+	h, client := setupPolicyHandler(t, setupMockRegistry(zap.NewNop()), "mock")
+	h.SetSensorCache(server.NewSensorCache(nil))
+
+	// Act
+	resp := sendAndDecode(t, h, client, sensorRequest("battery"))
+
+	// Assert
+	data, _ := resp.Payload["data"].(map[string]any)
+	if resp.Type != protocol.MsgTypeSensorData || resp.Payload["source"] != "adapter" || data["percentage"] != 100.0 {
+		t.Errorf("Expected a battery reading from the adapter, got %s %v (%s)", resp.Type, resp.Payload, resp.Error)
+	}
+}
+
+// TestSensorRequest_NoData は値のないトピックが no_sensor_data のエラーになることをテストする
+func TestSensorRequest_NoData(t *testing.T) {
+	// Arrange
+	h, client := setupPolicyHandler(t, setupMockRegistry(zap.NewNop()), "mock")
+
+	// Act: scan は無効で、その場で読むこともできない
+	resp := sendAndDecode(t, h, client, sensorRequest("scan"))
+
+	// Assert
+	if resp.Type != protocol.MsgTypeError || resp.Payload["code"] != protocol.ErrCodeNoSensorData {
+		t.Errorf("Expected a no_sensor_data error, got %s %v", resp.Type, resp.Payload)
+	}
+}
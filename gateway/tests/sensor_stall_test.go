@@ -0,0 +1,86 @@
+// =============================================================================
+// ファイル: sensor_stall_test.go
+// 概要: センサー停止検出（SensorStallDetector）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/safety"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// TestSensorStallDetector_StallAndRecover はデータが途絶えたトピックが停止とみなされ、
+// 再び届くと復旧することをテストする
+func TestSensorStallDetector_StallAndRecover(t *testing.T) {
+	// Arrange
+	detector := safety.NewSensorStallDetector(time.Second, zap.NewNop())
+	var events []bool
+	detector.SetStallCallback(func(robotID, topic string, stalled bool) {
+		if robotID == "robot-1" && topic == "scan" {
+			events = append(events, stalled)
+		}
+	})
+	detector.Mark("robot-1", "scan")
+	detector.Mark("robot-1", "odom")
+
+	// Act: 2秒後の時点でチェックする
+	later := time.Now().Add(2 * time.Second)
+	detector.CheckStalls(later)
+	detector.CheckStalls(later) // 2回目のチェックでは再通知しない
+
+	// Assert: どちらのトピックも1秒以上届いていないので停止とみなされる
+	if got := detector.Stalled("robot-1"); len(got) != 2 || got[0] != "odom" || got[1] != "scan" {
+		t.Fatalf("Expected [odom scan] stalled, got %v", got)
+	}
+	if len(events) != 1 || !events[0] {
+		t.Fatalf("Expected one stall event for scan, got %v", events)
+	}
+
+	// Act: scan が再び届く
+	detector.Mark("robot-1", "scan")
+
+	// Assert: 復旧が通知され、停止一覧から外れる
+	if len(events) != 2 || events[1] {
+		t.Errorf("Expected recovery event, got %v", events)
+	}
+	if got := detector.Stalled("robot-1"); len(got) != 1 || got[0] != "odom" {
+		t.Errorf("Expected only odom stalled, got %v", got)
+	}
+}
+
+// TestSensorStall_AlertBroadcast は停止が safety_alert として配信されることをテストする
+func TestSensorStall_AlertBroadcast(t *testing.T) {
+	// Arrange
+	logger := zap.NewNop()
+	hub := server.NewHub(logger)
+	go hub.Run()
+	client := &server.Client{ID: "client-1", Send: make(chan []byte, 4), Authenticated: true}
+	hub.Register(client)
+	for i := 0; hub.ClientCount() == 0 && i < 100; i++ { // Run() が登録を反映するまで待つ
+		time.Sleep(time.Millisecond)
+	}
+	hub.SetAlertSubscription(client, true)
+	h := server.NewHandler(hub, setupMockRegistry(logger), nil, nil, nil, nil, nil, nil, logger)
+
+	// Act
+	h.NotifySensorStall("robot-1", "scan", true)
+
+	// Assert
+	select {
+	case data := <-client.Send:
+		msg, err := protocol.NewCodec().Decode(data)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if msg.Type != protocol.MsgTypeSafetyAlert || msg.Payload["type"] != "sensor_stalled" || msg.Payload["topic"] != "scan" {
+			t.Errorf("Expected sensor_stalled alert for scan, got %s %v", msg.Type, msg.Payload)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected a safety alert")
+	}
+}
@@ -0,0 +1,86 @@
+// =============================================================================
+// ファイル: sensor_validation_test.go
+// 概要: センサーデータの検証（SensorValidator）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"math"
+	"testing"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/safety"
+	"go.uber.org/zap"
+)
+
+// TestSensorValidator_AppliesPerTopicPolicy はトピックごとのポリシー（clamp / flag / drop / off）で異常なサンプルを扱うことをテストする
+func TestSensorValidator_AppliesPerTopicPolicy(t *testing.T) {
+	// Arrange: battery は丸め、scan は印を付け、その他は捨てる
+	v := safety.NewSensorValidator(map[string]safety.SensorValidationPolicy{
+		"battery": safety.SensorValidationClamp,
+		"scan":    safety.SensorValidationFlag,
+		"*":       safety.SensorValidationDrop,
+	}, zap.NewNop())
+
+	ranges := []float64{1.0, 20.0, 0.05}
+	battery := adapter.SensorData{RobotID: "robot-1", Topic: "battery", DataType: "battery",
+		Data: map[string]any{"percentage": -5.0}}
+	scan := adapter.SensorData{RobotID: "robot-1", Topic: "scan", DataType: "lidar",
+		Data: map[string]any{"range_min": 0.1, "range_max": 12.0, "ranges": ranges}}
+	odom := adapter.SensorData{RobotID: "robot-1", Topic: "odom", DataType: "odometry",
+		Data: map[string]any{"position_x": math.NaN()}}
+
+	// Act & Assert: マイナスのバッテリー残量は 0 に丸められる
+	if !v.Check(&battery) || battery.Data["percentage"] != 0.0 {
+		t.Errorf("Expected the battery percentage to be clamped to 0, got %v", battery.Data)
+	}
+
+	// 範囲外の距離は、値はそのままで理由が付く（アダプターのスライスは変更しない）
+	if !v.Check(&scan) {
+		t.Fatal("Expected the flagged scan to be forwarded")
+	}
+	if problems, _ := scan.Data["validation_errors"].([]string); len(problems) != 1 {
+		t.Errorf("Expected one validation error, got %v", scan.Data["validation_errors"])
+	}
+	if ranges[1] != 20.0 {
+		t.Errorf("Expected the adapter's ranges to be untouched, got %v", ranges)
+	}
+
+	// NaN の位置を含むサンプルは捨てられる
+	if v.Check(&odom) {
+		t.Error("Expected the odometry sample with a NaN position to be dropped")
+	}
+
+	// 正常なサンプルはそのまま通る
+	valid := adapter.SensorData{RobotID: "robot-1", Topic: "odom", DataType: "odometry",
+		Data: map[string]any{"position_x": 1.0}}
+	if !v.Check(&valid) {
+		t.Error("Expected a valid sample to be forwarded")
+	}
+	if got := v.Anomalies(); got["robot-1/battery"] != 1 || got["robot-1/scan"] != 1 || got["robot-1/odom"] != 1 {
+		t.Errorf("Expected one anomaly per topic, got %v", got)
+	}
+}
+
+// TestSensorValidator_ClampDropsNonFinite は clamp でも NaN や ±Inf を含むサンプルは直せないので捨てることをテストする
+func TestSensorValidator_ClampDropsNonFinite(t *testing.T) {
+	// Arrange
+	v := safety.NewSensorValidator(map[string]safety.SensorValidationPolicy{
+		"scan": safety.SensorValidationClamp,
+	}, zap.NewNop())
+	clampable := adapter.SensorData{Topic: "scan", DataType: "lidar",
+		Data: map[string]any{"range_min": 0.1, "range_max": 12.0, "ranges": []float64{1.0, 20.0}}}
+	broken := adapter.SensorData{Topic: "scan", DataType: "lidar",
+		Data: map[string]any{"range_min": 0.1, "range_max": 12.0, "ranges": []float64{1.0, math.Inf(1)}}}
+
+	// Act & Assert
+	if !v.Check(&clampable) {
+		t.Fatal("Expected the clampable scan to be forwarded")
+	}
+	if got := clampable.Data["ranges"].([]float64); got[1] != 12.0 {
+		t.Errorf("Expected 20m to be clamped to range_max, got %v", got)
+	}
+	if v.Check(&broken) {
+		t.Error("Expected the scan with +Inf to be dropped")
+	}
+}
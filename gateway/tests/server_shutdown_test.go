@@ -0,0 +1,70 @@
+// =============================================================================
+// ファイル: server_shutdown_test.go
+// 概要: サーバー停止時の予告（server_shutdown）と、理由付きの Close フレームのテストコード
+// =============================================================================
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// TestServerShutdown_NotifiesAndClosesGoingAway は停止の予告の後に 1001（Going Away）で閉じることをテストする
+func TestServerShutdown_NotifiesAndClosesGoingAway(t *testing.T) {
+	// Arrange
+	logger := zap.NewNop()
+	hub := server.NewHub(logger)
+	go hub.Run()
+	ws := server.NewWebSocketServer(hub, server.NewHandler(hub, nil, nil, nil, nil, nil, nil, nil, logger), 0, 0, logger)
+	srv := httptest.NewServer(http.HandlerFunc(ws.HandleWebSocket))
+	t.Cleanup(srv.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	for hub.ClientCount() != 1 {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Act
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := ws.Shutdown(ctx, "server shutting down"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Assert: server_shutdown が届き、その後 1001（Going Away）と理由付きで閉じられる
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	msg, err := protocol.NewCodec().Decode(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if msg.Type != protocol.MsgTypeServerShutdown || msg.Payload["reason"] != "server shutting down" {
+		t.Errorf("Expected server_shutdown with reason, got %s %v", msg.Type, msg.Payload)
+	}
+
+	_, _, err = conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != websocket.CloseGoingAway || closeErr.Text != "server shutting down" {
+		t.Errorf("Expected a going-away closure with the reason, got %v", err)
+	}
+	if hub.ClientCount() != 0 {
+		t.Errorf("Expected no clients after shutdown, got %d", hub.ClientCount())
+	}
+}
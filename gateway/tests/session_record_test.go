@@ -0,0 +1,247 @@
+// =============================================================================
+// ファイル: session_record_test.go
+// 概要: セッションの記録（recorder / record_session）と記録の再生（ReplayRecording）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/recorder"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// TestRecorder_RotatesAndKeepsNewestFiles は上限を超えるとファイルを切り替え、古いファイルを消すことをテストする
+func TestRecorder_RotatesAndKeepsNewestFiles(t *testing.T) {
+	// Arrange: 1ファイルに2行ほどしか入らない上限
+	rec, err := recorder.New(t.TempDir(), 300, 2, zap.NewNop())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { _ = rec.Close() })
+
+	// Act
+	for i := 0; i < 10; i++ {
+		rec.Record("client-1", recorder.DirectionIn, protocol.NewMessage(protocol.MsgTypePing, "robot-1"))
+	}
+
+	// Assert: 残るのは新しい2ファイルだけで、どれも読み込める
+	files := rec.Files()
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 files to be kept, got %v", files)
+	}
+	for _, path := range files {
+		entries, err := recorder.Load(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(entries) == 0 || entries[0].Message.Type != protocol.MsgTypePing {
+			t.Errorf("Expected ping entries in %s, got %v", path, entries)
+		}
+	}
+}
+
+// TestRecordSession_RequiresAdminAndRecorder は record_session が管理者専用で、記録先がなければ拒否されることをテストする
+func TestRecordSession_RequiresAdminAndRecorder(t *testing.T) {
+	// Arrange
+	logger := zap.NewNop()
+	h := server.NewHandler(server.NewHub(logger), setupMockRegistry(logger), nil, nil, nil, nil, nil, nil, logger)
+	client := &server.Client{ID: "client-1", UserID: "user-1", Send: make(chan []byte, 4), Authenticated: true}
+	record := func(clientID string) *protocol.Message {
+		msg := protocol.NewMessage(protocol.MsgTypeRecordSession, "")
+		if clientID != "" {
+			msg.Payload["client_id"] = clientID
+		}
+		return sendAndDecode(t, h, client, msg)
+	}
+
+	// Act & Assert
+	if resp := record(""); resp.Payload["code"] != protocol.ErrCodeForbidden {
+		t.Errorf("Expected forbidden for a non-admin user, got %s %v", resp.Type, resp.Payload)
+	}
+	h.SetAdminUsers([]string{"user-1"})
+	if resp := record(""); resp.Payload["code"] != protocol.ErrCodeSessionRecordingDisabled {
+		t.Errorf("Expected session_recording_disabled without a recorder, got %s %v", resp.Type, resp.Payload)
+	}
+	rec, err := recorder.New(t.TempDir(), 1<<20, 1, logger)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { _ = rec.Close() })
+	h.SetSessionRecorder(rec, false)
+	if resp := record("no-such-client"); resp.Payload["code"] != protocol.ErrCodeInvalidMessage {
+		t.Errorf("Expected invalid_message for an unknown client, got %s %v", resp.Type, resp.Payload)
+	}
+	if resp := record(""); resp.Type != protocol.MsgTypeCommandAck || resp.Payload["enabled"] != true {
+		t.Errorf("Expected cmd_ack with enabled=true, got %s %v", resp.Type, resp.Payload)
+	}
+}
+
+// recordingServer: セッションレコーダーを設定した WebSocket サーバーに JSON で接続し、
+// メッセージを1往復させる関数と、レコーダーを返す（管理者は認証時のユーザー "user-from-token"）
+func recordingServer(t *testing.T, recordAll bool) (func(*protocol.Message) *protocol.Message, *recorder.Recorder) {
+	t.Helper()
+	logger := zap.NewNop()
+	hub := server.NewHub(logger)
+	go hub.Run()
+	h := server.NewHandler(hub, setupMockRegistry(logger), nil, nil, nil, nil, nil, nil, logger)
+	h.SetAdminUsers([]string{"user-from-token"})
+	rec, err := recorder.New(t.TempDir(), 1<<20, 1, logger)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { _ = rec.Close() })
+	h.SetSessionRecorder(rec, recordAll)
+	srv := httptest.NewServer(http.HandlerFunc(server.NewWebSocketServer(hub, h, 0, 0, logger).HandleWebSocket))
+	t.Cleanup(srv.Close)
+	dialer := websocket.Dialer{Subprotocols: []string{protocol.FormatJSON}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return func(msg *protocol.Message) *protocol.Message {
+		t.Helper()
+		data, _ := json.Marshal(msg)
+		if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, reply, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var resp protocol.Message
+		if err := json.Unmarshal(reply, &resp); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return &resp
+	}, rec
+}
+
+// loadRecording: 記録が want 行に達するまで待って読み込む（送信の記録は書き込みの後なので少し遅れる）
+func loadRecording(t *testing.T, rec *recorder.Recorder, want int) []recorder.Entry {
+	t.Helper()
+	var entries []recorder.Entry
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if files := rec.Files(); len(files) == 1 {
+			entries, _ = recorder.Load(files[0])
+			if len(entries) >= want {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return entries
+}
+
+// authenticate: auth を送り、接続のID（connection_status の client_id）を返す
+func authenticate(roundTrip func(*protocol.Message) *protocol.Message) string {
+	auth := protocol.NewMessage(protocol.MsgTypeAuth, "")
+	auth.Payload["token"] = "token"
+	clientID, _ := roundTrip(auth).Payload["client_id"].(string)
+	return clientID
+}
+
+// TestRecordSession_RecordsOnlyWhileEnabled は record_session で開始してから停止するまでだけが記録されることをテストする
+func TestRecordSession_RecordsOnlyWhileEnabled(t *testing.T) {
+	// Arrange
+	roundTrip, rec := recordingServer(t, false)
+	clientID := authenticate(roundTrip)
+
+	// Act: 記録を開始 → ping → 記録を停止 → ping（記録されない）
+	roundTrip(protocol.NewMessage(protocol.MsgTypeRecordSession, ""))
+	roundTrip(protocol.NewMessage(protocol.MsgTypePing, ""))
+	stop := protocol.NewMessage(protocol.MsgTypeRecordSession, "")
+	stop.Payload["enabled"] = false
+	roundTrip(stop)
+	roundTrip(protocol.NewMessage(protocol.MsgTypePing, ""))
+
+	// Assert: 開始の ack、ping と pong、停止の要求だけが残る
+	entries := loadRecording(t, rec, 4)
+	in := recorder.Messages(entries, clientID, recorder.DirectionIn)
+	out := recorder.Messages(entries, clientID, recorder.DirectionOut)
+	if len(in) != 2 || len(out) != 2 {
+		t.Fatalf("Expected 2 inbound and 2 outbound messages, got %d and %d", len(in), len(out))
+	}
+	if out[0].Type != protocol.MsgTypeCommandAck || in[0].Type != protocol.MsgTypePing || out[1].Type != protocol.MsgTypePong {
+		t.Errorf("Expected ack, ping and pong, got %s, %s and %s", out[0].Type, in[0].Type, out[1].Type)
+	}
+	if in[1].Type != protocol.MsgTypeRecordSession {
+		t.Errorf("Expected the stop request to be the last inbound message, got %s", in[1].Type)
+	}
+}
+
+// TestSessionRecording_ReplayReproducesReplies は全接続の記録を新しいハンドラーに再生すると同じ応答が返ることをテストする
+func TestSessionRecording_ReplayReproducesReplies(t *testing.T) {
+	// Arrange: 全接続を記録し、認証と ping を行う
+	roundTrip, rec := recordingServer(t, true)
+	clientID := authenticate(roundTrip)
+	roundTrip(protocol.NewMessage(protocol.MsgTypePing, ""))
+	entries := loadRecording(t, rec, 4)
+	recorded := recorder.Messages(entries, clientID, recorder.DirectionOut)
+
+	// Act: 記録を新しいハンドラーに再生する
+	logger := zap.NewNop()
+	fresh := server.NewHandler(server.NewHub(logger), setupMockRegistry(logger), nil, nil, nil, nil, nil, nil, logger)
+	replayed := server.ReplayRecording(fresh, entries)[clientID]
+
+	// Assert: 応答のタイプが記録時と同じ順で返る
+	if len(recorded) != 2 || len(replayed) != len(recorded) {
+		t.Fatalf("Expected 2 recorded and replayed replies, got %d and %d", len(recorded), len(replayed))
+	}
+	for i := range recorded {
+		if replayed[i].Type != recorded[i].Type {
+			t.Errorf("Reply %d: expected %s, got %s", i, recorded[i].Type, replayed[i].Type)
+		}
+	}
+	if replayed[0].Payload["client_id"] != clientID {
+		t.Errorf("Expected the replayed connection to keep its ID, got %v", replayed[0].Payload["client_id"])
+	}
+}
+
+// TestSessionRecording_RedactsAuthToken は auth のトークンが記録されず、ファイルが作成者だけに読めることをテストする
+func TestSessionRecording_RedactsAuthToken(t *testing.T) {
+	// Arrange
+	roundTrip, rec := recordingServer(t, true)
+	auth := protocol.NewMessage(protocol.MsgTypeAuth, "")
+	auth.Payload["token"] = "secret-jwt-token"
+
+	// Act
+	roundTrip(auth)
+	entries := loadRecording(t, rec, 2)
+
+	// Assert: 生のファイルにもトークンが残らない
+	files := rec.Files()
+	if len(entries) < 2 || len(files) != 1 {
+		t.Fatalf("Expected the auth exchange to be recorded, got %d entries in %d files", len(entries), len(files))
+	}
+	raw, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(string(raw), "secret-jwt-token") {
+		t.Errorf("Expected the token to be redacted, got %s", raw)
+	}
+	if in := recorder.Messages(entries, entries[0].ClientID, recorder.DirectionIn); len(in) != 1 || in[0].Payload["token"] != recorder.Redacted {
+		t.Errorf("Expected the recorded auth to carry %q, got %v", recorder.Redacted, in)
+	}
+	info, err := os.Stat(files[0])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("Expected the recording to be created with 0600, got %o", perm)
+	}
+}
@@ -0,0 +1,99 @@
+// =============================================================================
+// ファイル: session_resume_test.go
+// 概要: 短い切断からの再開（SessionBuffer）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/clock"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/safety"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// TestSessionResume_ReplaysBufferedData は再接続で購読が復元され、溜めたデータが順に届くことをテストする
+func TestSessionResume_ReplaysBufferedData(t *testing.T) {
+	// Arrange: トピックごとに2件まで溜める
+	logger := zap.NewNop()
+	registry := setupMockRegistry(logger)
+	hub := server.NewHub(logger)
+	h := server.NewHandler(hub, registry,
+		safety.NewEStopManager(registry, logger),
+		safety.NewVelocityLimiter(1.0, 2.0, logger),
+		safety.NewTimeoutWatchdog(time.Minute, registry, logger),
+		safety.NewOperationLock(time.Minute, logger),
+		nil, nil, logger)
+	buffer := server.NewSessionBuffer(2, 10*time.Second, logger)
+	h.SetSessionBuffer(buffer)
+
+	auth := protocol.NewMessage(protocol.MsgTypeAuth, "robot-1")
+	auth.Payload["token"] = "token"
+	old := &server.Client{ID: "client-1", Send: make(chan []byte, 8), Subscriptions: make(map[string]bool)}
+	resp := sendAndDecode(t, h, old, auth)
+	sessionID, _ := resp.Payload["session_id"].(string)
+	if sessionID == "" {
+		t.Fatalf("Expected a session_id in the auth response, got %v", resp.Payload)
+	}
+
+	// Act: 切断中に odom が3件、購読していない robot-2 のデータが1件届く
+	h.ParkSession(old)
+	buffer.Record("robot-1", "odom", []byte("odom-1"))
+	buffer.Record("robot-2", "odom", []byte("other"))
+	buffer.Record("robot-1", "odom", []byte("odom-2"))
+	buffer.Record("robot-1", "odom", []byte("odom-3"))
+
+	resume := protocol.NewMessage(protocol.MsgTypeAuth, "")
+	resume.Payload["token"] = "token"
+	resume.Payload["session_id"] = sessionID
+	client := &server.Client{ID: "client-2", Send: make(chan []byte, 8), Subscriptions: make(map[string]bool)}
+	resp = sendAndDecode(t, h, client, resume)
+
+	// Assert: 再開でき、購読が戻り、最新2件が届いた順に再送される
+	if resp.Payload["resumed"] != true || resp.Payload["session_id"] != sessionID {
+		t.Fatalf("Expected the session to be resumed, got %v", resp.Payload)
+	}
+	if robots := hub.SubscribedRobots(client); len(robots) != 1 || robots[0] != "robot-1" {
+		t.Errorf("Expected the robot-1 subscription to be restored, got %v", robots)
+	}
+	for _, want := range []string{"odom-2", "odom-3"} {
+		select {
+		case got := <-client.Send:
+			if string(got) != want {
+				t.Errorf("Expected %s, got %s", want, got)
+			}
+		default:
+			t.Fatalf("Expected %s to be replayed", want)
+		}
+	}
+	if len(client.Send) != 0 {
+		t.Errorf("Expected no other replayed messages, got %d", len(client.Send))
+	}
+}
+
+// TestSessionBuffer_ExpiresAfterGrace は猶予時間の経過後や別のユーザーでは再開できないことをテストする
+func TestSessionBuffer_ExpiresAfterGrace(t *testing.T) {
+	// Arrange
+	clk := clock.NewMock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	buffer := server.NewSessionBuffer(4, 10*time.Second, zap.NewNop())
+	buffer.SetClock(clk)
+	buffer.Park("session-1", "user-1", []string{"robot-1"})
+	buffer.Park("session-2", "user-1", []string{"robot-1"})
+
+	// Act & Assert: 別のユーザーは再開できない（セッションは破棄される）
+	if _, _, ok := buffer.Resume("session-1", "user-2"); ok {
+		t.Error("Expected resume by another user to be refused")
+	}
+	if _, _, ok := buffer.Resume("session-1", "user-1"); ok {
+		t.Error("Expected a refused session to be discarded")
+	}
+
+	// Act & Assert: 猶予時間を過ぎると再開できない
+	clk.Advance(10 * time.Second)
+	if _, _, ok := buffer.Resume("session-2", "user-1"); ok {
+		t.Error("Expected resume after the grace period to be refused")
+	}
+}
@@ -0,0 +1,88 @@
+// =============================================================================
+// ファイル: speed_limit_test.go
+// 概要: ロボットごとの速度上限の変更（set_speed_limit）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"go.uber.org/zap"
+)
+
+// TestSetSpeedLimit_LowersRobotLimit は管理者が上限を下げると以後の速度コマンドがその上限で制限されることをテストする
+func TestSetSpeedLimit_LowersRobotLimit(t *testing.T) {
+	// Arrange: 設定の上限は 1.0 m/s、モックのハードウェア上限も 1.0 m/s
+	h, client := setupPolicyHandler(t, setupMockRegistry(zap.NewNop()), "mock")
+	h.SetAdminUsers([]string{"user-1"})
+
+	velocity := protocol.NewMessage(protocol.MsgTypeVelocityCommand, "robot-1")
+	velocity.Payload["linear_x"] = 0.5
+	if resp := sendAndDecode(t, h, client, velocity); resp.Payload["clamped"] != false {
+		t.Fatalf("Expected 0.5 m/s not to be clamped before the change, got %v", resp.Payload)
+	}
+
+	// Act: 0.3 m/s に下げる
+	limit := protocol.NewMessage(protocol.MsgTypeSetSpeedLimit, "robot-1")
+	limit.Payload["max_linear"] = 0.3
+	resp := sendAndDecode(t, h, client, limit)
+
+	// Assert
+	if resp.Type != protocol.MsgTypeCommandAck || resp.Payload["max_linear"] != 0.3 || resp.Payload["max_angular"] != 2.0 {
+		t.Fatalf("Expected cmd_ack with the new limits, got %s %v", resp.Type, resp.Payload)
+	}
+	if resp := sendAndDecode(t, h, client, velocity); resp.Payload["clamped"] != true {
+		t.Errorf("Expected 0.5 m/s to be clamped after the change, got %v", resp.Payload)
+	}
+
+	// 現在の上限を HTTP で問い合わせられる
+	rec := httptest.NewRecorder()
+	h.SpeedLimitsHandler(rec, httptest.NewRequest("GET", "/speed-limits", nil))
+	var body struct {
+		Robots map[string]struct {
+			MaxLinear  float64 `json:"max_linear"`
+			Overridden bool    `json:"overridden"`
+		} `json:"robots"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := body.Robots["robot-1"]; got.MaxLinear != 0.3 || !got.Overridden {
+		t.Errorf("Expected /speed-limits to report 0.3 m/s for robot-1, got %+v", got)
+	}
+
+	// reset で設定の上限に戻る
+	reset := protocol.NewMessage(protocol.MsgTypeSetSpeedLimit, "robot-1")
+	reset.Payload["reset"] = true
+	if resp := sendAndDecode(t, h, client, reset); resp.Payload["max_linear"] != 1.0 || resp.Payload["overridden"] != false {
+		t.Errorf("Expected the limit to be reset to 1.0 m/s, got %v", resp.Payload)
+	}
+}
+
+// TestSetSpeedLimit_RejectsInvalidRequests は管理者以外と上限を超える値が拒否されることをテストする
+func TestSetSpeedLimit_RejectsInvalidRequests(t *testing.T) {
+	// Arrange
+	h, client := setupPolicyHandler(t, setupMockRegistry(zap.NewNop()), "mock")
+	limit := protocol.NewMessage(protocol.MsgTypeSetSpeedLimit, "robot-1")
+	limit.Payload["max_linear"] = 1.5
+
+	// Act & Assert: 管理者でなければ forbidden
+	if resp := sendAndDecode(t, h, client, limit); resp.Payload["code"] != protocol.ErrCodeForbidden {
+		t.Errorf("Expected forbidden for a non-admin, got %v", resp.Payload)
+	}
+
+	// 管理者でも、ハードウェアの上限（1.0 m/s）を超える値は velocity_out_of_range
+	h.SetAdminUsers([]string{"user-1"})
+	if resp := sendAndDecode(t, h, client, limit); resp.Payload["code"] != protocol.ErrCodeVelocityOutOfRange {
+		t.Errorf("Expected velocity_out_of_range above the hardware limit, got %v", resp.Payload)
+	}
+
+	// 0 以下は invalid_message
+	limit.Payload["max_linear"] = 0.0
+	if resp := sendAndDecode(t, h, client, limit); resp.Payload["code"] != protocol.ErrCodeInvalidMessage {
+		t.Errorf("Expected invalid_message for a zero limit, got %v", resp.Payload)
+	}
+}
@@ -0,0 +1,63 @@
+// =============================================================================
+// ファイル: stop_all_test.go
+// 概要: 操作中の全ロボットのソフトストップ（stop_all）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"testing"
+
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+)
+
+// TestStopAll_StopsControlledRobotsWithoutLatching は操作中のロボットが止まり、E-Stop と違って次のコマンドですぐ再開できることをテストする
+func TestStopAll_StopsControlledRobotsWithoutLatching(t *testing.T) {
+	// Arrange: user-1 が robot-1 を前進させている（速度コマンドで操作ロックも取得される）
+	_, h, _, rec := setupDisconnectStop(t)
+	client := &server.Client{ID: "client-1", UserID: "user-1", Send: make(chan []byte, 8), Authenticated: true}
+	sendVelocity(t, h, client, 0.5)
+
+	// Act
+	resp := sendAndDecode(t, h, client, protocol.NewMessage(protocol.MsgTypeStopAll, ""))
+
+	// Assert: robot-1 に速度 0 とナビゲーションの中止が送られる
+	if resp.Type != protocol.MsgTypeCommandAck || resp.Payload["command"] != "stop_all" {
+		t.Fatalf("Expected a stop_all cmd_ack, got %s %v", resp.Type, resp.Payload)
+	}
+	stopped, _ := resp.Payload["stopped"].([]any)
+	if len(stopped) != 1 || stopped[0] != "robot-1" {
+		t.Errorf("Expected robot-1 to be stopped, got %v", resp.Payload["stopped"])
+	}
+	rec.mu.Lock()
+	if n := len(rec.commands); n != 3 || rec.commands[1].Payload["linear_x"] != 0.0 || rec.commands[2].Type != "nav_cancel" {
+		t.Errorf("Expected a zero velocity and nav_cancel after the drive command, got %v", rec.commands)
+	}
+	rec.mu.Unlock()
+
+	// E-Stop はラッチされていないので、次の速度コマンドはそのまま送られる
+	sendVelocity(t, h, client, 0.3)
+	if cmd, _ := rec.last(); cmd.Payload["linear_x"] != 0.3 {
+		t.Errorf("Expected control to resume immediately, got %v", cmd.Payload)
+	}
+}
+
+// TestStopAll_IgnoresRobotsLockedByOthers は他のユーザーがロックしているロボットを止めないことをテストする
+func TestStopAll_IgnoresRobotsLockedByOthers(t *testing.T) {
+	// Arrange: user-1 が robot-1 を操作中
+	_, h, _, rec := setupDisconnectStop(t)
+	owner := &server.Client{ID: "client-1", UserID: "user-1", Send: make(chan []byte, 8), Authenticated: true}
+	sendVelocity(t, h, owner, 0.5)
+	other := &server.Client{ID: "client-2", UserID: "user-2", Send: make(chan []byte, 8), Authenticated: true}
+
+	// Act: 別のユーザーが stop_all を送る
+	resp := sendAndDecode(t, h, other, protocol.NewMessage(protocol.MsgTypeStopAll, ""))
+
+	// Assert
+	if stopped, _ := resp.Payload["stopped"].([]any); len(stopped) != 0 {
+		t.Errorf("Expected no robots to be stopped, got %v", stopped)
+	}
+	if _, n := rec.last(); n != 1 {
+		t.Errorf("Expected no commands besides the drive command, got %d", n)
+	}
+}
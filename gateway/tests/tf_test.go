@@ -0,0 +1,113 @@
+// =============================================================================
+// ファイル: tf_test.go
+// 概要: 座標変換ツリー（tf / tf_static）の生成と、ラッチによる後からの購読者への配信のテストコード
+// =============================================================================
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/adapter/mock"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// TestMockTF_EmitsStaticOnConnectAndDynamicWithOdometry は接続時の静的な変換と、オドメトリに合わせた動的な変換をテストする
+func TestMockTF_EmitsStaticOnConnectAndDynamicWithOdometry(t *testing.T) {
+	// Arrange
+	m := mock.NewMockAdapter(zap.NewNop())
+	ctx := context.Background()
+
+	// Act
+	if err := m.Connect(ctx, map[string]any{"enabled_topics": "odom,tf"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer m.Disconnect(ctx)
+
+	// Assert: tf_static は base_link から各センサーへの変換、tf は odom → base_link
+	children := map[string][]string{}
+	deadline := time.After(time.Second)
+	for len(children["tf_static"]) == 0 || len(children["tf"]) == 0 {
+		select {
+		case data := <-m.SensorDataChannel():
+			if data.DataType != "tf2_msgs/TFMessage" {
+				continue
+			}
+			for _, item := range data.Data["transforms"].([]any) {
+				tf := item.(map[string]any)
+				parent := tf["header"].(map[string]any)["frame_id"]
+				children[data.Topic] = append(children[data.Topic], parent.(string)+"->"+tf["child_frame_id"].(string))
+				rotation := tf["transform"].(map[string]any)["rotation"].(map[string]any)
+				if rotation["w"] != 1.0 {
+					t.Errorf("Expected an identity rotation for a robot that has not turned, got %v", rotation)
+				}
+			}
+		case <-deadline:
+			t.Fatalf("Expected tf and tf_static samples, got %v", children)
+		}
+	}
+	if got := children["tf_static"]; len(got) != 2 || got[0] != "base_link->lidar_link" || got[1] != "base_link->imu_link" {
+		t.Errorf("Expected static transforms to lidar_link and imu_link, got %v", got)
+	}
+	if got := children["tf"]; got[0] != "odom->base_link" {
+		t.Errorf("Expected a dynamic odom->base_link transform, got %v", got)
+	}
+}
+
+// TestMockTF_RequiresOdom は odom なしで tf を有効にすると接続が失敗することをテストする
+func TestMockTF_RequiresOdom(t *testing.T) {
+	m := mock.NewMockAdapter(zap.NewNop())
+	if err := m.Connect(context.Background(), map[string]any{"enabled_topics": "tf,battery"}); err == nil {
+		t.Error("Expected an error when tf is enabled without odom")
+	}
+}
+
+// TestLatchedTopics_LateSubscriberReceivesStaticTF は後から購読したクライアントにも tf_static が届くことをテストする
+func TestLatchedTopics_LateSubscriberReceivesStaticTF(t *testing.T) {
+	// Arrange: 購読者がいない間に tf_static が届く
+	logger := zap.NewNop()
+	hub := server.NewHub(logger)
+	go hub.Run()
+	fanout := server.NewSensorFanout(hub, protocol.NewCodec(), 1, 1, logger)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fanout.Start(ctx, nil)
+	fanout.Submit(ctx, adapter.SensorData{
+		RobotID:  "robot-1",
+		Topic:    "tf_static",
+		DataType: "tf2_msgs/TFMessage",
+		Data:     map[string]any{"transforms": []any{}},
+	})
+
+	client := &server.Client{ID: "client-1", Send: make(chan []byte, 4), Subscriptions: map[string]bool{}, Authenticated: true}
+	hub.Register(client)
+	for i := 0; hub.ClientCount() < 1 && i < 100; i++ { // Run() が登録を反映するまで待つ
+		time.Sleep(time.Millisecond)
+	}
+
+	// Act: ワーカーが保持するまで待ってから購読する
+	var msg *protocol.Message
+	for i := 0; msg == nil && i < 100; i++ {
+		time.Sleep(time.Millisecond)
+		hub.SubscribeClient(client, "robot-1")
+		select {
+		case data := <-client.Send:
+			decoded, err := protocol.NewCodec().Decode(data)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			msg = decoded
+		default:
+			hub.UnsubscribeClient(client, "robot-1")
+		}
+	}
+
+	// Assert
+	if msg == nil || msg.Type != protocol.MsgTypeSensorData || msg.Topic != "tf_static" {
+		t.Fatalf("Expected the latched tf_static on subscribe, got %+v", msg)
+	}
+}
@@ -0,0 +1,34 @@
+// =============================================================================
+// ファイル: topic_remap_test.go
+// 概要: トピック名の付け替え（TopicRemap）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"testing"
+
+	"github.com/robot-ai-webapp/gateway/internal/server"
+)
+
+// TestTopicRemap_Apply はロボットごとに付け替え、対応表にない名前はそのまま返すことをテストする
+func TestTopicRemap_Apply(t *testing.T) {
+	remap := server.TopicRemap{"robot-1": {"scan": "/robot1/laser"}}
+
+	tests := []struct {
+		robotID, topic, want string
+	}{
+		{"robot-1", "scan", "/robot1/laser"},
+		{"robot-1", "odom", "odom"}, // 対応表にないトピック
+		{"robot-2", "scan", "scan"}, // 対応表にないロボット
+	}
+	for _, tt := range tests {
+		if got := remap.Apply(tt.robotID, tt.topic); got != tt.want {
+			t.Errorf("Apply(%q, %q) = %q, want %q", tt.robotID, tt.topic, got, tt.want)
+		}
+	}
+
+	// nil の対応表でも使える
+	if got := server.TopicRemap(nil).Apply("robot-1", "scan"); got != "scan" {
+		t.Errorf("Expected nil remap to keep the topic, got %q", got)
+	}
+}
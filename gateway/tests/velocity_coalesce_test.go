@@ -0,0 +1,78 @@
+// =============================================================================
+// ファイル: velocity_coalesce_test.go
+// 概要: 速度コマンドの最小間隔（SetVelocityMinInterval）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+)
+
+// sendVelocity: robot-1 に前進コマンドを送り、ACK を返す
+func sendVelocity(t *testing.T, h *server.Handler, client *server.Client, linearX float64) *protocol.Message {
+	t.Helper()
+	msg := protocol.NewMessage(protocol.MsgTypeVelocityCommand, "robot-1")
+	msg.Payload["linear_x"] = linearX
+	resp := sendAndDecode(t, h, client, msg)
+	if resp.Type != protocol.MsgTypeCommandAck {
+		t.Fatalf("Expected cmd_ack, got %s %v", resp.Type, resp.Payload)
+	}
+	return resp
+}
+
+// TestVelocityMinInterval_CoalescesToLatest は間隔内のコマンドがまとめられ、最新のものだけが送られることをテストする
+func TestVelocityMinInterval_CoalescesToLatest(t *testing.T) {
+	// Arrange: 最小間隔 50ms
+	_, h, _, rec := setupDisconnectStop(t)
+	h.SetVelocityMinInterval(50 * time.Millisecond)
+	client := &server.Client{ID: "client-1", UserID: "user-1", Send: make(chan []byte, 8), Authenticated: true}
+
+	// Act: 5つのコマンドを続けて送る
+	if resp := sendVelocity(t, h, client, 0.1); resp.Payload["coalesced"] != nil {
+		t.Errorf("Expected the first command to be sent immediately, got %v", resp.Payload)
+	}
+	for _, v := range []float64{0.2, 0.3, 0.4, 0.5} {
+		if resp := sendVelocity(t, h, client, v); resp.Payload["coalesced"] != true {
+			t.Errorf("Expected %.1f m/s to be coalesced, got %v", v, resp.Payload)
+		}
+	}
+
+	// Assert: すぐに送られたのは最初の1つだけで、間隔の後に最新の 0.5 m/s が送られる
+	if _, n := rec.last(); n != 1 {
+		t.Fatalf("Expected 1 command before the interval elapsed, got %d", n)
+	}
+	time.Sleep(100 * time.Millisecond)
+	cmd, n := rec.last()
+	if n != 2 || cmd.Payload["linear_x"] != 0.5 {
+		t.Errorf("Expected the latest 0.5 m/s to be sent as the 2nd command, got %d commands, last %v", n, cmd.Payload)
+	}
+}
+
+// TestVelocityMinInterval_EStopDropsPending は E-Stop が保留中のコマンドを捨てることをテストする
+func TestVelocityMinInterval_EStopDropsPending(t *testing.T) {
+	// Arrange: 最小間隔 50ms。1つ目は送られ、2つ目は保留になる
+	_, h, _, rec := setupDisconnectStop(t)
+	h.SetVelocityMinInterval(50 * time.Millisecond)
+	client := &server.Client{ID: "client-1", UserID: "user-1", Send: make(chan []byte, 8), Authenticated: true}
+	sendVelocity(t, h, client, 0.1)
+	sendVelocity(t, h, client, 0.5)
+
+	// Act: 間隔が経つ前に E-Stop を発動する
+	estop := protocol.NewMessage(protocol.MsgTypeEmergencyStop, "robot-1")
+	estop.Payload["activate"] = true
+	h.HandleMessage(client, estop)
+	time.Sleep(100 * time.Millisecond)
+
+	// Assert: 保留中の 0.5 m/s は送られない
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	for _, cmd := range rec.commands {
+		if cmd.Payload["linear_x"] == 0.5 {
+			t.Fatalf("Expected the pending command to be dropped after E-Stop, got %v", rec.commands)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// =============================================================================
+// ファイル: velocity_delta_test.go
+// 概要: velocity_delta（相対速度コマンド）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"testing"
+
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+)
+
+// setupDeltaHandler: 接続済みのモックロボット robot-1 と、上限 1.0 m/s のハンドラーを作る
+func setupDeltaHandler(t *testing.T) (*server.Handler, *server.Client) {
+	t.Helper()
+	env := newTestHandler(t)
+	return env.h, env.client
+}
+
+// sendDelta: velocity_delta を送り、ACK の clamped を返す
+func sendDelta(t *testing.T, h *server.Handler, client *server.Client, linearX float64) bool {
+	t.Helper()
+	msg := protocol.NewMessage(protocol.MsgTypeVelocityDelta, "robot-1")
+	msg.Payload["linear_x"] = linearX
+	h.HandleMessage(client, msg)
+
+	resp, err := protocol.NewCodec().Decode(<-client.Send)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Type != protocol.MsgTypeCommandAck {
+		t.Fatalf("Expected cmd_ack, got %s (%s)", resp.Type, resp.Error)
+	}
+	clamped, _ := resp.Payload["clamped"].(bool)
+	return clamped
+}
+
+// TestVelocityDelta_AccumulatesAndClamps は差分が累積され、上限で頭打ちになることをテストする
+func TestVelocityDelta_AccumulatesAndClamps(t *testing.T) {
+	// Arrange
+	h, client := setupDeltaHandler(t)
+
+	// Act & Assert: 0.6 + 0.3 = 0.9 は上限以内、さらに +0.3 で 1.0 に頭打ち
+	if sendDelta(t, h, client, 0.6) || sendDelta(t, h, client, 0.3) {
+		t.Fatal("Expected no clamping below the limit")
+	}
+	if !sendDelta(t, h, client, 0.3) {
+		t.Fatal("Expected clamping when the accumulated velocity exceeds the limit")
+	}
+
+	// Assert: 頭打ち後の基準は 1.0 なので、-0.5 で 0.5 になる（上限を超えた分は累積しない）
+	h.ResetVelocityBaseline("robot-2") // 別のロボットの基準は影響しない
+	if sendDelta(t, h, client, -0.5) {
+		t.Error("Expected no clamping after slowing down")
+	}
+	if !sendDelta(t, h, client, 0.6) {
+		t.Error("Expected 0.5 + 0.6 to be clamped")
+	}
+}
+
+// TestVelocityDelta_EStopResetsBaseline は E-Stop で基準が 0 に戻ることをテストする
+func TestVelocityDelta_EStopResetsBaseline(t *testing.T) {
+	// Arrange: 0.8 m/s まで加速しておく
+	h, client := setupDeltaHandler(t)
+	sendDelta(t, h, client, 0.8)
+
+	// Act: E-Stop を発動して解除する
+	for _, activate := range []bool{true, false} {
+		msg := protocol.NewMessage(protocol.MsgTypeEmergencyStop, "robot-1")
+		msg.Payload["activate"] = activate
+		h.HandleMessage(client, msg)
+	}
+	for len(client.Send) > 0 {
+		<-client.Send
+	}
+
+	// Assert: 基準が 0 なら +0.5 は 0.5（クランプされない）、残っていれば 1.3 でクランプされる
+	if sendDelta(t, h, client, 0.5) {
+		t.Error("Expected the delta to resume from zero after E-Stop")
+	}
+}
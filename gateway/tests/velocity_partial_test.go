@@ -0,0 +1,88 @@
+// =============================================================================
+// ファイル: velocity_partial_test.go
+// 概要: 一部の成分だけを更新する速度コマンド（velocity_cmd の "partial": true）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"testing"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+)
+
+// sendVelocityPayload: velocity_cmd を送り、応答を返す
+func sendVelocityPayload(t *testing.T, h *server.Handler, client *server.Client, payload map[string]any) *protocol.Message {
+	t.Helper()
+	msg := protocol.NewMessage(protocol.MsgTypeVelocityCommand, "robot-1")
+	for k, v := range payload {
+		msg.Payload[k] = v
+	}
+	return sendAndDecode(t, h, client, msg)
+}
+
+// TestVelocityPartial_KeepsOmittedAxes は partial では省略した成分が直前の値のまま保たれることをテストする
+func TestVelocityPartial_KeepsOmittedAxes(t *testing.T) {
+	// Arrange: 前進 0.5 m/s、回転 0.3 rad/s で走行中
+	h, client := setupDeltaHandler(t)
+	sendVelocityPayload(t, h, client, map[string]any{"linear_x": 0.5, "angular_z": 0.3})
+
+	// Act: 回転だけを変える
+	resp := sendVelocityPayload(t, h, client, map[string]any{"angular_z": -0.2, "partial": true})
+
+	// Assert
+	if resp.Type != protocol.MsgTypeCommandAck {
+		t.Fatalf("Expected cmd_ack, got %s (%s)", resp.Type, resp.Error)
+	}
+	want := adapter.Velocity{LinearX: 0.5, AngularZ: -0.2}
+	if got := h.LastVelocity("robot-1"); got != want {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+}
+
+// TestVelocityPartial_WithoutFlagZeroesOmittedAxes はフラグがなければ従来どおり省略した成分が 0 になることをテストする
+func TestVelocityPartial_WithoutFlagZeroesOmittedAxes(t *testing.T) {
+	// Arrange
+	h, client := setupDeltaHandler(t)
+	sendVelocityPayload(t, h, client, map[string]any{"linear_x": 0.5, "angular_z": 0.3})
+
+	// Act
+	sendVelocityPayload(t, h, client, map[string]any{"angular_z": -0.2})
+
+	// Assert
+	want := adapter.Velocity{AngularZ: -0.2}
+	if got := h.LastVelocity("robot-1"); got != want {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+}
+
+// TestVelocityPartial_MergedResultIsLimited は合成した速度も速度制限を通ることをテストする
+func TestVelocityPartial_MergedResultIsLimited(t *testing.T) {
+	// Arrange: 上限 1.0 m/s
+	h, client := setupDeltaHandler(t)
+	sendVelocityPayload(t, h, client, map[string]any{"angular_z": 0.4})
+
+	// Act: 上限を超える前進を partial で指定
+	resp := sendVelocityPayload(t, h, client, map[string]any{"linear_x": 3.0, "partial": true})
+
+	// Assert: 前進は上限で頭打ちになり、回転は保たれる
+	if clamped, _ := resp.Payload["clamped"].(bool); !clamped {
+		t.Errorf("Expected clamped=true, got %v", resp.Payload)
+	}
+	want := adapter.Velocity{LinearX: 1.0, AngularZ: 0.4}
+	if got := h.LastVelocity("robot-1"); got != want {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+}
+
+// TestVelocityPartial_RejectsInvalidFlag は partial が真偽値でなければ拒否されることをテストする
+func TestVelocityPartial_RejectsInvalidFlag(t *testing.T) {
+	h, client := setupDeltaHandler(t)
+
+	resp := sendVelocityPayload(t, h, client, map[string]any{"linear_x": 0.2, "partial": "yes"})
+
+	if resp.Type != protocol.MsgTypeError || resp.Payload["code"] != protocol.ErrCodeInvalidMessage {
+		t.Errorf("Expected invalid_message error, got %s %v", resp.Type, resp.Payload)
+	}
+}
@@ -0,0 +1,58 @@
+// =============================================================================
+// ファイル: velocity_preset_test.go
+// 概要: 名前付き速度プリセット（velocity_preset）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"testing"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+)
+
+// setupPresetHandler: 接続済みのモックロボット robot-1 とプリセット付きのハンドラーを作る
+func setupPresetHandler(t *testing.T) (*server.Handler, *server.Client) {
+	t.Helper()
+	env := newTestHandler(t, withVelocityLimits(0.5, 1.0))
+	env.h.SetVelocityPresets(map[string]adapter.Velocity{"dash": {LinearX: 2.0}})
+	return env.h, env.client
+}
+
+// TestVelocityPreset_RunsThroughSafetyPipeline はプリセットが速度制限を受けて実行されることをテストする
+func TestVelocityPreset_RunsThroughSafetyPipeline(t *testing.T) {
+	// Arrange
+	h, client := setupPresetHandler(t)
+	msg := protocol.NewMessage(protocol.MsgTypeVelocityPreset, "robot-1")
+	msg.Payload["preset"] = "dash"
+
+	// Act
+	h.HandleMessage(client, msg)
+
+	// Assert: 上限 0.5 m/s を超える 2.0 m/s なので、クランプされた ACK が返る
+	resp, err := protocol.NewCodec().Decode(<-client.Send)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Type != protocol.MsgTypeCommandAck || resp.Payload["clamped"] != true {
+		t.Errorf("Expected clamped velocity ack, got %s %v (%s)", resp.Type, resp.Payload, resp.Error)
+	}
+}
+
+// TestVelocityPreset_UnknownName は未定義のプリセット名が unknown_preset で拒否されることをテストする
+func TestVelocityPreset_UnknownName(t *testing.T) {
+	h, client := setupPresetHandler(t)
+	msg := protocol.NewMessage(protocol.MsgTypeVelocityPreset, "robot-1")
+	msg.Payload["preset"] = "fly"
+
+	h.HandleMessage(client, msg)
+
+	resp, err := protocol.NewCodec().Decode(<-client.Send)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Payload["code"] != protocol.ErrCodeUnknownPreset {
+		t.Errorf("Expected unknown_preset error, got %s %v", resp.Type, resp.Payload)
+	}
+}
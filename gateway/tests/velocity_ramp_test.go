@@ -0,0 +1,60 @@
+// =============================================================================
+// ファイル: velocity_ramp_test.go
+// 概要: 速度指令のランプ補間（adapter.VelocityRamp）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+)
+
+// almostEqual は浮動小数点の誤差を許容して比較するヘルパー
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+// TestVelocityRamp_ReachesTargetOverRampTime はランプ時間かけて直線的に目標に達することをテストする
+func TestVelocityRamp_ReachesTargetOverRampTime(t *testing.T) {
+	// Arrange: 200ms で 0 → 1.0 m/s
+	ramp := adapter.NewVelocityRamp(200 * time.Millisecond)
+	ramp.SetTarget(adapter.Velocity{LinearX: 1.0})
+
+	// Act & Assert: 50ms ごとに 0.25 m/s ずつ増える
+	for i, want := range []float64{0.25, 0.5, 0.75, 1.0, 1.0} {
+		got := ramp.Step(50 * time.Millisecond).LinearX
+		if !almostEqual(got, want) {
+			t.Errorf("step %d: expected %.2f, got %.4f", i+1, want, got)
+		}
+	}
+}
+
+// TestVelocityRamp_RetargetStartsFromCurrent は途中で目標が変わっても速度が飛ばないことをテストする
+func TestVelocityRamp_RetargetStartsFromCurrent(t *testing.T) {
+	ramp := adapter.NewVelocityRamp(100 * time.Millisecond)
+	ramp.SetTarget(adapter.Velocity{AngularZ: 1.0})
+	ramp.Step(50 * time.Millisecond) // 0.5 rad/s まで上がる
+
+	// 逆向きの指令: 0.5 → -1.0 を 100ms かけて変化する
+	ramp.SetTarget(adapter.Velocity{AngularZ: -1.0})
+	got := ramp.Step(50 * time.Millisecond).AngularZ
+	if !almostEqual(got, -0.25) {
+		t.Errorf("Expected -0.25, got %.4f", got)
+	}
+}
+
+// TestVelocityRamp_ResetIsImmediate は Reset（緊急停止用）が即座に反映されることをテストする
+func TestVelocityRamp_ResetIsImmediate(t *testing.T) {
+	ramp := adapter.NewVelocityRamp(time.Second)
+	ramp.SetTarget(adapter.Velocity{LinearX: 1.0})
+	ramp.Step(500 * time.Millisecond)
+
+	ramp.Reset(adapter.Velocity{})
+
+	if v := ramp.Step(50 * time.Millisecond); v != (adapter.Velocity{}) {
+		t.Errorf("Expected zero velocity after reset, got %+v", v)
+	}
+}
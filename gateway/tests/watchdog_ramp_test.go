@@ -0,0 +1,212 @@
+// =============================================================================
+// ファイル: watchdog_ramp_test.go
+// 概要: ウォッチドッグのタイムアウト時のランプダウン（段階的な減速）のテストコード
+// =============================================================================
+package tests
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/robot-ai-webapp/gateway/internal/adapter"
+	"github.com/robot-ai-webapp/gateway/internal/adapter/mock"
+	"github.com/robot-ai-webapp/gateway/internal/clock"
+	"github.com/robot-ai-webapp/gateway/internal/convert"
+	"github.com/robot-ai-webapp/gateway/internal/protocol"
+	"github.com/robot-ai-webapp/gateway/internal/safety"
+	"github.com/robot-ai-webapp/gateway/internal/server"
+	"go.uber.org/zap"
+)
+
+// setupRampWatchdog: 0.4 m/s で走っていた robot-1 と、減速時間 200ms のウォッチドッグを作る
+func setupRampWatchdog(t *testing.T) (*safety.TimeoutWatchdog, *clock.Mock, *recordingAdapter) {
+	t.Helper()
+	watchdog, clk, rec, _ := setupRampWatchdogWithRegistry(t)
+	return watchdog, clk, rec
+}
+
+// setupRampWatchdogWithRegistry: setupRampWatchdog と同じものを、E-Stop 用のレジストリも付けて返す
+func setupRampWatchdogWithRegistry(t *testing.T) (*safety.TimeoutWatchdog, *clock.Mock, *recordingAdapter, *adapter.Registry) {
+	t.Helper()
+	logger := zap.NewNop()
+	rec := &recordingAdapter{MockAdapter: mock.NewMockAdapter(logger)}
+	registry := adapter.NewRegistry(logger)
+	registry.RegisterFactory("recording", func(*zap.Logger) adapter.RobotAdapter { return rec })
+	if _, err := registry.CreateAdapter("robot-1", "recording"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	clk := clock.NewMock(testEpoch)
+	watchdog := safety.NewTimeoutWatchdog(time.Second, registry, logger)
+	watchdog.SetClock(clk)
+	watchdog.SetRampDown(200*time.Millisecond, func(string) adapter.Velocity {
+		return adapter.Velocity{LinearX: 0.4}
+	})
+	watchdog.RecordCommand("robot-1")
+	return watchdog, clk, rec, registry
+}
+
+// sentLinearX: 記録された速度コマンドの linear_x を順に返す
+func sentLinearX(rec *recordingAdapter) []float64 {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	var xs []float64
+	for _, cmd := range rec.commands {
+		x, _ := convert.ToFloat64(cmd.Payload["linear_x"])
+		xs = append(xs, x)
+	}
+	return xs
+}
+
+// TestRampDownSequence_DecreasesToZero は速度が直線的に下がり、最後がちょうど 0 になることをテストする
+func TestRampDownSequence_DecreasesToZero(t *testing.T) {
+	// Act
+	seq := safety.RampDownSequence(adapter.Velocity{LinearX: 0.8, AngularZ: -0.4}, 200*time.Millisecond)
+
+	// Assert: 50ms ごとに4段階
+	want := []float64{0.6, 0.4, 0.2, 0}
+	if len(seq) != len(want) {
+		t.Fatalf("Expected %d steps, got %v", len(want), seq)
+	}
+	for i, v := range seq {
+		if math.Abs(v.LinearX-want[i]) > 1e-9 || math.Abs(v.AngularZ+want[i]/2) > 1e-9 {
+			t.Errorf("Step %d: expected linear_x %.2f, got %+v", i, want[i], v)
+		}
+	}
+	if seq[len(seq)-1] != (adapter.Velocity{}) {
+		t.Errorf("Expected the last step to be an exact stop, got %+v", seq[len(seq)-1])
+	}
+
+	// 減速時間が1段階以下なら即時停止
+	if seq := safety.RampDownSequence(adapter.Velocity{LinearX: 0.8}, 0); len(seq) != 1 || seq[0] != (adapter.Velocity{}) {
+		t.Errorf("Expected a single stop without a deceleration time, got %v", seq)
+	}
+}
+
+// TestTimeoutWatchdog_RampsDownOnTimeout はタイムアウト時に段階的に減速して止まることをテストする
+func TestTimeoutWatchdog_RampsDownOnTimeout(t *testing.T) {
+	// Arrange
+	watchdog, clk, rec := setupRampWatchdog(t)
+
+	// Act
+	clk.Advance(2 * time.Second)
+	watchdog.CheckTimeouts(context.Background(), clk.Now())
+
+	// Assert: 0.3 → 0.2 → 0.1 → 0 の順に届く
+	want := []float64{0.3, 0.2, 0.1, 0}
+	deadline := time.Now().Add(time.Second)
+	for len(sentLinearX(rec)) < len(want) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	got := sentLinearX(rec)
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d velocity commands, got %v", len(want), got)
+	}
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-9 {
+			t.Errorf("Expected the ramp %v, got %v", want, got)
+			break
+		}
+	}
+}
+
+// TestTimeoutWatchdog_NewCommandCancelsRampDown は減速中に新しいコマンドが届くと残りを送らないことをテストする
+func TestTimeoutWatchdog_NewCommandCancelsRampDown(t *testing.T) {
+	// Arrange: タイムアウトさせて減速を始める
+	watchdog, clk, rec := setupRampWatchdog(t)
+	clk.Advance(2 * time.Second)
+	watchdog.CheckTimeouts(context.Background(), clk.Now())
+
+	// Act: 新しいコマンドが届く
+	watchdog.RecordCommand("robot-1")
+	time.Sleep(4 * safety.WatchdogRampStep)
+
+	// Assert: 送られたとしても、打ち切る前の最初の段階だけ
+	if got := sentLinearX(rec); len(got) > 1 {
+		t.Errorf("Expected the ramp to stop after the new command, got %v", got)
+	}
+}
+
+// nonZeroAfter: n 件目以降に送られた速度コマンドのうち、0 でない linear_x を返す
+func nonZeroAfter(rec *recordingAdapter, n int) []float64 {
+	var xs []float64
+	for _, x := range sentLinearX(rec)[n:] {
+		if x != 0 {
+			xs = append(xs, x)
+		}
+	}
+	return xs
+}
+
+// TestTimeoutWatchdog_EStopDuringRampSendsNoVelocity は減速中に E-Stop が発動すると、
+// それ以降 0 でない速度を送らないことをテストする（打ち切りがなくても各段階の前に確認する）
+func TestTimeoutWatchdog_EStopDuringRampSendsNoVelocity(t *testing.T) {
+	// Arrange: タイムアウトさせて減速を始める
+	watchdog, clk, rec, registry := setupRampWatchdogWithRegistry(t)
+	estop := safety.NewEStopManager(registry, zap.NewNop())
+	watchdog.SetEStop(estop)
+	clk.Advance(2 * time.Second)
+	watchdog.CheckTimeouts(context.Background(), clk.Now())
+
+	// Act: 減速中に E-Stop が発動する
+	if err := estop.Activate(context.Background(), "robot-1", "user-1", "test"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sent := len(sentLinearX(rec))
+	time.Sleep(5 * safety.WatchdogRampStep)
+
+	// Assert
+	if got := nonZeroAfter(rec, sent); len(got) > 0 {
+		t.Errorf("Expected no non-zero velocity after E-Stop, got %v", got)
+	}
+}
+
+// TestHandlerEStop_CancelsWatchdogRampDown は estop メッセージで減速が打ち切られることをテストする
+func TestHandlerEStop_CancelsWatchdogRampDown(t *testing.T) {
+	// Arrange: E-Stop の確認（SetEStop）なしで、打ち切りだけで止まることを確かめる
+	logger := zap.NewNop()
+	watchdog, clk, rec, registry := setupRampWatchdogWithRegistry(t)
+	estop := safety.NewEStopManager(registry, logger)
+	h := server.NewHandler(server.NewHub(logger), registry, estop, nil, watchdog, nil, nil, nil, logger)
+	client := &server.Client{ID: "client-1", UserID: "user-1", Send: make(chan []byte, 8), Authenticated: true}
+	clk.Advance(2 * time.Second)
+	watchdog.CheckTimeouts(context.Background(), clk.Now())
+
+	// Act
+	msg := protocol.NewMessage(protocol.MsgTypeEmergencyStop, "robot-1")
+	msg.Payload["activate"] = true
+	h.HandleMessage(client, msg)
+	sent := len(sentLinearX(rec))
+	time.Sleep(5 * safety.WatchdogRampStep)
+
+	// Assert
+	if got := nonZeroAfter(rec, sent); len(got) > 0 {
+		t.Errorf("Expected no non-zero velocity after E-Stop, got %v", got)
+	}
+}
+
+// TestHandlerDisableRobot_CancelsWatchdogRampDown は set_robot_enabled で無効にすると減速が打ち切られることをテストする
+func TestHandlerDisableRobot_CancelsWatchdogRampDown(t *testing.T) {
+	// Arrange: タイムアウトさせて減速を始める
+	logger := zap.NewNop()
+	watchdog, clk, rec, registry := setupRampWatchdogWithRegistry(t)
+	h := server.NewHandler(server.NewHub(logger), registry, safety.NewEStopManager(registry, logger), nil, watchdog, nil, nil, nil, logger)
+	h.SetAdminUsers([]string{"user-1"})
+	client := &server.Client{ID: "client-1", UserID: "user-1", Send: make(chan []byte, 8), Authenticated: true}
+	clk.Advance(2 * time.Second)
+	watchdog.CheckTimeouts(context.Background(), clk.Now())
+
+	// Act
+	if resp := setRobotEnabled(t, h, client, false); resp.Type != protocol.MsgTypeCommandAck {
+		t.Fatalf("Expected the robot to be disabled, got %s %v", resp.Type, resp.Payload)
+	}
+	sent := len(sentLinearX(rec))
+	time.Sleep(5 * safety.WatchdogRampStep)
+
+	// Assert
+	if got := nonZeroAfter(rec, sent); len(got) > 0 {
+		t.Errorf("Expected no non-zero velocity after disabling the robot, got %v", got)
+	}
+}